@@ -0,0 +1,186 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os/user"
+	"sort"
+	"strings"
+	"time"
+
+	"grimm.is/flywall/internal/alerting"
+	"grimm.is/flywall/internal/brand"
+	"grimm.is/flywall/internal/ctlplane"
+)
+
+// matcherFlag collects repeated --match label=pattern flags.
+type matcherFlag map[string]string
+
+func (m matcherFlag) String() string {
+	parts := make([]string, 0, len(m))
+	for k, v := range m {
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func (m matcherFlag) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" || v == "" {
+		return fmt.Errorf("matcher must be label=pattern, got %q", s)
+	}
+	m[k] = v
+	return nil
+}
+
+// RunAlert handles the "alert" command.
+func RunAlert(args []string) error {
+	if len(args) < 1 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printAlertUsage()
+		return nil
+	}
+
+	cli, err := ctlplane.NewClient()
+	if err != nil {
+		return fmt.Errorf("failed to connect to local control plane: %w", err)
+	}
+	defer cli.Close()
+
+	switch args[0] {
+	case "list", "ls":
+		return runAlertList(cli)
+	case "ack":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: %s alert ack <id>", brand.LowerName)
+		}
+		if err := cli.AckAlert(args[1], cliActor()); err != nil {
+			return err
+		}
+		Printer.Printf("Acknowledged alert %s\n", args[1])
+	case "resolve":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: %s alert resolve <id>", brand.LowerName)
+		}
+		if err := cli.ResolveAlert(args[1], cliActor()); err != nil {
+			return err
+		}
+		Printer.Printf("Resolved alert %s\n", args[1])
+	case "silence":
+		return runAlertSilence(cli, args[1:])
+	default:
+		printAlertUsage()
+		return fmt.Errorf("unknown alert command: %s", args[0])
+	}
+	return nil
+}
+
+func runAlertList(cli *ctlplane.Client) error {
+	alerts, err := cli.GetAlerts()
+	if err != nil {
+		return err
+	}
+	if len(alerts) == 0 {
+		Printer.Println("No alerts")
+		return nil
+	}
+
+	Printer.Printf("%-14s %-13s %-9s %-20s %-6s %-17s %s\n", "ID", "STATUS", "SEVERITY", "RULE", "COUNT", "LAST SEEN", "MESSAGE")
+	Printer.Println(strings.Repeat("-", 110))
+	for _, a := range alerts {
+		Printer.Printf("%-14s %-13s %-9s %-20s %-6d %-17s %s\n",
+			a.ID, a.Status, a.Severity, a.RuleName, a.Count, a.LastSeen.Format("2006-01-02 15:04"), a.Message)
+	}
+	return nil
+}
+
+func runAlertSilence(cli *ctlplane.Client, args []string) error {
+	if len(args) < 1 {
+		args = []string{"list"}
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		silences, err := cli.GetSilences()
+		if err != nil {
+			return err
+		}
+		if len(silences) == 0 {
+			Printer.Println("No silences")
+			return nil
+		}
+		Printer.Printf("%-36s %-17s %-17s %-12s %s\n", "ID", "STARTS", "ENDS", "BY", "MATCHERS")
+		Printer.Println(strings.Repeat("-", 110))
+		for _, s := range silences {
+			Printer.Printf("%-36s %-17s %-17s %-12s %s\n",
+				s.ID, s.StartsAt.Format("2006-01-02 15:04"), s.EndsAt.Format("2006-01-02 15:04"),
+				s.CreatedBy, matcherFlag(s.Matchers).String())
+		}
+
+	case "add":
+		fs := flag.NewFlagSet("alert silence add", flag.ContinueOnError)
+		matchers := matcherFlag{}
+		fs.Var(matchers, "match", "Label matcher label=pattern (repeatable; globs allowed)")
+		fs.Var(matchers, "m", "Alias for -match")
+		duration := fs.Duration("duration", time.Hour, "How long the silence lasts")
+		fs.DurationVar(duration, "d", time.Hour, "Alias for -duration")
+		comment := fs.String("comment", "", "Reason for the silence")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		silence, err := cli.CreateSilence(alerting.Silence{
+			Matchers:  matchers,
+			StartsAt:  now,
+			EndsAt:    now.Add(*duration),
+			CreatedBy: cliActor(),
+			Comment:   *comment,
+		})
+		if err != nil {
+			return err
+		}
+		Printer.Printf("Created silence %s until %s\n", silence.ID, silence.EndsAt.Format(time.RFC3339))
+
+	case "rm", "delete":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: %s alert silence rm <id>", brand.LowerName)
+		}
+		if err := cli.DeleteSilence(args[1]); err != nil {
+			return err
+		}
+		Printer.Printf("Deleted silence %s\n", args[1])
+
+	default:
+		printAlertUsage()
+		return fmt.Errorf("unknown silence command: %s", args[0])
+	}
+	return nil
+}
+
+// cliActor identifies the local operator in acknowledgement records.
+func cliActor() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username + " (cli)"
+	}
+	return "cli"
+}
+
+func printAlertUsage() {
+	Printer.Printf(`Usage: %s alert <command>
+
+Commands:
+  list                          List alert groups
+  ack <id>                      Acknowledge an alert (stops repeats and escalation)
+  resolve <id>                  Resolve an alert
+  silence list                  List silences
+  silence add --match k=v ...   Silence matching alerts
+        Options: --duration (-d) <dur> (default 1h), --comment <text>
+  silence rm <id>               Delete a silence
+
+Examples:
+  %s alert silence add --match rule=port-scan --match src_ip='10.0.0.*' -d 2h
+`, brand.LowerName, brand.LowerName)
+}
@@ -0,0 +1,226 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"grimm.is/flywall/internal/brand"
+	"grimm.is/flywall/internal/ctlplane"
+	"grimm.is/flywall/internal/firewall"
+)
+
+// RunConntrack handles the "conntrack" command.
+func RunConntrack(args []string) error {
+	sub := "list"
+	if len(args) > 0 && (args[0] == "help" || args[0] == "-h" || args[0] == "--help") {
+		printConntrackUsage()
+		return nil
+	}
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		sub, args = args[0], args[1:]
+	}
+
+	var filter firewall.ConntrackFilter
+	var id uint
+	fs := flag.NewFlagSet("conntrack "+sub, flag.ContinueOnError)
+	fs.StringVar(&filter.Protocol, "proto", "", "Protocol: tcp, udp, icmp or a number")
+	fs.StringVar(&filter.Protocol, "p", "", "Alias for -proto")
+	fs.StringVar(&filter.IP, "ip", "", "Address or CIDR of either end")
+	fs.StringVar(&filter.SrcIP, "src", "", "Original source address or CIDR")
+	fs.StringVar(&filter.DstIP, "dst", "", "Original destination address or CIDR")
+	port := fs.Uint("port", 0, "Port of either end")
+	sport := fs.Uint("sport", 0, "Original source port")
+	dport := fs.Uint("dport", 0, "Original destination port")
+	fs.StringVar(&filter.State, "state", "", "TCP state, or assured, unreplied, offloaded")
+	fs.StringVar(&filter.Mark, "mark", "", "Conntrack mark, value or value/mask")
+	fs.StringVar(&filter.Zone, "zone", "", "Firewall zone of either end")
+	fs.StringVar(&filter.Zone, "z", "", "Alias for -zone")
+	fs.UintVar(&id, "id", 0, "Conntrack entry ID")
+	limit := fs.Int("limit", 0, "Show at most this many entries (list)")
+	jsonOutput := fs.Bool("json", false, "Output JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	for _, p := range []uint{*port, *sport, *dport} {
+		if p > 65535 {
+			return fmt.Errorf("invalid port %d", p)
+		}
+	}
+	filter.Port, filter.SrcPort, filter.DstPort = uint16(*port), uint16(*sport), uint16(*dport)
+	filter.ID = uint32(id)
+
+	cli, err := ctlplane.NewClient()
+	if err != nil {
+		return fmt.Errorf("failed to connect to local control plane: %w", err)
+	}
+	defer cli.Close()
+
+	switch sub {
+	case "list":
+		entries, total, err := cli.GetConntrack(filter, *limit, 0)
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return printConntrackJSON(entries)
+		}
+		printConntrackEntries(entries)
+		if len(entries) < total {
+			Printer.Printf("\n%d of %d connections shown\n", len(entries), total)
+		} else {
+			Printer.Printf("\n%d connections\n", total)
+		}
+		return nil
+
+	case "kill":
+		if filter.IsEmpty() {
+			return fmt.Errorf("kill needs at least one filter; refusing to flush the whole table")
+		}
+		deleted, err := cli.KillConntrack(filter)
+		if err != nil {
+			return err
+		}
+		Printer.Printf("Deleted %d connections\n", deleted)
+		return nil
+
+	case "watch":
+		return watchConntrack(cli, filter, *jsonOutput)
+
+	default:
+		printConntrackUsage()
+		return fmt.Errorf("unknown conntrack command: %s", sub)
+	}
+}
+
+// watchConntrack prints connections as they open and close until interrupted.
+func watchConntrack(cli *ctlplane.Client, filter firewall.ConntrackFilter, jsonOutput bool) error {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	// The first poll starts the listener and sets the cursor
+	_, lastID, err := cli.GetConntrackEvents(0, filter)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	enc := json.NewEncoder(os.Stdout)
+	for {
+		select {
+		case <-sigCh:
+			return nil
+		case <-ticker.C:
+			events, newLastID, err := cli.GetConntrackEvents(lastID, filter)
+			if err != nil {
+				return err
+			}
+			lastID = newLastID
+			for _, ev := range events {
+				if jsonOutput {
+					enc.Encode(ev)
+					continue
+				}
+				tag := "[NEW]"
+				if ev.Type == firewall.ConntrackEventDestroy {
+					tag = "[DESTROY]"
+				}
+				Printer.Printf("%s %-9s %-6s %s\n", ev.Time.Format("15:04:05"), tag, ev.Entry.Protocol, conntrackTuple(ev.Entry))
+			}
+		}
+	}
+}
+
+func printConntrackEntries(entries []firewall.ConntrackEntry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	Printer.Fprintln(w, "ID\tPROTO\tSTATE\tCONNECTION\tTIMEOUT\tMARK\tPACKETS\tBYTES")
+	for _, e := range entries {
+		state := e.State
+		switch {
+		case e.Offloaded:
+			state += " [OFFLOAD]"
+		case e.Unreplied:
+			state += " [UNREPLIED]"
+		}
+		Printer.Fprintf(w, "%d\t%s\t%s\t%s\t%ds\t%#x\t%d\t%d\n",
+			e.ID, e.Protocol, state, conntrackTuple(e), e.Timeout, e.Mark, e.Packets, e.Bytes)
+	}
+	w.Flush()
+}
+
+// conntrackTuple formats the original direction, and the reply addresses
+// when NAT rewrote them.
+func conntrackTuple(e firewall.ConntrackEntry) string {
+	s := conntrackEndpoint(e.SrcIP, e.SrcPort) + " -> " + conntrackEndpoint(e.DstIP, e.DstPort)
+	if e.ReplySrcIP != e.DstIP || e.ReplyDstIP != e.SrcIP ||
+		e.ReplySrcPort != e.DstPort || e.ReplyDstPort != e.SrcPort {
+		s += " (reply " + conntrackEndpoint(e.ReplySrcIP, e.ReplySrcPort) + " -> " + conntrackEndpoint(e.ReplyDstIP, e.ReplyDstPort) + ")"
+	}
+	return s
+}
+
+func conntrackEndpoint(ip string, port uint16) string {
+	if port == 0 {
+		return ip
+	}
+	return net.JoinHostPort(ip, strconv.Itoa(int(port)))
+}
+
+func printConntrackJSON(entries []firewall.ConntrackEntry) error {
+	if entries == nil {
+		entries = []firewall.ConntrackEntry{}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	Printer.Println(string(data))
+	return nil
+}
+
+func printConntrackUsage() {
+	Printer.Printf(`Usage: %s conntrack [list|kill|watch] [filters]
+
+Shows and manages the kernel's connection tracking table.
+
+Commands:
+  list      List tracked connections (default)
+  kill      Delete the matching connections; they are checked against the
+            ruleset again on their next packet. Needs at least one filter.
+  watch     Print connections as they open and close, until interrupted
+
+Filters (all given filters must match):
+  --proto (-p) <proto>  tcp, udp, icmp, icmpv6 or a protocol number
+  --ip <addr|cidr>      Either end, before or after NAT
+  --src <addr|cidr>     Original source
+  --dst <addr|cidr>     Original destination
+  --port <port>         Port of either end
+  --sport <port>        Original source port
+  --dport <port>        Original destination port
+  --state <state>       TCP state (established, time_wait, ...), or
+                        assured, unreplied, offloaded
+  --mark <mark>         Conntrack mark, value or value/mask (decimal or 0x hex)
+  --zone (-z) <zone>    Either end is in this firewall zone
+  --id <id>             Conntrack entry ID
+
+Options:
+  --limit <n>           Show at most n entries (list)
+  --json                Output JSON
+
+Examples:
+  %s conntrack --zone guest --state established
+  %s conntrack kill --src 192.168.1.50 --dport 22
+  %s conntrack watch -p udp --dport 53
+`, brand.LowerName, brand.LowerName, brand.LowerName, brand.LowerName)
+}
@@ -5,14 +5,17 @@ package cmd
 import (
 	"os"
 
+	"grimm.is/flywall/internal/auth"
 	"grimm.is/flywall/internal/ctlplane"
 	"grimm.is/flywall/internal/tui"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-// RunConsole starts the TUI console
-func RunConsole(remote string, apiKey string, insecure bool, debug bool) {
+// RunConsole starts the TUI console. Remote sessions are limited to what
+// the API key allows. If role is set, the session is further limited to
+// that role's permissions (e.g. for a restricted SSH ForceCommand).
+func RunConsole(remote string, apiKey string, insecure bool, debug bool, role string) {
 	if debug {
 		if err := tui.EnableDebugLogging("tui.log"); err != nil {
 			Printer.Fprintf(os.Stderr, "Failed to enable debug logging: %v\n", err)
@@ -23,13 +26,23 @@ func RunConsole(remote string, apiKey string, insecure bool, debug bool) {
 	}
 
 	var backend tui.Backend
+	var grants []tui.Grant
 
 	if remote != "" {
 		if apiKey == "" {
 			Printer.Fprintf(os.Stderr, "Error: --api-key is required for remote connection\n")
 			os.Exit(1)
 		}
-		backend = tui.NewRemoteBackend(remote, apiKey, insecure)
+		rb := tui.NewRemoteBackend(remote, apiKey, insecure)
+		id, err := rb.WhoAmI()
+		if err != nil {
+			Printer.Fprintf(os.Stderr, "Failed to authenticate: %v\n", err)
+			os.Exit(1)
+		}
+		if grant := id.Grant(); grant != nil {
+			grants = append(grants, grant)
+		}
+		backend = rb
 	} else {
 		// Local mode - connect to Unix socket - deferred to nil check below
 	}
@@ -46,6 +59,41 @@ func RunConsole(remote string, apiKey string, insecure bool, debug bool) {
 		backend = tui.NewLocalBackend(client)
 	}
 
+	if role != "" {
+		grants = append(grants, auth.Role(role))
+	}
+
+	// Custom roles are defined in config
+	needRoles := false
+	for _, g := range grants {
+		if _, ok := g.(auth.Role); ok {
+			needRoles = true
+		}
+	}
+	if needRoles {
+		cfg, err := backend.GetConfig()
+		if err != nil {
+			Printer.Fprintf(os.Stderr, "Failed to load roles: %v\n", err)
+			os.Exit(1)
+		}
+		if cfg.API != nil {
+			if err := auth.LoadRoles(cfg.API.Roles); err != nil {
+				Printer.Fprintf(os.Stderr, "Invalid role configuration: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		for _, g := range grants {
+			if r, ok := g.(auth.Role); ok && !r.Valid() {
+				Printer.Fprintf(os.Stderr, "Error: unknown role %q\n", r)
+				os.Exit(1)
+			}
+		}
+	}
+
+	if len(grants) > 0 {
+		backend = tui.NewRestrictedBackend(backend, grants...)
+	}
+
 	p := tea.NewProgram(tui.NewModel(backend), tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		Printer.Fprintf(os.Stderr, "Error running console: %v\n", err)
@@ -27,6 +27,7 @@ import (
 	"grimm.is/flywall/internal/i18n"
 	"grimm.is/flywall/internal/logging"
 	"grimm.is/flywall/internal/monitor"
+	"grimm.is/flywall/internal/routing"
 	"grimm.is/flywall/internal/tls"
 )
 
@@ -197,6 +198,13 @@ func runCtlOnce(configFile string, testMode bool, stateDir, logDir, runDir, shar
 		os.Exit(1)
 	}
 
+	// Start dynamic routing (non-fatal: static routing still works)
+	if cfg.FRR != nil {
+		if err := routing.ConfigureFRR(cfg.FRR); err != nil {
+			logging.Error("Failed to configure FRR", "error", err)
+		}
+	}
+
 	// Initialize core services
 	services, err := initializeCoreServices(ctx, cfg, netMgr, stateStore)
 	if err != nil {
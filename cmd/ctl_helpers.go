@@ -39,12 +39,14 @@ import (
 	"grimm.is/flywall/internal/network"
 	"grimm.is/flywall/internal/notification"
 	"grimm.is/flywall/internal/qos"
+	"grimm.is/flywall/internal/quota"
 	"grimm.is/flywall/internal/runtime"
 	"grimm.is/flywall/internal/sentinel"
 	"grimm.is/flywall/internal/ssh"
 	"grimm.is/flywall/internal/supervisor"
 	"grimm.is/flywall/internal/vpn"
 
+	"grimm.is/flywall/internal/services/ddns"
 	"grimm.is/flywall/internal/services/dhcp"
 	"grimm.is/flywall/internal/services/discovery"
 	"grimm.is/flywall/internal/services/dns"
@@ -54,9 +56,11 @@ import (
 	// "grimm.is/flywall/internal/services/hostmanager"
 	"grimm.is/flywall/internal/services/lldp"
 	"grimm.is/flywall/internal/services/mdns"
+	"grimm.is/flywall/internal/services/nat64"
 	"grimm.is/flywall/internal/services/ntp"
+	"grimm.is/flywall/internal/services/portal"
+	"grimm.is/flywall/internal/services/ra"
 
-	// "grimm.is/flywall/internal/services/ra"
 	// "grimm.is/flywall/internal/services/threatintel"
 	// "grimm.is/flywall/internal/services/upnp"
 	"grimm.is/flywall/internal/state"
@@ -87,6 +91,8 @@ type ctlServices struct {
 	alertEngine        *alerting.Engine
 	mdnsSvc            *mdns.Reflector
 	ntpSvc             *ntp.Service
+	ddnsSvc            *ddns.Service
+	raSvc              *ra.Service
 	dhcpSniffer        *dhcp.Sniffer
 	metricsCollector   *metrics.Collector
 	runtimeSvc         *runtime.DockerClient
@@ -95,6 +101,10 @@ type ctlServices struct {
 	sshSvc             *ssh.Server
 	analyticsStore     *analytics.Store
 	analyticsCollector *analytics.Collector
+	quotaMgr           *quota.Manager
+	portalSvc          *portal.Service
+	nat64Svc           *nat64.Service
+	vpnMgr             *vpn.Manager
 	queryLogStore      *querylog.Store
 	ebpfMgr            *ebpf.Manager
 
@@ -378,7 +388,10 @@ func configureHA(cfg *config.Config, services *ctlServices, replicator *state.Re
 	}
 
 	// Get node ID (use hostname by default)
-	nodeID, _ := os.Hostname()
+	nodeID := cfg.Replication.HA.NodeID
+	if nodeID == "" {
+		nodeID, _ = os.Hostname()
+	}
 	if nodeID == "" {
 		nodeID = "flywall-node"
 	}
@@ -394,10 +407,9 @@ func configureHA(cfg *config.Config, services *ctlServices, replicator *state.Re
 	// Wire callbacks for role transitions
 	haSvc.OnBecomePrimary(func() error {
 		logging.Info("HA: Becoming primary - starting services")
-		// Restart DHCP and DNS services in primary mode
+		// DHCP failover reads the role per packet, so no restart is needed
 		if services.dhcpSvc != nil {
-			logging.Info("HA: Reloading DHCP service")
-			// DHCP service will be restarted with its current config
+			logging.Info("HA: DHCP service takes over the pool")
 		}
 		if services.dnsSvc != nil {
 			logging.Info("HA: DNS service continues")
@@ -423,6 +435,22 @@ func configureHA(cfg *config.Config, services *ctlServices, replicator *state.Re
 		return nil
 	})
 
+	// VRRP tracks uplink health to lower its priority
+	if services.uplinkManager != nil {
+		haSvc.SetUplinkMonitor(services.uplinkManager)
+	}
+
+	// Active/active assigns virtual IPs by the zone of their interface
+	resolver := config.NewZoneResolver(cfg.Zones)
+	haSvc.SetZoneLookup(func(iface string) string {
+		for _, ifc := range cfg.Interfaces {
+			if ifc.Name == iface && ifc.Zone != "" {
+				return ifc.Zone
+			}
+		}
+		return resolver.ResolveInterface(iface)
+	})
+
 	if err := haSvc.Start(); err != nil {
 		logging.Error(fmt.Sprintf("Failed to start HA service: %v", err))
 		linkMgr.Close()
@@ -430,6 +458,28 @@ func configureHA(cfg *config.Config, services *ctlServices, replicator *state.Re
 	}
 
 	services.haSvc = haSvc
+
+	// Share DHCP pools with the peer over the replication link
+	if services.dhcpSvc != nil && cfg.DHCP != nil && cfg.DHCP.Failover != nil {
+		var fwd dhcp.LeaseForwarder
+		if replicator != nil {
+			fwd = replicator
+		}
+		services.dhcpSvc.SetFailover(func() dhcp.FailoverState {
+			peer := haSvc.GetPeerState()
+			role := haSvc.GetRole()
+			return dhcp.FailoverState{
+				// Active/active nodes split the pool by replication mode
+				Primary:      role == ha.RolePrimary || (role == ha.RoleActive && cfg.Replication.Mode == "primary"),
+				PeerAlive:    peer.Alive,
+				PeerLastSeen: peer.LastSeen,
+			}
+		}, fwd)
+		if _, err := services.dhcpSvc.Reload(cfg); err != nil {
+			logging.Error(fmt.Sprintf("Failed to enable DHCP failover: %v", err))
+		}
+	}
+
 	logging.Info("HA service started",
 		"role", cfg.Replication.Mode,
 		"priority", cfg.Replication.HA.Priority,
@@ -678,6 +728,7 @@ func initializeCoreServices(ctx context.Context, cfg *config.Config, netMgr *net
 				logging.Error(fmt.Sprintf("Error starting VPNs: %v", err))
 			} else {
 				logging.Info("VPN Service started.")
+				services.vpnMgr = vpnMgr
 				services.addCleanup(vpnMgr.Stop)
 			}
 		}
@@ -820,6 +871,7 @@ func initializeCoreServices(ctx context.Context, cfg *config.Config, netMgr *net
 	} else {
 		logging.Info("QoS policies applied.")
 	}
+	services.metricsCollector.SetQoSStatsProvider(services.qosMgr)
 
 	return services, nil
 }
@@ -831,6 +883,11 @@ func initializeAdditionalServices(ctx context.Context, cfg *config.Config, servi
 	if cfg.Notifications != nil {
 		services.alertEngine.UpdateConfig(cfg.Notifications)
 	}
+	if services.stateStore != nil {
+		if err := services.alertEngine.SetStore(services.stateStore); err != nil {
+			logging.Warn(fmt.Sprintf("Failed to load alert state: %v", err))
+		}
+	}
 	services.alertEngine.Start(ctx)
 
 	// eBPF Manager (high-performance packet processing)
@@ -945,13 +1002,13 @@ func initializeAdditionalServices(ctx context.Context, cfg *config.Config, servi
 
 	// DDNS
 	if cfg.DDNS != nil && cfg.DDNS.Enabled {
-		// services.ddnsSvc = ddns.NewService(cfg.DDNS, services.netMgr.GetPublicIP, logging.WithComponent("ddns"))
-		// if err := services.ddnsSvc.Start(ctx); err != nil {
-		// 	logging.Error(fmt.Sprintf("Error starting DDNS service: %v", err))
-		// } else {
-		// 	logging.Info("DDNS Service started.")
-		// 	services.addCleanup(services.ddnsSvc.Stop)
-		// }
+		services.ddnsSvc = ddns.NewService(logging.WithComponent("ddns"))
+		services.ddnsSvc.Reload(ddns.ConfigFromGlobal(cfg.DDNS))
+		if err := services.ddnsSvc.Start(ctx); err != nil {
+			logging.Error(fmt.Sprintf("Error starting DDNS service: %v", err))
+		} else {
+			services.addCleanup(services.ddnsSvc.Stop)
+		}
 	}
 
 	// Threat Intelligence
@@ -970,6 +1027,11 @@ func initializeAdditionalServices(ctx context.Context, cfg *config.Config, servi
 	services.ntpSvc.Start(ctx)
 	services.addCleanup(func() { services.ntpSvc.Stop(context.Background()) })
 
+	// Router Advertisements
+	services.raSvc = ra.NewService(cfg)
+	services.raSvc.Start(ctx)
+	services.addCleanup(func() { services.raSvc.Stop(context.Background()) })
+
 	// Notification Dispatcher
 	if cfg.Notifications != nil {
 		services.dispatcher = notification.NewDispatcher(cfg.Notifications, logging.WithComponent("notification"))
@@ -1025,11 +1087,16 @@ func initializeDeviceServices(ctx context.Context, cfg *config.Config, services
 		services.ctlServer.SetAnalyticsCollector(services.analyticsCollector)
 		services.addCleanup(func() { services.analyticsStore.Close() })
 
-		// Start retention cleanup task (7 days default)
+		// Start retention cleanup task (7 days default, longer when
+		// quotas need a full month of history)
+		retention := 7 * 24 * time.Hour
+		if len(cfg.Quotas) > 0 {
+			retention = quota.MaxPeriod
+		}
 		go func() {
 			for {
 				time.Sleep(24 * time.Hour)
-				affected, err := services.analyticsStore.Cleanup(7 * 24 * time.Hour)
+				affected, err := services.analyticsStore.Cleanup(retention)
 				if err != nil {
 					logging.Error(fmt.Sprintf("Analytics cleanup failed: %v", err))
 				} else if affected > 0 {
@@ -1050,6 +1117,43 @@ func initializeDeviceServices(ctx context.Context, cfg *config.Config, services
 	services.ctlServer.SetIPSetService(ipsetService)
 	services.identitySvc.SetFirewallDependencies(services.fwMgr, ipsetService)
 
+	// Device Quotas (usage comes from flow analytics)
+	if services.analyticsStore != nil {
+		var alerts quota.Alerter
+		if services.alertEngine != nil {
+			alerts = services.alertEngine
+		}
+		services.quotaMgr = quota.NewManager(services.stateStore, services.analyticsStore, services.identitySvc, ipsetService.GetIPSetManager(), alerts)
+		services.quotaMgr.SetConnectionFlusher(fw.DeleteDeviceConntrack)
+		services.quotaMgr.UpdateConfig(cfg)
+		services.quotaMgr.Start(ctx)
+		services.ctlServer.SetQuotaManager(services.quotaMgr)
+	}
+
+	// Captive Portal (idle until a zone enables it)
+	services.portalSvc = portal.NewService(services.stateStore, ipsetService.GetIPSetManager())
+	if _, err := services.portalSvc.Reload(cfg); err != nil {
+		logging.Warn(fmt.Sprintf("Failed to configure captive portal: %v", err))
+	}
+	if err := services.portalSvc.Start(ctx); err != nil {
+		logging.Warn(fmt.Sprintf("Failed to start captive portal: %v", err))
+	}
+	services.ctlServer.SetPortalService(services.portalSvc)
+	portalSvc := services.portalSvc
+	services.addCleanup(func() { portalSvc.Stop(context.Background()) })
+
+	// NAT64 translator (idle until a zone enables it)
+	services.nat64Svc = nat64.NewService()
+	if _, err := services.nat64Svc.Reload(cfg); err != nil {
+		logging.Warn(fmt.Sprintf("Failed to configure NAT64: %v", err))
+	}
+	if err := services.nat64Svc.Start(ctx); err != nil {
+		logging.Warn(fmt.Sprintf("Failed to start NAT64: %v", err))
+	}
+	services.ctlServer.RegisterService(services.nat64Svc)
+	nat64Svc := services.nat64Svc
+	services.addCleanup(func() { nat64Svc.Stop(context.Background()) })
+
 	// LLDP Service
 	services.lldpSvc = lldp.NewService()
 	services.lldpSvc.Start()
@@ -1245,6 +1349,9 @@ func startControlPlaneServer(cfg *config.Config, configFile string, netMgr *netw
 	}
 	services.ctlServer.RegisterService(services.dnsSvc)
 	services.ctlServer.RegisterService(services.dhcpSvc)
+	if services.raSvc != nil {
+		services.ctlServer.RegisterService(services.raSvc)
+	}
 	services.ctlServer.SetDHCPService(services.dhcpSvc) // Explicitly set needed for GetDHCPLeases
 	services.ctlServer.SetSentinelService(services.sentinelSvc)
 
@@ -1258,10 +1365,14 @@ func startControlPlaneServer(cfg *config.Config, configFile string, netMgr *netw
 
 	services.ctlServer.SetUplinkManager(services.uplinkManager)
 	services.dhcpSvc.SetLeaseListener(services.ctlServer)
+	netMgr.SetPrefixListener(services.ctlServer.UpdateDelegatedPrefixes)
 	if services.haSvc != nil {
 		services.haSvc.SetReplicator(replicator)
 		services.ctlServer.SetHAService(services.haSvc)
 	}
+	if services.vpnMgr != nil {
+		services.ctlServer.SetVPNManager(services.vpnMgr)
+	}
 
 	// Start the server
 	if listeners != nil && listeners["ctl"] != nil {
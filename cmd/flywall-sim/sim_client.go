@@ -16,8 +16,13 @@ import (
 	"grimm.is/flywall/internal/learning"
 	"grimm.is/flywall/internal/learning/flowdb"
 	"grimm.is/flywall/internal/metrics"
+	"grimm.is/flywall/internal/quota"
+	"grimm.is/flywall/internal/routing"
 	"grimm.is/flywall/internal/services/dns/querylog"
+	"grimm.is/flywall/internal/services/portal"
 	"grimm.is/flywall/internal/services/scanner"
+	"grimm.is/flywall/internal/trace"
+	"grimm.is/flywall/internal/vpn"
 )
 
 // SimControlPlaneClient implements ctlplane.ControlPlaneClient for the simulator.
@@ -362,6 +367,42 @@ func (c *SimControlPlaneClient) UnlinkMAC(mac string) error
 func (c *SimControlPlaneClient) Ping(target string, timeoutSeconds int) (*ctlplane.PingReply, error) {
 	return nil, nil
 }
+func (c *SimControlPlaneClient) TracePacket(pkt trace.Packet) (*trace.Result, error) {
+	return nil, errors.New("packet trace not available in simulator")
+}
+func (c *SimControlPlaneClient) GetRoutingStatus() (*routing.Status, error) {
+	return nil, errors.New("dynamic routing not available in simulator")
+}
+func (c *SimControlPlaneClient) GetQuotaStatus() ([]quota.Status, error) { return nil, nil }
+func (c *SimControlPlaneClient) ResetQuota(quotaName, subject string) error {
+	return errors.New("device quotas not available in simulator")
+}
+func (c *SimControlPlaneClient) GetPortalSessions() ([]portal.Session, error) { return nil, nil }
+func (c *SimControlPlaneClient) RevokePortalSession(mac string) error {
+	return errors.New("captive portal not available in simulator")
+}
+func (c *SimControlPlaneClient) GetPortalVouchers() ([]portal.Voucher, error) { return nil, nil }
+func (c *SimControlPlaneClient) CreatePortalVouchers(count int, duration, note string) ([]portal.Voucher, error) {
+	return nil, errors.New("captive portal not available in simulator")
+}
+func (c *SimControlPlaneClient) DeletePortalVoucher(code string) error {
+	return errors.New("captive portal not available in simulator")
+}
+func (c *SimControlPlaneClient) GetConntrack(filter firewall.ConntrackFilter, limit, offset int) ([]firewall.ConntrackEntry, int, error) {
+	return nil, 0, nil
+}
+func (c *SimControlPlaneClient) KillConntrack(filter firewall.ConntrackFilter) (int, error) {
+	return 0, errors.New("conntrack not available in simulator")
+}
+func (c *SimControlPlaneClient) GetConntrackEvents(sinceID int64, filter firewall.ConntrackFilter) ([]ctlplane.ConntrackEventRecord, int64, error) {
+	return nil, sinceID, nil
+}
+func (c *SimControlPlaneClient) ProvisionWireGuardPeer(args *ctlplane.ProvisionWireGuardPeerArgs) (*vpn.ClientProfile, error) {
+	return nil, errors.New("WireGuard provisioning not available in simulator")
+}
+func (c *SimControlPlaneClient) RotateWireGuardPeer(args *ctlplane.RotateWireGuardPeerArgs) (*vpn.ClientProfile, error) {
+	return nil, errors.New("WireGuard provisioning not available in simulator")
+}
 func (c *SimControlPlaneClient) IsInSafeMode() (bool, error) { return false, nil }
 func (c *SimControlPlaneClient) EnterSafeMode() error        { return nil }
 func (c *SimControlPlaneClient) ExitSafeMode() error         { return nil }
@@ -384,6 +425,27 @@ func (c *SimControlPlaneClient) GetAlertHistory(limit int) ([]alerting.AlertEven
 func (c *SimControlPlaneClient) GetAlertRules() ([]alerting.AlertRule, error) {
 	return nil, nil
 }
+func (c *SimControlPlaneClient) GetAlertChannelStatus() ([]alerting.ChannelStatus, error) {
+	return nil, nil
+}
+func (c *SimControlPlaneClient) GetAlerts() ([]alerting.Alert, error) {
+	return nil, nil
+}
+func (c *SimControlPlaneClient) AckAlert(id, actor string) error {
+	return nil
+}
+func (c *SimControlPlaneClient) ResolveAlert(id, actor string) error {
+	return nil
+}
+func (c *SimControlPlaneClient) GetSilences() ([]alerting.Silence, error) {
+	return nil, nil
+}
+func (c *SimControlPlaneClient) CreateSilence(silence alerting.Silence) (*alerting.Silence, error) {
+	return &silence, nil
+}
+func (c *SimControlPlaneClient) DeleteSilence(id string) error {
+	return nil
+}
 
 func (c *SimControlPlaneClient) UpdateAlertRule(rule alerting.AlertRule) error {
 	return nil
@@ -0,0 +1,114 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"grimm.is/flywall/internal/ctlplane"
+	"grimm.is/flywall/internal/routing"
+)
+
+// RunShowRouting prints live BGP, OSPF and BFD state from FRR.
+func RunShowRouting(args []string) error {
+	fs := flag.NewFlagSet("show routing", flag.ContinueOnError)
+	jsonOutput := fs.Bool("json", false, "Output JSON")
+	showRoutes := fs.Bool("routes", false, "Also list the routes learned by FRR")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cli, err := ctlplane.NewClient()
+	if err != nil {
+		return fmt.Errorf("failed to connect to local control plane: %w", err)
+	}
+	defer cli.Close()
+
+	st, err := cli.GetRoutingStatus()
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		data, err := json.MarshalIndent(st, "", "  ")
+		if err != nil {
+			return err
+		}
+		Printer.Println(string(data))
+		return nil
+	}
+
+	printRoutingStatus(st, *showRoutes)
+	return nil
+}
+
+func printRoutingStatus(st *routing.Status, showRoutes bool) {
+	table := func(header string, rows func(w *tabwriter.Writer)) {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		Printer.Fprintln(w, header)
+		rows(w)
+		w.Flush()
+		Printer.Println()
+	}
+
+	if len(st.BGPPeers) == 0 {
+		Printer.Println("No BGP sessions")
+		Printer.Println()
+	} else {
+		table("BGP NEIGHBOR\tFAMILY\tREMOTE AS\tSTATE\tUP/DOWN\tPFX RCVD\tPFX SENT\tDESCRIPTION", func(w *tabwriter.Writer) {
+			for _, p := range st.BGPPeers {
+				Printer.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%d\t%d\t%s\n",
+					p.Address, p.Family, p.RemoteASN, p.State, p.Uptime, p.PrefixesReceived, p.PrefixesSent, p.Description)
+			}
+		})
+	}
+
+	if len(st.OSPFNeighbors) > 0 {
+		table("OSPF NEIGHBOR\tADDRESS\tINTERFACE\tSTATE\tPRIORITY", func(w *tabwriter.Writer) {
+			for _, n := range st.OSPFNeighbors {
+				Printer.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", n.RouterID, n.Address, n.Interface, n.State, n.Priority)
+			}
+		})
+	}
+
+	if len(st.BFDPeers) > 0 {
+		table("BFD PEER\tLOCAL\tINTERFACE\tSTATUS\tUPTIME\tDIAGNOSTIC", func(w *tabwriter.Writer) {
+			for _, p := range st.BFDPeers {
+				uptime := "-"
+				if p.Status == "up" {
+					uptime = (time.Duration(p.Uptime) * time.Second).String()
+				}
+				Printer.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", p.Peer, dashIfEmpty(p.Local), dashIfEmpty(p.Interface), p.Status, uptime, p.Diagnostic)
+			}
+		})
+	}
+
+	if showRoutes {
+		table("PREFIX\tPROTOCOL\tSELECTED\tDISTANCE/METRIC\tNEXTHOPS", func(w *tabwriter.Writer) {
+			for _, r := range st.Routes {
+				selected := ""
+				if r.Selected {
+					selected = "*"
+				}
+				Printer.Fprintf(w, "%s\t%s\t%s\t%d/%d\t%s\n", r.Prefix, r.Protocol, selected, r.Distance, r.Metric, strings.Join(r.Nexthops, ", "))
+			}
+		})
+	}
+
+	for _, e := range st.Errors {
+		Printer.Fprintf(os.Stderr, "warning: %s\n", e)
+	}
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,114 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"grimm.is/flywall/internal/brand"
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/ctlplane"
+	"grimm.is/flywall/internal/trace"
+)
+
+// RunTrace handles the "trace" command.
+func RunTrace(args []string) error {
+	if len(args) < 1 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printTraceUsage()
+		return nil
+	}
+
+	var pkt trace.Packet
+	var mark string
+	fs := flag.NewFlagSet("trace", flag.ContinueOnError)
+	fs.StringVar(&pkt.InInterface, "in", "", "Ingress interface (omit for locally generated traffic)")
+	fs.StringVar(&pkt.InInterface, "i", "", "Alias for -in")
+	fs.StringVar(&pkt.Protocol, "proto", "tcp", "Protocol: tcp, udp, icmp, icmpv6 or a number")
+	fs.StringVar(&pkt.Protocol, "p", "tcp", "Alias for -proto")
+	fs.StringVar(&pkt.Src, "src", "", "Source address")
+	fs.StringVar(&pkt.Dst, "dst", "", "Destination address")
+	fs.IntVar(&pkt.SrcPort, "sport", 0, "Source port")
+	fs.IntVar(&pkt.DstPort, "dport", 0, "Destination port")
+	fs.StringVar(&pkt.State, "state", "", "Conntrack state: new, established, related, invalid")
+	fs.StringVar(&mark, "mark", "", "Packet mark, decimal or 0x hex")
+	fs.StringVar(&pkt.SrcMAC, "mac", "", "Source MAC (for learning-engine lookups)")
+	fs.StringVar(&pkt.ICMPType, "icmp-type", "", "ICMP type (default echo-request)")
+	configFile := fs.String("config", "", "Trace against this candidate config instead of the running ruleset")
+	fs.StringVar(configFile, "c", "", "Alias for -config")
+	jsonOutput := fs.Bool("json", false, "Output JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if mark != "" {
+		m, err := strconv.ParseUint(mark, 0, 32)
+		if err != nil {
+			return fmt.Errorf("invalid mark %q", mark)
+		}
+		pkt.Mark = uint32(m)
+	}
+
+	var res *trace.Result
+	if *configFile != "" {
+		result, err := config.LoadFileWithOptions(*configFile, config.DefaultLoadOptions())
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		tracer, err := trace.NewOfflineTracer(result.Config)
+		if err != nil {
+			return err
+		}
+		if res, err = tracer.Trace(pkt); err != nil {
+			return err
+		}
+	} else {
+		cli, err := ctlplane.NewClient()
+		if err != nil {
+			return fmt.Errorf("failed to connect to local control plane: %w", err)
+		}
+		defer cli.Close()
+		if res, err = cli.TracePacket(pkt); err != nil {
+			return err
+		}
+	}
+
+	if *jsonOutput {
+		data, err := json.MarshalIndent(res, "", "  ")
+		if err != nil {
+			return err
+		}
+		Printer.Println(string(data))
+		return nil
+	}
+	Printer.Printf("%s", res.Format())
+	return nil
+}
+
+func printTraceUsage() {
+	Printer.Printf(`Usage: %s trace [options]
+
+Walks a packet through the compiled nftables ruleset (NAT, marks, policy
+routing, zones, sets and learning-engine verdicts) and prints every chain
+and rule it hits, in the style of "nft monitor trace".
+
+Options:
+  --in (-i) <iface>     Ingress interface (omit for locally generated traffic)
+  --proto (-p) <proto>  tcp (default), udp, icmp, icmpv6 or a protocol number
+  --src <addr>          Source address (required)
+  --dst <addr>          Destination address (required)
+  --sport <port>        Source port
+  --dport <port>        Destination port (required for tcp/udp)
+  --state <state>       Conntrack state: new (default), established, related, invalid
+  --mark <mark>         Initial packet mark
+  --mac <mac>           Source MAC (looked up in the neighbour table if omitted)
+  --icmp-type <type>    ICMP type (default echo-request)
+  --config (-c) <file>  Trace against a candidate config instead of the running ruleset
+  --json                Output JSON
+
+Examples:
+  %s trace -i eth0 --src 198.51.100.7 --dst 203.0.113.1 --dport 80
+  %s trace -c candidate.hcl -i eth1 -p udp --src 10.0.0.50 --dst 1.1.1.1 --dport 53
+`, brand.LowerName, brand.LowerName, brand.LowerName)
+}
@@ -0,0 +1,135 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"grimm.is/flywall/internal/brand"
+	"grimm.is/flywall/internal/ctlplane"
+	"grimm.is/flywall/internal/vpn"
+)
+
+// RunWireGuard handles the "wireguard" command.
+func RunWireGuard(args []string) error {
+	if len(args) < 1 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printWireGuardUsage()
+		return nil
+	}
+
+	fs := flag.NewFlagSet("wireguard "+args[0], flag.ContinueOnError)
+	connection := fs.String("connection", "", "WireGuard connection name or interface")
+	fs.StringVar(connection, "c", "", "Alias for -connection")
+	expires := fs.String("expires", "", "Disable the peer after this date (YYYY-MM-DD or RFC 3339)")
+	output := fs.String("output", "", "Also write the client config to this file")
+	fs.StringVar(output, "o", "", "Alias for -output")
+	noApply := fs.Bool("no-apply", false, "Only stage the change")
+	noQR := fs.Bool("no-qr", false, "Don't print a QR code")
+
+	// Accept the peer name before or after the options
+	rest := args[1:]
+	var name string
+	if len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+		name, rest = rest[0], rest[1:]
+	}
+	if err := fs.Parse(rest); err != nil {
+		return err
+	}
+	if name == "" {
+		name = fs.Arg(0)
+	}
+	if name == "" {
+		return fmt.Errorf("usage: %s wireguard %s <name> [options]", brand.LowerName, args[0])
+	}
+
+	cli, err := ctlplane.NewClient()
+	if err != nil {
+		return fmt.Errorf("failed to connect to local control plane: %w", err)
+	}
+	defer cli.Close()
+
+	var profile *vpn.ClientProfile
+	switch args[0] {
+	case "add", "provision":
+		profile, err = cli.ProvisionWireGuardPeer(&ctlplane.ProvisionWireGuardPeerArgs{
+			Connection: *connection,
+			Name:       name,
+			Expires:    *expires,
+			Apply:      !*noApply,
+		})
+	case "rotate":
+		profile, err = cli.RotateWireGuardPeer(&ctlplane.RotateWireGuardPeerArgs{
+			Connection: *connection,
+			Name:       name,
+			Apply:      !*noApply,
+		})
+	default:
+		printWireGuardUsage()
+		return fmt.Errorf("unknown wireguard command: %s", args[0])
+	}
+	if profile == nil {
+		return err
+	}
+	// An apply error still comes with the staged peer's config, which can't
+	// be recovered later, so show it first
+	if perr := printClientProfile(profile, *output, !*noQR); perr != nil {
+		return perr
+	}
+	if err != nil {
+		return err
+	}
+
+	if *noApply {
+		Printer.Println("Peer staged; apply the configuration to activate it.")
+	}
+	return nil
+}
+
+// printClientProfile shows a client config and, for phones, its QR code.
+func printClientProfile(profile *vpn.ClientProfile, output string, showQR bool) error {
+	Printer.Printf("Peer %s on %s: %s\n", profile.Name, profile.Connection, strings.Join(profile.Addresses, ", "))
+	Printer.Printf("Public key: %s\n\n", profile.PublicKey)
+	Printer.Print(profile.ClientConfig)
+
+	if output != "" {
+		// Contains the client's private key
+		if err := os.WriteFile(output, []byte(profile.ClientConfig), 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", output, err)
+		}
+		Printer.Printf("\nWrote %s\n", output)
+	}
+
+	if showQR {
+		qr, err := vpn.QRCodeText(profile.ClientConfig)
+		if err != nil {
+			return err
+		}
+		Printer.Printf("\nScan with the WireGuard app:\n\n%s", qr)
+	}
+	Printer.Println("\nThe private key is not stored; keep this config safe.")
+	return nil
+}
+
+func printWireGuardUsage() {
+	Printer.Printf(`Usage: %s wireguard <command> <name> [options]
+
+Commands:
+  add <name>      Provision a road-warrior peer: allocate a tunnel address,
+                  generate keys and print the client config and QR code
+  rotate <name>   Replace a peer's keys and print its new client config
+
+Options:
+  --connection (-c) <name>   WireGuard connection (required if several)
+  --expires <date>           Disable the peer after this date (add only)
+  --output (-o) <file>       Also write the client config to a file
+  --no-apply                 Only stage the change
+  --no-qr                    Don't print the QR code
+
+Examples:
+  %s wireguard add alice-phone --expires 2026-12-31
+  %s wireguard rotate alice-phone -o alice-phone.conf
+`, brand.LowerName, brand.LowerName, brand.LowerName)
+}
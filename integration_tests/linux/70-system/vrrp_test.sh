@@ -0,0 +1,196 @@
+#!/bin/sh
+# VRRP Test
+# Runs two nodes with protocol = "vrrp" on a shared LAN and verifies the
+# election, tracked interface failover, preemption and graceful handover.
+
+set -x
+TEST_TIMEOUT=90
+. "$(dirname "$0")/../common.sh"
+require_linux
+export FLYWALL_LOG_FILE=stdout
+export FLYWALL_NO_SANDBOX=1
+
+plan 7
+
+diag "Starting VRRP Test..."
+
+# Use /tmp to stay within the Unix socket path limit (108 chars)
+BASE_DIR="/tmp/flywall_vrrp_$$"
+PRIM_DIR="$BASE_DIR/p"
+BACK_DIR="$BASE_DIR/b"
+rm -rf $BASE_DIR
+mkdir -p $PRIM_DIR/state $PRIM_DIR/run
+mkdir -p $BACK_DIR/state $BACK_DIR/run
+
+P_NS="ns_p_vrrp"
+B_NS="ns_b_vrrp"
+ip netns add $P_NS
+ip netns add $B_NS
+ip netns exec $P_NS ip link set lo up
+ip netns exec $B_NS ip link set lo up
+
+cleanup() {
+    P_PID=$(cat $PRIM_DIR/pid 2>/dev/null)
+    B_PID=$(cat $BACK_DIR/pid 2>/dev/null)
+    pkill -P $$ 2>/dev/null
+    [ -n "$P_PID" ] && kill $P_PID 2>/dev/null
+    [ -n "$B_PID" ] && kill $B_PID 2>/dev/null
+    for i in $(seq 1 50); do
+        if ! kill -0 $P_PID 2>/dev/null && ! kill -0 $B_PID 2>/dev/null; then
+            break
+        fi
+        sleep 0.1
+    done
+    if [ -n "$P_PID" ]; then kill -9 $P_PID 2>/dev/null; fi
+    if [ -n "$B_PID" ]; then kill -9 $B_PID 2>/dev/null; fi
+
+    ip netns del $P_NS 2>/dev/null
+    ip netns del $B_NS 2>/dev/null
+    ip link del br-vrrp 2>/dev/null
+    ip link del v-tk-r 2>/dev/null
+    rm -rf $BASE_DIR
+}
+trap cleanup EXIT
+
+# LAN shared by both nodes, where the VIP lives
+ip link add br-vrrp type bridge
+ip link set br-vrrp up
+
+ETH_P="v-vr-p"
+ETH_B="v-vr-b"
+ip link add $ETH_P type veth peer name v-vb-p
+ip link add $ETH_B type veth peer name v-vb-b
+ip link set $ETH_P netns $P_NS
+ip link set $ETH_B netns $B_NS
+ip link set v-vb-p master br-vrrp
+ip link set v-vb-b master br-vrrp
+ip link set v-vb-p up
+ip link set v-vb-b up
+
+ip netns exec $P_NS ip addr add 10.0.5.10/24 dev $ETH_P
+ip netns exec $P_NS ip link set $ETH_P up
+ip netns exec $B_NS ip addr add 10.0.5.20/24 dev $ETH_B
+ip netns exec $B_NS ip link set $ETH_B up
+
+# Link tracked by the primary (stands in for its WAN)
+TRK_P="v-tk-p"
+ip link add $TRK_P type veth peer name v-tk-r
+ip link set $TRK_P netns $P_NS
+ip link set v-tk-r up
+ip netns exec $P_NS ip link set $TRK_P up
+
+ip netns exec $P_NS ping -c 1 10.0.5.20 >/dev/null 2>&1
+ok $? "LAN link up"
+
+VIP="10.0.5.1"
+
+# write_config NAME IFACE ADDR PRIORITY TRACK_BLOCK STATE
+write_config() {
+    cat > $STATE_DIR/vrrp_$1_$$.hcl <<EOF
+schema_version = "1.0"
+interface "lo" {
+    ipv4 = ["127.0.0.1/8"]
+}
+interface "$2" {
+    ipv4 = ["$3/24"]
+    zone = "lan"
+}
+zone "lan" {}
+api { enabled = false }
+
+replication {
+    mode = "primary"
+    listen_addr = "$3:9001"
+
+    ha {
+        enabled  = true
+        protocol = "vrrp"
+        virtual_ip {
+            address         = "${VIP}/24"
+            interface       = "$2"
+            vrid            = 51
+            priority        = $4
+            advert_interval = "200ms"
+            preempt_delay   = "2s"
+            $5
+        }
+    }
+}
+state_dir = "$6"
+EOF
+}
+
+write_config primary $ETH_P 10.0.5.10 200 "track {
+                interface = \"$TRK_P\"
+                weight    = 150
+            }" $PRIM_DIR/state
+write_config backup $ETH_B 10.0.5.20 100 "" $BACK_DIR/state
+
+# start_node NS DIR NAME
+start_node() {
+    rm -f "$2/run/flywall.pid"
+    ip netns exec $1 sh -c "FLYWALL_RUN_DIR=$2/run \
+        FLYWALL_CTL_SOCKET=$2/run/ctl.sock \
+        FLYWALL_NO_SANDBOX=1 \
+        FLYWALL_LOG_LEVEL=debug FLYWALL_LOG_FILE=$2/log \
+        $CTL_BIN ctl --state-dir $2/state $STATE_DIR/vrrp_$3_$$.hcl &"
+    for i in $(seq 1 20); do
+        if [ -f "$2/run/flywall.pid" ]; then
+            NODE_PID=$(cat "$2/run/flywall.pid")
+            [ -n "$NODE_PID" ] && break
+        fi
+        dilated_sleep 0.5
+    done
+    echo "${NODE_PID:-}" > $2/pid
+}
+
+# wait_owner p|b: waits until only that node holds the VIP
+wait_owner() {
+    for i in $(seq 1 40); do
+        P_HAS=0
+        B_HAS=0
+        ip netns exec $P_NS ip addr show $ETH_P | grep -q "$VIP/" && P_HAS=1
+        ip netns exec $B_NS ip addr show $ETH_B | grep -q "$VIP/" && B_HAS=1
+        if [ "$1" = "p" ] && [ $P_HAS -eq 1 ] && [ $B_HAS -eq 0 ]; then
+            return 0
+        fi
+        if [ "$1" = "b" ] && [ $B_HAS -eq 1 ] && [ $P_HAS -eq 0 ]; then
+            return 0
+        fi
+        dilated_sleep 0.5
+    done
+    diag "VIP owner mismatch: primary=$P_HAS backup=$B_HAS"
+    echo "### PRIMARY LOGS ###"
+    cat $PRIM_DIR/log
+    echo "### BACKUP LOGS ###"
+    cat $BACK_DIR/log
+    return 1
+}
+
+diag "Starting nodes..."
+start_node $B_NS $BACK_DIR backup
+start_node $P_NS $PRIM_DIR primary
+
+kill -0 $(cat $PRIM_DIR/pid) 2>/dev/null && kill -0 $(cat $BACK_DIR/pid) 2>/dev/null
+ok $? "Both nodes running"
+
+wait_owner p
+ok $? "Higher priority node is master"
+
+diag "Taking the tracked link down..."
+ip netns exec $P_NS ip link set $TRK_P down
+wait_owner b
+ok $? "Backup takes over when the tracked link fails"
+
+diag "Restoring the tracked link..."
+ip netns exec $P_NS ip link set $TRK_P up
+wait_owner p
+ok $? "Primary preempts after preempt_delay"
+
+diag "Stopping the primary..."
+kill $(cat $PRIM_DIR/pid)
+wait_owner b
+ok $? "Backup takes over on graceful shutdown"
+
+grep -q "VRRP state changed" $BACK_DIR/log
+ok $? "Backup logged VRRP transitions"
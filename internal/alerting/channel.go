@@ -0,0 +1,138 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package alerting
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/errors"
+)
+
+// Sender delivers an alert over one type of notification channel.
+type Sender interface {
+	Send(ctx context.Context, ch config.NotificationChannel, event AlertEvent) error
+}
+
+// SenderFunc adapts a function to the Sender interface.
+type SenderFunc func(ctx context.Context, ch config.NotificationChannel, event AlertEvent) error
+
+func (f SenderFunc) Send(ctx context.Context, ch config.NotificationChannel, event AlertEvent) error {
+	return f(ctx, ch, event)
+}
+
+// ChannelStatus tracks delivery results for a notification channel.
+type ChannelStatus struct {
+	Name                string    `json:"name"`
+	Type                string    `json:"type"`
+	Enabled             bool      `json:"enabled"`
+	Successes           int       `json:"successes"`
+	Failures            int       `json:"failures"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	LastAttempt         time.Time `json:"last_attempt"`
+	LastSuccess         time.Time `json:"last_success"`
+	LastError           string    `json:"last_error,omitempty"`
+}
+
+const (
+	defaultRetries  = 3
+	maxRetryBackoff = time.Minute
+)
+
+// RegisterSender adds or replaces the sender for a channel type.
+func (e *Engine) RegisterSender(typ string, s Sender) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.senders[typ] = s
+}
+
+// deliver sends an event to one channel, retrying with exponential backoff,
+// and records the outcome.
+func (e *Engine) deliver(ctx context.Context, ch config.NotificationChannel, event AlertEvent) {
+	e.mu.RLock()
+	sender, ok := e.senders[ch.Type]
+	backoff := e.retryBackoff
+	e.mu.RUnlock()
+
+	if !ok {
+		err := errors.Errorf(errors.KindValidation, "unsupported channel type %q", ch.Type)
+		log.Printf("[ALERT] %s: %v", ch.Name, err)
+		e.recordDelivery(ch, err)
+		return
+	}
+
+	retries := ch.Retries
+	if retries == 0 {
+		retries = defaultRetries
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = sender.Send(ctx, ch, event); err == nil {
+			break
+		}
+		if attempt >= retries {
+			break
+		}
+		log.Printf("[ALERT] Delivery to %s failed (attempt %d), retrying in %s: %v", ch.Name, attempt+1, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+
+	if err != nil {
+		log.Printf("[ALERT] Delivery to %s failed: %v", ch.Name, err)
+	}
+	e.recordDelivery(ch, err)
+}
+
+func (e *Engine) recordDelivery(ch config.NotificationChannel, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	st, ok := e.status[ch.Name]
+	if !ok {
+		st = &ChannelStatus{Name: ch.Name}
+		e.status[ch.Name] = st
+	}
+	st.Type = ch.Type
+	st.LastAttempt = time.Now()
+	if err != nil {
+		st.Failures++
+		st.ConsecutiveFailures++
+		st.LastError = err.Error()
+		return
+	}
+	st.Successes++
+	st.ConsecutiveFailures = 0
+	st.LastSuccess = st.LastAttempt
+	st.LastError = ""
+}
+
+// GetChannelStatus returns the delivery status of every configured channel.
+func (e *Engine) GetChannelStatus() []ChannelStatus {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	res := make([]ChannelStatus, 0, len(e.channels))
+	for name, ch := range e.channels {
+		st := ChannelStatus{Name: name}
+		if s, ok := e.status[name]; ok {
+			st = *s
+		}
+		st.Type = ch.Type
+		st.Enabled = ch.Enabled
+		res = append(res, st)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
@@ -0,0 +1,248 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"grimm.is/flywall/internal/brand"
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/errors"
+)
+
+func testEvent() AlertEvent {
+	return AlertEvent{
+		RuleName:  "wan-down",
+		Message:   "WAN link lost",
+		Severity:  LevelCritical,
+		Timestamp: time.Unix(1700000000, 0),
+	}
+}
+
+func newTestEngine(channels ...config.NotificationChannel) *Engine {
+	e := NewEngine()
+	e.retryBackoff = time.Millisecond
+	e.UpdateConfig(&config.NotificationsConfig{Enabled: true, Channels: channels})
+	return e
+}
+
+func TestDeliver_RetriesWithBackoff(t *testing.T) {
+	ch := config.NotificationChannel{Name: "flaky", Type: "flaky", Enabled: true, Retries: 2}
+	e := newTestEngine(ch)
+
+	calls := 0
+	e.RegisterSender("flaky", SenderFunc(func(ctx context.Context, ch config.NotificationChannel, event AlertEvent) error {
+		calls++
+		if calls < 3 {
+			return errors.New(errors.KindUnavailable, "temporary failure")
+		}
+		return nil
+	}))
+
+	e.deliver(context.Background(), ch, testEvent())
+
+	assert.Equal(t, 3, calls)
+	status := e.GetChannelStatus()
+	require.Len(t, status, 1)
+	assert.Equal(t, 1, status[0].Successes)
+	assert.Equal(t, 0, status[0].ConsecutiveFailures)
+	assert.Empty(t, status[0].LastError)
+}
+
+func TestDeliver_RecordsFailure(t *testing.T) {
+	ch := config.NotificationChannel{Name: "broken", Type: "broken", Enabled: true, Retries: -1}
+	e := newTestEngine(ch, config.NotificationChannel{Name: "idle", Type: "webhook"})
+
+	calls := 0
+	e.RegisterSender("broken", SenderFunc(func(ctx context.Context, ch config.NotificationChannel, event AlertEvent) error {
+		calls++
+		return errors.New(errors.KindUnavailable, "connection refused")
+	}))
+
+	e.deliver(context.Background(), ch, testEvent())
+	e.deliver(context.Background(), ch, testEvent())
+
+	assert.Equal(t, 2, calls, "negative retries should disable retrying")
+	status := e.GetChannelStatus()
+	require.Len(t, status, 2)
+	assert.Equal(t, "broken", status[0].Name)
+	assert.Equal(t, 2, status[0].Failures)
+	assert.Equal(t, 2, status[0].ConsecutiveFailures)
+	assert.Equal(t, "connection refused", status[0].LastError)
+	assert.True(t, status[0].LastSuccess.IsZero())
+
+	// Configured channels are listed even before their first delivery
+	assert.Equal(t, "idle", status[1].Name)
+	assert.True(t, status[1].LastAttempt.IsZero())
+}
+
+func TestDeliver_UnsupportedType(t *testing.T) {
+	ch := config.NotificationChannel{Name: "pager", Type: "carrier-pigeon", Enabled: true}
+	e := newTestEngine(ch)
+
+	e.deliver(context.Background(), ch, testEvent())
+
+	status := e.GetChannelStatus()
+	require.Len(t, status, 1)
+	assert.Equal(t, 1, status[0].Failures)
+	assert.Contains(t, status[0].LastError, "unsupported channel type")
+}
+
+// captured holds the last request seen by captureServer.
+type captured struct {
+	method, path string
+	header       http.Header
+	body         map[string]interface{}
+	form         map[string][]string
+}
+
+func captureServer(t *testing.T) (*httptest.Server, *captured) {
+	c := &captured{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.method, c.path, c.header = r.Method, r.URL.Path, r.Header
+		if r.Header.Get("Content-Type") == "application/json" {
+			json.NewDecoder(r.Body).Decode(&c.body)
+		} else {
+			r.ParseForm()
+			c.form = r.PostForm
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, c
+}
+
+func TestSenders(t *testing.T) {
+	e := NewEngine()
+	event := testEvent()
+
+	t.Run("pushover", func(t *testing.T) {
+		srv, c := captureServer(t)
+		ch := config.NotificationChannel{Type: "pushover", Server: srv.URL, APIToken: "app", UserKey: "user", Priority: 2, Sound: "siren"}
+		require.NoError(t, e.sendPushover(context.Background(), ch, event))
+		assert.Equal(t, []string{"app"}, c.form["token"])
+		assert.Equal(t, []string{"user"}, c.form["user"])
+		assert.Equal(t, []string{"2"}, c.form["priority"])
+		assert.NotEmpty(t, c.form["expire"], "emergency priority needs retry/expire")
+		assert.Equal(t, []string{"siren"}, c.form["sound"])
+	})
+
+	t.Run("telegram", func(t *testing.T) {
+		srv, c := captureServer(t)
+		ch := config.NotificationChannel{Type: "telegram", Server: srv.URL, APIToken: "123:abc", ChatID: "-10042"}
+		require.NoError(t, e.sendTelegram(context.Background(), ch, event))
+		assert.Equal(t, "/bot123:abc/sendMessage", c.path)
+		assert.Equal(t, "-10042", c.body["chat_id"])
+		assert.Equal(t, "[CRITICAL] wan-down: WAN link lost", c.body["text"])
+	})
+
+	t.Run("matrix", func(t *testing.T) {
+		srv, c := captureServer(t)
+		ch := config.NotificationChannel{Type: "matrix", Server: srv.URL, APIToken: "tok", Room: "!room:example.org"}
+		require.NoError(t, e.sendMatrix(context.Background(), ch, event))
+		assert.Equal(t, http.MethodPut, c.method)
+		assert.Contains(t, c.path, "/_matrix/client/v3/rooms/!room:example.org/send/m.room.message/")
+		assert.Equal(t, "Bearer tok", c.header.Get("Authorization"))
+		assert.Equal(t, "m.text", c.body["msgtype"])
+	})
+
+	t.Run("gotify", func(t *testing.T) {
+		srv, c := captureServer(t)
+		ch := config.NotificationChannel{Type: "gotify", Server: srv.URL, APIToken: "apptoken"}
+		require.NoError(t, e.sendGotify(context.Background(), ch, event))
+		assert.Equal(t, "/message", c.path)
+		assert.Equal(t, "apptoken", c.header.Get("X-Gotify-Key"))
+		assert.Equal(t, float64(8), c.body["priority"])
+	})
+
+	t.Run("http error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "bad token", http.StatusUnauthorized)
+		}))
+		defer srv.Close()
+		ch := config.NotificationChannel{Type: "gotify", Server: srv.URL, APIToken: "wrong"}
+		err := e.sendGotify(context.Background(), ch, event)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "401")
+	})
+
+	t.Run("token not leaked", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		srv.Close()
+		ch := config.NotificationChannel{Type: "telegram", Server: srv.URL, APIToken: "123:secret", ChatID: "1"}
+		err := e.sendTelegram(context.Background(), ch, event)
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "secret")
+	})
+
+	t.Run("missing settings", func(t *testing.T) {
+		assert.Error(t, e.sendTelegram(context.Background(), config.NotificationChannel{Type: "telegram"}, event))
+		assert.Error(t, e.sendMatrix(context.Background(), config.NotificationChannel{Type: "matrix"}, event))
+		assert.Error(t, sendExec(context.Background(), config.NotificationChannel{Type: "exec"}, event))
+	})
+}
+
+func TestSendExec(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(brand.ConfigEnvPrefix+"_CONFIG_DIR", dir)
+	hooks := filepath.Join(dir, "hooks.d")
+	require.NoError(t, os.Mkdir(hooks, 0o755))
+	hook := func(name, script string) string {
+		path := filepath.Join(hooks, name)
+		require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755))
+		return path
+	}
+
+	out := filepath.Join(dir, "alert.json")
+	ch := config.NotificationChannel{
+		Name:    "hook",
+		Type:    "exec",
+		Command: []string{hook("save.sh", `cat > "$1"; test "$ALERT_SEVERITY" = critical`), out},
+	}
+
+	require.NoError(t, sendExec(context.Background(), ch, testEvent()))
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	var got AlertEvent
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, "WAN link lost", got.Message)
+
+	ch.Command = []string{hook("fail.sh", "echo nope >&2; exit 3")}
+	err = sendExec(context.Background(), ch, testEvent())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nope")
+
+	for _, cmd := range []string{"sh", "/bin/sh", hooks + "/../alert.json"} {
+		ch.Command = []string{cmd, "-c", "true"}
+		assert.Error(t, sendExec(context.Background(), ch, testEvent()), cmd)
+	}
+}
+
+func TestSendSyslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ch := config.NotificationChannel{Type: "syslog", Address: "udp://" + conn.LocalAddr().String(), Facility: "local0"}
+	require.NoError(t, sendSyslog(context.Background(), ch, testEvent()))
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	// local0 (16) * 8 + crit (2)
+	assert.Regexp(t, `^<130>.* WAN link lost$`, string(buf[:n]))
+
+	ch.Facility = "bogus"
+	assert.Error(t, sendSyslog(context.Background(), ch, testEvent()))
+}
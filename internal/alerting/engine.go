@@ -3,18 +3,14 @@
 package alerting
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
-	"net/smtp"
-	"strings"
 	"sync"
 	"time"
 
 	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/state"
 )
 
 // Engine manages alert rules and handles incoming events.
@@ -27,11 +23,27 @@ type Engine struct {
 	eventChan  chan AlertEvent
 	stopChan   chan struct{}
 	httpClient *http.Client
+
+	// Delivery
+	ctx          context.Context
+	senders      map[string]Sender
+	status       map[string]*ChannelStatus
+	retryBackoff time.Duration
+
+	// Grouping, silences and escalation
+	groupBy        []string
+	groupWait      time.Duration
+	groupInterval  time.Duration
+	resolveTimeout time.Duration
+	alerts         map[string]*Alert
+	silences       map[string]*Silence
+	store          state.Store
+	tickInterval   time.Duration
 }
 
 // NewEngine creates a new Alerting Engine.
 func NewEngine() *Engine {
-	return &Engine{
+	e := &Engine{
 		rules:      make(map[string]*AlertRule),
 		channels:   make(map[string]config.NotificationChannel),
 		history:    make([]AlertEvent, 0),
@@ -41,7 +53,17 @@ func NewEngine() *Engine {
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-	}
+		ctx:            context.Background(),
+		status:         make(map[string]*ChannelStatus),
+		retryBackoff:   2 * time.Second,
+		groupInterval:  defaultGroupInterval,
+		resolveTimeout: defaultResolveTimeout,
+		alerts:         make(map[string]*Alert),
+		silences:       make(map[string]*Silence),
+		tickInterval:   5 * time.Second,
+	}
+	e.senders = e.defaultSenders()
+	return e
 }
 
 // UpdateConfig updates the engine's rules and channels from the configuration.
@@ -61,24 +83,27 @@ func (e *Engine) UpdateConfig(cfg *config.NotificationsConfig) {
 		e.channels[ch.Name] = ch
 	}
 
+	e.groupBy = cfg.GroupBy
+	e.groupWait = parseDuration(cfg.GroupWait, 0)
+	e.groupInterval = parseDuration(cfg.GroupInterval, defaultGroupInterval)
+	e.resolveTimeout = parseDuration(cfg.ResolveTimeout, defaultResolveTimeout)
+
 	// Update rules, preserving runtime state (LastFired)
 	newRules := make(map[string]*AlertRule)
 	for _, r := range cfg.Rules {
 		existing, ok := e.rules[r.Name]
 
-		cooldown, _ := time.ParseDuration(r.Cooldown)
-		if cooldown == 0 {
-			cooldown = 15 * time.Minute // Default cooldown
-		}
-
 		rule := &AlertRule{
-			ID:        r.Name,
-			Name:      r.Name,
-			Enabled:   r.Enabled,
-			Severity:  AlertLevel(r.Severity),
-			Condition: r.Condition,
-			Channels:  r.Channels,
-			Cooldown:  cooldown,
+			ID:               r.Name,
+			Name:             r.Name,
+			Enabled:          r.Enabled,
+			Severity:         AlertLevel(r.Severity),
+			Condition:        r.Condition,
+			Channels:         r.Channels,
+			Cooldown:         parseDuration(r.Cooldown, e.groupInterval),
+			GroupBy:          r.GroupBy,
+			EscalateAfter:    parseDuration(r.EscalateAfter, 0),
+			EscalateChannels: r.EscalateChannels,
 		}
 
 		if ok {
@@ -89,16 +114,31 @@ func (e *Engine) UpdateConfig(cfg *config.NotificationsConfig) {
 	e.rules = newRules
 }
 
+func parseDuration(s string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+
 // Start starts the engine's background processing.
 func (e *Engine) Start(ctx context.Context) {
+	e.mu.Lock()
+	e.ctx = ctx
+	e.mu.Unlock()
 	go e.run(ctx)
 }
 
 func (e *Engine) run(ctx context.Context) {
+	ticker := time.NewTicker(e.tickInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case event := <-e.eventChan:
 			e.handleEvent(event)
+		case now := <-ticker.C:
+			e.tick(now)
 		case <-e.stopChan:
 			return
 		case <-ctx.Done():
@@ -112,9 +152,6 @@ func (e *Engine) handleEvent(event AlertEvent) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	// Logic to match event to rules or generic handling
-	// For now, let's assume events are pre-matched or we just log them/add to history
-
 	e.history = append(e.history, event)
 	if len(e.history) > e.maxHistory {
 		e.history = e.history[1:]
@@ -122,110 +159,23 @@ func (e *Engine) handleEvent(event AlertEvent) {
 
 	log.Printf("[ALERT] %s: %s (%s)", event.Severity, event.Message, event.RuleName)
 
-	// Send to channels if associated with a rule
+	// Group and notify if associated with a rule
 	if event.RuleID != "" {
 		if rule, ok := e.rules[event.RuleID]; ok && rule.Enabled {
-			if time.Since(rule.LastFired) > rule.Cooldown {
-				rule.LastFired = time.Now()
-				e.notify(rule, event)
-			}
+			e.groupEventLocked(rule, event, time.Now())
 		}
 	}
 }
 
-// notify sends the alert to configured channels.
-func (e *Engine) notify(rule *AlertRule, event AlertEvent) {
-	for _, chName := range rule.Channels {
+// notify sends the alert to the given channels.
+func (e *Engine) notify(channels []string, event AlertEvent) {
+	for _, chName := range channels {
 		if ch, ok := e.channels[chName]; ok && ch.Enabled {
-			go e.sendToChannel(ch, event)
+			go e.deliver(e.ctx, ch, event)
 		}
 	}
 }
 
-func (e *Engine) sendToChannel(ch config.NotificationChannel, event AlertEvent) {
-	switch ch.Type {
-	case "webhook", "slack", "discord", "ntfy":
-		e.sendWebhook(ch, event)
-	case "email":
-		e.sendEmail(ch, event)
-	default:
-		log.Printf("[ALERT] Unsupported channel type: %s", ch.Type)
-	}
-}
-
-func (e *Engine) sendWebhook(ch config.NotificationChannel, event AlertEvent) {
-	url := ch.WebhookURL
-	if ch.Type == "ntfy" && ch.Server != "" && ch.Topic != "" {
-		url = fmt.Sprintf("%s/%s", ch.Server, ch.Topic)
-	}
-
-	if url == "" {
-		log.Printf("[ALERT] Webhook URL missing for channel %s", ch.Name)
-		return
-	}
-
-	var payload interface{}
-	switch ch.Type {
-	case "slack":
-		payload = map[string]string{"text": fmt.Sprintf("*%s*: %s", event.Severity, event.Message)}
-	case "discord":
-		payload = map[string]string{"content": fmt.Sprintf("**%s**: %s", event.Severity, event.Message)}
-	default: // generic webhook or ntfy
-		payload = event
-	}
-
-	data, err := json.Marshal(payload)
-	if err != nil {
-		log.Printf("[ALERT] Failed to marshal webhook payload: %v", err)
-		return
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
-	if err != nil {
-		log.Printf("[ALERT] Failed to create webhook request: %v", err)
-		return
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	for k, v := range ch.Headers {
-		req.Header.Set(k, v)
-	}
-
-	resp, err := e.httpClient.Do(req)
-	if err != nil {
-		log.Printf("[ALERT] Webhook delivery failed for %s: %v", ch.Name, err)
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		log.Printf("[ALERT] Webhook returned non-success status for %s: %d", ch.Name, resp.StatusCode)
-	}
-}
-
-func (e *Engine) sendEmail(ch config.NotificationChannel, event AlertEvent) {
-	if ch.SMTPHost == "" || len(ch.To) == 0 {
-		log.Printf("[ALERT] SMTP configuration missing for channel %s", ch.Name)
-		return
-	}
-
-	auth := smtp.PlainAuth("", ch.SMTPUser, string(ch.SMTPPassword), ch.SMTPHost)
-	addr := fmt.Sprintf("%s:%d", ch.SMTPHost, ch.SMTPPort)
-
-	subject := fmt.Sprintf("Flywall Alert: %s", event.RuleName)
-	body := fmt.Sprintf("Severity: %s\nMessage: %s\nTime: %s\n",
-		event.Severity, event.Message, event.Timestamp.Format(time.RFC3339))
-
-	msg := []byte(fmt.Sprintf("To: %s\r\nSubject: %s\r\n\r\n%s",
-		strings.Join(ch.To, ","), subject, body))
-
-	err := smtp.SendMail(addr, auth, ch.From, ch.To, msg)
-	if err != nil {
-		log.Printf("[ALERT] Email delivery failed for %s: %v", ch.Name, err)
-	}
-}
-
-
 // Trigger triggers a manual alert event.
 func (e *Engine) Trigger(event AlertEvent) {
 	select {
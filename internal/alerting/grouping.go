@@ -0,0 +1,431 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package alerting
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"grimm.is/flywall/internal/errors"
+	"grimm.is/flywall/internal/state"
+)
+
+// State store buckets
+const (
+	BucketAlerts   = "alerts"
+	BucketSilences = "alert_silences"
+)
+
+const (
+	defaultGroupInterval  = 15 * time.Minute
+	defaultResolveTimeout = time.Hour
+
+	// Resolved alerts are kept this long for the API before being pruned
+	resolvedRetention = 24 * time.Hour
+)
+
+// SetStore persists alert groups and silences in store and loads any saved
+// by a previous run. Call before Start.
+func (e *Engine) SetStore(store state.Store) error {
+	for _, b := range []string{BucketAlerts, BucketSilences} {
+		if err := store.CreateBucket(b); err != nil && err != state.ErrBucketExists {
+			return err
+		}
+	}
+
+	alerts, err := store.List(BucketAlerts)
+	if err != nil {
+		return err
+	}
+	silences, err := store.List(BucketSilences)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.store = store
+	for id, data := range alerts {
+		var a Alert
+		if err := json.Unmarshal(data, &a); err != nil {
+			log.Printf("[ALERT] Skipping corrupt alert %s: %v", id, err)
+			continue
+		}
+		e.alerts[a.ID] = &a
+	}
+	for id, data := range silences {
+		var s Silence
+		if err := json.Unmarshal(data, &s); err != nil {
+			log.Printf("[ALERT] Skipping corrupt silence %s: %v", id, err)
+			continue
+		}
+		e.silences[s.ID] = &s
+	}
+	return nil
+}
+
+func (e *Engine) saveAlertLocked(a *Alert) {
+	if e.store == nil {
+		return
+	}
+	if err := e.store.SetJSON(BucketAlerts, a.ID, a); err != nil {
+		log.Printf("[ALERT] Failed to persist alert %s: %v", a.ID, err)
+	}
+}
+
+func (e *Engine) saveSilenceLocked(s *Silence) {
+	if e.store == nil {
+		return
+	}
+	if err := e.store.SetJSON(BucketSilences, s.ID, s); err != nil {
+		log.Printf("[ALERT] Failed to persist silence %s: %v", s.ID, err)
+	}
+}
+
+func (e *Engine) deleteLocked(bucket, id string) {
+	if e.store == nil {
+		return
+	}
+	if err := e.store.Delete(bucket, id); err != nil {
+		log.Printf("[ALERT] Failed to delete %s/%s: %v", bucket, id, err)
+	}
+}
+
+// eventLabels returns the labels an event can be grouped and silenced by:
+// the rule, the severity, and every scalar field of its data.
+func eventLabels(event AlertEvent) map[string]string {
+	labels := map[string]string{
+		"rule":     event.RuleID,
+		"severity": string(event.Severity),
+	}
+	switch data := event.Data.(type) {
+	case map[string]string:
+		for k, v := range data {
+			labels[k] = v
+		}
+	case map[string]interface{}:
+		for k, v := range data {
+			switch v.(type) {
+			case string, bool, int, int64, uint16, uint32, uint64, float64:
+				labels[k] = fmt.Sprint(v)
+			}
+		}
+	}
+	return labels
+}
+
+// groupKey selects the grouping labels and derives a stable group ID.
+func groupKey(labels map[string]string, groupBy []string) (string, map[string]string) {
+	grouped := map[string]string{"rule": labels["rule"]}
+	for _, k := range groupBy {
+		if v, ok := labels[k]; ok {
+			grouped[k] = v
+		}
+	}
+
+	keys := make([]string, 0, len(grouped))
+	for k := range grouped {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%q;", k, grouped[k])
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:6]), grouped
+}
+
+// silencedLocked reports whether an active silence matches labels.
+func (e *Engine) silencedLocked(labels map[string]string, now time.Time) bool {
+	for _, s := range e.silences {
+		if s.Active(now) && s.matches(labels) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Silence) matches(labels map[string]string) bool {
+	for k, pattern := range s.Matchers {
+		v, ok := labels[k]
+		if !ok {
+			return false
+		}
+		if matched, err := path.Match(pattern, v); err != nil || !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// groupEventLocked adds an event to its alert group and notifies if the group
+// is due.
+func (e *Engine) groupEventLocked(rule *AlertRule, event AlertEvent, now time.Time) {
+	labels := eventLabels(event)
+	if e.silencedLocked(labels, now) {
+		log.Printf("[ALERT] Silenced: %s (%s)", event.Message, event.RuleName)
+		return
+	}
+
+	groupBy := rule.GroupBy
+	if len(groupBy) == 0 {
+		groupBy = e.groupBy
+	}
+	id, grouped := groupKey(labels, groupBy)
+
+	a, ok := e.alerts[id]
+	if !ok || a.Status == StatusResolved {
+		// New group, or a resolved one firing again
+		a = &Alert{
+			ID:        id,
+			RuleID:    rule.ID,
+			RuleName:  rule.Name,
+			Labels:    grouped,
+			Status:    StatusFiring,
+			FirstSeen: now,
+		}
+		e.alerts[id] = a
+	}
+	a.Severity = event.Severity
+	a.Message = event.Message
+	a.Count++
+	a.Pending++
+	a.LastSeen = now
+
+	e.flushLocked(a, rule, now)
+	e.saveAlertLocked(a)
+}
+
+// flushLocked sends a group's pending events once group_wait (for the first
+// notification) or the rule's interval (for later ones) has passed.
+// Acknowledged groups stay quiet until they resolve.
+func (e *Engine) flushLocked(a *Alert, rule *AlertRule, now time.Time) bool {
+	if a.Pending == 0 || a.Status != StatusFiring {
+		return false
+	}
+	due := a.FirstSeen.Add(e.groupWait)
+	if !a.LastNotified.IsZero() {
+		due = a.LastNotified.Add(rule.Cooldown)
+	}
+	if now.Before(due) {
+		return false
+	}
+
+	pending := a.Pending
+	a.Pending = 0
+	a.LastNotified = now
+	if a.FirstNotified.IsZero() {
+		a.FirstNotified = now
+	}
+	if e.silencedLocked(a.Labels, now) {
+		return true
+	}
+
+	rule.LastFired = now
+	e.notify(rule.Channels, a.notification(pending, now, ""))
+	return true
+}
+
+// notification builds the event sent to channels for a group.
+func (a *Alert) notification(pending int, now time.Time, prefix string) AlertEvent {
+	msg := a.Message
+	if pending > 1 {
+		msg = fmt.Sprintf("%s (+%d more)", msg, pending-1)
+	}
+	return AlertEvent{
+		ID:        a.ID,
+		RuleID:    a.RuleID,
+		RuleName:  a.RuleName,
+		Message:   prefix + msg,
+		Severity:  a.Severity,
+		Timestamp: now,
+		Data: map[string]interface{}{
+			"labels": a.Labels,
+			"count":  a.Count,
+			"status": a.Status,
+		},
+	}
+}
+
+// tick flushes due groups, escalates unacknowledged ones, resolves stale
+// ones and prunes expired silences.
+func (e *Engine) tick(now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for id, a := range e.alerts {
+		if a.Status == StatusResolved {
+			if now.Sub(a.ResolvedAt) > resolvedRetention {
+				delete(e.alerts, id)
+				e.deleteLocked(BucketAlerts, id)
+			}
+			continue
+		}
+
+		changed := false
+		if now.Sub(a.LastSeen) > e.resolveTimeout {
+			a.Status = StatusResolved
+			a.ResolvedBy = "timeout"
+			a.ResolvedAt = now
+			a.Pending = 0
+			e.saveAlertLocked(a)
+			continue
+		}
+
+		rule, ok := e.rules[a.RuleID]
+		if !ok {
+			continue
+		}
+		if e.flushLocked(a, rule, now) {
+			changed = true
+		}
+		if e.escalateLocked(a, rule, now) {
+			changed = true
+		}
+		if changed {
+			e.saveAlertLocked(a)
+		}
+	}
+
+	for id, s := range e.silences {
+		if !now.Before(s.EndsAt) {
+			delete(e.silences, id)
+			e.deleteLocked(BucketSilences, id)
+		}
+	}
+}
+
+// escalateLocked notifies the rule's escalation channels once if a group is
+// still unacknowledged escalate_after its first notification.
+func (e *Engine) escalateLocked(a *Alert, rule *AlertRule, now time.Time) bool {
+	if rule.EscalateAfter == 0 || len(rule.EscalateChannels) == 0 {
+		return false
+	}
+	if a.Status != StatusFiring || a.Escalated || a.FirstNotified.IsZero() {
+		return false
+	}
+	if now.Sub(a.FirstNotified) < rule.EscalateAfter {
+		return false
+	}
+	a.Escalated = true
+	if e.silencedLocked(a.Labels, now) {
+		return true
+	}
+	log.Printf("[ALERT] Escalating unacknowledged alert %s (%s)", a.ID, a.RuleName)
+	e.notify(rule.EscalateChannels, a.notification(a.Count, now, "[ESCALATED] "))
+	return true
+}
+
+// GetAlerts returns all alert groups, most recent first.
+func (e *Engine) GetAlerts() []Alert {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	res := make([]Alert, 0, len(e.alerts))
+	for _, a := range e.alerts {
+		res = append(res, *a)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].LastSeen.After(res[j].LastSeen) })
+	return res
+}
+
+// Acknowledge marks a firing alert as handled. It stops further
+// notifications and escalation for the group until it resolves.
+func (e *Engine) Acknowledge(id, by string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	a, ok := e.alerts[id]
+	if !ok {
+		return errors.Errorf(errors.KindNotFound, "alert %q not found", id)
+	}
+	if a.Status == StatusResolved {
+		return errors.Errorf(errors.KindConflict, "alert %q is already resolved", id)
+	}
+	a.Status = StatusAcknowledged
+	a.AcknowledgedBy = by
+	a.AcknowledgedAt = time.Now()
+	e.saveAlertLocked(a)
+	return nil
+}
+
+// Resolve closes an alert group. A later matching event opens a new one.
+func (e *Engine) Resolve(id, by string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	a, ok := e.alerts[id]
+	if !ok {
+		return errors.Errorf(errors.KindNotFound, "alert %q not found", id)
+	}
+	a.Status = StatusResolved
+	a.ResolvedBy = by
+	a.ResolvedAt = time.Now()
+	a.Pending = 0
+	e.saveAlertLocked(a)
+	return nil
+}
+
+// AddSilence validates and stores a silence, filling in its ID and start
+// time if unset.
+func (e *Engine) AddSilence(s Silence) (*Silence, error) {
+	if len(s.Matchers) == 0 {
+		return nil, errors.New(errors.KindValidation, "silence needs at least one matcher")
+	}
+	for k, pattern := range s.Matchers {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Errorf(errors.KindValidation, "invalid pattern for %q: %v", k, err)
+		}
+	}
+	now := time.Now()
+	if s.StartsAt.IsZero() {
+		s.StartsAt = now
+	}
+	if !s.EndsAt.After(s.StartsAt) || !s.EndsAt.After(now) {
+		return nil, errors.New(errors.KindValidation, "silence must end in the future and after it starts")
+	}
+	s.ID = uuid.NewString()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.silences[s.ID] = &s
+	e.saveSilenceLocked(&s)
+	return &s, nil
+}
+
+// DeleteSilence removes a silence before it expires.
+func (e *Engine) DeleteSilence(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.silences[id]; !ok {
+		return errors.Errorf(errors.KindNotFound, "silence %q not found", id)
+	}
+	delete(e.silences, id)
+	e.deleteLocked(BucketSilences, id)
+	return nil
+}
+
+// GetSilences returns current and scheduled silences ordered by end time.
+func (e *Engine) GetSilences() []Silence {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	res := make([]Silence, 0, len(e.silences))
+	for _, s := range e.silences {
+		res = append(res, *s)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].EndsAt.Before(res[j].EndsAt) })
+	return res
+}
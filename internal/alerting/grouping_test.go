@@ -0,0 +1,193 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package alerting
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/state"
+)
+
+// recorder collects deliveries per channel.
+type recorder chan string
+
+func (r recorder) Send(ctx context.Context, ch config.NotificationChannel, event AlertEvent) error {
+	r <- ch.Name + ": " + event.Message
+	return nil
+}
+
+func (r recorder) expect(t *testing.T, want string) {
+	t.Helper()
+	select {
+	case got := <-r:
+		assert.Equal(t, want, got)
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected delivery %q", want)
+	}
+}
+
+func (r recorder) expectNone(t *testing.T) {
+	t.Helper()
+	select {
+	case got := <-r:
+		t.Fatalf("unexpected delivery %q", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func newGroupingEngine(t *testing.T, rule config.AlertRule) (*Engine, recorder) {
+	e := NewEngine()
+	e.UpdateConfig(&config.NotificationsConfig{
+		Enabled:   true,
+		GroupBy:   []string{"src_ip"},
+		GroupWait: "30s",
+		Channels: []config.NotificationChannel{
+			{Name: "oncall", Type: "test", Enabled: true},
+			{Name: "manager", Type: "test", Enabled: true},
+		},
+		Rules: []config.AlertRule{rule},
+	})
+	rec := make(recorder, 10)
+	e.RegisterSender("test", rec)
+	return e, rec
+}
+
+func scanEvent(src, msg string) AlertEvent {
+	return AlertEvent{
+		RuleID:   "port-scan",
+		RuleName: "port-scan",
+		Message:  msg,
+		Severity: LevelWarning,
+		Data:     map[string]interface{}{"src_ip": src, "port": 22},
+	}
+}
+
+func (e *Engine) ingest(event AlertEvent, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.groupEventLocked(e.rules[event.RuleID], event, now)
+}
+
+func TestGrouping_BatchesEvents(t *testing.T) {
+	e, rec := newGroupingEngine(t, config.AlertRule{
+		Name: "port-scan", Enabled: true, Channels: []string{"oncall"}, Cooldown: "5m",
+	})
+	t0 := time.Now()
+
+	e.ingest(scanEvent("10.0.0.5", "scan 1"), t0)
+	e.ingest(scanEvent("10.0.0.5", "scan 2"), t0.Add(time.Second))
+	e.ingest(scanEvent("10.0.0.9", "other"), t0.Add(2*time.Second))
+	rec.expectNone(t)
+
+	// group_wait elapsed: one notification per source
+	e.tick(t0.Add(32 * time.Second))
+	got := []string{<-rec, <-rec}
+	assert.ElementsMatch(t, []string{"oncall: scan 2 (+1 more)", "oncall: other"}, got)
+
+	// Further events wait for the interval
+	e.ingest(scanEvent("10.0.0.5", "scan 3"), t0.Add(time.Minute))
+	e.tick(t0.Add(2 * time.Minute))
+	rec.expectNone(t)
+	e.tick(t0.Add(6 * time.Minute))
+	rec.expect(t, "oncall: scan 3")
+
+	alerts := e.GetAlerts()
+	require.Len(t, alerts, 2)
+	assert.Equal(t, 3, alerts[0].Count)
+	assert.Equal(t, map[string]string{"rule": "port-scan", "src_ip": "10.0.0.5"}, alerts[0].Labels)
+}
+
+func TestGrouping_Silence(t *testing.T) {
+	e, rec := newGroupingEngine(t, config.AlertRule{
+		Name: "port-scan", Enabled: true, Channels: []string{"oncall"},
+	})
+	t0 := time.Now()
+
+	_, err := e.AddSilence(Silence{EndsAt: t0.Add(time.Hour)})
+	assert.Error(t, err, "matchers are required")
+	_, err = e.AddSilence(Silence{Matchers: map[string]string{"rule": "x"}, EndsAt: t0.Add(-time.Minute)})
+	assert.Error(t, err, "silence must end in the future")
+
+	s, err := e.AddSilence(Silence{Matchers: map[string]string{"src_ip": "10.0.0.*"}, EndsAt: t0.Add(time.Hour)})
+	require.NoError(t, err)
+	assert.NotEmpty(t, s.ID)
+
+	e.ingest(scanEvent("10.0.0.5", "silenced"), t0)
+	e.ingest(scanEvent("192.168.1.1", "loud"), t0)
+	e.tick(t0.Add(31 * time.Second))
+	rec.expect(t, "oncall: loud")
+	rec.expectNone(t)
+
+	require.NoError(t, e.DeleteSilence(s.ID))
+	assert.Empty(t, e.GetSilences())
+}
+
+func TestGrouping_Escalation(t *testing.T) {
+	e, rec := newGroupingEngine(t, config.AlertRule{
+		Name: "port-scan", Enabled: true, Channels: []string{"oncall"},
+		EscalateAfter: "10m", EscalateChannels: []string{"manager"},
+	})
+	t0 := time.Now()
+
+	e.ingest(scanEvent("10.0.0.5", "a"), t0)
+	e.ingest(scanEvent("10.0.0.9", "b"), t0)
+	e.tick(t0.Add(30 * time.Second))
+	<-rec
+	<-rec
+
+	var acked string
+	for _, a := range e.GetAlerts() {
+		if a.Labels["src_ip"] == "10.0.0.9" {
+			acked = a.ID
+		}
+	}
+	require.NoError(t, e.Acknowledge(acked, "alice"))
+	assert.Error(t, e.Acknowledge("missing", "alice"))
+
+	// Only the unacknowledged group escalates, and only once
+	e.tick(t0.Add(11 * time.Minute))
+	rec.expect(t, "manager: [ESCALATED] a")
+	e.tick(t0.Add(12 * time.Minute))
+	rec.expectNone(t)
+}
+
+func TestGrouping_ResolveAndPersist(t *testing.T) {
+	store, err := state.NewSQLiteStore(state.DefaultOptions(":memory:"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	rule := config.AlertRule{Name: "port-scan", Enabled: true, Channels: []string{"oncall"}}
+	e, _ := newGroupingEngine(t, rule)
+	require.NoError(t, e.SetStore(store))
+
+	t0 := time.Now()
+	e.ingest(scanEvent("10.0.0.5", "a"), t0)
+	_, err = e.AddSilence(Silence{Matchers: map[string]string{"rule": "other"}, EndsAt: t0.Add(time.Hour)})
+	require.NoError(t, err)
+	id := e.GetAlerts()[0].ID
+	require.NoError(t, e.Resolve(id, "bob"))
+
+	// A new engine picks up the saved state
+	e2, _ := newGroupingEngine(t, rule)
+	require.NoError(t, e2.SetStore(store))
+	alerts := e2.GetAlerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, StatusResolved, alerts[0].Status)
+	assert.Equal(t, "bob", alerts[0].ResolvedBy)
+	assert.Len(t, e2.GetSilences(), 1)
+
+	// A resolved group reopens on the next event
+	e2.ingest(scanEvent("10.0.0.5", "again"), t0.Add(time.Minute))
+	alerts = e2.GetAlerts()
+	assert.Equal(t, StatusFiring, alerts[0].Status)
+	assert.Equal(t, 1, alerts[0].Count)
+
+	// And resolves on its own once quiet for resolve_timeout
+	e2.tick(t0.Add(2 * time.Hour))
+	assert.Equal(t, "timeout", e2.GetAlerts()[0].ResolvedBy)
+}
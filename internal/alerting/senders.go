@@ -0,0 +1,341 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"grimm.is/flywall/internal/brand"
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/errors"
+)
+
+const (
+	pushoverAPI = "https://api.pushover.net/1/messages.json"
+	telegramAPI = "https://api.telegram.org"
+
+	execTimeout = 30 * time.Second
+)
+
+// defaultSenders returns the built-in senders keyed by channel type.
+func (e *Engine) defaultSenders() map[string]Sender {
+	webhook := SenderFunc(e.sendWebhook)
+	return map[string]Sender{
+		"webhook":  webhook,
+		"slack":    webhook,
+		"discord":  webhook,
+		"ntfy":     webhook,
+		"email":    SenderFunc(sendEmail),
+		"pushover": SenderFunc(e.sendPushover),
+		"telegram": SenderFunc(e.sendTelegram),
+		"matrix":   SenderFunc(e.sendMatrix),
+		"gotify":   SenderFunc(e.sendGotify),
+		"syslog":   SenderFunc(sendSyslog),
+		"exec":     SenderFunc(sendExec),
+	}
+}
+
+// formatText renders an event as a single line for chat-style channels.
+func formatText(event AlertEvent) string {
+	if event.RuleName != "" {
+		return fmt.Sprintf("[%s] %s: %s", strings.ToUpper(string(event.Severity)), event.RuleName, event.Message)
+	}
+	return fmt.Sprintf("[%s] %s", strings.ToUpper(string(event.Severity)), event.Message)
+}
+
+func alertTitle(event AlertEvent) string {
+	if event.RuleName != "" {
+		return fmt.Sprintf("%s Alert: %s", brand.Name, event.RuleName)
+	}
+	return brand.Name + " Alert"
+}
+
+// do sends a request and treats any non-2xx response as an error.
+func (e *Engine) do(req *http.Request) error {
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		// Drop the URL, which may carry a token (e.g. Telegram)
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			return errors.Wrap(uerr.Err, errors.KindUnavailable, uerr.Op+" failed")
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.Errorf(errors.KindUnavailable, "HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (e *Engine) postJSON(ctx context.Context, method, url string, payload interface{}, headers map[string]string) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, errors.KindValidation, "failed to marshal payload")
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, errors.KindValidation, "failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return e.do(req)
+}
+
+func (e *Engine) sendWebhook(ctx context.Context, ch config.NotificationChannel, event AlertEvent) error {
+	url := ch.WebhookURL
+	if ch.Type == "ntfy" && ch.Server != "" && ch.Topic != "" {
+		url = fmt.Sprintf("%s/%s", ch.Server, ch.Topic)
+	}
+	if url == "" {
+		return errors.New(errors.KindValidation, "webhook URL missing")
+	}
+
+	var payload interface{}
+	switch ch.Type {
+	case "slack":
+		payload = map[string]string{"text": fmt.Sprintf("*%s*: %s", event.Severity, event.Message)}
+	case "discord":
+		payload = map[string]string{"content": fmt.Sprintf("**%s**: %s", event.Severity, event.Message)}
+	default: // generic webhook or ntfy
+		payload = event
+	}
+	return e.postJSON(ctx, http.MethodPost, url, payload, ch.Headers)
+}
+
+func sendEmail(ctx context.Context, ch config.NotificationChannel, event AlertEvent) error {
+	if ch.SMTPHost == "" || len(ch.To) == 0 {
+		return errors.New(errors.KindValidation, "SMTP configuration missing")
+	}
+
+	auth := smtp.PlainAuth("", ch.SMTPUser, string(ch.SMTPPassword), ch.SMTPHost)
+	addr := fmt.Sprintf("%s:%d", ch.SMTPHost, ch.SMTPPort)
+
+	subject := alertTitle(event)
+	body := fmt.Sprintf("Severity: %s\nMessage: %s\nTime: %s\n",
+		event.Severity, event.Message, event.Timestamp.Format(time.RFC3339))
+
+	msg := []byte(fmt.Sprintf("To: %s\r\nSubject: %s\r\n\r\n%s",
+		strings.Join(ch.To, ","), subject, body))
+
+	return smtp.SendMail(addr, auth, ch.From, ch.To, msg)
+}
+
+// sendPushover posts to the Pushover messages API. Server overrides the API
+// URL.
+func (e *Engine) sendPushover(ctx context.Context, ch config.NotificationChannel, event AlertEvent) error {
+	if ch.APIToken == "" || ch.UserKey == "" {
+		return errors.New(errors.KindValidation, "pushover requires api_token and user_key")
+	}
+	form := url.Values{
+		"token":   {string(ch.APIToken)},
+		"user":    {string(ch.UserKey)},
+		"title":   {alertTitle(event)},
+		"message": {event.Message},
+	}
+	if ch.Priority != 0 {
+		form.Set("priority", strconv.Itoa(ch.Priority))
+	}
+	if ch.Priority == 2 {
+		// Emergency priority repeats until acknowledged
+		form.Set("retry", "60")
+		form.Set("expire", "3600")
+	}
+	if ch.Sound != "" {
+		form.Set("sound", ch.Sound)
+	}
+
+	endpoint := pushoverAPI
+	if ch.Server != "" {
+		endpoint = ch.Server
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return errors.Wrap(err, errors.KindValidation, "failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return e.do(req)
+}
+
+// sendTelegram posts through the Bot API. api_token is the bot token and
+// Server overrides the API base URL.
+func (e *Engine) sendTelegram(ctx context.Context, ch config.NotificationChannel, event AlertEvent) error {
+	if ch.APIToken == "" || ch.ChatID == "" {
+		return errors.New(errors.KindValidation, "telegram requires api_token and chat_id")
+	}
+	base := telegramAPI
+	if ch.Server != "" {
+		base = strings.TrimRight(ch.Server, "/")
+	}
+	payload := map[string]interface{}{
+		"chat_id": ch.ChatID,
+		"text":    formatText(event),
+	}
+	return e.postJSON(ctx, http.MethodPost, fmt.Sprintf("%s/bot%s/sendMessage", base, string(ch.APIToken)), payload, nil)
+}
+
+// sendMatrix sends an m.text message to a room. Server is the homeserver URL
+// and api_token an access token for a user already joined to the room.
+func (e *Engine) sendMatrix(ctx context.Context, ch config.NotificationChannel, event AlertEvent) error {
+	if ch.Server == "" || ch.Room == "" || ch.APIToken == "" {
+		return errors.New(errors.KindValidation, "matrix requires server, room and api_token")
+	}
+	// Derived from the event so retries are deduplicated by the homeserver
+	txnID := fmt.Sprintf("%s-%s-%d", brand.LowerName, url.PathEscape(ch.Name), event.Timestamp.UnixNano())
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimRight(ch.Server, "/"), url.PathEscape(ch.Room), txnID)
+	payload := map[string]string{
+		"msgtype": "m.text",
+		"body":    formatText(event),
+	}
+	return e.postJSON(ctx, http.MethodPut, endpoint, payload, map[string]string{
+		"Authorization": "Bearer " + string(ch.APIToken),
+	})
+}
+
+// sendGotify posts to a Gotify server using an application token.
+func (e *Engine) sendGotify(ctx context.Context, ch config.NotificationChannel, event AlertEvent) error {
+	if ch.Server == "" || ch.APIToken == "" {
+		return errors.New(errors.KindValidation, "gotify requires server and api_token")
+	}
+	priority := ch.Priority
+	if priority == 0 {
+		priority = gotifyPriority(event.Severity)
+	}
+	payload := map[string]interface{}{
+		"title":    alertTitle(event),
+		"message":  event.Message,
+		"priority": priority,
+	}
+	return e.postJSON(ctx, http.MethodPost, strings.TrimRight(ch.Server, "/")+"/message", payload, map[string]string{
+		"X-Gotify-Key": string(ch.APIToken),
+	})
+}
+
+func gotifyPriority(level AlertLevel) int {
+	switch level {
+	case LevelCritical:
+		return 8
+	case LevelWarning:
+		return 5
+	default:
+		return 2
+	}
+}
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// sendSyslog writes an RFC 3164 message to the local syslog socket, or to
+// Address ("udp://host:514", "tcp://host:514").
+func sendSyslog(ctx context.Context, ch config.NotificationChannel, event AlertEvent) error {
+	facility := syslogFacilities["daemon"]
+	if ch.Facility != "" {
+		f, ok := syslogFacilities[ch.Facility]
+		if !ok {
+			return errors.Errorf(errors.KindValidation, "unknown syslog facility %q", ch.Facility)
+		}
+		facility = f
+	}
+
+	network, addr := "unixgram", "/dev/log"
+	if ch.Address != "" {
+		u, err := url.Parse(ch.Address)
+		if err != nil || u.Host == "" {
+			return errors.Errorf(errors.KindValidation, "invalid syslog address %q", ch.Address)
+		}
+		network, addr = u.Scheme, u.Host
+		if u.Port() == "" {
+			addr = net.JoinHostPort(u.Hostname(), "514")
+		}
+	}
+
+	var d net.Dialer
+	dctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	conn, err := d.DialContext(dctx, network, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	hostname, _ := os.Hostname()
+	msg := fmt.Sprintf("<%d>%s %s %s: %s",
+		facility*8+syslogSeverity(event.Severity), time.Now().Format(time.Stamp), hostname, brand.LowerName, formatText(event))
+	if network == "tcp" {
+		// Octet-counting framing (RFC 6587)
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Write([]byte(msg))
+	return err
+}
+
+func syslogSeverity(level AlertLevel) int {
+	switch level {
+	case LevelCritical:
+		return 2 // crit
+	case LevelWarning:
+		return 4 // warning
+	default:
+		return 6 // info
+	}
+}
+
+// sendExec runs a local command with the event as JSON on stdin and the
+// main fields in ALERT_* environment variables. A non-zero exit is a failed
+// delivery.
+func sendExec(ctx context.Context, ch config.NotificationChannel, event AlertEvent) error {
+	if len(ch.Command) == 0 {
+		return errors.New(errors.KindValidation, "exec requires command")
+	}
+	if !config.IsHookPath(ch.Command[0]) {
+		return errors.Errorf(errors.KindPermission, "exec command %q is outside the hooks directory", ch.Command[0])
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, errors.KindValidation, "failed to marshal event")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, execTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, ch.Command[0], ch.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(),
+		"ALERT_CHANNEL="+ch.Name,
+		"ALERT_RULE="+event.RuleName,
+		"ALERT_SEVERITY="+string(event.Severity),
+		"ALERT_MESSAGE="+event.Message,
+		"ALERT_TIMESTAMP="+event.Timestamp.Format(time.RFC3339),
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return errors.Errorf(errors.KindUnavailable, "%v: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
@@ -26,6 +26,10 @@ type AlertRule struct {
 	Channels    []string      `json:"channels"`  // Names of notification channels
 	Cooldown    time.Duration `json:"cooldown"`
 	LastFired   time.Time     `json:"last_fired"`
+
+	GroupBy          []string      `json:"group_by,omitempty"`
+	EscalateAfter    time.Duration `json:"escalate_after,omitempty"`
+	EscalateChannels []string      `json:"escalate_to,omitempty"`
 }
 
 // AlertEvent represents a triggered alert occurrence.
@@ -44,3 +48,52 @@ type Action struct {
 	Type   string `json:"type"`   // "webhook", "email", "log"
 	Target string `json:"target"` // URL, Email address, or channel name
 }
+
+// AlertStatus is the lifecycle state of an alert group.
+type AlertStatus string
+
+const (
+	StatusFiring       AlertStatus = "firing"
+	StatusAcknowledged AlertStatus = "acknowledged"
+	StatusResolved     AlertStatus = "resolved"
+)
+
+// Alert is a group of events from one rule that share the same grouping
+// label values. Notifications are sent per group rather than per event.
+type Alert struct {
+	ID             string            `json:"id"`
+	RuleID         string            `json:"rule_id"`
+	RuleName       string            `json:"rule_name"`
+	Severity       AlertLevel        `json:"severity"`
+	Labels         map[string]string `json:"labels"`
+	Status         AlertStatus       `json:"status"`
+	Message        string            `json:"message"` // Latest event message
+	Count          int               `json:"count"`   // Events since the group opened
+	Pending        int               `json:"pending"` // Events not yet notified
+	FirstSeen      time.Time         `json:"first_seen"`
+	LastSeen       time.Time         `json:"last_seen"`
+	FirstNotified  time.Time         `json:"first_notified,omitempty"`
+	LastNotified   time.Time         `json:"last_notified,omitempty"`
+	Escalated      bool              `json:"escalated"`
+	AcknowledgedBy string            `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt time.Time         `json:"acknowledged_at,omitempty"`
+	ResolvedBy     string            `json:"resolved_by,omitempty"`
+	ResolvedAt     time.Time         `json:"resolved_at,omitempty"`
+}
+
+// Silence suppresses notifications for alerts whose labels match all of its
+// matchers between StartsAt and EndsAt. Matcher values may use path.Match
+// globs, e.g. {"rule": "port-scan", "src_ip": "10.0.0.*"}.
+type Silence struct {
+	ID        string            `json:"id"`
+	Matchers  map[string]string `json:"matchers"`
+	StartsAt  time.Time         `json:"starts_at"`
+	EndsAt    time.Time         `json:"ends_at"`
+	CreatedBy string            `json:"created_by,omitempty"`
+	Comment   string            `json:"comment,omitempty"`
+}
+
+// Active reports whether the silence is in effect at t.
+func (s *Silence) Active(t time.Time) bool {
+	return !t.Before(s.StartsAt) && t.Before(s.EndsAt)
+}
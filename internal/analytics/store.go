@@ -159,6 +159,31 @@ func (s *Store) GetBandwidthUsage(srcMAC string, from, to time.Time) ([]struct {
 	return result, nil
 }
 
+// GetUsageByMAC returns total bytes per source MAC in a time range
+func (s *Store) GetUsageByMAC(from, to time.Time) (map[string]int64, error) {
+	rows, err := s.db.Query(`
+		SELECT src_mac, SUM(bytes)
+		FROM flow_summaries
+		WHERE bucket_time >= ? AND bucket_time <= ?
+		GROUP BY src_mac
+	`, from.Unix(), to.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := make(map[string]int64)
+	for rows.Next() {
+		var mac string
+		var b int64
+		if err := rows.Scan(&mac, &b); err != nil {
+			return nil, err
+		}
+		usage[mac] = b
+	}
+	return usage, rows.Err()
+}
+
 // GetTopTalkers returns the top N devices by byte count in a time range
 func (s *Store) GetTopTalkers(from, to time.Time, limit int) ([]Summary, error) {
 	query := `
@@ -6,8 +6,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"grimm.is/flywall/internal/alerting"
+	"grimm.is/flywall/internal/auth"
 )
 
 // HandleGetAlertHistory returns the alert history.
@@ -41,6 +44,18 @@ func (s *Server) HandleGetAlertRules(w http.ResponseWriter, r *http.Request) {
 	WriteJSON(w, http.StatusOK, rules)
 }
 
+// HandleGetAlertChannels returns delivery status for each notification channel.
+// GET /api/alerts/channels
+func (s *Server) HandleGetAlertChannels(w http.ResponseWriter, r *http.Request) {
+	channels, err := s.client.GetAlertChannelStatus()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, channels)
+}
+
 // HandleUpdateAlertRule updates or creates an alert rule.
 // POST /api/alerts/rules
 func (s *Server) HandleUpdateAlertRule(w http.ResponseWriter, r *http.Request) {
@@ -62,3 +77,125 @@ func (s *Server) HandleUpdateAlertRule(w http.ResponseWriter, r *http.Request) {
 
 	WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
 }
+
+// HandleGetAlerts returns alert groups with their acknowledgement state.
+// GET /api/alerts
+func (s *Server) HandleGetAlerts(w http.ResponseWriter, r *http.Request) {
+	alerts, err := s.client.GetAlerts()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, alerts)
+}
+
+// HandleAckAlert acknowledges an alert group.
+// POST /api/alerts/{id}/ack
+func (s *Server) HandleAckAlert(w http.ResponseWriter, r *http.Request) {
+	if err := s.client.AckAlert(r.PathValue("id"), alertActor(r)); err != nil {
+		writeAlertError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
+
+// HandleResolveAlert resolves an alert group.
+// POST /api/alerts/{id}/resolve
+func (s *Server) HandleResolveAlert(w http.ResponseWriter, r *http.Request) {
+	if err := s.client.ResolveAlert(r.PathValue("id"), alertActor(r)); err != nil {
+		writeAlertError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
+
+// HandleGetSilences returns active and scheduled silences.
+// GET /api/alerts/silences
+func (s *Server) HandleGetSilences(w http.ResponseWriter, r *http.Request) {
+	silences, err := s.client.GetSilences()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, silences)
+}
+
+// createSilenceRequest takes either an explicit end time or a duration.
+type createSilenceRequest struct {
+	Matchers map[string]string `json:"matchers"`
+	StartsAt time.Time         `json:"starts_at,omitempty"`
+	EndsAt   time.Time         `json:"ends_at,omitempty"`
+	Duration string            `json:"duration,omitempty"` // e.g. "2h", from starts_at or now
+	Comment  string            `json:"comment,omitempty"`
+}
+
+// HandleCreateSilence creates a time-bounded silence.
+// POST /api/alerts/silences
+func (s *Server) HandleCreateSilence(w http.ResponseWriter, r *http.Request) {
+	var req createSilenceRequest
+	if !BindJSON(w, r, &req) {
+		return
+	}
+
+	silence := alerting.Silence{
+		Matchers:  req.Matchers,
+		StartsAt:  req.StartsAt,
+		EndsAt:    req.EndsAt,
+		CreatedBy: alertActor(r),
+		Comment:   req.Comment,
+	}
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			WriteError(w, http.StatusBadRequest, "invalid duration")
+			return
+		}
+		start := req.StartsAt
+		if start.IsZero() {
+			start = time.Now()
+		}
+		silence.EndsAt = start.Add(d)
+	}
+
+	created, err := s.client.CreateSilence(silence)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, created)
+}
+
+// HandleDeleteSilence removes a silence.
+// DELETE /api/alerts/silences/{id}
+func (s *Server) HandleDeleteSilence(w http.ResponseWriter, r *http.Request) {
+	if err := s.client.DeleteSilence(r.PathValue("id")); err != nil {
+		writeAlertError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
+
+// alertActor names the caller for acknowledgement and silence records.
+func alertActor(r *http.Request) string {
+	if user := auth.GetUserFromContext(r.Context()); user != nil {
+		return user.Username
+	}
+	if key := GetAPIKey(r.Context()); key != nil {
+		return key.Name
+	}
+	return "unknown"
+}
+
+func writeAlertError(w http.ResponseWriter, err error) {
+	if strings.Contains(err.Error(), "not found") {
+		WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	WriteError(w, http.StatusBadRequest, err.Error())
+}
@@ -36,6 +36,10 @@ func (s *Server) handleSafeApply(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.checkExecChannels(w, r, &req.Config) {
+		return
+	}
+
 	if !s.RequireControlPlane(w, r) {
 		return
 	}
@@ -86,6 +90,7 @@ func (s *Server) handleSafeApply(w http.ResponseWriter, r *http.Request) {
 	s.configMu.Lock()
 	s.Config = &req.Config
 	s.configMu.Unlock()
+	s.loadAuthConfig()
 
 	// Step 3: If ping targets specified, verify connectivity
 	if len(req.PingTargets) > 0 {
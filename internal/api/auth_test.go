@@ -254,6 +254,53 @@ func TestAPIKey_WildcardPermissions(t *testing.T) {
 	}
 }
 
+func TestAPIKey_LegacyPermissionsImplyResources(t *testing.T) {
+	key := &storage.APIKey{
+		Permissions: []storage.Permission{storage.PermWriteFirewall, storage.PermWriteConfig},
+	}
+
+	for _, perm := range []storage.Permission{
+		storage.PermWritePolicies,
+		storage.PermWriteNAT,
+		storage.PermApplyLearning,
+		storage.PermApplyConfig,
+		storage.PermWriteVPN,
+	} {
+		if !key.HasPermission(perm) {
+			t.Errorf("expected legacy key to have %s", perm)
+		}
+	}
+	if key.HasPermission(storage.PermWriteUsers) {
+		t.Error("expected legacy key not to have users:write")
+	}
+
+	applyAll := &storage.APIKey{Permissions: []storage.Permission{storage.PermWriteAll}}
+	if !applyAll.HasPermission(storage.PermApplyConfig) {
+		t.Error("expected write:* to imply config:apply")
+	}
+}
+
+func TestPermToResource(t *testing.T) {
+	tests := []struct {
+		perm     storage.Permission
+		resource string
+		verb     string
+	}{
+		{storage.PermReadDHCP, "dhcp", "read"},
+		{storage.PermApplyLearning, "learning", "apply"},
+		{storage.PermAdminSystem, "system", "write"},
+		{storage.PermAdminBackup, "backup", "write"},
+		{storage.PermAll, "", ""},
+	}
+
+	for _, tt := range tests {
+		resource, verb := permToResource(tt.perm)
+		if resource != tt.resource || verb != tt.verb {
+			t.Errorf("permToResource(%s) = %s:%s, want %s:%s", tt.perm, resource, verb, tt.resource, tt.verb)
+		}
+	}
+}
+
 func TestWithExpiry(t *testing.T) {
 	store := storage.NewMemoryAPIKeyStore()
 	manager := NewAPIKeyManager(store)
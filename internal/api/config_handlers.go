@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/pmezard/go-difflib/difflib"
+	"grimm.is/flywall/internal/api/storage"
 	"grimm.is/flywall/internal/config"
 	"grimm.is/flywall/internal/logging"
 	"grimm.is/flywall/internal/vpn"
@@ -56,6 +57,22 @@ func (s *Server) broadcastPendingStatus() {
 	})
 }
 
+// checkExecChannels rejects a config that adds, edits or removes exec
+// notification channels unless the caller holds admin:system. Exec
+// channels run commands as root, which config:write alone must not grant.
+func (s *Server) checkExecChannels(w http.ResponseWriter, r *http.Request, newCfg *config.Config) bool {
+	s.configMu.RLock()
+	before, _ := json.Marshal(s.Config.ExecChannels())
+	s.configMu.RUnlock()
+	after, _ := json.Marshal(newCfg.ExecChannels())
+
+	if string(before) != string(after) && !hasPermission(r, storage.PermAdminSystem) {
+		WriteErrorCtx(w, r, http.StatusForbidden, fmt.Sprintf("changing exec notification channels requires %s", storage.PermAdminSystem))
+		return false
+	}
+	return true
+}
+
 // applyConfigUpdate safely applies a config modification:
 // 1. Clones current config
 // 2. Applies the update function to the clone
@@ -67,6 +84,15 @@ func (s *Server) broadcastPendingStatus() {
 // On validation errors, writes 400 Bad Request.
 // On RPC errors, writes 500 Internal Server Error.
 func (s *Server) applyConfigUpdate(w http.ResponseWriter, r *http.Request, updateFn func(cfg *config.Config)) bool {
+	return s.tryConfigUpdate(w, r, func(cfg *config.Config) error {
+		updateFn(cfg)
+		return nil
+	})
+}
+
+// tryConfigUpdate is applyConfigUpdate for updates that can fail. An error
+// from updateFn fails the request with 400 Bad Request and stages nothing.
+func (s *Server) tryConfigUpdate(w http.ResponseWriter, r *http.Request, updateFn func(cfg *config.Config) error) bool {
 	s.configMu.RLock()
 	// Deep clone via JSON (safe for nested structs)
 	data, err := json.Marshal(s.Config)
@@ -83,7 +109,14 @@ func (s *Server) applyConfigUpdate(w http.ResponseWriter, r *http.Request, updat
 	}
 
 	// Apply the update to the clone
-	updateFn(&cloned)
+	if err := updateFn(&cloned); err != nil {
+		WriteErrorCtx(w, r, http.StatusBadRequest, err.Error())
+		return false
+	}
+
+	if !s.checkExecChannels(w, r, &cloned) {
+		return false
+	}
 
 	// Validate the modified config
 	if errs := cloned.Validate(); errs.HasErrors() {
@@ -97,8 +130,12 @@ func (s *Server) applyConfigUpdate(w http.ResponseWriter, r *http.Request, updat
 
 	// Update local config (Staged)
 	s.configMu.Lock()
-	updateFn(s.Config)
+	err = updateFn(s.Config)
 	s.configMu.Unlock()
+	if err != nil {
+		WriteErrorCtx(w, r, http.StatusInternalServerError, err.Error())
+		return false
+	}
 
 	// Notify UI of pending changes
 	go s.broadcastPendingStatus()
@@ -781,6 +818,7 @@ func (s *Server) handleDiscardConfig(w http.ResponseWriter, r *http.Request) {
 	s.configMu.Lock()
 	s.Config = cfg
 	s.configMu.Unlock()
+	s.loadAuthConfig()
 
 	// Notify UI
 	go s.broadcastPendingStatus()
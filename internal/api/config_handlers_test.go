@@ -3,13 +3,19 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"grimm.is/flywall/internal/api/storage"
 	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/ctlplane"
 	"grimm.is/flywall/internal/logging"
+	"grimm.is/flywall/internal/vpn"
 )
 
 func TestHandlePolicies_Get(t *testing.T) {
@@ -327,3 +333,143 @@ func TestHandleDHCP_UpdateCheck(t *testing.T) {
 		t.Errorf("expected range_start in response, got: %s", body)
 	}
 }
+
+func TestHandleWireGuardProvision(t *testing.T) {
+	logger := logging.New(logging.DefaultConfig())
+	serverKey, _, _ := vpn.GenerateKeyPair()
+	wg := config.WireGuardConfig{
+		Name:           "wg0",
+		Enabled:        true,
+		PrivateKey:     config.SecureString(serverKey),
+		Address:        []string{"10.200.0.1/24"},
+		ClientEndpoint: "vpn.example.com:51820",
+	}
+
+	// The control plane provisions against its own copy with real secrets
+	cpCfg := &config.Config{VPN: &config.VPNConfig{WireGuard: []config.WireGuardConfig{wg}}}
+	profile, err := vpn.ProvisionPeer(cpCfg, vpn.ProvisionRequest{Name: "phone"}, time.Now())
+	if err != nil {
+		t.Fatalf("ProvisionPeer() error = %v", err)
+	}
+
+	mockClient := new(ctlplane.MockControlPlaneClient)
+	mockClient.On("ProvisionWireGuardPeer", &ctlplane.ProvisionWireGuardPeerArgs{Name: "phone"}).Return(profile, nil)
+	mockClient.On("ProvisionWireGuardPeer", &ctlplane.ProvisionWireGuardPeerArgs{Name: "tablet"}).Return(nil, fmt.Errorf("peer exists"))
+	laptop, err := vpn.ProvisionPeer(cpCfg, vpn.ProvisionRequest{Name: "laptop"}, time.Now())
+	if err != nil {
+		t.Fatalf("ProvisionPeer() error = %v", err)
+	}
+	mockClient.On("ProvisionWireGuardPeer", &ctlplane.ProvisionWireGuardPeerArgs{Name: "laptop"}).Return(laptop, fmt.Errorf("peer staged but apply failed: nft error"))
+
+	server := &Server{
+		Config: &config.Config{VPN: &config.VPNConfig{WireGuard: []config.WireGuardConfig{wg}}},
+		client: mockClient,
+		logger: logger,
+	}
+
+	req, _ := http.NewRequest("POST", "/api/wireguard/provision", strings.NewReader(`{"name": "phone"}`))
+	rr := httptest.NewRecorder()
+
+	server.handleWireGuardProvision(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("expected OK, got %v: %s", status, rr.Body.String())
+	}
+	var resp struct {
+		PublicKey    string `json:"public_key"`
+		ClientConfig string `json:"client_config"`
+		QRCode       string `json:"qr_code"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.QRCode == "" || !strings.Contains(resp.ClientConfig, "Address = 10.200.0.2/32") {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	// Mirrored into the API's staged config with the same keys
+	peers := server.Config.VPN.WireGuard[0].Peers
+	if len(peers) != 1 || peers[0].PublicKey != resp.PublicKey || peers[0].PresharedKey != profile.Peer.PresharedKey {
+		t.Fatalf("expected staged peer with key %s, got %+v", resp.PublicKey, peers)
+	}
+
+	req, _ = http.NewRequest("POST", "/api/wireguard/provision", strings.NewReader(`{"name": "tablet"}`))
+	rr = httptest.NewRecorder()
+	server.handleWireGuardProvision(rr, req)
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("expected BadRequest, got %v", status)
+	}
+
+	// A failed apply still returns the client config and stages the peer
+	req, _ = http.NewRequest("POST", "/api/wireguard/provision", strings.NewReader(`{"name": "laptop"}`))
+	rr = httptest.NewRecorder()
+	server.handleWireGuardProvision(rr, req)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("expected OK when only the apply fails, got %v: %s", status, rr.Body.String())
+	}
+	var applied struct {
+		ClientConfig string `json:"client_config"`
+		QRCode       string `json:"qr_code"`
+		ApplyError   string `json:"apply_error"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &applied); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if applied.QRCode == "" || applied.ClientConfig == "" || !strings.Contains(applied.ApplyError, "nft error") {
+		t.Errorf("expected client config with apply_error, got %+v", applied)
+	}
+	if peers := server.Config.VPN.WireGuard[0].Peers; len(peers) != 2 || peers[1].PublicKey != laptop.Peer.PublicKey {
+		t.Errorf("expected laptop peer staged, got %+v", peers)
+	}
+
+	// A peer that can't be staged fails the request instead of being lost
+	server.Config = &config.Config{}
+	req, _ = http.NewRequest("POST", "/api/wireguard/provision", strings.NewReader(`{"name": "phone"}`))
+	rr = httptest.NewRecorder()
+	server.handleWireGuardProvision(rr, req)
+	if status := rr.Code; status != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "no WireGuard connections") {
+		t.Errorf("expected BadRequest for unstageable peer, got %v: %s", status, rr.Body.String())
+	}
+}
+
+func TestHandleUpdateConfig_ExecChannels(t *testing.T) {
+	t.Setenv("FLYWALL_CONFIG_DIR", "/etc/flywall")
+
+	hook := config.NotificationChannel{Name: "hook", Type: "exec", Command: []string{"/etc/flywall/hooks.d/page"}}
+	withHook := func(cmd ...string) config.Config {
+		ch := hook
+		if cmd != nil {
+			ch.Command = cmd
+		}
+		return config.Config{SchemaVersion: "1.0", Notifications: &config.NotificationsConfig{Channels: []config.NotificationChannel{ch}}}
+	}
+
+	tests := []struct {
+		name   string
+		cfg    config.Config
+		perms  []storage.Permission
+		status int
+	}{
+		{"add hook with config:write", withHook(), []storage.Permission{storage.PermWriteConfig}, http.StatusForbidden},
+		{"add hook with admin:system", withHook(), []storage.Permission{storage.PermWriteConfig, storage.PermAdminSystem}, http.StatusOK},
+		{"hook outside hooks.d", withHook("/bin/sh", "-c", "id"), []storage.Permission{storage.PermAll}, http.StatusBadRequest},
+		{"other change with config:write", config.Config{SchemaVersion: "1.0", IPForwarding: true}, []storage.Permission{storage.PermWriteConfig}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &Server{Config: &config.Config{SchemaVersion: "1.0"}, logger: logging.New(logging.DefaultConfig())}
+
+			body, _ := json.Marshal(tt.cfg)
+			req := httptest.NewRequest("POST", "/api/config", strings.NewReader(string(body)))
+			req = req.WithContext(WithAPIKey(req.Context(), &storage.APIKey{Name: "ops", Permissions: tt.perms}))
+			rr := httptest.NewRecorder()
+
+			server.handleUpdateConfig(rr, req)
+
+			if rr.Code != tt.status {
+				t.Errorf("got %d, want %d: %s", rr.Code, tt.status, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"grimm.is/flywall/internal/firewall"
+)
+
+// defaultConntrackLimit caps a listing unless the client asks for more.
+const defaultConntrackLimit = 1000
+
+// ConntrackResponse is a page of connection tracking entries.
+type ConntrackResponse struct {
+	Entries []firewall.ConntrackEntry `json:"entries"`
+	Total   int                       `json:"total"`
+	Limit   int                       `json:"limit"`
+	Offset  int                       `json:"offset"`
+}
+
+// handleGetConnections lists tracked connections
+// GET /api/firewall/connections?zone=lan&protocol=tcp&port=443&state=established&limit=100
+func (s *Server) handleGetConnections(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter, err := conntrackFilterFromQuery(q)
+	if err != nil {
+		WriteErrorCtx(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit, offset := defaultConntrackLimit, 0
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+			WriteErrorCtx(w, r, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			WriteErrorCtx(w, r, http.StatusBadRequest, "Invalid offset")
+			return
+		}
+	}
+
+	entries, total, err := s.client.GetConntrack(filter, limit, offset)
+	if err != nil {
+		WriteErrorCtx(w, r, http.StatusInternalServerError, "Failed to get connections: "+err.Error())
+		return
+	}
+
+	// Ensure empty list not null
+	if entries == nil {
+		entries = []firewall.ConntrackEntry{}
+	}
+
+	WriteJSON(w, http.StatusOK, ConntrackResponse{Entries: entries, Total: total, Limit: limit, Offset: offset})
+}
+
+// handleKillConnections deletes the tracked connections matching a filter
+// POST /api/firewall/connections/kill {"src_ip": "192.168.1.50", "dst_port": 22}
+func (s *Server) handleKillConnections(w http.ResponseWriter, r *http.Request) {
+	var filter firewall.ConntrackFilter
+	if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+		WriteErrorCtx(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if filter.IsEmpty() {
+		WriteErrorCtx(w, r, http.StatusBadRequest, "At least one filter field is required")
+		return
+	}
+
+	deleted, err := s.client.KillConntrack(filter)
+	if err != nil {
+		WriteErrorCtx(w, r, http.StatusBadRequest, "Failed to kill connections: "+err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]int{"deleted": deleted})
+}
+
+// conntrackFilterFromQuery reads a filter from query parameters named like
+// the filter's JSON fields.
+func conntrackFilterFromQuery(q url.Values) (firewall.ConntrackFilter, error) {
+	f := firewall.ConntrackFilter{
+		Protocol: q.Get("protocol"),
+		IP:       q.Get("ip"),
+		SrcIP:    q.Get("src_ip"),
+		DstIP:    q.Get("dst_ip"),
+		State:    q.Get("state"),
+		Mark:     q.Get("mark"),
+		Zone:     q.Get("zone"),
+	}
+	ports := map[string]*uint16{"port": &f.Port, "src_port": &f.SrcPort, "dst_port": &f.DstPort}
+	for name, dst := range ports {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.ParseUint(v, 10, 16)
+			if err != nil {
+				return f, fmt.Errorf("invalid %s %q", name, v)
+			}
+			*dst = uint16(n)
+		}
+	}
+	if v := q.Get("id"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return f, fmt.Errorf("invalid id %q", v)
+		}
+		f.ID = uint32(n)
+	}
+	return f, nil
+}
@@ -0,0 +1,75 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"grimm.is/flywall/internal/ctlplane"
+	"grimm.is/flywall/internal/firewall"
+	"grimm.is/flywall/internal/logging"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleGetConnections(t *testing.T) {
+	mockClient := new(ctlplane.MockControlPlaneClient)
+
+	filter := firewall.ConntrackFilter{Protocol: "tcp", Zone: "lan", DstPort: 443}
+	entries := []firewall.ConntrackEntry{{ID: 7, Protocol: "tcp", SrcIP: "192.168.1.10", DstIP: "1.1.1.1", DstPort: 443}}
+	mockClient.On("GetConntrack", filter, 50, 100).Return(entries, 151, nil)
+
+	server := &Server{
+		client: mockClient,
+		logger: logging.New(logging.DefaultConfig()),
+	}
+
+	req, _ := http.NewRequest("GET", "/api/firewall/connections?protocol=tcp&zone=lan&dst_port=443&limit=50&offset=100", nil)
+	rr := httptest.NewRecorder()
+
+	server.handleGetConnections(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var result ConntrackResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &result)
+	assert.NoError(t, err)
+	assert.Equal(t, entries, result.Entries)
+	assert.Equal(t, 151, result.Total)
+
+	mockClient.AssertExpectations(t)
+
+	// Bad ports are rejected before reaching the control plane
+	req, _ = http.NewRequest("GET", "/api/firewall/connections?port=70000", nil)
+	rr = httptest.NewRecorder()
+	server.handleGetConnections(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestHandleKillConnections(t *testing.T) {
+	mockClient := new(ctlplane.MockControlPlaneClient)
+	mockClient.On("KillConntrack", firewall.ConntrackFilter{SrcIP: "192.168.1.50", DstPort: 22}).Return(3, nil)
+
+	server := &Server{
+		client: mockClient,
+		logger: logging.New(logging.DefaultConfig()),
+	}
+
+	req, _ := http.NewRequest("POST", "/api/firewall/connections/kill", strings.NewReader(`{"src_ip": "192.168.1.50", "dst_port": 22}`))
+	rr := httptest.NewRecorder()
+	server.handleKillConnections(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"deleted": 3}`, rr.Body.String())
+
+	// An empty filter would flush the whole table
+	req, _ = http.NewRequest("POST", "/api/firewall/connections/kill", strings.NewReader(`{}`))
+	rr = httptest.NewRecorder()
+	server.handleKillConnections(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockClient.AssertExpectations(t)
+}
@@ -15,6 +15,8 @@ import (
 	"time"
 
 	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/errors"
+	"grimm.is/flywall/internal/trace"
 )
 
 // --- Packet Simulator ---
@@ -250,6 +252,58 @@ func isPrivateIP(ip net.IP) bool {
 	return false
 }
 
+// --- Packet Trace ---
+
+// TracePacketRequest is a packet to walk through the ruleset. With Candidate
+// set, the staged configuration is compiled and traced instead of the live
+// kernel ruleset.
+type TracePacketRequest struct {
+	trace.Packet
+	Candidate bool `json:"candidate,omitempty"`
+}
+
+// handleTracePacket traces a packet through the compiled nftables ruleset,
+// reporting every chain and rule it hits.
+// POST /api/debug/trace
+func (s *Server) handleTracePacket(w http.ResponseWriter, r *http.Request) {
+	var req TracePacketRequest
+	if !BindJSON(w, r, &req) {
+		return
+	}
+
+	var res *trace.Result
+	var err error
+	if req.Candidate {
+		s.configMu.RLock()
+		cfg := s.Config
+		s.configMu.RUnlock()
+		if cfg == nil {
+			WriteErrorCtx(w, r, http.StatusServiceUnavailable, "No configuration loaded")
+			return
+		}
+		var tracer *trace.Tracer
+		if tracer, err = trace.NewOfflineTracer(cfg); err == nil {
+			res, err = tracer.Trace(req.Packet)
+		}
+	} else {
+		if s.client == nil {
+			WriteErrorCtx(w, r, http.StatusServiceUnavailable, "Control plane not connected")
+			return
+		}
+		res, err = s.client.TracePacket(req.Packet)
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.GetKind(err) == errors.KindValidation {
+			status = http.StatusBadRequest
+		}
+		WriteErrorCtx(w, r, status, err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, res)
+}
+
 // --- Packet Capture ---
 
 type CaptureRequest struct {
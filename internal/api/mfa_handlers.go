@@ -0,0 +1,329 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"grimm.is/flywall/internal/auth"
+)
+
+// --- Second Factor Handlers ---
+//
+// These endpoints authenticate with the session cookie directly rather than
+// through s.require, because they must accept sessions that are still waiting
+// for a second factor. A pending session is only usable here.
+
+// mfaSession resolves the request's session, pending or not. It writes the
+// error response and returns false if there is none.
+func (s *Server) mfaSession(w http.ResponseWriter, r *http.Request) (auth.MFAStore, *auth.User, string, bool) {
+	store, ok := s.authStore.(auth.MFAStore)
+	if !ok {
+		WriteErrorCtx(w, r, http.StatusNotImplemented, "Second factors not supported")
+		return nil, nil, "", false
+	}
+
+	// Rate limiting: 10 attempts per minute per IP
+	clientIP := getClientIP(r)
+	if !s.rateLimiter.Allow("mfa:"+clientIP, 10, time.Minute) {
+		s.logger.Warn("Rate limit exceeded for MFA", "ip", clientIP)
+		WriteErrorCtx(w, r, http.StatusTooManyRequests, "Too many attempts. Please try again later.")
+		return nil, nil, "", false
+	}
+
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		WriteErrorCtx(w, r, http.StatusUnauthorized, "Authentication required")
+		return nil, nil, "", false
+	}
+	user, _, err := store.ValidatePendingSession(cookie.Value)
+	if err != nil {
+		WriteErrorCtx(w, r, http.StatusUnauthorized, "Authentication required")
+		return nil, nil, "", false
+	}
+	return store, user, cookie.Value, true
+}
+
+// mfaMethods lists the second factors a pending session can be completed with.
+func mfaMethods(st *auth.MFAStatus) []string {
+	methods := []string{}
+	if st.TOTP {
+		methods = append(methods, "totp")
+	}
+	if len(st.SecurityKeys) > 0 {
+		methods = append(methods, "webauthn")
+	}
+	if st.RecoveryCodes > 0 {
+		methods = append(methods, "recovery")
+	}
+	return methods
+}
+
+// writePendingLogin responds to a password login that still needs a second
+// factor. The pending session gets a cookie and CSRF token so the browser can
+// call the MFA endpoints, but nothing else accepts it.
+func (s *Server) writePendingLogin(w http.ResponseWriter, r *http.Request, user *auth.User, sess *auth.Session) {
+	csrfToken, err := s.csrfManager.GenerateToken(sess.Token)
+	if err != nil {
+		s.logger.Error("Failed to generate CSRF token", "error", err)
+	}
+	auth.SetSessionCookie(w, r, sess)
+
+	resp := map[string]interface{}{
+		"authenticated": false,
+		"username":      user.Username,
+		"mfa":           sess.MFA,
+		"csrf_token":    csrfToken,
+	}
+	if store, ok := s.authStore.(auth.MFAStore); ok {
+		if st, err := store.MFAStatus(user.Username); err == nil {
+			resp["mfa_methods"] = mfaMethods(st)
+			resp["webauthn_available"] = st.WebAuthnAvailable
+		}
+	}
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+// completeMFA swaps the pending session for the promoted one and writes the
+// same response as a successful password login.
+func (s *Server) completeMFA(w http.ResponseWriter, r *http.Request, oldToken string, user *auth.User, sess *auth.Session, extra map[string]interface{}) {
+	s.csrfManager.DeleteToken(oldToken)
+	csrfToken, err := s.csrfManager.GenerateToken(sess.Token)
+	if err != nil {
+		s.logger.Error("Failed to generate CSRF token", "error", err)
+	}
+	auth.SetSessionCookie(w, r, sess)
+	s.rateLimiter.Reset(getClientIP(r))
+
+	resp := map[string]interface{}{
+		"authenticated": true,
+		"username":      user.Username,
+		"role":          user.Role,
+		"csrf_token":    csrfToken,
+	}
+	for k, v := range extra {
+		resp[k] = v
+	}
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+// mfaFailed logs a rejected second factor and counts it toward the IP's
+// fail2ban-style block.
+func (s *Server) mfaFailed(w http.ResponseWriter, r *http.Request, user *auth.User, method string, err error) {
+	clientIP := getClientIP(r)
+	s.logger.Warn("Failed second factor", "username", user.Username, "method", method, "ip", clientIP, "error", err)
+	if s.security != nil {
+		if blockErr := s.security.RecordFailedAttempt(clientIP, "failed_mfa", 5, 5*time.Minute); blockErr != nil {
+			s.logger.Warn("Failed to record attempt", "error", blockErr)
+		}
+	}
+	WriteErrorCtx(w, r, http.StatusUnauthorized, "Invalid second factor")
+}
+
+// handleMFAStatus returns the caller's enrolled second factors
+func (s *Server) handleMFAStatus(w http.ResponseWriter, r *http.Request) {
+	store, user, _, ok := s.mfaSession(w, r)
+	if !ok {
+		return
+	}
+	st, err := store.MFAStatus(user.Username)
+	if err != nil {
+		WriteErrorCtx(w, r, http.StatusNotFound, "User not found")
+		return
+	}
+	WriteJSON(w, http.StatusOK, st)
+}
+
+// handleMFAVerifyTOTP completes a login with an authenticator app code
+func (s *Server) handleMFAVerifyTOTP(w http.ResponseWriter, r *http.Request) {
+	store, user, token, ok := s.mfaSession(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	if !BindJSON(w, r, &req) {
+		return
+	}
+	sess, err := store.VerifyTOTP(token, req.Code)
+	if err != nil {
+		s.mfaFailed(w, r, user, "totp", err)
+		return
+	}
+	s.logger.Info("Successful login", "username", user.Username, "ip", getClientIP(r), "mfa", "totp")
+	s.completeMFA(w, r, token, user, sess, nil)
+}
+
+// handleMFAVerifyRecovery completes a login with a single-use recovery code
+func (s *Server) handleMFAVerifyRecovery(w http.ResponseWriter, r *http.Request) {
+	store, user, token, ok := s.mfaSession(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	if !BindJSON(w, r, &req) {
+		return
+	}
+	sess, err := store.VerifyRecoveryCode(token, req.Code)
+	if err != nil {
+		s.mfaFailed(w, r, user, "recovery", err)
+		return
+	}
+	s.logger.Info("Successful login", "username", user.Username, "ip", getClientIP(r), "mfa", "recovery")
+	s.audit(r, "auth.mfa_recovery", "user "+user.Username+" signed in with a recovery code", map[string]interface{}{
+		"username": user.Username,
+	})
+	s.completeMFA(w, r, token, user, sess, nil)
+}
+
+// handleMFAWebAuthnLoginBegin issues a security key challenge
+func (s *Server) handleMFAWebAuthnLoginBegin(w http.ResponseWriter, r *http.Request) {
+	store, _, token, ok := s.mfaSession(w, r)
+	if !ok {
+		return
+	}
+	opts, err := store.BeginWebAuthnLogin(token)
+	if err != nil {
+		WriteErrorCtx(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	WriteJSON(w, http.StatusOK, opts)
+}
+
+// handleMFAWebAuthnLoginFinish completes a login with a security key assertion
+func (s *Server) handleMFAWebAuthnLoginFinish(w http.ResponseWriter, r *http.Request) {
+	store, user, token, ok := s.mfaSession(w, r)
+	if !ok {
+		return
+	}
+	var resp auth.CredentialResponse
+	if !BindJSON(w, r, &resp) {
+		return
+	}
+	sess, err := store.FinishWebAuthnLogin(token, &resp)
+	if err != nil {
+		s.mfaFailed(w, r, user, "webauthn", err)
+		return
+	}
+	s.logger.Info("Successful login", "username", user.Username, "ip", getClientIP(r), "mfa", "webauthn")
+	s.completeMFA(w, r, token, user, sess, nil)
+}
+
+// handleMFATOTPEnroll starts authenticator app enrollment
+func (s *Server) handleMFATOTPEnroll(w http.ResponseWriter, r *http.Request) {
+	store, _, token, ok := s.mfaSession(w, r)
+	if !ok {
+		return
+	}
+	secret, uri, err := store.BeginTOTPEnrollment(token)
+	if err != nil {
+		WriteErrorCtx(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]string{
+		"secret": secret,
+		"uri":    uri,
+	})
+}
+
+// handleMFATOTPConfirm activates TOTP once the user proves they can generate
+// codes, and returns a fresh set of recovery codes.
+func (s *Server) handleMFATOTPConfirm(w http.ResponseWriter, r *http.Request) {
+	store, user, token, ok := s.mfaSession(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	if !BindJSON(w, r, &req) {
+		return
+	}
+	codes, sess, err := store.ConfirmTOTPEnrollment(token, req.Code)
+	if err != nil {
+		s.mfaFailed(w, r, user, "totp", err)
+		return
+	}
+
+	s.audit(r, "auth.mfa_enroll", "user "+user.Username+" enrolled an authenticator app", map[string]interface{}{
+		"username": user.Username,
+		"method":   "totp",
+	})
+
+	s.completeMFA(w, r, token, user, sess, map[string]interface{}{
+		"recovery_codes": codes,
+	})
+}
+
+// handleMFAWebAuthnRegisterBegin starts security key registration
+func (s *Server) handleMFAWebAuthnRegisterBegin(w http.ResponseWriter, r *http.Request) {
+	store, _, token, ok := s.mfaSession(w, r)
+	if !ok {
+		return
+	}
+	opts, err := store.BeginWebAuthnRegistration(token)
+	if err != nil {
+		WriteErrorCtx(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	WriteJSON(w, http.StatusOK, opts)
+}
+
+// handleMFAWebAuthnRegisterFinish stores a new security key
+func (s *Server) handleMFAWebAuthnRegisterFinish(w http.ResponseWriter, r *http.Request) {
+	store, user, token, ok := s.mfaSession(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Name       string                  `json:"name"`
+		Credential auth.CredentialResponse `json:"credential"`
+	}
+	if !BindJSON(w, r, &req) {
+		return
+	}
+	sess, err := store.FinishWebAuthnRegistration(token, req.Name, &req.Credential)
+	if err != nil {
+		WriteErrorCtx(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.audit(r, "auth.mfa_enroll", "user "+user.Username+" registered a security key", map[string]interface{}{
+		"username": user.Username,
+		"method":   "webauthn",
+		"name":     req.Name,
+	})
+
+	s.completeMFA(w, r, token, user, sess, nil)
+}
+
+// handleAdminResetMFA removes all of another user's second factors, e.g.
+// after a lost device. The user must enroll again if their role requires it.
+func (s *Server) handleAdminResetMFA(w http.ResponseWriter, r *http.Request) {
+	store, ok := s.authStore.(auth.MFAStore)
+	if !ok {
+		WriteErrorCtx(w, r, http.StatusNotImplemented, "Second factors not supported")
+		return
+	}
+
+	targetUsername := r.PathValue("username")
+	if targetUsername == "" {
+		WriteErrorCtx(w, r, http.StatusBadRequest, "Username required")
+		return
+	}
+
+	if err := store.ResetMFA(targetUsername); err != nil {
+		WriteErrorCtx(w, r, http.StatusNotFound, "User not found")
+		return
+	}
+
+	s.audit(r, "auth.mfa_reset", "admin reset second factors for user "+targetUsername, map[string]interface{}{
+		"target_user": targetUsername,
+	})
+
+	SuccessResponse(w)
+}
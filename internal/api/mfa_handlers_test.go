@@ -0,0 +1,91 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"grimm.is/flywall/internal/auth"
+	"grimm.is/flywall/internal/config"
+)
+
+func TestLogin_MFAEnrollRequired(t *testing.T) {
+	store, err := auth.NewStore(filepath.Join(t.TempDir(), "auth.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateUser("admin", "ProductionPassword123!", auth.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := NewServer(ServerOptions{
+		Config: &config.Config{
+			API: &config.APIConfig{
+				RequireAuth: true,
+				MFA:         &config.MFAConfig{RequiredRoles: []string{"admin"}},
+			},
+		},
+		AuthStore: store,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	handler := srv.Handler()
+
+	req := httptest.NewRequest("POST", "/api/auth/login",
+		strings.NewReader(`{"username":"admin","password":"ProductionPassword123!"}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Login failed: %d Body: %s", w.Code, w.Body.String())
+	}
+
+	var login map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &login)
+	if login["authenticated"] != false || login["mfa"] != auth.MFAEnroll {
+		t.Fatalf("Expected pending enrollment, got %v", login)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) == 0 {
+		t.Fatal("Expected a session cookie for the pending session")
+	}
+
+	// The pending session is not accepted by protected routes
+	req = httptest.NewRequest("GET", "/api/users", nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Protected route with pending session: got %d, want 401", w.Code)
+	}
+
+	// Status reports the pending step so the UI can resume it
+	req = httptest.NewRequest("GET", "/api/auth/status", nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	var status map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &status)
+	if status["authenticated"] != false || status["mfa"] != auth.MFAEnroll {
+		t.Errorf("Expected pending status, got %v", status)
+	}
+
+	// But it can reach the MFA endpoints
+	req = httptest.NewRequest("GET", "/api/auth/mfa", nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("MFA status failed: %d Body: %s", w.Code, w.Body.String())
+	}
+	var st auth.MFAStatus
+	json.Unmarshal(w.Body.Bytes(), &st)
+	if !st.Required || st.TOTP {
+		t.Errorf("Unexpected MFA status: %+v", st)
+	}
+}
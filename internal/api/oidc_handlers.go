@@ -0,0 +1,123 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"grimm.is/flywall/internal/auth"
+)
+
+// --- OIDC Single Sign-On Handlers ---
+
+// oidcStateCookie binds an in-flight SSO login to the browser that started it.
+const oidcStateCookie = "oidc_state"
+
+// handleOIDCLogin redirects the browser to the identity provider.
+func (s *Server) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if s.oidc == nil {
+		WriteErrorCtx(w, r, http.StatusNotFound, "Single sign-on not configured")
+		return
+	}
+
+	clientIP := getClientIP(r)
+	if !s.rateLimiter.Allow("oidc:"+clientIP, 10, time.Minute) {
+		WriteErrorCtx(w, r, http.StatusTooManyRequests, "Too many login attempts. Please try again later.")
+		return
+	}
+
+	authURL, state, err := s.oidc.AuthCodeURL(r.Context())
+	if err != nil {
+		s.logger.Error("OIDC login failed", "error", err)
+		WriteErrorCtx(w, r, http.StatusBadGateway, "Identity provider unavailable")
+		return
+	}
+
+	// Lax, not Strict: the callback is a cross-site navigation from the IdP
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc",
+		MaxAge:   600,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// handleOIDCCallback completes the SSO login and issues a session.
+func (s *Server) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if s.oidc == nil {
+		WriteErrorCtx(w, r, http.StatusNotFound, "Single sign-on not configured")
+		return
+	}
+
+	clientIP := getClientIP(r)
+	q := r.URL.Query()
+	state := q.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	validState := err == nil && state != "" && cookie.Value == state
+
+	if idpErr := q.Get("error"); idpErr != "" {
+		s.logger.Warn("OIDC login denied by provider", "error", idpErr, "description", q.Get("error_description"), "ip", clientIP)
+		if validState {
+			s.oidc.Cancel(state)
+			http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/api/auth/oidc", MaxAge: -1})
+		}
+		http.Redirect(w, r, "/login?sso_error="+url.QueryEscape(idpErr), http.StatusFound)
+		return
+	}
+
+	if !validState {
+		WriteErrorCtx(w, r, http.StatusBadRequest, "Invalid login state")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/api/auth/oidc", MaxAge: -1})
+
+	sess, user, err := s.oidc.Exchange(r.Context(), state, q.Get("code"))
+	if err != nil {
+		s.logger.Warn("Failed SSO login", "error", err, "ip", clientIP)
+		if s.security != nil {
+			if blockErr := s.security.RecordFailedAttempt(clientIP, "failed_login", 5, 5*time.Minute); blockErr != nil {
+				s.logger.Warn("Failed to record attempt", "error", blockErr)
+			}
+		}
+		http.Redirect(w, r, "/login?sso_error=access_denied", http.StatusFound)
+		return
+	}
+
+	s.logger.Info("Successful SSO login", "username", user.Username, "role", user.Role, "ip", clientIP)
+
+	if _, err := s.csrfManager.GenerateToken(sess.Token); err != nil {
+		s.logger.Error("Failed to generate CSRF token", "error", err)
+	}
+
+	auth.SetSessionCookie(w, r, sess)
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// oidcEnabled reports whether SSO login should be offered.
+func (s *Server) oidcEnabled() bool {
+	return s.oidc != nil
+}
+
+// newOIDCProvider builds the SSO provider from config, if enabled.
+func (s *Server) newOIDCProvider() *auth.OIDCProvider {
+	if s.Config == nil || s.Config.API == nil || s.Config.API.OIDC == nil || !s.Config.API.OIDC.Enabled {
+		return nil
+	}
+	if s.authStore == nil {
+		s.logger.Warn("OIDC configured but no auth store available; single sign-on disabled")
+		return nil
+	}
+	cfg := s.Config.API.OIDC
+	if cfg.Issuer == "" || cfg.ClientID == "" || !strings.HasPrefix(cfg.RedirectURL, "http") {
+		s.logger.Warn("OIDC configuration incomplete; single sign-on disabled")
+		return nil
+	}
+	return auth.NewOIDCProvider(cfg, s.authStore)
+}
@@ -0,0 +1,59 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"grimm.is/flywall/internal/auth"
+	"grimm.is/flywall/internal/config"
+)
+
+func TestOIDCCallback_ProviderError(t *testing.T) {
+	store, err := auth.NewStore(filepath.Join(t.TempDir(), "auth.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := NewServer(ServerOptions{
+		Config: &config.Config{
+			API: &config.APIConfig{
+				RequireAuth: true,
+				OIDC: &config.OIDCConfig{
+					Enabled:     true,
+					Issuer:      "https://idp.example.com",
+					ClientID:    "flywall",
+					RedirectURL: "https://fw.example.com/api/auth/oidc/callback",
+				},
+			},
+		},
+		AuthStore: store,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	// The IdP's error value must not add parameters to the login page
+	req := httptest.NewRequest("GET", "/api/auth/oidc/callback?state=s1&error=access_denied%26next%3Dhttps://evil.example", nil)
+	req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: "s1"})
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusFound {
+		t.Fatalf("Expected redirect, got %d", w.Code)
+	}
+	if got, want := w.Header().Get("Location"), "/login?sso_error=access_denied%26next%3Dhttps%3A%2F%2Fevil.example"; got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+	cleared := false
+	for _, c := range w.Result().Cookies() {
+		if c.Name == oidcStateCookie && c.MaxAge < 0 {
+			cleared = true
+		}
+	}
+	if !cleared {
+		t.Error("Expected the state cookie to be cleared")
+	}
+}
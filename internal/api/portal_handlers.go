@@ -0,0 +1,100 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"grimm.is/flywall/internal/ctlplane"
+	"grimm.is/flywall/internal/services/portal"
+)
+
+// handleGetPortalSessions returns the clients logged in to the captive portal
+// GET /api/portal/sessions
+func (s *Server) handleGetPortalSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := s.client.GetPortalSessions()
+	if err != nil {
+		WriteErrorCtx(w, r, http.StatusInternalServerError, "Failed to get portal sessions: "+err.Error())
+		return
+	}
+
+	// Ensure empty list not null
+	if sessions == nil {
+		sessions = []portal.Session{}
+	}
+
+	WriteJSON(w, http.StatusOK, sessions)
+}
+
+// handleRevokePortalSession logs a client out of the captive portal
+// DELETE /api/portal/sessions/{mac}
+func (s *Server) handleRevokePortalSession(w http.ResponseWriter, r *http.Request) {
+	mac := r.PathValue("mac")
+	if mac == "" {
+		WriteErrorCtx(w, r, http.StatusBadRequest, "MAC address is required")
+		return
+	}
+
+	if err := s.client.RevokePortalSession(mac); err != nil {
+		WriteErrorCtx(w, r, http.StatusBadRequest, "Failed to revoke session: "+err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
+
+// handleGetPortalVouchers returns the issued captive portal vouchers
+// GET /api/portal/vouchers
+func (s *Server) handleGetPortalVouchers(w http.ResponseWriter, r *http.Request) {
+	vouchers, err := s.client.GetPortalVouchers()
+	if err != nil {
+		WriteErrorCtx(w, r, http.StatusInternalServerError, "Failed to get vouchers: "+err.Error())
+		return
+	}
+
+	// Ensure empty list not null
+	if vouchers == nil {
+		vouchers = []portal.Voucher{}
+	}
+
+	WriteJSON(w, http.StatusOK, vouchers)
+}
+
+// handleCreatePortalVouchers issues a batch of vouchers
+// POST /api/portal/vouchers {"count": 20, "duration": "24h", "note": "conference"}
+func (s *Server) handleCreatePortalVouchers(w http.ResponseWriter, r *http.Request) {
+	var req ctlplane.CreatePortalVouchersArgs
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorCtx(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+
+	vouchers, err := s.client.CreatePortalVouchers(req.Count, req.Duration, req.Note)
+	if err != nil {
+		WriteErrorCtx(w, r, http.StatusBadRequest, "Failed to create vouchers: "+err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, vouchers)
+}
+
+// handleDeletePortalVoucher withdraws a voucher and ends the session it granted
+// DELETE /api/portal/vouchers/{code}
+func (s *Server) handleDeletePortalVoucher(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	if code == "" {
+		WriteErrorCtx(w, r, http.StatusBadRequest, "Voucher code is required")
+		return
+	}
+
+	if err := s.client.DeletePortalVoucher(code); err != nil {
+		WriteErrorCtx(w, r, http.StatusNotFound, "Failed to delete voucher: "+err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
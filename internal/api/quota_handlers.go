@@ -0,0 +1,49 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"grimm.is/flywall/internal/ctlplane"
+	"grimm.is/flywall/internal/quota"
+)
+
+// handleGetQuotas returns usage against every device quota counter
+// GET /api/quotas
+func (s *Server) handleGetQuotas(w http.ResponseWriter, r *http.Request) {
+	status, err := s.client.GetQuotaStatus()
+	if err != nil {
+		WriteErrorCtx(w, r, http.StatusInternalServerError, "Failed to get quota status: "+err.Error())
+		return
+	}
+
+	// Ensure empty list not null
+	if status == nil {
+		status = []quota.Status{}
+	}
+
+	WriteJSON(w, http.StatusOK, status)
+}
+
+// handleResetQuota restarts a quota's counters and lifts enforcement
+// POST /api/quotas/reset {"quota": "kids", "subject": "aa:bb:cc:dd:ee:ff"}
+func (s *Server) handleResetQuota(w http.ResponseWriter, r *http.Request) {
+	var req ctlplane.ResetQuotaArgs
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorCtx(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Quota == "" {
+		WriteErrorCtx(w, r, http.StatusBadRequest, "Quota name is required")
+		return
+	}
+
+	if err := s.client.ResetQuota(req.Quota, req.Subject); err != nil {
+		WriteErrorCtx(w, r, http.StatusBadRequest, "Failed to reset quota: "+err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]string{"status": "success"})
+}
@@ -0,0 +1,16 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package api
+
+import (
+	"net/http"
+
+	"grimm.is/flywall/internal/auth"
+)
+
+// --- Role Handlers ---
+
+// handleGetRoles returns the built-in and custom roles users can be assigned.
+func (s *Server) handleGetRoles(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, auth.ListRoles())
+}
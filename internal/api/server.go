@@ -88,7 +88,8 @@ type Server struct {
 	client          ctlplane.ControlPlaneClient // RPC client for control plane communication
 	authStore       auth.AuthStore
 	authMw          *auth.Middleware
-	apiKeyManager   *APIKeyManager // Use local type alias/import
+	oidc            *auth.OIDCProvider // Optional SSO provider
+	apiKeyManager   *APIKeyManager     // Use local type alias/import
 	logger          *logging.Logger
 	collector       *metrics.Collector
 	startTime       time.Time
@@ -175,6 +176,12 @@ func NewServer(opts ServerOptions) (*Server, error) {
 		// We need to ensure 'require' doesn't panic.
 	}
 
+	// Custom roles must be registered before SSO maps groups to them
+	s.loadAuthConfig()
+
+	// Single sign-on (requires an auth store for JIT provisioning)
+	s.oidc = s.newOIDCProvider()
+
 	// Start WebSocket manager
 	s.wsManager = NewWSManager(s.client, s.checkPendingStatus)
 	if s.runtime != nil {
@@ -226,6 +233,19 @@ func (s *Server) initRoutes() {
 	mux.HandleFunc("POST /api/auth/login", s.handleLogin)
 	mux.HandleFunc("POST /api/auth/logout", s.handleLogout)
 	mux.HandleFunc("GET /api/auth/status", s.handleAuthStatus)
+	mux.HandleFunc("GET /api/auth/whoami", s.handleWhoAmI)
+	mux.HandleFunc("GET /api/auth/oidc/login", s.handleOIDCLogin)
+	mux.HandleFunc("GET /api/auth/oidc/callback", s.handleOIDCCallback)
+	// Second factors: authenticated by the (possibly pending) session cookie
+	mux.HandleFunc("GET /api/auth/mfa", s.handleMFAStatus)
+	mux.HandleFunc("POST /api/auth/mfa/totp", s.handleMFAVerifyTOTP)
+	mux.HandleFunc("POST /api/auth/mfa/recovery", s.handleMFAVerifyRecovery)
+	mux.HandleFunc("POST /api/auth/mfa/webauthn/begin", s.handleMFAWebAuthnLoginBegin)
+	mux.HandleFunc("POST /api/auth/mfa/webauthn/finish", s.handleMFAWebAuthnLoginFinish)
+	mux.HandleFunc("POST /api/auth/mfa/totp/enroll", s.handleMFATOTPEnroll)
+	mux.HandleFunc("POST /api/auth/mfa/totp/confirm", s.handleMFATOTPConfirm)
+	mux.HandleFunc("POST /api/auth/mfa/webauthn/register/begin", s.handleMFAWebAuthnRegisterBegin)
+	mux.HandleFunc("POST /api/auth/mfa/webauthn/register/finish", s.handleMFAWebAuthnRegisterFinish)
 	mux.HandleFunc("GET /api/setup/status", s.handleSetupStatus)
 	mux.HandleFunc("POST /api/setup/create-admin", s.handleCreateAdmin)
 	mux.HandleFunc("GET /api/status", s.handleStatus) // Health status - public for monitoring
@@ -260,9 +280,9 @@ func (s *Server) initRoutes() {
 	// General Config
 	mux.Handle("GET /api/config", s.require(storage.PermReadConfig, s.requireControlPlane(s.handleConfig)))
 	mux.Handle("POST /api/config", s.require(storage.PermWriteConfig, s.requireControlPlane(s.handleUpdateConfig)))
-	mux.Handle("POST /api/config/apply", s.require(storage.PermWriteConfig, s.requireControlPlane(s.handleApplyConfig)))
-	mux.Handle("POST /api/config/safe-apply", s.require(storage.PermWriteConfig, s.requireControlPlane(s.handleSafeApply)))
-	mux.Handle("POST /api/config/confirm", s.require(storage.PermWriteConfig, s.requireControlPlane(s.handleConfirmApply)))
+	mux.Handle("POST /api/config/apply", s.require(storage.PermApplyConfig, s.requireControlPlane(s.handleApplyConfig)))
+	mux.Handle("POST /api/config/safe-apply", s.require(storage.PermApplyConfig, s.requireControlPlane(s.handleSafeApply)))
+	mux.Handle("POST /api/config/confirm", s.require(storage.PermApplyConfig, s.requireControlPlane(s.handleConfirmApply)))
 	mux.Handle("GET /api/config/pending", s.require(storage.PermReadConfig, s.requireControlPlane(s.handlePendingApply)))
 	mux.Handle("POST /api/config/ip-forwarding", s.require(storage.PermWriteConfig, s.requireControlPlane(s.handleSetIPForwarding)))
 	mux.Handle("POST /api/config/settings", s.require(storage.PermWriteConfig, s.requireControlPlane(s.handleSystemSettings)))
@@ -278,15 +298,17 @@ func (s *Server) initRoutes() {
 	mux.Handle("GET /api/traffic", s.require(storage.PermReadMetrics, http.HandlerFunc(s.handleTraffic)))
 
 	// User Management
-	mux.Handle("GET /api/users", s.require(storage.PermAdminSystem, http.HandlerFunc(s.handleGetUsers)))
-	mux.Handle("POST /api/users", s.require(storage.PermAdminSystem, http.HandlerFunc(s.handleCreateUser)))
-	mux.Handle("GET /api/users/", s.require(storage.PermAdminSystem, http.HandlerFunc(s.handleGetUser)))
-	mux.Handle("PUT /api/users/", s.require(storage.PermAdminSystem, http.HandlerFunc(s.handleUpdateUser)))
-	mux.Handle("DELETE /api/users/", s.require(storage.PermAdminSystem, http.HandlerFunc(s.handleDeleteUser)))
+	mux.Handle("GET /api/users", s.require(storage.PermReadUsers, http.HandlerFunc(s.handleGetUsers)))
+	mux.Handle("POST /api/users", s.require(storage.PermWriteUsers, http.HandlerFunc(s.handleCreateUser)))
+	mux.Handle("GET /api/users/", s.require(storage.PermReadUsers, http.HandlerFunc(s.handleGetUser)))
+	mux.Handle("PUT /api/users/", s.require(storage.PermWriteUsers, http.HandlerFunc(s.handleUpdateUser)))
+	mux.Handle("DELETE /api/users/", s.require(storage.PermWriteUsers, http.HandlerFunc(s.handleDeleteUser)))
+	mux.Handle("GET /api/roles", s.require(storage.PermReadUsers, http.HandlerFunc(s.handleGetRoles)))
 
 	// Password Management
 	mux.Handle("PUT /api/auth/password", s.require(storage.PermAll, http.HandlerFunc(s.handleChangePassword)))
-	mux.Handle("PUT /api/users/{username}/password", s.require(storage.PermAdminSystem, http.HandlerFunc(s.handleAdminResetPassword)))
+	mux.Handle("PUT /api/users/{username}/password", s.require(storage.PermWriteUsers, http.HandlerFunc(s.handleAdminResetPassword)))
+	mux.Handle("DELETE /api/users/{username}/mfa", s.require(storage.PermWriteUsers, http.HandlerFunc(s.handleAdminResetMFA)))
 
 	// Interface management
 	mux.Handle("GET /api/interfaces", s.require(storage.PermReadConfig, http.HandlerFunc(s.handleInterfaces))) // Using Config perms for now
@@ -307,7 +329,14 @@ func (s *Server) initRoutes() {
 	// Alerts
 	mux.Handle("GET /api/alerts/history", s.require(storage.PermReadConfig, http.HandlerFunc(s.HandleGetAlertHistory)))
 	mux.Handle("GET /api/alerts/rules", s.require(storage.PermReadConfig, http.HandlerFunc(s.HandleGetAlertRules)))
+	mux.Handle("GET /api/alerts/channels", s.require(storage.PermReadConfig, http.HandlerFunc(s.HandleGetAlertChannels)))
 	mux.Handle("POST /api/alerts/rules", s.require(storage.PermWriteConfig, http.HandlerFunc(s.HandleUpdateAlertRule)))
+	mux.Handle("GET /api/alerts", s.require(storage.PermReadConfig, http.HandlerFunc(s.HandleGetAlerts)))
+	mux.Handle("POST /api/alerts/{id}/ack", s.require(storage.PermWriteConfig, http.HandlerFunc(s.HandleAckAlert)))
+	mux.Handle("POST /api/alerts/{id}/resolve", s.require(storage.PermWriteConfig, http.HandlerFunc(s.HandleResolveAlert)))
+	mux.Handle("GET /api/alerts/silences", s.require(storage.PermReadConfig, http.HandlerFunc(s.HandleGetSilences)))
+	mux.Handle("POST /api/alerts/silences", s.require(storage.PermWriteConfig, http.HandlerFunc(s.HandleCreateSilence)))
+	mux.Handle("DELETE /api/alerts/silences/{id}", s.require(storage.PermWriteConfig, http.HandlerFunc(s.HandleDeleteSilence)))
 
 	// Services
 	mux.Handle("GET /api/services", s.require(storage.PermReadConfig, http.HandlerFunc(s.handleServices)))
@@ -316,10 +345,10 @@ func (s *Server) initRoutes() {
 	mux.Handle("GET /api/network", s.require(storage.PermReadConfig, http.HandlerFunc(s.handleGetNetworkDevices)))
 
 	// Config Sections (CRUD handlers usually switch on method, so we keep generic path or would need to register GET/POST separately)
-	mux.Handle("GET /api/config/policies", s.require(storage.PermWriteFirewall, http.HandlerFunc(s.handleGetPolicies)))
-	mux.Handle("POST /api/config/policies", s.require(storage.PermWriteFirewall, http.HandlerFunc(s.handleUpdatePolicies)))
-	mux.Handle("GET /api/config/nat", s.require(storage.PermWriteFirewall, http.HandlerFunc(s.handleGetNAT)))
-	mux.Handle("POST /api/config/nat", s.require(storage.PermWriteFirewall, http.HandlerFunc(s.handleUpdateNAT)))
+	mux.Handle("GET /api/config/policies", s.require(storage.PermReadPolicies, http.HandlerFunc(s.handleGetPolicies)))
+	mux.Handle("POST /api/config/policies", s.require(storage.PermWritePolicies, http.HandlerFunc(s.handleUpdatePolicies)))
+	mux.Handle("GET /api/config/nat", s.require(storage.PermReadNAT, http.HandlerFunc(s.handleGetNAT)))
+	mux.Handle("POST /api/config/nat", s.require(storage.PermWriteNAT, http.HandlerFunc(s.handleUpdateNAT)))
 	mux.Handle("GET /api/config/ipsets", s.require(storage.PermWriteFirewall, http.HandlerFunc(s.handleGetIPSets)))
 	mux.Handle("POST /api/config/ipsets", s.require(storage.PermWriteFirewall, http.HandlerFunc(s.handleUpdateIPSets)))
 	mux.Handle("GET /api/config/dhcp", s.require(storage.PermWriteDHCP, http.HandlerFunc(s.handleGetDHCP)))
@@ -343,20 +372,22 @@ func (s *Server) initRoutes() {
 	mux.Handle("POST /api/config/qos", s.require(storage.PermWriteFirewall, http.HandlerFunc(s.handleUpdateQoS)))
 	mux.Handle("GET /api/config/scheduler", s.require(storage.PermWriteConfig, http.HandlerFunc(s.handleGetSchedulerConfig)))
 	mux.Handle("POST /api/config/scheduler", s.require(storage.PermWriteConfig, http.HandlerFunc(s.handleUpdateSchedulerConfig)))
-	mux.Handle("GET /api/config/vpn", s.require(storage.PermWriteConfig, http.HandlerFunc(s.handleGetVPN)))
-	mux.Handle("POST /api/config/vpn", s.require(storage.PermWriteConfig, http.HandlerFunc(s.handleUpdateVPN)))
-	mux.Handle("POST /api/vpn/import", s.require(storage.PermWriteConfig, http.HandlerFunc(s.handleImportVPNConfig)))
+	mux.Handle("GET /api/config/vpn", s.require(storage.PermReadVPN, http.HandlerFunc(s.handleGetVPN)))
+	mux.Handle("POST /api/config/vpn", s.require(storage.PermWriteVPN, http.HandlerFunc(s.handleUpdateVPN)))
+	mux.Handle("POST /api/vpn/import", s.require(storage.PermWriteVPN, http.HandlerFunc(s.handleImportVPNConfig)))
 
 	// WireGuard API (key generation is stateless, other ops via config)
-	mux.Handle("POST /api/wireguard/generate-key", s.require(storage.PermWriteConfig, http.HandlerFunc(s.handleWireGuardGenerateKey)))
+	mux.Handle("POST /api/wireguard/generate-key", s.require(storage.PermWriteVPN, http.HandlerFunc(s.handleWireGuardGenerateKey)))
+	mux.Handle("POST /api/wireguard/provision", s.require(storage.PermWriteVPN, http.HandlerFunc(s.handleWireGuardProvision)))
+	mux.Handle("POST /api/wireguard/rotate", s.require(storage.PermWriteVPN, http.HandlerFunc(s.handleWireGuardRotate)))
 	mux.Handle("GET /api/config/mark_rules", s.require(storage.PermWriteConfig, http.HandlerFunc(s.handleGetMarkRules)))
 	mux.Handle("POST /api/config/mark_rules", s.require(storage.PermWriteConfig, http.HandlerFunc(s.handleUpdateMarkRules)))
 	mux.Handle("GET /api/config/uid_routing", s.require(storage.PermWriteConfig, http.HandlerFunc(s.handleGetUIDRouting)))
 	mux.Handle("POST /api/config/uid_routing", s.require(storage.PermWriteConfig, http.HandlerFunc(s.handleUpdateUIDRouting)))
 
 	// Reordering
-	mux.Handle("POST /api/policies/reorder", s.require(storage.PermWriteFirewall, http.HandlerFunc(s.handlePolicyReorder)))
-	mux.Handle("POST /api/rules/reorder", s.require(storage.PermWriteFirewall, http.HandlerFunc(s.handleRuleReorder)))
+	mux.Handle("POST /api/policies/reorder", s.require(storage.PermWritePolicies, http.HandlerFunc(s.handlePolicyReorder)))
+	mux.Handle("POST /api/rules/reorder", s.require(storage.PermWritePolicies, http.HandlerFunc(s.handleRuleReorder)))
 
 	// ClearPath Policy Editor - Enriched Rules API
 	rulesHandler := NewRulesHandler(s, s.statsCollector, s.deviceLookup)
@@ -373,10 +404,10 @@ func (s *Server) initRoutes() {
 
 	// Flow Management
 	flowHandlers := NewFlowHandlers(s.client)
-	mux.Handle("GET /api/flows", s.require(storage.PermReadFirewall, s.requireControlPlane(http.HandlerFunc(flowHandlers.HandleGetFlows))))
-	mux.Handle("POST /api/flows/approve", s.require(storage.PermWriteFirewall, s.requireControlPlane(http.HandlerFunc(flowHandlers.HandleApprove))))
-	mux.Handle("POST /api/flows/deny", s.require(storage.PermWriteFirewall, s.requireControlPlane(http.HandlerFunc(flowHandlers.HandleDeny))))
-	mux.Handle("DELETE /api/flows", s.require(storage.PermWriteFirewall, s.requireControlPlane(http.HandlerFunc(flowHandlers.HandleDelete))))
+	mux.Handle("GET /api/flows", s.require(storage.PermReadLearning, s.requireControlPlane(http.HandlerFunc(flowHandlers.HandleGetFlows))))
+	mux.Handle("POST /api/flows/approve", s.require(storage.PermApplyLearning, s.requireControlPlane(http.HandlerFunc(flowHandlers.HandleApprove))))
+	mux.Handle("POST /api/flows/deny", s.require(storage.PermApplyLearning, s.requireControlPlane(http.HandlerFunc(flowHandlers.HandleDeny))))
+	mux.Handle("DELETE /api/flows", s.require(storage.PermWriteLearning, s.requireControlPlane(http.HandlerFunc(flowHandlers.HandleDelete))))
 
 	// System actions
 	mux.Handle("POST /api/system/reboot", s.require(storage.PermAdminSystem, http.HandlerFunc(s.handleReboot)))
@@ -423,7 +454,8 @@ func (s *Server) initRoutes() {
 	// Extended System Operations
 	mux.Handle("GET /api/system/stats", s.require(storage.PermAdminSystem, http.HandlerFunc(s.handleSystemStats)))
 	mux.Handle("GET /api/system/routes", s.require(storage.PermAdminSystem, http.HandlerFunc(s.handleSystemRoutes)))
-	mux.Handle("GET /api/vpn/status", s.require(storage.PermReadConfig, http.HandlerFunc(s.handleVPNStatus)))
+	mux.Handle("GET /api/routing/status", s.require(storage.PermReadConfig, http.HandlerFunc(s.handleRoutingStatus)))
+	mux.Handle("GET /api/vpn/status", s.require(storage.PermReadVPN, http.HandlerFunc(s.handleVPNStatus)))
 	mux.Handle("GET /api/replication/status", s.require(storage.PermReadConfig, http.HandlerFunc(s.handleReplicationStatus)))
 
 	// Import Wizard
@@ -432,6 +464,7 @@ func (s *Server) initRoutes() {
 
 	// Debug endpoints (Admin only)
 	mux.Handle("POST /api/debug/simulate-packet", s.require(storage.PermAdminSystem, http.HandlerFunc(s.handleSimulatePacket)))
+	mux.Handle("POST /api/debug/trace", s.require(storage.PermAdminSystem, http.HandlerFunc(s.handleTracePacket)))
 
 	// Debug - Capture
 	mux.Handle("POST /api/debug/capture", s.require(storage.PermAdminSystem, http.HandlerFunc(s.handleStartCapture)))
@@ -449,8 +482,10 @@ func (s *Server) initRoutes() {
 	mux.Handle("GET /api/runtime/containers", s.require(storage.PermReadConfig, http.HandlerFunc(s.getContainersHandler)))
 
 	// Learning Engine
-	mux.Handle("GET /api/learning/rules", s.require(storage.PermReadFirewall, http.HandlerFunc(s.handleLearningRules)))
-	mux.Handle("/api/learning/rules/", s.require(storage.PermReadFirewall, http.HandlerFunc(s.handleLearningRule)))
+	mux.Handle("GET /api/learning/rules", s.require(storage.PermReadLearning, http.HandlerFunc(s.handleLearningRules)))
+	mux.Handle("GET /api/learning/rules/", s.require(storage.PermReadLearning, http.HandlerFunc(s.handleLearningRule)))
+	mux.Handle("POST /api/learning/rules/", s.require(storage.PermApplyLearning, http.HandlerFunc(s.handleLearningRule)))
+	mux.Handle("DELETE /api/learning/rules/", s.require(storage.PermWriteLearning, http.HandlerFunc(s.handleLearningRule)))
 
 	// Device Management
 	mux.Handle("POST /api/devices/identity", s.require(storage.PermWriteConfig, http.HandlerFunc(s.handleUpdateDeviceIdentity)))
@@ -463,6 +498,21 @@ func (s *Server) initRoutes() {
 	mux.Handle("POST /api/groups", s.require(storage.PermWriteConfig, http.HandlerFunc(s.handleUpdateDeviceGroup)))
 	mux.Handle("DELETE /api/groups/", s.require(storage.PermWriteConfig, http.HandlerFunc(s.handleDeleteDeviceGroup)))
 
+	// Device Quotas
+	mux.Handle("GET /api/quotas", s.require(storage.PermReadConfig, http.HandlerFunc(s.handleGetQuotas)))
+	mux.Handle("POST /api/quotas/reset", s.require(storage.PermWriteConfig, http.HandlerFunc(s.handleResetQuota)))
+
+	// Captive Portal
+	mux.Handle("GET /api/portal/sessions", s.require(storage.PermReadConfig, http.HandlerFunc(s.handleGetPortalSessions)))
+	mux.Handle("DELETE /api/portal/sessions/{mac}", s.require(storage.PermWriteConfig, http.HandlerFunc(s.handleRevokePortalSession)))
+	mux.Handle("GET /api/portal/vouchers", s.require(storage.PermReadConfig, http.HandlerFunc(s.handleGetPortalVouchers)))
+	mux.Handle("POST /api/portal/vouchers", s.require(storage.PermWriteConfig, http.HandlerFunc(s.handleCreatePortalVouchers)))
+	mux.Handle("DELETE /api/portal/vouchers/{code}", s.require(storage.PermWriteConfig, http.HandlerFunc(s.handleDeletePortalVoucher)))
+
+	// Connection Tracking
+	mux.Handle("GET /api/firewall/connections", s.require(storage.PermReadConfig, http.HandlerFunc(s.handleGetConnections)))
+	mux.Handle("POST /api/firewall/connections/kill", s.require(storage.PermWriteConfig, http.HandlerFunc(s.handleKillConnections)))
+
 	// Staging & Diff
 	mux.Handle("GET /api/config/diff", s.require(storage.PermReadConfig, http.HandlerFunc(s.handleGetConfigDiff)))
 	mux.Handle("POST /api/config/discard", s.require(storage.PermWriteConfig, http.HandlerFunc(s.handleDiscardConfig)))
@@ -505,11 +555,30 @@ func (s *Server) syncConfig() error {
 	s.configMu.Lock()
 	s.Config = cfg
 	s.configMu.Unlock()
+	s.loadAuthConfig()
 
 	s.logger.Info("Synchronized local config cache from control plane")
 	return nil
 }
 
+// loadAuthConfig applies the custom roles and second-factor policy from the
+// current config. Invalid role definitions are logged and the previously
+// loaded roles are kept.
+func (s *Server) loadAuthConfig() {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+
+	if s.Config == nil || s.Config.API == nil {
+		return
+	}
+	if err := auth.LoadRoles(s.Config.API.Roles); err != nil {
+		s.logger.Error("Failed to load custom roles", "error", err)
+	}
+	if store, ok := s.authStore.(auth.MFAStore); ok {
+		store.SetMFAConfig(s.Config.API.MFA)
+	}
+}
+
 // require middleware ensures the control plane client is available
 func (s *Server) requireControlPlane(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -874,20 +943,7 @@ func (s *Server) require(perm storage.Permission, handler http.Handler) http.Han
 		s.configMu.RUnlock()
 
 		// 1. API Key Check
-		authHeader := r.Header.Get("Authorization")
-		var apiKeyStr string
-		if strings.HasPrefix(authHeader, "Bearer ") {
-			// Could be Session Token OR API Key.
-			token := strings.TrimPrefix(authHeader, "Bearer ")
-			if strings.HasPrefix(token, brand.APIKeyPrefixFull()) && s.apiKeyManager != nil {
-				apiKeyStr = token
-			}
-		} else if strings.HasPrefix(authHeader, "ApiKey ") {
-			apiKeyStr = strings.TrimPrefix(authHeader, "ApiKey ")
-		} else {
-			apiKeyStr = r.Header.Get("X-API-Key")
-		}
-
+		apiKeyStr := apiKeyFromRequest(r)
 		if apiKeyStr != "" && s.apiKeyManager != nil {
 			key, err := s.apiKeyManager.ValidateKey(apiKeyStr)
 			if err == nil {
@@ -924,8 +980,8 @@ func (s *Server) require(perm storage.Permission, handler http.Handler) http.Han
 			if cookie, err := r.Cookie("session"); err == nil {
 				if user, err := s.authStore.ValidateSession(cookie.Value); err == nil {
 					// Valid User Session found
-					requiredRole := s.permToRole(perm)
-					if user.Role.CanAccess(requiredRole) {
+					resource, verb := permToResource(perm)
+					if resource == "" || user.Role.Can(resource, verb) {
 						// Success! Inject user into context
 						ctx := context.WithValue(r.Context(), auth.UserContextKey, user)
 						protectedHandler.ServeHTTP(w, r.WithContext(ctx))
@@ -942,21 +998,49 @@ func (s *Server) require(perm storage.Permission, handler http.Handler) http.Han
 	})
 }
 
-// permToRole maps fine-grained permissions to coarse-grained user roles.
-// permToRole maps fine-grained permissions to coarse-grained user roles.
-func (s *Server) permToRole(perm storage.Permission) string {
-	// Admin permissions require Admin role
-	if strings.HasPrefix(string(perm), "admin:") {
-		return "admin"
+// hasPermission reports whether the caller that require() admitted also
+// holds perm. Requests let through with auth disabled carry no identity
+// and are allowed.
+func hasPermission(r *http.Request, perm storage.Permission) bool {
+	if key := GetAPIKey(r.Context()); key != nil {
+		return key.HasPermission(perm)
+	}
+	if user := auth.GetUserFromContext(r.Context()); user != nil {
+		resource, verb := permToResource(perm)
+		return resource == "" || user.Role.Can(resource, verb)
 	}
+	return true
+}
 
-	// Write permissions require Operator role (modify)
-	if strings.HasSuffix(string(perm), ":write") {
-		return "modify"
+// apiKeyFromRequest returns the API key a request presents, if any.
+func apiKeyFromRequest(r *http.Request) string {
+	authHeader := r.Header.Get("Authorization")
+	if strings.HasPrefix(authHeader, "Bearer ") {
+		// Could be Session Token OR API Key.
+		token := strings.TrimPrefix(authHeader, "Bearer ")
+		if strings.HasPrefix(token, brand.APIKeyPrefixFull()) {
+			return token
+		}
+		return ""
+	}
+	if strings.HasPrefix(authHeader, "ApiKey ") {
+		return strings.TrimPrefix(authHeader, "ApiKey ")
 	}
+	return r.Header.Get("X-API-Key")
+}
 
-	// Default/Read permissions require Viewer role (view)
-	return "view"
+// permToResource maps an API permission to the resource class and verb a
+// user role must be granted. Admin permissions map to writing the named
+// admin-only resource. An empty resource means any signed-in user.
+func permToResource(perm storage.Permission) (resource, verb string) {
+	if perm == storage.PermAll {
+		return "", ""
+	}
+	if rest, ok := strings.CutPrefix(string(perm), "admin:"); ok {
+		return rest, auth.VerbWrite
+	}
+	resource, verb, _ = strings.Cut(string(perm), ":")
+	return resource, verb
 }
 
 // Observatory History (Mock)
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"grimm.is/flywall/internal/api/storage"
 	"grimm.is/flywall/internal/auth"
 )
 
@@ -56,6 +57,13 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Password accepted but a second factor is still required
+	if sess.MFA != "" {
+		user, _ := s.authStore.GetUser(creds.Username)
+		s.writePendingLogin(w, r, user, sess)
+		return
+	}
+
 	// Successful login - log it
 	s.logger.Info("Successful login", "username", creds.Username, "ip", clientIP)
 
@@ -119,15 +127,38 @@ func (s *Server) handleAuthStatus(w http.ResponseWriter, r *http.Request) {
 		WriteJSON(w, http.StatusOK, map[string]interface{}{
 			"authenticated":  false,
 			"setup_required": !s.authStore.HasUsers(),
+			"sso_enabled":    s.oidcEnabled(),
 		})
 		return
 	}
 
 	user, err := s.authStore.ValidateSession(cookie.Value)
 	if err != nil {
+		// Report a login that is waiting for its second factor so the UI
+		// can resume it after a reload
+		if store, ok := s.authStore.(auth.MFAStore); ok {
+			if pending, sess, perr := store.ValidatePendingSession(cookie.Value); perr == nil && sess.MFA != "" {
+				csrfToken, _ := s.csrfManager.GetToken(cookie.Value)
+				resp := map[string]interface{}{
+					"authenticated":  false,
+					"username":       pending.Username,
+					"mfa":            sess.MFA,
+					"setup_required": false,
+					"sso_enabled":    s.oidcEnabled(),
+					"csrf_token":     csrfToken,
+				}
+				if st, serr := store.MFAStatus(pending.Username); serr == nil {
+					resp["mfa_methods"] = mfaMethods(st)
+					resp["webauthn_available"] = st.WebAuthnAvailable
+				}
+				WriteJSON(w, http.StatusOK, resp)
+				return
+			}
+		}
 		WriteJSON(w, http.StatusOK, map[string]interface{}{
 			"authenticated":  false,
 			"setup_required": !s.authStore.HasUsers(),
+			"sso_enabled":    s.oidcEnabled(),
 		})
 		return
 	}
@@ -147,6 +178,7 @@ func (s *Server) handleAuthStatus(w http.ResponseWriter, r *http.Request) {
 		"username":       user.Username,
 		"role":           user.Role,
 		"setup_required": false,
+		"sso_enabled":    s.oidcEnabled(),
 		"csrf_token":     csrfToken,
 	})
 }
@@ -213,6 +245,7 @@ func (s *Server) handleCreateAdmin(w http.ResponseWriter, r *http.Request) {
 		}
 		s.authStore = store
 		s.authMw = auth.NewMiddleware(store)
+		s.oidc = s.newOIDCProvider()
 	}
 
 	if err := s.authStore.CreateUser(creds.Username, creds.Password, auth.RoleAdmin); err != nil {
@@ -361,3 +394,47 @@ func (s *Server) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
 	}
 	SuccessResponse(w)
 }
+
+// WhoAmI describes the caller of a request: an API key and its permissions,
+// or a signed-in user and their role.
+type WhoAmI struct {
+	Type        string               `json:"type"` // "api_key", "user" or "anonymous"
+	Name        string               `json:"name,omitempty"`
+	Role        string               `json:"role,omitempty"`
+	Permissions []storage.Permission `json:"permissions,omitempty"`
+}
+
+// handleWhoAmI reports who the caller is authenticated as, so clients such
+// as the remote console can enforce the grants the API checks.
+func (s *Server) handleWhoAmI(w http.ResponseWriter, r *http.Request) {
+	s.configMu.RLock()
+	open := s.Config != nil && s.Config.API != nil && !s.Config.API.RequireAuth
+	s.configMu.RUnlock()
+	if open {
+		WriteJSON(w, http.StatusOK, WhoAmI{Type: "anonymous", Permissions: []storage.Permission{storage.PermAll}})
+		return
+	}
+
+	if keyStr := apiKeyFromRequest(r); keyStr != "" && s.apiKeyManager != nil {
+		key, err := s.apiKeyManager.ValidateKey(keyStr)
+		if err != nil {
+			if s.security != nil {
+				_ = s.security.RecordFailedAttempt(getClientIP(r), "invalid_api_key", 5, 5*time.Minute)
+			}
+			writeAuthError(w, http.StatusUnauthorized, "invalid api key")
+			return
+		}
+		WriteJSON(w, http.StatusOK, WhoAmI{Type: "api_key", Name: key.Name, Permissions: key.Permissions})
+		return
+	}
+
+	if s.authStore != nil {
+		if cookie, err := r.Cookie("session"); err == nil {
+			if user, err := s.authStore.ValidateSession(cookie.Value); err == nil {
+				WriteJSON(w, http.StatusOK, WhoAmI{Type: "user", Name: user.Username, Role: string(user.Role)})
+				return
+			}
+		}
+	}
+	writeAuthError(w, http.StatusUnauthorized, "authentication required (api key or user session)")
+}
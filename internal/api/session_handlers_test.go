@@ -11,6 +11,7 @@ import (
 	"strings"
 	"testing"
 
+	"grimm.is/flywall/internal/api/storage"
 	"grimm.is/flywall/internal/auth"
 	"grimm.is/flywall/internal/config"
 	"grimm.is/flywall/internal/logging"
@@ -208,3 +209,37 @@ func TestConcurrentAdminCreation_Regression(t *testing.T) {
 		t.Errorf("Got unexpected status codes: %d requests failed with non-403", otherCount)
 	}
 }
+
+func TestHandleWhoAmI(t *testing.T) {
+	manager := NewAPIKeyManager(storage.NewMemoryAPIKeyStore())
+	fullKey, _, err := manager.GenerateKey("console", []storage.Permission{storage.PermReadConfig, storage.PermApplyLearning})
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	server := &Server{
+		Config:        &config.Config{API: &config.APIConfig{RequireAuth: true}},
+		logger:        logging.New(logging.DefaultConfig()),
+		apiKeyManager: manager,
+	}
+
+	req, _ := http.NewRequest("GET", "/api/auth/whoami", nil)
+	req.Header.Set("X-API-Key", fullKey)
+	rr := httptest.NewRecorder()
+	server.handleWhoAmI(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned %d: %s", rr.Code, rr.Body.String())
+	}
+	var who WhoAmI
+	json.Unmarshal(rr.Body.Bytes(), &who)
+	if who.Type != "api_key" || who.Name != "console" || len(who.Permissions) != 2 {
+		t.Errorf("whoami = %+v, want the console key and its permissions", who)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/auth/whoami", nil)
+	req.Header.Set("X-API-Key", "invalid")
+	rr = httptest.NewRecorder()
+	server.handleWhoAmI(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("invalid key returned %d, want 401", rr.Code)
+	}
+}
@@ -85,6 +85,7 @@ func GenerateSpec() (*OpenAPI, error) {
 	registerSchema(spec, "Interface", config.Interface{})
 	registerSchema(spec, "Zone", config.Zone{})
 	registerSchema(spec, "Policy", config.Policy{})
+	registerSchema(spec, "PolicyRule", config.PolicyRule{})
 	registerSchema(spec, "NATRule", config.NATRule{})
 	registerSchema(spec, "DHCPServer", config.DHCPServer{})
 	// config.ProtocolConfig might not exist or be named differently, skip for now
@@ -111,11 +112,26 @@ func registerSchema(spec *OpenAPI, name string, t interface{}) {
 	spec.Components.Schemas[name] = schema
 }
 
+// portSpecType is documented explicitly because a bare "string" hides that
+// numbers, ranges and sets are all accepted.
+var portSpecType = reflect.TypeOf(config.PortSpec(""))
+
+func portSpecSchema() Schema {
+	return Schema{
+		Type:        "string",
+		Description: "Port (443), range (6881-6999) or comma-separated set (6881-6999, 51413); single ports may be sent as integers",
+	}
+}
+
 func reflectSchema(t reflect.Type) Schema {
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
 
+	if t == portSpecType {
+		return portSpecSchema()
+	}
+
 	switch t.Kind() {
 	case reflect.Struct:
 		props := make(map[string]Schema)
@@ -141,6 +157,10 @@ func reflectSchema(t reflect.Type) Schema {
 		}
 		return Schema{Type: "object", Properties: props}
 	case reflect.Slice:
+		if t.Elem() == portSpecType {
+			items := portSpecSchema()
+			return Schema{Type: "array", Items: &items}
+		}
 		return Schema{Type: "array", Items: &Schema{Type: "string"}} // Simplify for now
 	case reflect.Bool:
 		return Schema{Type: "boolean"}
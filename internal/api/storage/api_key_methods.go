@@ -7,29 +7,49 @@ import (
 	"strings"
 )
 
+// impliedPermissions keeps keys issued before resources were split out
+// working: a legacy grant also satisfies the finer permissions carved from it.
+var impliedPermissions = map[Permission][]Permission{
+	PermReadConfig:    {PermReadVPN},
+	PermWriteConfig:   {PermWriteVPN, PermApplyConfig},
+	PermReadFirewall:  {PermReadPolicies, PermReadNAT, PermReadLearning},
+	PermWriteFirewall: {PermWritePolicies, PermWriteNAT, PermWriteLearning, PermApplyLearning},
+	PermAdminSystem:   {PermReadUsers, PermWriteUsers},
+	PermWriteAll:      {PermApplyAll},
+}
+
 // HasPermission checks if a key has a specific permission.
 func (k *APIKey) HasPermission(required Permission) bool {
 	for _, p := range k.Permissions {
-		if p == PermAll {
-			return true
-		}
-		if p == required {
-			return true
-		}
-		// Check wildcards
-		if p == PermReadAll && strings.HasSuffix(string(required), ":read") {
-			return true
-		}
-		if p == PermWriteAll && strings.HasSuffix(string(required), ":write") {
+		if grants(p, required) {
 			return true
 		}
-		if p == PermAdminAll && strings.HasPrefix(string(required), "admin:") {
-			return true
+		for _, implied := range impliedPermissions[p] {
+			if grants(implied, required) {
+				return true
+			}
 		}
 	}
 	return false
 }
 
+// grants reports whether permission p covers required, expanding wildcards.
+func grants(p, required Permission) bool {
+	switch p {
+	case PermAll, required:
+		return true
+	case PermReadAll:
+		return strings.HasSuffix(string(required), ":read")
+	case PermWriteAll:
+		return strings.HasSuffix(string(required), ":write")
+	case PermApplyAll:
+		return strings.HasSuffix(string(required), ":apply")
+	case PermAdminAll:
+		return strings.HasPrefix(string(required), "admin:")
+	}
+	return false
+}
+
 // HasAnyPermission checks if a key has any of the specified permissions.
 func (k *APIKey) HasAnyPermission(required ...Permission) bool {
 	for _, r := range required {
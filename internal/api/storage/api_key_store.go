@@ -21,6 +21,10 @@ const (
 	PermReadHealth   Permission = "health:read"
 	PermReadLogs     Permission = "logs:read"
 	PermReadAudit    Permission = "audit:read"
+	PermReadPolicies Permission = "policies:read"
+	PermReadNAT      Permission = "nat:read"
+	PermReadVPN      Permission = "vpn:read"
+	PermReadUsers    Permission = "users:read"
 
 	// Write permissions
 	PermWriteConfig   Permission = "config:write"
@@ -29,6 +33,14 @@ const (
 	PermWriteDHCP     Permission = "dhcp:write"
 	PermWriteDNS      Permission = "dns:write"
 	PermWriteLearning Permission = "learning:write"
+	PermWritePolicies Permission = "policies:write"
+	PermWriteNAT      Permission = "nat:write"
+	PermWriteVPN      Permission = "vpn:write"
+	PermWriteUsers    Permission = "users:write"
+
+	// Apply permissions (commit staged changes, approve pending items)
+	PermApplyConfig   Permission = "config:apply"
+	PermApplyLearning Permission = "learning:apply"
 
 	// Admin permissions
 	PermAdminKeys   Permission = "admin:keys"   // Manage API keys
@@ -38,6 +50,7 @@ const (
 	// Wildcard permissions
 	PermReadAll  Permission = "read:*"
 	PermWriteAll Permission = "write:*"
+	PermApplyAll Permission = "apply:*"
 	PermAdminAll Permission = "admin:*"
 	PermAll      Permission = "*"
 )
@@ -249,6 +249,23 @@ func (s *Server) handleSystemRoutes(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleRoutingStatus returns live BGP, OSPF and BFD state from FRR
+// GET /api/routing/status
+func (s *Server) handleRoutingStatus(w http.ResponseWriter, r *http.Request) {
+	if s.client == nil {
+		WriteErrorCtx(w, r, http.StatusServiceUnavailable, "Control plane not connected")
+		return
+	}
+
+	status, err := s.client.GetRoutingStatus()
+	if err != nil {
+		WriteErrorCtx(w, r, http.StatusServiceUnavailable, "Failed to get routing status: "+err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, status)
+}
+
 // handleSafeModeStatus returns safe mode status
 // GET /api/system/safe-mode
 func (s *Server) handleSafeModeStatus(w http.ResponseWriter, r *http.Request) {
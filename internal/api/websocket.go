@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"grimm.is/flywall/internal/ctlplane"
+	"grimm.is/flywall/internal/firewall"
 	"grimm.is/flywall/internal/logging"
 
 	"github.com/gorilla/websocket"
@@ -183,6 +184,8 @@ func (m *WSManager) statusLoop() {
 	var lastLogTime string
 	// Track last notification ID for cursoring
 	var lastNotifyID int64
+	// Track last conntrack event ID for cursoring
+	var lastConntrackID int64
 	// Deduplication: track seen log message hashes (source + message)
 	seenLogs := make(map[string]struct{})
 	const maxSeenLogs = 500
@@ -298,6 +301,16 @@ func (m *WSManager) statusLoop() {
 				}
 			}
 
+			// Publish conntrack events (only if subscribed; the control plane
+			// listens for them only while they are being polled)
+			if m.hasSubscribers("conntrack") {
+				events, newLastID, err := m.client.GetConntrackEvents(lastConntrackID, firewall.ConntrackFilter{})
+				if err == nil && len(events) > 0 {
+					m.Publish("conntrack", events)
+				}
+				lastConntrackID = newLastID
+			}
+
 			// Publish container runtime (only if subscribed and runtime enabled)
 			if m.runtime != nil && m.hasSubscribers("runtime:containers") {
 				// Use background context for now; maybe add timeout
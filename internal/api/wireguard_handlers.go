@@ -0,0 +1,84 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/ctlplane"
+	"grimm.is/flywall/internal/vpn"
+)
+
+// WireGuardClientResponse is a provisioned client with its configuration
+// rendered as a QR code.
+type WireGuardClientResponse struct {
+	*vpn.ClientProfile
+	QRCode     string `json:"qr_code"`               // base64 PNG
+	ApplyError string `json:"apply_error,omitempty"` // Peer staged but not applied
+}
+
+// handleWireGuardProvision adds a road-warrior peer (Staged) and returns the
+// client configuration. The peer connects once the config is applied.
+func (s *Server) handleWireGuardProvision(w http.ResponseWriter, r *http.Request) {
+	var req ctlplane.ProvisionWireGuardPeerArgs
+	if !BindJSON(w, r, &req) {
+		return
+	}
+	if s.client == nil {
+		WriteErrorCtx(w, r, http.StatusServiceUnavailable, "Control plane not connected")
+		return
+	}
+	profile, err := s.client.ProvisionWireGuardPeer(&req)
+	s.writeWireGuardClient(w, r, profile, err)
+}
+
+// handleWireGuardRotate replaces a peer's keys (Staged) and returns the new
+// client configuration. The old keys keep working until the config is applied.
+func (s *Server) handleWireGuardRotate(w http.ResponseWriter, r *http.Request) {
+	var req ctlplane.RotateWireGuardPeerArgs
+	if !BindJSON(w, r, &req) {
+		return
+	}
+	if s.client == nil {
+		WriteErrorCtx(w, r, http.StatusServiceUnavailable, "Control plane not connected")
+		return
+	}
+	profile, err := s.client.RotateWireGuardPeer(&req)
+	s.writeWireGuardClient(w, r, profile, err)
+}
+
+// writeWireGuardClient mirrors the peer the control plane staged into the
+// API's staged config, so a later apply keeps it, and returns the client
+// configuration with its QR code. An error that still carries a profile
+// means the peer was staged but the apply failed; the client config is
+// returned anyway so the keys aren't lost.
+func (s *Server) writeWireGuardClient(w http.ResponseWriter, r *http.Request, profile *vpn.ClientProfile, err error) {
+	var applyErr string
+	if err != nil {
+		if profile == nil {
+			WriteErrorCtx(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		applyErr = err.Error()
+	}
+
+	png, err := vpn.QRCodePNG(profile.ClientConfig)
+	if err != nil {
+		WriteErrorCtx(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if !s.tryConfigUpdate(w, r, func(cfg *config.Config) error {
+		return vpn.SetPeer(cfg, profile.Connection, profile.Peer)
+	}) {
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, WireGuardClientResponse{
+		ClientProfile: profile,
+		QRCode:        base64.StdEncoding.EncodeToString(png),
+		ApplyError:    applyErr,
+	})
+}
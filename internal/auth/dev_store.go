@@ -40,6 +40,12 @@ type AuthStore interface {
 
 	// DeleteUser removes a user
 	DeleteUser(username string) error
+
+	// CreateSession issues a session for an externally authenticated user
+	CreateSession(username string) (*Session, error)
+
+	// ProvisionUser creates or updates an externally authenticated user
+	ProvisionUser(username string, role Role, provider string) (*User, error)
 }
 
 // DevStore is a dev/test auth store that auto-authenticates with full permissions.
@@ -115,6 +121,16 @@ func (d *DevStore) DeleteUser(username string) error {
 	return nil
 }
 
+// CreateSession always succeeds with dev session
+func (d *DevStore) CreateSession(username string) (*Session, error) {
+	return d.Authenticate(username, "")
+}
+
+// ProvisionUser returns the dev user
+func (d *DevStore) ProvisionUser(username string, role Role, provider string) (*User, error) {
+	return d.devUser, nil
+}
+
 // Verify interface compliance at compile time
 var _ AuthStore = (*Store)(nil)
 var _ AuthStore = (*DevStore)(nil)
@@ -0,0 +1,381 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"grimm.is/flywall/internal/brand"
+	"grimm.is/flywall/internal/clock"
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/errors"
+)
+
+// Session second-factor states.
+const (
+	MFAVerify = "verify" // Password accepted; waiting for a TOTP code, recovery code or security key
+	MFAEnroll = "enroll" // The user's role requires a second factor that is not enrolled yet
+)
+
+const (
+	pendingSessionTTL = 5 * time.Minute
+	maxMFAFailures    = 5
+	recoveryCodeCount = 10
+	totpPeriod        = 30 // seconds (RFC 6238 default)
+	totpSkew          = 1  // accepted steps either side of now
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFAStore is implemented by auth stores that support second factors.
+// Methods taking a token accept sessions that are still waiting for the
+// second factor; those returning a *Session return the usable session that
+// replaces it.
+type MFAStore interface {
+	// SetMFAConfig updates the second-factor policy
+	SetMFAConfig(cfg *config.MFAConfig)
+
+	// ValidatePendingSession returns the user for any unexpired session,
+	// including ones still waiting for a second factor
+	ValidatePendingSession(token string) (*User, *Session, error)
+
+	// MFAStatus reports the second factors a user has enrolled
+	MFAStatus(username string) (*MFAStatus, error)
+
+	BeginTOTPEnrollment(token string) (secret, uri string, err error)
+	ConfirmTOTPEnrollment(token, code string) (recoveryCodes []string, sess *Session, err error)
+	VerifyTOTP(token, code string) (*Session, error)
+	VerifyRecoveryCode(token, code string) (*Session, error)
+
+	BeginWebAuthnRegistration(token string) (*CredentialCreationOptions, error)
+	FinishWebAuthnRegistration(token, name string, resp *CredentialResponse) (*Session, error)
+	BeginWebAuthnLogin(token string) (*CredentialRequestOptions, error)
+	FinishWebAuthnLogin(token string, resp *CredentialResponse) (*Session, error)
+
+	// ResetMFA removes all of a user's second factors
+	ResetMFA(username string) error
+}
+
+// MFAStatus summarises a user's second factors.
+type MFAStatus struct {
+	Required          bool     `json:"required"`
+	TOTP              bool     `json:"totp"`
+	SecurityKeys      []string `json:"security_keys"`
+	RecoveryCodes     int      `json:"recovery_codes"`
+	WebAuthnAvailable bool     `json:"webauthn_available"`
+}
+
+// SetMFAConfig updates the second-factor policy. A nil config disables
+// enforcement and security keys; enrolled TOTP is still checked.
+func (s *Store) SetMFAConfig(cfg *config.MFAConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mfa = cfg
+}
+
+// mfaStateLocked returns the second-factor state a new password session
+// starts in.
+func (s *Store) mfaStateLocked(user *User) string {
+	if user.TOTPSecret != "" || len(user.Credentials) > 0 {
+		return MFAVerify
+	}
+	if s.mfaRequiredLocked(user.Role) {
+		return MFAEnroll
+	}
+	return ""
+}
+
+func (s *Store) mfaRequiredLocked(role Role) bool {
+	if s.mfa == nil {
+		return false
+	}
+	for _, r := range s.mfa.RequiredRoles {
+		if Role(r) == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) ValidatePendingSession(token string) (*User, *Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sessionLocked(token)
+}
+
+func (s *Store) sessionLocked(token string) (*User, *Session, error) {
+	sess, exists := s.sessions[token]
+	if !exists || sess.ExpiresAt.Before(clock.Now()) {
+		return nil, nil, errors.New(errors.KindPermission, "invalid session")
+	}
+	user, exists := s.users[sess.Username]
+	if !exists {
+		return nil, nil, errors.New(errors.KindNotFound, "user not found")
+	}
+	return user, sess, nil
+}
+
+// verifyingSessionLocked returns a session that is waiting for a second
+// factor to be presented.
+func (s *Store) verifyingSessionLocked(token string) (*User, *Session, error) {
+	user, sess, err := s.sessionLocked(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sess.MFA != MFAVerify {
+		return nil, nil, errors.New(errors.KindValidation, "no second factor pending")
+	}
+	return user, sess, nil
+}
+
+// promoteLocked replaces a pending session with a full one. The token is
+// rotated so a token seen before the second factor is worthless.
+func (s *Store) promoteLocked(sess *Session) (*Session, error) {
+	if sess.MFA == "" {
+		return sess, s.saveLocked()
+	}
+	delete(s.sessions, sess.Token)
+	delete(s.mfaFailures, sess.Token)
+	delete(s.challenges, sess.Token)
+	return s.newSessionLocked(sess.Username, "")
+}
+
+// failLocked counts a bad second factor; too many end the pending session.
+func (s *Store) failLocked(token string) error {
+	s.mfaFailures[token]++
+	if s.mfaFailures[token] >= maxMFAFailures {
+		delete(s.sessions, token)
+		delete(s.mfaFailures, token)
+		delete(s.challenges, token)
+		if err := s.saveLocked(); err != nil {
+			return err
+		}
+		return errors.New(errors.KindPermission, "too many failed attempts; log in again")
+	}
+	return errors.New(errors.KindPermission, "invalid code")
+}
+
+func (s *Store) MFAStatus(username string) (*MFAStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, exists := s.users[username]
+	if !exists {
+		return nil, errors.New(errors.KindNotFound, "user not found")
+	}
+	st := &MFAStatus{
+		Required:          s.mfaRequiredLocked(user.Role),
+		TOTP:              user.TOTPSecret != "",
+		SecurityKeys:      []string{},
+		RecoveryCodes:     len(user.RecoveryCodes),
+		WebAuthnAvailable: s.mfa != nil && s.mfa.RPID != "",
+	}
+	for _, c := range user.Credentials {
+		st.SecurityKeys = append(st.SecurityKeys, c.Name)
+	}
+	return st, nil
+}
+
+// BeginTOTPEnrollment generates a new TOTP secret for the session's user.
+// It takes effect once ConfirmTOTPEnrollment sees a valid code.
+func (s *Store) BeginTOTPEnrollment(token string) (string, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, sess, err := s.sessionLocked(token)
+	if err != nil {
+		return "", "", err
+	}
+	if sess.MFA == MFAVerify {
+		return "", "", errors.New(errors.KindPermission, "second factor required")
+	}
+
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	secret := totpEncoding.EncodeToString(raw)
+	user.TOTPPending = secret
+	if err := s.saveLocked(); err != nil {
+		return "", "", err
+	}
+
+	issuer := brand.Name
+	if s.mfa != nil && s.mfa.Issuer != "" {
+		issuer = s.mfa.Issuer
+	}
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", "6")
+	q.Set("period", fmt.Sprint(totpPeriod))
+	uri := (&url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + user.Username,
+		RawQuery: q.Encode(),
+	}).String()
+	return secret, uri, nil
+}
+
+// ConfirmTOTPEnrollment activates the pending TOTP secret and issues a fresh
+// set of recovery codes. They are returned once and only stored hashed.
+func (s *Store) ConfirmTOTPEnrollment(token, code string) ([]string, *Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, sess, err := s.sessionLocked(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sess.MFA == MFAVerify {
+		return nil, nil, errors.New(errors.KindPermission, "second factor required")
+	}
+	if user.TOTPPending == "" {
+		return nil, nil, errors.New(errors.KindValidation, "no TOTP enrollment in progress")
+	}
+	step, ok := checkTOTP(user.TOTPPending, code, clock.Now(), 0)
+	if !ok {
+		return nil, nil, s.failLocked(token)
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, nil, err
+	}
+	user.TOTPSecret = user.TOTPPending
+	user.TOTPPending = ""
+	user.TOTPLastStep = step
+	user.RecoveryCodes = hashes
+	user.UpdatedAt = clock.Now()
+
+	newSess, err := s.promoteLocked(sess)
+	if err != nil {
+		return nil, nil, err
+	}
+	return codes, newSess, nil
+}
+
+// VerifyTOTP completes a login with a code from the user's authenticator app.
+func (s *Store) VerifyTOTP(token, code string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, sess, err := s.verifyingSessionLocked(token)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.New(errors.KindValidation, "TOTP not enrolled")
+	}
+	step, ok := checkTOTP(user.TOTPSecret, code, clock.Now(), user.TOTPLastStep)
+	if !ok {
+		return nil, s.failLocked(token)
+	}
+	user.TOTPLastStep = step
+	return s.promoteLocked(sess)
+}
+
+// VerifyRecoveryCode completes a login with a single-use recovery code.
+func (s *Store) VerifyRecoveryCode(token, code string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, sess, err := s.verifyingSessionLocked(token)
+	if err != nil {
+		return nil, err
+	}
+	h := hashRecoveryCode(code)
+	for i, stored := range user.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(h)) == 1 {
+			user.RecoveryCodes = append(user.RecoveryCodes[:i], user.RecoveryCodes[i+1:]...)
+			return s.promoteLocked(sess)
+		}
+	}
+	return nil, s.failLocked(token)
+}
+
+// ResetMFA removes a user's TOTP secret, recovery codes and security keys,
+// e.g. after a lost device. Their role may force re-enrollment at next login.
+func (s *Store) ResetMFA(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.users[username]
+	if !exists {
+		return errors.New(errors.KindNotFound, "user not found")
+	}
+	user.TOTPSecret = ""
+	user.TOTPPending = ""
+	user.TOTPLastStep = 0
+	user.RecoveryCodes = nil
+	user.Credentials = nil
+	user.UpdatedAt = clock.Now()
+	return s.saveLocked()
+}
+
+// totpCode computes the RFC 6238 code for a time step (HMAC-SHA1, 6 digits).
+func totpCode(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	off := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", v%1000000)
+}
+
+// checkTOTP verifies code against the steps around now and returns the
+// matching step. Steps at or before lastStep are rejected so a code cannot
+// be replayed.
+func checkTOTP(secret, code string, now time.Time, lastStep int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != 6 {
+		return 0, false
+	}
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// newRecoveryCodes returns fresh recovery codes and their hashes.
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		c := strings.ToLower(enc.EncodeToString(raw))
+		code := c[:4] + "-" + c[4:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	norm := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(norm))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,192 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"grimm.is/flywall/internal/clock"
+	"grimm.is/flywall/internal/config"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 Appendix B, SHA-1, truncated to 6 digits
+	secret := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(secret, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("totpCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+// currentTOTP returns the code for the given step offset from now.
+func currentTOTP(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("bad secret: %v", err)
+	}
+	return totpCode(key, clock.Now().Unix()/totpPeriod+offset)
+}
+
+// enrollTOTP enrolls TOTP for a user with a full session and returns the
+// secret and recovery codes.
+func enrollTOTP(t *testing.T, store *Store, username string) (string, []string) {
+	t.Helper()
+	sess, err := store.Authenticate(username, "password123")
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	secret, uri, err := store.BeginTOTPEnrollment(sess.Token)
+	if err != nil {
+		t.Fatalf("BeginTOTPEnrollment failed: %v", err)
+	}
+	if uri == "" {
+		t.Error("Expected otpauth URI")
+	}
+	codes, _, err := store.ConfirmTOTPEnrollment(sess.Token, currentTOTP(t, secret, 0))
+	if err != nil {
+		t.Fatalf("ConfirmTOTPEnrollment failed: %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Errorf("got %d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+	return secret, codes
+}
+
+func TestTOTPLogin(t *testing.T) {
+	store, _ := NewStore(tempAuthPath(t))
+	store.CreateUser("alice", "password123", RoleAdmin)
+	secret, _ := enrollTOTP(t, store, "alice")
+
+	sess, err := store.Authenticate("alice", "password123")
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if sess.MFA != MFAVerify {
+		t.Fatalf("MFA = %q, want %q", sess.MFA, MFAVerify)
+	}
+	if _, err := store.ValidateSession(sess.Token); err == nil {
+		t.Fatal("Expected pending session to be rejected")
+	}
+
+	// The enrollment code cannot be replayed
+	if _, err := store.VerifyTOTP(sess.Token, currentTOTP(t, secret, 0)); err == nil {
+		t.Error("Expected replayed code to be rejected")
+	}
+
+	full, err := store.VerifyTOTP(sess.Token, currentTOTP(t, secret, 1))
+	if err != nil {
+		t.Fatalf("VerifyTOTP failed: %v", err)
+	}
+	if full.Token == sess.Token {
+		t.Error("Expected session token to be rotated")
+	}
+	if _, err := store.ValidateSession(full.Token); err != nil {
+		t.Errorf("ValidateSession failed: %v", err)
+	}
+	if _, err := store.ValidateSession(sess.Token); err == nil {
+		t.Error("Expected pending token to be invalidated")
+	}
+}
+
+func TestRecoveryCodeSingleUse(t *testing.T) {
+	store, _ := NewStore(tempAuthPath(t))
+	store.CreateUser("alice", "password123", RoleAdmin)
+	_, codes := enrollTOTP(t, store, "alice")
+
+	sess, _ := store.Authenticate("alice", "password123")
+	if _, err := store.VerifyRecoveryCode(sess.Token, codes[0]); err != nil {
+		t.Fatalf("VerifyRecoveryCode failed: %v", err)
+	}
+
+	sess, _ = store.Authenticate("alice", "password123")
+	if _, err := store.VerifyRecoveryCode(sess.Token, codes[0]); err == nil {
+		t.Error("Expected used recovery code to be rejected")
+	}
+
+	st, _ := store.MFAStatus("alice")
+	if st.RecoveryCodes != recoveryCodeCount-1 {
+		t.Errorf("RecoveryCodes = %d, want %d", st.RecoveryCodes, recoveryCodeCount-1)
+	}
+}
+
+func TestMFAFailuresEndPendingSession(t *testing.T) {
+	store, _ := NewStore(tempAuthPath(t))
+	store.CreateUser("alice", "password123", RoleAdmin)
+	enrollTOTP(t, store, "alice")
+
+	sess, _ := store.Authenticate("alice", "password123")
+	for i := 0; i < maxMFAFailures; i++ {
+		store.VerifyTOTP(sess.Token, "000000")
+	}
+	if _, _, err := store.ValidatePendingSession(sess.Token); err == nil {
+		t.Error("Expected pending session to be revoked after repeated failures")
+	}
+}
+
+func TestMFARequiredByRole(t *testing.T) {
+	store, _ := NewStore(tempAuthPath(t))
+	store.SetMFAConfig(&config.MFAConfig{RequiredRoles: []string{"admin"}})
+	store.CreateUser("alice", "password123", RoleAdmin)
+	store.CreateUser("bob", "password123", RoleViewer)
+
+	if sess, _ := store.Authenticate("bob", "password123"); sess.MFA != "" {
+		t.Errorf("viewer MFA = %q, want none", sess.MFA)
+	}
+
+	sess, _ := store.Authenticate("alice", "password123")
+	if sess.MFA != MFAEnroll {
+		t.Fatalf("admin MFA = %q, want %q", sess.MFA, MFAEnroll)
+	}
+	if _, err := store.ValidateSession(sess.Token); err == nil {
+		t.Fatal("Expected session to be unusable until enrollment")
+	}
+
+	secret, _, err := store.BeginTOTPEnrollment(sess.Token)
+	if err != nil {
+		t.Fatalf("BeginTOTPEnrollment failed: %v", err)
+	}
+	_, full, err := store.ConfirmTOTPEnrollment(sess.Token, currentTOTP(t, secret, 0))
+	if err != nil {
+		t.Fatalf("ConfirmTOTPEnrollment failed: %v", err)
+	}
+	if _, err := store.ValidateSession(full.Token); err != nil {
+		t.Errorf("ValidateSession after enrollment failed: %v", err)
+	}
+}
+
+func TestResetMFA(t *testing.T) {
+	store, _ := NewStore(tempAuthPath(t))
+	store.CreateUser("alice", "password123", RoleAdmin)
+	enrollTOTP(t, store, "alice")
+
+	if err := store.ResetMFA("alice"); err != nil {
+		t.Fatalf("ResetMFA failed: %v", err)
+	}
+	sess, _ := store.Authenticate("alice", "password123")
+	if sess.MFA != "" {
+		t.Errorf("MFA = %q after reset, want none", sess.MFA)
+	}
+}
+
+func TestPendingSessionExpires(t *testing.T) {
+	store, _ := NewStore(tempAuthPath(t))
+	store.CreateUser("alice", "password123", RoleAdmin)
+	enrollTOTP(t, store, "alice")
+
+	sess, _ := store.Authenticate("alice", "password123")
+	if ttl := sess.ExpiresAt.Sub(sess.CreatedAt); ttl > 10*time.Minute {
+		t.Errorf("pending session TTL = %v, want short-lived", ttl)
+	}
+}
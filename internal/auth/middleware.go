@@ -57,6 +57,25 @@ func (m *Middleware) RequireRole(action string, next http.Handler) http.Handler
 	})
 }
 
+// RequirePermission wraps a handler to require verb on a resource class
+func (m *Middleware) RequirePermission(resource, verb string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := m.getUserFromRequest(r)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if !user.Role.Can(resource, verb) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), UserContextKey, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // OptionalAuth adds user to context if authenticated, but doesn't require it
 func (m *Middleware) OptionalAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,492 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"grimm.is/flywall/internal/clock"
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/errors"
+)
+
+// ProviderOIDC marks users provisioned through OpenID Connect.
+const ProviderOIDC = "oidc"
+
+// oidcLoginTTL bounds how long an authorization request may take.
+const oidcLoginTTL = 10 * time.Minute
+
+// OIDCProvider implements the OpenID Connect authorization-code flow with
+// PKCE. Authenticated users are provisioned just-in-time in the AuthStore
+// and receive a regular session, so they pass through Middleware like
+// local users.
+type OIDCProvider struct {
+	cfg        *config.OIDCConfig
+	store      AuthStore
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]*rsa.PublicKey
+	pending   map[string]*oidcLogin // state -> in-flight login
+}
+
+// oidcDiscovery is the subset of the provider metadata we use.
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcLogin is an authorization request awaiting its callback.
+type oidcLogin struct {
+	verifier  string
+	nonce     string
+	expiresAt time.Time
+}
+
+// NewOIDCProvider creates a provider for the given configuration.
+// Provider discovery happens lazily on first use.
+func NewOIDCProvider(cfg *config.OIDCConfig, store AuthStore) *OIDCProvider {
+	return &OIDCProvider{
+		cfg:        cfg,
+		store:      store,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		pending:    make(map[string]*oidcLogin),
+	}
+}
+
+// AuthCodeURL starts a login. It returns the provider URL to redirect the
+// browser to and the state value, which the caller should bind to the
+// browser (e.g. a cookie) and compare on callback.
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context) (string, string, error) {
+	disc, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	p.mu.Lock()
+	now := clock.Now()
+	for s, l := range p.pending {
+		if l.expiresAt.Before(now) {
+			delete(p.pending, s)
+		}
+	}
+	p.pending[state] = &oidcLogin{
+		verifier:  verifier,
+		nonce:     nonce,
+		expiresAt: now.Add(oidcLoginTTL),
+	}
+	p.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(verifier))
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.scopes(), " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(disc.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return disc.AuthorizationEndpoint + sep + q.Encode(), state, nil
+}
+
+// Cancel drops an in-flight login, e.g. when the identity provider
+// reports an error instead of returning a code.
+func (p *OIDCProvider) Cancel(state string) {
+	p.mu.Lock()
+	delete(p.pending, state)
+	p.mu.Unlock()
+}
+
+// Exchange completes a login: it redeems the authorization code, verifies
+// the ID token, provisions the user and issues a session.
+func (p *OIDCProvider) Exchange(ctx context.Context, state, code string) (*Session, *User, error) {
+	p.mu.Lock()
+	login, ok := p.pending[state]
+	delete(p.pending, state)
+	p.mu.Unlock()
+	if !ok || login.expiresAt.Before(clock.Now()) {
+		return nil, nil, errors.New(errors.KindPermission, "unknown or expired login state")
+	}
+	if code == "" {
+		return nil, nil, errors.New(errors.KindValidation, "missing authorization code")
+	}
+
+	disc, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rawIDToken, err := p.redeemCode(ctx, disc, code, login.verifier)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	claims, err := p.verifyIDToken(ctx, rawIDToken, login.nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	username := p.username(claims)
+	if username == "" {
+		return nil, nil, errors.New(errors.KindPermission, "ID token has no usable username claim")
+	}
+	role, err := p.mapRole(claims)
+	if err != nil {
+		return nil, nil, errors.Attr(err, "username", username)
+	}
+
+	user, err := p.store.ProvisionUser(username, role, ProviderOIDC)
+	if err != nil {
+		return nil, nil, err
+	}
+	sess, err := p.store.CreateSession(user.Username)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sess, user, nil
+}
+
+func (p *OIDCProvider) scopes() []string {
+	if len(p.cfg.Scopes) > 0 {
+		return p.cfg.Scopes
+	}
+	return []string{"openid", "profile", "email", "groups"}
+}
+
+// getDiscovery fetches and caches the provider metadata.
+func (p *OIDCProvider) getDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	disc := p.discovery
+	p.mu.Unlock()
+	if disc != nil {
+		return disc, nil
+	}
+
+	issuer := strings.TrimSuffix(p.cfg.Issuer, "/")
+	disc = &oidcDiscovery{}
+	if err := p.getJSON(ctx, issuer+"/.well-known/openid-configuration", disc); err != nil {
+		return nil, errors.Wrap(err, errors.KindUnavailable, "OIDC discovery failed")
+	}
+	if strings.TrimSuffix(disc.Issuer, "/") != issuer {
+		return nil, errors.Errorf(errors.KindValidation, "OIDC issuer mismatch: configured %s, provider reports %s", p.cfg.Issuer, disc.Issuer)
+	}
+	if disc.AuthorizationEndpoint == "" || disc.TokenEndpoint == "" || disc.JWKSURI == "" {
+		return nil, errors.New(errors.KindValidation, "OIDC discovery document is missing endpoints")
+	}
+
+	p.mu.Lock()
+	p.discovery = disc
+	p.mu.Unlock()
+	return disc, nil
+}
+
+// redeemCode exchanges the authorization code for tokens and returns the
+// raw ID token.
+func (p *OIDCProvider) redeemCode(ctx context.Context, disc *oidcDiscovery, code, verifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", p.cfg.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, disc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(string(p.cfg.ClientSecret)))
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, errors.KindUnavailable, "OIDC token request failed")
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf(errors.KindPermission, "OIDC token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tok struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return "", errors.Wrap(err, errors.KindValidation, "invalid OIDC token response")
+	}
+	if tok.IDToken == "" {
+		return "", errors.New(errors.KindPermission, "OIDC token response has no id_token")
+	}
+	return tok.IDToken, nil
+}
+
+// verifyIDToken checks the ID token signature (RS256) and standard claims
+// and returns the decoded claims.
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, raw, nonce string) (map[string]interface{}, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New(errors.KindPermission, "malformed ID token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Alg != "RS256" {
+		return nil, errors.Errorf(errors.KindPermission, "unsupported ID token algorithm %q", header.Alg)
+	}
+
+	key, err := p.signingKey(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New(errors.KindPermission, "malformed ID token signature")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, errors.New(errors.KindPermission, "invalid ID token signature")
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != strings.TrimSuffix(p.cfg.Issuer, "/") {
+		return nil, errors.Errorf(errors.KindPermission, "ID token issuer mismatch: %s", iss)
+	}
+	if !audienceContains(claims["aud"], p.cfg.ClientID) {
+		return nil, errors.New(errors.KindPermission, "ID token audience mismatch")
+	}
+	exp, _ := claims["exp"].(float64)
+	if exp == 0 || clock.Now().After(time.Unix(int64(exp), 0)) {
+		return nil, errors.New(errors.KindPermission, "ID token expired")
+	}
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return nil, errors.New(errors.KindPermission, "ID token nonce mismatch")
+	}
+
+	return claims, nil
+}
+
+// signingKey returns the provider key with the given ID, refreshing the
+// JWKS once if the key is unknown (provider key rotation).
+func (p *OIDCProvider) signingKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	key := p.keys[kid]
+	p.mu.Unlock()
+	if key != nil {
+		return key, nil
+	}
+
+	disc, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, disc.JWKSURI, &jwks); err != nil {
+		return nil, errors.Wrap(err, errors.KindUnavailable, "failed to fetch OIDC signing keys")
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+
+	key = keys[kid]
+	if key == nil {
+		return nil, errors.Errorf(errors.KindPermission, "unknown ID token signing key %q", kid)
+	}
+	return key, nil
+}
+
+// username extracts the login name from the configured claim, falling
+// back to email and subject.
+func (p *OIDCProvider) username(claims map[string]interface{}) string {
+	candidates := []string{"preferred_username", "email", "sub"}
+	if p.cfg.UsernameClaim != "" {
+		candidates = append([]string{p.cfg.UsernameClaim}, candidates...)
+	}
+	for _, c := range candidates {
+		if v, ok := claims[c].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// mapRole derives the user's role from the groups claim. When a user is in
+// several mapped groups the most privileged role wins.
+func (p *OIDCProvider) mapRole(claims map[string]interface{}) (Role, error) {
+	claim := p.cfg.GroupsClaim
+	if claim == "" {
+		claim = "groups"
+	}
+
+	var groups []string
+	switch v := claims[claim].(type) {
+	case []interface{}:
+		for _, g := range v {
+			if s, ok := g.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+	case string:
+		groups = []string{v}
+	}
+
+	// Custom roles rank with viewer; the first mapped group wins a tie
+	rank := func(r Role) int {
+		switch r {
+		case RoleAdmin:
+			return 3
+		case RoleOperator:
+			return 2
+		}
+		if r.Valid() {
+			return 1
+		}
+		return 0
+	}
+	var best Role
+	for _, g := range groups {
+		role := Role(p.cfg.GroupRoles[g])
+		if rank(role) > rank(best) {
+			best = role
+		}
+	}
+	if best != "" {
+		return best, nil
+	}
+
+	if role := Role(p.cfg.DefaultRole); rank(role) > 0 {
+		return role, nil
+	}
+	return "", errors.New(errors.KindPermission, "no role mapped for user's groups")
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: HTTP %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return errors.New(errors.KindPermission, "malformed ID token")
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return errors.New(errors.KindPermission, "malformed ID token")
+	}
+	return nil
+}
+
+func audienceContains(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
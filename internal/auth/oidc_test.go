@@ -0,0 +1,283 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"grimm.is/flywall/internal/config"
+)
+
+// mockIdP is a minimal OpenID Connect provider for tests. It issues one
+// authorization code per authorize call and checks the PKCE verifier.
+type mockIdP struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	claims map[string]interface{}
+
+	challenge string
+	nonce     string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	m := &mockIdP{t: t, key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != m.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		if r.PostForm.Get("code") != "good-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "at",
+			"token_type":   "Bearer",
+			"id_token":     m.sign(),
+		})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// authorize simulates the browser visiting the authorization URL.
+func (m *mockIdP) authorize(authURL string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		m.t.Fatalf("invalid auth URL: %v", err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		m.t.Fatalf("code_challenge_method = %q, want S256", q.Get("code_challenge_method"))
+	}
+	m.challenge = q.Get("code_challenge")
+	m.nonce = q.Get("nonce")
+}
+
+func (m *mockIdP) sign() string {
+	claims := map[string]interface{}{
+		"iss":   m.server.URL,
+		"aud":   "flywall",
+		"sub":   "1234",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": m.nonce,
+	}
+	for k, v := range m.claims {
+		claims[k] = v
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, m.key, crypto.SHA256, digest[:])
+	if err != nil {
+		m.t.Fatalf("SignPKCS1v15 failed: %v", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func newTestOIDCProvider(t *testing.T, idp *mockIdP) (*OIDCProvider, *Store) {
+	store, err := NewStore(tempAuthPath(t))
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	cfg := &config.OIDCConfig{
+		Enabled:     true,
+		Issuer:      idp.server.URL,
+		ClientID:    "flywall",
+		RedirectURL: "https://fw.example.com/api/auth/oidc/callback",
+		GroupRoles: map[string]string{
+			"netops":  "operator",
+			"netadm":  "admin",
+			"support": "viewer",
+		},
+	}
+	return NewOIDCProvider(cfg, store), store
+}
+
+func TestOIDCLogin(t *testing.T) {
+	idp := newMockIdP(t)
+	idp.claims = map[string]interface{}{
+		"preferred_username": "alice",
+		"groups":             []string{"support", "netops"},
+	}
+	p, store := newTestOIDCProvider(t, idp)
+
+	authURL, state, err := p.AuthCodeURL(context.Background())
+	if err != nil {
+		t.Fatalf("AuthCodeURL failed: %v", err)
+	}
+	idp.authorize(authURL)
+
+	sess, user, err := p.Exchange(context.Background(), state, "good-code")
+	if err != nil {
+		t.Fatalf("Exchange failed: %v", err)
+	}
+	if user.Username != "alice" || user.Role != RoleOperator {
+		t.Errorf("user = %s/%s, want alice/operator", user.Username, user.Role)
+	}
+	if user.Provider != ProviderOIDC {
+		t.Errorf("Provider = %q, want %q", user.Provider, ProviderOIDC)
+	}
+
+	validated, err := store.ValidateSession(sess.Token)
+	if err != nil {
+		t.Fatalf("ValidateSession failed: %v", err)
+	}
+	if validated.Username != "alice" {
+		t.Errorf("session user = %q, want alice", validated.Username)
+	}
+
+	// SSO users have no password
+	if _, err := store.Authenticate("alice", ""); err == nil {
+		t.Error("Expected password login to fail for SSO user")
+	}
+
+	// State is single use
+	if _, _, err := p.Exchange(context.Background(), state, "good-code"); err == nil {
+		t.Error("Expected replayed state to be rejected")
+	}
+}
+
+func TestOIDCCancel(t *testing.T) {
+	idp := newMockIdP(t)
+	idp.claims = map[string]interface{}{"preferred_username": "alice"}
+	p, _ := newTestOIDCProvider(t, idp)
+
+	authURL, state, err := p.AuthCodeURL(context.Background())
+	if err != nil {
+		t.Fatalf("AuthCodeURL failed: %v", err)
+	}
+	idp.authorize(authURL)
+
+	p.Cancel(state)
+	if _, _, err := p.Exchange(context.Background(), state, "good-code"); err == nil {
+		t.Error("Expected cancelled state to be rejected")
+	}
+}
+
+func TestOIDCLoginRoleUpdatedOnEachLogin(t *testing.T) {
+	idp := newMockIdP(t)
+	idp.claims = map[string]interface{}{"preferred_username": "bob", "groups": []string{"netadm"}}
+	p, _ := newTestOIDCProvider(t, idp)
+
+	login := func() *User {
+		authURL, state, err := p.AuthCodeURL(context.Background())
+		if err != nil {
+			t.Fatalf("AuthCodeURL failed: %v", err)
+		}
+		idp.authorize(authURL)
+		_, user, err := p.Exchange(context.Background(), state, "good-code")
+		if err != nil {
+			t.Fatalf("Exchange failed: %v", err)
+		}
+		return user
+	}
+
+	if u := login(); u.Role != RoleAdmin {
+		t.Errorf("Role = %q, want admin", u.Role)
+	}
+	idp.claims["groups"] = []string{"support"}
+	if u := login(); u.Role != RoleViewer {
+		t.Errorf("Role = %q, want viewer", u.Role)
+	}
+}
+
+func TestOIDCLoginRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		code   string
+		local  bool // pre-create a local user with the same name
+	}{
+		{
+			name:   "no mapped group",
+			claims: map[string]interface{}{"preferred_username": "carol", "groups": []string{"sales"}},
+			code:   "good-code",
+		},
+		{
+			name:   "bad code",
+			claims: map[string]interface{}{"preferred_username": "carol", "groups": []string{"netops"}},
+			code:   "bad-code",
+		},
+		{
+			name:   "wrong audience",
+			claims: map[string]interface{}{"preferred_username": "carol", "groups": []string{"netops"}, "aud": "other"},
+			code:   "good-code",
+		},
+		{
+			name:   "expired token",
+			claims: map[string]interface{}{"preferred_username": "carol", "groups": []string{"netops"}, "exp": time.Now().Add(-time.Hour).Unix()},
+			code:   "good-code",
+		},
+		{
+			name:   "local user takeover",
+			claims: map[string]interface{}{"preferred_username": "carol", "groups": []string{"netadm"}},
+			code:   "good-code",
+			local:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newMockIdP(t)
+			idp.claims = tt.claims
+			p, store := newTestOIDCProvider(t, idp)
+			if tt.local {
+				store.CreateUser("carol", "password123", RoleViewer)
+			}
+
+			authURL, state, err := p.AuthCodeURL(context.Background())
+			if err != nil {
+				t.Fatalf("AuthCodeURL failed: %v", err)
+			}
+			idp.authorize(authURL)
+
+			if _, _, err := p.Exchange(context.Background(), state, tt.code); err == nil {
+				t.Error("Expected login to be rejected")
+			}
+		})
+	}
+}
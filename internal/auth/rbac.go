@@ -0,0 +1,200 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package auth
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/errors"
+)
+
+// Verbs that a role may be granted on a resource class.
+const (
+	VerbRead  = "read"  // View configuration and state
+	VerbWrite = "write" // Stage configuration changes
+	VerbApply = "apply" // Commit staged changes, approve pending items
+)
+
+// Resource classes that permissions are granted on.
+const (
+	ResourceConfig   = "config"   // General configuration (interfaces, routing, services)
+	ResourcePolicies = "policies" // Firewall policies and rules
+	ResourceNAT      = "nat"
+	ResourceFirewall = "firewall" // Zones, IP sets, protections, QoS
+	ResourceDHCP     = "dhcp"
+	ResourceDNS      = "dns"
+	ResourceVPN      = "vpn"
+	ResourceLearning = "learning" // Learning engine pending rules and flows
+	ResourceUplinks  = "uplinks"
+	ResourceMetrics  = "metrics"
+	ResourceHealth   = "health"
+	ResourceLogs     = "logs"
+	ResourceAudit    = "audit"
+
+	// Admin-only classes. Wildcard grants never cover these; a custom
+	// role must name them explicitly.
+	ResourceUsers  = "users"
+	ResourceSystem = "system" // Reboot, upgrade, raw HCL, debug tools
+	ResourceBackup = "backup"
+	ResourceKeys   = "keys"
+)
+
+var validVerbs = map[string]bool{VerbRead: true, VerbWrite: true, VerbApply: true}
+
+var validResources = map[string]bool{
+	ResourceConfig: true, ResourcePolicies: true, ResourceNAT: true, ResourceFirewall: true,
+	ResourceDHCP: true, ResourceDNS: true, ResourceVPN: true, ResourceLearning: true,
+	ResourceUplinks: true, ResourceMetrics: true, ResourceHealth: true, ResourceLogs: true,
+	ResourceAudit: true, ResourceUsers: true, ResourceSystem: true, ResourceBackup: true,
+	ResourceKeys: true,
+}
+
+var adminResources = map[string]bool{
+	ResourceUsers: true, ResourceSystem: true, ResourceBackup: true, ResourceKeys: true,
+}
+
+// RoleDefinition is a named set of permissions. Each permission has the
+// form "resource:verb"; either side may be "*", and "*" alone grants
+// everything.
+type RoleDefinition struct {
+	Name        Role     `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Permissions []string `json:"permissions"`
+	BuiltIn     bool     `json:"built_in,omitempty"`
+}
+
+// Allows reports whether the role grants verb on resource.
+func (d RoleDefinition) Allows(resource, verb string) bool {
+	for _, p := range d.Permissions {
+		if p == "*" {
+			return true
+		}
+		res, v, ok := strings.Cut(p, ":")
+		if !ok {
+			continue
+		}
+		if v != "*" && v != verb {
+			continue
+		}
+		if res == resource || (res == "*" && !adminResources[resource]) {
+			return true
+		}
+	}
+	return false
+}
+
+var builtinRoles = map[Role]RoleDefinition{
+	RoleAdmin: {
+		Name:        RoleAdmin,
+		Description: "Full access, user management",
+		Permissions: []string{"*"},
+		BuiltIn:     true,
+	},
+	RoleOperator: {
+		Name:        RoleOperator,
+		Description: "View & modify config, restart services",
+		Permissions: []string{"*:read", "*:write", "*:apply"},
+		BuiltIn:     true,
+	},
+	RoleViewer: {
+		Name:        RoleViewer,
+		Description: "Read-only dashboard access",
+		Permissions: []string{"*:read"},
+		BuiltIn:     true,
+	},
+}
+
+var (
+	rolesMu     sync.RWMutex
+	customRoles = map[Role]RoleDefinition{}
+)
+
+// LoadRoles replaces the set of custom roles with those defined in config.
+// On error the previous set is kept.
+func LoadRoles(roles []config.RoleConfig) error {
+	defs := make(map[Role]RoleDefinition, len(roles))
+	for _, rc := range roles {
+		name := Role(rc.Name)
+		if _, exists := builtinRoles[name]; exists {
+			return errors.Errorf(errors.KindValidation, "role %q: cannot redefine built-in role", rc.Name)
+		}
+		if _, exists := defs[name]; exists {
+			return errors.Errorf(errors.KindValidation, "role %q: defined more than once", rc.Name)
+		}
+		for _, p := range rc.Permissions {
+			if err := ValidatePermission(p); err != nil {
+				return errors.Wrapf(err, errors.KindValidation, "role %q", rc.Name)
+			}
+		}
+		defs[name] = RoleDefinition{
+			Name:        name,
+			Description: rc.Description,
+			Permissions: rc.Permissions,
+		}
+	}
+
+	rolesMu.Lock()
+	customRoles = defs
+	rolesMu.Unlock()
+	return nil
+}
+
+// ValidatePermission checks a "resource:verb" permission string.
+func ValidatePermission(p string) error {
+	if p == "*" {
+		return nil
+	}
+	res, verb, ok := strings.Cut(p, ":")
+	if !ok {
+		return errors.Errorf(errors.KindValidation, "permission %q must have the form resource:verb", p)
+	}
+	if res != "*" && !validResources[res] {
+		return errors.Errorf(errors.KindValidation, "permission %q: unknown resource %q", p, res)
+	}
+	if verb != "*" && !validVerbs[verb] {
+		return errors.Errorf(errors.KindValidation, "permission %q: unknown verb %q (expected read, write or apply)", p, verb)
+	}
+	return nil
+}
+
+// LookupRole returns the definition of a built-in or custom role.
+func LookupRole(r Role) (RoleDefinition, bool) {
+	if d, ok := builtinRoles[r]; ok {
+		return d, true
+	}
+	rolesMu.RLock()
+	defer rolesMu.RUnlock()
+	d, ok := customRoles[r]
+	return d, ok
+}
+
+// ListRoles returns all built-in and custom roles, sorted by name.
+func ListRoles() []RoleDefinition {
+	rolesMu.RLock()
+	defs := make([]RoleDefinition, 0, len(builtinRoles)+len(customRoles))
+	for _, d := range customRoles {
+		defs = append(defs, d)
+	}
+	rolesMu.RUnlock()
+	for _, d := range builtinRoles {
+		defs = append(defs, d)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs
+}
+
+// Valid reports whether the role is built in or currently defined.
+func (r Role) Valid() bool {
+	_, ok := LookupRole(r)
+	return ok
+}
+
+// Can reports whether the role grants verb on resource. Unknown roles
+// (e.g. a custom role removed from config) are granted nothing.
+func (r Role) Can(resource, verb string) bool {
+	d, ok := LookupRole(r)
+	return ok && d.Allows(resource, verb)
+}
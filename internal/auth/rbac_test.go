@@ -0,0 +1,113 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package auth
+
+import (
+	"testing"
+
+	"grimm.is/flywall/internal/config"
+)
+
+func loadTestRoles(t *testing.T, roles ...config.RoleConfig) {
+	t.Helper()
+	if err := LoadRoles(roles); err != nil {
+		t.Fatalf("LoadRoles failed: %v", err)
+	}
+	t.Cleanup(func() { LoadRoles(nil) })
+}
+
+func TestRoleCan(t *testing.T) {
+	loadTestRoles(t, config.RoleConfig{
+		Name:        "helpdesk",
+		Permissions: []string{"learning:apply", "learning:read", "dhcp:read"},
+	})
+
+	tests := []struct {
+		role     Role
+		resource string
+		verb     string
+		want     bool
+	}{
+		{RoleAdmin, ResourceUsers, VerbWrite, true},
+		{RoleAdmin, ResourcePolicies, VerbApply, true},
+		{RoleOperator, ResourcePolicies, VerbWrite, true},
+		{RoleOperator, ResourceConfig, VerbApply, true},
+		{RoleOperator, ResourceUsers, VerbRead, false},
+		{RoleOperator, ResourceSystem, VerbWrite, false},
+		{RoleViewer, ResourceDHCP, VerbRead, true},
+		{RoleViewer, ResourceDHCP, VerbWrite, false},
+		{RoleViewer, ResourceBackup, VerbRead, false},
+		{"helpdesk", ResourceLearning, VerbApply, true},
+		{"helpdesk", ResourceDHCP, VerbRead, true},
+		{"helpdesk", ResourceDHCP, VerbWrite, false},
+		{"helpdesk", ResourcePolicies, VerbRead, false},
+		{"helpdesk", ResourceLearning, VerbWrite, false},
+		{"removed", ResourceDHCP, VerbRead, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role)+"_"+tt.resource+":"+tt.verb, func(t *testing.T) {
+			if got := tt.role.Can(tt.resource, tt.verb); got != tt.want {
+				t.Errorf("%s.Can(%q, %q) = %v, want %v", tt.role, tt.resource, tt.verb, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoleWildcardExcludesAdminResources(t *testing.T) {
+	loadTestRoles(t,
+		config.RoleConfig{Name: "netops", Permissions: []string{"*:*"}},
+		config.RoleConfig{Name: "usermgr", Permissions: []string{"users:*"}},
+	)
+
+	if Role("netops").Can(ResourceUsers, VerbWrite) {
+		t.Error("*:* must not grant users:write")
+	}
+	if !Role("netops").Can(ResourceNAT, VerbApply) {
+		t.Error("*:* should grant nat:apply")
+	}
+	if !Role("usermgr").Can(ResourceUsers, VerbWrite) {
+		t.Error("users:* should grant users:write")
+	}
+}
+
+func TestLoadRolesInvalid(t *testing.T) {
+	loadTestRoles(t, config.RoleConfig{Name: "helpdesk", Permissions: []string{"dhcp:read"}})
+
+	tests := []struct {
+		name string
+		role config.RoleConfig
+	}{
+		{"built-in name", config.RoleConfig{Name: "admin", Permissions: []string{"*"}}},
+		{"unknown resource", config.RoleConfig{Name: "x", Permissions: []string{"printers:read"}}},
+		{"unknown verb", config.RoleConfig{Name: "x", Permissions: []string{"dhcp:delete"}}},
+		{"missing verb", config.RoleConfig{Name: "x", Permissions: []string{"dhcp"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := LoadRoles([]config.RoleConfig{tt.role}); err == nil {
+				t.Error("Expected LoadRoles to fail")
+			}
+			// Previous roles are kept on error
+			if !Role("helpdesk").Valid() {
+				t.Error("helpdesk role was dropped after failed load")
+			}
+		})
+	}
+}
+
+func TestCreateUserCustomRole(t *testing.T) {
+	loadTestRoles(t, config.RoleConfig{Name: "helpdesk", Permissions: []string{"dhcp:read"}})
+	store, _ := NewStore(tempAuthPath(t))
+
+	if err := store.CreateUser("alice", "password123", "helpdesk"); err != nil {
+		t.Fatalf("CreateUser with custom role failed: %v", err)
+	}
+	if err := store.CreateUser("bob", "password123", "nosuchrole"); err == nil {
+		t.Error("Expected CreateUser with unknown role to fail")
+	}
+	if err := store.UpdateRole("alice", "nosuchrole"); err == nil {
+		t.Error("Expected UpdateRole with unknown role to fail")
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	"grimm.is/flywall/internal/config"
 	"grimm.is/flywall/internal/install"
 
 	"grimm.is/flywall/internal/errors"
@@ -33,8 +34,16 @@ type User struct {
 	Username  string    `json:"username"`
 	Hash      string    `json:"hash"` // bcrypt hash
 	Role      Role      `json:"role"`
+	Provider  string    `json:"provider,omitempty"` // External identity provider ("oidc"); empty for local users
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// Second factor
+	TOTPSecret    string               `json:"totp_secret,omitempty"`    // Base32; set once enrollment is confirmed
+	TOTPPending   string               `json:"totp_pending,omitempty"`   // Secret awaiting a confirming code
+	TOTPLastStep  int64                `json:"totp_last_step,omitempty"` // Last accepted time step (replay protection)
+	RecoveryCodes []string             `json:"recovery_codes,omitempty"` // SHA-256 of unused recovery codes
+	Credentials   []WebAuthnCredential `json:"webauthn_credentials,omitempty"`
 }
 
 type Session struct {
@@ -42,6 +51,7 @@ type Session struct {
 	Username  string    `json:"username"`
 	CreatedAt time.Time `json:"created_at"`
 	ExpiresAt time.Time `json:"expires_at"`
+	MFA       string    `json:"mfa,omitempty"` // MFAVerify or MFAEnroll while a second factor is outstanding
 }
 
 type Store struct {
@@ -49,6 +59,11 @@ type Store struct {
 	users    map[string]*User
 	sessions map[string]*Session
 	mu       sync.RWMutex
+
+	// Second-factor state (not persisted)
+	mfa         *config.MFAConfig
+	challenges  map[string]*webauthnChallenge // By session token
+	mfaFailures map[string]int                // By session token
 }
 
 type AuthData struct {
@@ -66,9 +81,11 @@ func NewStore(path string) (*Store, error) {
 	}
 
 	s := &Store{
-		path:     path,
-		users:    make(map[string]*User),
-		sessions: make(map[string]*Session),
+		path:        path,
+		users:       make(map[string]*User),
+		sessions:    make(map[string]*Session),
+		challenges:  make(map[string]*webauthnChallenge),
+		mfaFailures: make(map[string]int),
 	}
 
 	// Try to load existing data
@@ -183,6 +200,9 @@ func (s *Store) CreateUser(username, password string, role Role) error {
 	if username == "" || password == "" {
 		return errors.New(errors.KindValidation, "username and password required")
 	}
+	if !role.Valid() {
+		return errors.Errorf(errors.KindValidation, "unknown role %q", role)
+	}
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -221,6 +241,27 @@ func (s *Store) Authenticate(username, password string) (*Session, error) {
 		return nil, errors.New(errors.KindPermission, "invalid credentials")
 	}
 
+	// The session stays unusable until any second factor is satisfied
+	return s.newSessionLocked(username, s.mfaStateLocked(user))
+}
+
+// CreateSession issues a session for an existing user without a password
+// check. Used after the user has been authenticated externally (SSO).
+func (s *Store) CreateSession(username string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.users[username]; !exists {
+		return nil, errors.New(errors.KindNotFound, "user not found")
+	}
+
+	return s.newSessionLocked(username, "")
+}
+
+// newSessionLocked generates and persists a new session. Sessions with an
+// outstanding second factor (mfa != "") are short-lived.
+// MUST be called while holding the write lock
+func (s *Store) newSessionLocked(username, mfa string) (*Session, error) {
 	// Generate session token
 	tokenBytes := make([]byte, 32)
 	if _, err := rand.Read(tokenBytes); err != nil {
@@ -228,11 +269,16 @@ func (s *Store) Authenticate(username, password string) (*Session, error) {
 	}
 	token := hex.EncodeToString(tokenBytes)
 
+	ttl := 24 * time.Hour
+	if mfa != "" {
+		ttl = pendingSessionTTL
+	}
 	session := &Session{
 		Token:     token,
 		Username:  username,
 		CreatedAt: clock.Now(),
-		ExpiresAt: clock.Now().Add(24 * time.Hour),
+		ExpiresAt: clock.Now().Add(ttl),
+		MFA:       mfa,
 	}
 
 	s.sessions[token] = session
@@ -244,6 +290,45 @@ func (s *Store) Authenticate(username, password string) (*Session, error) {
 	return session, nil
 }
 
+// ProvisionUser creates or updates an externally authenticated user
+// (just-in-time provisioning). The role is taken from the identity provider
+// on every login. Local users cannot be taken over by an external identity.
+func (s *Store) ProvisionUser(username string, role Role, provider string) (*User, error) {
+	if username == "" || provider == "" {
+		return nil, errors.New(errors.KindValidation, "username and provider required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := clock.Now()
+	user, exists := s.users[username]
+	if exists {
+		if user.Provider != provider {
+			return nil, errors.Errorf(errors.KindConflict, "user %q already exists and is not managed by %s", username, provider)
+		}
+		if user.Role == role {
+			return user, nil
+		}
+		user.Role = role
+		user.UpdatedAt = now
+	} else {
+		user = &User{
+			Username:  username,
+			Role:      role,
+			Provider:  provider,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		s.users[username] = user
+	}
+
+	if err := s.saveLocked(); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
 func (s *Store) ValidateSession(token string) (*User, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -263,6 +348,10 @@ func (s *Store) ValidateSession(token string) (*User, error) {
 		return nil, errors.New(errors.KindPermission, "session expired")
 	}
 
+	if session.MFA != "" {
+		return nil, errors.New(errors.KindPermission, "second factor required")
+	}
+
 	user, exists := s.users[session.Username]
 	if !exists {
 		return nil, errors.New(errors.KindNotFound, "user not found")
@@ -300,6 +389,7 @@ func (s *Store) ListUsers() []*User {
 		users = append(users, &User{
 			Username:  u.Username,
 			Role:      u.Role,
+			Provider:  u.Provider,
 			CreatedAt: u.CreatedAt,
 			UpdatedAt: u.UpdatedAt,
 		})
@@ -328,6 +418,10 @@ func (s *Store) UpdatePassword(username, newPassword string) error {
 }
 
 func (s *Store) UpdateRole(username string, role Role) error {
+	if !role.Valid() {
+		return errors.Errorf(errors.KindValidation, "unknown role %q", role)
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -386,15 +480,16 @@ func (s *Store) DeleteUser(username string) error {
 	return s.saveLocked()
 }
 
-// CanAccess checks if a role has permission for an action
+// CanAccess checks if a role has permission for a coarse-grained action.
+// Prefer Can for new code.
 func (r Role) CanAccess(action string) bool {
 	switch action {
 	case "view":
-		return true // All roles can view
+		return r.Valid() // All roles can view
 	case "modify":
-		return r == RoleAdmin || r == RoleOperator
+		return r.Can(ResourceConfig, VerbWrite)
 	case "admin":
-		return r == RoleAdmin
+		return r.Can(ResourceSystem, VerbWrite)
 	default:
 		return false
 	}
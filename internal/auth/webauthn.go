@@ -0,0 +1,499 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"grimm.is/flywall/internal/brand"
+	"grimm.is/flywall/internal/clock"
+	"grimm.is/flywall/internal/errors"
+)
+
+// WebAuthn (security keys and passkeys) as a second factor. Attestation is
+// not requested, so registration trusts the authenticator the user holds;
+// what matters is that later logins prove possession of the same key.
+
+const webauthnTimeout = 60 * time.Second
+
+// COSE algorithm identifiers.
+const (
+	coseES256 = -7
+	coseRS256 = -257
+)
+
+// Authenticator data flags.
+const (
+	flagUserPresent  = 0x01
+	flagAttestedData = 0x40
+)
+
+// WebAuthnCredential is a registered security key or passkey.
+type WebAuthnCredential struct {
+	ID        []byte    `json:"id"`
+	PublicKey []byte    `json:"public_key"` // COSE_Key
+	SignCount uint32    `json:"sign_count"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type webauthnChallenge struct {
+	value   []byte
+	typ     string // "webauthn.create" or "webauthn.get"
+	expires time.Time
+}
+
+type credentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"` // base64url
+}
+
+// CredentialCreationOptions is passed to navigator.credentials.create().
+// Binary fields are base64url encoded.
+type CredentialCreationOptions struct {
+	Challenge string `json:"challenge"`
+	RP        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	PubKeyCredParams []struct {
+		Type string `json:"type"`
+		Alg  int    `json:"alg"`
+	} `json:"pubKeyCredParams"`
+	Timeout                int                    `json:"timeout"`
+	Attestation            string                 `json:"attestation"`
+	ExcludeCredentials     []credentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection struct {
+		UserVerification string `json:"userVerification"`
+	} `json:"authenticatorSelection"`
+}
+
+// CredentialRequestOptions is passed to navigator.credentials.get().
+type CredentialRequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []credentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+	Timeout          int                    `json:"timeout"`
+}
+
+// CredentialResponse is a PublicKeyCredential as sent by the browser, with
+// binary fields base64url encoded.
+type CredentialResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AttestationObject string `json:"attestationObject,omitempty"` // Registration
+		AuthenticatorData string `json:"authenticatorData,omitempty"` // Login
+		Signature         string `json:"signature,omitempty"`         // Login
+	} `json:"response"`
+}
+
+// rpLocked returns the relying party ID and allowed origins.
+func (s *Store) rpLocked() (string, []string, error) {
+	if s.mfa == nil || s.mfa.RPID == "" {
+		return "", nil, errors.New(errors.KindUnavailable, "security keys are not configured (set api.mfa.rp_id)")
+	}
+	origins := s.mfa.Origins
+	if len(origins) == 0 {
+		origins = []string{"https://" + s.mfa.RPID}
+	}
+	return s.mfa.RPID, origins, nil
+}
+
+func (s *Store) newChallengeLocked(token, typ string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	s.challenges[token] = &webauthnChallenge{value: b, typ: typ, expires: clock.Now().Add(webauthnTimeout)}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// takeChallengeLocked returns and removes the outstanding challenge.
+func (s *Store) takeChallengeLocked(token, typ string) ([]byte, error) {
+	c, ok := s.challenges[token]
+	delete(s.challenges, token)
+	if !ok || c.typ != typ || c.expires.Before(clock.Now()) {
+		return nil, errors.New(errors.KindValidation, "no security key challenge pending")
+	}
+	return c.value, nil
+}
+
+// BeginWebAuthnRegistration starts registering a security key for the
+// session's user.
+func (s *Store) BeginWebAuthnRegistration(token string) (*CredentialCreationOptions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, sess, err := s.sessionLocked(token)
+	if err != nil {
+		return nil, err
+	}
+	if sess.MFA == MFAVerify {
+		return nil, errors.New(errors.KindPermission, "second factor required")
+	}
+	rpID, _, err := s.rpLocked()
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := s.newChallengeLocked(token, "webauthn.create")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &CredentialCreationOptions{
+		Challenge:          challenge,
+		Timeout:            int(webauthnTimeout / time.Millisecond),
+		Attestation:        "none",
+		ExcludeCredentials: descriptors(user.Credentials),
+	}
+	opts.RP.ID = rpID
+	opts.RP.Name = brand.Name
+	handle := sha256.Sum256([]byte(user.Username))
+	opts.User.ID = base64.RawURLEncoding.EncodeToString(handle[:16])
+	opts.User.Name = user.Username
+	opts.User.DisplayName = user.Username
+	for _, alg := range []int{coseES256, coseRS256} {
+		opts.PubKeyCredParams = append(opts.PubKeyCredParams, struct {
+			Type string `json:"type"`
+			Alg  int    `json:"alg"`
+		}{"public-key", alg})
+	}
+	opts.AuthenticatorSelection.UserVerification = "discouraged"
+	return opts, nil
+}
+
+// FinishWebAuthnRegistration verifies the authenticator's response and
+// stores the new credential.
+func (s *Store) FinishWebAuthnRegistration(token, name string, resp *CredentialResponse) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, sess, err := s.sessionLocked(token)
+	if err != nil {
+		return nil, err
+	}
+	if sess.MFA == MFAVerify {
+		return nil, errors.New(errors.KindPermission, "second factor required")
+	}
+	rpID, origins, err := s.rpLocked()
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := s.takeChallengeLocked(token, "webauthn.create")
+	if err != nil {
+		return nil, err
+	}
+
+	cred, err := verifyRegistration(resp, challenge, rpID, origins)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range s.users {
+		for _, c := range u.Credentials {
+			if bytes.Equal(c.ID, cred.ID) {
+				return nil, errors.New(errors.KindConflict, "security key already registered")
+			}
+		}
+	}
+	if name == "" {
+		name = "Security key"
+	}
+	cred.Name = name
+	cred.CreatedAt = clock.Now()
+	user.Credentials = append(user.Credentials, *cred)
+	user.UpdatedAt = clock.Now()
+
+	return s.promoteLocked(sess)
+}
+
+// BeginWebAuthnLogin challenges the session's user to present a registered
+// security key.
+func (s *Store) BeginWebAuthnLogin(token string) (*CredentialRequestOptions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, _, err := s.verifyingSessionLocked(token)
+	if err != nil {
+		return nil, err
+	}
+	if len(user.Credentials) == 0 {
+		return nil, errors.New(errors.KindValidation, "no security keys registered")
+	}
+	rpID, _, err := s.rpLocked()
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := s.newChallengeLocked(token, "webauthn.get")
+	if err != nil {
+		return nil, err
+	}
+	return &CredentialRequestOptions{
+		Challenge:        challenge,
+		RPID:             rpID,
+		AllowCredentials: descriptors(user.Credentials),
+		UserVerification: "discouraged",
+		Timeout:          int(webauthnTimeout / time.Millisecond),
+	}, nil
+}
+
+// FinishWebAuthnLogin completes a login with a security key assertion.
+func (s *Store) FinishWebAuthnLogin(token string, resp *CredentialResponse) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, sess, err := s.verifyingSessionLocked(token)
+	if err != nil {
+		return nil, err
+	}
+	rpID, origins, err := s.rpLocked()
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := s.takeChallengeLocked(token, "webauthn.get")
+	if err != nil {
+		return nil, err
+	}
+
+	rawID, err := base64.RawURLEncoding.DecodeString(resp.RawID)
+	if err != nil {
+		return nil, s.failLocked(token)
+	}
+	var cred *WebAuthnCredential
+	for i := range user.Credentials {
+		if bytes.Equal(user.Credentials[i].ID, rawID) {
+			cred = &user.Credentials[i]
+		}
+	}
+	if cred == nil {
+		return nil, s.failLocked(token)
+	}
+
+	count, err := verifyAssertion(resp, cred, challenge, rpID, origins)
+	if err != nil {
+		return nil, s.failLocked(token)
+	}
+	cred.SignCount = count
+	return s.promoteLocked(sess)
+}
+
+func descriptors(creds []WebAuthnCredential) []credentialDescriptor {
+	out := []credentialDescriptor{}
+	for _, c := range creds {
+		out = append(out, credentialDescriptor{Type: "public-key", ID: base64.RawURLEncoding.EncodeToString(c.ID)})
+	}
+	return out
+}
+
+// verifyRegistration checks a navigator.credentials.create() response and
+// returns the credential it registers.
+func verifyRegistration(resp *CredentialResponse, challenge []byte, rpID string, origins []string) (*WebAuthnCredential, error) {
+	clientData, err := base64.RawURLEncoding.DecodeString(resp.Response.ClientDataJSON)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.KindValidation, "invalid clientDataJSON")
+	}
+	if err := verifyClientData(clientData, "webauthn.create", challenge, origins); err != nil {
+		return nil, err
+	}
+
+	rawAtt, err := base64.RawURLEncoding.DecodeString(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.KindValidation, "invalid attestationObject")
+	}
+	var att struct {
+		Fmt      string `cbor:"fmt"`
+		AuthData []byte `cbor:"authData"`
+	}
+	if err := cbor.Unmarshal(rawAtt, &att); err != nil {
+		return nil, errors.Wrap(err, errors.KindValidation, "invalid attestationObject")
+	}
+
+	ad, err := parseAuthData(att.AuthData, rpID)
+	if err != nil {
+		return nil, err
+	}
+	if ad.flags&flagAttestedData == 0 {
+		return nil, errors.New(errors.KindValidation, "authenticator returned no credential")
+	}
+	if _, err := parseCOSEKey(ad.publicKey); err != nil {
+		return nil, err
+	}
+	return &WebAuthnCredential{ID: ad.credentialID, PublicKey: ad.publicKey, SignCount: ad.signCount}, nil
+}
+
+// verifyAssertion checks a navigator.credentials.get() response against a
+// stored credential and returns the authenticator's new signature counter.
+func verifyAssertion(resp *CredentialResponse, cred *WebAuthnCredential, challenge []byte, rpID string, origins []string) (uint32, error) {
+	clientData, err := base64.RawURLEncoding.DecodeString(resp.Response.ClientDataJSON)
+	if err != nil {
+		return 0, errors.Wrap(err, errors.KindValidation, "invalid clientDataJSON")
+	}
+	if err := verifyClientData(clientData, "webauthn.get", challenge, origins); err != nil {
+		return 0, err
+	}
+	authData, err := base64.RawURLEncoding.DecodeString(resp.Response.AuthenticatorData)
+	if err != nil {
+		return 0, errors.Wrap(err, errors.KindValidation, "invalid authenticatorData")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(resp.Response.Signature)
+	if err != nil {
+		return 0, errors.Wrap(err, errors.KindValidation, "invalid signature")
+	}
+	ad, err := parseAuthData(authData, rpID)
+	if err != nil {
+		return 0, err
+	}
+
+	key, err := parseCOSEKey(cred.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData[:len(authData):len(authData)], clientHash[:]...))
+	switch pub := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(pub, digest[:], sig) {
+			return 0, errors.New(errors.KindPermission, "invalid security key signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig); err != nil {
+			return 0, errors.New(errors.KindPermission, "invalid security key signature")
+		}
+	}
+
+	// A counter that fails to advance suggests a cloned authenticator.
+	// Authenticators that do not count always report zero.
+	if (ad.signCount != 0 || cred.SignCount != 0) && ad.signCount <= cred.SignCount {
+		return 0, errors.New(errors.KindPermission, "security key counter did not advance")
+	}
+	return ad.signCount, nil
+}
+
+func verifyClientData(raw []byte, typ string, challenge []byte, origins []string) error {
+	var cd struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+		Origin    string `json:"origin"`
+	}
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return errors.Wrap(err, errors.KindValidation, "invalid clientDataJSON")
+	}
+	if cd.Type != typ {
+		return errors.Errorf(errors.KindValidation, "unexpected client data type %q", cd.Type)
+	}
+	got, err := base64.RawURLEncoding.DecodeString(cd.Challenge)
+	if err != nil || !bytes.Equal(got, challenge) {
+		return errors.New(errors.KindPermission, "challenge mismatch")
+	}
+	for _, o := range origins {
+		if cd.Origin == o {
+			return nil
+		}
+	}
+	return errors.Errorf(errors.KindPermission, "origin %q not allowed", cd.Origin)
+}
+
+type authData struct {
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte // COSE_Key, only with flagAttestedData
+}
+
+// parseAuthData decodes authenticator data and checks the RP ID hash and
+// user presence.
+func parseAuthData(b []byte, rpID string) (*authData, error) {
+	if len(b) < 37 {
+		return nil, errors.New(errors.KindValidation, "authenticator data too short")
+	}
+	rpHash := sha256.Sum256([]byte(rpID))
+	if !bytes.Equal(b[:32], rpHash[:]) {
+		return nil, errors.New(errors.KindPermission, "authenticator data is for another relying party")
+	}
+	ad := &authData{flags: b[32], signCount: binary.BigEndian.Uint32(b[33:37])}
+	if ad.flags&flagUserPresent == 0 {
+		return nil, errors.New(errors.KindPermission, "user presence not confirmed")
+	}
+	if ad.flags&flagAttestedData == 0 {
+		return ad, nil
+	}
+
+	// Attested credential data: AAGUID (16), ID length (2), ID, COSE key
+	rest := b[37:]
+	if len(rest) < 18 {
+		return nil, errors.New(errors.KindValidation, "attested credential data too short")
+	}
+	n := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < n {
+		return nil, errors.New(errors.KindValidation, "credential ID truncated")
+	}
+	ad.credentialID = append([]byte(nil), rest[:n]...)
+	var key cbor.RawMessage
+	if _, err := cbor.UnmarshalFirst(rest[n:], &key); err != nil {
+		return nil, errors.Wrap(err, errors.KindValidation, "invalid credential public key")
+	}
+	ad.publicKey = append([]byte(nil), key...)
+	return ad, nil
+}
+
+// parseCOSEKey decodes an ES256 (P-256) or RS256 COSE_Key.
+func parseCOSEKey(b []byte) (crypto.PublicKey, error) {
+	var m map[int]interface{}
+	if err := cbor.Unmarshal(b, &m); err != nil {
+		return nil, errors.Wrap(err, errors.KindValidation, "invalid COSE key")
+	}
+	alg, _ := coseInt(m[3])
+	switch alg {
+	case coseES256:
+		x, _ := m[-2].([]byte)
+		y, _ := m[-3].([]byte)
+		if crv, _ := coseInt(m[-1]); crv != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, errors.New(errors.KindValidation, "unsupported EC2 key")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New(errors.KindValidation, "invalid EC2 key")
+		}
+		return pub, nil
+	case coseRS256:
+		n, _ := m[-1].([]byte)
+		e, _ := m[-2].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New(errors.KindValidation, "unsupported RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	}
+	return nil, errors.Errorf(errors.KindValidation, "unsupported COSE algorithm %d", alg)
+}
+
+func coseInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case uint64:
+		return int64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,198 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"grimm.is/flywall/internal/config"
+)
+
+// softKey is a software ES256 authenticator.
+type softKey struct {
+	t      *testing.T
+	key    *ecdsa.PrivateKey
+	id     []byte
+	rpID   string
+	origin string
+	count  uint32
+}
+
+func newSoftKey(t *testing.T, rpID, origin string) *softKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	return &softKey{t: t, key: key, id: []byte("cred-" + rpID), rpID: rpID, origin: origin}
+}
+
+func (k *softKey) clientData(typ, challenge string) []byte {
+	b, _ := json.Marshal(map[string]string{"type": typ, "challenge": challenge, "origin": k.origin})
+	return b
+}
+
+func (k *softKey) authData(attested bool) []byte {
+	rpHash := sha256.Sum256([]byte(k.rpID))
+	b := append([]byte(nil), rpHash[:]...)
+	flags := byte(flagUserPresent)
+	if attested {
+		flags |= flagAttestedData
+	}
+	b = append(b, flags)
+	b = binary.BigEndian.AppendUint32(b, k.count)
+	if attested {
+		b = append(b, make([]byte, 16)...) // AAGUID
+		b = binary.BigEndian.AppendUint16(b, uint16(len(k.id)))
+		b = append(b, k.id...)
+		cose, err := cbor.Marshal(map[int]interface{}{
+			1:  2,
+			3:  coseES256,
+			-1: 1,
+			-2: k.key.X.FillBytes(make([]byte, 32)),
+			-3: k.key.Y.FillBytes(make([]byte, 32)),
+		})
+		if err != nil {
+			k.t.Fatalf("cbor.Marshal failed: %v", err)
+		}
+		b = append(b, cose...)
+	}
+	return b
+}
+
+func (k *softKey) create(opts *CredentialCreationOptions) *CredentialResponse {
+	att, err := cbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": k.authData(true),
+	})
+	if err != nil {
+		k.t.Fatalf("cbor.Marshal failed: %v", err)
+	}
+	resp := &CredentialResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(k.id),
+		RawID: base64.RawURLEncoding.EncodeToString(k.id),
+		Type:  "public-key",
+	}
+	resp.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(k.clientData("webauthn.create", opts.Challenge))
+	resp.Response.AttestationObject = base64.RawURLEncoding.EncodeToString(att)
+	return resp
+}
+
+func (k *softKey) get(opts *CredentialRequestOptions) *CredentialResponse {
+	k.count++
+	ad := k.authData(false)
+	cd := k.clientData("webauthn.get", opts.Challenge)
+	cdHash := sha256.Sum256(cd)
+	digest := sha256.Sum256(append(ad, cdHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, k.key, digest[:])
+	if err != nil {
+		k.t.Fatalf("SignASN1 failed: %v", err)
+	}
+	resp := &CredentialResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(k.id),
+		RawID: base64.RawURLEncoding.EncodeToString(k.id),
+		Type:  "public-key",
+	}
+	resp.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(cd)
+	resp.Response.AuthenticatorData = base64.RawURLEncoding.EncodeToString(ad)
+	resp.Response.Signature = base64.RawURLEncoding.EncodeToString(sig)
+	return resp
+}
+
+func newWebAuthnStore(t *testing.T) *Store {
+	store, _ := NewStore(tempAuthPath(t))
+	store.SetMFAConfig(&config.MFAConfig{RPID: "fw.example.com"})
+	store.CreateUser("alice", "password123", RoleAdmin)
+	return store
+}
+
+func registerKey(t *testing.T, store *Store, key *softKey) {
+	t.Helper()
+	sess, _ := store.Authenticate("alice", "password123")
+	opts, err := store.BeginWebAuthnRegistration(sess.Token)
+	if err != nil {
+		t.Fatalf("BeginWebAuthnRegistration failed: %v", err)
+	}
+	if _, err := store.FinishWebAuthnRegistration(sess.Token, "yubikey", key.create(opts)); err != nil {
+		t.Fatalf("FinishWebAuthnRegistration failed: %v", err)
+	}
+}
+
+func TestWebAuthnLogin(t *testing.T) {
+	store := newWebAuthnStore(t)
+	key := newSoftKey(t, "fw.example.com", "https://fw.example.com")
+	registerKey(t, store, key)
+
+	sess, _ := store.Authenticate("alice", "password123")
+	if sess.MFA != MFAVerify {
+		t.Fatalf("MFA = %q, want %q", sess.MFA, MFAVerify)
+	}
+	opts, err := store.BeginWebAuthnLogin(sess.Token)
+	if err != nil {
+		t.Fatalf("BeginWebAuthnLogin failed: %v", err)
+	}
+	full, err := store.FinishWebAuthnLogin(sess.Token, key.get(opts))
+	if err != nil {
+		t.Fatalf("FinishWebAuthnLogin failed: %v", err)
+	}
+	if _, err := store.ValidateSession(full.Token); err != nil {
+		t.Errorf("ValidateSession failed: %v", err)
+	}
+
+	st, _ := store.MFAStatus("alice")
+	if len(st.SecurityKeys) != 1 || st.SecurityKeys[0] != "yubikey" {
+		t.Errorf("SecurityKeys = %v, want [yubikey]", st.SecurityKeys)
+	}
+}
+
+func TestWebAuthnLoginRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(k *softKey)
+	}{
+		{"wrong origin", func(k *softKey) { k.origin = "https://evil.example.com" }},
+		{"wrong rp", func(k *softKey) { k.rpID = "evil.example.com" }},
+		{"counter regression", func(k *softKey) { k.count = 4 }}, // get() signs 5, equal to the stored count
+		{"different key", func(k *softKey) {
+			other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			k.key = other
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newWebAuthnStore(t)
+			key := newSoftKey(t, "fw.example.com", "https://fw.example.com")
+			key.count = 5
+			registerKey(t, store, key)
+
+			sess, _ := store.Authenticate("alice", "password123")
+			opts, err := store.BeginWebAuthnLogin(sess.Token)
+			if err != nil {
+				t.Fatalf("BeginWebAuthnLogin failed: %v", err)
+			}
+			tt.mutate(key)
+			if _, err := store.FinishWebAuthnLogin(sess.Token, key.get(opts)); err == nil {
+				t.Error("Expected assertion to be rejected")
+			}
+		})
+	}
+}
+
+func TestWebAuthnRequiresRPID(t *testing.T) {
+	store, _ := NewStore(tempAuthPath(t))
+	store.CreateUser("alice", "password123", RoleAdmin)
+	sess, _ := store.Authenticate("alice", "password123")
+	if _, err := store.BeginWebAuthnRegistration(sess.Token); err == nil {
+		t.Error("Expected registration to fail without rp_id")
+	}
+}
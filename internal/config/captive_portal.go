@@ -0,0 +1,133 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Captive portal authentication methods.
+const (
+	PortalAuthClickThrough = "click_through"
+	PortalAuthPassword     = "password"
+	PortalAuthVoucher      = "voucher"
+)
+
+// Captive portal defaults.
+const (
+	DefaultPortalPort           = 8880
+	DefaultPortalSessionTimeout = 24 * time.Hour
+)
+
+// CaptivePortalConfig configures the portal shown to clients of zones with
+// services { captive_portal = true }. Zones with the service enabled use
+// click-through defaults when this block is omitted.
+type CaptivePortalConfig struct {
+	// How clients get online.
+	// @enum: click_through, password, voucher
+	// @default: "click_through"
+	Auth string `hcl:"auth,optional" json:"auth,omitempty"`
+	// Shared password clients enter (auth = "password").
+	Password SecureString `hcl:"password,optional" json:"password,omitempty"`
+	// How long a client stays online before seeing the portal again, e.g. "8h".
+	// Vouchers carry their own duration.
+	// @default: "24h"
+	SessionTimeout string `hcl:"session_timeout,optional" json:"session_timeout,omitempty"`
+	// Port the portal listens on. Intercepted HTTP is redirected here.
+	// @default: 8880
+	Port int `hcl:"port,optional" json:"port,omitempty"`
+
+	// Page heading and terms of use shown above the login form.
+	Title string `hcl:"title,optional" json:"title,omitempty"`
+	Terms string `hcl:"terms,optional" json:"terms,omitempty"`
+	// Page clients are sent to after logging in, instead of the one they asked for.
+	RedirectURL string `hcl:"redirect_url,optional" json:"redirect_url,omitempty"`
+
+	// Per-client bandwidth caps while online, e.g. "5mbit". Empty means uncapped.
+	UploadLimit   string `hcl:"upload_limit,optional" json:"upload_limit,omitempty"`
+	DownloadLimit string `hcl:"download_limit,optional" json:"download_limit,omitempty"`
+
+	// RFC 8908 API URI advertised in DHCP option 114. Defaults to the
+	// portal's plain HTTP API on the scope's router address; clients that
+	// require HTTPS ignore it and fall back to probing.
+	APIURL string `hcl:"api_url,optional" json:"api_url,omitempty"`
+}
+
+// PortalZones lists the zones with the captive portal service enabled.
+func (c *Config) PortalZones() []string {
+	var zones []string
+	for _, z := range c.Zones {
+		if z.Services != nil && z.Services.CaptivePortal {
+			zones = append(zones, z.Name)
+		}
+	}
+	return zones
+}
+
+// PortalSettings returns the captive portal configuration with defaults
+// applied. It is nil when no zone enables the portal.
+func (c *Config) PortalSettings() *CaptivePortalConfig {
+	if len(c.PortalZones()) == 0 {
+		return nil
+	}
+	p := CaptivePortalConfig{}
+	if c.CaptivePortal != nil {
+		p = *c.CaptivePortal
+	}
+	if p.Auth == "" {
+		p.Auth = PortalAuthClickThrough
+	}
+	if p.Port == 0 {
+		p.Port = DefaultPortalPort
+	}
+	if p.Title == "" {
+		p.Title = "Guest Wi-Fi"
+	}
+	return &p
+}
+
+// SessionDuration returns how long a portal login lasts.
+func (p *CaptivePortalConfig) SessionDuration() time.Duration {
+	if d, err := time.ParseDuration(p.SessionTimeout); err == nil && d > 0 {
+		return d
+	}
+	return DefaultPortalSessionTimeout
+}
+
+// bitRateUnits maps rate suffixes to bits per second.
+var bitRateUnits = map[string]int64{
+	"bit":  1,
+	"kbit": 1000,
+	"mbit": 1000 * 1000,
+	"gbit": 1000 * 1000 * 1000,
+	"bps":  1,
+	"kbps": 1000,
+	"mbps": 1000 * 1000,
+	"gbps": 1000 * 1000 * 1000,
+}
+
+// ParseBitRate parses rates like "512kbit", "5mbit" or "1gbit" into bits
+// per second.
+func ParseBitRate(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		return 0, fmt.Errorf("rate %q needs a unit such as kbit or mbit", s)
+	}
+	num, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+
+	mult, ok := bitRateUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("unknown rate unit %q", s[i:])
+	}
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	return int64(v * float64(mult)), nil
+}
@@ -21,7 +21,8 @@ type Config struct {
 	// Enable TCP MSS clamping to PMTU (recommended for VPNs).
 	// @default: false
 	MSSClamping bool `hcl:"mss_clamping,optional" json:"mss_clamping,omitempty"`
-	// Enable hardware flow offloading for improved performance.
+	// Offload established forwarded TCP/UDP connections to an nftables
+	// flowtable, in hardware where every forwarding NIC supports it.
 	// @default: false
 	EnableFlowOffload bool           `hcl:"enable_flow_offload,optional" json:"enable_flow_offload,omitempty"`
 	Interfaces        []Interface    `hcl:"interface,block" json:"interface,omitempty"`
@@ -50,6 +51,15 @@ type Config struct {
 	QoSPolicies []QoSPolicy           `hcl:"qos_policy,block" json:"qos_policy,omitempty"`
 	Protections []InterfaceProtection `hcl:"protection,block" json:"protection,omitempty"`
 
+	// Per-device traffic quotas
+	Quotas []DeviceQuota `hcl:"quota,block" json:"quota,omitempty"`
+
+	// Captive portal for zones with services { captive_portal = true }
+	CaptivePortal *CaptivePortalConfig `hcl:"captive_portal,block" json:"captive_portal,omitempty"`
+
+	// NAT64 translator for zones with services { nat64 = true }
+	NAT64 *NAT64Config `hcl:"nat64,block" json:"nat64,omitempty"`
+
 	// Rule learning and notifications
 	RuleLearning  *RuleLearningConfig  `hcl:"rule_learning,block" json:"rule_learning,omitempty"`
 	AnomalyConfig *AnomalyConfig       `hcl:"anomaly_detection,block" json:"anomaly_detection,omitempty"`
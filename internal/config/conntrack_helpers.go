@@ -0,0 +1,66 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// ConntrackHelper describes a connection tracking helper (ALG) that
+// policies and rules can assign. Helpers are explicit only: the kernel no
+// longer attaches them by port, so a connection gets one just when a
+// policy's helpers or a rule's helper asks for it.
+type ConntrackHelper struct {
+	Name      string // Kernel helper name, as used in config
+	Protocol  string // "tcp" or "udp"
+	Port      int    // Standard control port, matched by policy helpers
+	Module    string // Connection tracking module
+	NATModule string // Module that rewrites the payload when the connection is NATed
+}
+
+// ConntrackHelpers are the supported helpers by name.
+var ConntrackHelpers = map[string]ConntrackHelper{
+	"ftp":  {Name: "ftp", Protocol: "tcp", Port: 21, Module: "nf_conntrack_ftp", NATModule: "nf_nat_ftp"},
+	"tftp": {Name: "tftp", Protocol: "udp", Port: 69, Module: "nf_conntrack_tftp", NATModule: "nf_nat_tftp"},
+	"sip":  {Name: "sip", Protocol: "udp", Port: 5060, Module: "nf_conntrack_sip", NATModule: "nf_nat_sip"},
+	"pptp": {Name: "pptp", Protocol: "tcp", Port: 1723, Module: "nf_conntrack_pptp", NATModule: "nf_nat_pptp"},
+}
+
+// ConntrackHelperNames returns the supported helper names, sorted.
+func ConntrackHelperNames() []string {
+	names := make([]string, 0, len(ConntrackHelpers))
+	for name := range ConntrackHelpers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UsedConntrackHelpers returns the supported helpers assigned by enabled
+// policies and accept rules, sorted.
+func UsedConntrackHelpers(policies []Policy) []string {
+	used := make(map[string]bool)
+	for _, pol := range policies {
+		if pol.Disabled {
+			continue
+		}
+		for _, name := range pol.Helpers {
+			used[name] = true
+		}
+		for _, rule := range pol.Rules {
+			if !rule.Disabled && rule.Helper != "" && strings.EqualFold(rule.Action, "accept") {
+				used[rule.Helper] = true
+			}
+		}
+	}
+
+	var names []string
+	for name := range used {
+		if _, ok := ConntrackHelpers[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
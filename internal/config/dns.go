@@ -72,6 +72,12 @@ type DNSServe struct {
 	CacheMaxTTL      int  `hcl:"cache_max_ttl,optional" json:"cache_max_ttl,omitempty"`
 	NegativeCacheTTL int  `hcl:"negative_cache_ttl,optional" json:"negative_cache_ttl,omitempty"`
 
+	// DNS64 (RFC 6147): synthesize AAAA records from A records for names
+	// without IPv6 addresses, for IPv6-only clients behind NAT64.
+	// The prefix defaults to the nat64 prefix, else 64:ff9b::/96.
+	DNS64       bool   `hcl:"dns64,optional" json:"dns64,omitempty"`
+	DNS64Prefix string `hcl:"dns64_prefix,optional" json:"dns64_prefix,omitempty"`
+
 	// Encrypted DNS servers (serve DoH/DoT to clients in this zone)
 	DoHServer      *DoHServerConfig      `hcl:"doh_server,block" json:"doh_server,omitempty"`
 	DoTServer      *DoTServerConfig      `hcl:"dot_server,block" json:"dot_server,omitempty"`
@@ -6,7 +6,8 @@ package config
 // Both MaxMind (GeoLite2-Country.mmdb) and DB-IP (dbip-country-lite.mmdb) formats are supported.
 // DB-IP offers free databases at https://db-ip.com/db/lite.php without requiring a license key.
 type GeoIPConfig struct {
-	// Enabled activates GeoIP matching in firewall rules.
+	// Enabled activates GeoIP matching in firewall rules. Countries referenced by
+	// source_country/dest_country are compiled into geoip_country_XX sets.
 	Enabled bool `hcl:"enabled,optional" json:"enabled"`
 
 	// DatabasePath is the path to the MMDB file (MaxMind or DB-IP format).
@@ -14,10 +15,32 @@ type GeoIPConfig struct {
 	// For DB-IP: /opt/flywall/share/geoip/dbip-country-lite.mmdb
 	DatabasePath string `hcl:"database_path,optional" json:"database_path,omitempty"`
 
-	// AutoUpdate enables automatic database updates (future feature).
+	// AutoUpdate enables scheduled database updates from UpdateURL or UpdateDir.
+	// New databases are checksum-verified, staged next to the live file and
+	// swapped in only after a test lookup succeeds.
 	AutoUpdate bool `hcl:"auto_update,optional" json:"auto_update,omitempty"`
 
-	// LicenseKey for premium MaxMind database updates (future feature).
+	// UpdateURL is the HTTP(S) location of the MMDB file (optionally gzipped).
+	// The placeholder {license_key} is replaced with LicenseKey.
+	UpdateURL string `hcl:"update_url,optional" json:"update_url,omitempty"`
+
+	// ChecksumURL points to a sha256sum-style file for UpdateURL.
+	// Default: UpdateURL + ".sha256"
+	ChecksumURL string `hcl:"checksum_url,optional" json:"checksum_url,omitempty"`
+
+	// UpdateDir is a local drop directory for offline updates. The newest
+	// *.mmdb file with a matching *.mmdb.sha256 sidecar is installed.
+	// Takes precedence over UpdateURL when set.
+	UpdateDir string `hcl:"update_dir,optional" json:"update_dir,omitempty"`
+
+	// UpdateIntervalHours controls how often updates are checked. Default: 24
+	UpdateIntervalHours int `hcl:"update_interval_hours,optional" json:"update_interval_hours,omitempty"`
+
+	// TestIP is looked up in a staged database before it is swapped in.
+	// Default: 8.8.8.8
+	TestIP string `hcl:"test_ip,optional" json:"test_ip,omitempty"`
+
+	// LicenseKey for premium MaxMind database updates, substituted into UpdateURL.
 	// Not required for DB-IP or GeoLite2 (free tier).
 	LicenseKey string `hcl:"license_key,optional" json:"license_key,omitempty"`
 }
@@ -5,6 +5,8 @@ package config
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/zclconf/go-cty/cty"
@@ -71,6 +73,15 @@ func (cf *ConfigFile) SyncConfigToHCL() error {
 	if err := cf.syncFRR(); err != nil {
 		return fmt.Errorf("sync frr: %w", err)
 	}
+	if err := cf.syncQuotas(); err != nil {
+		return fmt.Errorf("sync quotas: %w", err)
+	}
+	if err := cf.syncCaptivePortal(); err != nil {
+		return fmt.Errorf("sync captive portal: %w", err)
+	}
+	if err := cf.syncNAT64(); err != nil {
+		return fmt.Errorf("sync nat64: %w", err)
+	}
 
 	return nil
 }
@@ -118,6 +129,150 @@ func (cf *ConfigFile) syncFRR() error {
 		}
 	}
 
+	if frr.BGP != nil {
+		bgpBlock := b.AppendNewBlock("bgp", nil)
+		bb := bgpBlock.Body()
+
+		if frr.BGP.ASN > 0 {
+			bb.SetAttributeValue("asn", cty.NumberIntVal(int64(frr.BGP.ASN)))
+		}
+		if frr.BGP.RouterID != "" {
+			bb.SetAttributeValue("router_id", cty.StringVal(frr.BGP.RouterID))
+		}
+		if len(frr.BGP.Networks) > 0 {
+			bb.SetAttributeValue("networks", toCtyStringList(frr.BGP.Networks))
+		}
+
+		for _, n := range frr.BGP.Neighbors {
+			nb := bb.AppendNewBlock("neighbor", []string{n.IP}).Body()
+			if n.RemoteASN > 0 {
+				nb.SetAttributeValue("remote_asn", cty.NumberIntVal(int64(n.RemoteASN)))
+			}
+			if n.Description != "" {
+				nb.SetAttributeValue("description", cty.StringVal(n.Description))
+			}
+			if n.Password != "" {
+				nb.SetAttributeValue("password", cty.StringVal(string(n.Password)))
+			}
+			if n.EBGPMultihop > 0 {
+				nb.SetAttributeValue("ebgp_multihop", cty.NumberIntVal(int64(n.EBGPMultihop)))
+			}
+			if n.UpdateSource != "" {
+				nb.SetAttributeValue("update_source", cty.StringVal(n.UpdateSource))
+			}
+			if n.BFD {
+				nb.SetAttributeValue("bfd", cty.True)
+			}
+			if n.BFDProfile != "" {
+				nb.SetAttributeValue("bfd_profile", cty.StringVal(n.BFDProfile))
+			}
+			for _, af := range n.AddressFamilies {
+				ab := nb.AppendNewBlock("address_family", []string{af.Family}).Body()
+				for _, kv := range []struct{ k, v string }{
+					{"route_map_in", af.RouteMapIn},
+					{"route_map_out", af.RouteMapOut},
+					{"prefix_list_in", af.PrefixListIn},
+					{"prefix_list_out", af.PrefixListOut},
+				} {
+					if kv.v != "" {
+						ab.SetAttributeValue(kv.k, cty.StringVal(kv.v))
+					}
+				}
+				if af.NextHopSelf {
+					ab.SetAttributeValue("next_hop_self", cty.True)
+				}
+				if af.SoftReconfiguration {
+					ab.SetAttributeValue("soft_reconfiguration", cty.True)
+				}
+				if af.MaxPrefix > 0 {
+					ab.SetAttributeValue("max_prefix", cty.NumberIntVal(int64(af.MaxPrefix)))
+				}
+				if af.DefaultOriginate {
+					ab.SetAttributeValue("default_originate", cty.True)
+				}
+			}
+		}
+	}
+
+	for _, pl := range frr.PrefixLists {
+		pb := b.AppendNewBlock("prefix_list", []string{pl.Name}).Body()
+		for _, r := range pl.Rules {
+			rb := pb.AppendNewBlock("rule", nil).Body()
+			if r.Seq > 0 {
+				rb.SetAttributeValue("seq", cty.NumberIntVal(int64(r.Seq)))
+			}
+			if r.Action != "" {
+				rb.SetAttributeValue("action", cty.StringVal(r.Action))
+			}
+			rb.SetAttributeValue("prefix", cty.StringVal(r.Prefix))
+			if r.GE > 0 {
+				rb.SetAttributeValue("ge", cty.NumberIntVal(int64(r.GE)))
+			}
+			if r.LE > 0 {
+				rb.SetAttributeValue("le", cty.NumberIntVal(int64(r.LE)))
+			}
+		}
+	}
+
+	for _, cl := range frr.CommunityLists {
+		cb := b.AppendNewBlock("community_list", []string{cl.Name}).Body()
+		if cl.Action != "" {
+			cb.SetAttributeValue("action", cty.StringVal(cl.Action))
+		}
+		cb.SetAttributeValue("communities", toCtyStringList(cl.Communities))
+	}
+
+	for _, rm := range frr.RouteMaps {
+		mb := b.AppendNewBlock("route_map", []string{rm.Name}).Body()
+		for _, r := range rm.Rules {
+			rb := mb.AppendNewBlock("rule", nil).Body()
+			if r.Seq > 0 {
+				rb.SetAttributeValue("seq", cty.NumberIntVal(int64(r.Seq)))
+			}
+			if r.Action != "" {
+				rb.SetAttributeValue("action", cty.StringVal(r.Action))
+			}
+			if r.MatchPrefixList != "" {
+				rb.SetAttributeValue("match_prefix_list", cty.StringVal(r.MatchPrefixList))
+			}
+			if r.MatchCommunity != "" {
+				rb.SetAttributeValue("match_community", cty.StringVal(r.MatchCommunity))
+			}
+			if r.SetLocalPreference > 0 {
+				rb.SetAttributeValue("set_local_preference", cty.NumberIntVal(int64(r.SetLocalPreference)))
+			}
+			if r.SetMetric != nil {
+				rb.SetAttributeValue("set_metric", cty.NumberIntVal(int64(*r.SetMetric)))
+			}
+			if len(r.SetCommunity) > 0 {
+				rb.SetAttributeValue("set_community", toCtyStringList(r.SetCommunity))
+			}
+			if r.SetCommunityAdditive {
+				rb.SetAttributeValue("set_community_additive", cty.True)
+			}
+			if len(r.SetASPathPrepend) > 0 {
+				asns := make([]cty.Value, len(r.SetASPathPrepend))
+				for i, asn := range r.SetASPathPrepend {
+					asns[i] = cty.NumberIntVal(int64(asn))
+				}
+				rb.SetAttributeValue("set_as_path_prepend", cty.ListVal(asns))
+			}
+		}
+	}
+
+	for _, p := range frr.BFDProfiles {
+		pb := b.AppendNewBlock("bfd_profile", []string{p.Name}).Body()
+		if p.DetectMultiplier > 0 {
+			pb.SetAttributeValue("detect_multiplier", cty.NumberIntVal(int64(p.DetectMultiplier)))
+		}
+		if p.ReceiveInterval > 0 {
+			pb.SetAttributeValue("receive_interval", cty.NumberIntVal(int64(p.ReceiveInterval)))
+		}
+		if p.TransmitInterval > 0 {
+			pb.SetAttributeValue("transmit_interval", cty.NumberIntVal(int64(p.TransmitInterval)))
+		}
+	}
+
 	return nil
 }
 
@@ -149,6 +304,12 @@ func (cf *ConfigFile) syncQoSPolicies() error {
 		if pol.DownloadMbps > 0 {
 			b.SetAttributeValue("download_mbps", cty.NumberIntVal(int64(pol.DownloadMbps)))
 		}
+		if pol.Direction != "" {
+			b.SetAttributeValue("direction", cty.StringVal(pol.Direction))
+		}
+		if pol.QueueType != "" {
+			b.SetAttributeValue("queue_type", cty.StringVal(pol.QueueType))
+		}
 
 		// Classes
 		for _, class := range pol.Classes {
@@ -163,6 +324,9 @@ func (cf *ConfigFile) syncQoSPolicies() error {
 			if class.Priority > 0 {
 				cbb.SetAttributeValue("priority", cty.NumberIntVal(int64(class.Priority)))
 			}
+			if class.QueueType != "" {
+				cbb.SetAttributeValue("queue_type", cty.StringVal(class.QueueType))
+			}
 		}
 
 		// Rules
@@ -185,6 +349,125 @@ func (cf *ConfigFile) syncQoSPolicies() error {
 	return nil
 }
 
+// syncQuotas synchronizes quota blocks
+func (cf *ConfigFile) syncQuotas() error {
+	body := cf.hclFile.Body()
+
+	for _, block := range body.Blocks() {
+		if block.Type() == "quota" {
+			body.RemoveBlock(block)
+		}
+	}
+
+	for _, q := range cf.Config.Quotas {
+		b := body.AppendNewBlock("quota", []string{q.Name}).Body()
+
+		if len(q.Devices) > 0 {
+			b.SetAttributeValue("devices", toCtyStringList(q.Devices))
+		}
+		if q.Group != "" {
+			b.SetAttributeValue("group", cty.StringVal(q.Group))
+		}
+		if q.PerDevice {
+			b.SetAttributeValue("per_device", cty.True)
+		}
+		if q.Daily != "" {
+			b.SetAttributeValue("daily", cty.StringVal(q.Daily))
+		}
+		if q.Weekly != "" {
+			b.SetAttributeValue("weekly", cty.StringVal(q.Weekly))
+		}
+		if q.Monthly != "" {
+			b.SetAttributeValue("monthly", cty.StringVal(q.Monthly))
+		}
+		if q.Action != "" {
+			b.SetAttributeValue("action", cty.StringVal(q.Action))
+		}
+		if q.QoSPolicy != "" {
+			b.SetAttributeValue("qos_policy", cty.StringVal(q.QoSPolicy))
+		}
+		if q.QoSClass != "" {
+			b.SetAttributeValue("qos_class", cty.StringVal(q.QoSClass))
+		}
+		if q.WarnPercent != nil {
+			b.SetAttributeValue("warn_percent", cty.NumberIntVal(int64(*q.WarnPercent)))
+		}
+		if q.ResetDay > 0 {
+			b.SetAttributeValue("reset_day", cty.NumberIntVal(int64(q.ResetDay)))
+		}
+	}
+
+	return nil
+}
+
+// syncCaptivePortal synchronizes the captive_portal block
+func (cf *ConfigFile) syncCaptivePortal() error {
+	body := cf.hclFile.Body()
+
+	for _, block := range body.Blocks() {
+		if block.Type() == "captive_portal" {
+			body.RemoveBlock(block)
+		}
+	}
+
+	p := cf.Config.CaptivePortal
+	if p == nil {
+		return nil
+	}
+	b := body.AppendNewBlock("captive_portal", nil).Body()
+
+	for _, attr := range [][2]string{
+		{"auth", p.Auth},
+		{"password", string(p.Password)},
+		{"session_timeout", p.SessionTimeout},
+		{"title", p.Title},
+		{"terms", p.Terms},
+		{"redirect_url", p.RedirectURL},
+		{"upload_limit", p.UploadLimit},
+		{"download_limit", p.DownloadLimit},
+		{"api_url", p.APIURL},
+	} {
+		if attr[1] != "" {
+			b.SetAttributeValue(attr[0], cty.StringVal(attr[1]))
+		}
+	}
+	if p.Port > 0 {
+		b.SetAttributeValue("port", cty.NumberIntVal(int64(p.Port)))
+	}
+
+	return nil
+}
+
+// syncNAT64 synchronizes the nat64 block
+func (cf *ConfigFile) syncNAT64() error {
+	body := cf.hclFile.Body()
+
+	for _, block := range body.Blocks() {
+		if block.Type() == "nat64" {
+			body.RemoveBlock(block)
+		}
+	}
+
+	n := cf.Config.NAT64
+	if n == nil {
+		return nil
+	}
+	b := body.AppendNewBlock("nat64", nil).Body()
+
+	for _, attr := range [][2]string{
+		{"prefix", n.Prefix},
+		{"pool4", n.Pool4},
+		{"interface", n.Interface},
+		{"binding_timeout", n.BindingTimeout},
+	} {
+		if attr[1] != "" {
+			b.SetAttributeValue(attr[0], cty.StringVal(attr[1]))
+		}
+	}
+
+	return nil
+}
+
 // syncFeatures synchronizes the features block
 func (cf *ConfigFile) syncFeatures() error {
 	body := cf.hclFile.Body()
@@ -294,6 +577,35 @@ func (cf *ConfigFile) syncAPI() error {
 		}
 	}
 
+	// Sync custom roles
+	for _, role := range api.Roles {
+		rBlock := b.AppendNewBlock("role", []string{role.Name})
+		rb := rBlock.Body()
+		if role.Description != "" {
+			rb.SetAttributeValue("description", cty.StringVal(role.Description))
+		}
+		rb.SetAttributeValue("permissions", toCtyStringList(role.Permissions))
+	}
+
+	// Sync MFA
+	if api.MFA != nil {
+		m := api.MFA
+		mBlock := b.AppendNewBlock("mfa", nil)
+		mb := mBlock.Body()
+		if len(m.RequiredRoles) > 0 {
+			mb.SetAttributeValue("required_roles", toCtyStringList(m.RequiredRoles))
+		}
+		if m.Issuer != "" {
+			mb.SetAttributeValue("issuer", cty.StringVal(m.Issuer))
+		}
+		if m.RPID != "" {
+			mb.SetAttributeValue("rp_id", cty.StringVal(m.RPID))
+		}
+		if len(m.Origins) > 0 {
+			mb.SetAttributeValue("origins", toCtyStringList(m.Origins))
+		}
+	}
+
 	// Sync Let's Encrypt
 	if api.LetsEncrypt != nil {
 		le := api.LetsEncrypt
@@ -316,6 +628,41 @@ func (cf *ConfigFile) syncAPI() error {
 		}
 	}
 
+	// Sync OIDC
+	if api.OIDC != nil {
+		o := api.OIDC
+		oBlock := b.AppendNewBlock("oidc", nil)
+		ob := oBlock.Body()
+		if o.Enabled {
+			ob.SetAttributeValue("enabled", cty.BoolVal(o.Enabled))
+		}
+		ob.SetAttributeValue("issuer", cty.StringVal(o.Issuer))
+		ob.SetAttributeValue("client_id", cty.StringVal(o.ClientID))
+		if o.ClientSecret != "" {
+			ob.SetAttributeValue("client_secret", cty.StringVal(string(o.ClientSecret)))
+		}
+		ob.SetAttributeValue("redirect_url", cty.StringVal(o.RedirectURL))
+		if len(o.Scopes) > 0 {
+			ob.SetAttributeValue("scopes", toCtyStringList(o.Scopes))
+		}
+		if o.UsernameClaim != "" {
+			ob.SetAttributeValue("username_claim", cty.StringVal(o.UsernameClaim))
+		}
+		if o.GroupsClaim != "" {
+			ob.SetAttributeValue("groups_claim", cty.StringVal(o.GroupsClaim))
+		}
+		if len(o.GroupRoles) > 0 {
+			roles := make(map[string]cty.Value, len(o.GroupRoles))
+			for group, role := range o.GroupRoles {
+				roles[group] = cty.StringVal(role)
+			}
+			ob.SetAttributeValue("group_roles", cty.MapVal(roles))
+		}
+		if o.DefaultRole != "" {
+			ob.SetAttributeValue("default_role", cty.StringVal(o.DefaultRole))
+		}
+	}
+
 	return nil
 }
 
@@ -561,6 +908,18 @@ func (cf *ConfigFile) syncServices() error {
 		if dhcp.Mode != "" {
 			b.SetAttributeValue("mode", cty.StringVal(dhcp.Mode))
 		}
+		if fo := dhcp.Failover; fo != nil {
+			fb := b.AppendNewBlock("failover", nil).Body()
+			if fo.Mode != "" {
+				fb.SetAttributeValue("mode", cty.StringVal(fo.Mode))
+			}
+			if fo.Split != 0 {
+				fb.SetAttributeValue("split", cty.NumberIntVal(int64(fo.Split)))
+			}
+			if fo.MCLT != "" {
+				fb.SetAttributeValue("mclt", cty.StringVal(fo.MCLT))
+			}
+		}
 		// Scopes
 		for _, scope := range dhcp.Scopes {
 			sb := b.AppendNewBlock("scope", []string{scope.Name})
@@ -578,6 +937,15 @@ func (cf *ConfigFile) syncServices() error {
 			if scope.LeaseTime != "" {
 				sbb.SetAttributeValue("lease_time", cty.StringVal(scope.LeaseTime))
 			}
+			if scope.RangeStartV6 != "" {
+				sbb.SetAttributeValue("range_start_v6", cty.StringVal(scope.RangeStartV6))
+			}
+			if scope.RangeEndV6 != "" {
+				sbb.SetAttributeValue("range_end_v6", cty.StringVal(scope.RangeEndV6))
+			}
+			if len(scope.DNSServersV6) > 0 {
+				sbb.SetAttributeValue("dns_v6", toCtyStringList(scope.DNSServersV6))
+			}
 			// Reservations
 			for _, res := range scope.Reservations {
 				rb := sbb.AppendNewBlock("reservation", []string{res.MAC})
@@ -589,6 +957,12 @@ func (cf *ConfigFile) syncServices() error {
 				if res.Description != "" {
 					rbb.SetAttributeValue("description", cty.StringVal(res.Description))
 				}
+				if res.DUID != "" {
+					rbb.SetAttributeValue("duid", cty.StringVal(res.DUID))
+				}
+				if res.IPv6 != "" {
+					rbb.SetAttributeValue("ipv6", cty.StringVal(res.IPv6))
+				}
 			}
 		}
 	}
@@ -705,8 +1079,39 @@ func (cf *ConfigFile) syncServices() error {
 		}
 		b.SetAttributeValue("provider", cty.StringVal(ddns.Provider))
 		b.SetAttributeValue("hostname", cty.StringVal(ddns.Hostname))
-		if ddns.Token != "" {
-			b.SetAttributeValue("token", cty.StringVal(ddns.Token))
+		for _, attr := range []struct{ name, val string }{
+			{"token", ddns.Token},
+			{"username", ddns.Username},
+			{"zone_id", ddns.ZoneID},
+			{"record_id", ddns.RecordID},
+			{"record_id_v6", ddns.RecordIDv6},
+			{"interface", ddns.Interface},
+			{"family", ddns.Family},
+			{"server", ddns.Server},
+			{"zone", ddns.Zone},
+			{"tsig_key", ddns.TSIGKey},
+			{"tsig_secret", string(ddns.TSIGSecret)},
+			{"tsig_algorithm", ddns.TSIGAlgorithm},
+			{"url", ddns.URL},
+			{"method", ddns.Method},
+			{"body", ddns.Body},
+		} {
+			if attr.val != "" {
+				b.SetAttributeValue(attr.name, cty.StringVal(attr.val))
+			}
+		}
+		if ddns.Interval > 0 {
+			b.SetAttributeValue("interval", cty.NumberIntVal(int64(ddns.Interval)))
+		}
+		if ddns.TTL > 0 {
+			b.SetAttributeValue("ttl", cty.NumberIntVal(int64(ddns.TTL)))
+		}
+		if len(ddns.Headers) > 0 {
+			headers := make(map[string]cty.Value, len(ddns.Headers))
+			for k, v := range ddns.Headers {
+				headers[k] = cty.StringVal(v)
+			}
+			b.SetAttributeValue("headers", cty.MapVal(headers))
 		}
 	}
 
@@ -736,26 +1141,75 @@ func (cf *ConfigFile) syncIntegrations() error {
 		for _, wg := range vpn.WireGuard {
 			wb := b.AppendNewBlock("wireguard", []string{wg.Name})
 			wbb := wb.Body()
-			wbb.SetAttributeValue("private_key", cty.StringVal(string(wg.PrivateKey)))
-			if wg.ListenPort > 0 {
-				wbb.SetAttributeValue("listen_port", cty.NumberIntVal(int64(wg.ListenPort)))
+			wbb.SetAttributeValue("enabled", cty.BoolVal(wg.Enabled))
+			if wg.ManagementAccess {
+				wbb.SetAttributeValue("management_access", cty.BoolVal(true))
+			}
+			for _, attr := range []struct{ name, val string }{
+				{"interface", wg.Interface},
+				{"zone", wg.Zone},
+				{"private_key", string(wg.PrivateKey)},
+				{"private_key_file", wg.PrivateKeyFile},
+				{"table", wg.Table},
+				{"client_endpoint", wg.ClientEndpoint},
+				{"key_rotation", wg.KeyRotation},
+				{"idle_timeout", wg.IdleTimeout},
+			} {
+				if attr.val != "" {
+					wbb.SetAttributeValue(attr.name, cty.StringVal(attr.val))
+				}
 			}
-			if len(wg.Address) > 0 {
-				wbb.SetAttributeValue("address", toCtyStringList(wg.Address))
+			for _, attr := range []struct {
+				name string
+				val  int
+			}{
+				{"listen_port", wg.ListenPort},
+				{"mtu", wg.MTU},
+				{"fwmark", wg.FWMark},
+			} {
+				if attr.val > 0 {
+					wbb.SetAttributeValue(attr.name, cty.NumberIntVal(int64(attr.val)))
+				}
+			}
+			for _, attr := range []struct {
+				name string
+				val  []string
+			}{
+				{"address", wg.Address},
+				{"dns", wg.DNS},
+				{"post_up", wg.PostUp},
+				{"post_down", wg.PostDown},
+				{"client_allowed_ips", wg.ClientAllowedIPs},
+				{"client_dns", wg.ClientDNS},
+			} {
+				if len(attr.val) > 0 {
+					wbb.SetAttributeValue(attr.name, toCtyStringList(attr.val))
+				}
 			}
 			// Peers
 			for _, peer := range wg.Peers {
-				pb := wbb.AppendNewBlock("peer", []string{peer.PublicKey})
+				pb := wbb.AppendNewBlock("peer", []string{peer.Name})
 				pbb := pb.Body()
+				pbb.SetAttributeValue("public_key", cty.StringVal(peer.PublicKey))
 				if peer.Endpoint != "" {
 					pbb.SetAttributeValue("endpoint", cty.StringVal(peer.Endpoint))
 				}
-				if len(peer.AllowedIPs) > 0 {
-					pbb.SetAttributeValue("allowed_ips", toCtyStringList(peer.AllowedIPs))
-				}
+				pbb.SetAttributeValue("allowed_ips", toCtyStringList(peer.AllowedIPs))
 				if peer.PresharedKey != "" {
 					pbb.SetAttributeValue("preshared_key", cty.StringVal(string(peer.PresharedKey)))
 				}
+				if peer.PersistentKeepalive > 0 {
+					pbb.SetAttributeValue("persistent_keepalive", cty.NumberIntVal(int64(peer.PersistentKeepalive)))
+				}
+				if peer.Created != "" {
+					pbb.SetAttributeValue("created", cty.StringVal(peer.Created))
+				}
+				if peer.Expires != "" {
+					pbb.SetAttributeValue("expires", cty.StringVal(peer.Expires))
+				}
+				if peer.Disabled {
+					pbb.SetAttributeValue("disabled", cty.BoolVal(true))
+				}
 			}
 		}
 		// Tailscale
@@ -860,14 +1314,100 @@ func (cf *ConfigFile) syncIntegrations() error {
 		if nc.Enabled {
 			b.SetAttributeValue("enabled", cty.BoolVal(nc.Enabled))
 		}
+		if len(nc.GroupBy) > 0 {
+			b.SetAttributeValue("group_by", toCtyStringList(nc.GroupBy))
+		}
+		if nc.GroupWait != "" {
+			b.SetAttributeValue("group_wait", cty.StringVal(nc.GroupWait))
+		}
+		if nc.GroupInterval != "" {
+			b.SetAttributeValue("group_interval", cty.StringVal(nc.GroupInterval))
+		}
+		if nc.ResolveTimeout != "" {
+			b.SetAttributeValue("resolve_timeout", cty.StringVal(nc.ResolveTimeout))
+		}
 		for _, ch := range nc.Channels {
 			cb := b.AppendNewBlock("channel", []string{ch.Name})
 			cbb := cb.Body()
 			cbb.SetAttributeValue("type", cty.StringVal(ch.Type))
-			if ch.WebhookURL != "" {
-				cbb.SetAttributeValue("webhook_url", cty.StringVal(ch.WebhookURL))
+			if ch.Level != "" {
+				cbb.SetAttributeValue("level", cty.StringVal(ch.Level))
+			}
+			if ch.Enabled {
+				cbb.SetAttributeValue("enabled", cty.BoolVal(ch.Enabled))
+			}
+			strs := []struct{ name, val string }{
+				{"smtp_host", ch.SMTPHost},
+				{"smtp_user", ch.SMTPUser},
+				{"smtp_password", string(ch.SMTPPassword)},
+				{"from", ch.From},
+				{"webhook_url", ch.WebhookURL},
+				{"channel", ch.Channel},
+				{"username", ch.Username},
+				{"api_token", string(ch.APIToken)},
+				{"user_key", string(ch.UserKey)},
+				{"sound", ch.Sound},
+				{"server", ch.Server},
+				{"topic", ch.Topic},
+				{"chat_id", ch.ChatID},
+				{"room", ch.Room},
+				{"address", ch.Address},
+				{"facility", ch.Facility},
+				{"password", string(ch.Password)},
+			}
+			for _, a := range strs {
+				if a.val != "" {
+					cbb.SetAttributeValue(a.name, cty.StringVal(a.val))
+				}
+			}
+			if ch.SMTPPort != 0 {
+				cbb.SetAttributeValue("smtp_port", cty.NumberIntVal(int64(ch.SMTPPort)))
+			}
+			if len(ch.To) > 0 {
+				cbb.SetAttributeValue("to", toCtyStringList(ch.To))
+			}
+			if ch.Priority != 0 {
+				cbb.SetAttributeValue("priority", cty.NumberIntVal(int64(ch.Priority)))
+			}
+			if len(ch.Command) > 0 {
+				cbb.SetAttributeValue("command", toCtyStringList(ch.Command))
+			}
+			if len(ch.Headers) > 0 {
+				headers := make(map[string]cty.Value, len(ch.Headers))
+				for k, v := range ch.Headers {
+					headers[k] = cty.StringVal(v)
+				}
+				cbb.SetAttributeValue("headers", cty.MapVal(headers))
+			}
+			if ch.Retries != 0 {
+				cbb.SetAttributeValue("retries", cty.NumberIntVal(int64(ch.Retries)))
+			}
+		}
+		for _, r := range nc.Rules {
+			rb := b.AppendNewBlock("rule", []string{r.Name})
+			rbb := rb.Body()
+			if r.Enabled {
+				rbb.SetAttributeValue("enabled", cty.BoolVal(r.Enabled))
+			}
+			rbb.SetAttributeValue("condition", cty.StringVal(r.Condition))
+			if r.Severity != "" {
+				rbb.SetAttributeValue("severity", cty.StringVal(r.Severity))
+			}
+			if len(r.Channels) > 0 {
+				rbb.SetAttributeValue("channels", toCtyStringList(r.Channels))
+			}
+			if r.Cooldown != "" {
+				rbb.SetAttributeValue("cooldown", cty.StringVal(r.Cooldown))
+			}
+			if len(r.GroupBy) > 0 {
+				rbb.SetAttributeValue("group_by", toCtyStringList(r.GroupBy))
+			}
+			if r.EscalateAfter != "" {
+				rbb.SetAttributeValue("escalate_after", cty.StringVal(r.EscalateAfter))
+			}
+			if len(r.EscalateChannels) > 0 {
+				rbb.SetAttributeValue("escalate_to", toCtyStringList(r.EscalateChannels))
 			}
-			// ... other channel fields
 		}
 	}
 
@@ -945,6 +1485,12 @@ func (cf *ConfigFile) appendPolicyBlock(body *hclwrite.Body, pol *Policy) {
 	if pol.Masquerade != nil {
 		blockBody.SetAttributeValue("masquerade", cty.BoolVal(*pol.Masquerade))
 	}
+	if pol.FlowOffload != nil {
+		blockBody.SetAttributeValue("flow_offload", cty.BoolVal(*pol.FlowOffload))
+	}
+	if len(pol.Helpers) > 0 {
+		blockBody.SetAttributeValue("helpers", toCtyStringList(pol.Helpers))
+	}
 	if pol.Log {
 		blockBody.SetAttributeValue("log", cty.BoolVal(pol.Log))
 	}
@@ -984,13 +1530,13 @@ func (cf *ConfigFile) appendRuleBlock(body *hclwrite.Body, rule *PolicyRule) {
 		blockBody.SetAttributeValue("dest_port", cty.NumberIntVal(int64(rule.DestPort)))
 	}
 	if len(rule.DestPorts) > 0 {
-		blockBody.SetAttributeValue("dest_ports", toCtyIntList(rule.DestPorts))
+		blockBody.SetAttributeValue("dest_ports", toCtyPortList(rule.DestPorts))
 	}
 	if rule.SrcPort != 0 {
 		blockBody.SetAttributeValue("src_port", cty.NumberIntVal(int64(rule.SrcPort)))
 	}
 	if len(rule.SrcPorts) > 0 {
-		blockBody.SetAttributeValue("src_ports", toCtyIntList(rule.SrcPorts))
+		blockBody.SetAttributeValue("src_ports", toCtyPortList(rule.SrcPorts))
 	}
 	if len(rule.Services) > 0 {
 		blockBody.SetAttributeValue("services", toCtyStringList(rule.Services))
@@ -1010,6 +1556,9 @@ func (cf *ConfigFile) appendRuleBlock(body *hclwrite.Body, rule *PolicyRule) {
 
 	// Action (required)
 	blockBody.SetAttributeValue("action", cty.StringVal(rule.Action))
+	if rule.Helper != "" {
+		blockBody.SetAttributeValue("helper", cty.StringVal(rule.Helper))
+	}
 
 	// Advanced match options
 	if rule.InvertSrc {
@@ -1228,6 +1777,12 @@ func (cf *ConfigFile) syncNAT() error {
 		if nat.Hairpin {
 			blockBody.SetAttributeValue("hairpin", cty.BoolVal(nat.Hairpin))
 		}
+		if len(nat.ToIPs) > 0 {
+			blockBody.SetAttributeValue("to_ips", toCtyStringList(nat.ToIPs))
+		}
+		if nat.LoadBalance != "" {
+			blockBody.SetAttributeValue("load_balance", cty.StringVal(nat.LoadBalance))
+		}
 	}
 
 	return nil
@@ -1373,14 +1928,19 @@ func toCtyStringList(strs []string) cty.Value {
 	return cty.ListVal(vals)
 }
 
-// toCtyIntList converts a []int to cty.Value list
-func toCtyIntList(ints []int) cty.Value {
-	if len(ints) == 0 {
-		return cty.ListValEmpty(cty.Number)
+// toCtyPortList serializes a port list, writing single ports as numbers and
+// ranges/sets as strings. HCL unifies the mixed tuple back into strings.
+func toCtyPortList(ports []PortSpec) cty.Value {
+	if len(ports) == 0 {
+		return cty.ListValEmpty(cty.String)
 	}
-	vals := make([]cty.Value, len(ints))
-	for i, n := range ints {
-		vals[i] = cty.NumberIntVal(int64(n))
+	vals := make([]cty.Value, len(ports))
+	for i, p := range ports {
+		if n, err := strconv.Atoi(strings.TrimSpace(string(p))); err == nil {
+			vals[i] = cty.NumberIntVal(int64(n))
+		} else {
+			vals[i] = cty.StringVal(string(p))
+		}
 	}
-	return cty.ListVal(vals)
+	return cty.TupleVal(vals)
 }
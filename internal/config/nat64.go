@@ -0,0 +1,116 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"time"
+)
+
+// NAT64 defaults.
+const (
+	DefaultNAT64Prefix         = "64:ff9b::/96" // RFC 6052 well-known prefix
+	DefaultNAT64Pool4          = "100.127.255.0/24"
+	DefaultNAT64Interface      = "nat64"
+	DefaultNAT64BindingTimeout = 2 * time.Hour
+)
+
+// NAT64Config configures the translator that lets IPv6-only clients of
+// zones with services { nat64 = true } reach IPv4 hosts. Clients address
+// an IPv4 host by embedding it in Prefix (RFC 6052), usually via DNS64.
+// The translator maps each client to an address from Pool4, and the pool
+// is masqueraded out the uplinks. Zones with the service enabled use the
+// defaults when this block is omitted.
+type NAT64Config struct {
+	// IPv6 prefix IPv4 addresses are embedded in. Length 32, 40, 48, 56,
+	// 64 or 96. The well-known prefix never reaches private IPv4 hosts.
+	// @default: "64:ff9b::/96"
+	Prefix string `hcl:"prefix,optional" json:"prefix,omitempty"`
+	// Private IPv4 range clients are mapped to, one address each. The first
+	// address is the translator's own.
+	// @default: "100.127.255.0/24"
+	Pool4 string `hcl:"pool4,optional" json:"pool4,omitempty"`
+	// TUN device the translator runs on.
+	// @default: "nat64"
+	Interface string `hcl:"interface,optional" json:"interface,omitempty"`
+	// How long an idle client keeps its pool address, e.g. "30m".
+	// @default: "2h"
+	BindingTimeout string `hcl:"binding_timeout,optional" json:"binding_timeout,omitempty"`
+}
+
+// NAT64Zones lists the zones with the NAT64 service enabled.
+func (c *Config) NAT64Zones() []string {
+	var zones []string
+	for _, z := range c.Zones {
+		if z.Services != nil && z.Services.NAT64 {
+			zones = append(zones, z.Name)
+		}
+	}
+	return zones
+}
+
+// NAT64Settings returns the NAT64 configuration with defaults applied. It
+// is nil when no zone enables NAT64.
+func (c *Config) NAT64Settings() *NAT64Config {
+	if len(c.NAT64Zones()) == 0 {
+		return nil
+	}
+	n := NAT64Config{}
+	if c.NAT64 != nil {
+		n = *c.NAT64
+	}
+	if n.Prefix == "" {
+		n.Prefix = DefaultNAT64Prefix
+	}
+	if n.Pool4 == "" {
+		n.Pool4 = DefaultNAT64Pool4
+	}
+	if n.Interface == "" {
+		n.Interface = DefaultNAT64Interface
+	}
+	return &n
+}
+
+// BindingDuration returns how long an idle client keeps its pool address.
+func (n *NAT64Config) BindingDuration() time.Duration {
+	if d, err := time.ParseDuration(n.BindingTimeout); err == nil && d > 0 {
+		return d
+	}
+	return DefaultNAT64BindingTimeout
+}
+
+// DNS64Prefix returns the prefix DNS64 synthesizes addresses in for a serve
+// block: its own dns64_prefix, else the NAT64 prefix, else the well-known
+// prefix.
+func (c *Config) DNS64Prefix(serve *DNSServe) string {
+	if serve.DNS64Prefix != "" {
+		return serve.DNS64Prefix
+	}
+	if c.NAT64 != nil && c.NAT64.Prefix != "" {
+		return c.NAT64.Prefix
+	}
+	return DefaultNAT64Prefix
+}
+
+// ParseNAT64Prefix parses an RFC 6052 IPv4-embedded IPv6 prefix. The
+// length must be one the RFC defines, and bits 64 to 71 (the "u" octet)
+// must be zero.
+func ParseNAT64Prefix(s string) (netip.Prefix, error) {
+	p, err := netip.ParsePrefix(s)
+	if err != nil || !p.Addr().Is6() || p.Addr().Is4In6() {
+		return netip.Prefix{}, fmt.Errorf("%q is not an IPv6 prefix", s)
+	}
+	switch p.Bits() {
+	case 32, 40, 48, 56, 64, 96:
+	default:
+		return netip.Prefix{}, fmt.Errorf("prefix length must be 32, 40, 48, 56, 64 or 96, got /%d", p.Bits())
+	}
+	if p.Masked() != p {
+		return netip.Prefix{}, fmt.Errorf("%q has host bits set", s)
+	}
+	if p.Addr().As16()[8] != 0 {
+		return netip.Prefix{}, fmt.Errorf("bits 64 to 71 of %q must be zero", s)
+	}
+	return p, nil
+}
@@ -36,6 +36,14 @@ type Interface struct {
 	// Enable DHCPv6 client for IPv6 address assignment.
 	// @default: false
 	DHCPv6 bool `hcl:"dhcp_v6,optional" json:"dhcp_v6"`
+	// Prefix length hint for DHCPv6 prefix delegation (IA_PD). The native
+	// DHCPv6 client always requests a delegated prefix; this only tells
+	// the ISP what size is wanted.
+	// @example: 56
+	DHCPv6PDLength int `hcl:"dhcp_v6_pd_length,optional" json:"dhcp_v6_pd_length,omitempty"`
+	// Assign this interface a /64 from a prefix delegated to an upstream
+	// DHCPv6 interface.
+	DelegatedPrefix *DelegatedPrefix `hcl:"delegated_prefix,block" json:"delegated_prefix,omitempty"`
 	// Enable Router Advertisements (for IPv6 server mode).
 	// @default: false
 	RA bool `hcl:"ra,optional" json:"ra"`
@@ -86,6 +94,22 @@ type Interface struct {
 	TLS *TLSConfig `hcl:"tls,block" json:"tls,omitempty"` // TLS/Certificate configuration for this interface
 }
 
+// DelegatedPrefix carves a /64 for a downstream interface out of a prefix
+// obtained by DHCPv6 prefix delegation on an upstream interface. The
+// address, RA prefix and zone networks follow the delegation as it changes.
+type DelegatedPrefix struct {
+	// Upstream interface running the DHCPv6 client.
+	// @example: "eth0"
+	From string `hcl:"from" json:"from"`
+	// Index of the /64 within the delegated prefix. Omit to take the lowest
+	// free index, in interface order.
+	// @example: 1
+	SubnetID *int `hcl:"subnet_id,optional" json:"subnet_id,omitempty"`
+	// Interface identifier of the router's own address in the /64.
+	// @default: "::1"
+	HostID string `hcl:"host_id,optional" json:"host_id,omitempty"`
+}
+
 // Bond represents the configuration for a bonding interface.
 type Bond struct {
 	Mode       string   `hcl:"mode,optional" json:"mode,omitempty"`
@@ -273,6 +297,12 @@ type FRRConfig struct {
 	Enabled bool  `hcl:"enabled,optional" json:"enabled,omitempty"`
 	OSPF    *OSPF `hcl:"ospf,block" json:"ospf,omitempty"`
 	BGP     *BGP  `hcl:"bgp,block" json:"bgp,omitempty"`
+
+	// Routing policy shared by all protocols
+	PrefixLists    []PrefixList    `hcl:"prefix_list,block" json:"prefix_lists,omitempty"`
+	CommunityLists []CommunityList `hcl:"community_list,block" json:"community_lists,omitempty"`
+	RouteMaps      []RouteMap      `hcl:"route_map,block" json:"route_maps,omitempty"`
+	BFDProfiles    []BFDProfile    `hcl:"bfd_profile,block" json:"bfd_profiles,omitempty"`
 }
 
 // OSPF configuration.
@@ -293,13 +323,96 @@ type BGP struct {
 	ASN       int        `hcl:"asn,optional" json:"asn,omitempty"`
 	RouterID  string     `hcl:"router_id,optional" json:"router_id,omitempty"`
 	Neighbors []Neighbor `hcl:"neighbor,block" json:"neighbor,omitempty"`
-	Networks  []string   `hcl:"networks,optional" json:"networks,omitempty"`
+	Networks  []string   `hcl:"networks,optional" json:"networks,omitempty"` // IPv4 and IPv6 prefixes to originate
 }
 
 // Neighbor BGP peer configuration.
 type Neighbor struct {
-	IP        string `hcl:"ip,label" json:"ip"`
-	RemoteASN int    `hcl:"remote_asn,optional" json:"remote_asn,omitempty"`
+	IP          string       `hcl:"ip,label" json:"ip"`
+	RemoteASN   int          `hcl:"remote_asn,optional" json:"remote_asn,omitempty"`
+	Description string       `hcl:"description,optional" json:"description,omitempty"`
+	Password    SecureString `hcl:"password,optional" json:"password,omitempty"` // TCP MD5 signature (RFC 2385)
+
+	// EBGPMultihop allows eBGP sessions to peers that are not directly
+	// connected, up to this many hops (0 = directly connected only)
+	EBGPMultihop int    `hcl:"ebgp_multihop,optional" json:"ebgp_multihop,omitempty"`
+	UpdateSource string `hcl:"update_source,optional" json:"update_source,omitempty"` // Interface or address to source the session from
+
+	// BFD enables fast failure detection, optionally with a named bfd_profile
+	BFD        bool   `hcl:"bfd,optional" json:"bfd,omitempty"`
+	BFDProfile string `hcl:"bfd_profile,optional" json:"bfd_profile,omitempty"`
+
+	// AddressFamilies activates the peer per family. Without any, the peer
+	// is activated for the family of its own address.
+	AddressFamilies []NeighborAddressFamily `hcl:"address_family,block" json:"address_families,omitempty"`
+}
+
+// NeighborAddressFamily activates a BGP peer for one unicast family and
+// attaches the policy applied to it.
+type NeighborAddressFamily struct {
+	Family              string `hcl:"family,label" json:"family"` // "ipv4" or "ipv6"
+	RouteMapIn          string `hcl:"route_map_in,optional" json:"route_map_in,omitempty"`
+	RouteMapOut         string `hcl:"route_map_out,optional" json:"route_map_out,omitempty"`
+	PrefixListIn        string `hcl:"prefix_list_in,optional" json:"prefix_list_in,omitempty"`
+	PrefixListOut       string `hcl:"prefix_list_out,optional" json:"prefix_list_out,omitempty"`
+	NextHopSelf         bool   `hcl:"next_hop_self,optional" json:"next_hop_self,omitempty"`
+	SoftReconfiguration bool   `hcl:"soft_reconfiguration,optional" json:"soft_reconfiguration,omitempty"` // Keep unfiltered routes for policy changes
+	MaxPrefix           int    `hcl:"max_prefix,optional" json:"max_prefix,omitempty"`                     // Tear down the session above this many prefixes
+	DefaultOriginate    bool   `hcl:"default_originate,optional" json:"default_originate,omitempty"`
+}
+
+// PrefixList is an ordered prefix filter ("ip prefix-list" in FRR).
+// All rules of a list must be of the same address family.
+type PrefixList struct {
+	Name  string           `hcl:"name,label" json:"name"`
+	Rules []PrefixListRule `hcl:"rule,block" json:"rules"`
+}
+
+// PrefixListRule matches a prefix, optionally with a range of lengths.
+type PrefixListRule struct {
+	Seq    int    `hcl:"seq,optional" json:"seq,omitempty"`       // Default: 5, 10, 15, ... in order
+	Action string `hcl:"action,optional" json:"action,omitempty"` // "permit" (default) or "deny"
+	Prefix string `hcl:"prefix" json:"prefix"`                    // CIDR, or "any"
+	GE     int    `hcl:"ge,optional" json:"ge,omitempty"`         // Match prefix lengths >= GE
+	LE     int    `hcl:"le,optional" json:"le,omitempty"`         // Match prefix lengths <= LE
+}
+
+// CommunityList is a standard BGP community list. A route matches if it
+// carries any of the listed communities.
+type CommunityList struct {
+	Name        string   `hcl:"name,label" json:"name"`
+	Action      string   `hcl:"action,optional" json:"action,omitempty"` // "permit" (default) or "deny"
+	Communities []string `hcl:"communities" json:"communities"`          // e.g. "65000:100", "no-export"
+}
+
+// RouteMap is an ordered list of match/set rules applied to routes.
+type RouteMap struct {
+	Name  string         `hcl:"name,label" json:"name"`
+	Rules []RouteMapRule `hcl:"rule,block" json:"rules"`
+}
+
+// RouteMapRule matches routes and rewrites their attributes. A rule with no
+// match conditions matches every route.
+type RouteMapRule struct {
+	Seq    int    `hcl:"seq,optional" json:"seq,omitempty"`       // Default: 10, 20, 30, ... in order
+	Action string `hcl:"action,optional" json:"action,omitempty"` // "permit" (default) or "deny"
+
+	MatchPrefixList string `hcl:"match_prefix_list,optional" json:"match_prefix_list,omitempty"`
+	MatchCommunity  string `hcl:"match_community,optional" json:"match_community,omitempty"`
+
+	SetLocalPreference   int      `hcl:"set_local_preference,optional" json:"set_local_preference,omitempty"`
+	SetMetric            *int     `hcl:"set_metric,optional" json:"set_metric,omitempty"` // MED
+	SetCommunity         []string `hcl:"set_community,optional" json:"set_community,omitempty"`
+	SetCommunityAdditive bool     `hcl:"set_community_additive,optional" json:"set_community_additive,omitempty"` // Add to, rather than replace, existing communities
+	SetASPathPrepend     []int    `hcl:"set_as_path_prepend,optional" json:"set_as_path_prepend,omitempty"`
+}
+
+// BFDProfile is a named set of BFD timers that neighbors can reference.
+type BFDProfile struct {
+	Name             string `hcl:"name,label" json:"name"`
+	DetectMultiplier int    `hcl:"detect_multiplier,optional" json:"detect_multiplier,omitempty"` // Default: 3
+	ReceiveInterval  int    `hcl:"receive_interval,optional" json:"receive_interval,omitempty"`   // Milliseconds (default: 300)
+	TransmitInterval int    `hcl:"transmit_interval,optional" json:"transmit_interval,omitempty"` // Milliseconds (default: 300)
 }
 
 // QoSPolicy defines Quality of Service settings for an interface.
@@ -307,9 +420,10 @@ type QoSPolicy struct {
 	Name         string     `hcl:"name,label" json:"name"`
 	Interface    string     `hcl:"interface" json:"interface"` // Interface to apply QoS
 	Enabled      bool       `hcl:"enabled,optional" json:"enabled"`
-	Direction    string     `hcl:"direction,optional" json:"direction"` // "ingress", "egress", "both" (default: both)
-	DownloadMbps int        `hcl:"download_mbps,optional" json:"download_mbps"`
+	Direction    string     `hcl:"direction,optional" json:"direction"`         // "ingress", "egress", "both" (default: both)
+	DownloadMbps int        `hcl:"download_mbps,optional" json:"download_mbps"` // Shaped on an IFB device fed from the interface's ingress
 	UploadMbps   int        `hcl:"upload_mbps,optional" json:"upload_mbps"`
+	QueueType    string     `hcl:"queue_type,optional" json:"queue_type,omitempty"` // Default leaf queue for classes (and unclassified traffic)
 	Classes      []QoSClass `hcl:"class,block" json:"class"`
 	Rules        []QoSRule  `hcl:"rule,block" json:"rule"` // Traffic classification rules
 }
@@ -321,7 +435,7 @@ type QoSClass struct {
 	Rate      string `hcl:"rate,optional" json:"rate"`             // Guaranteed rate e.g., "10mbit" or "10%"
 	Ceil      string `hcl:"ceil,optional" json:"ceil"`             // Maximum rate
 	Burst     string `hcl:"burst,optional" json:"burst"`           // Burst size
-	QueueType string `hcl:"queue_type,optional" json:"queue_type"` // "fq_codel", "sfq", "pfifo" (default: fq_codel)
+	QueueType string `hcl:"queue_type,optional" json:"queue_type"` // "fq_codel", "cake", "sfq", "pfifo" (default: policy queue_type, then fq_codel)
 }
 
 // QoSRule classifies traffic into QoS classes.
@@ -2,6 +2,8 @@
 
 package config
 
+import "strings"
+
 // Policy defines traffic rules between zones.
 // Rules are evaluated in order - first match wins.
 type Policy struct {
@@ -22,6 +24,18 @@ type Policy struct {
 	// false = never masquerade
 	Masquerade *bool `hcl:"masquerade,optional" json:"masquerade,omitempty"`
 
+	// FlowOffload controls whether established connections through this
+	// policy are offloaded to the flowtable (see enable_flow_offload).
+	// Offloaded packets skip the ruleset, QoS marking and per-packet
+	// accounting; set false when those must see every packet.
+	// nil = follow enable_flow_offload
+	FlowOffload *bool `hcl:"flow_offload,optional" json:"flow_offload,omitempty"`
+
+	// Helpers assigns conntrack helpers (ALGs) to new connections between
+	// the zones on each helper's standard port, e.g. ["ftp", "sip"].
+	// No helper is attached unless a policy or rule names it.
+	Helpers []string `hcl:"helpers,optional" json:"helpers,omitempty"`
+
 	Log       bool         `hcl:"log,optional" json:"log,omitempty"`               // Log packets matching default action
 	LogPrefix string       `hcl:"log_prefix,optional" json:"log_prefix,omitempty"` // Prefix for log messages
 	Rules     []PolicyRule `hcl:"rule,block" json:"rule,omitempty"`
@@ -103,17 +117,17 @@ type PolicyRule struct {
 	InsertAfter string `hcl:"insert_after,optional" json:"insert_after,omitempty"` // Insert after rule with this ID/name
 
 	// Match conditions
-	Protocol  string   `hcl:"proto,optional" json:"proto,omitempty"`
-	DestPort  int      `hcl:"dest_port,optional" json:"dest_port,omitempty"`
-	DestPorts []int    `hcl:"dest_ports,optional" json:"dest_ports,omitempty"` // Multiple ports
-	SrcPort   int      `hcl:"src_port,optional" json:"src_port,omitempty"`
-	SrcPorts  []int    `hcl:"src_ports,optional" json:"src_ports,omitempty"`
-	Service   string   `hcl:"service,optional" json:"service,omitempty"`       // Service Macro (e.g. "ssh") - expands to Proto/Port
-	Services  []string `hcl:"services,optional" json:"services,omitempty"`     // Service names like "http", "ssh"
-	SrcIP     string   `hcl:"src_ip,optional" json:"src_ip,omitempty"`         // Source IP/CIDR
-	SrcIPSet  string   `hcl:"src_ipset,optional" json:"src_ipset,omitempty"`   // Source IPSet name
-	DestIP    string   `hcl:"dest_ip,optional" json:"dest_ip,omitempty"`       // Destination IP/CIDR
-	DestIPSet string   `hcl:"dest_ipset,optional" json:"dest_ipset,omitempty"` // Destination IPSet name
+	Protocol  string     `hcl:"proto,optional" json:"proto,omitempty"`
+	DestPort  int        `hcl:"dest_port,optional" json:"dest_port,omitempty"`
+	DestPorts []PortSpec `hcl:"dest_ports,optional" json:"dest_ports,omitempty"` // Ports, ranges or sets: [80, "6881-6999", "8000-8080, 9000"]
+	SrcPort   int        `hcl:"src_port,optional" json:"src_port,omitempty"`
+	SrcPorts  []PortSpec `hcl:"src_ports,optional" json:"src_ports,omitempty"`
+	Service   string     `hcl:"service,optional" json:"service,omitempty"`       // Service Macro (e.g. "ssh") - expands to Proto/Port
+	Services  []string   `hcl:"services,optional" json:"services,omitempty"`     // Service names like "http", "ssh"
+	SrcIP     string     `hcl:"src_ip,optional" json:"src_ip,omitempty"`         // Source IP/CIDR
+	SrcIPSet  string     `hcl:"src_ipset,optional" json:"src_ipset,omitempty"`   // Source IPSet name
+	DestIP    string     `hcl:"dest_ip,optional" json:"dest_ip,omitempty"`       // Destination IP/CIDR
+	DestIPSet string     `hcl:"dest_ipset,optional" json:"dest_ipset,omitempty"` // Destination IPSet name
 
 	// Additional match conditions
 	SrcZone      string `hcl:"src_zone,optional" json:"src_zone,omitempty"`           // Override policy's From zone
@@ -147,6 +161,10 @@ type PolicyRule struct {
 	Action     string `hcl:"action" json:"action"`                              // accept, drop, reject, jump, return, log
 	JumpTarget string `hcl:"jump_target,optional" json:"jump_target,omitempty"` // Target chain for jump action
 
+	// Helper assigns a conntrack helper (ALG) to the connections this rule
+	// accepts, for services on non-standard ports. Needs a matching proto.
+	Helper string `hcl:"helper,optional" json:"helper,omitempty"`
+
 	// Logging & accounting
 	Log       bool   `hcl:"log,optional" json:"log,omitempty"`
 	LogPrefix string `hcl:"log_prefix,optional" json:"log_prefix,omitempty"`
@@ -169,7 +187,7 @@ type PolicyRule struct {
 type NATRule struct {
 	Name         string `hcl:"name,label" json:"name"`
 	Description  string `hcl:"description,optional" json:"description,omitempty"`
-	Type         string `hcl:"type" json:"type"`                                      // masquerade, dnat, snat, redirect
+	Type         string `hcl:"type" json:"type"`                                      // masquerade, dnat, snat, netmap
 	Protocol     string `hcl:"proto,optional" json:"proto,omitempty"`                 // tcp, udp
 	OutInterface string `hcl:"out_interface,optional" json:"out_interface,omitempty"` // for masquerade/snat
 	InInterface  string `hcl:"in_interface,optional" json:"in_interface,omitempty"`   // for dnat
@@ -178,7 +196,62 @@ type NATRule struct {
 	Mark         int    `hcl:"mark,optional" json:"mark,omitempty"`                   // FWMark match
 	DestPort     string `hcl:"dest_port,optional" json:"dest_port,omitempty"`         // Dest Port match (supports ranges "80-90")
 	ToIP         string `hcl:"to_ip,optional" json:"to_ip,omitempty"`                 // Target IP for DNAT
-	ToPort       string `hcl:"to_port,optional" json:"to_port,omitempty"`             // Target Port for DNAT
+	ToPort       string `hcl:"to_port,optional" json:"to_port,omitempty"`             // Target Port for DNAT (a range remaps a dest_port range)
 	SNATIP       string `hcl:"snat_ip,optional" json:"snat_ip,omitempty"`             // for snat (Target IP)
 	Hairpin      bool   `hcl:"hairpin,optional" json:"hairpin,omitempty"`             // Enable Hairpin NAT (NAT Reflection)
+
+	// Load balancing: DNAT across ToIP plus ToIPs.
+	ToIPs       []string `hcl:"to_ips,optional" json:"to_ips,omitempty"`             // Additional DNAT backends
+	LoadBalance string   `hcl:"load_balance,optional" json:"load_balance,omitempty"` // round_robin (default), hash (sticky per source IP)
+}
+
+// NAT load-balancing modes.
+const (
+	NATBalanceRoundRobin = "round_robin"
+	NATBalanceHash       = "hash"
+)
+
+// NATHashSeed is the jhash seed used for hash load balancing. It is fixed
+// so that the simulator can reproduce the kernel's backend selection.
+const NATHashSeed uint32 = 0x464c5957
+
+// MaxNATPortRemap bounds the size of a dest_port -> to_port range remap,
+// which is compiled to an explicit per-port map.
+const MaxNATPortRemap = 4096
+
+// Backends returns the DNAT targets of the rule: ToIP followed by ToIPs,
+// without duplicates.
+func (r NATRule) Backends() []string {
+	var backends []string
+	seen := make(map[string]bool)
+	for _, ip := range append([]string{r.ToIP}, r.ToIPs...) {
+		if ip == "" || seen[ip] {
+			continue
+		}
+		seen[ip] = true
+		backends = append(backends, ip)
+	}
+	return backends
+}
+
+// PortRemap returns the source and target ranges when DestPort and ToPort
+// are both ranges of equal size, i.e. each port is shifted by a fixed
+// offset (10000-10100 -> 20000-20100). ok is false for any other form.
+func (r NATRule) PortRemap() (from, to PortRange, ok bool) {
+	if !strings.Contains(r.ToPort, "-") || r.DestPort == "" {
+		return PortRange{}, PortRange{}, false
+	}
+	fromRanges, err := PortSpec(r.DestPort).Ranges()
+	if err != nil || len(fromRanges) != 1 {
+		return PortRange{}, PortRange{}, false
+	}
+	toRanges, err := PortSpec(r.ToPort).Ranges()
+	if err != nil || len(toRanges) != 1 {
+		return PortRange{}, PortRange{}, false
+	}
+	from, to = fromRanges[0], toRanges[0]
+	if from.End-from.Start != to.End-to.Start {
+		return PortRange{}, PortRange{}, false
+	}
+	return from, to, true
 }
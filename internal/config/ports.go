@@ -0,0 +1,133 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PortSpec is one entry of a rule's port list. It may be a single port
+// ("443"), an inclusive range ("6881-6999") or a comma-separated mix of
+// both ("6881-6999, 51413"). Bare numbers are accepted in HCL and JSON:
+//
+//	dest_ports = ["6881-6999", 51413]
+type PortSpec string
+
+// PortRange is an inclusive port range. Start == End for a single port.
+type PortRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Contains reports whether port falls within the range.
+func (r PortRange) Contains(port int) bool {
+	return port >= r.Start && port <= r.End
+}
+
+// String renders the range in nftables syntax ("80" or "6881-6999").
+func (r PortRange) String() string {
+	if r.Start == r.End {
+		return strconv.Itoa(r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// PortSpecs converts plain port numbers to a port list.
+func PortSpecs(ports ...int) []PortSpec {
+	specs := make([]PortSpec, len(ports))
+	for i, p := range ports {
+		specs[i] = PortSpec(strconv.Itoa(p))
+	}
+	return specs
+}
+
+// Ranges parses the spec into its port ranges.
+func (p PortSpec) Ranges() ([]PortRange, error) {
+	var ranges []PortRange
+	for _, part := range strings.Split(string(p), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		r, err := parsePortRange(part)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r)
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("empty port specification")
+	}
+	return ranges, nil
+}
+
+func parsePortRange(s string) (PortRange, error) {
+	startStr, endStr, isRange := strings.Cut(s, "-")
+	start, err := parsePortNumber(startStr)
+	if err != nil {
+		return PortRange{}, err
+	}
+	end := start
+	if isRange {
+		if end, err = parsePortNumber(endStr); err != nil {
+			return PortRange{}, err
+		}
+		if end < start {
+			return PortRange{}, fmt.Errorf("invalid port range %q: start exceeds end", s)
+		}
+	}
+	return PortRange{Start: start, End: end}, nil
+}
+
+func parsePortNumber(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	port, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	if port < 1 || port > 65535 {
+		return 0, fmt.Errorf("port must be between 1 and 65535, got %d", port)
+	}
+	return port, nil
+}
+
+// ParsePortSpecs flattens a port list into ranges.
+func ParsePortSpecs(specs []PortSpec) ([]PortRange, error) {
+	var ranges []PortRange
+	for _, spec := range specs {
+		r, err := spec.Ranges()
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r...)
+	}
+	return ranges, nil
+}
+
+// MarshalJSON emits single ports as numbers so existing API clients keep
+// seeing [80, 443]; ranges and sets are emitted as strings.
+func (p PortSpec) MarshalJSON() ([]byte, error) {
+	if n, err := strconv.Atoi(strings.TrimSpace(string(p))); err == nil {
+		return json.Marshal(n)
+	}
+	return json.Marshal(string(p))
+}
+
+// UnmarshalJSON accepts either a number or a string.
+func (p *PortSpec) UnmarshalJSON(data []byte) error {
+	var n int
+	if err := json.Unmarshal(data, &n); err == nil {
+		*p = PortSpec(strconv.Itoa(n))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("port must be a number or string: %w", err)
+	}
+	*p = PortSpec(s)
+	return nil
+}
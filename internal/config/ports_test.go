@@ -0,0 +1,94 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPortSpecRanges(t *testing.T) {
+	tests := []struct {
+		spec    PortSpec
+		want    []PortRange
+		wantErr bool
+	}{
+		{spec: "443", want: []PortRange{{443, 443}}},
+		{spec: "6881-6999", want: []PortRange{{6881, 6999}}},
+		{spec: "6881-6999, 51413", want: []PortRange{{6881, 6999}, {51413, 51413}}},
+		{spec: " 80 , 443 ", want: []PortRange{{80, 80}, {443, 443}}},
+		{spec: "", wantErr: true},
+		{spec: "0", wantErr: true},
+		{spec: "70000", wantErr: true},
+		{spec: "90-80", wantErr: true},
+		{spec: "http", wantErr: true},
+		{spec: "80-", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.spec), func(t *testing.T) {
+			got, err := tt.spec.Ranges()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Ranges() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Ranges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPortSpecJSON(t *testing.T) {
+	var rule PolicyRule
+	if err := json.Unmarshal([]byte(`{"dest_ports":[80,"6881-6999"]}`), &rule); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	want := []PortSpec{"80", "6881-6999"}
+	if !reflect.DeepEqual(rule.DestPorts, want) {
+		t.Errorf("DestPorts = %v, want %v", rule.DestPorts, want)
+	}
+
+	out, err := json.Marshal(rule.DestPorts)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(out) != `[80,"6881-6999"]` {
+		t.Errorf("Marshal() = %s, want [80,\"6881-6999\"]", out)
+	}
+}
+
+func TestPortSpecHCLRoundTrip(t *testing.T) {
+	input := &Config{
+		SchemaVersion: "1.0",
+		Policies: []Policy{
+			{
+				Name: "lan-to-wan", From: "lan", To: "wan", Action: "accept",
+				Rules: []PolicyRule{
+					{
+						Name:      "torrent",
+						Action:    "accept",
+						Protocol:  "tcp",
+						DestPorts: []PortSpec{"6881-6999", "51413"},
+					},
+				},
+			},
+		},
+	}
+
+	hclBytes, err := GenerateHCL(input)
+	if err != nil {
+		t.Fatalf("Failed to serialize to HCL: %v", err)
+	}
+
+	output, err := LoadHCL(hclBytes, "test.hcl")
+	if err != nil {
+		t.Logf("Generated HCL:\n%s", string(hclBytes))
+		t.Fatalf("Failed to deserialize HCL: %v", err)
+	}
+
+	got := output.Policies[0].Rules[0].DestPorts
+	if !reflect.DeepEqual(got, input.Policies[0].Rules[0].DestPorts) {
+		t.Errorf("DestPorts = %v, want %v", got, input.Policies[0].Rules[0].DestPorts)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DeviceQuota limits how much traffic a device, or a device group, may use
+// per day, week or month. Usage comes from the flow analytics store.
+type DeviceQuota struct {
+	Name string `hcl:"name,label" json:"name"`
+
+	// MAC addresses that each get their own counter.
+	Devices []string `hcl:"devices,optional" json:"devices,omitempty"`
+	// Device group (ID or name). Members share one counter unless per_device is set.
+	// @ref: DeviceGroup
+	Group     string `hcl:"group,optional" json:"group,omitempty"`
+	PerDevice bool   `hcl:"per_device,optional" json:"per_device,omitempty"`
+
+	// Byte limits, e.g. "2GB", "500MiB". Empty means no limit for that period.
+	Daily   string `hcl:"daily,optional" json:"daily,omitempty"`
+	Weekly  string `hcl:"weekly,optional" json:"weekly,omitempty"`   // Weeks start on Monday
+	Monthly string `hcl:"monthly,optional" json:"monthly,omitempty"` // Months start on reset_day
+
+	// What happens once a limit is crossed.
+	// @enum: block, throttle
+	// @default: "block"
+	Action string `hcl:"action,optional" json:"action,omitempty"`
+	// QoS class throttled devices are moved into (action = "throttle").
+	// @ref: QoSPolicy
+	QoSPolicy string `hcl:"qos_policy,optional" json:"qos_policy,omitempty"`
+	QoSClass  string `hcl:"qos_class,optional" json:"qos_class,omitempty"`
+
+	// Raise a warning alert when usage reaches this share of a limit (0 disables).
+	// @default: 80
+	WarnPercent *int `hcl:"warn_percent,optional" json:"warn_percent,omitempty"`
+	// Day of the month monthly counters reset (1-28).
+	// @default: 1
+	ResetDay int `hcl:"reset_day,optional" json:"reset_day,omitempty"`
+}
+
+// byteUnits maps size suffixes to multipliers. Both SI and IEC suffixes
+// are accepted; a bare number is bytes.
+var byteUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"kb":  1000,
+	"mb":  1000 * 1000,
+	"gb":  1000 * 1000 * 1000,
+	"tb":  1000 * 1000 * 1000 * 1000,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+// ParseByteSize parses sizes like "500MB", "2GB" or "1.5TiB" into bytes.
+func ParseByteSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	num, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+
+	mult, ok := byteUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("unknown size unit %q", s[i:])
+	}
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(v * float64(mult)), nil
+}
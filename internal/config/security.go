@@ -4,8 +4,12 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
+
+	"grimm.is/flywall/internal/install"
 )
 
 // VPNConfig configures VPN integrations.
@@ -181,6 +185,22 @@ type WireGuardConfig struct {
 	// Hooks
 	PostUp   []string `hcl:"post_up,optional" json:"post_up,omitempty"`
 	PostDown []string `hcl:"post_down,optional" json:"post_down,omitempty"`
+
+	// Public host:port clients connect to. Required to provision clients.
+	ClientEndpoint string `hcl:"client_endpoint,optional" json:"client_endpoint,omitempty"`
+
+	// Routes sent through the tunnel by provisioned clients (default: all traffic)
+	ClientAllowedIPs []string `hcl:"client_allowed_ips,optional" json:"client_allowed_ips,omitempty"`
+
+	// DNS servers for provisioned clients (default: dns)
+	ClientDNS []string `hcl:"client_dns,optional" json:"client_dns,omitempty"`
+
+	// Peer keys older than this are due for rotation, e.g. "2160h" (90 days).
+	// Empty disables rotation reminders.
+	KeyRotation string `hcl:"key_rotation,optional" json:"key_rotation,omitempty"`
+
+	// Disable peers with no handshake for this long, e.g. "720h". Empty never disables.
+	IdleTimeout string `hcl:"idle_timeout,optional" json:"idle_timeout,omitempty"`
 }
 
 // WireGuardPeerConfig configures a WireGuard peer.
@@ -202,6 +222,36 @@ type WireGuardPeerConfig struct {
 
 	// Keepalive interval in seconds (useful for NAT traversal)
 	PersistentKeepalive int `hcl:"persistent_keepalive,optional" json:"persistent_keepalive"`
+
+	// When the peer's keys were generated (RFC 3339). Set by provisioning.
+	Created string `hcl:"created,optional" json:"created,omitempty"`
+
+	// Peer is disabled after this time (RFC 3339 or YYYY-MM-DD)
+	Expires string `hcl:"expires,optional" json:"expires,omitempty"`
+
+	// Keep the peer configured but don't let it connect
+	Disabled bool `hcl:"disabled,optional" json:"disabled,omitempty"`
+}
+
+// CreatedTime returns when the peer's keys were generated, or the zero time
+// if unknown.
+func (p WireGuardPeerConfig) CreatedTime() (time.Time, error) {
+	if p.Created == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, p.Created)
+}
+
+// ExpiryTime returns when the peer expires, or the zero time if it doesn't.
+// A bare date expires at the start of that day, local time.
+func (p WireGuardPeerConfig) ExpiryTime() (time.Time, error) {
+	if p.Expires == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, p.Expires, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, p.Expires)
 }
 
 // ThreatIntel configures threat intelligence feeds.
@@ -369,6 +419,9 @@ type ZoneServices struct {
 	// Captive portal / guest access
 	CaptivePortal bool `hcl:"captive_portal,optional"` // Redirect HTTP to portal
 
+	// IPv6-only access to IPv4 hosts
+	NAT64 bool `hcl:"nat64,optional"` // Translate to IPv4 via the NAT64 prefix
+
 	// Custom service ports (auto-allow)
 	CustomPorts []ZoneServicePort `hcl:"port,block"`
 }
@@ -478,6 +531,13 @@ type NotificationsConfig struct {
 	Enabled  bool                  `hcl:"enabled,optional"`
 	Channels []NotificationChannel `hcl:"channel,block" json:"channel,omitempty"`
 	Rules    []AlertRule           `hcl:"rule,block" json:"rule,omitempty"`
+
+	// Grouping: events of a rule with the same group_by label values are
+	// batched into one notification.
+	GroupBy        []string `hcl:"group_by,optional" json:"group_by,omitempty"`               // Event labels, e.g. ["src_ip"]; "rule" is always included
+	GroupWait      string   `hcl:"group_wait,optional" json:"group_wait,omitempty"`           // Delay before the first notification of a group; default: 0s (immediate)
+	GroupInterval  string   `hcl:"group_interval,optional" json:"group_interval,omitempty"`   // Minimum time between notifications of a group; default: 15m
+	ResolveTimeout string   `hcl:"resolve_timeout,optional" json:"resolve_timeout,omitempty"` // Resolve groups with no new events for this long; default: 1h
 }
 
 // AlertRule defines when an alert should be triggered.
@@ -487,13 +547,42 @@ type AlertRule struct {
 	Condition string   `hcl:"condition"`
 	Severity  string   `hcl:"severity,optional"` // info, warning, critical
 	Channels  []string `hcl:"channels,optional"`
-	Cooldown  string   `hcl:"cooldown,optional"` // e.g. "1h"
+	Cooldown  string   `hcl:"cooldown,optional"` // e.g. "1h"; overrides group_interval for this rule
+
+	GroupBy          []string `hcl:"group_by,optional"`       // Overrides the global group_by
+	EscalateAfter    string   `hcl:"escalate_after,optional"` // e.g. "30m"; notify escalate_to if still unacknowledged
+	EscalateChannels []string `hcl:"escalate_to,optional"`
+}
+
+// IsHookPath reports whether path may be run by an exec notification
+// channel: an absolute, clean path inside the hooks directory.
+func IsHookPath(path string) bool {
+	if !filepath.IsAbs(path) || filepath.Clean(path) != path {
+		return false
+	}
+	rel, err := filepath.Rel(install.GetHooksDir(), path)
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..")
+}
+
+// ExecChannels returns the exec notification channels, which run commands
+// as root and so need admin rights to change.
+func (c *Config) ExecChannels() []NotificationChannel {
+	if c == nil || c.Notifications == nil {
+		return nil
+	}
+	var chans []NotificationChannel
+	for _, ch := range c.Notifications.Channels {
+		if ch.Type == "exec" {
+			chans = append(chans, ch)
+		}
+	}
+	return chans
 }
 
 // NotificationChannel defines a notification destination.
 type NotificationChannel struct {
 	Name    string `hcl:"name,label"`
-	Type    string `hcl:"type"`           // email, pushover, slack, discord, ntfy, webhook
+	Type    string `hcl:"type"`           // email, pushover, telegram, matrix, gotify, slack, discord, ntfy, webhook, syslog, exec
 	Level   string `hcl:"level,optional"` // critical, warning, info
 	Enabled bool   `hcl:"enabled,optional"`
 
@@ -510,17 +599,36 @@ type NotificationChannel struct {
 	Channel    string `hcl:"channel,optional"`
 	Username   string `hcl:"username,optional"`
 
-	// Pushover settings
+	// Pushover settings (api_token and priority are shared with Telegram,
+	// Matrix and Gotify)
 	APIToken SecureString `hcl:"api_token,optional"`
 	UserKey  SecureString `hcl:"user_key,optional"`
 	Priority int          `hcl:"priority,optional"`
 	Sound    string       `hcl:"sound,optional"`
 
-	// ntfy settings
+	// ntfy settings (server is also the Matrix homeserver and Gotify URL)
 	Server string `hcl:"server,optional"`
 	Topic  string `hcl:"topic,optional"`
 
+	// Telegram settings
+	ChatID string `hcl:"chat_id,optional"`
+
+	// Matrix settings
+	Room string `hcl:"room,optional"` // Room ID, e.g. "!abc:example.org"
+
+	// Syslog settings
+	Address  string `hcl:"address,optional"`  // e.g. "udp://10.0.0.5:514"; empty for the local syslog
+	Facility string `hcl:"facility,optional"` // default: daemon
+
+	// Exec settings: the alert is passed as JSON on stdin. The program
+	// must live in the hooks directory (<config dir>/hooks.d).
+	Command []string `hcl:"command,optional"`
+
 	// Generic auth (for ntfy, webhook)
 	Password SecureString      `hcl:"password,optional"`
 	Headers  map[string]string `hcl:"headers,optional"`
+
+	// Delivery attempts after the first failure, with exponential backoff.
+	// Default: 3; negative disables retries.
+	Retries int `hcl:"retries,optional"`
 }
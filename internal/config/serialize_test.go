@@ -41,12 +41,22 @@ func TestHCLSerializationRoundTrip(t *testing.T) {
 				From:   "lan",
 				To:     "wan",
 				Action: "accept",
+				// Pointer bools must survive as explicit false
+				FlowOffload: new(bool),
+				Helpers:     []string{"ftp", "sip"},
 				Rules: []PolicyRule{
 					{
 						Name:     "block-bad-merged",
 						Action:   "drop",
 						SrcIPSet: "blocklist",
 					},
+					{
+						Name:     "ftp-alt",
+						Protocol: "tcp",
+						DestPort: 2121,
+						Action:   "accept",
+						Helper:   "ftp",
+					},
 				},
 			},
 		},
@@ -62,6 +72,42 @@ func TestHCLSerializationRoundTrip(t *testing.T) {
 					},
 				},
 			},
+			BGP: &BGP{
+				ASN: 65001,
+				Neighbors: []Neighbor{{
+					IP: "192.0.2.254", RemoteASN: 64500, Password: "s3cret",
+					AddressFamilies: []NeighborAddressFamily{{Family: "ipv4", RouteMapOut: "out"}},
+				}},
+			},
+			RouteMaps: []RouteMap{{Name: "out", Rules: []RouteMapRule{{SetASPathPrepend: []int{65001, 65001}}}}},
+		},
+		VPN: &VPNConfig{
+			WireGuard: []WireGuardConfig{{
+				Name:           "roadwarrior",
+				Enabled:        true,
+				Interface:      "wg0",
+				Address:        []string{"10.8.0.1/24"},
+				ClientEndpoint: "vpn.example.com:51820",
+				KeyRotation:    "2160h",
+				Peers: []WireGuardPeerConfig{{
+					Name:       "phone",
+					PublicKey:  "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=",
+					AllowedIPs: []string{"10.8.0.2/32"},
+					Created:    "2026-03-04T15:30:00Z",
+					Expires:    "2027-01-01",
+				}},
+			}},
+		},
+		CaptivePortal: &CaptivePortalConfig{
+			Auth:           PortalAuthVoucher,
+			SessionTimeout: "8h",
+			Port:           8888,
+			UploadLimit:    "2mbit",
+		},
+		NAT64: &NAT64Config{
+			Prefix:         "2001:db8:64::/96",
+			Pool4:          "100.127.0.0/22",
+			BindingTimeout: "30m",
 		},
 	}
 
@@ -96,10 +142,47 @@ func TestHCLSerializationRoundTrip(t *testing.T) {
 		t.Errorf("VLAN count mismatch")
 	}
 
+	if len(output.Policies) != 1 || output.Policies[0].FlowOffload == nil || *output.Policies[0].FlowOffload {
+		t.Errorf("Policy flow_offload mismatch: %+v", output.Policies)
+	}
+	if len(output.Policies) == 1 {
+		if pol := output.Policies[0]; len(pol.Helpers) != 2 || len(pol.Rules) != 2 || pol.Rules[1].Helper != "ftp" {
+			t.Errorf("Policy helpers mismatch: %+v", pol)
+		}
+	}
+
 	if output.FRR == nil || !output.FRR.Enabled {
 		t.Fatalf("FRR config lost")
 	}
 	if len(output.FRR.OSPF.Areas) != 1 {
 		t.Errorf("OSPF Areas mismatch")
 	}
+	if output.FRR.BGP == nil || len(output.FRR.BGP.Neighbors) != 1 || output.FRR.BGP.Neighbors[0].Password != "s3cret" {
+		t.Fatalf("BGP neighbor lost: %+v", output.FRR.BGP)
+	}
+	if af := output.FRR.BGP.Neighbors[0].AddressFamilies; len(af) != 1 || af[0].RouteMapOut != "out" {
+		t.Errorf("Neighbor address family mismatch: %+v", af)
+	}
+	if len(output.FRR.RouteMaps) != 1 || len(output.FRR.RouteMaps[0].Rules[0].SetASPathPrepend) != 2 {
+		t.Errorf("Route map mismatch: %+v", output.FRR.RouteMaps)
+	}
+
+	if output.VPN == nil || len(output.VPN.WireGuard) != 1 {
+		t.Fatalf("WireGuard config lost")
+	}
+	wg := output.VPN.WireGuard[0]
+	if wg.Interface != "wg0" || wg.ClientEndpoint != "vpn.example.com:51820" || wg.KeyRotation != "2160h" {
+		t.Errorf("WireGuard connection mismatch: %+v", wg)
+	}
+	if len(wg.Peers) != 1 || wg.Peers[0].Name != "phone" || wg.Peers[0].PublicKey == "" || wg.Peers[0].Expires != "2027-01-01" {
+		t.Errorf("WireGuard peer mismatch: %+v", wg.Peers)
+	}
+
+	if p := output.CaptivePortal; p == nil || p.Auth != PortalAuthVoucher || p.Port != 8888 || p.UploadLimit != "2mbit" {
+		t.Errorf("Captive portal mismatch: %+v", output.CaptivePortal)
+	}
+
+	if n := output.NAT64; n == nil || n.Prefix != "2001:db8:64::/96" || n.Pool4 != "100.127.0.0/22" || n.BindingTimeout != "30m" {
+		t.Errorf("NAT64 mismatch: %+v", output.NAT64)
+	}
 }
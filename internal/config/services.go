@@ -14,6 +14,22 @@ type DHCPServer struct {
 	// ExternalLeaseFile is the path to external DHCP server's lease file (for import mode)
 	ExternalLeaseFile string            `hcl:"external_lease_file,optional" json:"external_lease_file,omitempty"`
 	VendorClasses     []DHCPVendorClass `hcl:"vendor_class,block" json:"vendor_class,omitempty"`
+	// Failover shares the pools with the HA peer (requires replication.ha)
+	Failover *DHCPFailover `hcl:"failover,block" json:"failover,omitempty"`
+}
+
+// DHCPFailover configures how the built-in server shares its pools with the
+// HA peer. Lease state is exchanged over the replication link in every mode.
+type DHCPFailover struct {
+	// Mode selects the failover strategy:
+	//   - "hot_standby" (default): only the HA primary answers clients
+	//   - "split": both nodes answer, each allocating from its own share of every pool
+	Mode string `hcl:"mode,optional" json:"mode,omitempty"`
+	// Split is the percentage of each pool owned by the primary in split mode (default 50)
+	Split int `hcl:"split,optional" json:"split,omitempty"`
+	// MCLT (maximum client lead time) caps lease times while the peer is
+	// unreachable and delays taking over its share of the pool (default "1h")
+	MCLT string `hcl:"mclt,optional" json:"mclt,omitempty"`
 }
 
 // DHCPVendorClass defines options to be sent to clients matching a specific vendor class identifier (Option 60).
@@ -42,11 +58,18 @@ type DHCPScope struct {
 	Reservations []DHCPReservation `hcl:"reservation,block" json:"reservation,omitempty"`
 
 	// IPv6 Support (SLAAC/DHCPv6)
+	// A range enables stateful DHCPv6 (IA_NA) and the RA "managed" flag;
+	// dns_v6 alone runs a stateless server and sets the "other" flag.
 	RangeStartV6 string   `hcl:"range_start_v6,optional" json:"range_start_v6,omitempty"` // For Stateful DHCPv6
 	RangeEndV6   string   `hcl:"range_end_v6,optional" json:"range_end_v6,omitempty"`
 	DNSServersV6 []string `hcl:"dns_v6,optional" json:"dns_v6,omitempty"`
 }
 
+// HasDHCPv6 reports whether the scope runs a DHCPv6 server.
+func (s DHCPScope) HasDHCPv6() bool {
+	return s.RangeStartV6 != "" || len(s.DNSServersV6) > 0
+}
+
 // DHCPReservation defines a static IP assignment for a MAC address.
 type DHCPReservation struct {
 	MAC         string            `hcl:"mac,label" json:"mac"`
@@ -54,6 +77,10 @@ type DHCPReservation struct {
 	Hostname    string            `hcl:"hostname,optional" json:"hostname,omitempty"`
 	Description string            `hcl:"description,optional" json:"description,omitempty"`
 	Options     map[string]string `hcl:"options,optional" json:"options,omitempty"` // Per-host custom DHCP options (same format as scope options)
+	// DHCPv6: clients are matched by DUID, or by the MAC embedded in a
+	// DUID-LL/DUID-LLT when DUID is empty.
+	DUID string `hcl:"duid,optional" json:"duid,omitempty"` // Hex, separators optional
+	IPv6 string `hcl:"ipv6,optional" json:"ipv6,omitempty"`
 	// DNS integration
 	RegisterDNS bool `hcl:"register_dns,optional" json:"register_dns"` // Auto-register in DNS
 }
@@ -254,15 +281,37 @@ type SyslogConfig struct {
 
 // DDNSConfig configures dynamic DNS updates.
 type DDNSConfig struct {
-	Enabled   bool   `hcl:"enabled,optional" json:"enabled"`
-	Provider  string `hcl:"provider" json:"provider"`                      // duckdns, cloudflare, noip
-	Hostname  string `hcl:"hostname" json:"hostname"`                      // Hostname to update
-	Token     string `hcl:"token,optional" json:"token,omitempty"`         // API token/password
-	Username  string `hcl:"username,optional" json:"username,omitempty"`   // For providers requiring username
-	ZoneID    string `hcl:"zone_id,optional" json:"zone_id,omitempty"`     // For Cloudflare
-	RecordID  string `hcl:"record_id,optional" json:"record_id,omitempty"` // For Cloudflare
-	Interface string `hcl:"interface,optional" json:"interface,omitempty"` // Interface to get IP from
-	Interval  int    `hcl:"interval,optional" json:"interval,omitempty"`   // Update interval in minutes (default: 5)
+	Enabled bool `hcl:"enabled,optional" json:"enabled"`
+	// @enum: duckdns, cloudflare, noip, rfc2136, http
+	Provider   string `hcl:"provider" json:"provider"`
+	Hostname   string `hcl:"hostname" json:"hostname"`                            // Hostname to update
+	Token      string `hcl:"token,optional" json:"token,omitempty"`               // API token/password
+	Username   string `hcl:"username,optional" json:"username,omitempty"`         // For providers requiring username
+	ZoneID     string `hcl:"zone_id,optional" json:"zone_id,omitempty"`           // For Cloudflare
+	RecordID   string `hcl:"record_id,optional" json:"record_id,omitempty"`       // For Cloudflare
+	RecordIDv6 string `hcl:"record_id_v6,optional" json:"record_id_v6,omitempty"` // Cloudflare AAAA record (family = "dual")
+	Interface  string `hcl:"interface,optional" json:"interface,omitempty"`       // Interface to get IP from
+	Interval   int    `hcl:"interval,optional" json:"interval,omitempty"`         // Update interval in minutes (default: 5)
+
+	// Records to publish: A, AAAA or both.
+	// @enum: ipv4, ipv6, dual
+	// @default: "ipv4"
+	Family string `hcl:"family,optional" json:"family,omitempty"`
+	TTL    int    `hcl:"ttl,optional" json:"ttl,omitempty"` // Record TTL in seconds (rfc2136, cloudflare; default: 300)
+
+	// RFC 2136 DNS UPDATE
+	Server        string       `hcl:"server,optional" json:"server,omitempty"`                 // Primary server, host[:port]
+	Zone          string       `hcl:"zone,optional" json:"zone,omitempty"`                     // Zone to update (default: hostname minus its first label)
+	TSIGKey       string       `hcl:"tsig_key,optional" json:"tsig_key,omitempty"`             // TSIG key name
+	TSIGSecret    SecureString `hcl:"tsig_secret,optional" json:"tsig_secret,omitempty"`       // Base64 TSIG secret
+	TSIGAlgorithm string       `hcl:"tsig_algorithm,optional" json:"tsig_algorithm,omitempty"` // Default: hmac-sha256
+
+	// Generic HTTP provider. URL, headers and body may use {hostname},
+	// {ip} and {type} (A or AAAA) placeholders.
+	URL     string            `hcl:"url,optional" json:"url,omitempty"`
+	Method  string            `hcl:"method,optional" json:"method,omitempty"` // Default: GET, or POST with a body
+	Headers map[string]string `hcl:"headers,optional" json:"headers,omitempty"`
+	Body    string            `hcl:"body,optional" json:"body,omitempty"`
 }
 
 // MDNSConfig configures the mDNS reflector service.
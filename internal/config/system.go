@@ -38,6 +38,50 @@ type APIConfig struct {
 
 	// Tailscale/tsnet configuration
 	TsNet *TsNetConfig `hcl:"tsnet,block" json:"tsnet,omitempty"`
+
+	// OpenID Connect single sign-on
+	OIDC *OIDCConfig `hcl:"oidc,block" json:"oidc,omitempty"`
+
+	// Custom user roles, in addition to the built-in admin, operator and viewer
+	Roles []RoleConfig `hcl:"role,block" json:"roles,omitempty"`
+
+	// Second-factor authentication for local logins
+	MFA *MFAConfig `hcl:"mfa,block" json:"mfa,omitempty"`
+}
+
+// MFAConfig configures second-factor authentication (TOTP or WebAuthn
+// security keys) for password logins. Users enroll themselves; members of
+// RequiredRoles must enroll before their first session is usable.
+type MFAConfig struct {
+	RequiredRoles []string `hcl:"required_roles,optional" json:"required_roles,omitempty"` // e.g. ["admin"]
+	Issuer        string   `hcl:"issuer,optional" json:"issuer,omitempty"`                 // TOTP issuer shown in authenticator apps; default: product name
+	RPID          string   `hcl:"rp_id,optional" json:"rp_id,omitempty"`                   // WebAuthn relying party ID (the UI's domain); required for security keys
+	Origins       []string `hcl:"origins,optional" json:"origins,omitempty"`               // Allowed WebAuthn origins; default: https://<rp_id>
+}
+
+// RoleConfig defines a custom user role as a set of "resource:verb"
+// permissions, e.g. "learning:apply" or "dhcp:read". Verbs are read, write
+// and apply; "*" matches any resource or verb.
+type RoleConfig struct {
+	Name        string   `hcl:"name,label" json:"name"`
+	Description string   `hcl:"description,optional" json:"description,omitempty"`
+	Permissions []string `hcl:"permissions" json:"permissions"`
+}
+
+// OIDCConfig configures single sign-on through an OpenID Connect provider
+// using the authorization-code flow with PKCE. Users are provisioned on
+// first login; their role is derived from the IdP group claim.
+type OIDCConfig struct {
+	Enabled       bool              `hcl:"enabled,optional" json:"enabled"`
+	Issuer        string            `hcl:"issuer" json:"issuer"`                                    // Provider URL; discovery is fetched from /.well-known/openid-configuration
+	ClientID      string            `hcl:"client_id" json:"client_id"`                              // OAuth2 client ID
+	ClientSecret  SecureString      `hcl:"client_secret,optional" json:"client_secret,omitempty"`   // Omit for public clients
+	RedirectURL   string            `hcl:"redirect_url" json:"redirect_url"`                        // e.g. https://fw.example.com/api/auth/oidc/callback
+	Scopes        []string          `hcl:"scopes,optional" json:"scopes,omitempty"`                 // Default: openid, profile, email, groups
+	UsernameClaim string            `hcl:"username_claim,optional" json:"username_claim,omitempty"` // Default: preferred_username (falls back to email, sub)
+	GroupsClaim   string            `hcl:"groups_claim,optional" json:"groups_claim,omitempty"`     // Default: groups
+	GroupRoles    map[string]string `hcl:"group_roles,optional" json:"group_roles,omitempty"`       // IdP group -> role name
+	DefaultRole   string            `hcl:"default_role,optional" json:"default_role,omitempty"`     // Role for users in no mapped group; empty denies login
 }
 
 // TsNetConfig configures the embedded Tailscale client.
@@ -118,6 +162,25 @@ type HAConfig struct {
 	// Enabled activates HA monitoring and failover
 	Enabled bool `hcl:"enabled,optional" json:"enabled,omitempty"`
 
+	// Protocol selects how nodes decide who holds the virtual IPs:
+	//   "heartbeat" - Flywall's own UDP heartbeat between two nodes (default)
+	//   "vrrp"      - VRRPv3 (RFC 5798), interoperable with other routers.
+	//                 Each virtual_ip is elected separately using its vrid and priority.
+	Protocol string `hcl:"protocol,optional" json:"protocol,omitempty"`
+
+	// Mode selects how the heartbeat protocol shares the virtual IPs:
+	//   "active_passive" - the primary holds every virtual IP (default)
+	//   "active_active"  - each node holds the virtual IPs it owns and both forward.
+	//                      A surviving node takes over all addresses of a dead peer.
+	Mode string `hcl:"mode,optional" json:"mode,omitempty"`
+
+	// NodeID names this node in owner and zone_owners (default: hostname)
+	NodeID string `hcl:"node_id,optional" json:"node_id,omitempty"`
+
+	// ZoneOwners maps zone names to the node_id that holds the virtual IPs on
+	// the zone's interfaces in active_active mode (e.g., { lan = "fw-a", dmz = "fw-b" })
+	ZoneOwners map[string]string `hcl:"zone_owners,optional" json:"zone_owners,omitempty"`
+
 	// Priority determines which node becomes primary (lower = higher priority)
 	// Default: 100. Set one node to 50 and another to 150 for deterministic election.
 	Priority int `hcl:"priority,optional" json:"priority,omitempty"`
@@ -150,6 +213,14 @@ type HAConfig struct {
 	ConntrackSync *ConntrackSyncConfig `hcl:"conntrack_sync,block" json:"conntrack_sync,omitempty"`
 }
 
+// VirtualRouterID returns the VRRP virtual router ID of the i-th virtual IP.
+func (h *HAConfig) VirtualRouterID(i int) int {
+	if vrid := h.VirtualIPs[i].VRID; vrid > 0 {
+		return vrid
+	}
+	return i + 1
+}
+
 // ConntrackSyncConfig configures connection tracking state synchronization.
 // Uses conntrackd to replicate established connections between HA nodes,
 // allowing TCP sessions to survive failover without being reset.
@@ -182,6 +253,44 @@ type VirtualIP struct {
 
 	// Label is an optional interface label for the address (e.g., "eth1:vip")
 	Label string `hcl:"label,optional" json:"label,omitempty"`
+
+	// Owner is the node_id holding this address in active_active mode while both
+	// nodes are up (default: zone_owners, then the node with the lower priority)
+	Owner string `hcl:"owner,optional" json:"owner,omitempty"`
+
+	// The following apply when ha.protocol = "vrrp".
+
+	// VRID is the VRRP virtual router ID (1-255, default: position in the list, from 1).
+	// Addresses of the same family sharing a VRID and interface are advertised together.
+	VRID int `hcl:"vrid,optional" json:"vrid,omitempty"`
+
+	// Priority is this node's VRRP priority for the address (1-254, higher wins, default: 100)
+	Priority int `hcl:"priority,optional" json:"priority,omitempty"`
+
+	// Preempt lets a higher-priority node take the address from a lower-priority master (default: true)
+	Preempt *bool `hcl:"preempt,optional" json:"preempt,omitempty"`
+
+	// PreemptDelay waits this long after seeing a lower-priority master before preempting it (e.g., "30s")
+	PreemptDelay string `hcl:"preempt_delay,optional" json:"preempt_delay,omitempty"`
+
+	// AdvertInterval is the VRRP advertisement interval (10ms-40s, default: "1s")
+	AdvertInterval string `hcl:"advert_interval,optional" json:"advert_interval,omitempty"`
+
+	// Track lowers the priority while an interface or uplink is down
+	Track []VRRPTrack `hcl:"track,block" json:"track,omitempty"`
+}
+
+// VRRPTrack is an interface or uplink a VRRP virtual IP depends on.
+type VRRPTrack struct {
+	// Interface is tracked by link state
+	Interface string `hcl:"interface,optional" json:"interface,omitempty"`
+
+	// Uplink is tracked by its health check (name of an uplink or multi_wan connection)
+	Uplink string `hcl:"uplink,optional" json:"uplink,omitempty"`
+
+	// Weight is subtracted from the priority while the tracked object is down.
+	// 0 (default) gives up the address entirely instead.
+	Weight int `hcl:"weight,optional" json:"weight,omitempty"`
 }
 
 // VirtualMAC defines a shared MAC address for HA failover.
@@ -3,12 +3,21 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
 	"log"
 	"net"
+	"net/netip"
+	"net/url"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
+
+	"grimm.is/flywall/internal/install"
 )
 
 // isWildcardZone checks if a zone name is a wildcard pattern.
@@ -92,6 +101,36 @@ func (c *Config) Validate() ValidationErrors {
 	// Validate QoS policies
 	errs = append(errs, c.validateQoS()...)
 
+	// Validate custom roles
+	errs = append(errs, c.validateRoles()...)
+
+	// Validate DHCP failover
+	errs = append(errs, c.validateDHCPFailover()...)
+
+	// Validate HA virtual routers
+	errs = append(errs, c.validateHA()...)
+
+	// Validate FRR routing policy
+	errs = append(errs, c.validateFRR()...)
+
+	// Validate device quotas
+	errs = append(errs, c.validateQuotas()...)
+
+	// Validate captive portal
+	errs = append(errs, c.validateCaptivePortal()...)
+
+	// Validate NAT64 and DNS64
+	errs = append(errs, c.validateNAT64()...)
+
+	// Validate dynamic DNS
+	errs = append(errs, c.validateDDNS()...)
+
+	// Validate WireGuard road-warrior settings
+	errs = append(errs, c.validateWireGuard()...)
+
+	// Validate notification channels
+	errs = append(errs, c.validateNotifications()...)
+
 	return errs
 }
 
@@ -192,6 +231,17 @@ func (c *Config) validateInterfaces() ValidationErrors {
 			}
 		}
 
+		// Validate DHCPv6 prefix delegation
+		if iface.DHCPv6PDLength != 0 && (iface.DHCPv6PDLength < 1 || iface.DHCPv6PDLength > 64) {
+			errs = append(errs, ValidationError{
+				Field:   field + ".dhcp_v6_pd_length",
+				Message: fmt.Sprintf("prefix delegation length must be between 1 and 64, got %d", iface.DHCPv6PDLength),
+			})
+		}
+		if dp := iface.DelegatedPrefix; dp != nil {
+			errs = append(errs, c.validateDelegatedPrefix(field+".delegated_prefix", iface.Name, dp)...)
+		}
+
 		// Validate MTU
 		if iface.MTU != 0 && (iface.MTU < 576 || iface.MTU > 65535) {
 			errs = append(errs, ValidationError{
@@ -217,6 +267,53 @@ func (c *Config) validateInterfaces() ValidationErrors {
 	return errs
 }
 
+// validateDelegatedPrefix checks that a downstream interface takes its prefix
+// from an interface running the DHCPv6 client.
+func (c *Config) validateDelegatedPrefix(field, name string, dp *DelegatedPrefix) ValidationErrors {
+	var errs ValidationErrors
+
+	var upstream *Interface
+	for i := range c.Interfaces {
+		if c.Interfaces[i].Name == dp.From {
+			upstream = &c.Interfaces[i]
+			break
+		}
+	}
+	switch {
+	case dp.From == name:
+		errs = append(errs, ValidationError{
+			Field:   field + ".from",
+			Message: fmt.Sprintf("interface %s cannot delegate a prefix to itself", name),
+		})
+	case upstream == nil:
+		errs = append(errs, ValidationError{
+			Field:   field + ".from",
+			Message: fmt.Sprintf("unknown upstream interface: %s", dp.From),
+		})
+	case !upstream.DHCPv6:
+		errs = append(errs, ValidationError{
+			Field:   field + ".from",
+			Message: fmt.Sprintf("upstream interface %s must have dhcp_v6 enabled", dp.From),
+		})
+	}
+
+	if dp.SubnetID != nil && *dp.SubnetID < 0 {
+		errs = append(errs, ValidationError{
+			Field:   field + ".subnet_id",
+			Message: fmt.Sprintf("subnet_id must not be negative, got %d", *dp.SubnetID),
+		})
+	}
+	if dp.HostID != "" {
+		if ip := net.ParseIP(dp.HostID); ip == nil || ip.To4() != nil {
+			errs = append(errs, ValidationError{
+				Field:   field + ".host_id",
+				Message: fmt.Sprintf("host_id must be an IPv6 interface identifier such as ::1, got %s", dp.HostID),
+			})
+		}
+	}
+	return errs
+}
+
 // validateInterfaceOverlaps checks for overlapping subnets across interfaces, respecting VRFs.
 func (c *Config) validateInterfaceOverlaps() ValidationErrors {
 	var errs ValidationErrors
@@ -531,6 +628,18 @@ func (c *Config) validatePolicies() ValidationErrors {
 					Message: fmt.Sprintf("port must be between 1 and 65535, got %d", rule.DestPort),
 				})
 			}
+			if _, err := ParsePortSpecs(rule.DestPorts); err != nil {
+				errs = append(errs, ValidationError{
+					Field:   ruleField + ".dest_ports",
+					Message: err.Error(),
+				})
+			}
+			if _, err := ParsePortSpecs(rule.SrcPorts); err != nil {
+				errs = append(errs, ValidationError{
+					Field:   ruleField + ".src_ports",
+					Message: err.Error(),
+				})
+			}
 
 			// Validate IPSet references
 			if rule.SrcIPSet != "" && !c.hasIPSet(rule.SrcIPSet) {
@@ -545,6 +654,21 @@ func (c *Config) validatePolicies() ValidationErrors {
 					Message: fmt.Sprintf("unknown IPSet: %s", rule.DestIPSet),
 				})
 			}
+
+			// Validate conntrack helper
+			if rule.Helper != "" {
+				errs = append(errs, validateRuleHelper(ruleField, rule)...)
+			}
+		}
+
+		// Validate conntrack helpers
+		for j, name := range policy.Helpers {
+			if _, ok := ConntrackHelpers[name]; !ok {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("%s.helpers[%d]", field, j),
+					Message: fmt.Sprintf("unknown conntrack helper: %s (supported: %s)", name, strings.Join(ConntrackHelperNames(), ", ")),
+				})
+			}
 		}
 
 		// Validate inheritance
@@ -641,13 +765,162 @@ func (c *Config) validateNAT() ValidationErrors {
 	for i, nat := range c.NAT {
 		field := fmt.Sprintf("nat[%d]", i)
 
-		validTypes := map[string]bool{"masquerade": true, "snat": true, "dnat": true}
+		validTypes := map[string]bool{"masquerade": true, "snat": true, "dnat": true, "netmap": true}
 		if !validTypes[strings.ToLower(nat.Type)] {
 			errs = append(errs, ValidationError{
 				Field:   field + ".type",
 				Message: fmt.Sprintf("invalid NAT type: %s", nat.Type),
 			})
 		}
+
+		for j, ip := range nat.ToIPs {
+			if net.ParseIP(ip) == nil {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("%s.to_ips[%d]", field, j),
+					Message: fmt.Sprintf("invalid backend IP: %s", ip),
+				})
+			}
+		}
+
+		switch nat.LoadBalance {
+		case "", NATBalanceRoundRobin, NATBalanceHash:
+		default:
+			errs = append(errs, ValidationError{
+				Field:   field + ".load_balance",
+				Message: fmt.Sprintf("invalid load_balance mode %q (expected round_robin or hash)", nat.LoadBalance),
+			})
+		}
+
+		if strings.EqualFold(nat.Type, "netmap") {
+			errs = append(errs, validateNetmap(field, nat)...)
+		}
+
+		if nat.DestPort != "" && strings.Contains(nat.ToPort, "-") {
+			errs = append(errs, validateNATPortRemap(field, nat)...)
+			if len(nat.Backends()) > 1 {
+				errs = append(errs, ValidationError{
+					Field:   field + ".to_ips",
+					Message: "load balancing cannot be combined with a to_port range",
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+// validateNetmap checks that a 1:1 prefix mapping has equally sized
+// external (dest_ip) and internal (to_ip) networks.
+func validateNetmap(field string, nat NATRule) ValidationErrors {
+	var errs ValidationErrors
+
+	_, ext, err := net.ParseCIDR(nat.DestIP)
+	if err != nil {
+		errs = append(errs, ValidationError{
+			Field:   field + ".dest_ip",
+			Message: fmt.Sprintf("netmap requires dest_ip to be the external CIDR, got %q", nat.DestIP),
+		})
+	}
+	_, internal, err2 := net.ParseCIDR(nat.ToIP)
+	if err2 != nil {
+		errs = append(errs, ValidationError{
+			Field:   field + ".to_ip",
+			Message: fmt.Sprintf("netmap requires to_ip to be the internal CIDR, got %q", nat.ToIP),
+		})
+	}
+	if err != nil || err2 != nil {
+		return errs
+	}
+
+	extOnes, _ := ext.Mask.Size()
+	intOnes, _ := internal.Mask.Size()
+	if extOnes != intOnes {
+		errs = append(errs, ValidationError{
+			Field:   field + ".to_ip",
+			Message: fmt.Sprintf("netmap prefixes must be the same size: %s vs %s", ext, internal),
+		})
+	}
+	if len(nat.ToIPs) > 0 {
+		errs = append(errs, ValidationError{
+			Field:   field + ".to_ips",
+			Message: "to_ips cannot be used with netmap",
+		})
+	}
+	return errs
+}
+
+// validateNATPortRemap checks a dest_port range -> to_port range remap.
+func validateNATPortRemap(field string, nat NATRule) ValidationErrors {
+	from, err := PortSpec(nat.DestPort).Ranges()
+	if err != nil {
+		return ValidationErrors{{Field: field + ".dest_port", Message: err.Error()}}
+	}
+	to, err := PortSpec(nat.ToPort).Ranges()
+	if err != nil {
+		return ValidationErrors{{Field: field + ".to_port", Message: err.Error()}}
+	}
+	if len(from) != 1 || len(to) != 1 || from[0].End-from[0].Start != to[0].End-to[0].Start {
+		return ValidationErrors{{
+			Field:   field + ".to_port",
+			Message: fmt.Sprintf("to_port range %s must be the same size as dest_port %s", nat.ToPort, nat.DestPort),
+		}}
+	}
+	if size := from[0].End - from[0].Start + 1; size > MaxNATPortRemap {
+		return ValidationErrors{{
+			Field:   field + ".to_port",
+			Message: fmt.Sprintf("port remap of %d ports exceeds the maximum of %d", size, MaxNATPortRemap),
+		}}
+	}
+	return nil
+}
+
+// validateRoles checks custom role definitions. Resource names are checked
+// against the permission model when the roles are loaded by the API.
+func (c *Config) validateRoles() ValidationErrors {
+	var errs ValidationErrors
+	if c.API == nil {
+		return errs
+	}
+
+	seen := make(map[string]bool)
+	for i, role := range c.API.Roles {
+		field := fmt.Sprintf("api.role[%d]", i)
+
+		switch role.Name {
+		case "":
+			errs = append(errs, ValidationError{Field: field, Message: "role name is required"})
+		case "admin", "operator", "viewer":
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("cannot redefine built-in role %q", role.Name),
+			})
+		}
+		if seen[role.Name] {
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("duplicate role %q", role.Name),
+			})
+		}
+		seen[role.Name] = true
+
+		for j, perm := range role.Permissions {
+			if perm == "*" {
+				continue
+			}
+			resource, verb, ok := strings.Cut(perm, ":")
+			switch {
+			case !ok || resource == "":
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("%s.permissions[%d]", field, j),
+					Message: fmt.Sprintf("invalid permission %q (expected resource:verb)", perm),
+				})
+			case verb != "read" && verb != "write" && verb != "apply" && verb != "*":
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("%s.permissions[%d]", field, j),
+					Message: fmt.Sprintf("invalid verb %q (expected read, write or apply)", verb),
+				})
+			}
+		}
 	}
 
 	return errs
@@ -703,11 +976,773 @@ func (c *Config) validateQoS() ValidationErrors {
 				Message: fmt.Sprintf("upload_mbps cannot be negative: %d", policy.UploadMbps),
 			})
 		}
+
+		switch policy.Direction {
+		case "", "ingress", "egress", "both":
+		default:
+			errs = append(errs, ValidationError{
+				Field:   field + ".direction",
+				Message: fmt.Sprintf("direction must be ingress, egress or both, got %q", policy.Direction),
+			})
+		}
+
+		if !isValidQueueType(policy.QueueType) {
+			errs = append(errs, ValidationError{
+				Field:   field + ".queue_type",
+				Message: fmt.Sprintf("unknown queue_type %q (expected fq_codel, cake, sfq or pfifo)", policy.QueueType),
+			})
+		}
+		for _, class := range policy.Classes {
+			if !isValidQueueType(class.QueueType) {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("%s.class[%s].queue_type", field, class.Name),
+					Message: fmt.Sprintf("unknown queue_type %q (expected fq_codel, cake, sfq or pfifo)", class.QueueType),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+func (c *Config) validateQuotas() ValidationErrors {
+	var errs ValidationErrors
+
+	seen := make(map[string]bool)
+	for _, q := range c.Quotas {
+		field := fmt.Sprintf("quota[%s]", q.Name)
+
+		if seen[q.Name] {
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: "duplicate quota name",
+			})
+		}
+		seen[q.Name] = true
+
+		if len(q.Devices) == 0 && q.Group == "" {
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: "quota needs devices or a group",
+			})
+		}
+		for _, mac := range q.Devices {
+			if _, err := net.ParseMAC(mac); err != nil {
+				errs = append(errs, ValidationError{
+					Field:   field + ".devices",
+					Message: fmt.Sprintf("invalid MAC address: %s", mac),
+				})
+			}
+		}
+
+		limits := 0
+		for _, l := range [][2]string{{"daily", q.Daily}, {"weekly", q.Weekly}, {"monthly", q.Monthly}} {
+			name, v := l[0], l[1]
+			if v == "" {
+				continue
+			}
+			if n, err := ParseByteSize(v); err != nil || n <= 0 {
+				errs = append(errs, ValidationError{
+					Field:   field + "." + name,
+					Message: fmt.Sprintf("invalid size %q", v),
+				})
+			}
+			limits++
+		}
+		if limits == 0 {
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: "quota needs at least one of daily, weekly or monthly",
+			})
+		}
+
+		switch q.Action {
+		case "", "block":
+		case "throttle":
+			if !c.hasQoSClass(q.QoSPolicy, q.QoSClass) {
+				errs = append(errs, ValidationError{
+					Field:   field + ".qos_class",
+					Message: fmt.Sprintf("throttle needs an existing qos_policy and qos_class, got %q/%q", q.QoSPolicy, q.QoSClass),
+				})
+			}
+		default:
+			errs = append(errs, ValidationError{
+				Field:   field + ".action",
+				Message: fmt.Sprintf("action must be block or throttle, got %q", q.Action),
+			})
+		}
+
+		if q.WarnPercent != nil && (*q.WarnPercent < 0 || *q.WarnPercent >= 100) {
+			errs = append(errs, ValidationError{
+				Field:   field + ".warn_percent",
+				Message: fmt.Sprintf("warn_percent must be between 0 and 99, got %d", *q.WarnPercent),
+			})
+		}
+		if q.ResetDay < 0 || q.ResetDay > 28 {
+			errs = append(errs, ValidationError{
+				Field:   field + ".reset_day",
+				Message: fmt.Sprintf("reset_day must be between 1 and 28, got %d", q.ResetDay),
+			})
+		}
+	}
+
+	return errs
+}
+
+func (c *Config) validateCaptivePortal() ValidationErrors {
+	var errs ValidationErrors
+	p := c.CaptivePortal
+	if p == nil {
+		return errs
+	}
+
+	switch p.Auth {
+	case "", PortalAuthClickThrough, PortalAuthVoucher:
+	case PortalAuthPassword:
+		if p.Password == "" {
+			errs = append(errs, ValidationError{
+				Field:   "captive_portal.password",
+				Message: "password auth needs a password",
+			})
+		}
+	default:
+		errs = append(errs, ValidationError{
+			Field:   "captive_portal.auth",
+			Message: fmt.Sprintf("auth must be click_through, password or voucher, got %q", p.Auth),
+		})
+	}
+
+	if p.SessionTimeout != "" {
+		if d, err := time.ParseDuration(p.SessionTimeout); err != nil || d <= 0 {
+			errs = append(errs, ValidationError{
+				Field:   "captive_portal.session_timeout",
+				Message: fmt.Sprintf("invalid duration %q", p.SessionTimeout),
+			})
+		}
+	}
+	if p.Port < 0 || p.Port > 65535 {
+		errs = append(errs, ValidationError{
+			Field:   "captive_portal.port",
+			Message: fmt.Sprintf("port must be between 1 and 65535, got %d", p.Port),
+		})
+	}
+	for _, l := range [][2]string{{"upload_limit", p.UploadLimit}, {"download_limit", p.DownloadLimit}} {
+		name, v := l[0], l[1]
+		if v == "" {
+			continue
+		}
+		if n, err := ParseBitRate(v); err != nil || n <= 0 {
+			errs = append(errs, ValidationError{
+				Field:   "captive_portal." + name,
+				Message: fmt.Sprintf("invalid rate %q", v),
+			})
+		}
+	}
+	if p.APIURL != "" {
+		if u, err := url.Parse(p.APIURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errs = append(errs, ValidationError{
+				Field:   "captive_portal.api_url",
+				Message: fmt.Sprintf("api_url must be an http or https URL, got %q", p.APIURL),
+			})
+		}
+	}
+
+	return errs
+}
+
+func (c *Config) validateNAT64() ValidationErrors {
+	var errs ValidationErrors
+
+	if c.DNS != nil {
+		for _, serve := range c.DNS.Serve {
+			if serve.DNS64Prefix == "" {
+				continue
+			}
+			if _, err := ParseNAT64Prefix(serve.DNS64Prefix); err != nil {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("dns.serve[%s].dns64_prefix", serve.Zone),
+					Message: err.Error(),
+				})
+			}
+		}
+	}
+
+	n := c.NAT64
+	if n == nil {
+		return errs
+	}
+	if n.Prefix != "" {
+		if _, err := ParseNAT64Prefix(n.Prefix); err != nil {
+			errs = append(errs, ValidationError{
+				Field:   "nat64.prefix",
+				Message: err.Error(),
+			})
+		}
+	}
+	if n.Pool4 != "" {
+		if pool, err := netip.ParsePrefix(n.Pool4); err != nil || !pool.Addr().Is4() {
+			errs = append(errs, ValidationError{
+				Field:   "nat64.pool4",
+				Message: fmt.Sprintf("%q is not an IPv4 prefix", n.Pool4),
+			})
+		} else if pool.Bits() > 30 {
+			errs = append(errs, ValidationError{
+				Field:   "nat64.pool4",
+				Message: fmt.Sprintf("pool4 must be /30 or larger, got /%d", pool.Bits()),
+			})
+		} else if iface := c.interfaceInPrefix(pool); iface != "" {
+			errs = append(errs, ValidationError{
+				Field:   "nat64.pool4",
+				Message: fmt.Sprintf("pool4 %s overlaps the addresses of interface %s", n.Pool4, iface),
+			})
+		}
+	}
+	if n.Interface != "" && !isValidInterfaceName(n.Interface) {
+		errs = append(errs, ValidationError{
+			Field:   "nat64.interface",
+			Message: fmt.Sprintf("invalid interface name %q", n.Interface),
+		})
+	}
+	if n.BindingTimeout != "" {
+		if d, err := time.ParseDuration(n.BindingTimeout); err != nil || d <= 0 {
+			errs = append(errs, ValidationError{
+				Field:   "nat64.binding_timeout",
+				Message: fmt.Sprintf("invalid duration %q", n.BindingTimeout),
+			})
+		}
+	}
+
+	return errs
+}
+
+// interfaceInPrefix returns the first interface with an IPv4 address
+// inside or containing p, or "".
+func (c *Config) interfaceInPrefix(p netip.Prefix) string {
+	for _, iface := range c.Interfaces {
+		for _, cidr := range iface.IPv4 {
+			addr, err := netip.ParsePrefix(cidr)
+			if err == nil && addr.Overlaps(p) {
+				return iface.Name
+			}
+		}
+	}
+	return ""
+}
+
+func (c *Config) validateDDNS() ValidationErrors {
+	var errs ValidationErrors
+	d := c.DDNS
+	if d == nil || !d.Enabled {
+		return errs
+	}
+
+	switch strings.ToLower(d.Provider) {
+	case "duckdns", "cloudflare", "noip", "no-ip":
+	case "rfc2136":
+		if d.Server == "" {
+			errs = append(errs, ValidationError{
+				Field:   "ddns.server",
+				Message: "rfc2136 provider needs the primary server to update",
+			})
+		}
+		if (d.TSIGKey == "") != (d.TSIGSecret == "") {
+			errs = append(errs, ValidationError{
+				Field:   "ddns.tsig_key",
+				Message: "tsig_key and tsig_secret must be set together",
+			})
+		}
+		if d.TSIGSecret != "" {
+			if _, err := base64.StdEncoding.DecodeString(string(d.TSIGSecret)); err != nil {
+				errs = append(errs, ValidationError{
+					Field:   "ddns.tsig_secret",
+					Message: "tsig_secret must be base64",
+				})
+			}
+		}
+		switch strings.ToLower(d.TSIGAlgorithm) {
+		case "", "hmac-sha1", "hmac-sha224", "hmac-sha256", "hmac-sha384", "hmac-sha512":
+		default:
+			errs = append(errs, ValidationError{
+				Field:   "ddns.tsig_algorithm",
+				Message: fmt.Sprintf("unsupported TSIG algorithm %q", d.TSIGAlgorithm),
+			})
+		}
+	case "http":
+		if u, err := url.Parse(d.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, ValidationError{
+				Field:   "ddns.url",
+				Message: fmt.Sprintf("http provider needs an http(s) URL, got %q", d.URL),
+			})
+		}
+	default:
+		errs = append(errs, ValidationError{
+			Field:   "ddns.provider",
+			Message: fmt.Sprintf("unknown provider %q (expected duckdns, cloudflare, noip, rfc2136 or http)", d.Provider),
+		})
+	}
+
+	switch d.Family {
+	case "", "ipv4", "ipv6", "dual":
+	default:
+		errs = append(errs, ValidationError{
+			Field:   "ddns.family",
+			Message: fmt.Sprintf("family must be ipv4, ipv6 or dual, got %q", d.Family),
+		})
+	}
+
+	return errs
+}
+
+func (c *Config) validateWireGuard() ValidationErrors {
+	var errs ValidationErrors
+	if c.VPN == nil {
+		return errs
+	}
+
+	for i, wg := range c.VPN.WireGuard {
+		prefix := fmt.Sprintf("vpn.wireguard[%d]", i)
+
+		if wg.ClientEndpoint != "" {
+			if _, port, err := net.SplitHostPort(wg.ClientEndpoint); err != nil || port == "" {
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".client_endpoint",
+					Message: fmt.Sprintf("client_endpoint must be host:port, got %q", wg.ClientEndpoint),
+				})
+			}
+		}
+		for _, cidr := range wg.ClientAllowedIPs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".client_allowed_ips",
+					Message: fmt.Sprintf("invalid CIDR %q", cidr),
+				})
+			}
+		}
+		for _, d := range []struct{ field, val string }{
+			{"key_rotation", wg.KeyRotation},
+			{"idle_timeout", wg.IdleTimeout},
+		} {
+			if d.val == "" {
+				continue
+			}
+			if dur, err := time.ParseDuration(d.val); err != nil || dur <= 0 {
+				errs = append(errs, ValidationError{
+					Field:   prefix + "." + d.field,
+					Message: fmt.Sprintf("%s must be a positive duration like \"720h\", got %q", d.field, d.val),
+				})
+			}
+		}
+
+		for j, peer := range wg.Peers {
+			peerPrefix := fmt.Sprintf("%s.peer[%d]", prefix, j)
+			if _, err := peer.CreatedTime(); err != nil {
+				errs = append(errs, ValidationError{
+					Field:   peerPrefix + ".created",
+					Message: fmt.Sprintf("created must be an RFC 3339 time, got %q", peer.Created),
+				})
+			}
+			if _, err := peer.ExpiryTime(); err != nil {
+				errs = append(errs, ValidationError{
+					Field:   peerPrefix + ".expires",
+					Message: fmt.Sprintf("expires must be a date (YYYY-MM-DD) or RFC 3339 time, got %q", peer.Expires),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+func (c *Config) validateNotifications() ValidationErrors {
+	var errs ValidationErrors
+	if c.Notifications == nil {
+		return errs
+	}
+
+	for i, ch := range c.Notifications.Channels {
+		if ch.Type != "exec" {
+			continue
+		}
+		field := fmt.Sprintf("notifications.channel[%d].command", i)
+		if len(ch.Command) == 0 {
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: "exec channel requires a command",
+			})
+		} else if !IsHookPath(ch.Command[0]) {
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("command must be an absolute path under %s, got %q", install.GetHooksDir(), ch.Command[0]),
+			})
+		}
 	}
 
 	return errs
 }
 
+func (c *Config) hasQoSClass(policy, class string) bool {
+	for _, p := range c.QoSPolicies {
+		if p.Name != policy {
+			continue
+		}
+		for _, cl := range p.Classes {
+			if cl.Name == class {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isValidQueueType(q string) bool {
+	switch q {
+	case "", "fq_codel", "cake", "sfq", "pfifo":
+		return true
+	}
+	return false
+}
+
+func (c *Config) validateDHCPFailover() ValidationErrors {
+	var errs ValidationErrors
+	if c.DHCP == nil || c.DHCP.Failover == nil {
+		return errs
+	}
+	fo := c.DHCP.Failover
+	field := "dhcp.failover"
+
+	switch fo.Mode {
+	case "", "hot_standby", "split":
+	default:
+		errs = append(errs, ValidationError{
+			Field:   field + ".mode",
+			Message: fmt.Sprintf("unknown failover mode %q (expected hot_standby or split)", fo.Mode),
+		})
+	}
+
+	if fo.Split < 0 || fo.Split > 99 {
+		errs = append(errs, ValidationError{
+			Field:   field + ".split",
+			Message: fmt.Sprintf("split must be between 1 and 99 percent, got %d", fo.Split),
+		})
+	}
+
+	if fo.MCLT != "" {
+		if d, err := time.ParseDuration(fo.MCLT); err != nil || d <= 0 {
+			errs = append(errs, ValidationError{
+				Field:   field + ".mclt",
+				Message: fmt.Sprintf("invalid duration %q", fo.MCLT),
+			})
+		}
+	}
+
+	if c.Replication == nil || c.Replication.HA == nil || !c.Replication.HA.Enabled {
+		errs = append(errs, ValidationError{
+			Field:    field,
+			Message:  "failover has no effect without replication.ha enabled",
+			Severity: "warning",
+		})
+	}
+
+	return errs
+}
+
+// validateHA checks the failover protocol and, for VRRP, each virtual IP's
+// virtual router settings.
+func (c *Config) validateHA() ValidationErrors {
+	var errs ValidationErrors
+	if c.Replication == nil || c.Replication.HA == nil {
+		return errs
+	}
+	ha := c.Replication.HA
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch ha.Mode {
+	case "", "active_passive":
+	case "active_active":
+		if ha.Protocol == "vrrp" {
+			add("replication.ha.mode", "active_active uses the heartbeat protocol; with vrrp give each node the higher priority on different virtual IPs")
+		}
+		zones := make(map[string]bool)
+		for _, z := range c.Zones {
+			zones[z.Name] = true
+		}
+		var names []string
+		for zone := range ha.ZoneOwners {
+			names = append(names, zone)
+		}
+		sort.Strings(names)
+		for _, zone := range names {
+			if !zones[zone] {
+				add("replication.ha.zone_owners", "unknown zone %q", zone)
+			}
+		}
+	default:
+		add("replication.ha.mode", "mode must be active_passive or active_active, got %q", ha.Mode)
+	}
+
+	switch ha.Protocol {
+	case "", "heartbeat":
+		return errs
+	case "vrrp":
+	default:
+		add("replication.ha.protocol", "protocol must be heartbeat or vrrp, got %q", ha.Protocol)
+		return errs
+	}
+
+	// Addresses advertised together must agree on how they are elected
+	type router struct {
+		priority int
+		interval string
+	}
+	routers := make(map[string]router)
+
+	for i, vip := range ha.VirtualIPs {
+		field := fmt.Sprintf("replication.ha.virtual_ip[%d]", i)
+
+		ip, _, err := net.ParseCIDR(vip.Address)
+		if err != nil {
+			add(field+".address", "invalid CIDR %q", vip.Address)
+			continue
+		}
+		vrid := ha.VirtualRouterID(i)
+		if vrid < 1 || vrid > 255 {
+			add(field+".vrid", "vrid must be between 1 and 255, got %d", vrid)
+		}
+		if vip.Priority < 0 || vip.Priority > 254 {
+			add(field+".priority", "priority must be between 1 and 254, got %d", vip.Priority)
+		}
+		if vip.PreemptDelay != "" {
+			if d, err := time.ParseDuration(vip.PreemptDelay); err != nil || d < 0 {
+				add(field+".preempt_delay", "invalid duration %q", vip.PreemptDelay)
+			}
+		}
+		if vip.AdvertInterval != "" {
+			// Carried in centiseconds in a 12-bit field
+			if d, err := time.ParseDuration(vip.AdvertInterval); err != nil || d < 10*time.Millisecond || d > 4095*10*time.Millisecond {
+				add(field+".advert_interval", "advert_interval must be between 10ms and 40.95s, got %q", vip.AdvertInterval)
+			}
+		}
+		for j, tr := range vip.Track {
+			tf := fmt.Sprintf("%s.track[%d]", field, j)
+			if (tr.Interface == "") == (tr.Uplink == "") {
+				add(tf, "track needs exactly one of interface or uplink")
+			}
+			if tr.Weight < 0 || tr.Weight > 253 {
+				add(tf+".weight", "weight must be between 0 and 253, got %d", tr.Weight)
+			}
+		}
+
+		family := "ipv4"
+		if ip.To4() == nil {
+			family = "ipv6"
+		}
+		key := fmt.Sprintf("%s/%d/%s", vip.Interface, vrid, family)
+		r := router{vip.Priority, vip.AdvertInterval}
+		if prev, ok := routers[key]; ok && prev != r {
+			add(field, "virtual IPs sharing vrid %d on %s must use the same priority and advert_interval", vrid, vip.Interface)
+		}
+		routers[key] = r
+	}
+
+	return errs
+}
+
+// validateFRR checks routing policy definitions and that every name a
+// neighbor or route-map refers to is defined.
+func (c *Config) validateFRR() ValidationErrors {
+	var errs ValidationErrors
+	if c.FRR == nil {
+		return errs
+	}
+	frr := c.FRR
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	checkAction := func(field, action string) {
+		if action != "" && action != "permit" && action != "deny" {
+			add(field, "action must be permit or deny, got %q", action)
+		}
+	}
+	// Names and passwords are written into frr.conf as single tokens
+	checkWord := func(field, attr, value string) {
+		if !isFRRWord(value) {
+			add(field, "%s must be a single word without whitespace or control characters, got %q", attr, value)
+		}
+	}
+
+	prefixLists := make(map[string]bool)
+	for _, pl := range frr.PrefixLists {
+		field := fmt.Sprintf("frr.prefix_list[%s]", pl.Name)
+		checkWord(field, "name", pl.Name)
+		if prefixLists[pl.Name] {
+			add(field, "duplicate prefix_list %q", pl.Name)
+		}
+		prefixLists[pl.Name] = true
+
+		family := ""
+		for i, r := range pl.Rules {
+			rf := fmt.Sprintf("%s.rule[%d]", field, i)
+			checkAction(rf+".action", r.Action)
+			if r.Prefix == "any" {
+				continue
+			}
+			_, ipnet, err := net.ParseCIDR(r.Prefix)
+			if err != nil {
+				add(rf+".prefix", "invalid prefix %q", r.Prefix)
+				continue
+			}
+			ones, bits := ipnet.Mask.Size()
+			f := "ipv4"
+			if bits == 128 {
+				f = "ipv6"
+			}
+			if family != "" && f != family {
+				add(rf+".prefix", "prefix list mixes IPv4 and IPv6 prefixes")
+			}
+			family = f
+			if (r.GE != 0 && (r.GE <= ones || r.GE > bits)) || (r.LE != 0 && (r.LE < max(ones, r.GE) || r.LE > bits)) {
+				add(rf, "ge/le must satisfy %d < ge <= le <= %d", ones, bits)
+			}
+		}
+	}
+
+	communityLists := make(map[string]bool)
+	for _, cl := range frr.CommunityLists {
+		field := fmt.Sprintf("frr.community_list[%s]", cl.Name)
+		checkWord(field, "name", cl.Name)
+		communityLists[cl.Name] = true
+		checkAction(field+".action", cl.Action)
+		if len(cl.Communities) == 0 {
+			add(field+".communities", "at least one community is required")
+		}
+		for _, comm := range cl.Communities {
+			if !isValidCommunity(comm) {
+				add(field+".communities", "invalid community %q", comm)
+			}
+		}
+	}
+
+	routeMaps := make(map[string]bool)
+	for _, rm := range frr.RouteMaps {
+		field := fmt.Sprintf("frr.route_map[%s]", rm.Name)
+		checkWord(field, "name", rm.Name)
+		routeMaps[rm.Name] = true
+		for i, r := range rm.Rules {
+			rf := fmt.Sprintf("%s.rule[%d]", field, i)
+			checkAction(rf+".action", r.Action)
+			if r.MatchPrefixList != "" && !prefixLists[r.MatchPrefixList] {
+				add(rf+".match_prefix_list", "undefined prefix_list %q", r.MatchPrefixList)
+			}
+			if r.MatchCommunity != "" && !communityLists[r.MatchCommunity] {
+				add(rf+".match_community", "undefined community_list %q", r.MatchCommunity)
+			}
+			for _, comm := range r.SetCommunity {
+				if !isValidCommunity(comm) {
+					add(rf+".set_community", "invalid community %q", comm)
+				}
+			}
+		}
+	}
+
+	bfdProfiles := make(map[string]bool)
+	for _, p := range frr.BFDProfiles {
+		field := fmt.Sprintf("frr.bfd_profile[%s]", p.Name)
+		checkWord(field, "name", p.Name)
+		bfdProfiles[p.Name] = true
+		if p.DetectMultiplier < 0 || p.DetectMultiplier > 255 {
+			add(field+".detect_multiplier", "detect_multiplier must be between 1 and 255, got %d", p.DetectMultiplier)
+		}
+		if p.ReceiveInterval < 0 || p.TransmitInterval < 0 {
+			add(field, "BFD intervals must be positive")
+		}
+	}
+
+	if frr.BGP == nil {
+		return errs
+	}
+	if frr.BGP.ASN <= 0 || int64(frr.BGP.ASN) > 4294967295 {
+		add("frr.bgp.asn", "asn must be between 1 and 4294967295, got %d", frr.BGP.ASN)
+	}
+	for _, n := range frr.BGP.Neighbors {
+		field := fmt.Sprintf("frr.bgp.neighbor[%s]", n.IP)
+		if net.ParseIP(n.IP) == nil {
+			add(field, "invalid neighbor address %q", n.IP)
+		}
+		if strings.IndexFunc(n.Description, unicode.IsControl) >= 0 {
+			add(field+".description", "description must not contain newlines or control characters")
+		}
+		if n.Password != "" && !isFRRWord(string(n.Password)) {
+			add(field+".password", "password must not contain whitespace or control characters")
+		}
+		if n.UpdateSource != "" {
+			checkWord(field+".update_source", "update_source", n.UpdateSource)
+		}
+		if n.EBGPMultihop < 0 || n.EBGPMultihop > 255 {
+			add(field+".ebgp_multihop", "ebgp_multihop must be between 1 and 255, got %d", n.EBGPMultihop)
+		}
+		if n.BFDProfile != "" && !bfdProfiles[n.BFDProfile] {
+			add(field+".bfd_profile", "undefined bfd_profile %q", n.BFDProfile)
+		}
+		families := make(map[string]bool)
+		for _, af := range n.AddressFamilies {
+			afField := fmt.Sprintf("%s.address_family[%s]", field, af.Family)
+			if af.Family != "ipv4" && af.Family != "ipv6" {
+				add(afField, "address family must be ipv4 or ipv6, got %q", af.Family)
+			}
+			if families[af.Family] {
+				add(afField, "duplicate address_family %q", af.Family)
+			}
+			families[af.Family] = true
+			for _, ref := range []struct{ attr, name string }{
+				{"route_map_in", af.RouteMapIn}, {"route_map_out", af.RouteMapOut},
+			} {
+				if ref.name != "" && !routeMaps[ref.name] {
+					add(afField+"."+ref.attr, "undefined route_map %q", ref.name)
+				}
+			}
+			for _, ref := range []struct{ attr, name string }{
+				{"prefix_list_in", af.PrefixListIn}, {"prefix_list_out", af.PrefixListOut},
+			} {
+				if ref.name != "" && !prefixLists[ref.name] {
+					add(afField+"."+ref.attr, "undefined prefix_list %q", ref.name)
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+// isFRRWord reports whether s can be written into frr.conf as one token:
+// non-empty, with no whitespace or control characters.
+func isFRRWord(s string) bool {
+	return s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}) < 0
+}
+
+// isValidCommunity accepts a standard BGP community in AA:NN form or one of
+// the well-known names FRR understands.
+func isValidCommunity(s string) bool {
+	switch s {
+	case "no-export", "no-advertise", "local-AS", "internet", "graceful-shutdown", "blackhole", "no-peer":
+		return true
+	}
+	asn, val, ok := strings.Cut(s, ":")
+	if !ok {
+		return false
+	}
+	for _, part := range []string{asn, val} {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || n > 65535 {
+			return false
+		}
+	}
+	return true
+}
+
 // Helper functions
 
 func (c *Config) getDefinedZones() map[string]bool {
@@ -756,6 +1791,39 @@ func (c *Config) hasIPSet(name string) bool {
 	return false
 }
 
+// validateRuleHelper checks a rule's conntrack helper. The helper is set on
+// the connections the rule accepts, and the kernel does not check that it
+// fits the connection's protocol, so the rule must match that protocol.
+func validateRuleHelper(ruleField string, rule PolicyRule) ValidationErrors {
+	helper, ok := ConntrackHelpers[rule.Helper]
+	if !ok {
+		return ValidationErrors{{
+			Field:   ruleField + ".helper",
+			Message: fmt.Sprintf("unknown conntrack helper: %s (supported: %s)", rule.Helper, strings.Join(ConntrackHelperNames(), ", ")),
+		}}
+	}
+
+	var errs ValidationErrors
+	if !strings.EqualFold(rule.Action, "accept") {
+		errs = append(errs, ValidationError{
+			Field:   ruleField + ".helper",
+			Message: fmt.Sprintf("helper %s needs action = \"accept\"", rule.Helper),
+		})
+	}
+	// A service of the same name expands to the helper's protocol
+	proto := strings.ToLower(rule.Protocol)
+	if proto == "" && rule.Service == helper.Name {
+		proto = helper.Protocol
+	}
+	if proto != helper.Protocol {
+		errs = append(errs, ValidationError{
+			Field:   ruleField + ".helper",
+			Message: fmt.Sprintf("helper %s needs proto = %q", rule.Helper, helper.Protocol),
+		})
+	}
+	return errs
+}
+
 func isValidInterfaceName(name string) bool {
 	if name == "" || len(name) > 15 {
 		return false
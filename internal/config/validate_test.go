@@ -160,6 +160,30 @@ func TestValidateInterfaces(t *testing.T) {
 			},
 			wantErrs: 1,
 		},
+		{
+			name: "valid delegated prefix",
+			interfaces: []Interface{
+				{Name: "eth0", Zone: "wan", DHCPv6: true, DHCPv6PDLength: 56},
+				{Name: "eth1", Zone: "lan", RA: true, DelegatedPrefix: &DelegatedPrefix{From: "eth0"}},
+			},
+			wantErrs: 0,
+		},
+		{
+			name: "delegated prefix from interface without DHCPv6",
+			interfaces: []Interface{
+				{Name: "eth0", Zone: "wan"},
+				{Name: "eth1", Zone: "lan", DelegatedPrefix: &DelegatedPrefix{From: "eth0"}},
+			},
+			wantErrs: 1,
+		},
+		{
+			name: "invalid delegated prefix host ID and PD length",
+			interfaces: []Interface{
+				{Name: "eth0", Zone: "wan", DHCPv6: true, DHCPv6PDLength: 72},
+				{Name: "eth1", Zone: "lan", DelegatedPrefix: &DelegatedPrefix{From: "eth0", HostID: "10.0.0.1"}},
+			},
+			wantErrs: 2,
+		},
 	}
 
 	for _, tt := range tests {
@@ -339,6 +363,38 @@ func TestValidatePolicies(t *testing.T) {
 			},
 			wantErrs: 0,
 		},
+		{
+			name:       "conntrack helpers",
+			interfaces: []Interface{{Name: "eth0", Zone: "wan"}, {Name: "eth1", Zone: "lan"}},
+			policies: []Policy{
+				{From: "lan", To: "wan", Helpers: []string{"ftp", "sip"}, Rules: []PolicyRule{
+					{Action: "accept", Protocol: "tcp", DestPort: 2121, Helper: "ftp"},
+					{Action: "accept", Service: "tftp", Helper: "tftp"},
+				}},
+			},
+			wantErrs: 0,
+		},
+		{
+			name:       "unknown conntrack helper",
+			interfaces: []Interface{{Name: "eth0", Zone: "wan"}, {Name: "eth1", Zone: "lan"}},
+			policies: []Policy{
+				{From: "lan", To: "wan", Helpers: []string{"irc"}, Rules: []PolicyRule{
+					{Action: "accept", Protocol: "tcp", Helper: "h323"},
+				}},
+			},
+			wantErrs: 2,
+		},
+		{
+			name:       "rule helper needs accept and a matching proto",
+			interfaces: []Interface{{Name: "eth0", Zone: "wan"}, {Name: "eth1", Zone: "lan"}},
+			policies: []Policy{
+				{From: "lan", To: "wan", Rules: []PolicyRule{
+					{Action: "drop", Protocol: "tcp", Helper: "ftp"},
+					{Action: "accept", DestPort: 5080, Helper: "sip"},
+				}},
+			},
+			wantErrs: 2,
+		},
 	}
 
 	for _, tt := range tests {
@@ -379,6 +435,36 @@ func TestValidateNAT(t *testing.T) {
 			nat:      []NATRule{{Type: "invalid"}},
 			wantErrs: 1,
 		},
+		{
+			name:     "valid netmap",
+			nat:      []NATRule{{Type: "netmap", DestIP: "203.0.113.8/29", ToIP: "192.168.10.8/29"}},
+			wantErrs: 0,
+		},
+		{
+			name:     "netmap prefix size mismatch",
+			nat:      []NATRule{{Type: "netmap", DestIP: "203.0.113.8/29", ToIP: "192.168.10.0/24"}},
+			wantErrs: 1,
+		},
+		{
+			name:     "valid load balance",
+			nat:      []NATRule{{Type: "dnat", ToIP: "10.0.0.1", ToIPs: []string{"10.0.0.2"}, LoadBalance: "hash"}},
+			wantErrs: 0,
+		},
+		{
+			name:     "invalid load balance mode",
+			nat:      []NATRule{{Type: "dnat", ToIP: "10.0.0.1", ToIPs: []string{"bogus"}, LoadBalance: "random"}},
+			wantErrs: 2,
+		},
+		{
+			name:     "valid port remap",
+			nat:      []NATRule{{Type: "dnat", ToIP: "10.0.0.1", DestPort: "10000-10100", ToPort: "20000-20100"}},
+			wantErrs: 0,
+		},
+		{
+			name:     "port remap size mismatch",
+			nat:      []NATRule{{Type: "dnat", ToIP: "10.0.0.1", DestPort: "10000-10100", ToPort: "20000-20050"}},
+			wantErrs: 1,
+		},
 	}
 
 	for _, tt := range tests {
@@ -427,6 +513,404 @@ func TestValidateRoutes(t *testing.T) {
 	}
 }
 
+func TestValidateDHCPFailover(t *testing.T) {
+	ha := &ReplicationConfig{Mode: "primary", HA: &HAConfig{Enabled: true}}
+	tests := []struct {
+		name        string
+		failover    *DHCPFailover
+		replication *ReplicationConfig
+		wantErrs    int
+	}{
+		{"defaults", &DHCPFailover{}, ha, 0},
+		{"split", &DHCPFailover{Mode: "split", Split: 70, MCLT: "30m"}, ha, 0},
+		{"unknown mode", &DHCPFailover{Mode: "load_balance"}, ha, 1},
+		{"split out of range", &DHCPFailover{Mode: "split", Split: 100}, ha, 1},
+		{"bad mclt", &DHCPFailover{MCLT: "soon"}, ha, 1},
+		{"no ha", &DHCPFailover{}, nil, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{DHCP: &DHCPServer{Failover: tt.failover}, Replication: tt.replication}
+			errs := cfg.validateDHCPFailover()
+			if len(errs) != tt.wantErrs {
+				t.Errorf("got %d errors, want %d: %v", len(errs), tt.wantErrs, errs)
+			}
+		})
+	}
+}
+
+func TestValidateHA(t *testing.T) {
+	vrrp := func() *HAConfig {
+		return &HAConfig{Enabled: true, Protocol: "vrrp", VirtualIPs: []VirtualIP{
+			{Address: "10.0.0.1/24", Interface: "eth1", Priority: 200, PreemptDelay: "30s",
+				Track: []VRRPTrack{{Interface: "eth0", Weight: 50}, {Uplink: "wan1"}}},
+			{Address: "fd00::1/64", Interface: "eth1", VRID: 1, AdvertInterval: "100ms"},
+		}}
+	}
+	tests := []struct {
+		name     string
+		mutate   func(*HAConfig)
+		wantErrs int
+	}{
+		{"valid", func(*HAConfig) {}, 0},
+		{"heartbeat ignores vrrp settings", func(h *HAConfig) {
+			h.Protocol = ""
+			h.VirtualIPs[0].Priority = 300
+		}, 0},
+		{"unknown protocol", func(h *HAConfig) { h.Protocol = "carp" }, 1},
+		{"priority out of range", func(h *HAConfig) { h.VirtualIPs[0].Priority = 255 }, 1},
+		{"vrid out of range", func(h *HAConfig) { h.VirtualIPs[1].VRID = 256 }, 1},
+		{"bad interval", func(h *HAConfig) { h.VirtualIPs[1].AdvertInterval = "1m" }, 1},
+		{"bad track", func(h *HAConfig) { h.VirtualIPs[0].Track[1] = VRRPTrack{Interface: "eth0", Uplink: "wan1"} }, 1},
+		{"shared vrid disagrees", func(h *HAConfig) {
+			h.VirtualIPs[1].Address = "10.0.0.2/24"
+			h.VirtualIPs[1].AdvertInterval = ""
+		}, 1},
+		{"active_active", func(h *HAConfig) {
+			h.Protocol = ""
+			h.Mode = "active_active"
+			h.ZoneOwners = map[string]string{"lan": "fw-a"}
+		}, 0},
+		{"active_active with vrrp", func(h *HAConfig) { h.Mode = "active_active" }, 1},
+		{"unknown mode", func(h *HAConfig) { h.Mode = "active_standby" }, 1},
+		{"unknown zone owner", func(h *HAConfig) {
+			h.Protocol = ""
+			h.Mode = "active_active"
+			h.ZoneOwners = map[string]string{"guest": "fw-b"}
+		}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ha := vrrp()
+			tt.mutate(ha)
+			errs := (&Config{Zones: []Zone{{Name: "lan"}}, Replication: &ReplicationConfig{HA: ha}}).validateHA()
+			if len(errs) != tt.wantErrs {
+				t.Errorf("got %d errors, want %d: %v", len(errs), tt.wantErrs, errs)
+			}
+		})
+	}
+}
+
+func TestValidateFRR(t *testing.T) {
+	policy := func() *FRRConfig {
+		return &FRRConfig{
+			PrefixLists: []PrefixList{{Name: "customers", Rules: []PrefixListRule{
+				{Prefix: "203.0.113.0/24", LE: 32},
+				{Action: "deny", Prefix: "any"},
+			}}},
+			CommunityLists: []CommunityList{{Name: "blackhole", Communities: []string{"65000:666", "no-export"}}},
+			RouteMaps: []RouteMap{{Name: "in", Rules: []RouteMapRule{
+				{MatchPrefixList: "customers", MatchCommunity: "blackhole", SetLocalPreference: 200},
+			}}},
+			BFDProfiles: []BFDProfile{{Name: "fast", DetectMultiplier: 3}},
+			BGP: &BGP{ASN: 65000, Neighbors: []Neighbor{{
+				IP: "10.0.0.2", RemoteASN: 65001, BFDProfile: "fast",
+				AddressFamilies: []NeighborAddressFamily{{Family: "ipv4", RouteMapIn: "in", PrefixListOut: "customers"}},
+			}}},
+		}
+	}
+	tests := []struct {
+		name     string
+		mutate   func(*FRRConfig)
+		wantErrs int
+	}{
+		{"valid", func(*FRRConfig) {}, 0},
+		{"undefined references", func(f *FRRConfig) {
+			f.RouteMaps[0].Rules[0].MatchPrefixList = "nope"
+			f.BGP.Neighbors[0].BFDProfile = "slow"
+			f.BGP.Neighbors[0].AddressFamilies[0].RouteMapOut = "out"
+		}, 3},
+		{"mixed families", func(f *FRRConfig) {
+			f.PrefixLists[0].Rules = append(f.PrefixLists[0].Rules, PrefixListRule{Prefix: "2001:db8::/32"})
+		}, 1},
+		{"bad le", func(f *FRRConfig) { f.PrefixLists[0].Rules[0].LE = 16 }, 1},
+		{"bad community", func(f *FRRConfig) { f.CommunityLists[0].Communities = []string{"65000"} }, 1},
+		{"bad action", func(f *FRRConfig) { f.RouteMaps[0].Rules[0].Action = "accept" }, 1},
+		{"bad family", func(f *FRRConfig) { f.BGP.Neighbors[0].AddressFamilies[0].Family = "vpnv4" }, 1},
+		{"missing asn", func(f *FRRConfig) { f.BGP.ASN = 0 }, 1},
+		{"description with spaces", func(f *FRRConfig) { f.BGP.Neighbors[0].Description = "Transit: Example Networks" }, 0},
+		{"description with newline", func(f *FRRConfig) { f.BGP.Neighbors[0].Description = "peer\n neighbor 10.0.0.9 remote-as 1" }, 1},
+		{"password with space", func(f *FRRConfig) { f.BGP.Neighbors[0].Password = "s3cret route-map x" }, 1},
+		{"password with newline", func(f *FRRConfig) { f.BGP.Neighbors[0].Password = "s3cret\n" }, 1},
+		{"update_source with space", func(f *FRRConfig) { f.BGP.Neighbors[0].UpdateSource = "lo remote-as 1" }, 1},
+		{"prefix_list name with space", func(f *FRRConfig) {
+			f.PrefixLists[0].Name = "customers permit any"
+			f.RouteMaps[0].Rules[0].MatchPrefixList = f.PrefixLists[0].Name
+			f.BGP.Neighbors[0].AddressFamilies[0].PrefixListOut = f.PrefixLists[0].Name
+		}, 1},
+		{"community_list name with newline", func(f *FRRConfig) {
+			f.CommunityLists[0].Name = "blackhole\n!"
+			f.RouteMaps[0].Rules[0].MatchCommunity = f.CommunityLists[0].Name
+		}, 1},
+		{"route_map name with tab", func(f *FRRConfig) {
+			f.RouteMaps[0].Name = "in\tx"
+			f.BGP.Neighbors[0].AddressFamilies[0].RouteMapIn = f.RouteMaps[0].Name
+		}, 1},
+		{"empty bfd_profile name", func(f *FRRConfig) {
+			f.BFDProfiles[0].Name = ""
+			f.BGP.Neighbors[0].BFDProfile = ""
+		}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frr := policy()
+			tt.mutate(frr)
+			errs := (&Config{FRR: frr}).validateFRR()
+			if len(errs) != tt.wantErrs {
+				t.Errorf("got %d errors, want %d: %v", len(errs), tt.wantErrs, errs)
+			}
+		})
+	}
+}
+
+// TestValidateQuotas tests device quota validation
+func TestValidateQuotas(t *testing.T) {
+	quota := func() DeviceQuota {
+		return DeviceQuota{
+			Name:      "kids",
+			Devices:   []string{"aa:bb:cc:dd:ee:01"},
+			Group:     "kids",
+			Daily:     "2GB",
+			Monthly:   "50GiB",
+			Action:    "throttle",
+			QoSPolicy: "wan",
+			QoSClass:  "slow",
+		}
+	}
+	tests := []struct {
+		name     string
+		mutate   func(*DeviceQuota)
+		wantErrs int
+	}{
+		{"valid", func(*DeviceQuota) {}, 0},
+		{"no subjects", func(q *DeviceQuota) { q.Devices, q.Group = nil, "" }, 1},
+		{"bad mac", func(q *DeviceQuota) { q.Devices = []string{"aa:bb"} }, 1},
+		{"no limits", func(q *DeviceQuota) { q.Daily, q.Monthly = "", "" }, 1},
+		{"bad size", func(q *DeviceQuota) { q.Weekly = "10 parsecs" }, 1},
+		{"unknown class", func(q *DeviceQuota) { q.QoSClass = "fast" }, 1},
+		{"bad action", func(q *DeviceQuota) { q.Action = "drop" }, 1},
+		{"bad reset day", func(q *DeviceQuota) { q.ResetDay = 31 }, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := quota()
+			tt.mutate(&q)
+			cfg := &Config{
+				Quotas:      []DeviceQuota{q},
+				QoSPolicies: []QoSPolicy{{Name: "wan", Classes: []QoSClass{{Name: "slow"}}}},
+			}
+			errs := cfg.validateQuotas()
+			if len(errs) != tt.wantErrs {
+				t.Errorf("got %d errors, want %d: %v", len(errs), tt.wantErrs, errs)
+			}
+		})
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]int64{
+		"1024":   1024,
+		"500MB":  500_000_000,
+		"2 GB":   2_000_000_000,
+		"1.5GiB": 3 << 29,
+		"1tb":    1_000_000_000_000,
+	}
+	for in, want := range tests {
+		if got, err := ParseByteSize(in); err != nil || got != want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "GB", "5 furlongs", "-1GB"} {
+		if _, err := ParseByteSize(bad); err == nil {
+			t.Errorf("ParseByteSize(%q) should fail", bad)
+		}
+	}
+}
+
+// TestValidateRoles tests custom role validation
+func TestValidateCaptivePortal(t *testing.T) {
+	portal := func() CaptivePortalConfig {
+		return CaptivePortalConfig{
+			Auth:           PortalAuthPassword,
+			Password:       "guest",
+			SessionTimeout: "8h",
+			UploadLimit:    "2mbit",
+			DownloadLimit:  "10mbit",
+			APIURL:         "https://portal.example.com/api/captive",
+		}
+	}
+	tests := []struct {
+		name     string
+		mutate   func(*CaptivePortalConfig)
+		wantErrs int
+	}{
+		{"valid", func(*CaptivePortalConfig) {}, 0},
+		{"defaults", func(p *CaptivePortalConfig) { *p = CaptivePortalConfig{} }, 0},
+		{"bad auth", func(p *CaptivePortalConfig) { p.Auth = "radius" }, 1},
+		{"no password", func(p *CaptivePortalConfig) { p.Password = "" }, 1},
+		{"bad timeout", func(p *CaptivePortalConfig) { p.SessionTimeout = "1 day" }, 1},
+		{"bad rate", func(p *CaptivePortalConfig) { p.UploadLimit = "fast" }, 1},
+		{"bad api url", func(p *CaptivePortalConfig) { p.APIURL = "portal.example.com" }, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := portal()
+			tt.mutate(&p)
+			cfg := &Config{CaptivePortal: &p}
+			errs := cfg.validateCaptivePortal()
+			if len(errs) != tt.wantErrs {
+				t.Errorf("got %d errors, want %d: %v", len(errs), tt.wantErrs, errs)
+			}
+		})
+	}
+}
+
+func TestPortalSettings(t *testing.T) {
+	cfg := &Config{Zones: []Zone{{Name: "lan"}}}
+	if p := cfg.PortalSettings(); p != nil {
+		t.Fatalf("PortalSettings() = %+v without a portal zone; want nil", p)
+	}
+
+	cfg.Zones = append(cfg.Zones, Zone{Name: "guest", Services: &ZoneServices{CaptivePortal: true}})
+	p := cfg.PortalSettings()
+	if p == nil || p.Auth != PortalAuthClickThrough || p.Port != DefaultPortalPort ||
+		p.SessionDuration() != DefaultPortalSessionTimeout {
+		t.Errorf("PortalSettings() = %+v; want click-through defaults", p)
+	}
+}
+
+func TestValidateNAT64(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(*Config)
+		wantErrs int
+	}{
+		{"valid", func(*Config) {}, 0},
+		{"defaults", func(c *Config) { *c.NAT64 = NAT64Config{} }, 0},
+		{"/64 prefix", func(c *Config) { c.NAT64.Prefix = "2001:db8:64:1::/64" }, 0},
+		{"bad prefix length", func(c *Config) { c.NAT64.Prefix = "2001:db8:64::/80" }, 1},
+		{"u octet set", func(c *Config) { c.NAT64.Prefix = "2001:db8:64:0:ff00::/96" }, 1},
+		{"ipv4 prefix", func(c *Config) { c.NAT64.Prefix = "10.0.0.0/8" }, 1},
+		{"ipv6 pool", func(c *Config) { c.NAT64.Pool4 = "2001:db8::/64" }, 1},
+		{"pool too small", func(c *Config) { c.NAT64.Pool4 = "100.127.255.0/31" }, 1},
+		{"pool overlaps lan", func(c *Config) { c.NAT64.Pool4 = "192.168.1.0/25" }, 1},
+		{"bad interface", func(c *Config) { c.NAT64.Interface = "this-name-is-too-long" }, 1},
+		{"bad timeout", func(c *Config) { c.NAT64.BindingTimeout = "forever" }, 1},
+		{"bad dns64 prefix", func(c *Config) { c.DNS.Serve[0].DNS64Prefix = "64:ff9b::/97" }, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Interfaces: []Interface{{Name: "eth1", IPv4: []string{"192.168.1.1/24"}}},
+				DNS:        &DNS{Serve: []DNSServe{{Zone: "iot6", DNS64: true}}},
+				NAT64: &NAT64Config{
+					Prefix:         "64:ff9b::/96",
+					Pool4:          "100.127.255.0/24",
+					Interface:      "nat64",
+					BindingTimeout: "30m",
+				},
+			}
+			tt.mutate(cfg)
+			errs := cfg.validateNAT64()
+			if len(errs) != tt.wantErrs {
+				t.Errorf("got %d errors, want %d: %v", len(errs), tt.wantErrs, errs)
+			}
+		})
+	}
+}
+
+func TestNAT64Settings(t *testing.T) {
+	cfg := &Config{Zones: []Zone{{Name: "lan"}}}
+	if n := cfg.NAT64Settings(); n != nil {
+		t.Fatalf("NAT64Settings() = %+v without a NAT64 zone; want nil", n)
+	}
+
+	cfg.Zones = append(cfg.Zones, Zone{Name: "iot6", Services: &ZoneServices{NAT64: true}})
+	n := cfg.NAT64Settings()
+	if n == nil || n.Prefix != DefaultNAT64Prefix || n.Pool4 != DefaultNAT64Pool4 ||
+		n.Interface != DefaultNAT64Interface || n.BindingDuration() != DefaultNAT64BindingTimeout {
+		t.Errorf("NAT64Settings() = %+v; want defaults", n)
+	}
+
+	serve := &DNSServe{Zone: "iot6", DNS64: true}
+	if got := cfg.DNS64Prefix(serve); got != DefaultNAT64Prefix {
+		t.Errorf("DNS64Prefix() = %q; want %q", got, DefaultNAT64Prefix)
+	}
+	cfg.NAT64 = &NAT64Config{Prefix: "2001:db8:64::/96"}
+	if got := cfg.DNS64Prefix(serve); got != "2001:db8:64::/96" {
+		t.Errorf("DNS64Prefix() = %q; want the nat64 prefix", got)
+	}
+}
+
+func TestParseBitRate(t *testing.T) {
+	tests := map[string]int64{
+		"512kbit": 512_000,
+		"5mbit":   5_000_000,
+		"1.5Mbps": 1_500_000,
+		"1 gbit":  1_000_000_000,
+	}
+	for in, want := range tests {
+		if got, err := ParseBitRate(in); err != nil || got != want {
+			t.Errorf("ParseBitRate(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "100", "mbit", "5 furlongs"} {
+		if _, err := ParseBitRate(bad); err == nil {
+			t.Errorf("ParseBitRate(%q) should fail", bad)
+		}
+	}
+}
+
+func TestValidateRoles(t *testing.T) {
+	tests := []struct {
+		name     string
+		roles    []RoleConfig
+		wantErrs int
+	}{
+		{
+			name:     "valid role",
+			roles:    []RoleConfig{{Name: "helpdesk", Permissions: []string{"learning:apply", "dhcp:read"}}},
+			wantErrs: 0,
+		},
+		{
+			name:     "wildcards",
+			roles:    []RoleConfig{{Name: "auditor", Permissions: []string{"*:read", "policies:*"}}},
+			wantErrs: 0,
+		},
+		{
+			name:     "built-in name",
+			roles:    []RoleConfig{{Name: "admin", Permissions: []string{"*"}}},
+			wantErrs: 1,
+		},
+		{
+			name:     "duplicate",
+			roles:    []RoleConfig{{Name: "a", Permissions: []string{"dns:read"}}, {Name: "a", Permissions: []string{"dns:read"}}},
+			wantErrs: 1,
+		},
+		{
+			name:     "bad permissions",
+			roles:    []RoleConfig{{Name: "a", Permissions: []string{"dhcp", "dhcp:delete"}}},
+			wantErrs: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{API: &APIConfig{Roles: tt.roles}}
+			errs := cfg.validateRoles()
+			if len(errs) != tt.wantErrs {
+				t.Errorf("got %d errors, want %d: %v", len(errs), tt.wantErrs, errs)
+			}
+		})
+	}
+}
+
 // TestValidationHelpers tests helper functions
 func TestValidationHelpers(t *testing.T) {
 	// isValidInterfaceName
@@ -834,3 +1318,105 @@ func TestDeepValidate_WithErrors(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateDDNS(t *testing.T) {
+	tests := []struct {
+		name     string
+		ddns     DDNSConfig
+		wantErrs int
+	}{
+		{"duckdns", DDNSConfig{Provider: "duckdns", Hostname: "home", Family: "dual"}, 0},
+		{"rfc2136", DDNSConfig{Provider: "rfc2136", Hostname: "gw.example.com", Server: "ns1.example.com",
+			TSIGKey: "ddns", TSIGSecret: "c2VjcmV0", TSIGAlgorithm: "hmac-sha512"}, 0},
+		{"rfc2136 no server", DDNSConfig{Provider: "rfc2136", Hostname: "gw.example.com"}, 1},
+		{"rfc2136 key without secret", DDNSConfig{Provider: "rfc2136", Hostname: "gw.example.com", Server: "ns1", TSIGKey: "ddns"}, 1},
+		{"rfc2136 bad secret", DDNSConfig{Provider: "rfc2136", Hostname: "gw.example.com", Server: "ns1", TSIGKey: "ddns", TSIGSecret: "not base64!"}, 1},
+		{"rfc2136 bad algorithm", DDNSConfig{Provider: "rfc2136", Hostname: "gw.example.com", Server: "ns1", TSIGAlgorithm: "hmac-md4"}, 1},
+		{"http", DDNSConfig{Provider: "http", Hostname: "gw", URL: "https://dyn.example.com/update?ip={ip}"}, 0},
+		{"http no url", DDNSConfig{Provider: "http", Hostname: "gw"}, 1},
+		{"unknown provider", DDNSConfig{Provider: "dyndns", Hostname: "gw"}, 1},
+		{"bad family", DDNSConfig{Provider: "noip", Hostname: "gw", Family: "ipv5"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.ddns.Enabled = true
+			cfg := &Config{DDNS: &tt.ddns}
+			errs := cfg.validateDDNS()
+			if len(errs) != tt.wantErrs {
+				t.Errorf("got %d errors, want %d: %v", len(errs), tt.wantErrs, errs)
+			}
+		})
+	}
+}
+
+func TestValidateWireGuard(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(*WireGuardConfig)
+		wantErrs int
+	}{
+		{"valid", func(*WireGuardConfig) {}, 0},
+		{"endpoint without port", func(wg *WireGuardConfig) { wg.ClientEndpoint = "vpn.example.com" }, 1},
+		{"bad client route", func(wg *WireGuardConfig) { wg.ClientAllowedIPs = []string{"10.0.0.0"} }, 1},
+		{"bad rotation", func(wg *WireGuardConfig) { wg.KeyRotation = "90 days" }, 1},
+		{"negative idle timeout", func(wg *WireGuardConfig) { wg.IdleTimeout = "-1h" }, 1},
+		{"bad created", func(wg *WireGuardConfig) { wg.Peers[0].Created = "yesterday" }, 1},
+		{"rfc3339 expiry", func(wg *WireGuardConfig) { wg.Peers[0].Expires = "2027-01-01T12:00:00Z" }, 0},
+		{"bad expiry", func(wg *WireGuardConfig) { wg.Peers[0].Expires = "01/01/2027" }, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wg := WireGuardConfig{
+				Name:             "rw",
+				ClientEndpoint:   "vpn.example.com:51820",
+				ClientAllowedIPs: []string{"0.0.0.0/0", "::/0"},
+				KeyRotation:      "2160h",
+				IdleTimeout:      "720h",
+				Peers: []WireGuardPeerConfig{{
+					Name:    "phone",
+					Created: "2026-03-04T15:30:00Z",
+					Expires: "2027-01-01",
+				}},
+			}
+			tt.mutate(&wg)
+			cfg := &Config{VPN: &VPNConfig{WireGuard: []WireGuardConfig{wg}}}
+			errs := cfg.validateWireGuard()
+			if len(errs) != tt.wantErrs {
+				t.Errorf("got %d errors, want %d: %v", len(errs), tt.wantErrs, errs)
+			}
+		})
+	}
+}
+
+func TestValidateNotifications(t *testing.T) {
+	t.Setenv("FLYWALL_CONFIG_DIR", "/etc/flywall")
+
+	tests := []struct {
+		name     string
+		command  []string
+		wantErrs int
+	}{
+		{"hook", []string{"/etc/flywall/hooks.d/page-oncall", "--urgent"}, 0},
+		{"nested hook", []string{"/etc/flywall/hooks.d/site/notify.sh"}, 0},
+		{"no command", nil, 1},
+		{"relative", []string{"hooks.d/notify.sh"}, 1},
+		{"outside hooks", []string{"/bin/sh", "-c", "id"}, 1},
+		{"escapes hooks", []string{"/etc/flywall/hooks.d/../../../bin/sh"}, 1},
+		{"hooks dir itself", []string{"/etc/flywall/hooks.d"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Notifications: &NotificationsConfig{Channels: []NotificationChannel{
+				{Name: "slack", Type: "slack", WebhookURL: "https://hooks.slack.com/x"},
+				{Name: "hook", Type: "exec", Command: tt.command},
+			}}}
+			errs := cfg.validateNotifications()
+			if len(errs) != tt.wantErrs {
+				t.Errorf("got %d errors, want %d: %v", len(errs), tt.wantErrs, errs)
+			}
+		})
+	}
+}
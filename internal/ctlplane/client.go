@@ -21,8 +21,13 @@ import (
 	"grimm.is/flywall/internal/learning"
 	"grimm.is/flywall/internal/learning/flowdb"
 	"grimm.is/flywall/internal/metrics"
+	"grimm.is/flywall/internal/quota"
+	"grimm.is/flywall/internal/routing"
 	"grimm.is/flywall/internal/services/dns/querylog"
+	"grimm.is/flywall/internal/services/portal"
 	"grimm.is/flywall/internal/services/scanner"
+	"grimm.is/flywall/internal/trace"
+	"grimm.is/flywall/internal/vpn"
 )
 
 type Client struct {
@@ -1179,6 +1184,195 @@ func (c *Client) Ping(target string, timeoutSeconds int) (*PingReply, error) {
 	return &reply, nil
 }
 
+// --- Packet Trace ---
+
+// TracePacket traces a packet through the live ruleset and routing tables
+func (c *Client) TracePacket(pkt trace.Packet) (*trace.Result, error) {
+	var reply TracePacketReply
+	if err := c.call("Server.TracePacket", &TracePacketArgs{Packet: pkt}, &reply); err != nil {
+		return nil, err
+	}
+	if reply.Error != "" {
+		return nil, fmt.Errorf("%s", reply.Error)
+	}
+	return reply.Result, nil
+}
+
+// --- Dynamic Routing ---
+
+// GetRoutingStatus returns live BGP, OSPF and BFD state from FRR
+func (c *Client) GetRoutingStatus() (*routing.Status, error) {
+	var reply GetRoutingStatusReply
+	if err := c.call("Server.GetRoutingStatus", &Empty{}, &reply); err != nil {
+		return nil, err
+	}
+	if reply.Error != "" {
+		return nil, fmt.Errorf("%s", reply.Error)
+	}
+	return reply.Status, nil
+}
+
+// --- Device Quotas ---
+
+// GetQuotaStatus returns usage against every configured device quota
+func (c *Client) GetQuotaStatus() ([]quota.Status, error) {
+	var reply GetQuotaStatusReply
+	if err := c.call("Server.GetQuotaStatus", &Empty{}, &reply); err != nil {
+		return nil, err
+	}
+	if reply.Error != "" {
+		return nil, fmt.Errorf("%s", reply.Error)
+	}
+	return reply.Status, nil
+}
+
+// ResetQuota resets a quota's counters for one subject, or all if subject is empty
+func (c *Client) ResetQuota(quotaName, subject string) error {
+	var reply ResetQuotaReply
+	if err := c.call("Server.ResetQuota", &ResetQuotaArgs{Quota: quotaName, Subject: subject}, &reply); err != nil {
+		return err
+	}
+	if reply.Error != "" {
+		return fmt.Errorf("%s", reply.Error)
+	}
+	return nil
+}
+
+// --- Captive Portal ---
+
+// GetPortalSessions returns the clients logged in to the captive portal
+func (c *Client) GetPortalSessions() ([]portal.Session, error) {
+	var reply GetPortalSessionsReply
+	if err := c.call("Server.GetPortalSessions", &Empty{}, &reply); err != nil {
+		return nil, err
+	}
+	if reply.Error != "" {
+		return nil, fmt.Errorf("%s", reply.Error)
+	}
+	return reply.Sessions, nil
+}
+
+// RevokePortalSession logs a client out of the captive portal
+func (c *Client) RevokePortalSession(mac string) error {
+	var reply PortalReply
+	if err := c.call("Server.RevokePortalSession", &RevokePortalSessionArgs{MAC: mac}, &reply); err != nil {
+		return err
+	}
+	if reply.Error != "" {
+		return fmt.Errorf("%s", reply.Error)
+	}
+	return nil
+}
+
+// GetPortalVouchers returns the issued captive portal vouchers
+func (c *Client) GetPortalVouchers() ([]portal.Voucher, error) {
+	var reply GetPortalVouchersReply
+	if err := c.call("Server.GetPortalVouchers", &Empty{}, &reply); err != nil {
+		return nil, err
+	}
+	if reply.Error != "" {
+		return nil, fmt.Errorf("%s", reply.Error)
+	}
+	return reply.Vouchers, nil
+}
+
+// CreatePortalVouchers issues new captive portal vouchers
+func (c *Client) CreatePortalVouchers(count int, duration, note string) ([]portal.Voucher, error) {
+	var reply GetPortalVouchersReply
+	args := &CreatePortalVouchersArgs{Count: count, Duration: duration, Note: note}
+	if err := c.call("Server.CreatePortalVouchers", args, &reply); err != nil {
+		return nil, err
+	}
+	if reply.Error != "" {
+		return nil, fmt.Errorf("%s", reply.Error)
+	}
+	return reply.Vouchers, nil
+}
+
+// DeletePortalVoucher withdraws a voucher and ends the session it granted
+func (c *Client) DeletePortalVoucher(code string) error {
+	var reply PortalReply
+	if err := c.call("Server.DeletePortalVoucher", &DeletePortalVoucherArgs{Code: code}, &reply); err != nil {
+		return err
+	}
+	if reply.Error != "" {
+		return fmt.Errorf("%s", reply.Error)
+	}
+	return nil
+}
+
+// --- Connection Tracking ---
+
+// GetConntrack returns the matching conntrack entries and how many matched before paging
+func (c *Client) GetConntrack(filter firewall.ConntrackFilter, limit, offset int) ([]firewall.ConntrackEntry, int, error) {
+	var reply GetConntrackReply
+	args := &GetConntrackArgs{Filter: filter, Limit: limit, Offset: offset}
+	if err := c.call("Server.GetConntrack", args, &reply); err != nil {
+		return nil, 0, err
+	}
+	if reply.Error != "" {
+		return nil, 0, fmt.Errorf("%s", reply.Error)
+	}
+	return reply.Entries, reply.Total, nil
+}
+
+// KillConntrack deletes the matching conntrack entries and returns how many were deleted
+func (c *Client) KillConntrack(filter firewall.ConntrackFilter) (int, error) {
+	var reply KillConntrackReply
+	if err := c.call("Server.KillConntrack", &KillConntrackArgs{Filter: filter}, &reply); err != nil {
+		return 0, err
+	}
+	if reply.Error != "" {
+		return reply.Deleted, fmt.Errorf("%s", reply.Error)
+	}
+	return reply.Deleted, nil
+}
+
+// GetConntrackEvents returns conntrack events after sinceID and the latest event ID
+func (c *Client) GetConntrackEvents(sinceID int64, filter firewall.ConntrackFilter) ([]ConntrackEventRecord, int64, error) {
+	var reply GetConntrackEventsReply
+	args := &GetConntrackEventsArgs{SinceID: sinceID, Filter: filter}
+	if err := c.call("Server.GetConntrackEvents", args, &reply); err != nil {
+		return nil, sinceID, err
+	}
+	if reply.Error != "" {
+		return reply.Events, reply.LastID, fmt.Errorf("%s", reply.Error)
+	}
+	return reply.Events, reply.LastID, nil
+}
+
+// ProvisionWireGuardPeer stages a new road-warrior peer and returns its client config.
+// If applying fails the profile is returned along with the error, since the
+// peer remains staged.
+func (c *Client) ProvisionWireGuardPeer(args *ProvisionWireGuardPeerArgs) (*vpn.ClientProfile, error) {
+	var reply WireGuardClientReply
+	if err := c.call("Server.ProvisionWireGuardPeer", args, &reply); err != nil {
+		return nil, err
+	}
+	if reply.Error != "" {
+		return nil, fmt.Errorf("%s", reply.Error)
+	}
+	if reply.ApplyError != "" {
+		return &reply.Profile, fmt.Errorf("peer staged but apply failed: %s", reply.ApplyError)
+	}
+	return &reply.Profile, nil
+}
+
+// RotateWireGuardPeer stages new keys for a peer and returns its client config
+func (c *Client) RotateWireGuardPeer(args *RotateWireGuardPeerArgs) (*vpn.ClientProfile, error) {
+	var reply WireGuardClientReply
+	if err := c.call("Server.RotateWireGuardPeer", args, &reply); err != nil {
+		return nil, err
+	}
+	if reply.Error != "" {
+		return nil, fmt.Errorf("%s", reply.Error)
+	}
+	if reply.ApplyError != "" {
+		return &reply.Profile, fmt.Errorf("peer staged but apply failed: %s", reply.ApplyError)
+	}
+	return &reply.Profile, nil
+}
+
 // --- Safe Mode Operations ---
 
 // IsInSafeMode checks if safe mode is currently active.
@@ -1216,6 +1410,94 @@ func (c *Client) GetAlertHistory(limit int) ([]alerting.AlertEvent, error) {
 	return reply.Events, nil
 }
 
+// GetAlertChannelStatus returns delivery status for each notification channel
+func (c *Client) GetAlertChannelStatus() ([]alerting.ChannelStatus, error) {
+	var reply GetAlertChannelStatusReply
+	err := c.call("Server.GetAlertChannelStatus", &GetAlertChannelStatusArgs{}, &reply)
+	if err != nil {
+		return nil, err
+	}
+	if reply.Error != "" {
+		return nil, fmt.Errorf("%s", reply.Error)
+	}
+	return reply.Channels, nil
+}
+
+// GetAlerts returns alert groups and their acknowledgement state
+func (c *Client) GetAlerts() ([]alerting.Alert, error) {
+	var reply GetAlertsReply
+	err := c.call("Server.GetAlerts", &GetAlertsArgs{}, &reply)
+	if err != nil {
+		return nil, err
+	}
+	if reply.Error != "" {
+		return nil, fmt.Errorf("%s", reply.Error)
+	}
+	return reply.Alerts, nil
+}
+
+// AckAlert acknowledges an alert group on behalf of actor
+func (c *Client) AckAlert(id, actor string) error {
+	var reply AlertActionReply
+	if err := c.call("Server.AckAlert", &AlertActionArgs{ID: id, Actor: actor}, &reply); err != nil {
+		return err
+	}
+	if reply.Error != "" {
+		return fmt.Errorf("%s", reply.Error)
+	}
+	return nil
+}
+
+// ResolveAlert resolves an alert group on behalf of actor
+func (c *Client) ResolveAlert(id, actor string) error {
+	var reply AlertActionReply
+	if err := c.call("Server.ResolveAlert", &AlertActionArgs{ID: id, Actor: actor}, &reply); err != nil {
+		return err
+	}
+	if reply.Error != "" {
+		return fmt.Errorf("%s", reply.Error)
+	}
+	return nil
+}
+
+// GetSilences returns active and scheduled alert silences
+func (c *Client) GetSilences() ([]alerting.Silence, error) {
+	var reply GetSilencesReply
+	err := c.call("Server.GetSilences", &GetSilencesArgs{}, &reply)
+	if err != nil {
+		return nil, err
+	}
+	if reply.Error != "" {
+		return nil, fmt.Errorf("%s", reply.Error)
+	}
+	return reply.Silences, nil
+}
+
+// CreateSilence adds an alert silence and returns it with its assigned ID
+func (c *Client) CreateSilence(silence alerting.Silence) (*alerting.Silence, error) {
+	var reply CreateSilenceReply
+	err := c.call("Server.CreateSilence", &CreateSilenceArgs{Silence: silence}, &reply)
+	if err != nil {
+		return nil, err
+	}
+	if reply.Error != "" {
+		return nil, fmt.Errorf("%s", reply.Error)
+	}
+	return reply.Silence, nil
+}
+
+// DeleteSilence removes an alert silence
+func (c *Client) DeleteSilence(id string) error {
+	var reply DeleteSilenceReply
+	if err := c.call("Server.DeleteSilence", &DeleteSilenceArgs{ID: id}, &reply); err != nil {
+		return err
+	}
+	if reply.Error != "" {
+		return fmt.Errorf("%s", reply.Error)
+	}
+	return nil
+}
+
 // GetAlertRules returns currently configured alert rules
 func (c *Client) GetAlertRules() ([]alerting.AlertRule, error) {
 	var reply GetAlertRulesReply
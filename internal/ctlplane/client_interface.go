@@ -12,9 +12,14 @@ import (
 	"grimm.is/flywall/internal/identity"
 	"grimm.is/flywall/internal/learning"
 	"grimm.is/flywall/internal/learning/flowdb"
-	"grimm.is/flywall/internal/metrics"               // Added import
+	"grimm.is/flywall/internal/metrics" // Added import
+	"grimm.is/flywall/internal/quota"
+	"grimm.is/flywall/internal/routing"
 	"grimm.is/flywall/internal/services/dns/querylog" // Added import
+	"grimm.is/flywall/internal/services/portal"
 	"grimm.is/flywall/internal/services/scanner"
+	"grimm.is/flywall/internal/trace"
+	"grimm.is/flywall/internal/vpn"
 )
 
 // ControlPlaneClient defines the interface for communicating with the control plane.
@@ -134,6 +139,13 @@ type ControlPlaneClient interface {
 	// --- Alerting ---
 	GetAlertHistory(limit int) ([]alerting.AlertEvent, error)
 	GetAlertRules() ([]alerting.AlertRule, error)
+	GetAlertChannelStatus() ([]alerting.ChannelStatus, error)
+	GetAlerts() ([]alerting.Alert, error)
+	AckAlert(id, actor string) error
+	ResolveAlert(id, actor string) error
+	GetSilences() ([]alerting.Silence, error)
+	CreateSilence(silence alerting.Silence) (*alerting.Silence, error)
+	DeleteSilence(id string) error
 	UpdateAlertRule(rule alerting.AlertRule) error
 
 	// --- Network Scanner ---
@@ -156,6 +168,32 @@ type ControlPlaneClient interface {
 	// --- Ping (Connectivity Verification) ---
 	Ping(target string, timeoutSeconds int) (*PingReply, error)
 
+	// --- Packet Trace ---
+	TracePacket(pkt trace.Packet) (*trace.Result, error)
+
+	// --- Dynamic Routing ---
+	GetRoutingStatus() (*routing.Status, error)
+
+	// --- Device Quotas ---
+	GetQuotaStatus() ([]quota.Status, error)
+	ResetQuota(quotaName, subject string) error
+
+	// --- Captive Portal ---
+	GetPortalSessions() ([]portal.Session, error)
+	RevokePortalSession(mac string) error
+	GetPortalVouchers() ([]portal.Voucher, error)
+	CreatePortalVouchers(count int, duration, note string) ([]portal.Voucher, error)
+	DeletePortalVoucher(code string) error
+
+	// --- Connection Tracking ---
+	GetConntrack(filter firewall.ConntrackFilter, limit, offset int) ([]firewall.ConntrackEntry, int, error)
+	KillConntrack(filter firewall.ConntrackFilter) (int, error)
+	GetConntrackEvents(sinceID int64, filter firewall.ConntrackFilter) ([]ConntrackEventRecord, int64, error)
+
+	// --- WireGuard Provisioning ---
+	ProvisionWireGuardPeer(args *ProvisionWireGuardPeerArgs) (*vpn.ClientProfile, error)
+	RotateWireGuardPeer(args *RotateWireGuardPeerArgs) (*vpn.ClientProfile, error)
+
 	// --- Safe Mode ---
 	IsInSafeMode() (bool, error)
 	EnterSafeMode() error
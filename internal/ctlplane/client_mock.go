@@ -13,8 +13,13 @@ import (
 	"grimm.is/flywall/internal/learning"
 	"grimm.is/flywall/internal/learning/flowdb"
 	"grimm.is/flywall/internal/metrics"
+	"grimm.is/flywall/internal/quota"
+	"grimm.is/flywall/internal/routing"
 	"grimm.is/flywall/internal/services/dns/querylog"
+	"grimm.is/flywall/internal/services/portal"
 	"grimm.is/flywall/internal/services/scanner"
+	"grimm.is/flywall/internal/trace"
+	"grimm.is/flywall/internal/vpn"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -626,6 +631,112 @@ func (m *MockControlPlaneClient) Ping(target string, timeoutSeconds int) (*PingR
 	return callArgs.Get(0).(*PingReply), callArgs.Error(1)
 }
 
+func (m *MockControlPlaneClient) TracePacket(pkt trace.Packet) (*trace.Result, error) {
+	args := m.Called(pkt)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*trace.Result), args.Error(1)
+}
+
+func (m *MockControlPlaneClient) GetRoutingStatus() (*routing.Status, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*routing.Status), args.Error(1)
+}
+
+func (m *MockControlPlaneClient) GetQuotaStatus() ([]quota.Status, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]quota.Status), args.Error(1)
+}
+
+func (m *MockControlPlaneClient) ResetQuota(quotaName, subject string) error {
+	args := m.Called(quotaName, subject)
+	return args.Error(0)
+}
+
+// --- Captive Portal ---
+
+func (m *MockControlPlaneClient) GetPortalSessions() ([]portal.Session, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]portal.Session), args.Error(1)
+}
+
+func (m *MockControlPlaneClient) RevokePortalSession(mac string) error {
+	args := m.Called(mac)
+	return args.Error(0)
+}
+
+func (m *MockControlPlaneClient) GetPortalVouchers() ([]portal.Voucher, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]portal.Voucher), args.Error(1)
+}
+
+func (m *MockControlPlaneClient) CreatePortalVouchers(count int, duration, note string) ([]portal.Voucher, error) {
+	args := m.Called(count, duration, note)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]portal.Voucher), args.Error(1)
+}
+
+func (m *MockControlPlaneClient) DeletePortalVoucher(code string) error {
+	args := m.Called(code)
+	return args.Error(0)
+}
+
+// --- Connection Tracking ---
+
+func (m *MockControlPlaneClient) GetConntrack(filter firewall.ConntrackFilter, limit, offset int) ([]firewall.ConntrackEntry, int, error) {
+	args := m.Called(filter, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]firewall.ConntrackEntry), args.Int(1), args.Error(2)
+}
+
+func (m *MockControlPlaneClient) KillConntrack(filter firewall.ConntrackFilter) (int, error) {
+	args := m.Called(filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockControlPlaneClient) GetConntrackEvents(sinceID int64, filter firewall.ConntrackFilter) ([]ConntrackEventRecord, int64, error) {
+	args := m.Called(sinceID, filter)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]ConntrackEventRecord), args.Get(1).(int64), args.Error(2)
+}
+
+// --- WireGuard Provisioning ---
+
+func (m *MockControlPlaneClient) ProvisionWireGuardPeer(args *ProvisionWireGuardPeerArgs) (*vpn.ClientProfile, error) {
+	callArgs := m.Called(args)
+	if callArgs.Get(0) == nil {
+		return nil, callArgs.Error(1)
+	}
+	return callArgs.Get(0).(*vpn.ClientProfile), callArgs.Error(1)
+}
+
+func (m *MockControlPlaneClient) RotateWireGuardPeer(args *RotateWireGuardPeerArgs) (*vpn.ClientProfile, error) {
+	callArgs := m.Called(args)
+	if callArgs.Get(0) == nil {
+		return nil, callArgs.Error(1)
+	}
+	return callArgs.Get(0).(*vpn.ClientProfile), callArgs.Error(1)
+}
+
 // --- Safe Mode ---
 
 func (m *MockControlPlaneClient) IsInSafeMode() (bool, error) {
@@ -684,6 +795,50 @@ func (m *MockControlPlaneClient) GetAlertRules() ([]alerting.AlertRule, error) {
 	return args.Get(0).([]alerting.AlertRule), args.Error(1)
 }
 
+func (m *MockControlPlaneClient) GetAlertChannelStatus() ([]alerting.ChannelStatus, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]alerting.ChannelStatus), args.Error(1)
+}
+
+func (m *MockControlPlaneClient) GetAlerts() ([]alerting.Alert, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]alerting.Alert), args.Error(1)
+}
+
+func (m *MockControlPlaneClient) AckAlert(id, actor string) error {
+	return m.Called(id, actor).Error(0)
+}
+
+func (m *MockControlPlaneClient) ResolveAlert(id, actor string) error {
+	return m.Called(id, actor).Error(0)
+}
+
+func (m *MockControlPlaneClient) GetSilences() ([]alerting.Silence, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]alerting.Silence), args.Error(1)
+}
+
+func (m *MockControlPlaneClient) CreateSilence(silence alerting.Silence) (*alerting.Silence, error) {
+	args := m.Called(silence)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*alerting.Silence), args.Error(1)
+}
+
+func (m *MockControlPlaneClient) DeleteSilence(id string) error {
+	return m.Called(id).Error(0)
+}
+
 func (m *MockControlPlaneClient) UpdateAlertRule(rule alerting.AlertRule) error {
 	return m.Called(rule).Error(0)
 }
@@ -0,0 +1,242 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package ctlplane
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/errors"
+	"grimm.is/flywall/internal/firewall"
+)
+
+// Conntrack event log sizing. Events are only collected while someone is
+// reading them; the listener stops after conntrackWatchIdle without a read.
+const (
+	conntrackEventBuffer = 1000
+	conntrackWatchIdle   = time.Minute
+)
+
+// GetConntrack lists connection tracking entries, ordered by ID.
+func (s *Server) GetConntrack(args *GetConntrackArgs, reply *GetConntrackReply) error {
+	m, err := s.compileConntrackFilter(args.Filter)
+	if err != nil {
+		reply.Error = err.Error()
+		return nil
+	}
+	entries, err := firewall.ListConntrack(m)
+	if err != nil {
+		reply.Error = err.Error()
+		return nil
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+
+	reply.Total = len(entries)
+	if args.Offset > 0 {
+		if args.Offset >= len(entries) {
+			entries = nil
+		} else {
+			entries = entries[args.Offset:]
+		}
+	}
+	if args.Limit > 0 && len(entries) > args.Limit {
+		entries = entries[:args.Limit]
+	}
+	reply.Entries = entries
+	return nil
+}
+
+// KillConntrack deletes the matching connection tracking entries.
+func (s *Server) KillConntrack(args *KillConntrackArgs, reply *KillConntrackReply) error {
+	m, err := s.compileConntrackFilter(args.Filter)
+	if err != nil {
+		reply.Error = err.Error()
+		return nil
+	}
+	deleted, err := firewall.DeleteConntrack(m)
+	reply.Deleted = deleted
+	if err != nil {
+		reply.Error = err.Error()
+		return nil
+	}
+	log.Printf("[CTL] Deleted %d conntrack entries (filter %+v)", deleted, args.Filter)
+	return nil
+}
+
+// GetConntrackEvents returns connections created or destroyed since a given
+// event ID. The first call starts listening, so it returns no events.
+func (s *Server) GetConntrackEvents(args *GetConntrackEventsArgs, reply *GetConntrackEventsReply) error {
+	m, err := s.compileConntrackFilter(args.Filter)
+	if err != nil {
+		reply.Error = err.Error()
+		return nil
+	}
+	events, lastID, err := s.conntrackEvents.since(args.SinceID)
+	if err != nil {
+		reply.Error = err.Error()
+	}
+	reply.LastID = lastID
+	for i := range events {
+		if m.Match(&events[i].Entry) {
+			reply.Events = append(reply.Events, events[i])
+		}
+	}
+	return nil
+}
+
+// compileConntrackFilter resolves the filter's zone to the networks it
+// currently covers.
+func (s *Server) compileConntrackFilter(f firewall.ConntrackFilter) (*firewall.ConntrackMatcher, error) {
+	var nets []netip.Prefix
+	if f.Zone != "" {
+		s.mu.RLock()
+		var zones []config.Zone
+		if s.config != nil {
+			zones = s.config.Zones
+		}
+		s.mu.RUnlock()
+
+		var err error
+		if nets, err = zoneNetworks(zones, f.Zone); err != nil {
+			return nil, err
+		}
+	}
+	return f.Compile(nets)
+}
+
+// zoneNetworks returns the source networks of a zone's matches, and the
+// networks on the interfaces it matches.
+func zoneNetworks(zones []config.Zone, zone string) ([]netip.Prefix, error) {
+	resolver := config.NewZoneResolver(zones)
+	matches := resolver.GetEffectiveMatches(zone)
+	if matches == nil {
+		return nil, errors.Errorf(errors.KindNotFound, "zone %q not found", zone)
+	}
+
+	ifaces, _ := net.Interfaces()
+	var nets []netip.Prefix
+	for _, m := range matches {
+		if m.Src != "" {
+			for _, cidr := range strings.Split(m.Src, ",") {
+				cidr = strings.TrimSpace(cidr)
+				if p, err := netip.ParsePrefix(cidr); err == nil {
+					nets = append(nets, p.Masked())
+				} else if a, err := netip.ParseAddr(cidr); err == nil {
+					nets = append(nets, netip.PrefixFrom(a, a.BitLen()))
+				}
+			}
+			continue
+		}
+		if m.Interface == "" {
+			continue
+		}
+		for _, iface := range ifaces {
+			if iface.Name != m.Interface &&
+				!(m.IsPrefix && strings.HasPrefix(iface.Name, config.InterfaceBase(m.Interface))) {
+				continue
+			}
+			addrs, _ := iface.Addrs()
+			for _, addr := range addrs {
+				ipnet, ok := addr.(*net.IPNet)
+				if !ok {
+					continue
+				}
+				ip, _ := netip.AddrFromSlice(ipnet.IP)
+				ones, _ := ipnet.Mask.Size()
+				if p, err := ip.Unmap().Prefix(ones); err == nil {
+					nets = append(nets, p)
+				}
+			}
+		}
+	}
+	return nets, nil
+}
+
+// conntrackEventLog keeps the latest conntrack events for polling readers.
+type conntrackEventLog struct {
+	mu       sync.Mutex
+	events   []ConntrackEventRecord
+	nextID   int64
+	cancel   context.CancelFunc // Set while listening
+	lastRead time.Time
+	lastErr  error
+}
+
+// since returns the events after sinceID and the ID of the latest event,
+// starting the listener if it is not running.
+func (l *conntrackEventLog) since(sinceID int64) ([]ConntrackEventRecord, int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lastRead = time.Now()
+	err := l.lastErr
+	l.lastErr = nil
+	if l.cancel == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		l.cancel = cancel
+		go l.run(ctx)
+	}
+
+	var res []ConntrackEventRecord
+	for _, ev := range l.events {
+		if ev.ID > sinceID {
+			res = append(res, ev)
+		}
+	}
+	return res, l.nextID, err
+}
+
+func (l *conntrackEventLog) add(ev firewall.ConntrackEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.nextID++
+	if len(l.events) >= conntrackEventBuffer {
+		l.events = l.events[1:]
+	}
+	l.events = append(l.events, ConntrackEventRecord{
+		ID: l.nextID, Time: time.Now(), Type: ev.Type, Entry: ev.Entry,
+	})
+}
+
+// run listens for events until nobody has read them for a while.
+func (l *conntrackEventLog) run(ctx context.Context) {
+	done := make(chan error, 1)
+	go func() { done <- firewall.WatchConntrack(ctx, l.add) }()
+
+	ticker := time.NewTicker(conntrackWatchIdle / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			l.mu.Lock()
+			l.cancel()
+			l.cancel = nil
+			l.lastErr = err
+			l.mu.Unlock()
+			if err != nil {
+				log.Printf("[CTL] Conntrack event listener stopped: %v", err)
+			}
+			return
+		case <-ticker.C:
+			l.mu.Lock()
+			if time.Since(l.lastRead) < conntrackWatchIdle {
+				l.mu.Unlock()
+				continue
+			}
+			l.cancel()
+			l.cancel = nil
+			l.events = nil
+			l.mu.Unlock()
+			<-done
+			return
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package ctlplane
+
+import (
+	"net/netip"
+	"testing"
+
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/firewall"
+)
+
+func TestZoneNetworks(t *testing.T) {
+	zones := []config.Zone{
+		{Name: "lan", Src: "192.168.1.0/24"},
+		{Name: "loop", Interface: "lo"},
+		{Name: "dmz", Matches: []config.RuleMatch{{Src: "10.0.0.0/24"}, {Src: "10.0.1.5"}}},
+	}
+
+	nets, err := zoneNetworks(zones, "LAN")
+	if err != nil || len(nets) != 1 || nets[0] != netip.MustParsePrefix("192.168.1.0/24") {
+		t.Errorf("zoneNetworks(lan) = %v, %v", nets, err)
+	}
+
+	nets, err = zoneNetworks(zones, "dmz")
+	want := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24"), netip.MustParsePrefix("10.0.1.5/32")}
+	if err != nil || len(nets) != 2 || nets[0] != want[0] || nets[1] != want[1] {
+		t.Errorf("zoneNetworks(dmz) = %v, %v; want %v", nets, err, want)
+	}
+
+	if _, err := zoneNetworks(zones, "wan"); err == nil {
+		t.Error("zoneNetworks() of an unknown zone should fail")
+	}
+
+	// Interface zones take the addresses configured on the interface
+	nets, err = zoneNetworks(zones, "loop")
+	if err != nil {
+		t.Fatalf("zoneNetworks(loop) error = %v", err)
+	}
+	found := false
+	for _, p := range nets {
+		found = found || p.Contains(netip.MustParseAddr("127.0.0.1"))
+	}
+	if !found {
+		t.Skipf("loopback has no 127.0.0.1 here: %v", nets)
+	}
+}
+
+func TestConntrackEventLogRing(t *testing.T) {
+	var l conntrackEventLog
+	for i := 0; i < conntrackEventBuffer+5; i++ {
+		l.add(firewall.ConntrackEvent{Type: firewall.ConntrackEventNew, Entry: firewall.ConntrackEntry{ID: uint32(i)}})
+	}
+	if len(l.events) != conntrackEventBuffer {
+		t.Fatalf("buffer holds %d events; want %d", len(l.events), conntrackEventBuffer)
+	}
+	if first := l.events[0]; first.ID != 6 || first.Entry.ID != 5 {
+		t.Errorf("oldest event = %+v; want the sixth", first)
+	}
+	if l.nextID != conntrackEventBuffer+5 {
+		t.Errorf("nextID = %d", l.nextID)
+	}
+}
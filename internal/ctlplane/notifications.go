@@ -3,8 +3,11 @@
 package ctlplane
 
 import (
+	"fmt"
 	"sync"
 	"time"
+
+	"grimm.is/flywall/internal/events"
 )
 
 // NotificationType represents the type of notification
@@ -102,3 +105,28 @@ func (h *NotificationHub) LastID() int64 {
 	}
 	return h.notifications[len(h.notifications)-1].ID
 }
+
+// RelayGeoIPEvents publishes GeoIP database swaps and rollbacks from the
+// event bus as notifications, which the API streams to WebSocket clients.
+func (h *NotificationHub) RelayGeoIPEvents(bus *events.Hub) {
+	ch := bus.Subscribe(16, events.EventGeoIPUpdated, events.EventGeoIPRollback)
+	go func() {
+		for e := range ch {
+			data, ok := e.Data.(events.GeoIPUpdateData)
+			if !ok {
+				continue
+			}
+			switch e.Type {
+			case events.EventGeoIPUpdated:
+				h.Publish(NotifyInfo, "GeoIP Database Updated",
+					fmt.Sprintf("Swapped in %s from %s (sha256 %s)", data.Path, data.Source, data.SHA256))
+			case events.EventGeoIPRollback:
+				msg := fmt.Sprintf("Restored %s from %s", data.Path, data.Previous)
+				if data.Error != "" {
+					msg += ": " + data.Error
+				}
+				h.Publish(NotifyWarning, "GeoIP Database Rolled Back", msg)
+			}
+		}
+	}()
+}
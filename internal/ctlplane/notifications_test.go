@@ -5,6 +5,8 @@ package ctlplane
 import (
 	"testing"
 	"time"
+
+	"grimm.is/flywall/internal/events"
 )
 
 func TestNotificationHub_Publish(t *testing.T) {
@@ -108,3 +110,35 @@ func TestNotificationHub_TimeSet(t *testing.T) {
 		t.Errorf("notification time not in expected range")
 	}
 }
+
+func TestNotificationHub_RelayGeoIPEvents(t *testing.T) {
+	hub := NewNotificationHub(10)
+	bus := events.NewHub()
+	hub.RelayGeoIPEvents(bus)
+
+	bus.EmitGeoIPUpdate(events.EventGeoIPUpdated, events.GeoIPUpdateData{
+		Path:   "/var/lib/flywall/geoip/country.mmdb",
+		Source: "https://example.com/country.mmdb.gz",
+		SHA256: "abc123",
+	})
+	bus.EmitGeoIPUpdate(events.EventGeoIPRollback, events.GeoIPUpdateData{
+		Path:     "/var/lib/flywall/geoip/country.mmdb",
+		Previous: "/var/lib/flywall/geoip/country.mmdb.previous",
+		Error:    "reload failed",
+	})
+
+	deadline := time.Now().Add(time.Second)
+	for len(hub.GetSince(0)) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	got := hub.GetSince(0)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 notifications, got %d", len(got))
+	}
+	if got[0].Type != NotifyInfo || got[0].Title != "GeoIP Database Updated" {
+		t.Errorf("swap notification = %+v", got[0])
+	}
+	if got[1].Type != NotifyWarning || got[1].Message != "Restored /var/lib/flywall/geoip/country.mmdb from /var/lib/flywall/geoip/country.mmdb.previous: reload failed" {
+		t.Errorf("rollback notification = %+v", got[1])
+	}
+}
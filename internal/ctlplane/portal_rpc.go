@@ -0,0 +1,64 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package ctlplane
+
+const errPortalNotInitialized = "captive portal not initialized"
+
+// GetPortalSessions lists the clients logged in to the captive portal.
+func (s *Server) GetPortalSessions(_ *Empty, reply *GetPortalSessionsReply) error {
+	if s.portalService == nil {
+		reply.Error = errPortalNotInitialized
+		return nil
+	}
+	reply.Sessions = s.portalService.Sessions()
+	return nil
+}
+
+// RevokePortalSession logs a client out; it sees the portal again.
+func (s *Server) RevokePortalSession(args *RevokePortalSessionArgs, reply *PortalReply) error {
+	if s.portalService == nil {
+		reply.Error = errPortalNotInitialized
+		return nil
+	}
+	if err := s.portalService.Revoke(args.MAC); err != nil {
+		reply.Error = err.Error()
+	}
+	return nil
+}
+
+// GetPortalVouchers lists issued vouchers.
+func (s *Server) GetPortalVouchers(_ *Empty, reply *GetPortalVouchersReply) error {
+	if s.portalService == nil {
+		reply.Error = errPortalNotInitialized
+		return nil
+	}
+	reply.Vouchers = s.portalService.Vouchers()
+	return nil
+}
+
+// CreatePortalVouchers issues vouchers for voucher authentication.
+func (s *Server) CreatePortalVouchers(args *CreatePortalVouchersArgs, reply *GetPortalVouchersReply) error {
+	if s.portalService == nil {
+		reply.Error = errPortalNotInitialized
+		return nil
+	}
+	vouchers, err := s.portalService.CreateVouchers(args.Count, args.Duration, args.Note)
+	if err != nil {
+		reply.Error = err.Error()
+		return nil
+	}
+	reply.Vouchers = vouchers
+	return nil
+}
+
+// DeletePortalVoucher withdraws a voucher and ends the session it granted.
+func (s *Server) DeletePortalVoucher(args *DeletePortalVoucherArgs, reply *PortalReply) error {
+	if s.portalService == nil {
+		reply.Error = errPortalNotInitialized
+		return nil
+	}
+	if err := s.portalService.DeleteVoucher(args.Code); err != nil {
+		reply.Error = err.Error()
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package ctlplane
+
+// GetQuotaStatus reports usage against every configured device quota.
+func (s *Server) GetQuotaStatus(_ *Empty, reply *GetQuotaStatusReply) error {
+	if s.quotaManager == nil {
+		reply.Error = "quota manager not initialized"
+		return nil
+	}
+	reply.Status = s.quotaManager.Status()
+	return nil
+}
+
+// ResetQuota restarts a quota's counters and lifts any block or throttle.
+func (s *Server) ResetQuota(args *ResetQuotaArgs, reply *ResetQuotaReply) error {
+	if s.quotaManager == nil {
+		reply.Error = "quota manager not initialized"
+		return nil
+	}
+	if err := s.quotaManager.Reset(args.Quota, args.Subject); err != nil {
+		reply.Error = err.Error()
+	}
+	return nil
+}
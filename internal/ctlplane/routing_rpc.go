@@ -0,0 +1,30 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package ctlplane
+
+import (
+	"context"
+
+	"grimm.is/flywall/internal/routing"
+)
+
+// GetRoutingStatus reports BGP sessions, OSPF adjacencies, BFD peers and
+// the RIB as FRR currently sees them.
+func (s *Server) GetRoutingStatus(_ *Empty, reply *GetRoutingStatusReply) error {
+	s.mu.RLock()
+	cfg := s.config
+	s.mu.RUnlock()
+
+	if cfg == nil || cfg.FRR == nil || !cfg.FRR.Enabled {
+		reply.Error = "dynamic routing is not enabled (frr.enabled = false)"
+		return nil
+	}
+
+	st, err := routing.GetStatus(context.Background())
+	if err != nil {
+		reply.Error = err.Error()
+		return nil
+	}
+	reply.Status = st
+	return nil
+}
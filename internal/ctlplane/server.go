@@ -32,6 +32,7 @@ import (
 	"grimm.is/flywall/internal/brand"
 	"grimm.is/flywall/internal/config"
 	"grimm.is/flywall/internal/device"
+	"grimm.is/flywall/internal/events"
 	"grimm.is/flywall/internal/firewall"
 	"grimm.is/flywall/internal/identity"
 	"grimm.is/flywall/internal/learning"
@@ -39,6 +40,8 @@ import (
 	"grimm.is/flywall/internal/metrics"
 	"grimm.is/flywall/internal/monitor"
 	"grimm.is/flywall/internal/network"
+	"grimm.is/flywall/internal/quota"
+	"grimm.is/flywall/internal/routing"
 	"grimm.is/flywall/internal/scheduler"
 	"grimm.is/flywall/internal/sentinel"
 	"grimm.is/flywall/internal/services"
@@ -47,9 +50,11 @@ import (
 	"grimm.is/flywall/internal/services/dns/querylog"
 	"grimm.is/flywall/internal/services/ha"
 	"grimm.is/flywall/internal/services/lldp"
+	"grimm.is/flywall/internal/services/portal"
 	"grimm.is/flywall/internal/services/scanner"
 	"grimm.is/flywall/internal/state"
 	"grimm.is/flywall/internal/upgrade"
+	"grimm.is/flywall/internal/vpn"
 )
 
 // Server is the privileged control plane RPC server.
@@ -100,12 +105,25 @@ type Server struct {
 	alertEngine         *alerting.Engine
 	queryLogStore       *querylog.Store
 	identityService     *identity.Service
+	quotaManager        *quota.Manager
+	portalService       *portal.Service
 	haService           *ha.Service
+	vpnManager          *vpn.Manager
 	monitorService      *monitor.Service
 
+	// Downstream prefixes carved from DHCPv6-PD leases, overlaid on the
+	// config handed to services (RA, DHCP, firewall) without persisting
+	delegatedPrefixes []network.DelegatedPrefix
+
 	// Notification hub for broadcasting to all consumers
 	notifyHub *NotificationHub
 
+	// Recent conntrack events, collected while being polled
+	conntrackEvents conntrackEventLog
+
+	// Event bus for subsystem events (GeoIP updates, etc.)
+	eventHub *events.Hub
+
 	// Disarm hook to stop monitors (watchdog, auto-restart) in the main process
 	disarmFunc func()
 
@@ -174,6 +192,7 @@ func NewServer(cfg *config.Config, configFile string, netLib network.NetworkMana
 		uplinkManager:       network.NewUplinkManager(),
 		scannerService:      scanner.New(logging.WithComponent("scanner"), scannerCfg),
 		notifyHub:           NewNotificationHub(100),
+		eventHub:            events.NewHub(),
 		scheduler:           scheduler.New(logging.WithComponent("scheduler")),
 	}
 
@@ -214,6 +233,9 @@ func NewServer(cfg *config.Config, configFile string, netLib network.NetworkMana
 		log.Printf("[CTL] Warning: failed to start sni reader: %v", err)
 	}
 
+	// GeoIP update events reach the UI as notifications
+	s.notifyHub.RelayGeoIPEvents(s.eventHub)
+
 	// Start Scheduler
 	s.scheduler.Start()
 
@@ -449,6 +471,37 @@ func (s *Server) Notify(ntype NotificationType, title, message string) {
 	}
 }
 
+// UpdateDelegatedPrefixes is called by the network manager when the
+// prefixes delegated to downstream interfaces change. Services are
+// reloaded against the running config with the new prefixes overlaid.
+func (s *Server) UpdateDelegatedPrefixes(prefixes []network.DelegatedPrefix) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.delegatedPrefixes = prefixes
+	if s.config == nil || s.serviceOrchestrator == nil {
+		return
+	}
+
+	result := s.serviceOrchestrator.ReloadAll(network.ApplyDelegatedPrefixes(s.config, prefixes))
+	if !result.Success {
+		for svc, errMsg := range result.FailedServices {
+			log.Printf("[CTL] Service %s reload after prefix delegation change failed: %s", svc, errMsg)
+		}
+	}
+
+	var list []string
+	for _, dp := range prefixes {
+		list = append(list, fmt.Sprintf("%s on %s", dp.Prefix, dp.Interface))
+	}
+	msg := "No delegated prefixes"
+	if len(list) > 0 {
+		msg = strings.Join(list, ", ")
+	}
+	log.Printf("[CTL] Delegated prefixes updated: %s", msg)
+	s.Notify(NotifyInfo, "IPv6 Prefix Delegation", msg)
+}
+
 func (s *Server) SetDeviceManager(mgr *device.Manager) {
 	s.deviceManager = mgr
 }
@@ -1111,6 +1164,115 @@ func (s *Server) GetAlertHistory(args *GetAlertHistoryArgs, reply *GetAlertHisto
 	return nil
 }
 
+// GetAlertChannelStatus returns per-channel delivery results
+func (s *Server) GetAlertChannelStatus(args *GetAlertChannelStatusArgs, reply *GetAlertChannelStatusReply) error {
+	s.mu.RLock()
+	engine := s.alertEngine
+	s.mu.RUnlock()
+
+	if engine == nil {
+		reply.Error = "alert engine not initialized"
+		return nil
+	}
+	reply.Channels = engine.GetChannelStatus()
+	return nil
+}
+
+// GetAlerts returns alert groups and their acknowledgement state
+func (s *Server) GetAlerts(args *GetAlertsArgs, reply *GetAlertsReply) error {
+	s.mu.RLock()
+	engine := s.alertEngine
+	s.mu.RUnlock()
+
+	if engine == nil {
+		reply.Error = "alert engine not initialized"
+		return nil
+	}
+	reply.Alerts = engine.GetAlerts()
+	return nil
+}
+
+// AckAlert acknowledges an alert group, stopping notifications and escalation
+func (s *Server) AckAlert(args *AlertActionArgs, reply *AlertActionReply) error {
+	s.mu.RLock()
+	engine := s.alertEngine
+	s.mu.RUnlock()
+
+	if engine == nil {
+		reply.Error = "alert engine not initialized"
+		return nil
+	}
+	if err := engine.Acknowledge(args.ID, args.Actor); err != nil {
+		reply.Error = err.Error()
+	}
+	return nil
+}
+
+// ResolveAlert closes an alert group
+func (s *Server) ResolveAlert(args *AlertActionArgs, reply *AlertActionReply) error {
+	s.mu.RLock()
+	engine := s.alertEngine
+	s.mu.RUnlock()
+
+	if engine == nil {
+		reply.Error = "alert engine not initialized"
+		return nil
+	}
+	if err := engine.Resolve(args.ID, args.Actor); err != nil {
+		reply.Error = err.Error()
+	}
+	return nil
+}
+
+// GetSilences returns active and scheduled alert silences
+func (s *Server) GetSilences(args *GetSilencesArgs, reply *GetSilencesReply) error {
+	s.mu.RLock()
+	engine := s.alertEngine
+	s.mu.RUnlock()
+
+	if engine == nil {
+		reply.Error = "alert engine not initialized"
+		return nil
+	}
+	reply.Silences = engine.GetSilences()
+	return nil
+}
+
+// CreateSilence adds a time-bounded alert silence
+func (s *Server) CreateSilence(args *CreateSilenceArgs, reply *CreateSilenceReply) error {
+	s.mu.RLock()
+	engine := s.alertEngine
+	s.mu.RUnlock()
+
+	if engine == nil {
+		reply.Error = "alert engine not initialized"
+		return nil
+	}
+	silence, err := engine.AddSilence(args.Silence)
+	if err != nil {
+		reply.Error = err.Error()
+		return nil
+	}
+	reply.Silence = silence
+	return nil
+}
+
+// DeleteSilence removes an alert silence
+func (s *Server) DeleteSilence(args *DeleteSilenceArgs, reply *DeleteSilenceReply) error {
+	s.mu.RLock()
+	engine := s.alertEngine
+	s.mu.RUnlock()
+
+	if engine == nil {
+		reply.Error = "alert engine not initialized"
+		return nil
+	}
+	if err := engine.DeleteSilence(args.ID); err != nil {
+		reply.Error = err.Error()
+	}
+	return nil
+}
+
 func (s *Server) GetAlertRules(args *GetAlertRulesArgs, reply *GetAlertRulesReply) error {
 	if s.config.Notifications == nil {
 		reply.Rules = []alerting.AlertRule{}
@@ -1410,6 +1572,14 @@ func (s *Server) reloadConfigInternal(newCfg *config.Config) error {
 		criticalErrors = append(criticalErrors, fmt.Sprintf("policy routing: %v", err))
 	}
 
+	// Apply dynamic routing (FRR); unchanged configs are left alone
+	if newCfg.FRR != nil {
+		if err := routing.ConfigureFRR(newCfg.FRR); err != nil {
+			log.Printf("[CTL] Error applying FRR config: %v", err)
+			s.Notify(NotifyWarning, "Dynamic Routing Error", fmt.Sprintf("Failed to apply: %v", err))
+		}
+	}
+
 	// Apply Multi-WAN Policy Rules (if enabled)
 	if newCfg.MultiWAN != nil && newCfg.MultiWAN.Enabled {
 		var wanConfigs []network.WANConfig
@@ -1511,7 +1681,7 @@ func (s *Server) reloadConfigInternal(newCfg *config.Config) error {
 	}
 
 	// 3. Apply Config to all services - CRITICAL (includes firewall)
-	result := s.serviceOrchestrator.ReloadAll(newCfg)
+	result := s.serviceOrchestrator.ReloadAll(network.ApplyDelegatedPrefixes(newCfg, s.delegatedPrefixes))
 	if !result.Success {
 		for svc, errMsg := range result.FailedServices {
 			log.Printf("[CTL] Service %s reload failed: %s", svc, errMsg)
@@ -1546,6 +1716,23 @@ func (s *Server) reloadConfigInternal(newCfg *config.Config) error {
 	// 7. Sync Monitors (non-critical)
 	s.syncMonitors(newCfg)
 
+	// 8. Re-evaluate device quotas; the firewall reload emptied their sets
+	if s.quotaManager != nil {
+		s.quotaManager.UpdateConfig(newCfg)
+	}
+
+	// 9. Apply WireGuard peer changes (provisioned, rotated, expired peers)
+	if s.vpnManager != nil {
+		s.vpnManager.Reload(newCfg.VPN)
+	}
+
+	// 10. Reconfigure the captive portal and refill its authorized set
+	if s.portalService != nil {
+		if _, err := s.portalService.Reload(newCfg); err != nil {
+			log.Printf("[CTL] Warning: Failed to reload captive portal: %v", err)
+		}
+	}
+
 	// Return aggregated critical errors
 	if len(criticalErrors) > 0 {
 		log.Printf("[CTL] Configuration applied with critical errors: %v", criticalErrors)
@@ -2366,6 +2553,18 @@ func (s *Server) startScheduler() error {
 		RefreshDNS: func() error {
 			return s.refreshDNSBlocklists()
 		},
+		ReloadGeoIP: func() error {
+			if s.firewallManager == nil {
+				return nil
+			}
+			// Nil until a policy references a country; the next apply
+			// then opens the swapped-in file directly.
+			if geo := s.firewallManager.GeoIP(); geo != nil {
+				return geo.Reload()
+			}
+			return nil
+		},
+		Events: s.eventHub,
 	}
 
 	// Register IPSet update task
@@ -2384,6 +2583,17 @@ func (s *Server) startScheduler() error {
 		log.Printf("[CTL] Registered DNS blocklist update task (interval: %v)", interval)
 	}
 
+	// Register GeoIP database update task
+	if geoCfg := s.config.GeoIP; geoCfg != nil && geoCfg.AutoUpdate {
+		livePath := geoCfg.DatabasePath
+		if livePath == "" {
+			livePath = firewall.DefaultGeoIPDatabasePath()
+		}
+		task := scheduler.NewGeoIPUpdateTask(registry, geoCfg, livePath)
+		s.scheduler.AddTask(task)
+		log.Printf("[CTL] Registered GeoIP update task (database: %s)", livePath)
+	}
+
 	// Register backup task if enabled
 	if s.config.Scheduler.BackupEnabled {
 		// Parse cron schedule or use default (2:00 AM daily)
@@ -3124,6 +3334,21 @@ func (s *Server) SetIdentityService(svc *identity.Service) {
 	s.identityService = svc
 }
 
+// SetQuotaManager injects the device quota manager
+func (s *Server) SetQuotaManager(m *quota.Manager) {
+	s.quotaManager = m
+}
+
+// SetPortalService injects the captive portal service
+func (s *Server) SetPortalService(svc *portal.Service) {
+	s.portalService = svc
+}
+
+// SetVPNManager injects the VPN manager
+func (s *Server) SetVPNManager(m *vpn.Manager) {
+	s.vpnManager = m
+}
+
 // SetHAService injects the HA service
 func (s *Server) SetHAService(svc *ha.Service) {
 	s.haService = svc
@@ -3158,6 +3383,11 @@ func (s *Server) GetReplicationStatus(args *Empty, reply *GetReplicationStatusRe
 	if s.haService != nil {
 		reply.Status.HAEnabled = true
 		reply.Status.HARole = string(s.haService.GetRole())
+		reply.Status.VRRP = s.haService.VRRPStatus()
+		reply.Status.HAMode = s.haService.Mode()
+		peer := s.haService.GetPeerState()
+		reply.Status.Peer = &peer
+		reply.Status.Ownership = s.haService.Ownership()
 	}
 
 	return nil
@@ -6,6 +6,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/netip"
 	"os"
 	"testing"
 
@@ -225,6 +226,35 @@ func (m *MockServiceManager) ReloadAll(cfg *config.Config) *ReloadResult {
 	return &ReloadResult{Success: true}
 }
 
+func TestUpdateDelegatedPrefixes_OverlaysServiceConfig(t *testing.T) {
+	cfg := &config.Config{
+		Interfaces: []config.Interface{
+			{Name: "lan0", Zone: "lan", RA: true},
+		},
+	}
+	s := NewServer(cfg, "/tmp/test-config.hcl", &MockNetLib{})
+	mockOrch := &MockServiceManager{}
+	s.serviceOrchestrator = mockOrch
+
+	s.UpdateDelegatedPrefixes([]network.DelegatedPrefix{{
+		Interface: "lan0",
+		Upstream:  "eth0",
+		Prefix:    netip.MustParsePrefix("2001:db8:aa00:1::/64"),
+		Address:   netip.MustParsePrefix("2001:db8:aa00:1::1/64"),
+	}})
+
+	if !mockOrch.ReloadAllCalled {
+		t.Fatal("Expected services to be reloaded")
+	}
+	got := mockOrch.ReloadAllConfig.Interfaces[0].IPv6
+	if len(got) != 1 || got[0] != "2001:db8:aa00:1::1/64" {
+		t.Errorf("Expected delegated address on lan0, got %v", got)
+	}
+	if len(cfg.Interfaces[0].IPv6) != 0 {
+		t.Error("Running config should not be modified")
+	}
+}
+
 func TestRestoreBackup_AppliesConfig(t *testing.T) {
 	// 1. Setup Environment
 	tmpFile, err := os.CreateTemp("", "config-restore-*.hcl")
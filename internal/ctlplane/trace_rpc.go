@@ -0,0 +1,72 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package ctlplane
+
+import (
+	"fmt"
+	"strings"
+
+	"grimm.is/flywall/internal/learning"
+	"grimm.is/flywall/internal/learning/flowdb"
+	"grimm.is/flywall/internal/trace"
+)
+
+// TracePacket walks a packet through the live nftables ruleset, the kernel
+// routing tables and the learning engine, reporting every chain and rule hit.
+func (s *Server) TracePacket(args *TracePacketArgs, reply *TracePacketReply) error {
+	s.mu.RLock()
+	cfg := s.config
+	engine := s.learningEngine
+	s.mu.RUnlock()
+
+	rs, err := trace.LoadKernelRuleset()
+	if err != nil {
+		reply.Error = err.Error()
+		return nil
+	}
+
+	pkt := args.Packet
+	if pkt.SrcMAC == "" && pkt.InInterface != "" {
+		pkt.SrcMAC = trace.NeighborMAC(pkt.Src)
+	}
+
+	tracer := &trace.Tracer{Ruleset: rs, Router: trace.KernelRouter{}}
+	if cfg != nil {
+		tracer.Zones = trace.ConfigZones(cfg)
+	}
+	if engine != nil {
+		tracer.Learning = traceLearning(engine)
+	}
+
+	result, err := tracer.Trace(pkt)
+	if err != nil {
+		reply.Error = err.Error()
+		return nil
+	}
+	reply.Result = result
+	return nil
+}
+
+// traceLearning reports what the inline learning engine would decide for a
+// queued packet, without recording it as a flow.
+func traceLearning(engine *learning.Engine) trace.LearningFunc {
+	return func(pkt trace.Packet) (string, string) {
+		if pkt.SrcMAC == "" {
+			return "accept", "source MAC unknown; flow not looked up"
+		}
+		flow, err := engine.LookupFlow(pkt.SrcMAC, strings.ToUpper(pkt.Protocol), pkt.DstPort)
+		if err != nil {
+			return "accept", fmt.Sprintf("flow lookup failed (%v); the queue fails open", err)
+		}
+		if flow == nil {
+			return "accept", "new flow, recorded as pending and accepted while inspected"
+		}
+		switch flow.State {
+		case flowdb.StateDenied:
+			return "drop", fmt.Sprintf("flow %d is denied", flow.ID)
+		case flowdb.StateAllowed:
+			return "accept", fmt.Sprintf("flow %d is allowed", flow.ID)
+		}
+		return "accept", fmt.Sprintf("flow %d is %s, accepted while inspected", flow.ID, flow.State)
+	}
+}
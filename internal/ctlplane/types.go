@@ -70,8 +70,14 @@ import (
 	"grimm.is/flywall/internal/learning"
 	"grimm.is/flywall/internal/learning/flowdb"
 	"grimm.is/flywall/internal/metrics"
+	"grimm.is/flywall/internal/quota"
+	"grimm.is/flywall/internal/routing"
 	"grimm.is/flywall/internal/services/dns/querylog"
+	"grimm.is/flywall/internal/services/ha"
+	"grimm.is/flywall/internal/services/portal"
 	"grimm.is/flywall/internal/services/scanner"
+	"grimm.is/flywall/internal/trace"
+	"grimm.is/flywall/internal/vpn"
 )
 
 // GetSocketPath returns the path to the control plane socket.
@@ -1176,6 +1182,159 @@ type PingReply struct {
 	Error     string `json:"error,omitempty"`
 }
 
+// --- Packet Trace ---
+
+// TracePacketArgs is the request for TracePacket
+type TracePacketArgs struct {
+	Packet trace.Packet `json:"packet"`
+}
+
+// TracePacketReply is the response for TracePacket
+type TracePacketReply struct {
+	Result *trace.Result `json:"result,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+// --- Dynamic Routing ---
+
+// GetRoutingStatusReply is the response for GetRoutingStatus
+type GetRoutingStatusReply struct {
+	Status *routing.Status `json:"status,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// --- Device Quotas ---
+
+// GetQuotaStatusReply is the response for GetQuotaStatus
+type GetQuotaStatusReply struct {
+	Status []quota.Status `json:"status"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// ResetQuotaArgs selects the counters to reset. An empty Subject resets
+// every device and group counter of the quota.
+type ResetQuotaArgs struct {
+	Quota   string `json:"quota"`
+	Subject string `json:"subject,omitempty"`
+}
+
+// ResetQuotaReply is the response for ResetQuota
+type ResetQuotaReply struct {
+	Error string `json:"error,omitempty"`
+}
+
+// --- WireGuard Provisioning ---
+
+// ProvisionWireGuardPeerArgs asks for a new road-warrior peer. With Apply
+// the staged config, including any other pending changes, is applied too.
+type ProvisionWireGuardPeerArgs struct {
+	Connection string `json:"connection,omitempty"`
+	Name       string `json:"name"`
+	Expires    string `json:"expires,omitempty"`
+	Apply      bool   `json:"-"`
+}
+
+// RotateWireGuardPeerArgs selects the peer whose keys are replaced
+type RotateWireGuardPeerArgs struct {
+	Connection string `json:"connection,omitempty"`
+	Name       string `json:"name"`
+	Apply      bool   `json:"-"`
+}
+
+// WireGuardClientReply carries a provisioned client's configuration.
+// ApplyError is set when the peer was staged but applying failed.
+type WireGuardClientReply struct {
+	Profile    vpn.ClientProfile `json:"profile"`
+	ApplyError string            `json:"apply_error,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// --- Captive Portal ---
+
+// GetPortalSessionsReply is the response for GetPortalSessions
+type GetPortalSessionsReply struct {
+	Sessions []portal.Session `json:"sessions"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// RevokePortalSessionArgs selects the client to log out
+type RevokePortalSessionArgs struct {
+	MAC string `json:"mac"`
+}
+
+// GetPortalVouchersReply is the response for GetPortalVouchers
+type GetPortalVouchersReply struct {
+	Vouchers []portal.Voucher `json:"vouchers"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// CreatePortalVouchersArgs asks for Count vouchers granting Duration (e.g. "24h") each
+type CreatePortalVouchersArgs struct {
+	Count    int    `json:"count"`
+	Duration string `json:"duration"`
+	Note     string `json:"note,omitempty"`
+}
+
+// DeletePortalVoucherArgs selects the voucher to withdraw
+type DeletePortalVoucherArgs struct {
+	Code string `json:"code"`
+}
+
+// PortalReply is the response for captive portal changes
+type PortalReply struct {
+	Error string `json:"error,omitempty"`
+}
+
+// --- Connection Tracking ---
+
+// GetConntrackArgs selects connection tracking entries. A Limit of 0
+// returns all of them.
+type GetConntrackArgs struct {
+	Filter firewall.ConntrackFilter `json:"filter"`
+	Limit  int                      `json:"limit,omitempty"`
+	Offset int                      `json:"offset,omitempty"`
+}
+
+// GetConntrackReply is the response for GetConntrack. Total counts the
+// matching entries before paging.
+type GetConntrackReply struct {
+	Entries []firewall.ConntrackEntry `json:"entries"`
+	Total   int                       `json:"total"`
+	Error   string                    `json:"error,omitempty"`
+}
+
+// KillConntrackArgs selects the entries to delete; an empty filter is refused
+type KillConntrackArgs struct {
+	Filter firewall.ConntrackFilter `json:"filter"`
+}
+
+// KillConntrackReply is the response for KillConntrack
+type KillConntrackReply struct {
+	Deleted int    `json:"deleted"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ConntrackEventRecord is a conntrack event with its position in the event log
+type ConntrackEventRecord struct {
+	ID    int64                   `json:"id"`
+	Time  time.Time               `json:"time"`
+	Type  string                  `json:"type"` // new or destroy
+	Entry firewall.ConntrackEntry `json:"entry"`
+}
+
+// GetConntrackEventsArgs asks for the events after SinceID that match Filter
+type GetConntrackEventsArgs struct {
+	SinceID int64                    `json:"since_id"`
+	Filter  firewall.ConntrackFilter `json:"filter"`
+}
+
+// GetConntrackEventsReply is the response for GetConntrackEvents
+type GetConntrackEventsReply struct {
+	Events []ConntrackEventRecord `json:"events"`
+	LastID int64                  `json:"last_id"`
+	Error  string                 `json:"error,omitempty"`
+}
+
 // (Device Identity types moved to end of file)
 
 // --- Network Device Discovery ---
@@ -1250,6 +1409,18 @@ type ReplicationStatus struct {
 	LastSyncTime time.Time `json:"last_sync_time"`
 	Version      int64     `json:"version"`
 	Error        string    `json:"error,omitempty"`
+
+	// VRRP lists the virtual routers when HA runs the VRRP protocol
+	VRRP []ha.VRRPStatus `json:"vrrp,omitempty"`
+
+	// HAMode is "active_passive" or "active_active"
+	HAMode string `json:"ha_mode,omitempty"`
+
+	// Peer is the HA peer as seen by this node
+	Peer *ha.PeerState `json:"peer,omitempty"`
+
+	// Ownership lists who owns and holds each virtual IP (active/active)
+	Ownership []ha.Ownership `json:"ownership,omitempty"`
 }
 
 // GetReplicationStatusReply is the response for GetReplicationStatus
@@ -1326,6 +1497,54 @@ type UpdateAlertRuleReply struct {
 	Error string `json:"error,omitempty"`
 }
 
+type GetAlertChannelStatusArgs struct{}
+
+type GetAlertChannelStatusReply struct {
+	Channels []alerting.ChannelStatus `json:"channels"`
+	Error    string                   `json:"error,omitempty"`
+}
+
+type GetAlertsArgs struct{}
+
+type GetAlertsReply struct {
+	Alerts []alerting.Alert `json:"alerts"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// AlertActionArgs acknowledges or resolves an alert group
+type AlertActionArgs struct {
+	ID    string `json:"id"`
+	Actor string `json:"actor"`
+}
+
+type AlertActionReply struct {
+	Error string `json:"error,omitempty"`
+}
+
+type GetSilencesArgs struct{}
+
+type GetSilencesReply struct {
+	Silences []alerting.Silence `json:"silences"`
+	Error    string             `json:"error,omitempty"`
+}
+
+type CreateSilenceArgs struct {
+	Silence alerting.Silence `json:"silence"`
+}
+
+type CreateSilenceReply struct {
+	Silence *alerting.Silence `json:"silence,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+type DeleteSilenceArgs struct {
+	ID string `json:"id"`
+}
+
+type DeleteSilenceReply struct {
+	Error string `json:"error,omitempty"`
+}
+
 // --- Device Identity & Grouping ---
 
 // UpdateDeviceIdentityArgs is the request for UpdateDeviceIdentity
@@ -0,0 +1,65 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package ctlplane
+
+import (
+	"log"
+
+	"grimm.is/flywall/internal/clock"
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/vpn"
+)
+
+// ProvisionWireGuardPeer adds a road-warrior peer to the staged config and
+// returns its client configuration. Provisioning runs here rather than in
+// the API because the server's private key is masked outside the control
+// plane.
+func (s *Server) ProvisionWireGuardPeer(args *ProvisionWireGuardPeerArgs, reply *WireGuardClientReply) error {
+	req := vpn.ProvisionRequest{Connection: args.Connection, Name: args.Name, Expires: args.Expires}
+	err := s.cm.Stage(func(cfg *config.Config) error {
+		profile, err := vpn.ProvisionPeer(cfg, req, clock.Now())
+		if err != nil {
+			return err
+		}
+		reply.Profile = *profile
+		return nil
+	})
+	if err != nil {
+		reply.Error = err.Error()
+		return nil
+	}
+	log.Printf("[CTL] Provisioned WireGuard peer %s on %s", args.Name, reply.Profile.Connection)
+	s.applyWireGuardChange(args.Apply, reply)
+	return nil
+}
+
+// RotateWireGuardPeer replaces a peer's keys in the staged config and returns
+// the new client configuration.
+func (s *Server) RotateWireGuardPeer(args *RotateWireGuardPeerArgs, reply *WireGuardClientReply) error {
+	err := s.cm.Stage(func(cfg *config.Config) error {
+		profile, err := vpn.RotatePeer(cfg, args.Connection, args.Name, clock.Now())
+		if err != nil {
+			return err
+		}
+		reply.Profile = *profile
+		return nil
+	})
+	if err != nil {
+		reply.Error = err.Error()
+		return nil
+	}
+	log.Printf("[CTL] Rotated keys of WireGuard peer %s on %s", args.Name, reply.Profile.Connection)
+	s.applyWireGuardChange(args.Apply, reply)
+	return nil
+}
+
+// applyWireGuardChange applies the staged config when requested. The peer
+// stays staged if that fails.
+func (s *Server) applyWireGuardChange(apply bool, reply *WireGuardClientReply) {
+	if !apply {
+		return
+	}
+	if err := s.cm.Apply(); err != nil {
+		reply.ApplyError = err.Error()
+	}
+}
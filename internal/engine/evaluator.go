@@ -5,6 +5,7 @@ package engine
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"grimm.is/flywall/internal/config"
 )
@@ -24,6 +25,10 @@ type RuleEngine struct {
 	// Fast lookup maps (optional optimization, unnecessary for small simulations)
 	// For simulator, we need to map Interfaces -> Zones to know which Policy chain to start.
 	InterfaceToZone map[string]string
+
+	// Per-rule numgen counters for round-robin DNAT simulation
+	natMu       sync.Mutex
+	natCounters map[string]uint32
 }
 
 // NewRuleEngine creates a new evaluator
@@ -52,8 +57,17 @@ func (e *RuleEngine) buildLookups() {
 // Evaluate determines the fate of a packet
 // Returns verdict and the RuleID (or PolicyName if default action) that decided it
 func (e *RuleEngine) Evaluate(pkt Packet) (Verdict, string) {
+	verdict, ruleID, _ := e.EvaluateConn(pkt)
+	return verdict, ruleID
+}
+
+// EvaluateConn is Evaluate for the first packet of a connection. It also
+// returns the conntrack helper the ruleset would attach to the accepted
+// connection, or "" for none. As in the ruleset, a policy's helpers apply
+// on their standard ports, ahead of the helper of the accepting rule.
+func (e *RuleEngine) EvaluateConn(pkt Packet) (Verdict, string, string) {
 	if e.Config == nil {
-		return VerdictAccept, "no-config" // Open by default if no config? Or Drop? SafeMode=Open usually
+		return VerdictAccept, "no-config", "" // Open by default if no config? Or Drop? SafeMode=Open usually
 	}
 
 	// 1. Identify Source Zone
@@ -64,9 +78,12 @@ func (e *RuleEngine) Evaluate(pkt Packet) (Verdict, string) {
 		// For simulation, if InInterface is empty (e.g. locally generated?), maybe "mgmt"?
 		// Let's assume assume "wan" if unknown or handle as strict drop.
 		// Actually, default policy is implicit drop usually.
-		return VerdictDrop, "unknown-zone"
+		return VerdictDrop, "unknown-zone", ""
 	}
 
+	// DNAT happens in prerouting, before the filter rules see the packet
+	pkt, _ = e.TranslateDNAT(pkt)
+
 	// 2. Identify Destination Zone
 	// This is TRICKY in simulation without a routing table.
 	// We know DstIP but not which Interface/Zone it belongs to.
@@ -115,10 +132,17 @@ func (e *RuleEngine) Evaluate(pkt Packet) (Verdict, string) {
 		// For MVP: We just iterate all rules in all matching source-policies.
 		// (This is inexact but better than nothing).
 
+		helper := policyHelper(policy, pkt)
 		effectiveRules := policy.GetEffectiveRules(e.Config.Policies)
 		for _, rule := range effectiveRules {
 			if Match(rule, pkt) {
-				return Verdict(strings.ToLower(rule.Action)), fmt.Sprintf("rule:%s:%s", policy.Name, rule.Name)
+				verdict := Verdict(strings.ToLower(rule.Action))
+				if verdict != VerdictAccept {
+					helper = ""
+				} else if helper == "" {
+					helper = rule.Helper
+				}
+				return verdict, fmt.Sprintf("rule:%s:%s", policy.Name, rule.Name), helper
 			}
 		}
 
@@ -130,5 +154,17 @@ func (e *RuleEngine) Evaluate(pkt Packet) (Verdict, string) {
 		// Usually we fallback to global drop.
 	}
 
-	return VerdictDrop, "default-drop"
+	return VerdictDrop, "default-drop", ""
+}
+
+// policyHelper returns the policy helper assigned to a connection on the
+// helper's standard port.
+func policyHelper(policy config.Policy, pkt Packet) string {
+	for _, name := range policy.Helpers {
+		h, ok := config.ConntrackHelpers[name]
+		if ok && MatchProtocol(h.Protocol, pkt.Protocol) && pkt.DstPort == h.Port {
+			return name
+		}
+	}
+	return ""
 }
@@ -0,0 +1,50 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package engine
+
+import (
+	"testing"
+
+	"grimm.is/flywall/internal/config"
+)
+
+func TestEvaluateConnHelpers(t *testing.T) {
+	cfg := &config.Config{
+		Zones: []config.Zone{
+			{Name: "lan", Matches: []config.RuleMatch{{Interface: "eth1"}}},
+			{Name: "wan", Matches: []config.RuleMatch{{Interface: "eth0"}}},
+		},
+		Policies: []config.Policy{
+			{Name: "lan-wan", From: "lan", To: "wan", Helpers: []string{"ftp"}, Rules: []config.PolicyRule{
+				{Name: "ftp-alt", Protocol: "tcp", DestPort: 2121, Action: "accept", Helper: "ftp"},
+				{Name: "no-sip", Protocol: "udp", DestPort: 5060, Action: "drop"},
+				{Name: "all", Action: "accept"},
+			}},
+		},
+	}
+	e := NewRuleEngine(cfg)
+
+	tests := []struct {
+		name        string
+		proto       string
+		port        int
+		wantVerdict Verdict
+		wantHelper  string
+	}{
+		{"policy helper on the standard port", "tcp", 21, VerdictAccept, "ftp"},
+		{"rule helper on another port", "tcp", 2121, VerdictAccept, "ftp"},
+		{"no helper unless assigned", "udp", 69, VerdictAccept, ""},
+		{"dropped connections get none", "udp", 5060, VerdictDrop, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict, _, helper := e.EvaluateConn(Packet{
+				SrcIP: "192.168.1.10", DstIP: "198.51.100.1", SrcPort: 40000, DstPort: tt.port,
+				Protocol: tt.proto, InInterface: "eth1",
+			})
+			if verdict != tt.wantVerdict || helper != tt.wantHelper {
+				t.Errorf("EvaluateConn() = %s, %q; want %s, %q", verdict, helper, tt.wantVerdict, tt.wantHelper)
+			}
+		})
+	}
+}
@@ -105,8 +105,10 @@ func MatchIP(ruleIP, pktIP string) bool {
 	return ruleIP == pktIP
 }
 
-// MatchPort checks if a packet port matches rule port(s)
-func MatchPort(single int, multiple []int, packetPort int) bool {
+// MatchPort checks if a packet port matches rule port(s).
+// Entries in multiple may be single ports, ranges ("6881-6999") or
+// comma-separated sets; malformed entries never match.
+func MatchPort(single int, multiple []config.PortSpec, packetPort int) bool {
 	// If no ports specified, match all
 	if single == 0 && len(multiple) == 0 {
 		return true
@@ -118,9 +120,16 @@ func MatchPort(single int, multiple []int, packetPort int) bool {
 	}
 
 	// Check multiple
-	for _, p := range multiple {
-		if p == packetPort {
-			return true
+	for _, spec := range multiple {
+		ranges, err := spec.Ranges()
+		if err != nil {
+			log.Printf("Invalid rule port spec %q: %v", spec, err)
+			continue
+		}
+		for _, r := range ranges {
+			if r.Contains(packetPort) {
+				return true
+			}
 		}
 	}
 
@@ -0,0 +1,186 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package engine
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+
+	"grimm.is/flywall/internal/config"
+)
+
+// TranslateDNAT applies the first matching prerouting NAT rule (dnat or
+// netmap) to the packet, mirroring the rules generated by the firewall
+// script builder. It returns the translated packet and the name of the
+// rule that matched, or the original packet and "" if none did.
+func (e *RuleEngine) TranslateDNAT(pkt Packet) (Packet, string) {
+	if e.Config == nil {
+		return pkt, ""
+	}
+
+	for _, r := range e.Config.NAT {
+		if !e.matchNAT(r, pkt) {
+			continue
+		}
+
+		switch r.Type {
+		case "netmap":
+			if ip := netmapAddr(pkt.DstIP, r.DestIP, r.ToIP); ip != "" {
+				pkt.DstIP = ip
+				return pkt, r.Name
+			}
+		case "dnat":
+			backends := r.Backends()
+			if len(backends) == 0 && r.ToPort == "" {
+				continue
+			}
+			if len(backends) > 0 {
+				pkt.DstIP = backends[e.selectBackend(r, pkt, len(backends))]
+			}
+			pkt.DstPort = dnatPort(r, pkt.DstPort)
+			return pkt, r.Name
+		}
+	}
+
+	return pkt, ""
+}
+
+// matchNAT checks a NAT rule's match criteria against a packet.
+func (e *RuleEngine) matchNAT(r config.NATRule, pkt Packet) bool {
+	// Packets carry no mark in simulation
+	if r.Mark != 0 {
+		return false
+	}
+	if r.InInterface != "" && r.InInterface != pkt.InInterface && r.InInterface != e.InterfaceToZone[pkt.InInterface] {
+		return false
+	}
+	if r.SrcIP != "" && !MatchIP(r.SrcIP, pkt.SrcIP) {
+		return false
+	}
+	if r.DestIP != "" && !MatchIP(r.DestIP, pkt.DstIP) {
+		return false
+	}
+
+	proto := r.Protocol
+	if proto == "any" {
+		proto = ""
+	}
+	if r.DestPort != "" {
+		// The script builder defaults dport matches to tcp
+		if proto == "" {
+			proto = "tcp"
+		}
+		ranges, err := config.PortSpec(r.DestPort).Ranges()
+		if err != nil {
+			return false
+		}
+		matched := false
+		for _, pr := range ranges {
+			if pr.Contains(pkt.DstPort) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return MatchProtocol(proto, pkt.Protocol)
+}
+
+// selectBackend picks a backend index the way the kernel does: numgen inc
+// keeps a per-rule counter, jhash hashes the source address with the
+// fixed seed.
+func (e *RuleEngine) selectBackend(r config.NATRule, pkt Packet, n int) int {
+	if n == 1 {
+		return 0
+	}
+	if r.LoadBalance == config.NATBalanceHash {
+		ip := net.ParseIP(pkt.SrcIP).To4()
+		if ip == nil {
+			return 0
+		}
+		return int(ReciprocalScale(JHash(ip, config.NATHashSeed), uint32(n)))
+	}
+
+	e.natMu.Lock()
+	defer e.natMu.Unlock()
+	if e.natCounters == nil {
+		e.natCounters = make(map[string]uint32)
+	}
+	idx := e.natCounters[r.Name] % uint32(n)
+	e.natCounters[r.Name]++
+	return int(idx)
+}
+
+// dnatPort returns the translated destination port.
+func dnatPort(r config.NATRule, port int) int {
+	if from, to, ok := r.PortRemap(); ok {
+		return to.Start + port - from.Start
+	}
+	if r.ToPort == "" {
+		return port
+	}
+	p, err := strconv.Atoi(strings.TrimSpace(r.ToPort))
+	if err != nil {
+		return port
+	}
+	return p
+}
+
+// netmapAddr maps ip from the external prefix onto the internal prefix,
+// keeping the host bits. Returns "" if ip is outside the external prefix.
+func netmapAddr(ip, external, internal string) string {
+	addr := net.ParseIP(ip).To4()
+	_, ext, err := net.ParseCIDR(external)
+	if err != nil || addr == nil || !ext.Contains(addr) {
+		return ""
+	}
+	_, in, err := net.ParseCIDR(internal)
+	if err != nil || in.IP.To4() == nil {
+		return ""
+	}
+
+	mapped := make(net.IP, net.IPv4len)
+	for i := range mapped {
+		mapped[i] = in.IP.To4()[i]&in.Mask[i] | addr[i]&^in.Mask[i]
+	}
+	return mapped.String()
+}
+
+// JHash is the kernel's jhash() for a 4-byte key, as used by nft_jhash
+// on an IPv4 address.
+func JHash(key []byte, seed uint32) uint32 {
+	a := uint32(0xdeadbeef) + uint32(len(key)) + seed
+	b, c := a, a
+
+	a += binary.LittleEndian.Uint32(key)
+
+	// __jhash_final
+	c ^= b
+	c -= rol32(b, 14)
+	a ^= c
+	a -= rol32(c, 11)
+	b ^= a
+	b -= rol32(a, 25)
+	c ^= b
+	c -= rol32(b, 16)
+	a ^= c
+	a -= rol32(c, 4)
+	b ^= a
+	b -= rol32(a, 14)
+	c ^= b
+	c -= rol32(b, 24)
+	return c
+}
+
+func rol32(v uint32, shift uint) uint32 {
+	return v<<shift | v>>(32-shift)
+}
+
+// ReciprocalScale maps val into [0, n) as the kernel's reciprocal_scale.
+func ReciprocalScale(val, n uint32) uint32 {
+	return uint32((uint64(val) * uint64(n)) >> 32)
+}
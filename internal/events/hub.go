@@ -168,3 +168,12 @@ func (h *Hub) EmitDeviceSeen(mac, ip, hostname, vendor, iface, method string) {
 		},
 	})
 }
+
+// EmitGeoIPUpdate publishes a GeoIP database swap or rollback.
+func (h *Hub) EmitGeoIPUpdate(eventType EventType, data GeoIPUpdateData) {
+	h.Publish(Event{
+		Type:   eventType,
+		Source: "geoip",
+		Data:   data,
+	})
+}
@@ -32,6 +32,10 @@ const (
 	EventFlowNew      EventType = "flow.new"
 	EventFlowApproved EventType = "flow.approved"
 	EventFlowBlocked  EventType = "flow.blocked"
+
+	// GeoIP database events
+	EventGeoIPUpdated  EventType = "geoip.updated"
+	EventGeoIPRollback EventType = "geoip.rollback"
 )
 
 // Event is the core message passed through the event bus.
@@ -103,3 +107,12 @@ type FlowData struct {
 	SNI       string `json:"sni,omitempty"`       // TLS Server Name
 	Encrypted bool   `json:"encrypted,omitempty"` // Was TLS?
 }
+
+// GeoIPUpdateData is the payload for EventGeoIPUpdated/EventGeoIPRollback.
+type GeoIPUpdateData struct {
+	Path     string `json:"path"`               // Live database path
+	Source   string `json:"source"`             // URL or drop-directory file
+	SHA256   string `json:"sha256"`             // Checksum of the database now live
+	Previous string `json:"previous,omitempty"` // Path of the retained rollback copy
+	Error    string `json:"error,omitempty"`    // Reason for a rollback
+}
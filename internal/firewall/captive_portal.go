@@ -0,0 +1,120 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package firewall
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"grimm.is/flywall/internal/config"
+)
+
+// PortalAuthSet holds the MACs of clients logged in to the captive
+// portal. It is filled at runtime by the portal service; each element
+// carries its session's remaining time, so the kernel expires it.
+const PortalAuthSet = "portal_auth"
+
+// PortalAuthElement formats a set element for a client whose session
+// ends after ttl.
+func PortalAuthElement(mac string, ttl time.Duration) string {
+	secs := int64(ttl / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	return fmt.Sprintf("%s timeout %ds", mac, secs)
+}
+
+// portalInterfaces returns the interfaces of zones with the captive
+// portal service, as an anonymous nft set.
+func portalInterfaces(cfg *Config) string {
+	if cfg.CaptivePortal == nil {
+		return ""
+	}
+	zoneMap := buildZoneMapForScript(cfg)
+	var ifaces []string
+	seen := make(map[string]bool)
+	for _, zone := range cfg.Zones {
+		if zone.Services == nil || !zone.Services.CaptivePortal {
+			continue
+		}
+		for _, iface := range zoneMap[canonicalZoneName(zone.Name)] {
+			if !seen[iface] {
+				seen[iface] = true
+				ifaces = append(ifaces, forceQuote(iface))
+			}
+		}
+	}
+	if len(ifaces) == 0 {
+		return ""
+	}
+	return "{ " + strings.Join(ifaces, ", ") + " }"
+}
+
+// addCaptivePortalRules declares the authorized set and redirects web and
+// DNS traffic of clients not in it to the portal and the local resolver.
+// Redirection lives in this table rather than the ip nat table so it can
+// use the set and covers IPv6 too.
+func addCaptivePortalRules(sb *ScriptBuilder, cfg *Config) {
+	ifaces := portalInterfaces(cfg)
+	if ifaces == "" {
+		return
+	}
+
+	sb.AddSet(PortalAuthSet, "ether_addr", "[portal] Authorized clients", 0, "timeout")
+
+	// Before the nat table's DNAT (-100) so port forwards can't bypass it
+	sb.AddChain("portal_prerouting", "nat", "prerouting", -110, "accept", "[portal] Unauthenticated redirect")
+	unauth := fmt.Sprintf("iifname %s ether saddr != @%s", ifaces, PortalAuthSet)
+	sb.AddRule("portal_prerouting", fmt.Sprintf("%s tcp dport 80 redirect to :%d", unauth, cfg.CaptivePortal.Port), "[portal] HTTP to portal")
+	sb.AddRule("portal_prerouting", fmt.Sprintf("%s meta l4proto { tcp, udp } th dport 53 redirect to :53", unauth), "[portal] DNS hijack")
+}
+
+// addCaptivePortalForwardRules stops unauthenticated clients from
+// forwarding and applies the per-client bandwidth caps. It runs before the
+// stateful accept, so expired or revoked sessions lose established flows.
+func addCaptivePortalForwardRules(sb *ScriptBuilder, cfg *Config) {
+	ifaces := portalInterfaces(cfg)
+	if ifaces == "" {
+		return
+	}
+	p := cfg.CaptivePortal
+
+	sb.AddRule("forward", fmt.Sprintf("iifname %s ether saddr != @%s counter reject", ifaces, PortalAuthSet), "[portal] Not logged in")
+
+	if rate := portalRate(p.UploadLimit); rate != "" {
+		sb.AddRule("forward", fmt.Sprintf("iifname %s meter portal_up { ether saddr limit rate over %s } drop", ifaces, rate), "[portal] Upload cap")
+	}
+	if rate := portalRate(p.DownloadLimit); rate != "" {
+		sb.AddRule("forward", fmt.Sprintf("oifname %s meter portal_down4 { ip daddr limit rate over %s } drop", ifaces, rate), "[portal] Download cap")
+		sb.AddRule("forward", fmt.Sprintf("oifname %s meter portal_down6 { ip6 daddr limit rate over %s } drop", ifaces, rate), "[portal] Download cap")
+	}
+}
+
+// addCaptivePortalInputRules lets portal clients reach the portal and the
+// resolver their DNS is redirected to.
+func addCaptivePortalInputRules(sb *ScriptBuilder, cfg *Config) {
+	ifaces := portalInterfaces(cfg)
+	if ifaces == "" {
+		return
+	}
+	sb.AddRule("input", fmt.Sprintf("iifname %s tcp dport %d accept", ifaces, cfg.CaptivePortal.Port), "[portal] Portal page")
+	sb.AddRule("input", fmt.Sprintf("iifname %s meta l4proto { tcp, udp } th dport 53 accept", ifaces), "[portal] DNS")
+}
+
+// portalRate renders a bandwidth cap as an nft byte rate with one second
+// of burst, or "" when uncapped.
+func portalRate(limit string) string {
+	if limit == "" {
+		return ""
+	}
+	bits, err := config.ParseBitRate(limit)
+	if err != nil || bits <= 0 {
+		return ""
+	}
+	kbytes := bits / 8 / 1024
+	if kbytes < 1 {
+		kbytes = 1
+	}
+	return fmt.Sprintf("%d kbytes/second burst %d kbytes", kbytes, kbytes)
+}
@@ -29,6 +29,10 @@ type Config struct {
 	System            *config.SystemConfig
 	Replication       *config.ReplicationConfig
 	QoSPolicies       []config.QoSPolicy
+	Quotas            []config.DeviceQuota
+	CaptivePortal     *config.CaptivePortalConfig // Defaults applied; nil without portal zones
+	NAT64             *config.NAT64Config         // Defaults applied; nil without NAT64 zones
+	GeoIP             *config.GeoIPConfig
 }
 
 // FromGlobalConfig extracts the firewall configuration from the global config.
@@ -64,5 +68,9 @@ func FromGlobalConfig(g *config.Config) *Config {
 		System:            g.System,
 		Replication:       g.Replication,
 		QoSPolicies:       g.QoSPolicies,
+		Quotas:            g.Quotas,
+		CaptivePortal:     g.PortalSettings(),
+		NAT64:             g.NAT64Settings(),
+		GeoIP:             g.GeoIP,
 	}
 }
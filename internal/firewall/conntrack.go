@@ -0,0 +1,233 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package firewall
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"grimm.is/flywall/internal/errors"
+)
+
+// ConntrackEntry represents a single connection tracking entry. The Src and
+// Dst fields are the original direction; the Reply fields differ from them
+// when the connection is NATed.
+type ConntrackEntry struct {
+	ID           uint32 `json:"id"`
+	Family       string `json:"family"` // ipv4 or ipv6
+	Protocol     string `json:"protocol"`
+	SrcIP        string `json:"src_ip"`
+	DstIP        string `json:"dst_ip"`
+	SrcPort      uint16 `json:"src_port,omitempty"`
+	DstPort      uint16 `json:"dst_port,omitempty"`
+	ReplySrcIP   string `json:"reply_src_ip"`
+	ReplyDstIP   string `json:"reply_dst_ip"`
+	ReplySrcPort uint16 `json:"reply_src_port,omitempty"`
+	ReplyDstPort uint16 `json:"reply_dst_port,omitempty"`
+	State        string `json:"state,omitempty"` // TCP state
+	Timeout      uint32 `json:"timeout"`         // Seconds until the entry expires
+	Mark         uint32 `json:"mark"`
+	Assured      bool   `json:"assured"`
+	Unreplied    bool   `json:"unreplied"`
+	Offloaded    bool   `json:"offloaded"`
+	Packets      uint64 `json:"packets"` // Both directions; needs nf_conntrack_acct
+	Bytes        uint64 `json:"bytes"`
+}
+
+// ConntrackEvent is a connection entering or leaving the table.
+type ConntrackEvent struct {
+	Type  string         `json:"type"` // new or destroy
+	Entry ConntrackEntry `json:"entry"`
+}
+
+// Conntrack event types.
+const (
+	ConntrackEventNew     = "new"
+	ConntrackEventDestroy = "destroy"
+)
+
+// ConntrackFilter selects connection tracking entries. Empty fields match
+// everything; set fields must all match.
+type ConntrackFilter struct {
+	Protocol string `json:"protocol,omitempty"` // Name or number
+	IP       string `json:"ip,omitempty"`       // Address or CIDR of either end, before or after NAT
+	SrcIP    string `json:"src_ip,omitempty"`   // Address or CIDR of the original source
+	DstIP    string `json:"dst_ip,omitempty"`   // Address or CIDR of the original destination
+	Port     uint16 `json:"port,omitempty"`     // Port of either end
+	SrcPort  uint16 `json:"src_port,omitempty"`
+	DstPort  uint16 `json:"dst_port,omitempty"`
+	State    string `json:"state,omitempty"` // TCP state, or assured, unreplied or offloaded
+	Mark     string `json:"mark,omitempty"`  // value or value/mask, decimal or 0x hex
+	Zone     string `json:"zone,omitempty"`  // Firewall zone of either end
+	ID       uint32 `json:"id,omitempty"`
+}
+
+// IsEmpty reports whether the filter matches every entry.
+func (f ConntrackFilter) IsEmpty() bool {
+	return f == ConntrackFilter{}
+}
+
+// ConntrackMatcher is a compiled ConntrackFilter.
+type ConntrackMatcher struct {
+	filter   ConntrackFilter
+	protocol string
+	ip       *netip.Prefix
+	src      *netip.Prefix
+	dst      *netip.Prefix
+	mark     uint32
+	markMask uint32
+	zone     []netip.Prefix
+}
+
+// Compile checks the filter and prepares it for matching. zoneNets are the
+// networks of f.Zone, resolved by the caller; a zone without networks
+// matches nothing.
+func (f ConntrackFilter) Compile(zoneNets []netip.Prefix) (*ConntrackMatcher, error) {
+	m := &ConntrackMatcher{filter: f, zone: zoneNets}
+
+	if f.Protocol != "" {
+		m.protocol = strings.ToLower(f.Protocol)
+		if n, err := strconv.ParseUint(m.protocol, 10, 8); err == nil {
+			m.protocol = conntrackProtoName(uint8(n))
+		}
+	}
+
+	var err error
+	if m.ip, err = parseConntrackPrefix("ip", f.IP); err != nil {
+		return nil, err
+	}
+	if m.src, err = parseConntrackPrefix("src_ip", f.SrcIP); err != nil {
+		return nil, err
+	}
+	if m.dst, err = parseConntrackPrefix("dst_ip", f.DstIP); err != nil {
+		return nil, err
+	}
+
+	if f.State != "" {
+		m.filter.State = strings.ToUpper(f.State)
+	}
+
+	if f.Mark != "" {
+		value, mask, hasMask := strings.Cut(f.Mark, "/")
+		v, err := strconv.ParseUint(value, 0, 32)
+		if err != nil {
+			return nil, errors.Errorf(errors.KindValidation, "invalid mark %q", f.Mark)
+		}
+		m.mark, m.markMask = uint32(v), 0xffffffff
+		if hasMask {
+			mv, err := strconv.ParseUint(mask, 0, 32)
+			if err != nil {
+				return nil, errors.Errorf(errors.KindValidation, "invalid mark mask %q", f.Mark)
+			}
+			m.markMask = uint32(mv)
+		}
+	}
+	return m, nil
+}
+
+func parseConntrackPrefix(field, s string) (*netip.Prefix, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, errors.Errorf(errors.KindValidation, "invalid %s %q", field, s)
+		}
+		p = p.Masked()
+		return &p, nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return nil, errors.Errorf(errors.KindValidation, "invalid %s %q", field, s)
+	}
+	p := netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+	return &p, nil
+}
+
+// Match reports whether e passes the filter.
+func (m *ConntrackMatcher) Match(e *ConntrackEntry) bool {
+	f := &m.filter
+	switch {
+	case f.ID != 0 && e.ID != f.ID:
+		return false
+	case m.protocol != "" && e.Protocol != m.protocol:
+		return false
+	case f.SrcPort != 0 && e.SrcPort != f.SrcPort:
+		return false
+	case f.DstPort != 0 && e.DstPort != f.DstPort:
+		return false
+	case f.Port != 0 && e.SrcPort != f.Port && e.DstPort != f.Port &&
+		e.ReplySrcPort != f.Port && e.ReplyDstPort != f.Port:
+		return false
+	case m.markMask != 0 && e.Mark&m.markMask != m.mark&m.markMask:
+		return false
+	case f.State != "" && !matchConntrackState(e, f.State):
+		return false
+	}
+
+	if m.src != nil && !prefixContains(*m.src, e.SrcIP) {
+		return false
+	}
+	if m.dst != nil && !prefixContains(*m.dst, e.DstIP) {
+		return false
+	}
+	addrs := [4]string{e.SrcIP, e.DstIP, e.ReplySrcIP, e.ReplyDstIP}
+	if m.ip != nil && !anyAddrIn(addrs[:], []netip.Prefix{*m.ip}) {
+		return false
+	}
+	if f.Zone != "" && !anyAddrIn(addrs[:], m.zone) {
+		return false
+	}
+	return true
+}
+
+func matchConntrackState(e *ConntrackEntry, state string) bool {
+	switch state {
+	case "ASSURED":
+		return e.Assured
+	case "UNREPLIED":
+		return e.Unreplied
+	case "OFFLOADED":
+		return e.Offloaded
+	}
+	return e.State == state
+}
+
+func prefixContains(p netip.Prefix, s string) bool {
+	addr, err := netip.ParseAddr(s)
+	return err == nil && p.Contains(addr.Unmap())
+}
+
+func anyAddrIn(addrs []string, nets []netip.Prefix) bool {
+	for _, s := range addrs {
+		for _, p := range nets {
+			if prefixContains(p, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// conntrackProtoName names the protocols conntrack tracks individually.
+func conntrackProtoName(proto uint8) string {
+	switch proto {
+	case 1:
+		return "icmp"
+	case 6:
+		return "tcp"
+	case 17:
+		return "udp"
+	case 47:
+		return "gre"
+	case 58:
+		return "icmpv6"
+	case 132:
+		return "sctp"
+	case 136:
+		return "udplite"
+	}
+	return strconv.Itoa(int(proto))
+}
@@ -0,0 +1,60 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package firewall
+
+import (
+	"fmt"
+
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/errors"
+)
+
+// conntrackHelperObject names the ct helper object declared for a helper.
+func conntrackHelperObject(helper string) string {
+	return "helper_" + helper
+}
+
+// addConntrackHelpers declares a ct helper object for each helper that a
+// policy or rule assigns. Nothing is declared, and no connection gets a
+// helper, unless the config names one.
+func addConntrackHelpers(sb *ScriptBuilder, cfg *Config) {
+	for _, name := range config.UsedConntrackHelpers(cfg.Policies) {
+		h := config.ConntrackHelpers[name]
+		sb.AddCtHelper(conntrackHelperObject(name), name, h.Protocol)
+	}
+}
+
+// addPolicyHelperRules assigns a policy's helpers to new connections on
+// their standard ports. The rules go first in the policy chain and fall
+// through to its rules, which still decide the verdict.
+func addPolicyHelperRules(sb *ScriptBuilder, chain, from, to string, helpers []string) {
+	for _, name := range helpers {
+		h, ok := config.ConntrackHelpers[name]
+		if !ok {
+			continue
+		}
+		sb.AddRule(chain, fmt.Sprintf("ct state new %s dport %d ct helper set %q",
+			h.Protocol, h.Port, conntrackHelperObject(name)),
+			fmt.Sprintf("[policy:%s->%s] helper %s", from, to, name))
+	}
+}
+
+// loadHelperModules makes sure the kernel modules of the given helpers are
+// loaded. A missing tracking module is an error, as the ct helper object
+// cannot be created without it. A missing NAT module is only a warning: the
+// helper still works for connections that are not NATed.
+func loadHelperModules(helpers []string) (warnings []string, err error) {
+	for _, name := range helpers {
+		h, ok := config.ConntrackHelpers[name]
+		if !ok {
+			continue
+		}
+		if err := loadKernelModule(h.Module); err != nil {
+			return warnings, errors.Wrapf(err, errors.KindUnavailable, "conntrack helper %s needs kernel module %s", name, h.Module)
+		}
+		if err := loadKernelModule(h.NATModule); err != nil {
+			warnings = append(warnings, fmt.Sprintf("conntrack helper %s will not work through NAT: %v", name, err))
+		}
+	}
+	return warnings, nil
+}
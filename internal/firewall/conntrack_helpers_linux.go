@@ -0,0 +1,38 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+//go:build linux
+
+package firewall
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// autoHelperSysctl turns on port-based helper assignment on kernels before
+// 6.0. It is kept off so that only the ruleset assigns helpers.
+const autoHelperSysctl = "/proc/sys/net/netfilter/nf_conntrack_helper"
+
+// loadKernelModule makes sure a module is loaded, running modprobe if it is
+// not. Built-in modules are accepted by modprobe as well.
+func loadKernelModule(module string) error {
+	if _, err := os.Stat("/sys/module/" + module); err == nil {
+		return nil
+	}
+	if out, err := exec.Command("modprobe", module).CombinedOutput(); err != nil {
+		return fmt.Errorf("modprobe %s: %s", module, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// disableAutoHelpers turns off automatic helper assignment where the kernel
+// still has it.
+func disableAutoHelpers() error {
+	current, err := os.ReadFile(autoHelperSysctl)
+	if err != nil || strings.TrimSpace(string(current)) == "0" {
+		return nil
+	}
+	return os.WriteFile(autoHelperSysctl, []byte("0"), 0644)
+}
@@ -0,0 +1,15 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+//go:build !linux
+
+package firewall
+
+// loadKernelModule is a stub for non-Linux platforms.
+func loadKernelModule(string) error {
+	return nil
+}
+
+// disableAutoHelpers is a stub for non-Linux platforms.
+func disableAutoHelpers() error {
+	return nil
+}
@@ -5,13 +5,27 @@
 package firewall
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"net"
+	"syscall"
 
 	"github.com/ti-mo/conntrack"
+	"github.com/ti-mo/netfilter"
+	"github.com/vishvananda/netlink"
+
+	"grimm.is/flywall/internal/errors"
 )
 
 // GetConntrackEntries returns active connection tracking entries using netlink API.
 func GetConntrackEntries() ([]ConntrackEntry, error) {
+	return ListConntrack(nil)
+}
+
+// ListConntrack returns the connection tracking entries m matches, or all
+// of them if m is nil.
+func ListConntrack(m *ConntrackMatcher) ([]ConntrackEntry, error) {
 	conn, err := conntrack.Dial(nil)
 	if err != nil {
 		return nil, fmt.Errorf("conntrack dial failed: %w", err)
@@ -24,44 +38,161 @@ func GetConntrackEntries() ([]ConntrackEntry, error) {
 	}
 
 	entries := make([]ConntrackEntry, 0, len(flows))
-	for _, f := range flows {
-		entry := ConntrackEntry{
-			Timeout: f.Timeout,
+	for i := range flows {
+		entry := conntrackEntry(&flows[i])
+		if m == nil || m.Match(&entry) {
+			entries = append(entries, entry)
 		}
+	}
+	return entries, nil
+}
 
-		// Protocol
-		if f.TupleOrig.Proto.Protocol != 0 {
-			switch f.TupleOrig.Proto.Protocol {
-			case 6:
-				entry.Protocol = "tcp"
-			case 17:
-				entry.Protocol = "udp"
-			case 1:
-				entry.Protocol = "icmp"
-			default:
-				entry.Protocol = fmt.Sprintf("%d", f.TupleOrig.Proto.Protocol)
+// DeleteConntrack removes the entries m matches and returns how many were
+// removed. Connections still open start over as new connections, so they
+// are checked against the current ruleset again.
+func DeleteConntrack(m *ConntrackMatcher) (int, error) {
+	if m == nil || m.filter.IsEmpty() {
+		return 0, errors.New(errors.KindValidation, "refusing to flush the whole conntrack table; give a filter")
+	}
+
+	conn, err := conntrack.Dial(nil)
+	if err != nil {
+		return 0, fmt.Errorf("conntrack dial failed: %w", err)
+	}
+	defer conn.Close()
+
+	flows, err := conn.Dump(nil)
+	if err != nil {
+		return 0, fmt.Errorf("conntrack dump failed: %w", err)
+	}
+
+	deleted := 0
+	for i := range flows {
+		entry := conntrackEntry(&flows[i])
+		if !m.Match(&entry) {
+			continue
+		}
+		if err := conn.Delete(flows[i]); err != nil {
+			if errors.Is(err, syscall.ENOENT) {
+				continue // Expired since the dump
 			}
+			return deleted, fmt.Errorf("conntrack delete failed: %w", err)
 		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// DeleteDeviceConntrack removes the entries of every address the neighbor
+// table maps mac to, and returns how many were removed.
+func DeleteDeviceConntrack(mac string) (int, error) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return 0, errors.Wrap(err, errors.KindValidation, "invalid MAC address")
+	}
+	neighs, err := netlink.NeighList(0, netlink.FAMILY_ALL)
+	if err != nil {
+		return 0, fmt.Errorf("neighbor list failed: %w", err)
+	}
 
-		// Source and destination from original tuple
-		if f.TupleOrig.IP.SourceAddress.IsValid() {
-			entry.SrcIP = f.TupleOrig.IP.SourceAddress.String()
+	deleted := 0
+	for _, n := range neighs {
+		if n.IP == nil || !bytes.Equal(n.HardwareAddr, hw) {
+			continue
+		}
+		m, err := ConntrackFilter{IP: n.IP.String()}.Compile(nil)
+		if err != nil {
+			continue
 		}
-		if f.TupleOrig.IP.DestinationAddress.IsValid() {
-			entry.DstIP = f.TupleOrig.IP.DestinationAddress.String()
+		d, err := DeleteConntrack(m)
+		deleted += d
+		if err != nil {
+			return deleted, err
 		}
-		entry.SrcPort = f.TupleOrig.Proto.SourcePort
-		entry.DstPort = f.TupleOrig.Proto.DestinationPort
+	}
+	return deleted, nil
+}
 
-		// TCP state (if available)
-		if entry.Protocol == "tcp" && f.ProtoInfo.TCP != nil {
-			entry.State = tcpStateString(f.ProtoInfo.TCP.State)
+// WatchConntrack calls fn for every connection created or destroyed until
+// ctx is done. fn runs on the listener's goroutine and must not block, or
+// the kernel drops events.
+func WatchConntrack(ctx context.Context, fn func(ConntrackEvent)) error {
+	conn, err := conntrack.Dial(nil)
+	if err != nil {
+		return fmt.Errorf("conntrack dial failed: %w", err)
+	}
+	defer conn.Close()
+
+	events := make(chan conntrack.Event, 1024)
+	errs, err := conn.Listen(events, 1, []netfilter.NetlinkGroup{netfilter.GroupCTNew, netfilter.GroupCTDestroy})
+	if err != nil {
+		return fmt.Errorf("conntrack listen failed: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errs:
+			return fmt.Errorf("conntrack events failed: %w", err)
+		case ev := <-events:
+			if ev.Flow == nil {
+				continue
+			}
+			var typ string
+			switch ev.Type {
+			case conntrack.EventNew:
+				typ = ConntrackEventNew
+			case conntrack.EventDestroy:
+				typ = ConntrackEventDestroy
+			default:
+				continue
+			}
+			fn(ConntrackEvent{Type: typ, Entry: conntrackEntry(ev.Flow)})
 		}
+	}
+}
 
-		entries = append(entries, entry)
+func conntrackEntry(f *conntrack.Flow) ConntrackEntry {
+	orig, reply := f.TupleOrig, f.TupleReply
+	entry := ConntrackEntry{
+		ID:           f.ID,
+		Family:       "ipv4",
+		Protocol:     conntrackProtoName(orig.Proto.Protocol),
+		SrcPort:      orig.Proto.SourcePort,
+		DstPort:      orig.Proto.DestinationPort,
+		ReplySrcPort: reply.Proto.SourcePort,
+		ReplyDstPort: reply.Proto.DestinationPort,
+		Timeout:      f.Timeout,
+		Mark:         f.Mark,
+		Assured:      f.Status.Assured(),
+		Unreplied:    !f.Status.SeenReply(),
+		Offloaded:    f.Status.Offload(),
+		Packets:      f.CountersOrig.Packets + f.CountersReply.Packets,
+		Bytes:        f.CountersOrig.Bytes + f.CountersReply.Bytes,
+	}
+	if orig.IP.SourceAddress.Is6() && !orig.IP.SourceAddress.Is4In6() {
+		entry.Family = "ipv6"
 	}
 
-	return entries, nil
+	if orig.IP.SourceAddress.IsValid() {
+		entry.SrcIP = orig.IP.SourceAddress.String()
+	}
+	if orig.IP.DestinationAddress.IsValid() {
+		entry.DstIP = orig.IP.DestinationAddress.String()
+	}
+	if reply.IP.SourceAddress.IsValid() {
+		entry.ReplySrcIP = reply.IP.SourceAddress.String()
+	}
+	if reply.IP.DestinationAddress.IsValid() {
+		entry.ReplyDstIP = reply.IP.DestinationAddress.String()
+	}
+
+	// TCP state (if available)
+	if entry.Protocol == "tcp" && f.ProtoInfo.TCP != nil {
+		entry.State = tcpStateString(f.ProtoInfo.TCP.State)
+	}
+	return entry
 }
 
 // tcpStateString converts TCP state constant to string.
@@ -5,6 +5,7 @@
 package firewall
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -13,3 +14,23 @@ import (
 func GetConntrackEntries() ([]ConntrackEntry, error) {
 	return nil, fmt.Errorf("conntrack not supported on this platform")
 }
+
+// ListConntrack is a stub for non-Linux platforms.
+func ListConntrack(m *ConntrackMatcher) ([]ConntrackEntry, error) {
+	return nil, fmt.Errorf("conntrack not supported on this platform")
+}
+
+// DeleteConntrack is a stub for non-Linux platforms.
+func DeleteConntrack(m *ConntrackMatcher) (int, error) {
+	return 0, fmt.Errorf("conntrack not supported on this platform")
+}
+
+// DeleteDeviceConntrack is a stub for non-Linux platforms.
+func DeleteDeviceConntrack(mac string) (int, error) {
+	return 0, fmt.Errorf("conntrack not supported on this platform")
+}
+
+// WatchConntrack is a stub for non-Linux platforms.
+func WatchConntrack(ctx context.Context, fn func(ConntrackEvent)) error {
+	return fmt.Errorf("conntrack not supported on this platform")
+}
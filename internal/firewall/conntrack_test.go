@@ -0,0 +1,86 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package firewall
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestConntrackFilterMatch(t *testing.T) {
+	// A LAN client reaching a web server through SNAT
+	entry := ConntrackEntry{
+		ID: 42, Family: "ipv4", Protocol: "tcp",
+		SrcIP: "192.168.1.10", DstIP: "93.184.216.34", SrcPort: 51000, DstPort: 443,
+		ReplySrcIP: "93.184.216.34", ReplyDstIP: "203.0.113.5", ReplySrcPort: 443, ReplyDstPort: 51000,
+		State: "ESTABLISHED", Mark: 0x200123, Assured: true,
+	}
+	lan := []netip.Prefix{netip.MustParsePrefix("192.168.1.0/24")}
+
+	tests := []struct {
+		name   string
+		filter ConntrackFilter
+		want   bool
+	}{
+		{"empty", ConntrackFilter{}, true},
+		{"protocol", ConntrackFilter{Protocol: "TCP"}, true},
+		{"protocol number", ConntrackFilter{Protocol: "6"}, true},
+		{"other protocol", ConntrackFilter{Protocol: "udp"}, false},
+		{"ip either end", ConntrackFilter{IP: "93.184.216.34"}, true},
+		{"ip after nat", ConntrackFilter{IP: "203.0.113.5"}, true},
+		{"ip cidr", ConntrackFilter{IP: "192.168.0.0/16"}, true},
+		{"src ip", ConntrackFilter{SrcIP: "192.168.1.10"}, true},
+		{"src ip is not dst", ConntrackFilter{SrcIP: "93.184.216.34"}, false},
+		{"dst ip", ConntrackFilter{DstIP: "93.184.216.0/24"}, true},
+		{"port", ConntrackFilter{Port: 443}, true},
+		{"dst port", ConntrackFilter{DstPort: 80}, false},
+		{"state", ConntrackFilter{State: "established"}, true},
+		{"status flag", ConntrackFilter{State: "assured"}, true},
+		{"unset status flag", ConntrackFilter{State: "unreplied"}, false},
+		{"mark", ConntrackFilter{Mark: "0x200123"}, true},
+		{"mark mask", ConntrackFilter{Mark: "0x200000/0xff0000"}, true},
+		{"other mark", ConntrackFilter{Mark: "0x100000/0xff0000"}, false},
+		{"zone", ConntrackFilter{Zone: "lan"}, true},
+		{"id", ConntrackFilter{ID: 43}, false},
+		{"all", ConntrackFilter{Protocol: "tcp", SrcIP: "192.168.1.10", DstPort: 443, State: "ESTABLISHED", Zone: "lan"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := tt.filter.Compile(lan)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if got := m.Match(&entry); got != tt.want {
+				t.Errorf("Match() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A zone with no known networks matches nothing
+	m, _ := ConntrackFilter{Zone: "dmz"}.Compile(nil)
+	if m.Match(&entry) {
+		t.Error("zone without networks matched")
+	}
+}
+
+func TestConntrackFilterInvalid(t *testing.T) {
+	for _, f := range []ConntrackFilter{
+		{IP: "not-an-ip"},
+		{SrcIP: "10.0.0.0/33"},
+		{Mark: "0xzz"},
+		{Mark: "1/x"},
+	} {
+		if _, err := f.Compile(nil); err == nil {
+			t.Errorf("Compile(%+v) succeeded; want error", f)
+		}
+	}
+}
+
+func TestConntrackFilterIsEmpty(t *testing.T) {
+	if !(ConntrackFilter{}).IsEmpty() {
+		t.Error("zero filter is not empty")
+	}
+	if (ConntrackFilter{Port: 22}).IsEmpty() {
+		t.Error("filter with a port is empty")
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package firewall
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"grimm.is/flywall/internal/brand"
+)
+
+// FlowtableName is the flowtable established forwarded connections are
+// offloaded to.
+const FlowtableName = "ft"
+
+// FlowOffloadCounter counts the packets that handed a connection to the
+// flowtable, roughly one per offloaded connection.
+const FlowOffloadCounter = "cnt_flow_offload"
+
+// hwOffloadCapable reports whether a device can offload flows in hardware.
+// Replaced in tests.
+var hwOffloadCapable = deviceSupportsHWOffload
+
+// flowOffloadPlan is what the ruleset needs for flow offload.
+type flowOffloadPlan struct {
+	devices  []string    // Interfaces of zones that forward to each other
+	hardware bool        // Every device supports hardware offload
+	optOut   [][2]string // iifname/oifname patterns of policies with flow_offload = false
+	portal   string      // Captive portal interfaces, never offloaded
+	quotas   bool        // Device quotas are configured
+	throttle []string    // Quota throttle sets, whose devices are never offloaded
+}
+
+// planFlowOffload collects the devices of zones joined by a forwarding
+// policy, and the zone pairs whose policies opt out of offload.
+func planFlowOffload(cfg *Config) *flowOffloadPlan {
+	if !cfg.EnableFlowOffload {
+		return nil
+	}
+	zoneMap := buildZoneMapForScript(cfg)
+
+	plan := &flowOffloadPlan{portal: portalInterfaces(cfg), quotas: len(cfg.Quotas) > 0}
+	seenSet := make(map[string]bool)
+	for _, q := range cfg.Quotas {
+		if set, _, ok := QuotaThrottleTarget(q, cfg.QoSPolicies); ok && !seenSet[set] {
+			seenSet[set] = true
+			plan.throttle = append(plan.throttle, set)
+		}
+	}
+	sort.Strings(plan.throttle)
+	seen := make(map[string]bool)
+	optedOut := make(map[[2]string]bool)
+	for _, pol := range cfg.Policies {
+		from, to := canonicalZoneName(pol.From), canonicalZoneName(pol.To)
+		if pol.Disabled || from == brand.LowerName || to == brand.LowerName {
+			continue
+		}
+		fromIfaces, toIfaces := zoneMap[from], zoneMap[to]
+		if len(fromIfaces) == 0 || len(toIfaces) == 0 {
+			continue
+		}
+
+		if pol.FlowOffload != nil && !*pol.FlowOffload {
+			for _, src := range fromIfaces {
+				for _, dst := range toIfaces {
+					pair := [2]string{src, dst}
+					if !optedOut[pair] {
+						optedOut[pair] = true
+						plan.optOut = append(plan.optOut, pair)
+					}
+				}
+			}
+		}
+
+		for _, iface := range append(append([]string{}, fromIfaces...), toIfaces...) {
+			// Flowtables take concrete devices only
+			if seen[iface] || strings.HasSuffix(iface, "*") {
+				continue
+			}
+			seen[iface] = true
+			plan.devices = append(plan.devices, iface)
+		}
+	}
+	if len(plan.devices) == 0 {
+		return nil
+	}
+	sort.Strings(plan.devices)
+
+	plan.hardware = true
+	for _, dev := range plan.devices {
+		if !hwOffloadCapable(dev) {
+			plan.hardware = false
+			break
+		}
+	}
+	return plan
+}
+
+// addFlowtable declares the flowtable. Hardware offload is all or nothing
+// for a flowtable, so it is used only when every device supports it.
+func addFlowtable(sb *ScriptBuilder, plan *flowOffloadPlan) {
+	if plan == nil {
+		return
+	}
+	// counter keeps conntrack accounting (flow analytics, quotas) current
+	flags := []string{"counter"}
+	if plan.hardware {
+		flags = append(flags, "flags offload")
+	}
+	sb.AddFlowtable(FlowtableName, plan.devices, flags)
+}
+
+// addFlowOffloadRules hands established TCP and UDP connections to the
+// flowtable. Once offloaded, their packets skip the forward chain, so this
+// runs after the drops that must keep applying to established traffic.
+// Connections of opted-out policies are told apart by the direction of the
+// packet, so the reverse policy between the same zones is unaffected.
+func addFlowOffloadRules(sb *ScriptBuilder, plan *flowOffloadPlan) {
+	if plan == nil {
+		return
+	}
+
+	sb.AddCounter(FlowOffloadCounter, "[feature] Offloaded connections")
+	sb.AddChain("flow_offload", "", "", 0, "", "[feature] Flow offload")
+	if plan.portal != "" {
+		// Portal logouts must cut off established connections too
+		sb.AddRule("flow_offload", fmt.Sprintf("iifname %s return", plan.portal), "[portal] Not offloaded")
+		sb.AddRule("flow_offload", fmt.Sprintf("oifname %s return", plan.portal), "[portal] Not offloaded")
+	}
+	if plan.quotas {
+		// Quota sets match a device's MAC, which only the packets it sends
+		// carry, so offload is left to the side that opened the connection.
+		// Blocked devices are dropped before this chain; connections opened
+		// from outside to a throttled device can still be offloaded. The
+		// quota manager drops a device's connections when it enters a set.
+		sb.AddRule("flow_offload", "ct direction reply return", "[quota] Offload from the client side only")
+		for _, set := range plan.throttle {
+			sb.AddRule("flow_offload", fmt.Sprintf("ether saddr @%s return", set), "[quota] Throttled devices not offloaded")
+		}
+	}
+	for _, pair := range plan.optOut {
+		src, dst := forceQuote(pair[0]), forceQuote(pair[1])
+		sb.AddRule("flow_offload", fmt.Sprintf("ct direction original iifname %s oifname %s return", src, dst), "[policy] flow_offload = false")
+		sb.AddRule("flow_offload", fmt.Sprintf("ct direction reply iifname %s oifname %s return", dst, src), "[policy] flow_offload = false")
+	}
+	sb.AddRule("flow_offload", fmt.Sprintf("counter name %s flow add @%s", FlowOffloadCounter, FlowtableName), "[feature] Offload")
+
+	sb.AddRule("forward", "meta l4proto { tcp, udp } ct state established jump flow_offload", "[feature] Flow offload")
+}
@@ -0,0 +1,25 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+//go:build linux
+
+package firewall
+
+import (
+	"github.com/safchain/ethtool"
+)
+
+// deviceSupportsHWOffload reports whether a device has TC flower offload
+// (ethtool hw-tc-offload) turned on, which hardware flowtables build on.
+func deviceSupportsHWOffload(iface string) bool {
+	e, err := ethtool.NewEthtool()
+	if err != nil {
+		return false
+	}
+	defer e.Close()
+
+	features, err := e.Features(iface)
+	if err != nil {
+		return false
+	}
+	return features["hw-tc-offload"]
+}
@@ -0,0 +1,10 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+//go:build !linux
+
+package firewall
+
+// deviceSupportsHWOffload is a stub for non-Linux platforms.
+func deviceSupportsHWOffload(string) bool {
+	return false
+}
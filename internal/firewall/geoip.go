@@ -4,27 +4,45 @@ package firewall
 
 import (
 	"grimm.is/flywall/internal/install"
+	"encoding/binary"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 
+	"grimm.is/flywall/internal/config"
+
 	"github.com/oschwald/geoip2-golang"
+	"github.com/oschwald/maxminddb-golang"
 )
 
+// geoIPSetPrefix is the nftables set name prefix for per-country sets.
+const geoIPSetPrefix = "geoip_country_"
+
 // GeoIPManager handles country lookups from MaxMind databases.
 type GeoIPManager struct {
 	mu     sync.RWMutex
 	reader *geoip2.Reader
 	path   string
+
+	// onReload callbacks fire after a new database has been opened.
+	onReload []func()
+}
+
+// DefaultGeoIPDatabasePath returns the database location used when the
+// config does not set database_path.
+func DefaultGeoIPDatabasePath() string {
+	return filepath.Join(install.GetShareDir(), "geoip", "GeoLite2-Country.mmdb")
 }
 
 // NewGeoIPManager creates a new GeoIP manager with the specified database path.
 // If path is empty, uses the default location.
 func NewGeoIPManager(dbPath string) (*GeoIPManager, error) {
 	if dbPath == "" {
-		dbPath = filepath.Join(install.GetShareDir(), "geoip", "GeoLite2-Country.mmdb")
+		dbPath = DefaultGeoIPDatabasePath()
 	}
 
 	// Check if database exists
@@ -73,24 +91,186 @@ func (g *GeoIPManager) Close() error {
 }
 
 // Reload reopens the database (for updates).
+// Registered OnReload callbacks run after the new database is in place.
 func (g *GeoIPManager) Reload() error {
 	g.mu.Lock()
-	defer g.mu.Unlock()
-
-	if g.reader != nil {
-		g.reader.Close()
-	}
 
+	// Open the new database before closing the old one so a corrupt
+	// update leaves the previous reader in service.
 	reader, err := geoip2.Open(g.path)
 	if err != nil {
+		g.mu.Unlock()
 		return fmt.Errorf("failed to reload GeoIP database: %w", err)
 	}
 
+	if g.reader != nil {
+		g.reader.Close()
+	}
 	g.reader = reader
+	callbacks := append([]func(){}, g.onReload...)
+	g.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn()
+	}
 	return nil
 }
 
+// OnReload registers a callback invoked after every successful Reload.
+// The firewall manager uses this to refresh the country sets in place.
+func (g *GeoIPManager) OnReload(fn func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.onReload = append(g.onReload, fn)
+}
+
+// CountryNetworks walks the database and returns the IPv4 networks assigned
+// to each requested country, keyed by upper-case ISO code. Adjacent networks
+// are coalesced into ranges to keep the resulting nftables sets small.
+func (g *GeoIPManager) CountryNetworks(codes []string) (map[string][]string, error) {
+	wanted := make(map[string]bool, len(codes))
+	for _, c := range codes {
+		wanted[strings.ToUpper(c)] = true
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	// geoip2.Reader does not expose network iteration, so walk the same file
+	// with the lower-level reader while holding the lock.
+	db, err := maxminddb.Open(g.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	defer db.Close()
+
+	var record struct {
+		Country struct {
+			IsoCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+	}
+
+	ranges := make(map[string][][2]uint32)
+	allIPv4 := &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}
+	networks := db.NetworksWithin(allIPv4, maxminddb.SkipAliasedNetworks)
+	for networks.Next() {
+		record.Country.IsoCode = ""
+		network, err := networks.Network(&record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode GeoIP network: %w", err)
+		}
+		code := strings.ToUpper(record.Country.IsoCode)
+		if !wanted[code] {
+			continue
+		}
+		ip4 := network.IP.To4()
+		if ip4 == nil {
+			continue
+		}
+		ones, _ := network.Mask.Size()
+		start := binary.BigEndian.Uint32(ip4)
+		end := start | uint32(uint64(1)<<(32-ones)-1)
+		ranges[code] = append(ranges[code], [2]uint32{start, end})
+	}
+	if err := networks.Err(); err != nil {
+		return nil, fmt.Errorf("failed to walk GeoIP database: %w", err)
+	}
+
+	result := make(map[string][]string, len(wanted))
+	for code := range wanted {
+		result[code] = coalesceIPv4Ranges(ranges[code])
+	}
+	return result, nil
+}
+
 // DatabasePath returns the path to the loaded database.
 func (g *GeoIPManager) DatabasePath() string {
 	return g.path
 }
+
+// coalesceIPv4Ranges merges adjacent or overlapping ranges and renders them
+// as nftables interval elements (single address, CIDR or "a-b" range).
+func coalesceIPv4Ranges(ranges [][2]uint32) []string {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+
+	merged := [][2]uint32{ranges[0]}
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if last[1] == ^uint32(0) || r[0] <= last[1]+1 {
+			if r[1] > last[1] {
+				last[1] = r[1]
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	elements := make([]string, 0, len(merged))
+	for _, r := range merged {
+		elements = append(elements, formatIPv4Range(r[0], r[1]))
+	}
+	return elements
+}
+
+func formatIPv4Range(start, end uint32) string {
+	toIP := func(v uint32) string {
+		b := make(net.IP, 4)
+		binary.BigEndian.PutUint32(b, v)
+		return b.String()
+	}
+	if start == end {
+		return toIP(start)
+	}
+	// Emit CIDR notation when the range is an aligned power of two.
+	size := uint64(end) - uint64(start) + 1
+	if size&(size-1) == 0 && uint64(start)%size == 0 {
+		ones := 32
+		for s := size; s > 1; s >>= 1 {
+			ones--
+		}
+		return fmt.Sprintf("%s/%d", toIP(start), ones)
+	}
+	return fmt.Sprintf("%s-%s", toIP(start), toIP(end))
+}
+
+// geoIPSetName returns the nftables set name for an ISO country code.
+func geoIPSetName(code string) string {
+	return geoIPSetPrefix + strings.ToUpper(code)
+}
+
+// usedGeoIPCountries returns the sorted, upper-case country codes referenced
+// by enabled policy rules.
+func usedGeoIPCountries(cfg *Config) []string {
+	seen := make(map[string]bool)
+	for _, pol := range cfg.Policies {
+		if pol.Disabled {
+			continue
+		}
+		for _, rule := range pol.Rules {
+			if rule.Disabled {
+				continue
+			}
+			if rule.SourceCountry != "" {
+				seen[strings.ToUpper(rule.SourceCountry)] = true
+			}
+			if rule.DestCountry != "" {
+				seen[strings.ToUpper(rule.DestCountry)] = true
+			}
+		}
+	}
+
+	codes := make([]string, 0, len(seen))
+	for code := range seen {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// geoIPEnabled reports whether runtime GeoIP matching is configured.
+func geoIPEnabled(g *config.GeoIPConfig) bool {
+	return g != nil && g.Enabled
+}
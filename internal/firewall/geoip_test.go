@@ -0,0 +1,87 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package firewall
+
+import (
+	"reflect"
+	"testing"
+
+	"grimm.is/flywall/internal/config"
+)
+
+func TestCoalesceIPv4Ranges(t *testing.T) {
+	ip := func(a, b, c, d uint32) uint32 { return a<<24 | b<<16 | c<<8 | d }
+
+	tests := []struct {
+		name   string
+		ranges [][2]uint32
+		want   []string
+	}{
+		{
+			name: "empty",
+			want: nil,
+		},
+		{
+			name:   "single host",
+			ranges: [][2]uint32{{ip(1, 2, 3, 4), ip(1, 2, 3, 4)}},
+			want:   []string{"1.2.3.4"},
+		},
+		{
+			name: "adjacent networks merge into cidr",
+			ranges: [][2]uint32{
+				{ip(10, 0, 1, 0), ip(10, 0, 1, 255)},
+				{ip(10, 0, 0, 0), ip(10, 0, 0, 255)},
+			},
+			want: []string{"10.0.0.0/23"},
+		},
+		{
+			name: "unaligned merge becomes range",
+			ranges: [][2]uint32{
+				{ip(10, 0, 1, 0), ip(10, 0, 1, 255)},
+				{ip(10, 0, 2, 0), ip(10, 0, 2, 255)},
+			},
+			want: []string{"10.0.1.0-10.0.2.255"},
+		},
+		{
+			name: "gap keeps networks separate",
+			ranges: [][2]uint32{
+				{ip(10, 0, 0, 0), ip(10, 0, 0, 255)},
+				{ip(10, 0, 2, 0), ip(10, 0, 2, 255)},
+			},
+			want: []string{"10.0.0.0/24", "10.0.2.0/24"},
+		},
+		{
+			name:   "whole address space",
+			ranges: [][2]uint32{{0, ^uint32(0)}},
+			want:   []string{"0.0.0.0/0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := coalesceIPv4Ranges(tt.ranges)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("coalesceIPv4Ranges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsedGeoIPCountries(t *testing.T) {
+	cfg := &Config{
+		Policies: []config.Policy{
+			{Rules: []config.PolicyRule{
+				{SourceCountry: "us"},
+				{DestCountry: "CN"},
+				{SourceCountry: "US"},
+			}},
+			{Disabled: true, Rules: []config.PolicyRule{{SourceCountry: "RU"}}},
+		},
+	}
+
+	got := usedGeoIPCountries(cfg)
+	want := []string{"CN", "US"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("usedGeoIPCountries() = %v, want %v", got, want)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -46,6 +47,9 @@ type Manager struct {
 	logger   *logging.Logger
 	cacheDir string
 
+	// GeoIP database backing the geoip_country_* sets (nil until needed)
+	geoip *GeoIPManager
+
 	// Integrity restore callback
 	restoreCallback func()
 }
@@ -150,6 +154,19 @@ func (m *Manager) ApplyConfig(cfg *Config) error {
 		return fmt.Errorf("config validation failed: %w", err)
 	}
 
+	// 0b. Load the kernel modules of the conntrack helpers in use, and
+	// keep the kernel from assigning helpers on its own
+	warnings, err := loadHelperModules(config.UsedConntrackHelpers(effectiveCfg.Policies))
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		m.logger.Warn(w)
+	}
+	if err := disableAutoHelpers(); err != nil {
+		m.logger.Warn("Failed to disable automatic conntrack helpers", "error", err)
+	}
+
 	// 1. Resolve IPSets (Download/Fetch)
 	// This happens BEFORE rule generation to ensure the set
 	// elements are included in the atomic script.
@@ -263,9 +280,117 @@ func (m *Manager) resolveIPSets(cfg *Config) (map[string][]string, error) {
 			resolved[ipset.Name] = entries
 		}
 	}
+
+	// 4. GeoIP country sets
+	geoSets, err := m.resolveGeoIPSets(cfg)
+	if err != nil {
+		// A missing or unreadable database must not block the rest of the
+		// ruleset; country rules simply match nothing until it appears.
+		m.logger.Warn("GeoIP resolution failed (country sets left empty)", "error", err)
+	}
+	for name, elements := range geoSets {
+		resolved[name] = elements
+	}
 	return resolved, nil
 }
 
+// resolveGeoIPSets returns the elements for every geoip_country_* set
+// referenced by cfg, keyed by set name.
+// Caller must hold m.mu.
+func (m *Manager) resolveGeoIPSets(cfg *Config) (map[string][]string, error) {
+	codes := usedGeoIPCountries(cfg)
+	if len(codes) == 0 || !geoIPEnabled(cfg.GeoIP) {
+		return nil, nil
+	}
+
+	geo, err := m.ensureGeoIP(cfg.GeoIP.DatabasePath)
+	if err != nil {
+		return nil, err
+	}
+
+	networks, err := geo.CountryNetworks(codes)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make(map[string][]string, len(networks))
+	for code, elements := range networks {
+		resolved[geoIPSetName(code)] = elements
+		m.logger.Info("Resolved GeoIP country set", "country", code, "ranges", len(elements))
+	}
+	return resolved, nil
+}
+
+// ensureGeoIP opens the GeoIP database on first use (or when the configured
+// path changes) and wires reloads to an in-place set refresh.
+// Caller must hold m.mu.
+func (m *Manager) ensureGeoIP(dbPath string) (*GeoIPManager, error) {
+	if m.geoip != nil && (dbPath == "" || m.geoip.DatabasePath() == dbPath) {
+		return m.geoip, nil
+	}
+
+	geo, err := NewGeoIPManager(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	geo.OnReload(func() {
+		if err := m.RefreshGeoIPSets(); err != nil {
+			m.logger.Warn("Failed to refresh GeoIP sets after reload", "error", err)
+		}
+	})
+
+	if m.geoip != nil {
+		m.geoip.Close()
+	}
+	m.geoip = geo
+	return geo, nil
+}
+
+// GeoIP returns the GeoIP database backing the country sets, or nil if no
+// applied policy references a country.
+func (m *Manager) GeoIP() *GeoIPManager {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.geoip
+}
+
+// RefreshGeoIPSets rebuilds the contents of every geoip_country_* set from
+// the current database and swaps them in with a single atomic nft
+// transaction. Chains and other sets are left untouched.
+func (m *Manager) RefreshGeoIPSets() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.currentConfig == nil {
+		return nil
+	}
+
+	geoSets, err := m.resolveGeoIPSets(m.currentConfig)
+	if err != nil {
+		return err
+	}
+	if len(geoSets) == 0 {
+		return nil
+	}
+
+	sb := NewScriptBuilder(brand.LowerName, "inet", "UTC")
+	names := make([]string, 0, len(geoSets))
+	for name := range geoSets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sb.AddLine(fmt.Sprintf("flush set %s %s %s", sb.family, sb.tableName, quote(name)))
+		sb.AddSetElements(name, geoSets[name])
+	}
+
+	if err := NewAtomicApplier().ApplyScript(sb.Build()); err != nil {
+		return fmt.Errorf("failed to refresh GeoIP sets: %w", err)
+	}
+	m.logger.Info("GeoIP country sets refreshed", "sets", len(names))
+	return nil
+}
+
 // enableRouteLocalnet enables route_localnet on interfaces where Web/API access is required.
 func (m *Manager) enableRouteLocalnet(cfg *Config) error {
 	// Helper to write file
@@ -0,0 +1,89 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package firewall
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// nat64Interfaces returns the interfaces of zones with the NAT64 service
+// and of external zones, as anonymous nft sets.
+func nat64Interfaces(cfg *Config) (clients, uplinks string) {
+	if cfg.NAT64 == nil {
+		return "", ""
+	}
+	zoneMap := buildZoneMapForScript(cfg)
+	var in, out []string
+	seenIn := make(map[string]bool)
+	seenOut := make(map[string]bool)
+	for i := range cfg.Zones {
+		zone := &cfg.Zones[i]
+		ifaces := zoneMap[canonicalZoneName(zone.Name)]
+		if zone.Services != nil && zone.Services.NAT64 {
+			for _, iface := range ifaces {
+				if !seenIn[iface] {
+					seenIn[iface] = true
+					in = append(in, forceQuote(iface))
+				}
+			}
+		}
+		if isZoneExternal(zone, ifaces, cfg.Interfaces) {
+			for _, iface := range ifaces {
+				if !seenOut[iface] {
+					seenOut[iface] = true
+					out = append(out, forceQuote(iface))
+				}
+			}
+		}
+	}
+	if len(in) == 0 {
+		return "", ""
+	}
+	clients = "{ " + strings.Join(in, ", ") + " }"
+	if len(out) > 0 {
+		uplinks = "{ " + strings.Join(out, ", ") + " }"
+	}
+	return clients, uplinks
+}
+
+// addNAT64ForwardRules lets NAT64 zones send to the prefix routed into the
+// translator, and the translated IPv4 traffic out the uplinks. They run
+// after the policy dispatch, so a policy for a zone holding the translator
+// interface takes precedence.
+func addNAT64ForwardRules(sb *ScriptBuilder, cfg *Config) {
+	clients, uplinks := nat64Interfaces(cfg)
+	if clients == "" {
+		return
+	}
+	n := cfg.NAT64
+	prefix, err := netip.ParsePrefix(n.Prefix)
+	if err != nil {
+		return
+	}
+	pool, err := netip.ParsePrefix(n.Pool4)
+	if err != nil {
+		return
+	}
+	tun := forceQuote(n.Interface)
+
+	sb.AddRule("forward", fmt.Sprintf("iifname %s oifname %s ip6 daddr %s accept", clients, tun, prefix.Masked()), "[nat64] IPv6 to translator")
+	if uplinks != "" {
+		sb.AddRule("forward", fmt.Sprintf("iifname %s ip saddr %s oifname %s accept", tun, pool.Masked(), uplinks), "[nat64] Translated to uplink")
+	}
+}
+
+// addNAT64Masquerade hides the translator's IPv4 pool behind the uplink
+// addresses, which makes the translation stateful.
+func addNAT64Masquerade(sb *ScriptBuilder, cfg *Config) {
+	_, uplinks := nat64Interfaces(cfg)
+	if uplinks == "" {
+		return
+	}
+	pool, err := netip.ParsePrefix(cfg.NAT64.Pool4)
+	if err != nil {
+		return
+	}
+	sb.AddRule("postrouting", fmt.Sprintf("ip saddr %s oifname %s masquerade comment \"nat64\"", pool.Masked(), uplinks))
+}
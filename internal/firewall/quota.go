@@ -0,0 +1,75 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package firewall
+
+import (
+	"fmt"
+	"sort"
+
+	"grimm.is/flywall/internal/config"
+)
+
+// QuotaBlockedSet holds the MACs of devices that exhausted a quota with
+// action "block". It is filled at runtime by the quota manager.
+const QuotaBlockedSet = "quota_blocked"
+
+// quotaThrottleSetPrefix names the per-class sets of throttled MACs.
+const quotaThrottleSetPrefix = "quota_throttle_"
+
+// QuotaThrottleTarget returns the set a throttled device of q is added to
+// and the QoS fwmark its traffic is given. ok is false when q doesn't
+// throttle or its class doesn't exist.
+func QuotaThrottleTarget(q config.DeviceQuota, policies []config.QoSPolicy) (set string, mark uint32, ok bool) {
+	if q.Action != "throttle" {
+		return "", 0, false
+	}
+	for i, pol := range policies {
+		if pol.Name != q.QoSPolicy {
+			continue
+		}
+		for j, class := range pol.Classes {
+			if class.Name == q.QoSClass {
+				// Same scheme as the mangle QoS marks (qos.CalculateFWMark)
+				mark = uint32(0xF000 + (i << 8) + j)
+				return fmt.Sprintf("%s%x", quotaThrottleSetPrefix, mark), mark, true
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// addQuotaRules declares the quota sets and the rules that act on them.
+// Blocked devices lose forwarding, including established flows, but can
+// still reach the firewall itself. Throttled devices get their class mark
+// in a prerouting chain that runs after the mangle table, so the mark
+// reaches the egress shaper. Downloads aren't reclassified: ingress
+// shaping runs before any nftables hook.
+func addQuotaRules(sb *ScriptBuilder, cfg *Config) {
+	if len(cfg.Quotas) == 0 {
+		return
+	}
+
+	sb.AddSet(QuotaBlockedSet, "ether_addr", "[quota] Over-quota devices", 0)
+
+	marks := make(map[string]uint32)
+	for _, q := range cfg.Quotas {
+		if set, mark, ok := QuotaThrottleTarget(q, cfg.QoSPolicies); ok {
+			marks[set] = mark
+		}
+	}
+	if len(marks) == 0 {
+		return
+	}
+
+	sets := make([]string, 0, len(marks))
+	for set := range marks {
+		sets = append(sets, set)
+	}
+	sort.Strings(sets)
+
+	sb.AddChain("quota_prerouting", "filter", "prerouting", -140, "accept", "[quota] Throttle marks")
+	for _, set := range sets {
+		sb.AddSet(set, "ether_addr", "[quota] Throttled devices", 0)
+		sb.AddRule("quota_prerouting", fmt.Sprintf("ether saddr @%s meta mark set 0x%x", set, marks[set]), "[quota] Throttle")
+	}
+}
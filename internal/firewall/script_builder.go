@@ -26,6 +26,7 @@ type ScriptBuilder struct {
 	sets       []string            // Set definitions
 	maps       []string            // Map definitions
 	counters   []string            // Counter definitions
+	ctHelpers  []string            // Conntrack helper definitions
 	chainOrder []string            // Order of chains to output (preserving addition order)
 
 	// Optimization settings
@@ -161,7 +162,17 @@ func (sb *ScriptBuilder) AddCounter(name, comment string) {
 	sb.counters = append(sb.counters, def)
 }
 
-func (sb *ScriptBuilder) AddFlowtable(name string, devices []string, comment ...string) {
+// AddCtHelper declares a conntrack helper object for the kernel helper
+// helperType, which rules assign with "ct helper set".
+func (sb *ScriptBuilder) AddCtHelper(name, helperType, protocol string) {
+	def := fmt.Sprintf("add ct helper %s %s %s { type %q protocol %s; l3proto inet; }",
+		sb.family, sb.tableName, quote(name), helperType, protocol)
+	sb.ctHelpers = append(sb.ctHelpers, def)
+}
+
+// AddFlowtable declares a flowtable. flags are extra statements such as
+// "counter" or "flags offload".
+func (sb *ScriptBuilder) AddFlowtable(name string, devices, flags []string, comment ...string) {
 	// flowtable ft { hook ingress priority 0; devices = { ... }; }
 	// Need to force quote devices
 	var qDevices []string
@@ -170,6 +181,9 @@ func (sb *ScriptBuilder) AddFlowtable(name string, devices []string, comment ...
 	}
 	def := fmt.Sprintf("add flowtable %s %s %s { hook ingress priority 0; devices = { %s };",
 		sb.family, sb.tableName, name, strings.Join(qDevices, ", "))
+	for _, flag := range flags {
+		def += fmt.Sprintf(" %s;", flag)
+	}
 	if len(comment) > 0 {
 		def += fmt.Sprintf(" comment %q;", comment[0])
 	}
@@ -181,7 +195,7 @@ func (sb *ScriptBuilder) AddFlowtable(name string, devices []string, comment ...
 // The order of operations is critical for nftables:
 // 1. Tables (container)
 // 2. Sets (used by rules)
-// 3. Counters (used by rules)
+// 3. Counters and conntrack helpers (used by rules)
 // 4. Flowtables (used by rules)
 // 5. Chains (contain rules)
 // 6. Maps (may reference chains)
@@ -204,6 +218,9 @@ func (sb *ScriptBuilder) Build() string {
 	// Counters
 	lines = append(lines, sb.counters...)
 
+	// Conntrack helpers
+	lines = append(lines, sb.ctHelpers...)
+
 	// Flowtables
 	lines = append(lines, sb.flowtables...)
 
@@ -5,6 +5,7 @@ package firewall
 import (
 	"fmt"
 	"log"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -57,49 +58,32 @@ func BuildFilterTableScript(
 
 	// Flowtables (Performance Optimization)
 	// Hardware/Software Offload for established connections.
-	if cfg.EnableFlowOffload {
-		var flowDevices []string
-		for _, iface := range cfg.Interfaces {
-			// Only add interfaces that exist and are managed.
-			// Ideally we verify they support offload, but we can just try adding them.
-			flowDevices = append(flowDevices, iface.Name)
-		}
-		if len(flowDevices) > 0 {
-			sb.AddFlowtable("ft", flowDevices)
-		}
-	}
+	flowOffload := planFlowOffload(cfg)
+	addFlowtable(sb, flowOffload)
+
+	// Conntrack helpers (ALGs) assigned by policies and rules
+	addConntrackHelpers(sb, cfg)
 
 	// Add Protection Chain (Raw Prerouting)
 	addProtectionRules(cfg, sb)
 
 	// Define GeoIP sets if referenced by any policy rule
 	// We scan all policies to find used identifiers like "US", "CN", etc.
-	usedCountries := make(map[string]bool)
-	for _, pol := range cfg.Policies {
-		if pol.Disabled {
-			continue
-		}
-		for _, rule := range pol.Rules {
-			if rule.Disabled {
-				continue
-			}
-			if rule.SourceCountry != "" {
-				usedCountries[strings.ToUpper(rule.SourceCountry)] = true
-			}
-			if rule.DestCountry != "" {
-				usedCountries[strings.ToUpper(rule.DestCountry)] = true
-			}
-		}
-	}
-
-	for code := range usedCountries {
-		if len(code) != 2 {
+	// Elements are resolved from the MMDB by the Manager and passed in via
+	// resolvedIPSets under the set name; without a database the sets stay
+	// empty and country rules never match.
+	for _, code := range usedGeoIPCountries(cfg) {
+		if len(code) != 2 || !isValidIdentifier(code) {
 			return nil, fmt.Errorf("invalid country code: %s", code)
 		}
-		setName := fmt.Sprintf("geoip_country_%s", code)
+		setName := geoIPSetName(code)
 		// Add set definition: type ipv4_addr; flags interval;
 		// note: Currently only supporting IPv4 for GeoIP as per BuildRuleExpression
 		sb.AddSet(setName, "ipv4_addr", fmt.Sprintf("[geoip] %s", code), 0, "interval")
+		sb.AddLine(fmt.Sprintf("flush set %s %s %s", sb.family, sb.tableName, quote(setName)))
+		if elements := resolvedIPSets[setName]; len(elements) > 0 {
+			sb.AddSetElements(setName, elements)
+		}
 	}
 
 	// CRITICAL: Define IPSets BEFORE rules that reference them
@@ -172,6 +156,12 @@ func BuildFilterTableScript(
 	// This set is managed dynamically via RPC from the API server
 	sb.AddSet("blocked_ips", "ipv4_addr", "[ipset:blocked_ips]", 0)
 
+	// Per-device quota sets, managed dynamically by the quota manager
+	addQuotaRules(sb, cfg)
+
+	// Captive portal set and redirects, filled by the portal service
+	addCaptivePortalRules(sb, cfg)
+
 	// Create base chains with default drop policy
 	sb.AddChain("input", "filter", "input", 0, "drop", "[base] Incoming traffic")
 	sb.AddChain("forward", "filter", "forward", 0, "drop", "[base] Routed traffic")
@@ -220,6 +210,13 @@ func BuildFilterTableScript(
 	// This runs early to block malicious IPs before any accept rules
 	sb.AddRule("input", "ip saddr @blocked_ips drop", "[security] Blocked IPs")
 	sb.AddRule("forward", "ip saddr @blocked_ips drop", "[security] Blocked IPs")
+	if len(cfg.Quotas) > 0 {
+		sb.AddRule("forward", fmt.Sprintf("ether saddr @%s counter drop", QuotaBlockedSet), "[quota] Over quota")
+	}
+	addCaptivePortalForwardRules(sb, cfg)
+
+	// Established connections past the drops above may leave the ruleset
+	addFlowOffloadRules(sb, flowOffload)
 
 	sb.AddRule("input", "ct state established,related accept", "[base] Stateful")
 	sb.AddRule("forward", "ct state established,related accept", "[base] Stateful")
@@ -261,13 +258,19 @@ func BuildFilterTableScript(
 
 		// HA Heartbeat and Conntrack Sync
 		if cfg.Replication.HA != nil && cfg.Replication.HA.Enabled {
-			// Heartbeat
-			hbPort := 9002
-			if cfg.Replication.HA.HeartbeatPort > 0 {
-				hbPort = cfg.Replication.HA.HeartbeatPort
+			if cfg.Replication.HA.Protocol == "vrrp" {
+				// VRRP advertisements (IP protocol 112)
+				sb.AddRule("input", "meta l4proto 112 accept", "[ha] VRRP input")
+				sb.AddRule("output", "meta l4proto 112 accept", "[ha] VRRP output")
+			} else {
+				// Heartbeat
+				hbPort := 9002
+				if cfg.Replication.HA.HeartbeatPort > 0 {
+					hbPort = cfg.Replication.HA.HeartbeatPort
+				}
+				sb.AddRule("input", fmt.Sprintf("udp dport %d accept", hbPort), "[ha] Heartbeat input")
+				sb.AddRule("output", fmt.Sprintf("udp dport %d accept", hbPort), "[ha] Heartbeat output")
 			}
-			sb.AddRule("input", fmt.Sprintf("udp dport %d accept", hbPort), "[ha] Heartbeat input")
-			sb.AddRule("output", fmt.Sprintf("udp dport %d accept", hbPort), "[ha] Heartbeat output")
 
 			// Conntrack Sync
 			if cfg.Replication.HA.ConntrackSync != nil && cfg.Replication.HA.ConntrackSync.Enabled {
@@ -285,6 +288,8 @@ func BuildFilterTableScript(
 	// DHCP Client (WAN) and Server (LAN) - Must be before DROP_INVALID
 	sb.AddRule("input", "udp dport 67-68 accept", "[svc:dhcp] DHCP server/client")
 	sb.AddRule("output", "udp dport 67-68 accept", "[svc:dhcp] DHCP client")
+	sb.AddRule("input", "meta nfproto ipv6 udp dport 546-547 accept", "[svc:dhcp] DHCPv6 server/client")
+	sb.AddRule("output", "meta nfproto ipv6 udp dport 546-547 accept", "[svc:dhcp] DHCPv6 client")
 
 	// VPN Lockout Protection Rules (ManagementAccess = true)
 	// These rules ensure VPN traffic is ALWAYS accepted, even if other rules fail
@@ -325,12 +330,6 @@ func BuildFilterTableScript(
 		sb.AddRule("forward", "tcp flags syn tcp option maxseg size set rt mtu", "[feature] MSS clamping")
 	}
 
-	// Flowtable Offload Rule (Forward Chain)
-	// Bypasses the rest of the ruleset for established connections in the flowtable.
-	if cfg.EnableFlowOffload {
-		sb.AddRule("forward", "ip protocol { tcp, udp } flow add @ft", "[feature] Flow offload")
-	}
-
 	// Add ICMP accept rules
 	sb.AddRule("input", "meta l4proto icmp accept", "[base] ICMP")
 	sb.AddRule("input", "meta l4proto icmpv6 accept", "[base] ICMPv6")
@@ -609,6 +608,8 @@ func BuildFilterTableScript(
 		}
 	}
 
+	addCaptivePortalInputRules(sb, cfg)
+
 	// Apply Consolidated Rules
 	if len(tcpElements) > 0 {
 		sb.AddRule("input", fmt.Sprintf("iifname . tcp dport { %s } accept", strings.Join(tcpElements, ", ")), "[svc] Consolidated TCP services")
@@ -687,6 +688,7 @@ func BuildFilterTableScript(
 		Rules   []config.PolicyRule
 		Action  string   // "accept", "drop", or "reject"
 		Sources []string // Names of source policies for comments
+		Helpers []string // Conntrack helpers, without duplicates
 	}
 
 	// Use map for aggregation: key = "canonical(From)->canonical(To)"
@@ -714,6 +716,12 @@ func BuildFilterTableScript(
 		// Append rules
 		agg.Rules = append(agg.Rules, pol.Rules...)
 
+		for _, helper := range pol.Helpers {
+			if !slices.Contains(agg.Helpers, helper) {
+				agg.Helpers = append(agg.Helpers, helper)
+			}
+		}
+
 		// Update Action (Last one matches standard imperative config behavior)
 		if pol.Action != "" {
 			agg.Action = strings.ToLower(pol.Action)
@@ -779,6 +787,8 @@ func BuildFilterTableScript(
 		chainComment := fmt.Sprintf("[policy:%s->%s] sources: %s", pol.From, pol.To, sourcesStr)
 		sb.AddChain(chainName, "", "", 0, "", chainComment)
 
+		addPolicyHelperRules(sb, chainName, pol.From, pol.To, pol.Helpers)
+
 		// Add rules to policy chain
 		for i, rule := range pol.Rules {
 			if rule.Disabled {
@@ -881,6 +891,8 @@ func BuildFilterTableScript(
 		sb.AddRule("forward", "meta iifname . meta oifname vmap @forward_vmap", "[base] Policy dispatch")
 	}
 
+	addNAT64ForwardRules(sb, cfg)
+
 	// Add final drop rules (already in chain policy, but explicit for logging)
 	// Add final drop rules with rate-limited logging to prevent "Log Spam Death Spiral"
 	//
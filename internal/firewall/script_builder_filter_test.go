@@ -5,6 +5,7 @@ package firewall
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"grimm.is/flywall/internal/config"
 )
@@ -65,28 +66,136 @@ func TestFilterTableGeneration(t *testing.T) {
 }
 
 func TestFlowOffloadGeneration(t *testing.T) {
+	noOffload := false
 	cfg := &config.Config{
 		EnableFlowOffload: true,
-		Interfaces: []config.Interface{
-			{Name: "eth0"},
-			{Name: "eth1"},
+		Zones: []config.Zone{
+			{Name: "WAN", Matches: []config.RuleMatch{{Interface: "eth0"}}},
+			{Name: "LAN", Matches: []config.RuleMatch{{Interface: "eth1"}}},
+			{Name: "VoIP", Matches: []config.RuleMatch{{Interface: "eth2"}}},
+			{Name: "Mgmt", Matches: []config.RuleMatch{{Interface: "eth3"}}},
+		},
+		Policies: []config.Policy{
+			{From: "LAN", To: "WAN", Action: "accept"},
+			{From: "VoIP", To: "WAN", Action: "accept", FlowOffload: &noOffload},
+			{From: "Mgmt", To: "firewall", Action: "accept"},
 		},
 	}
 
+	restore := hwOffloadCapable
+	defer func() { hwOffloadCapable = restore }()
+	hwOffloadCapable = func(string) bool { return false }
+
 	sb, err := BuildFilterTableScript(FromGlobalConfig(cfg), nil, "test_table", "", nil)
 	if err != nil {
 		t.Fatalf("BuildFilterTableScript() error = %v", err)
 	}
 	script := sb.Build()
 
-	// Verify Flowtable Creation
-	if !strings.Contains(script, "add flowtable inet test_table ft") {
-		t.Error("Missing flowtable definition")
+	// Only forwarding zones' devices, software offload with accounting
+	if !strings.Contains(script, `add flowtable inet test_table ft { hook ingress priority 0; devices = { "eth0", "eth1", "eth2" }; counter; }`) {
+		t.Errorf("Missing or wrong flowtable definition:\n%s", script)
+	}
+
+	for _, want := range []string{
+		"add counter inet test_table cnt_flow_offload",
+		"forward meta l4proto { tcp, udp } ct state established jump flow_offload",
+		`flow_offload ct direction original iifname "eth2" oifname "eth0" return`,
+		`flow_offload ct direction reply iifname "eth0" oifname "eth2" return`,
+		"flow_offload counter name cnt_flow_offload flow add @ft",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Missing %q", want)
+		}
+	}
+
+	// Offload comes before the stateful accept, or nothing is ever offloaded
+	stateful := strings.Index(script, "forward ct state established,related accept")
+	if jump := strings.Index(script, "jump flow_offload"); jump < 0 || jump > stateful {
+		t.Error("Flow offload must come before the stateful accept")
+	}
+	if strings.Contains(script, "flags offload") {
+		t.Error("Hardware offload enabled without capable devices")
+	}
+
+	// Hardware offload once every device supports it
+	hwOffloadCapable = func(string) bool { return true }
+	sb, _ = BuildFilterTableScript(FromGlobalConfig(cfg), nil, "test_table", "", nil)
+	if !strings.Contains(sb.Build(), "counter; flags offload; }") {
+		t.Error("Missing hardware offload flag")
+	}
+	if strings.Contains(sb.Build(), "[quota]") {
+		t.Error("Quota offload exemptions generated without quotas")
+	}
+
+	// Quota devices keep going through the ruleset
+	cfg.QoSPolicies = []config.QoSPolicy{{Name: "wan", Interface: "eth0", Classes: []config.QoSClass{{Name: "slow"}}}}
+	cfg.Quotas = []config.DeviceQuota{
+		{Name: "guests", Devices: []string{"aa:bb:cc:dd:ee:01"}, Daily: "1GB"},
+		{Name: "kids", Devices: []string{"aa:bb:cc:dd:ee:02"}, Daily: "2GB", Action: "throttle", QoSPolicy: "wan", QoSClass: "slow"},
 	}
+	sb, _ = BuildFilterTableScript(FromGlobalConfig(cfg), nil, "test_table", "", nil)
+	script = sb.Build()
+	offload := strings.Index(script, "flow_offload counter name cnt_flow_offload flow add @ft")
+	for _, want := range []string{
+		"flow_offload ct direction reply return",
+		"flow_offload ether saddr @quota_throttle_f000 return",
+	} {
+		if i := strings.Index(script, want); i < 0 || i > offload {
+			t.Errorf("Missing %q before the offload rule", want)
+		}
+	}
+	cfg.Quotas, cfg.QoSPolicies = nil, nil
 
-	// Verify Flowtable Rule
-	if !strings.Contains(script, "ip protocol { tcp, udp } flow add @ft") {
-		t.Error("Missing flow offload rule")
+	// Disabled globally
+	cfg.EnableFlowOffload = false
+	sb, _ = BuildFilterTableScript(FromGlobalConfig(cfg), nil, "test_table", "", nil)
+	if script := sb.Build(); strings.Contains(script, "flowtable") || strings.Contains(script, "flow_offload") {
+		t.Error("Flow offload generated while disabled")
+	}
+}
+
+func TestConntrackHelperGeneration(t *testing.T) {
+	cfg := &config.Config{
+		Zones: []config.Zone{
+			{Name: "WAN", Matches: []config.RuleMatch{{Interface: "eth0"}}},
+			{Name: "LAN", Matches: []config.RuleMatch{{Interface: "eth1"}}},
+		},
+		Policies: []config.Policy{
+			{From: "LAN", To: "WAN", Action: "accept", Helpers: []string{"ftp", "sip"}},
+			{From: "WAN", To: "LAN", Rules: []config.PolicyRule{
+				{Name: "ftp-alt", Protocol: "tcp", DestPort: 2121, Action: "accept", Helper: "ftp"},
+				{Name: "tftp-drop", Protocol: "udp", DestPort: 69, Action: "drop", Helper: "tftp"},
+			}},
+		},
+	}
+
+	sb, err := BuildFilterTableScript(FromGlobalConfig(cfg), nil, "test_table", "", nil)
+	if err != nil {
+		t.Fatalf("BuildFilterTableScript() error = %v", err)
+	}
+	script := sb.Build()
+
+	for _, want := range []string{
+		`add ct helper inet test_table helper_ftp { type "ftp" protocol tcp; l3proto inet; }`,
+		`add ct helper inet test_table helper_sip { type "sip" protocol udp; l3proto inet; }`,
+		`ct state new tcp dport 21 ct helper set "helper_ftp"`,
+		`ct state new udp dport 5060 ct helper set "helper_sip"`,
+		`meta l4proto tcp tcp dport 2121 ct helper set "helper_ftp" counter accept`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Missing %q", want)
+		}
+	}
+
+	// Helpers are explicit only: none for drops, none nobody asked for
+	if strings.Contains(script, "helper_tftp") || strings.Contains(script, "helper_pptp") {
+		t.Errorf("Unrequested helper generated:\n%s", script)
+	}
+
+	// Objects are declared before the rules that use them
+	if decl, use := strings.Index(script, "add ct helper"), strings.Index(script, "ct helper set"); decl < 0 || decl > use {
+		t.Error("ct helper objects must be declared before use")
 	}
 }
 
@@ -200,3 +309,176 @@ func TestDNSSetFilterGeneration(t *testing.T) {
 		t.Errorf("Missing or incorrect yahoo_dns set definition (optimization check):\n%s", script)
 	}
 }
+
+func TestGeoIPSetGeneration(t *testing.T) {
+	cfg := &config.Config{
+		Zones: []config.Zone{
+			{Name: "LAN", Matches: []config.RuleMatch{{Interface: "eth1"}}},
+			{Name: "WAN", Matches: []config.RuleMatch{{Interface: "eth0"}}},
+		},
+		Policies: []config.Policy{
+			{
+				From: "WAN", To: "LAN", Action: "accept",
+				Rules: []config.PolicyRule{
+					{SourceCountry: "cn", Action: "drop"},
+					{DestCountry: "RU", Action: "drop", Disabled: true},
+				},
+			},
+		},
+	}
+
+	resolved := map[string][]string{
+		"geoip_country_CN": {"1.0.1.0/24", "1.0.2.0-1.0.3.255"},
+	}
+	sb, err := BuildFilterTableScript(FromGlobalConfig(cfg), nil, "test_table", "", resolved)
+	if err != nil {
+		t.Fatalf("BuildFilterTableScript() error = %v", err)
+	}
+	script := sb.Build()
+
+	if !strings.Contains(script, `add set inet test_table geoip_country_CN { type ipv4_addr; flags interval;`) {
+		t.Error("Missing geoip_country_CN set definition")
+	}
+	if !strings.Contains(script, "flush set inet test_table geoip_country_CN") {
+		t.Error("Missing geoip_country_CN flush")
+	}
+	if !strings.Contains(script, "add element inet test_table geoip_country_CN { 1.0.1.0/24, 1.0.2.0-1.0.3.255 }") {
+		t.Error("Missing geoip_country_CN elements")
+	}
+	if !strings.Contains(script, "ip saddr @geoip_country_CN") {
+		t.Error("Missing country match in policy rule")
+	}
+	if strings.Contains(script, "geoip_country_RU") {
+		t.Error("Disabled rule should not define a country set")
+	}
+}
+
+func TestQuotaSetGeneration(t *testing.T) {
+	cfg := &config.Config{
+		Zones: []config.Zone{
+			{Name: "LAN", Matches: []config.RuleMatch{{Interface: "eth1"}}},
+		},
+		QoSPolicies: []config.QoSPolicy{
+			{Name: "wan", Interface: "eth0", Classes: []config.QoSClass{{Name: "voip"}, {Name: "slow"}}},
+		},
+		Quotas: []config.DeviceQuota{
+			{Name: "guests", Group: "guests", Daily: "1GB"},
+			{Name: "kids", Group: "kids", Daily: "2GB", Action: "throttle", QoSPolicy: "wan", QoSClass: "slow"},
+		},
+	}
+
+	sb, err := BuildFilterTableScript(FromGlobalConfig(cfg), nil, "test_table", "", nil)
+	if err != nil {
+		t.Fatalf("BuildFilterTableScript() error = %v", err)
+	}
+	script := sb.Build()
+
+	if !strings.Contains(script, "add set inet test_table quota_blocked { type ether_addr;") {
+		t.Error("Missing quota_blocked set definition")
+	}
+	if !strings.Contains(script, "ether saddr @quota_blocked counter drop") {
+		t.Error("Missing quota block rule")
+	}
+	if !strings.Contains(script, "add set inet test_table quota_throttle_f001 { type ether_addr;") {
+		t.Error("Missing throttle set definition")
+	}
+	if !strings.Contains(script, "ether saddr @quota_throttle_f001 meta mark set 0xf001") {
+		t.Error("Missing throttle mark rule")
+	}
+
+	// No quotas, no quota sets
+	cfg.Quotas = nil
+	sb, _ = BuildFilterTableScript(FromGlobalConfig(cfg), nil, "test_table", "", nil)
+	if strings.Contains(sb.Build(), "quota_") {
+		t.Error("Quota sets generated without quotas")
+	}
+}
+
+func TestCaptivePortalRules(t *testing.T) {
+	cfg := &config.Config{
+		Zones: []config.Zone{
+			{Name: "LAN", Matches: []config.RuleMatch{{Interface: "eth1"}}},
+			{Name: "Guest", Matches: []config.RuleMatch{{Interface: "eth2"}}, Services: &config.ZoneServices{CaptivePortal: true}},
+		},
+		CaptivePortal: &config.CaptivePortalConfig{UploadLimit: "2mbit", DownloadLimit: "16mbit"},
+	}
+
+	sb, err := BuildFilterTableScript(FromGlobalConfig(cfg), nil, "test_table", "", nil)
+	if err != nil {
+		t.Fatalf("BuildFilterTableScript() error = %v", err)
+	}
+	script := sb.Build()
+
+	for _, want := range []string{
+		"add set inet test_table portal_auth { type ether_addr; flags timeout;",
+		`iifname { "eth2" } ether saddr != @portal_auth tcp dport 80 redirect to :8880`,
+		`iifname { "eth2" } ether saddr != @portal_auth meta l4proto { tcp, udp } th dport 53 redirect to :53`,
+		`iifname { "eth2" } ether saddr != @portal_auth counter reject`,
+		`iifname { "eth2" } meter portal_up { ether saddr limit rate over 244 kbytes/second burst 244 kbytes } drop`,
+		`oifname { "eth2" } meter portal_down4 { ip daddr limit rate over 1953 kbytes/second burst 1953 kbytes } drop`,
+		`iifname { "eth2" } tcp dport 8880 accept`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Missing %q", want)
+		}
+	}
+	stateful := strings.Index(script, "forward ct state established,related accept")
+	if stateful < 0 || strings.Index(script, "@portal_auth counter reject") > stateful {
+		t.Error("Portal reject must come before the stateful accept")
+	}
+
+	// No portal zone, no portal rules
+	cfg.Zones[1].Services = nil
+	sb, _ = BuildFilterTableScript(FromGlobalConfig(cfg), nil, "test_table", "", nil)
+	if strings.Contains(sb.Build(), "portal") {
+		t.Error("Portal rules generated without a portal zone")
+	}
+}
+
+func TestNAT64Rules(t *testing.T) {
+	cfg := &config.Config{
+		Zones: []config.Zone{
+			{Name: "WAN", Matches: []config.RuleMatch{{Interface: "eth0"}}},
+			{Name: "IoT6", Matches: []config.RuleMatch{{Interface: "eth3"}}, Services: &config.ZoneServices{NAT64: true}},
+		},
+	}
+	fw := FromGlobalConfig(cfg)
+
+	sb, err := BuildFilterTableScript(fw, nil, "test_table", "", nil)
+	if err != nil {
+		t.Fatalf("BuildFilterTableScript() error = %v", err)
+	}
+	script := sb.Build()
+	for _, want := range []string{
+		`iifname { "eth3" } oifname "nat64" ip6 daddr 64:ff9b::/96 accept`,
+		`iifname "nat64" ip saddr 100.127.255.0/24 oifname { "eth0" } accept`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Missing %q", want)
+		}
+	}
+
+	sb, err = BuildNATTableScript(fw, "test_nat")
+	if err != nil || sb == nil {
+		t.Fatalf("BuildNATTableScript() = %v, %v; want a NAT table for the pool", sb, err)
+	}
+	if want := `ip saddr 100.127.255.0/24 oifname { "eth0" } masquerade comment "nat64"`; !strings.Contains(sb.Build(), want) {
+		t.Errorf("Missing %q", want)
+	}
+
+	// No NAT64 zone, no NAT64 rules
+	cfg.Zones[1].Services = nil
+	sb, _ = BuildFilterTableScript(FromGlobalConfig(cfg), nil, "test_table", "", nil)
+	if strings.Contains(sb.Build(), "nat64") {
+		t.Error("NAT64 rules generated without a NAT64 zone")
+	}
+}
+
+func TestPortalAuthElement(t *testing.T) {
+	if got := PortalAuthElement("aa:bb:cc:dd:ee:01", 90*time.Minute); got != "aa:bb:cc:dd:ee:01 timeout 5400s" {
+		t.Errorf("PortalAuthElement() = %q", got)
+	}
+	if got := PortalAuthElement("aa:bb:cc:dd:ee:01", 0); got != "aa:bb:cc:dd:ee:01 timeout 1s" {
+		t.Errorf("PortalAuthElement() with no time left = %q", got)
+	}
+}
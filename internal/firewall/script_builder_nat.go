@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"net"
 	"strings"
+
+	"grimm.is/flywall/internal/config"
 )
 
 // BuildNATTableScript builds the NAT table script from config.
 func BuildNATTableScript(cfg *Config, tableName string) (*ScriptBuilder, error) {
-	if len(cfg.NAT) == 0 && (cfg.Policies == nil || len(cfg.Policies) == 0) {
+	if len(cfg.NAT) == 0 && (cfg.Policies == nil || len(cfg.Policies) == 0) && cfg.NAT64 == nil {
 		return nil, nil
 	}
 
@@ -106,7 +108,7 @@ func BuildNATTableScript(cfg *Config, tableName string) (*ScriptBuilder, error)
 					seenMasq[r.OutInterface] = true
 				}
 
-			} else if r.Type == "dnat" && (r.ToIP != "" || r.ToPort != "") {
+			} else if r.Type == "dnat" && (len(r.Backends()) > 0 || r.ToPort != "") {
 				// DNAT
 				matchExpr := match
 				if ifaceName != "" {
@@ -122,16 +124,17 @@ func BuildNATTableScript(cfg *Config, tableName string) (*ScriptBuilder, error)
 					matchExpr += fmt.Sprintf(" %s dport %s", proto, r.DestPort)
 				}
 
-				target := ""
-				if r.ToIP != "" && r.ToPort != "" {
-					target = fmt.Sprintf("%s:%s", r.ToIP, r.ToPort)
-				} else if r.ToIP != "" {
-					target = r.ToIP
-				} else if r.ToPort != "" {
-					target = fmt.Sprintf(":%s", r.ToPort)
-				}
+				sb.AddRule("prerouting", fmt.Sprintf("%s %s%s", strings.TrimSpace(matchExpr), dnatStatement(r), commentSuffix))
 
-				sb.AddRule("prerouting", fmt.Sprintf("%s dnat to %s%s", strings.TrimSpace(matchExpr), target, commentSuffix))
+			} else if r.Type == "netmap" && r.DestIP != "" && r.ToIP != "" {
+				// 1:1 NAT between two equally sized prefixes, both directions.
+				// Inbound: external prefix -> internal prefix
+				dnatMatch := match
+				if ifaceName != "" {
+					dnatMatch = fmt.Sprintf("iifname \"%s\" %s", ifaceName, match)
+				}
+				sb.AddRule("prerouting", fmt.Sprintf("%sdnat ip prefix to %s%s", dnatMatch, r.ToIP, commentSuffix))
+				// Outbound half is added once, after the loop
 
 			} else if r.Type == "snat" && r.OutInterface != "" && r.SNATIP != "" {
 				// SNAT
@@ -144,7 +147,7 @@ func BuildNATTableScript(cfg *Config, tableName string) (*ScriptBuilder, error)
 			}
 
 			// Hairpin NAT (Reflected)
-			if r.Type == "dnat" && r.Hairpin && r.ToIP != "" {
+			if r.Type == "dnat" && r.Hairpin && len(r.Backends()) > 0 {
 				// Resolve WAN IPs
 				var hairpinIPs []string
 				if r.DestIP != "" {
@@ -188,12 +191,7 @@ func BuildNATTableScript(cfg *Config, tableName string) (*ScriptBuilder, error)
 							hairpinMatch = fmt.Sprintf("iifname != \"%s\" %s", ifaceName, hairpinMatch)
 						}
 
-						target := fmt.Sprintf("%s:%s", r.ToIP, r.ToPort)
-						if r.ToPort == "" {
-							target = r.ToIP
-						}
-
-						sb.AddRule("prerouting", fmt.Sprintf("%sdnat to %s comment \"hairpin: %s\"", hairpinMatch, target, r.Name))
+						sb.AddRule("prerouting", fmt.Sprintf("%s%s comment \"hairpin: %s\"", hairpinMatch, dnatStatement(r), r.Name))
 
 						// 2. Hairpin SNAT (Masquerade)
 						masqMatch := ""
@@ -201,7 +199,11 @@ func BuildNATTableScript(cfg *Config, tableName string) (*ScriptBuilder, error)
 							masqMatch += fmt.Sprintf("iifname != \"%s\" ", ifaceName)
 						}
 
-						masqMatch += fmt.Sprintf("ip daddr %s ", r.ToIP)
+						if backends := r.Backends(); len(backends) == 1 {
+							masqMatch += fmt.Sprintf("ip daddr %s ", backends[0])
+						} else {
+							masqMatch += fmt.Sprintf("ip daddr { %s } ", strings.Join(backends, ", "))
+						}
 						if r.ToPort != "" {
 							proto := r.Protocol
 							if proto == "" || proto == "any" {
@@ -215,6 +217,24 @@ func BuildNATTableScript(cfg *Config, tableName string) (*ScriptBuilder, error)
 				}
 			}
 		} // end ifaceName loop
+
+		// Netmap outbound: internal prefix -> external prefix. It doesn't
+		// depend on the inbound interface, so one rule covers the zone.
+		if r.Type == "netmap" && r.DestIP != "" && r.ToIP != "" {
+			snatMatch := ""
+			if r.OutInterface != "" {
+				snatMatch += fmt.Sprintf("oifname \"%s\" ", r.OutInterface)
+			}
+			if r.Protocol != "" && r.Protocol != "any" {
+				snatMatch += fmt.Sprintf("meta l4proto %s ", r.Protocol)
+			}
+			snatMatch += fmt.Sprintf("ip saddr %s ", r.ToIP)
+			commentSuffix := ""
+			if r.Description != "" {
+				commentSuffix = fmt.Sprintf(" comment %q", r.Description)
+			}
+			sb.AddRule("postrouting", fmt.Sprintf("%ssnat ip prefix to %s%s", snatMatch, r.DestIP, commentSuffix))
+		}
 	}
 
 	// 1b. Policy-based auto-masquerade
@@ -259,6 +279,9 @@ func BuildNATTableScript(cfg *Config, tableName string) (*ScriptBuilder, error)
 		}
 	}
 
+	// 1c. NAT64 pool
+	addNAT64Masquerade(sb, cfg)
+
 	// 2. Auto-generated Web UI Access rules
 	// Sandbox mode (privilege separation via network namespace) is enabled by default.
 	// Sandbox mode (privilege separation via network namespace) is enabled by default.
@@ -313,3 +336,51 @@ func BuildNATTableScript(cfg *Config, tableName string) (*ScriptBuilder, error)
 	}
 	return sb, nil
 }
+
+// dnatStatement renders the DNAT statement for a rule. Multiple backends
+// are selected with numgen (round robin) or jhash (sticky per source IP),
+// and an equally sized to_port range is compiled to a per-port dport map.
+func dnatStatement(r config.NATRule) string {
+	proto := r.Protocol
+	if proto == "" || proto == "any" {
+		proto = "tcp"
+	}
+
+	backends := r.Backends()
+	if len(backends) > 1 {
+		selector := fmt.Sprintf("numgen inc mod %d", len(backends))
+		if r.LoadBalance == config.NATBalanceHash {
+			selector = fmt.Sprintf("jhash ip saddr mod %d seed 0x%x", len(backends), config.NATHashSeed)
+		}
+
+		elems := make([]string, len(backends))
+		for i, ip := range backends {
+			elems[i] = fmt.Sprintf("%d : %s", i, ip)
+			if r.ToPort != "" {
+				elems[i] += " . " + r.ToPort
+			}
+		}
+		if r.ToPort != "" {
+			return fmt.Sprintf("dnat ip addr . port to %s map { %s }", selector, strings.Join(elems, ", "))
+		}
+		return fmt.Sprintf("dnat to %s map { %s }", selector, strings.Join(elems, ", "))
+	}
+
+	port := r.ToPort
+	if from, to, ok := r.PortRemap(); ok && from.Start != to.Start {
+		elems := make([]string, 0, from.End-from.Start+1)
+		for p := from.Start; p <= from.End; p++ {
+			elems = append(elems, fmt.Sprintf("%d : %d", p, to.Start+p-from.Start))
+		}
+		port = fmt.Sprintf("%s dport map { %s }", proto, strings.Join(elems, ", "))
+	}
+
+	switch {
+	case len(backends) == 1 && port != "":
+		return fmt.Sprintf("dnat to %s:%s", backends[0], port)
+	case len(backends) == 1:
+		return fmt.Sprintf("dnat to %s", backends[0])
+	default:
+		return fmt.Sprintf("dnat to :%s", port)
+	}
+}
@@ -44,3 +44,112 @@ func TestNATTableGeneration(t *testing.T) {
 		t.Errorf("DNAT rule missing. Got:\n%s", script)
 	}
 }
+
+func TestNATTableGeneration_Netmap(t *testing.T) {
+	cfg := &Config{
+		NAT: []config.NATRule{
+			{
+				Name:         "one-to-one",
+				Type:         "netmap",
+				InInterface:  "eth0",
+				OutInterface: "eth0",
+				DestIP:       "203.0.113.8/29",
+				ToIP:         "192.168.10.8/29",
+			},
+		},
+	}
+
+	sb, err := BuildNATTableScript(cfg, "flywall")
+	if err != nil {
+		t.Fatalf("BuildNATTableScript error: %v", err)
+	}
+	script := sb.Build()
+
+	if !strings.Contains(script, `iifname "eth0" ip daddr 203.0.113.8/29 dnat ip prefix to 192.168.10.8/29`) {
+		t.Errorf("Inbound netmap rule missing. Got:\n%s", script)
+	}
+	if !strings.Contains(script, `oifname "eth0" ip saddr 192.168.10.8/29 snat ip prefix to 203.0.113.8/29`) {
+		t.Errorf("Outbound netmap rule missing. Got:\n%s", script)
+	}
+
+	// A zone expands the inbound half per interface; the outbound half
+	// stays a single rule.
+	cfg.Zones = []config.Zone{{Name: "wan", Matches: []config.RuleMatch{{Interface: "eth0"}, {Interface: "eth1"}}}}
+	cfg.NAT[0].InInterface = "wan"
+	sb, err = BuildNATTableScript(cfg, "flywall")
+	if err != nil {
+		t.Fatalf("BuildNATTableScript error: %v", err)
+	}
+	script = sb.Build()
+	if n := strings.Count(script, "dnat ip prefix to 192.168.10.8/29"); n != 2 {
+		t.Errorf("got %d inbound netmap rules, want one per zone interface", n)
+	}
+	if n := strings.Count(script, "snat ip prefix to 203.0.113.8/29"); n != 1 {
+		t.Errorf("got %d outbound netmap rules, want 1. Got:\n%s", n, script)
+	}
+}
+
+func TestNATTableGeneration_LoadBalance(t *testing.T) {
+	cfg := &Config{
+		NAT: []config.NATRule{
+			{
+				Name:     "web-rr",
+				Type:     "dnat",
+				Protocol: "tcp",
+				DestPort: "80",
+				ToIP:     "10.0.0.10",
+				ToIPs:    []string{"10.0.0.11"},
+			},
+			{
+				Name:        "web-hash",
+				Type:        "dnat",
+				Protocol:    "tcp",
+				DestPort:    "443",
+				ToIP:        "10.0.0.10",
+				ToIPs:       []string{"10.0.0.11", "10.0.0.12"},
+				ToPort:      "8443",
+				LoadBalance: config.NATBalanceHash,
+			},
+		},
+	}
+
+	sb, err := BuildNATTableScript(cfg, "flywall")
+	if err != nil {
+		t.Fatalf("BuildNATTableScript error: %v", err)
+	}
+	script := sb.Build()
+
+	if !strings.Contains(script, `tcp dport 80 dnat to numgen inc mod 2 map { 0 : 10.0.0.10, 1 : 10.0.0.11 }`) {
+		t.Errorf("Round-robin DNAT rule missing. Got:\n%s", script)
+	}
+	want := `tcp dport 443 dnat ip addr . port to jhash ip saddr mod 3 seed 0x464c5957 map { 0 : 10.0.0.10 . 8443, 1 : 10.0.0.11 . 8443, 2 : 10.0.0.12 . 8443 }`
+	if !strings.Contains(script, want) {
+		t.Errorf("Hash DNAT rule missing. Got:\n%s", script)
+	}
+}
+
+func TestNATTableGeneration_PortRemap(t *testing.T) {
+	cfg := &Config{
+		NAT: []config.NATRule{
+			{
+				Name:     "remap",
+				Type:     "dnat",
+				Protocol: "udp",
+				DestPort: "10000-10002",
+				ToIP:     "10.0.0.20",
+				ToPort:   "20000-20002",
+			},
+		},
+	}
+
+	sb, err := BuildNATTableScript(cfg, "flywall")
+	if err != nil {
+		t.Fatalf("BuildNATTableScript error: %v", err)
+	}
+	script := sb.Build()
+
+	want := `udp dport 10000-10002 dnat to 10.0.0.20:udp dport map { 10000 : 20000, 10001 : 20001, 10002 : 20002 }`
+	if !strings.Contains(script, want) {
+		t.Errorf("Port remap rule missing. Got:\n%s", script)
+	}
+}
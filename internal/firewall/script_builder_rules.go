@@ -137,7 +137,7 @@ func BuildRuleExpression(rule config.PolicyRule, timezone string) (string, error
 				if svc.Port > 0 {
 					rule.DestPort = svc.Port
 				} else if len(svc.Ports) > 0 {
-					rule.DestPorts = svc.Ports
+					rule.DestPorts = config.PortSpecs(svc.Ports...)
 				}
 			}
 		}
@@ -224,20 +224,20 @@ func BuildRuleExpression(rule config.PolicyRule, timezone string) (string, error
 		match += fmt.Sprintf("%s dport %d", proto, rule.DestPort)
 	}
 
-	// Multi-port matching
+	// Multi-port matching (single ports, ranges and mixed sets)
 	if len(rule.SrcPorts) > 0 {
 		proto := rule.Protocol
 		if proto == "" || proto == "any" {
 			proto = "tcp"
 		}
-		var pStrs []string
-		for _, p := range rule.SrcPorts {
-			pStrs = append(pStrs, fmt.Sprintf("%d", p))
+		set, err := buildPortSet(rule.SrcPorts)
+		if err != nil {
+			return "", fmt.Errorf("invalid source ports: %w", err)
 		}
 		if match != "" {
 			match += " "
 		}
-		match += fmt.Sprintf("%s sport { %s }", proto, strings.Join(pStrs, ", "))
+		match += fmt.Sprintf("%s sport %s", proto, set)
 	}
 
 	if len(rule.DestPorts) > 0 {
@@ -245,14 +245,14 @@ func BuildRuleExpression(rule config.PolicyRule, timezone string) (string, error
 		if proto == "" || proto == "any" {
 			proto = "tcp"
 		}
-		var pStrs []string
-		for _, p := range rule.DestPorts {
-			pStrs = append(pStrs, fmt.Sprintf("%d", p))
+		set, err := buildPortSet(rule.DestPorts)
+		if err != nil {
+			return "", fmt.Errorf("invalid destination ports: %w", err)
 		}
 		if match != "" {
 			match += " "
 		}
-		match += fmt.Sprintf("%s dport { %s }", proto, strings.Join(pStrs, ", "))
+		match += fmt.Sprintf("%s dport %s", proto, set)
 	}
 
 	if match != "" {
@@ -355,6 +355,14 @@ func BuildRuleExpression(rule config.PolicyRule, timezone string) (string, error
 		parts = append(parts, fmt.Sprintf(`limit rate 10/minute log group 0 prefix "DROP_RULE: "`))
 	}
 
+	// Conntrack helper for the accepted connection
+	if rule.Helper != "" && action == "accept" {
+		if _, ok := config.ConntrackHelpers[rule.Helper]; !ok {
+			return "", fmt.Errorf("unknown conntrack helper: %s", rule.Helper)
+		}
+		parts = append(parts, fmt.Sprintf("ct helper set %q", conntrackHelperObject(rule.Helper)))
+	}
+
 	// Add counter for observability (required for sparklines)
 	// Named counter if specified, anonymous otherwise
 	if rule.Counter != "" {
@@ -455,3 +463,18 @@ func addProtectionRules(cfg *Config, sb *ScriptBuilder) {
 		}
 	}
 }
+
+// buildPortSet renders a port list as an anonymous nft set, e.g.
+// "{ 6881-6999, 51413 }". Ranges are allowed because anonymous sets get the
+// interval flag implicitly.
+func buildPortSet(specs []config.PortSpec) (string, error) {
+	ranges, err := config.ParsePortSpecs(specs)
+	if err != nil {
+		return "", err
+	}
+	elems := make([]string, len(ranges))
+	for i, r := range ranges {
+		elems[i] = r.String()
+	}
+	return fmt.Sprintf("{ %s }", strings.Join(elems, ", ")), nil
+}
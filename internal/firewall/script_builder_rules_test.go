@@ -60,7 +60,32 @@ func TestRuleExpressionGeneration(t *testing.T) {
 			},
 			want: "meta l4proto udp ip saddr 10.0.0.0/24 ct state new udp dport 53 counter accept",
 		},
+		{
+			name: "Port Range And Set",
+			rule: config.PolicyRule{
+				Protocol:  "tcp",
+				DestPorts: []config.PortSpec{"6881-6999", "51413"},
+				Action:    "accept",
+			},
+			want: "meta l4proto tcp tcp dport { 6881-6999, 51413 } counter accept",
+		},
+		{
+			name: "Mixed Set In One Entry",
+			rule: config.PolicyRule{
+				Protocol: "udp",
+				SrcPorts: []config.PortSpec{"1024-2048, 4000"},
+				Action:   "accept",
+			},
+			want: "meta l4proto udp udp sport { 1024-2048, 4000 } counter accept",
+		},
 		// Sad Paths
+		{
+			name: "Invalid Port Range",
+			rule: config.PolicyRule{
+				DestPorts: []config.PortSpec{"9000-8000"},
+			},
+			wantErr: true,
+		},
 		{
 			name: "Invalid IPSet Name",
 			rule: config.PolicyRule{
@@ -96,7 +96,7 @@ func TestScriptBuilderComments(t *testing.T) {
 	t.Run("FlowtableWithComment", func(t *testing.T) {
 		sb := NewScriptBuilder("test", "inet", "UTC")
 		sb.AddTable()
-		sb.AddFlowtable("ft", []string{"eth0"}, "[feature] Flow offload")
+		sb.AddFlowtable("ft", []string{"eth0"}, nil, "[feature] Flow offload")
 		script := sb.Build()
 
 		if !strings.Contains(script, `comment "[feature] Flow offload"`) {
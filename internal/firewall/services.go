@@ -589,18 +589,3 @@ func SetConntrackMax(max int) error {
 		0644,
 	)
 }
-
-// ConntrackEntry represents a single connection tracking entry.
-type ConntrackEntry struct {
-	Protocol string
-	SrcIP    string
-	DstIP    string
-	SrcPort  uint16
-	DstPort  uint16
-	State    string
-	Timeout  uint32
-}
-
-// GetConntrackEntries is implemented in platform-specific files:
-// - conntrack_linux.go: uses ti-mo/conntrack netlink library
-// - conntrack_stub.go: stub for non-Linux platforms
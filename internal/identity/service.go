@@ -300,6 +300,32 @@ func (s *Service) GetGroups() []DeviceGroup {
 	return groups
 }
 
+// GroupMACs returns the MAC addresses of every device in a group.
+// The group may be given by ID or name.
+func (s *Service) GroupMACs(group string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	groupID := ""
+	for id, g := range s.groups {
+		if id == group || g.Name == group {
+			groupID = id
+			break
+		}
+	}
+	if groupID == "" {
+		return nil
+	}
+
+	var macs []string
+	for _, ident := range s.identities {
+		if ident.GroupID == groupID {
+			macs = append(macs, ident.MACs...)
+		}
+	}
+	return macs
+}
+
 // UpdateGroup creates or updates a group
 func (s *Service) UpdateGroup(g DeviceGroup) error {
 	s.mu.Lock()
@@ -4,6 +4,7 @@ package imports
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"grimm.is/flywall/internal/config"
@@ -200,15 +201,14 @@ func (r *ImportResult) ToConfig(mappings map[string]string) *config.Config {
 
 		// Ports
 		if rule.DestPort != "" {
-			// Try to parse as int
-			var port int
-			if _, err := fmt.Sscanf(rule.DestPort, "%d", &port); err == nil {
+			if port, err := strconv.Atoi(strings.TrimSpace(rule.DestPort)); err == nil {
 				newRule.DestPort = port
 			} else {
-				// Might be range or list, which PolicyRule supports via DestPorts []int,
-				// but input is string. For now, if simple parse fails, maybe ignore or log?
-				// To keep it simple, we ignore complex port strings for now or try to parse ranges if needed.
-				// Since rule.DestPort is string from import, we might need a helper.
+				// Ranges ("80-90" or "80:90") and lists map onto DestPorts.
+				spec := config.PortSpec(strings.ReplaceAll(rule.DestPort, ":", "-"))
+				if _, err := spec.Ranges(); err == nil {
+					newRule.DestPorts = []config.PortSpec{spec}
+				}
 			}
 		}
 
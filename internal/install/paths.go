@@ -105,6 +105,12 @@ func GetConfigDir() string {
 	return DefaultConfigDir
 }
 
+// GetHooksDir returns the directory that exec notification channels must
+// run their commands from.
+func GetHooksDir() string {
+	return filepath.Join(GetConfigDir(), "hooks.d")
+}
+
 // GetCacheDir returns the cache directory, checking env vars first.
 // Priority: FLYWALL_CACHE_DIR > FLYWALL_PREFIX/cache > DefaultCacheDir
 func GetCacheDir() string {
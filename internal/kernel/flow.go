@@ -22,6 +22,7 @@ type Flow struct {
 	DstPort   uint16    // Destination port
 	Protocol  string    // "tcp", "udp", "icmp"
 	State     FlowState // Connection state
+	Helper    string    // Conntrack helper (ALG), "" for none
 	Packets   uint64    // Packet count
 	Bytes     uint64    // Byte count
 	StartTime time.Time // Connection start time
@@ -211,10 +211,10 @@ func (s *SimKernel) InjectPacket(packet gopacket.Packet) bool {
 	}
 
 	var verdict engine.Verdict = engine.VerdictAccept
-	var ruleID string
+	var ruleID, helper string
 
 	if s.Engine != nil {
-		verdict, ruleID = s.Engine.Evaluate(pktInfo)
+		verdict, ruleID, helper = s.Engine.EvaluateConn(pktInfo)
 	}
 
 	// Update Rule Stats
@@ -246,6 +246,7 @@ func (s *SimKernel) InjectPacket(packet gopacket.Packet) bool {
 			DstPort:   dstPort,
 			Protocol:  protocol,
 			State:     FlowStateNew,
+			Helper:    helper,
 			StartTime: now,
 		}
 		s.FlowTable[key] = flow
@@ -994,6 +994,16 @@ func (e *Engine) GetFlow(id int64) (*flowdb.FlowWithHints, error) {
 	return e.db.GetFlowWithHints(id)
 }
 
+// LookupFlow returns the learned flow for a fingerprint without recording
+// the packet, or nil if the flow has not been seen. Used by packet traces.
+func (e *Engine) LookupFlow(srcMAC, protocol string, dstPort int) (*flowdb.Flow, error) {
+	if entry, ok := e.flowCache.Get(srcMAC, protocol, dstPort); ok {
+		flow := *entry.Flow
+		return &flow, nil
+	}
+	return e.db.FindFlow(srcMAC, protocol, dstPort)
+}
+
 // ListFlows returns flows with full options (search, pagination)
 func (e *Engine) ListFlows(opts flowdb.ListOptions) ([]flowdb.FlowWithHints, error) {
 	return e.db.ListFlowsWithHints(opts)
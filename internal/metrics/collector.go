@@ -35,6 +35,10 @@ type Collector struct {
 	systemStats    *SystemStats
 	conntrackStats *ConntrackStats
 	vpnStats       map[string]map[string]*PeerStats // Interface -> PublicKey -> Stats
+	qosStats       []QoSClassStats
+
+	// Traffic shaping statistics source (nil when QoS is not running)
+	qosProvider QoSStatsProvider
 
 	// Baseline persistence for restart continuity
 	baselineBucket BaselinePersister
@@ -63,6 +67,25 @@ type BaselinePersister interface {
 	LoadPolicyBaseline(key string) (packets, bytes uint64, err error)
 }
 
+// QoSStatsProvider reports per-class traffic shaping counters.
+// This is satisfied by qos.Manager.
+type QoSStatsProvider interface {
+	QoSStats() ([]QoSClassStats, error)
+}
+
+// QoSClassStats holds counters for one shaped traffic class.
+type QoSClassStats struct {
+	Interface  string `json:"interface"` // Shaped device (the IFB device for ingress)
+	Policy     string `json:"policy"`
+	Class      string `json:"class"`
+	Direction  string `json:"direction"` // "egress" or "ingress"
+	Bytes      uint64 `json:"bytes"`
+	Packets    uint64 `json:"packets"`
+	Drops      uint64 `json:"drops"`
+	Overlimits uint64 `json:"overlimits"`
+	Backlog    uint64 `json:"backlog"` // Bytes queued
+}
+
 // InterfaceStats holds traffic statistics for a network interface.
 type InterfaceStats struct {
 	Name      string  `json:"name"`
@@ -185,6 +208,11 @@ type ConntrackStats struct {
 	InsertFailed uint64 `json:"insert_failed"`
 	Drop         uint64 `json:"drop"`
 	EarlyDrop    uint64 `json:"early_drop"`
+
+	// Flow offload (zero unless enable_flow_offload is set)
+	Offloaded     int    `json:"offloaded"`
+	HWOffloaded   int    `json:"hw_offloaded"`
+	OffloadsTotal uint64 `json:"offloads_total"`
 }
 
 // NewCollector creates a new metrics collector.
@@ -209,6 +237,13 @@ func (c *Collector) SetBaselinePersister(bp BaselinePersister) {
 	c.baselineBucket = bp
 }
 
+// SetQoSStatsProvider sets the source of per-class QoS statistics.
+func (c *Collector) SetQoSStatsProvider(p QoSStatsProvider) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.qosProvider = p
+}
+
 // Start begins the metrics collection loop.
 func (c *Collector) Start() {
 	c.logger.Info("Starting metrics collector", "interval", c.interval.String())
@@ -331,6 +366,11 @@ func (c *Collector) collectMetrics() {
 		c.logger.Warn("Failed to collect conntrack stats", "error", err)
 	}
 
+	// Collect flowtable offload statistics
+	if err := c.collectFlowOffloadStats(ctx); err != nil {
+		c.logger.Warn("Failed to collect flow offload stats", "error", err)
+	}
+
 	// Collect system statistics
 	if err := c.collectSystemStats(ctx); err != nil {
 		c.logger.Warn("Failed to collect system stats", "error", err)
@@ -341,6 +381,11 @@ func (c *Collector) collectMetrics() {
 		c.logger.Warn("Failed to collect VPN stats", "error", err)
 	}
 
+	// Collect QoS class statistics
+	if err := c.collectQoSStats(ctx); err != nil {
+		c.logger.Warn("Failed to collect QoS stats", "error", err)
+	}
+
 	c.lastUpdate = clock.Now()
 }
 
@@ -634,6 +679,34 @@ func (c *Collector) collectConntrackStats(_ context.Context) error {
 	return nil
 }
 
+// flowOffloadCounter is the named counter the firewall increments when it
+// offloads a connection (firewall.FlowOffloadCounter).
+const flowOffloadCounter = "cnt_flow_offload"
+
+// collectFlowOffloadStats counts connections in the flowtable. The
+// conntrack table is only walked while the ruleset has offload enabled.
+func (c *Collector) collectFlowOffloadStats(_ context.Context) error {
+	nftStats, err := collectNFTablesNative("flywall")
+	if err != nil {
+		return err
+	}
+	var software, hardware int
+	total, ok := nftStats.Counters[flowOffloadCounter]
+	if ok {
+		if software, hardware, err = countOffloadedFlows(); err != nil {
+			return err
+		}
+	}
+	c.conntrackStats.Offloaded = software + hardware
+	c.conntrackStats.HWOffloaded = hardware
+	c.conntrackStats.OffloadsTotal = total
+
+	c.registry.FlowsOffloaded.WithLabelValues("software").Set(float64(software))
+	c.registry.FlowsOffloaded.WithLabelValues("hardware").Set(float64(hardware))
+	c.registry.FlowOffloadTotal.Set(float64(total))
+	return nil
+}
+
 // collectSystemStats gathers system-level statistics.
 func (c *Collector) collectSystemStats(_ context.Context) error {
 	// Read uptime
@@ -916,3 +989,40 @@ func (c *Collector) collectVPNStats(_ context.Context) error {
 	c.vpnStats = newStats
 	return nil
 }
+
+// GetQoSStats returns a copy of the current QoS class statistics.
+func (c *Collector) GetQoSStats() []QoSClassStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := make([]QoSClassStats, len(c.qosStats))
+	copy(result, c.qosStats)
+	return result
+}
+
+// collectQoSStats reads per-class counters from the QoS manager.
+func (c *Collector) collectQoSStats(_ context.Context) error {
+	if c.qosProvider == nil {
+		return nil
+	}
+
+	stats, err := c.qosProvider.QoSStats()
+	if err != nil {
+		return err
+	}
+	c.qosStats = stats
+
+	// Reset so classes removed by a reload don't linger
+	c.registry.QoSClassBytes.Reset()
+	c.registry.QoSClassPackets.Reset()
+	c.registry.QoSClassDrops.Reset()
+	c.registry.QoSClassBacklog.Reset()
+	for _, s := range stats {
+		labels := []string{s.Interface, s.Policy, s.Class, s.Direction}
+		c.registry.QoSClassBytes.WithLabelValues(labels...).Set(float64(s.Bytes))
+		c.registry.QoSClassPackets.WithLabelValues(labels...).Set(float64(s.Packets))
+		c.registry.QoSClassDrops.WithLabelValues(labels...).Set(float64(s.Drops))
+		c.registry.QoSClassBacklog.WithLabelValues(labels...).Set(float64(s.Backlog))
+	}
+	return nil
+}
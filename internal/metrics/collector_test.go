@@ -3,6 +3,7 @@
 package metrics
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -129,3 +130,30 @@ func TestIncrementConfigReload(t *testing.T) {
 		t.Errorf("Expected final counts (1, 1), got (%d, %d)", success, failure)
 	}
 }
+
+type fakeQoSProvider struct {
+	stats []QoSClassStats
+}
+
+func (f *fakeQoSProvider) QoSStats() ([]QoSClassStats, error) { return f.stats, nil }
+
+func TestCollector_QoSStats(t *testing.T) {
+	c := NewCollector(logging.New(logging.DefaultConfig()), time.Minute)
+
+	// No provider: nothing collected
+	if err := c.collectQoSStats(context.Background()); err != nil || len(c.GetQoSStats()) != 0 {
+		t.Fatalf("Expected no QoS stats without a provider, got %v (%v)", c.GetQoSStats(), err)
+	}
+
+	c.SetQoSStatsProvider(&fakeQoSProvider{stats: []QoSClassStats{
+		{Interface: "ifb4eth0", Policy: "wan", Class: "bulk", Direction: "ingress", Bytes: 1500, Drops: 3, Backlog: 600},
+	}})
+	if err := c.collectQoSStats(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := c.GetQoSStats()
+	if len(stats) != 1 || stats[0].Drops != 3 || stats[0].Backlog != 600 {
+		t.Errorf("Unexpected QoS stats: %+v", stats)
+	}
+}
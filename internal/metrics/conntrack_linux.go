@@ -0,0 +1,37 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+//go:build linux
+
+package metrics
+
+import (
+	"github.com/ti-mo/conntrack"
+)
+
+// statusHWOffload is IPS_HW_OFFLOAD, set alongside IPS_OFFLOAD when the
+// NIC carries the flow.
+const statusHWOffload conntrack.Status = 1 << 15
+
+// countOffloadedFlows counts conntrack entries offloaded to a flowtable
+// in software and in hardware.
+func countOffloadedFlows() (software, hardware int, err error) {
+	conn, err := conntrack.Dial(nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer conn.Close()
+
+	flows, err := conn.Dump(nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, f := range flows {
+		switch {
+		case f.Status&statusHWOffload != 0:
+			hardware++
+		case f.Status.Offload():
+			software++
+		}
+	}
+	return software, hardware, nil
+}
@@ -0,0 +1,10 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+//go:build !linux
+
+package metrics
+
+// countOffloadedFlows is a no-op on non-Linux platforms.
+func countOffloadedFlows() (software, hardware int, err error) {
+	return 0, 0, nil
+}
@@ -63,6 +63,15 @@ func collectNFTablesNative(tableName string) (*NFTStats, error) {
 		return stats, nil
 	}
 
+	// Collect named counters (nft list counters)
+	if objs, err := conn.GetObjects(targetTable); err == nil {
+		for _, obj := range objs {
+			if counter, ok := obj.(*nftables.CounterObj); ok {
+				stats.Counters[counter.Name] = counter.Packets
+			}
+		}
+	}
+
 	// Collect set statistics
 	sets, err := conn.GetSets(targetTable)
 	if err == nil {
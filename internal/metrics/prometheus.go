@@ -37,6 +37,10 @@ type Registry struct {
 	ConntrackNew     prometheus.Counter
 	ConntrackDestroy prometheus.Counter
 
+	// Flow offload metrics
+	FlowsOffloaded   *prometheus.GaugeVec
+	FlowOffloadTotal prometheus.Gauge
+
 	// NAT metrics
 	NATTranslations *prometheus.CounterVec
 	NATErrors       *prometheus.CounterVec
@@ -60,6 +64,12 @@ type Registry struct {
 	DNSCacheMisses prometheus.Counter
 	DNSBlocked     prometheus.Counter
 
+	// QoS metrics
+	QoSClassBytes   *prometheus.GaugeVec
+	QoSClassPackets *prometheus.GaugeVec
+	QoSClassDrops   *prometheus.GaugeVec
+	QoSClassBacklog *prometheus.GaugeVec
+
 	// System metrics
 	Uptime       prometheus.Gauge
 	ConfigReload *prometheus.CounterVec
@@ -151,6 +161,17 @@ func newRegistry() *Registry {
 		Help: "Total connections removed from tracking",
 	})
 
+	// Flow offload
+	r.FlowsOffloaded = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "firewall_flows_offloaded",
+		Help: "Connections currently offloaded to the flowtable",
+	}, []string{"mode"})
+
+	r.FlowOffloadTotal = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "firewall_flow_offload_total",
+		Help: "Connections handed to the flowtable since the ruleset was loaded",
+	})
+
 	// NAT metrics
 	r.NATTranslations = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "firewall_nat_translations_total",
@@ -230,6 +251,28 @@ func newRegistry() *Registry {
 		Help: "Total DNS queries blocked",
 	})
 
+	// QoS metrics
+	qosLabels := []string{"interface", "policy", "class", "direction"}
+	r.QoSClassBytes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "firewall_qos_class_bytes",
+		Help: "Bytes sent through each QoS class",
+	}, qosLabels)
+
+	r.QoSClassPackets = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "firewall_qos_class_packets",
+		Help: "Packets sent through each QoS class",
+	}, qosLabels)
+
+	r.QoSClassDrops = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "firewall_qos_class_drops",
+		Help: "Packets dropped by each QoS class queue",
+	}, qosLabels)
+
+	r.QoSClassBacklog = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "firewall_qos_class_backlog_bytes",
+		Help: "Bytes currently queued in each QoS class",
+	}, qosLabels)
+
 	// System metrics
 	r.Uptime = promauto.NewGauge(prometheus.GaugeOpts{
 		Name: "firewall_uptime_seconds",
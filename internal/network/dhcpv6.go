@@ -3,13 +3,26 @@
 package network
 
 import (
+	"context"
+	"encoding/binary"
 	"fmt"
 	"log"
+	"net"
+	"net/netip"
 	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/vishvananda/netlink"
+
+	"grimm.is/flywall/internal/config"
 )
 
-// StartDHCPv6Client starts a DHCPv6 client on the interface.
-// Currently wraps 'dhclient -6'.
+// StartDHCPv6Client starts the system DHCPv6 client on the interface.
+// Used for interfaces whose DHCP client is not the builtin one; it wraps
+// 'dhclient -6' and does not feed delegated prefixes back to the manager.
 func StartDHCPv6Client(iface string) error {
 	// Check if already running
 	if isProcessRunning(fmt.Sprintf("dhclient -6 -P %s", iface)) {
@@ -44,3 +57,396 @@ func isProcessRunning(cmdPattern string) bool {
 func EnableIPv6Forwarding() error {
 	return DefaultSystemController.WriteSysctl("/proc/sys/net/ipv6/conf/all/forwarding", "1")
 }
+
+// DHCPv6Lease is what the native DHCPv6 client holds for an upstream
+// interface: an IA_NA address and the IA_PD prefixes delegated to us.
+type DHCPv6Lease struct {
+	Interface  string
+	Address    netip.Addr // IA_NA address; invalid if the server gave none
+	Prefixes   []LeasedPrefix
+	DNSServers []netip.Addr
+	T1         time.Duration
+	T2         time.Duration
+	ValidUntil time.Time // when the longest-lived binding expires
+	ObtainedAt time.Time
+}
+
+// LeasedPrefix is a single IA_PD prefix with its lifetimes.
+type LeasedPrefix struct {
+	Prefix            netip.Prefix
+	PreferredLifetime time.Duration
+	ValidLifetime     time.Duration
+}
+
+// DelegatedPrefix is a /64 carved from an upstream delegation and assigned
+// to a downstream interface.
+type DelegatedPrefix struct {
+	Interface string       `json:"interface"`
+	Upstream  string       `json:"upstream"`
+	Prefix    netip.Prefix `json:"prefix"`  // the /64
+	Address   netip.Prefix `json:"address"` // router address within Prefix
+}
+
+// dhcpv6Runner tracks a running native DHCPv6 client.
+type dhcpv6Runner struct {
+	cancel   context.CancelFunc
+	pdLength int
+}
+
+// leaseFromReply extracts the bindings from a DHCPv6 Reply.
+func leaseFromReply(iface string, msg *dhcpv6.Message, now time.Time) (*DHCPv6Lease, error) {
+	if msg == nil {
+		return nil, fmt.Errorf("no reply")
+	}
+	if status := msg.Options.Status(); status != nil && status.StatusCode != 0 {
+		return nil, fmt.Errorf("server returned %s", status)
+	}
+
+	lease := &DHCPv6Lease{Interface: iface, ObtainedAt: now}
+	var valid time.Duration
+
+	if iana := msg.Options.OneIANA(); iana != nil {
+		if status := iana.Options.Status(); status == nil || status.StatusCode == 0 {
+			lease.T1, lease.T2 = iana.T1, iana.T2
+			if a := iana.Options.OneAddress(); a != nil && a.ValidLifetime > 0 {
+				if addr, ok := netip.AddrFromSlice(a.IPv6Addr); ok {
+					lease.Address = addr.Unmap()
+					valid = max(valid, a.ValidLifetime)
+				}
+			}
+		}
+	}
+	if iapd := msg.Options.OneIAPD(); iapd != nil {
+		if status := iapd.Options.Status(); status == nil || status.StatusCode == 0 {
+			if lease.T1 == 0 || (iapd.T1 > 0 && iapd.T1 < lease.T1) {
+				lease.T1, lease.T2 = iapd.T1, iapd.T2
+			}
+			for _, p := range iapd.Options.Prefixes() {
+				if p.Prefix == nil || p.ValidLifetime == 0 {
+					continue
+				}
+				addr, ok := netip.AddrFromSlice(p.Prefix.IP)
+				if !ok {
+					continue
+				}
+				bits, _ := p.Prefix.Mask.Size()
+				lease.Prefixes = append(lease.Prefixes, LeasedPrefix{
+					Prefix:            netip.PrefixFrom(addr.Unmap(), bits).Masked(),
+					PreferredLifetime: p.PreferredLifetime,
+					ValidLifetime:     p.ValidLifetime,
+				})
+				valid = max(valid, p.ValidLifetime)
+			}
+		}
+	}
+	if !lease.Address.IsValid() && len(lease.Prefixes) == 0 {
+		return nil, fmt.Errorf("reply contains no usable address or prefix")
+	}
+
+	for _, ip := range msg.Options.DNS() {
+		if addr, ok := netip.AddrFromSlice(ip); ok {
+			lease.DNSServers = append(lease.DNSServers, addr)
+		}
+	}
+
+	// RFC 8415 18.2.4: servers may leave T1/T2 to the client.
+	if lease.T1 == 0 {
+		lease.T1 = valid / 2
+	}
+	if lease.T2 == 0 || lease.T2 < lease.T1 {
+		lease.T2 = valid * 4 / 5
+	}
+	if valid == dhcpv6InfiniteLifetime {
+		lease.ValidUntil = now.Add(100 * 365 * 24 * time.Hour)
+	} else {
+		lease.ValidUntil = now.Add(valid)
+	}
+	return lease, nil
+}
+
+// dhcpv6InfiniteLifetime is the 0xffffffff lifetime from RFC 8415 7.7.
+const dhcpv6InfiniteLifetime = time.Duration(0xffffffff) * time.Second
+
+// CarvePrefix returns the subnetID'th /64 within a delegated prefix.
+func CarvePrefix(delegated netip.Prefix, subnetID int) (netip.Prefix, error) {
+	bits := delegated.Bits()
+	if !delegated.Addr().Is6() || bits < 0 || bits > 64 {
+		return netip.Prefix{}, fmt.Errorf("cannot carve /64s from %s", delegated)
+	}
+	if subnetID < 0 || uint64(subnetID) > (uint64(1)<<(64-bits))-1 {
+		return netip.Prefix{}, fmt.Errorf("subnet %d does not fit in %s", subnetID, delegated)
+	}
+
+	a := delegated.Masked().Addr().As16()
+	hi := binary.BigEndian.Uint64(a[:8]) | uint64(subnetID)
+	binary.BigEndian.PutUint64(a[:8], hi)
+	clear(a[8:])
+	return netip.PrefixFrom(netip.AddrFrom16(a), 64), nil
+}
+
+// hostAddress places the interface identifier hostID ("::1" by default)
+// in a /64.
+func hostAddress(subnet netip.Prefix, hostID string) (netip.Prefix, error) {
+	if hostID == "" {
+		hostID = "::1"
+	}
+	id, err := netip.ParseAddr(hostID)
+	if err != nil || !id.Is6() {
+		return netip.Prefix{}, fmt.Errorf("invalid host_id %q", hostID)
+	}
+	a, h := subnet.Addr().As16(), id.As16()
+	copy(a[8:], h[8:])
+	return netip.PrefixFrom(netip.AddrFrom16(a), 64), nil
+}
+
+// AssignDelegatedPrefixes carves a /64 for every interface with a
+// delegated_prefix block whose upstream holds a delegation. Explicit
+// subnet IDs are honoured first; the rest take the lowest free index in
+// interface order.
+func AssignDelegatedPrefixes(ifaces []config.Interface, leases map[string]*DHCPv6Lease) []DelegatedPrefix {
+	used := make(map[string]map[int]bool)
+	for _, iface := range ifaces {
+		dp := iface.DelegatedPrefix
+		if dp != nil && dp.SubnetID != nil {
+			if used[dp.From] == nil {
+				used[dp.From] = make(map[int]bool)
+			}
+			used[dp.From][*dp.SubnetID] = true
+		}
+	}
+
+	var out []DelegatedPrefix
+	for _, iface := range ifaces {
+		dp := iface.DelegatedPrefix
+		if dp == nil {
+			continue
+		}
+		lease := leases[dp.From]
+		if lease == nil || len(lease.Prefixes) == 0 {
+			continue
+		}
+		delegated := lease.Prefixes[0].Prefix
+
+		id := 0
+		if dp.SubnetID != nil {
+			id = *dp.SubnetID
+		} else {
+			if used[dp.From] == nil {
+				used[dp.From] = make(map[int]bool)
+			}
+			for used[dp.From][id] {
+				id++
+			}
+			used[dp.From][id] = true
+		}
+
+		subnet, err := CarvePrefix(delegated, id)
+		if err != nil {
+			log.Printf("[DHCPv6] Cannot assign prefix to %s: %v", iface.Name, err)
+			continue
+		}
+		addr, err := hostAddress(subnet, dp.HostID)
+		if err != nil {
+			log.Printf("[DHCPv6] Cannot assign prefix to %s: %v", iface.Name, err)
+			continue
+		}
+		out = append(out, DelegatedPrefix{
+			Interface: iface.Name,
+			Upstream:  dp.From,
+			Prefix:    subnet,
+			Address:   addr,
+		})
+	}
+	return out
+}
+
+// ApplyDelegatedPrefixes returns a copy of cfg with each delegated /64
+// added to its interface's IPv6 addresses (so RA and DNS pick it up) and to
+// the networks of its zone when the zone lists networks explicitly. cfg is
+// returned unchanged when nothing is delegated; it is never modified, so the
+// delegation is never persisted.
+func ApplyDelegatedPrefixes(cfg *config.Config, prefixes []DelegatedPrefix) *config.Config {
+	if cfg == nil || len(prefixes) == 0 {
+		return cfg
+	}
+	out := cfg.Clone()
+	if out == nil {
+		return cfg
+	}
+
+	for _, dp := range prefixes {
+		for i := range out.Interfaces {
+			iface := &out.Interfaces[i]
+			if iface.Name != dp.Interface {
+				continue
+			}
+			if addr := dp.Address.String(); !slices.Contains(iface.IPv6, addr) {
+				iface.IPv6 = append(iface.IPv6, addr)
+			}
+			for j := range out.Zones {
+				zone := &out.Zones[j]
+				if !strings.EqualFold(zone.Name, iface.Zone) || len(zone.Networks) == 0 {
+					continue
+				}
+				if network := dp.Prefix.String(); !slices.Contains(zone.Networks, network) {
+					zone.Networks = append(zone.Networks, network)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// SetPrefixListener registers fn to receive the full set of delegated
+// prefixes whenever it changes. fn runs on its own goroutine, one call at a
+// time, and is called straight away if prefixes are already delegated.
+func (m *Manager) SetPrefixListener(fn func([]DelegatedPrefix)) {
+	m.pdMu.Lock()
+	m.prefixListener = fn
+	pending := len(m.delegated) > 0
+	m.pdMu.Unlock()
+
+	if pending {
+		go m.notifyPrefixListener()
+	}
+}
+
+// DelegatedPrefixes returns the /64s currently assigned to downstream
+// interfaces.
+func (m *Manager) DelegatedPrefixes() []DelegatedPrefix {
+	m.pdMu.Lock()
+	defer m.pdMu.Unlock()
+	return slices.Clone(m.delegated)
+}
+
+// DHCPv6Leases returns the native DHCPv6 client leases by interface.
+func (m *Manager) DHCPv6Leases() map[string]DHCPv6Lease {
+	m.pdMu.Lock()
+	defer m.pdMu.Unlock()
+	out := make(map[string]DHCPv6Lease, len(m.dhcpv6Leases))
+	for name, lease := range m.dhcpv6Leases {
+		out[name] = *lease
+	}
+	return out
+}
+
+// trackDownstream records whether ifaceCfg takes a delegated prefix,
+// keeping the apply order that automatic subnet IDs follow.
+func (m *Manager) trackDownstream(ifaceCfg config.Interface) {
+	m.pdMu.Lock()
+	idx := slices.IndexFunc(m.downstream, func(i config.Interface) bool { return i.Name == ifaceCfg.Name })
+	switch {
+	case ifaceCfg.DelegatedPrefix != nil && idx >= 0:
+		m.downstream[idx] = ifaceCfg
+	case ifaceCfg.DelegatedPrefix != nil:
+		m.downstream = append(m.downstream, ifaceCfg)
+	case idx >= 0:
+		m.downstream = slices.Delete(m.downstream, idx, idx+1)
+	default:
+		m.pdMu.Unlock()
+		return
+	}
+	m.pdMu.Unlock()
+	m.refreshDelegation()
+}
+
+// setDHCPv6Lease records (or, with nil, drops) the lease for an upstream
+// interface and redistributes the delegation.
+func (m *Manager) setDHCPv6Lease(iface string, lease *DHCPv6Lease) {
+	m.pdMu.Lock()
+	if m.dhcpv6Leases == nil {
+		m.dhcpv6Leases = make(map[string]*DHCPv6Lease)
+	}
+	if lease == nil {
+		delete(m.dhcpv6Leases, iface)
+	} else {
+		m.dhcpv6Leases[iface] = lease
+	}
+	m.pdMu.Unlock()
+	m.refreshDelegation()
+}
+
+// refreshDelegation recomputes the downstream /64s, moves interface
+// addresses to match and notifies the listener if anything changed.
+func (m *Manager) refreshDelegation() {
+	m.pdMu.Lock()
+	old := m.delegated
+	next := AssignDelegatedPrefixes(m.downstream, m.dhcpv6Leases)
+	m.delegated = next
+	m.pdMu.Unlock()
+
+	for _, dp := range old {
+		if !slices.Contains(next, dp) {
+			log.Printf("[DHCPv6] Withdrawing %s from %s", dp.Address, dp.Interface)
+			m.delegatedAddr(dp.Interface, dp.Address, false)
+		}
+	}
+	for _, dp := range next {
+		if !slices.Contains(old, dp) {
+			log.Printf("[DHCPv6] Assigning %s (from %s) to %s", dp.Address, dp.Upstream, dp.Interface)
+			m.delegatedAddr(dp.Interface, dp.Address, true)
+		}
+	}
+
+	if !slices.Equal(old, next) {
+		go m.notifyPrefixListener()
+	}
+}
+
+// restoreDelegatedAddrs re-adds delegated and DHCPv6 addresses to an
+// interface after ApplyInterface has flushed it.
+func (m *Manager) restoreDelegatedAddrs(name string) {
+	m.pdMu.Lock()
+	var addrs []netip.Prefix
+	for _, dp := range m.delegated {
+		if dp.Interface == name {
+			addrs = append(addrs, dp.Address)
+		}
+	}
+	if lease := m.dhcpv6Leases[name]; lease != nil && lease.Address.IsValid() {
+		addrs = append(addrs, netip.PrefixFrom(lease.Address, 128))
+	}
+	m.pdMu.Unlock()
+
+	for _, addr := range addrs {
+		m.delegatedAddr(name, addr, true)
+	}
+}
+
+// delegatedAddr adds or removes a single address on an interface.
+func (m *Manager) delegatedAddr(name string, addr netip.Prefix, add bool) {
+	link, err := m.nl.LinkByName(name)
+	if err != nil {
+		log.Printf("[DHCPv6] Interface %s not found: %v", name, err)
+		return
+	}
+	nlAddr := &netlink.Addr{IPNet: &net.IPNet{
+		IP:   net.IP(addr.Addr().AsSlice()),
+		Mask: net.CIDRMask(addr.Bits(), 128),
+	}}
+	if add {
+		if err := m.nl.AddrAdd(link, nlAddr); err != nil && !strings.Contains(err.Error(), "file exists") {
+			log.Printf("[DHCPv6] Failed to add %s to %s: %v", addr, name, err)
+		}
+		return
+	}
+	if err := m.nl.AddrDel(link, nlAddr); err != nil {
+		log.Printf("[DHCPv6] Failed to remove %s from %s: %v", addr, name, err)
+	}
+}
+
+// notifyPrefixListener delivers the current delegation. Calls are
+// serialised so the listener always ends on the latest state.
+func (m *Manager) notifyPrefixListener() {
+	m.pdNotifyMu.Lock()
+	defer m.pdNotifyMu.Unlock()
+
+	m.pdMu.Lock()
+	fn := m.prefixListener
+	prefixes := slices.Clone(m.delegated)
+	m.pdMu.Unlock()
+
+	if fn != nil {
+		fn(prefixes)
+	}
+}
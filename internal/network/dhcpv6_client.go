@@ -0,0 +1,366 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+//go:build linux
+// +build linux
+
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"grimm.is/flywall/internal/install"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/dhcpv6/nclient6"
+	"github.com/insomniacslk/dhcp/iana"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// StartNativeDHCPv6Client starts the native DHCPv6 client on an upstream
+// interface. It requests an address (IA_NA) and a delegated prefix (IA_PD),
+// hinting pdLength if set, and keeps both renewed. Delegated prefixes are
+// carved onto downstream interfaces as they arrive or change.
+// Calling it again with the same settings is a no-op.
+func (m *Manager) StartNativeDHCPv6Client(ifaceName string, pdLength int) error {
+	ifi, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return fmt.Errorf("interface %s not found: %w", ifaceName, err)
+	}
+	if len(ifi.HardwareAddr) < 6 {
+		// DUID-LL and the IAID are derived from the MAC address.
+		return fmt.Errorf("interface %s has no hardware address", ifaceName)
+	}
+
+	m.pdMu.Lock()
+	defer m.pdMu.Unlock()
+	if r := m.dhcpv6Clients[ifaceName]; r != nil {
+		if r.pdLength == pdLength {
+			return nil
+		}
+		r.cancel()
+	}
+	if m.dhcpv6Clients == nil {
+		m.dhcpv6Clients = make(map[string]*dhcpv6Runner)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.dhcpv6Clients[ifaceName] = &dhcpv6Runner{cancel: cancel, pdLength: pdLength}
+
+	log.Printf("[network] Starting native DHCPv6 client on %s", ifaceName)
+	go m.runDHCPv6ClientLoop(ctx, ifi.HardwareAddr, ifaceName, pdLength)
+	return nil
+}
+
+// StopNativeDHCPv6Client stops the native DHCPv6 client on an interface, if
+// running, and withdraws everything its lease provided.
+func (m *Manager) StopNativeDHCPv6Client(ifaceName string) {
+	m.pdMu.Lock()
+	r := m.dhcpv6Clients[ifaceName]
+	delete(m.dhcpv6Clients, ifaceName)
+	lease := m.dhcpv6Leases[ifaceName]
+	m.pdMu.Unlock()
+
+	if r == nil {
+		return
+	}
+	log.Printf("[network] Stopping native DHCPv6 client on %s", ifaceName)
+	r.cancel()
+	if lease != nil {
+		m.applyDHCPv6Lease(ifaceName, lease, nil)
+	}
+}
+
+// savedDHCPv6Lease is the on-disk form of a DHCPv6 lease.
+type savedDHCPv6Lease struct {
+	Reply      []byte    `json:"reply"`
+	ObtainedAt time.Time `json:"obtained_at"`
+}
+
+// runDHCPv6ClientLoop acquires a lease and then renews it at T1, rebinds
+// at T2 and starts over if it expires.
+func (m *Manager) runDHCPv6ClientLoop(ctx context.Context, hwAddr net.HardwareAddr, ifaceName string, pdLength int) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[network] CRITICAL: DHCPv6 client panic on %s: %v", ifaceName, r)
+			os.Remove(dhcpv6LeasePath(ifaceName))
+		}
+	}()
+
+	// The socket binds to the link-local address, which only exists once
+	// DAD has finished on a freshly raised link.
+	var client *nclient6.Client
+	for backoff := time.Second; client == nil; backoff = min(backoff*2, time.Minute) {
+		var err error
+		client, err = nclient6.New(ifaceName, nclient6.WithTimeout(3*time.Second), nclient6.WithRetry(3))
+		if err != nil {
+			log.Printf("[network] DHCPv6 client on %s not ready: %v", ifaceName, err)
+			if !sleepCtx(ctx, backoff) {
+				return
+			}
+		}
+	}
+	defer client.Close()
+
+	var reply *dhcpv6.Message
+	var lease *DHCPv6Lease
+
+	if saved, err := loadDHCPv6Lease(ifaceName); err == nil {
+		if l, err := leaseFromReply(ifaceName, saved.msg, saved.ObtainedAt); err == nil && time.Now().Before(l.ValidUntil) {
+			log.Printf("[network] Reusing saved DHCPv6 lease on %s", ifaceName)
+			reply, lease = saved.msg, l
+			m.applyDHCPv6Lease(ifaceName, nil, lease)
+		}
+	}
+
+	backoff := 2 * time.Second
+	for {
+		if lease == nil {
+			var err error
+			reply, err = dhcpv6Solicit(ctx, client, hwAddr, pdLength)
+			if err == nil {
+				lease, err = leaseFromReply(ifaceName, reply, time.Now())
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("[network] DHCPv6 acquisition failed on %s: %v", ifaceName, err)
+				if !sleepCtx(ctx, backoff) {
+					return
+				}
+				backoff = min(backoff*2, time.Minute)
+				continue
+			}
+			backoff = 2 * time.Second
+			saveDHCPv6Lease(ifaceName, reply, lease.ObtainedAt)
+			m.applyDHCPv6Lease(ifaceName, nil, lease)
+		}
+
+		log.Printf("[network] DHCPv6 lease active on %s (address %v, prefixes %v). Renewing in %s",
+			ifaceName, lease.Address, lease.Prefixes, time.Until(lease.ObtainedAt.Add(lease.T1)).Round(time.Second))
+		if !sleepCtx(ctx, time.Until(lease.ObtainedAt.Add(lease.T1))) {
+			return
+		}
+
+		// Renew with our server until T2, then rebind with any server
+		// until the lease runs out.
+		for {
+			now := time.Now()
+			if !now.Before(lease.ValidUntil) {
+				log.Printf("[network] DHCPv6 lease on %s expired", ifaceName)
+				m.applyDHCPv6Lease(ifaceName, lease, nil)
+				lease = nil
+				break
+			}
+			msgType := dhcpv6.MessageTypeRenew
+			if !now.Before(lease.ObtainedAt.Add(lease.T2)) {
+				msgType = dhcpv6.MessageTypeRebind
+			}
+
+			next, err := dhcpv6Exchange(ctx, client, msgType, reply)
+			var nextLease *DHCPv6Lease
+			if err == nil {
+				nextLease, err = leaseFromReply(ifaceName, next, time.Now())
+			}
+			if err == nil {
+				saveDHCPv6Lease(ifaceName, next, nextLease.ObtainedAt)
+				m.applyDHCPv6Lease(ifaceName, lease, nextLease)
+				reply, lease = next, nextLease
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("[network] DHCPv6 %s failed on %s: %v", msgType, ifaceName, err)
+			if !sleepCtx(ctx, min(10*time.Second, time.Until(lease.ValidUntil))) {
+				return
+			}
+		}
+	}
+}
+
+// dhcpv6Solicit obtains a lease, using rapid commit when the server allows.
+func dhcpv6Solicit(ctx context.Context, client *nclient6.Client, hwAddr net.HardwareAddr, pdLength int) (*dhcpv6.Message, error) {
+	// NewSolicit derives the IA_NA IAID from the last four bytes of the
+	// MAC; IA_PD reuses it. DUID-LL keeps the identity stable across
+	// restarts, unlike the default DUID-LLT.
+	var iaid [4]byte
+	copy(iaid[:], hwAddr[len(hwAddr)-4:])
+	var hints []*dhcpv6.OptIAPrefix
+	if pdLength > 0 {
+		hints = append(hints, &dhcpv6.OptIAPrefix{
+			Prefix: &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(pdLength, 128)},
+		})
+	}
+
+	solicit, err := dhcpv6.NewSolicit(hwAddr,
+		dhcpv6.WithClientID(&dhcpv6.DUIDLL{HWType: iana.HWTypeEthernet, LinkLayerAddr: hwAddr}),
+		dhcpv6.WithIAPD(iaid, hints...),
+		dhcpv6.WithRapidCommit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := client.SendAndRead(ctx, client.RemoteAddr(), solicit,
+		nclient6.IsMessageType(dhcpv6.MessageTypeReply, dhcpv6.MessageTypeAdvertise))
+	if err != nil {
+		return nil, err
+	}
+	if msg.MessageType == dhcpv6.MessageTypeReply {
+		return msg, nil
+	}
+	return dhcpv6Exchange(ctx, client, dhcpv6.MessageTypeRequest, msg)
+}
+
+// dhcpv6Exchange sends a Request, Renew or Rebind for the bindings in prev
+// (an Advertise or an earlier Reply) and waits for the Reply.
+func dhcpv6Exchange(ctx context.Context, client *nclient6.Client, msgType dhcpv6.MessageType, prev *dhcpv6.Message) (*dhcpv6.Message, error) {
+	msg, err := dhcpv6.NewMessage()
+	if err != nil {
+		return nil, err
+	}
+	msg.MessageType = msgType
+
+	clientID := prev.GetOneOption(dhcpv6.OptionClientID)
+	serverID := prev.GetOneOption(dhcpv6.OptionServerID)
+	if clientID == nil || serverID == nil {
+		return nil, fmt.Errorf("%s is missing the client or server ID", prev.MessageType)
+	}
+	msg.AddOption(clientID)
+	if msgType != dhcpv6.MessageTypeRebind {
+		msg.AddOption(serverID)
+	}
+	msg.AddOption(dhcpv6.OptElapsedTime(0))
+	if iana := prev.Options.OneIANA(); iana != nil {
+		msg.AddOption(iana)
+	}
+	if iapd := prev.Options.OneIAPD(); iapd != nil {
+		msg.AddOption(iapd)
+	}
+	msg.AddOption(dhcpv6.OptRequestedOption(dhcpv6.OptionDNSRecursiveNameServer, dhcpv6.OptionDomainSearchList))
+
+	return client.SendAndRead(ctx, client.RemoteAddr(), msg, nclient6.IsMessageType(dhcpv6.MessageTypeReply))
+}
+
+// applyDHCPv6Lease moves the upstream interface from one lease to the next
+// (either may be nil): the IA_NA address, an unreachable route covering
+// each delegated prefix so unassigned space is not routed back upstream
+// (RFC 7084 WPD-5), and the downstream /64s.
+func (m *Manager) applyDHCPv6Lease(ifaceName string, old, next *DHCPv6Lease) {
+	var oldAddr, nextAddr netip.Addr
+	var oldPrefixes, nextPrefixes []netip.Prefix
+	if old != nil {
+		oldAddr = old.Address
+		for _, p := range old.Prefixes {
+			oldPrefixes = append(oldPrefixes, p.Prefix)
+		}
+	}
+	if next != nil {
+		nextAddr = next.Address
+		for _, p := range next.Prefixes {
+			nextPrefixes = append(nextPrefixes, p.Prefix)
+		}
+	}
+
+	if oldAddr.IsValid() && oldAddr != nextAddr {
+		m.delegatedAddr(ifaceName, netip.PrefixFrom(oldAddr, 128), false)
+	}
+	if nextAddr.IsValid() {
+		m.delegatedAddr(ifaceName, netip.PrefixFrom(nextAddr, 128), true)
+	}
+
+	for _, p := range oldPrefixes {
+		if !containsPrefix(nextPrefixes, p) {
+			if err := m.nl.RouteDel(unreachableRoute(p)); err != nil {
+				log.Printf("[DHCPv6] Failed to remove unreachable route for %s: %v", p, err)
+			}
+		}
+	}
+	for _, p := range nextPrefixes {
+		if err := m.nl.RouteAdd(unreachableRoute(p)); err != nil && !strings.Contains(err.Error(), "file exists") {
+			log.Printf("[DHCPv6] Failed to add unreachable route for %s: %v", p, err)
+		}
+	}
+
+	m.setDHCPv6Lease(ifaceName, next)
+}
+
+func unreachableRoute(p netip.Prefix) *netlink.Route {
+	return &netlink.Route{
+		Dst:  &net.IPNet{IP: net.IP(p.Addr().AsSlice()), Mask: net.CIDRMask(p.Bits(), 128)},
+		Type: unix.RTN_UNREACHABLE,
+	}
+}
+
+func containsPrefix(list []netip.Prefix, p netip.Prefix) bool {
+	for _, q := range list {
+		if q == p {
+			return true
+		}
+	}
+	return false
+}
+
+// sleepCtx waits for d, returning false if ctx is cancelled first.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func dhcpv6LeasePath(ifaceName string) string {
+	return filepath.Join(install.GetStateDir(), fmt.Sprintf("dhcpv6_client_%s.json", ifaceName))
+}
+
+func saveDHCPv6Lease(ifaceName string, reply *dhcpv6.Message, obtained time.Time) {
+	data, err := json.Marshal(savedDHCPv6Lease{Reply: reply.ToBytes(), ObtainedAt: obtained})
+	if err != nil {
+		log.Printf("[network] Failed to marshal DHCPv6 lease: %v", err)
+		return
+	}
+	path := dhcpv6LeasePath(ifaceName)
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		log.Printf("[network] Failed to save DHCPv6 lease to disk: %v", err)
+	}
+}
+
+type loadedDHCPv6Lease struct {
+	savedDHCPv6Lease
+	msg *dhcpv6.Message
+}
+
+func loadDHCPv6Lease(ifaceName string) (*loadedDHCPv6Lease, error) {
+	data, err := os.ReadFile(dhcpv6LeasePath(ifaceName))
+	if err != nil {
+		return nil, err
+	}
+	var sl loadedDHCPv6Lease
+	if err := json.Unmarshal(data, &sl.savedDHCPv6Lease); err != nil {
+		return nil, err
+	}
+	msg, err := dhcpv6.MessageFromBytes(sl.Reply)
+	if err != nil {
+		return nil, err
+	}
+	sl.msg = msg
+	return &sl, nil
+}
@@ -0,0 +1,17 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+//go:build !linux
+// +build !linux
+
+package network
+
+import "log"
+
+// StartNativeDHCPv6Client is a stub for non-Linux systems.
+func (m *Manager) StartNativeDHCPv6Client(ifaceName string, pdLength int) error {
+	log.Printf("[network] [DRY RUN] Starting DHCPv6 Client on interface '%s' (PD hint: /%d). (Not supported on non-Linux, simulation only)", ifaceName, pdLength)
+	return nil
+}
+
+// StopNativeDHCPv6Client is a stub for non-Linux systems.
+func (m *Manager) StopNativeDHCPv6Client(ifaceName string) {}
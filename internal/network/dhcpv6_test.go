@@ -0,0 +1,179 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package network
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"grimm.is/flywall/internal/config"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+)
+
+func TestCarvePrefix(t *testing.T) {
+	tests := []struct {
+		delegated string
+		id        int
+		want      string
+		wantErr   bool
+	}{
+		{"2001:db8:aa00::/56", 0, "2001:db8:aa00::/64", false},
+		{"2001:db8:aa00::/56", 1, "2001:db8:aa00:1::/64", false},
+		{"2001:db8:aa00::/56", 255, "2001:db8:aa00:ff::/64", false},
+		{"2001:db8:aa00::/56", 256, "", true},
+		{"2001:db8:aa00:5500::/60", 3, "2001:db8:aa00:5503::/64", false},
+		{"2001:db8:aa00:1::/64", 0, "2001:db8:aa00:1::/64", false},
+		{"2001:db8:aa00:1::/64", 1, "", true},
+		{"2001:db8::/80", 0, "", true},
+	}
+	for _, tt := range tests {
+		got, err := CarvePrefix(netip.MustParsePrefix(tt.delegated), tt.id)
+		if tt.wantErr {
+			assert.Error(t, err, "%s #%d", tt.delegated, tt.id)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got.String())
+	}
+}
+
+func intPtr(i int) *int { return &i }
+
+func TestAssignDelegatedPrefixes(t *testing.T) {
+	ifaces := []config.Interface{
+		{Name: "eth0", DHCPv6: true},
+		{Name: "lan0", DelegatedPrefix: &config.DelegatedPrefix{From: "eth0"}},
+		{Name: "guest0", DelegatedPrefix: &config.DelegatedPrefix{From: "eth0", SubnetID: intPtr(0), HostID: "::fe"}},
+		{Name: "iot0", DelegatedPrefix: &config.DelegatedPrefix{From: "eth0"}},
+		{Name: "dmz0", DelegatedPrefix: &config.DelegatedPrefix{From: "eth9"}},
+	}
+	leases := map[string]*DHCPv6Lease{
+		"eth0": {Prefixes: []LeasedPrefix{{Prefix: netip.MustParsePrefix("2001:db8:aa00::/56")}}},
+	}
+
+	got := AssignDelegatedPrefixes(ifaces, leases)
+	require.Len(t, got, 3)
+
+	byIface := make(map[string]DelegatedPrefix)
+	for _, dp := range got {
+		assert.Equal(t, "eth0", dp.Upstream)
+		byIface[dp.Interface] = dp
+	}
+	assert.Equal(t, "2001:db8:aa00::fe/64", byIface["guest0"].Address.String())
+	assert.Equal(t, "2001:db8:aa00:1::/64", byIface["lan0"].Prefix.String())
+	assert.Equal(t, "2001:db8:aa00:1::1/64", byIface["lan0"].Address.String())
+	assert.Equal(t, "2001:db8:aa00:2::/64", byIface["iot0"].Prefix.String())
+
+	assert.Empty(t, AssignDelegatedPrefixes(ifaces, nil))
+}
+
+func TestApplyDelegatedPrefixes(t *testing.T) {
+	cfg := &config.Config{
+		Interfaces: []config.Interface{
+			{Name: "lan0", Zone: "lan", IPv6: []string{"fd00::1/64"}},
+			{Name: "guest0", Zone: "guest"},
+		},
+		Zones: []config.Zone{
+			{Name: "lan", Networks: []string{"192.168.1.0/24"}},
+			{Name: "guest"},
+		},
+	}
+	prefixes := []DelegatedPrefix{
+		{Interface: "lan0", Prefix: netip.MustParsePrefix("2001:db8:1::/64"), Address: netip.MustParsePrefix("2001:db8:1::1/64")},
+		{Interface: "guest0", Prefix: netip.MustParsePrefix("2001:db8:2::/64"), Address: netip.MustParsePrefix("2001:db8:2::1/64")},
+	}
+
+	out := ApplyDelegatedPrefixes(cfg, prefixes)
+	assert.Equal(t, []string{"fd00::1/64", "2001:db8:1::1/64"}, out.Interfaces[0].IPv6)
+	assert.Equal(t, []string{"2001:db8:2::1/64"}, out.Interfaces[1].IPv6)
+	assert.Equal(t, []string{"192.168.1.0/24", "2001:db8:1::/64"}, out.Zones[0].Networks)
+	// Interface-only zones stay interface-only
+	assert.Empty(t, out.Zones[1].Networks)
+
+	// The running config is left alone
+	assert.Equal(t, []string{"fd00::1/64"}, cfg.Interfaces[0].IPv6)
+	assert.Len(t, cfg.Zones[0].Networks, 1)
+	assert.Same(t, cfg, ApplyDelegatedPrefixes(cfg, nil))
+}
+
+func TestLeaseFromReply(t *testing.T) {
+	msg, err := dhcpv6.NewMessage()
+	require.NoError(t, err)
+	msg.MessageType = dhcpv6.MessageTypeReply
+	msg.AddOption(&dhcpv6.OptIANA{
+		T1: time.Hour, T2: 2 * time.Hour,
+		Options: dhcpv6.IdentityOptions{Options: dhcpv6.Options{&dhcpv6.OptIAAddress{
+			IPv6Addr: net.ParseIP("2001:db8::100"), PreferredLifetime: 3 * time.Hour, ValidLifetime: 4 * time.Hour,
+		}}},
+	})
+	_, pd, _ := net.ParseCIDR("2001:db8:aa00::/56")
+	msg.AddOption(&dhcpv6.OptIAPD{
+		T1: 30 * time.Minute, T2: time.Hour,
+		Options: dhcpv6.PDOptions{Options: dhcpv6.Options{&dhcpv6.OptIAPrefix{
+			Prefix: pd, PreferredLifetime: 2 * time.Hour, ValidLifetime: 8 * time.Hour,
+		}}},
+	})
+	msg.AddOption(dhcpv6.OptDNS(net.ParseIP("2001:db8::53")))
+
+	now := time.Now()
+	lease, err := leaseFromReply("eth0", msg, now)
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::100", lease.Address.String())
+	require.Len(t, lease.Prefixes, 1)
+	assert.Equal(t, "2001:db8:aa00::/56", lease.Prefixes[0].Prefix.String())
+	assert.Equal(t, 30*time.Minute, lease.T1, "earliest T1 across IAs")
+	assert.Equal(t, now.Add(8*time.Hour), lease.ValidUntil)
+	assert.Equal(t, []netip.Addr{netip.MustParseAddr("2001:db8::53")}, lease.DNSServers)
+
+	empty, _ := dhcpv6.NewMessage()
+	_, err = leaseFromReply("eth0", empty, now)
+	assert.Error(t, err)
+}
+
+func TestDelegation_FollowsPrefixChange(t *testing.T) {
+	mockNetlink := new(MockNetlinker)
+	m := NewManagerWithDeps(mockNetlink, nil, nil)
+
+	lan := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "lan0", Index: 3}}
+	mockNetlink.On("LinkByName", "lan0").Return(lan, nil)
+	addr := func(s string) interface{} {
+		return mock.MatchedBy(func(a *netlink.Addr) bool { return a.IPNet.String() == s })
+	}
+	mockNetlink.On("AddrAdd", lan, addr("2001:db8:aa00:1::1/64")).Return(nil).Once()
+	mockNetlink.On("AddrDel", lan, addr("2001:db8:aa00:1::1/64")).Return(nil).Once()
+	mockNetlink.On("AddrAdd", lan, addr("2001:db8:bb00:1::1/64")).Return(nil).Once()
+
+	updates := make(chan []DelegatedPrefix, 4)
+	m.SetPrefixListener(func(p []DelegatedPrefix) { updates <- p })
+
+	m.trackDownstream(config.Interface{Name: "lan0", DelegatedPrefix: &config.DelegatedPrefix{From: "eth0", SubnetID: intPtr(1)}})
+	m.setDHCPv6Lease("eth0", &DHCPv6Lease{Prefixes: []LeasedPrefix{{Prefix: netip.MustParsePrefix("2001:db8:aa00::/56")}}})
+
+	select {
+	case p := <-updates:
+		require.Len(t, p, 1)
+		assert.Equal(t, "2001:db8:aa00:1::/64", p[0].Prefix.String())
+	case <-time.After(time.Second):
+		t.Fatal("listener not called")
+	}
+
+	// The ISP hands out a new prefix
+	m.setDHCPv6Lease("eth0", &DHCPv6Lease{Prefixes: []LeasedPrefix{{Prefix: netip.MustParsePrefix("2001:db8:bb00::/56")}}})
+	select {
+	case p := <-updates:
+		require.Len(t, p, 1)
+		assert.Equal(t, "2001:db8:bb00:1::/64", p[0].Prefix.String())
+	case <-time.After(time.Second):
+		t.Fatal("listener not called")
+	}
+
+	assert.Equal(t, "2001:db8:bb00:1::1/64", m.DelegatedPrefixes()[0].Address.String())
+	mockNetlink.AssertExpectations(t)
+}
@@ -9,6 +9,7 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"grimm.is/flywall/internal/config"
@@ -33,6 +34,15 @@ type Manager struct {
 	cmd             CommandExecutor
 	dns             DNSUpdater
 	uidRulePriority int
+
+	// DHCPv6 prefix delegation (see dhcpv6.go)
+	pdMu           sync.Mutex
+	pdNotifyMu     sync.Mutex
+	dhcpv6Clients  map[string]*dhcpv6Runner
+	dhcpv6Leases   map[string]*DHCPv6Lease
+	downstream     []config.Interface
+	delegated      []DelegatedPrefix
+	prefixListener func([]DelegatedPrefix)
 }
 
 // DNSUpdater is an interface for updating the DNS service dynamically.
@@ -237,10 +247,24 @@ func (m *Manager) ApplyInterface(ifaceCfg config.Interface) error {
 			// Client Mode
 			_ = m.sys.WriteSysctl(fmt.Sprintf("/proc/sys/net/ipv6/conf/%s/accept_ra", ifaceCfg.Name), "2")
 
-			if err := StartDHCPv6Client(ifaceCfg.Name); err != nil {
+			clientType := ifaceCfg.DHCPClient
+			if clientType == "" {
+				clientType = "native"
+			}
+			if clientType == "native" || clientType == "builtin" {
+				if err := m.StartNativeDHCPv6Client(ifaceCfg.Name, ifaceCfg.DHCPv6PDLength); err != nil {
+					log.Printf("Error starting native DHCPv6 client on %s: %v. Falling back to system client.", ifaceCfg.Name, err)
+					if err := StartDHCPv6Client(ifaceCfg.Name); err != nil {
+						log.Printf("Error starting DHCPv6 client on %s: %v", ifaceCfg.Name, err)
+					}
+				}
+			} else if err := StartDHCPv6Client(ifaceCfg.Name); err != nil {
 				log.Printf("Error starting DHCPv6 client on %s: %v", ifaceCfg.Name, err)
 			}
 		}
+		if ifaceCfg.RA || !ifaceCfg.DHCPv6 {
+			m.StopNativeDHCPv6Client(ifaceCfg.Name)
+		}
 
 		// 2. Static IPv6 Addresses
 		for _, ipStr := range ifaceCfg.IPv6 {
@@ -253,6 +277,10 @@ func (m *Manager) ApplyInterface(ifaceCfg config.Interface) error {
 				log.Printf("Warning: failed to add IPv6 address %s to %s: %v", ipStr, ifaceCfg.Name, err)
 			}
 		}
+
+		// 3. Delegated prefixes and DHCPv6 addresses (flushed above)
+		m.trackDownstream(ifaceCfg)
+		m.restoreDelegatedAddrs(ifaceCfg.Name)
 	}
 
 	// Interface is already brought up above.
@@ -1079,6 +1079,23 @@ func (m *UplinkManager) GetAllGroups() []*UplinkGroup {
 	return groups
 }
 
+// UplinkHealthy reports whether the named uplink, in any group, is enabled
+// and passing its health checks.
+func (m *UplinkManager) UplinkHealthy(name string) (healthy, found bool) {
+	for _, g := range m.GetAllGroups() {
+		g.mu.RLock()
+		for _, u := range g.Uplinks {
+			if u.Name == name {
+				healthy = u.Enabled && u.Healthy
+				g.mu.RUnlock()
+				return healthy, true
+			}
+		}
+		g.mu.RUnlock()
+	}
+	return false, false
+}
+
 // IsHealthCheckRunning returns whether health checking is active.
 func (m *UplinkManager) IsHealthCheckRunning() bool {
 	return m.healthChecker != nil
@@ -165,6 +165,18 @@ func TestUplinkManager_Groups(t *testing.T) {
 
 	list := m.ListGroups()
 	assert.Contains(t, list, "group1")
+
+	g.AddUplink(&Uplink{Name: "wan1", Interface: "eth0", Enabled: true, Healthy: true})
+	healthy, found := m.UplinkHealthy("wan1")
+	assert.True(t, found)
+	assert.True(t, healthy)
+
+	g.SetUplinkHealth("wan1", false)
+	healthy, _ = m.UplinkHealthy("wan1")
+	assert.False(t, healthy)
+
+	_, found = m.UplinkHealthy("wan2")
+	assert.False(t, found)
 }
 func TestUplinkHealthChecker_Hysteresis(t *testing.T) {
 	mockExec := new(MockCommandExecutor)
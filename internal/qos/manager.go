@@ -9,16 +9,23 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
+	"sync"
 
 	"grimm.is/flywall/internal/config"
 	"grimm.is/flywall/internal/logging"
+	"grimm.is/flywall/internal/metrics"
 
 	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
 )
 
 // Manager handles QoS traffic shaping configuration.
 type Manager struct {
 	logger *logging.Logger
+
+	mu      sync.Mutex
+	applied []shaper // Trees installed by the last ApplyConfig
 }
 
 // NewManager creates a new QoS manager.
@@ -33,143 +40,159 @@ func NewManager(logger *logging.Logger) *Manager {
 
 // ApplyConfig applies QoS configuration to interfaces.
 func (m *Manager) ApplyConfig(cfg *config.Config) error {
+	var applied []shaper
+	defer func() {
+		m.mu.Lock()
+		m.applied = applied
+		m.mu.Unlock()
+	}()
+
 	for i, policy := range cfg.QoSPolicies {
 		if !policy.Enabled {
 			continue
 		}
 
-		if err := m.applyPolicy(policy, i); err != nil {
+		shapers, err := m.applyPolicy(policy, i)
+		if err != nil {
 			return fmt.Errorf("failed to apply QoS policy %s: %w", policy.Name, err)
 		}
+		applied = append(applied, shapers...)
 	}
 	return nil
 }
 
-func (m *Manager) applyPolicy(pol config.QoSPolicy, policyIdx int) error {
+func (m *Manager) applyPolicy(pol config.QoSPolicy, policyIdx int) ([]shaper, error) {
 	link, err := netlink.LinkByName(pol.Interface)
 	if err != nil {
-		return fmt.Errorf("interface %s not found: %w", pol.Interface, err)
+		return nil, fmt.Errorf("interface %s not found: %w", pol.Interface, err)
 	}
 
-	// 1. Clear existing qdiscs (root)
-	qdiscs, err := netlink.QdiscList(link)
-	if err != nil {
-		return fmt.Errorf("failed to list qdiscs: %w", err)
+	shapers, unmatched := buildShapers(pol, policyIdx)
+	if len(shapers) == 0 {
+		m.logger.Warn("QoS policy has no rate for its direction, nothing to shape",
+			"policy", pol.Name, "direction", pol.Direction)
 	}
-	for _, q := range qdiscs {
-		if q.Attrs().Parent == netlink.HANDLE_ROOT {
-			netlink.QdiscDel(q)
+	for _, rule := range unmatched {
+		// Ingress sees packets before NAT and before the mangle rules set
+		// their marks, so only the remote side of a flow can be matched
+		m.logger.Debug("QoS rule has no download match, traffic uses the default class",
+			"policy", pol.Name, "rule", rule)
+	}
+
+	// Clear existing root qdisc so a reapply starts from scratch
+	if err := clearRoot(link); err != nil {
+		return nil, err
+	}
+
+	ingress := false
+	for _, s := range shapers {
+		dev := link
+		if s.Direction == directionIngress {
+			ingress = true
+			if dev, err = m.setupIngressRedirect(link, s.Device); err != nil {
+				return nil, err
+			}
+		}
+		if err := m.installShaper(dev, s); err != nil {
+			return nil, fmt.Errorf("%s on %s: %w", s.Direction, s.Device, err)
 		}
 	}
 
-	// 2. Create Root HTB Qdisc
-	// Handle 1:
-	rootQdisc := netlink.NewHtb(netlink.QdiscAttrs{
-		LinkIndex: link.Attrs().Index,
+	if !ingress {
+		// Download shaping may have been turned off since the last apply
+		m.removeIngressRedirect(link, ifbName(pol.Interface))
+	}
+	return shapers, nil
+}
+
+// installShaper builds an HTB tree on dev: root qdisc 1:, parent class 1:1
+// at the shaper's rate, and one child class with its own leaf queue per
+// traffic class.
+func (m *Manager) installShaper(dev netlink.Link, s shaper) error {
+	idx := dev.Attrs().Index
+
+	root := netlink.NewHtb(netlink.QdiscAttrs{
+		LinkIndex: idx,
 		Parent:    netlink.HANDLE_ROOT,
 		Handle:    netlink.MakeHandle(1, 0),
 	})
-	// Set default class to 0 (unclassified) or specific default
-	// rootQdisc.Defcls = 0
-
-	if err := netlink.QdiscAdd(rootQdisc); err != nil {
+	// Unclassified traffic must still be shaped; HTB's default of 0 would
+	// send it out unthrottled
+	root.Defcls = uint32(s.Default)
+	if err := netlink.QdiscAdd(root); err != nil {
 		return fmt.Errorf("failed to add root HTB qdisc: %w", err)
 	}
 
-	// 3. Create Root Class (Total Bandwidth)
-	// Class 1:1
-	rate := parseRate(pol.UploadMbps) // Assume upload shaping for egress interface
-	if pol.DownloadMbps > 0 && pol.UploadMbps == 0 {
-		// If only download set, maybe we rely on ingress qdisc (which is harder)
-		// For now, let's assume this policy applies to the interface's EGRESS
-		rate = parseRate(pol.DownloadMbps)
-	}
-
+	// NewHtbClass takes rates in bits/s
 	rootClass := netlink.NewHtbClass(netlink.ClassAttrs{
-		LinkIndex: link.Attrs().Index,
+		LinkIndex: idx,
 		Parent:    netlink.MakeHandle(1, 0),
 		Handle:    netlink.MakeHandle(1, 1),
 	}, netlink.HtbClassAttrs{
-		Rate:    rate,
-		Ceil:    rate,
-		Buffer:  1514, // Reasonable default
-		Cbuffer: 1514,
+		Rate: s.Rate * 8,
+		Ceil: s.Rate * 8,
 	})
-
 	if err := netlink.ClassAdd(rootClass); err != nil {
 		return fmt.Errorf("failed to add root HTB class: %w", err)
 	}
 
-	// 4. Create Child Classes
-	classIDMap := make(map[string]uint16) // Map name to minor handle
-
-	for i, class := range pol.Classes {
-		minorID := uint16(10 + i) // Start at 1:10
-		classIDMap[class.Name] = minorID
-
-		classRate := parseRateStr(class.Rate, rate) // Convert % or unit to uint64
-		classCeil := parseRateStr(class.Ceil, rate)
-		if classCeil == 0 {
-			classCeil = rate // Default ceil to max
-		}
-
-		prio := 0
-		if class.Priority > 0 {
-			prio = class.Priority
-		}
-
-		childClass := netlink.NewHtbClass(netlink.ClassAttrs{
-			LinkIndex: link.Attrs().Index,
+	for _, class := range s.Classes {
+		child := netlink.NewHtbClass(netlink.ClassAttrs{
+			LinkIndex: idx,
 			Parent:    netlink.MakeHandle(1, 1),
-			Handle:    netlink.MakeHandle(1, minorID),
+			Handle:    netlink.MakeHandle(1, class.Minor),
 		}, netlink.HtbClassAttrs{
-			// Convert to bytes/s for netlink
-			Rate:    classRate,
-			Ceil:    classCeil,
-			Prio:    uint32(prio),
-			Buffer:  1514,
-			Cbuffer: 1514,
+			Rate: class.Rate * 8,
+			Ceil: class.Ceil * 8,
+			Prio: class.Prio,
 		})
-
-		if err := netlink.ClassAdd(childClass); err != nil {
-			return fmt.Errorf("failed to add child class %s: %w", class.Name, err)
+		if err := netlink.ClassAdd(child); err != nil {
+			return fmt.Errorf("failed to add class %s: %w", class.Name, err)
 		}
-
-		// Add Leaf Qdisc (fq_codel or sfq)
-		leafParent := netlink.MakeHandle(1, minorID)
-
-		// Default to fq_codel
-		fq := netlink.NewFqCodel(netlink.QdiscAttrs{
-			LinkIndex: link.Attrs().Index,
-			Parent:    leafParent,
-			Handle:    netlink.MakeHandle(100+uint16(i), 0),
-		})
-
-		if err := netlink.QdiscAdd(fq); err != nil {
+		if err := addLeaf(dev, s, class); err != nil {
 			return fmt.Errorf("failed to add leaf qdisc for class %s: %w", class.Name, err)
 		}
 	}
 
-	// 5. Apply Classification Rules (Filters) using FWMark
-	for j, rule := range pol.Rules {
-		classMinor, ok := classIDMap[rule.Class]
-		if !ok {
-			continue
-		}
+	if s.Direction == directionEgress {
+		m.addMarkFilters(s)
+		return nil
+	}
+	return m.addFlowerFilters(dev, s)
+}
 
-		classIdx := -1
-		for idx, class := range pol.Classes {
-			if class.Name == rule.Class {
-				classIdx = idx
-				break
-			}
+// addLeaf attaches the class's queue discipline below its HTB class.
+func addLeaf(dev netlink.Link, s shaper, class shapedClass) error {
+	attrs := netlink.QdiscAttrs{
+		LinkIndex: dev.Attrs().Index,
+		Parent:    netlink.MakeHandle(1, class.Minor),
+		Handle:    netlink.MakeHandle(leafMajor(class.Minor), 0),
+	}
+
+	switch class.Queue {
+	case "cake":
+		// The netlink library has no CAKE support, so this goes through tc
+		args := cakeArgs(s.Device, class.Minor, s.Direction)
+		if out, err := exec.Command("tc", args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("tc %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
 		}
-		if classIdx == -1 {
+		return nil
+	case "sfq":
+		return netlink.QdiscAdd(&netlink.Sfq{QdiscAttrs: attrs, Perturb: 10})
+	case "pfifo":
+		return netlink.QdiscAdd(&netlink.GenericQdisc{QdiscAttrs: attrs, QdiscType: "pfifo"})
+	default:
+		return netlink.QdiscAdd(netlink.NewFqCodel(attrs))
+	}
+}
+
+// addMarkFilters classifies egress traffic by the fwmark the mangle rules set.
+func (m *Manager) addMarkFilters(s shaper) {
+	for j, class := range s.Classes {
+		if class.Mark == 0 {
 			continue
 		}
 
-		mark := CalculateFWMark(policyIdx, classIdx)
-
 		// FWMark filter using raw tc command (netlink lib limitations)
 		//
 		// CRITICAL IMPLEMENTATION NOTE:
@@ -188,46 +211,208 @@ func (m *Manager) applyPolicy(pol config.QoSPolicy, policyIdx int) error {
 		// Until the upstream library is updated/patched, we use `os/exec` for reliability on this critical path.
 		// Do not revert to `netlink.FilterAdd` for `fw` type without verifying `tc filter show` contains
 		// correct handles (e.g. 0xf000) and classids.
-		cmd := exec.Command("tc", "filter", "add", "dev", pol.Interface,
+		cmd := exec.Command("tc", "filter", "add", "dev", s.Device,
 			"parent", "1:0",
 			"protocol", "ip",
 			"prio", fmt.Sprintf("%d", 100+j),
-			"handle", fmt.Sprintf("0x%x", mark),
+			"handle", fmt.Sprintf("0x%x", class.Mark),
 			"fw",
-			"classid", fmt.Sprintf("1:%x", classMinor),
+			"classid", fmt.Sprintf("1:%x", class.Minor),
 		)
 
 		if out, err := cmd.CombinedOutput(); err != nil {
-			m.logger.Warn("failed to add fwmark filter", "mark", mark, "error", err, "output", string(out))
+			m.logger.Warn("failed to add fwmark filter", "mark", class.Mark, "error", err, "output", string(out))
+		}
+	}
+}
+
+// addFlowerFilters classifies download traffic on the IFB device. Marks
+// aren't set yet at ingress, so rules are matched on the packet headers.
+func (m *Manager) addFlowerFilters(dev netlink.Link, s shaper) error {
+	for j, f := range s.Filters {
+		flower := &netlink.Flower{
+			FilterAttrs: netlink.FilterAttrs{
+				LinkIndex: dev.Attrs().Index,
+				Parent:    netlink.MakeHandle(1, 0),
+				Priority:  uint16(100 + j),
+				Protocol:  f.EthType,
+			},
+			ClassId:  netlink.MakeHandle(1, f.Minor),
+			EthType:  f.EthType,
+			SrcPort:  f.SrcPort,
+			DestPort: f.DstPort,
+		}
+		if f.IPProto != 0 {
+			proto := nl.IPProto(f.IPProto)
+			flower.IPProto = &proto
+		}
+		if f.Src != nil {
+			flower.SrcIP = f.Src.IP
+			flower.SrcIPMask = f.Src.Mask
+		}
+		if err := netlink.FilterAdd(flower); err != nil {
+			m.logger.Warn("failed to add ingress QoS filter", "device", s.Device, "rule", f.Rule, "error", err)
 		}
 	}
 	return nil
 }
 
-// Helpers
+// setupIngressRedirect creates (or reuses) the IFB device for link and
+// redirects everything arriving on link to it, so download traffic can be
+// shaped on the IFB's egress.
+func (m *Manager) setupIngressRedirect(link netlink.Link, name string) (netlink.Link, error) {
+	ifb, err := netlink.LinkByName(name)
+	if err != nil {
+		if err := netlink.LinkAdd(&netlink.Ifb{LinkAttrs: netlink.LinkAttrs{Name: name, TxQLen: 1000}}); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", name, err)
+		}
+		if ifb, err = netlink.LinkByName(name); err != nil {
+			return nil, fmt.Errorf("failed to find %s: %w", name, err)
+		}
+	}
+	if err := netlink.LinkSetUp(ifb); err != nil {
+		return nil, fmt.Errorf("failed to bring up %s: %w", name, err)
+	}
+	if err := clearRoot(ifb); err != nil {
+		return nil, err
+	}
+
+	// Reuse an existing ingress or clsact qdisc rather than replacing it
+	if ingressQdisc(link) == nil {
+		ingress := &netlink.Ingress{QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    netlink.HANDLE_INGRESS,
+			Handle:    netlink.MakeHandle(0xffff, 0),
+		}}
+		if err := netlink.QdiscAdd(ingress); err != nil {
+			return nil, fmt.Errorf("failed to add ingress qdisc: %w", err)
+		}
+	}
 
-func parseRate(mbps int) uint64 {
-	// Mbps to Bytes/s
-	// 1 Mbps = 1000 * 1000 bits / 8 = 125,000 bytes/s
-	return uint64(mbps) * 125000
+	removeRedirects(link, ifb.Attrs().Index)
+	redirect := &netlink.MatchAll{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    netlink.HANDLE_MIN_INGRESS,
+			Priority:  1,
+			Protocol:  unix.ETH_P_ALL,
+		},
+		Actions: []netlink.Action{netlink.NewMirredAction(ifb.Attrs().Index)},
+	}
+	if err := netlink.FilterAdd(redirect); err != nil {
+		return nil, fmt.Errorf("failed to redirect ingress to %s: %w", name, err)
+	}
+	return ifb, nil
 }
 
-func parseRateStr(rateStr string, parentRate uint64) uint64 {
-	if rateStr == "" {
-		return 0
+// removeIngressRedirect undoes setupIngressRedirect. It is a no-op when
+// download shaping was never set up for link.
+func (m *Manager) removeIngressRedirect(link netlink.Link, name string) {
+	ifb, err := netlink.LinkByName(name)
+	if err != nil {
+		return
+	}
+	removeRedirects(link, ifb.Attrs().Index)
+	if q := ingressQdisc(link); q != nil && q.Type() == "ingress" {
+		netlink.QdiscDel(q)
 	}
-	// Handle percentages
-	if strings.HasSuffix(rateStr, "%") {
-		var percent float64
-		fmt.Sscanf(rateStr, "%f%%", &percent)
-		return uint64(float64(parentRate) * percent / 100.0)
+	if err := netlink.LinkDel(ifb); err != nil {
+		m.logger.Warn("failed to remove IFB device", "device", name, "error", err)
 	}
-	// Handle raw numbers (assume mbit)
-	var rate int
-	_, err := fmt.Sscanf(rateStr, "%dmbit", &rate)
-	if err == nil {
-		return parseRate(rate)
+}
+
+// QoSStats returns per-class counters for the trees installed by the last
+// ApplyConfig. It implements metrics.QoSStatsProvider.
+func (m *Manager) QoSStats() ([]metrics.QoSClassStats, error) {
+	m.mu.Lock()
+	applied := m.applied
+	m.mu.Unlock()
+
+	var stats []metrics.QoSClassStats
+	for _, s := range applied {
+		link, err := netlink.LinkByName(s.Device)
+		if err != nil {
+			continue // Interface went away; it is reshaped on the next apply
+		}
+		classes, err := netlink.ClassList(link, netlink.MakeHandle(1, 0))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list classes on %s: %w", s.Device, err)
+		}
+
+		byMinor := make(map[uint16]*netlink.ClassStatistics)
+		for _, c := range classes {
+			if htb, ok := c.(*netlink.HtbClass); ok && htb.Statistics != nil {
+				byMinor[uint16(htb.Handle&0xffff)] = htb.Statistics
+			}
+		}
+
+		for _, class := range s.Classes {
+			cs, ok := byMinor[class.Minor]
+			if !ok {
+				continue
+			}
+			st := metrics.QoSClassStats{
+				Interface: s.Device,
+				Policy:    s.Policy,
+				Class:     class.Name,
+				Direction: s.Direction,
+			}
+			if cs.Basic != nil {
+				st.Bytes = cs.Basic.Bytes
+				st.Packets = uint64(cs.Basic.Packets)
+			}
+			if cs.Queue != nil {
+				st.Drops = uint64(cs.Queue.Drops)
+				st.Overlimits = uint64(cs.Queue.Overlimits)
+				st.Backlog = uint64(cs.Queue.Backlog)
+			}
+			stats = append(stats, st)
+		}
 	}
+	return stats, nil
+}
 
-	return 0 // Fallback
+// clearRoot deletes the root qdisc, which takes its classes and filters with it.
+func clearRoot(link netlink.Link) error {
+	qdiscs, err := netlink.QdiscList(link)
+	if err != nil {
+		return fmt.Errorf("failed to list qdiscs: %w", err)
+	}
+	for _, q := range qdiscs {
+		if q.Attrs().Parent == netlink.HANDLE_ROOT {
+			netlink.QdiscDel(q)
+		}
+	}
+	return nil
+}
+
+// ingressQdisc returns link's ingress or clsact qdisc, if it has one.
+func ingressQdisc(link netlink.Link) netlink.Qdisc {
+	qdiscs, _ := netlink.QdiscList(link)
+	for _, q := range qdiscs {
+		if q.Attrs().Parent == netlink.HANDLE_INGRESS {
+			return q
+		}
+	}
+	return nil
+}
+
+// removeRedirects deletes ingress filters on link that redirect to ifbIndex.
+func removeRedirects(link netlink.Link, ifbIndex int) {
+	filters, err := netlink.FilterList(link, netlink.HANDLE_MIN_INGRESS)
+	if err != nil {
+		return
+	}
+	for _, f := range filters {
+		ma, ok := f.(*netlink.MatchAll)
+		if !ok {
+			continue
+		}
+		for _, a := range ma.Actions {
+			if mirred, ok := a.(*netlink.MirredAction); ok && mirred.Ifindex == ifbIndex {
+				netlink.FilterDel(f)
+				break
+			}
+		}
+	}
 }
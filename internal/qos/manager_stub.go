@@ -8,6 +8,7 @@ package qos
 import (
 	"grimm.is/flywall/internal/config"
 	"grimm.is/flywall/internal/logging"
+	"grimm.is/flywall/internal/metrics"
 )
 
 // Manager handles QoS traffic shaping configuration (Stub).
@@ -22,3 +23,8 @@ func NewManager(logger *logging.Logger) *Manager {
 func (m *Manager) ApplyConfig(cfg *config.Config) error {
 	return nil
 }
+
+// QoSStats returns per-class QoS statistics (Stub).
+func (m *Manager) QoSStats() ([]metrics.QoSClassStats, error) {
+	return nil, nil
+}
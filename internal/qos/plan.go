@@ -0,0 +1,294 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package qos
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"grimm.is/flywall/internal/config"
+)
+
+const (
+	directionEgress  = "egress"
+	directionIngress = "ingress"
+
+	// defaultQueue is the leaf qdisc used when neither the class nor the
+	// policy sets queue_type.
+	defaultQueue = "fq_codel"
+
+	// defaultClassMinor is the catch-all class added when a policy has no
+	// class named "default". Configured classes start at 1:10.
+	defaultClassMinor = 9
+
+	ethPIP   = 0x0800
+	ethPIPv6 = 0x86DD
+
+	ipProtoICMP = 1
+	ipProtoTCP  = 6
+	ipProtoUDP  = 17
+)
+
+// shaper is one HTB tree: either on the interface itself (egress), or on
+// the IFB device its incoming traffic is redirected to (ingress).
+type shaper struct {
+	Policy    string
+	Interface string // Configured interface
+	Device    string // Device the tree is attached to
+	Direction string // "egress" or "ingress"
+	Rate      uint64 // Bytes/s
+	Default   uint16 // Minor of the class unclassified traffic falls into
+	Classes   []shapedClass
+	Filters   []flowFilter // Ingress only; egress classifies by fwmark
+}
+
+// shapedClass is an HTB class and its leaf queue.
+type shapedClass struct {
+	Name  string
+	Minor uint16
+	Rate  uint64 // Bytes/s
+	Ceil  uint64 // Bytes/s
+	Prio  uint32
+	Queue string
+	Mark  uint32 // fwmark set by the mangle rules, 0 for the catch-all class
+}
+
+// flowFilter matches download traffic into a class. Rules describe flows
+// as they leave the network, so on ingress the match is reversed: the
+// rule's destination is the packet's source.
+type flowFilter struct {
+	Rule    string
+	Minor   uint16
+	EthType uint16
+	IPProto uint8
+	Src     *net.IPNet
+	SrcPort uint16
+	DstPort uint16
+}
+
+// ifbName returns the IFB device used for download shaping on iface,
+// following the sqm-scripts "ifb4<iface>" convention.
+func ifbName(iface string) string {
+	name := "ifb4" + iface
+	if len(name) > 15 { // IFNAMSIZ - 1
+		name = name[:15]
+	}
+	return name
+}
+
+// leafMajor is the handle major of a class's leaf qdisc: 100 for 1:10,
+// 101 for 1:11 and so on.
+func leafMajor(minor uint16) uint16 {
+	return 90 + minor
+}
+
+// buildShapers plans the HTB trees for a policy. It also returns the
+// names of rules that can't be matched on ingress.
+func buildShapers(pol config.QoSPolicy, policyIdx int) ([]shaper, []string) {
+	var shapers []shaper
+	var unmatched []string
+
+	egress, ingress := true, true
+	switch pol.Direction {
+	case directionEgress:
+		ingress = false
+	case directionIngress:
+		egress = false
+	}
+
+	if egress && pol.UploadMbps > 0 {
+		shapers = append(shapers, buildShaper(pol, policyIdx, directionEgress, pol.Interface, parseRate(pol.UploadMbps)))
+	}
+	if ingress && pol.DownloadMbps > 0 {
+		s := buildShaper(pol, policyIdx, directionIngress, ifbName(pol.Interface), parseRate(pol.DownloadMbps))
+		s.Filters, unmatched = ingressFilters(pol, s)
+		shapers = append(shapers, s)
+	}
+	return shapers, unmatched
+}
+
+func buildShaper(pol config.QoSPolicy, policyIdx int, direction, device string, rate uint64) shaper {
+	s := shaper{
+		Policy:    pol.Name,
+		Interface: pol.Interface,
+		Device:    device,
+		Direction: direction,
+		Rate:      rate,
+		Default:   defaultClassMinor,
+	}
+
+	policyQueue := pol.QueueType
+	if policyQueue == "" {
+		policyQueue = defaultQueue
+	}
+
+	hasDefault := false
+	for i, class := range pol.Classes {
+		c := shapedClass{
+			Name:  class.Name,
+			Minor: uint16(10 + i),
+			Rate:  parseRateStr(class.Rate, rate),
+			Ceil:  parseRateStr(class.Ceil, rate),
+			Prio:  uint32(class.Priority),
+			Queue: class.QueueType,
+			Mark:  CalculateFWMark(policyIdx, i),
+		}
+		if c.Rate == 0 {
+			c.Rate = rate / 10 // HTB rejects a zero rate
+		}
+		if c.Ceil < c.Rate {
+			c.Ceil = rate // Default ceil to max
+		}
+		if c.Queue == "" {
+			c.Queue = policyQueue
+		}
+		if class.Name == "default" {
+			s.Default = c.Minor
+			hasDefault = true
+		}
+		s.Classes = append(s.Classes, c)
+	}
+
+	if !hasDefault {
+		// Catch-all for unclassified traffic, lowest priority but allowed
+		// to borrow up to the full rate
+		s.Classes = append(s.Classes, shapedClass{
+			Name:  "default",
+			Minor: defaultClassMinor,
+			Rate:  max(rate/100, 1),
+			Ceil:  rate,
+			Prio:  7,
+			Queue: policyQueue,
+		})
+	}
+	return s
+}
+
+// ingressFilters turns the policy's rules into header matches for
+// download traffic. Source addresses are skipped: on the WAN side the
+// local host is hidden behind NAT until after the ingress hook.
+func ingressFilters(pol config.QoSPolicy, s shaper) ([]flowFilter, []string) {
+	minors := make(map[string]uint16)
+	for _, c := range s.Classes {
+		minors[c.Name] = c.Minor
+	}
+
+	var filters []flowFilter
+	var unmatched []string
+	for _, rule := range pol.Rules {
+		minor, ok := minors[rule.Class]
+		if !ok {
+			continue
+		}
+
+		var remote *net.IPNet
+		if rule.DestIP != "" {
+			remote = parseIPNet(rule.DestIP)
+		}
+		hasProto := rule.Protocol == "tcp" || rule.Protocol == "udp" || rule.Protocol == "icmp"
+		if remote == nil && rule.DestPort == 0 && rule.SrcPort == 0 && !hasProto {
+			unmatched = append(unmatched, rule.Name)
+			continue
+		}
+
+		// Flower needs an explicit family, and a transport protocol to
+		// match ports. Expand unspecified ones like nft's "th dport" does.
+		ethTypes := []uint16{ethPIP, ethPIPv6}
+		if remote != nil {
+			ethTypes = ethTypes[:1]
+			if remote.IP.To4() == nil {
+				ethTypes = []uint16{ethPIPv6}
+			}
+		}
+		var protos []uint8
+		switch rule.Protocol {
+		case "tcp":
+			protos = []uint8{ipProtoTCP}
+		case "udp":
+			protos = []uint8{ipProtoUDP}
+		case "icmp":
+			protos = []uint8{ipProtoICMP}
+			ethTypes = []uint16{ethPIP}
+		default:
+			protos = []uint8{0}
+			if rule.DestPort > 0 || rule.SrcPort > 0 {
+				protos = []uint8{ipProtoTCP, ipProtoUDP}
+			}
+		}
+
+		for _, eth := range ethTypes {
+			for _, proto := range protos {
+				filters = append(filters, flowFilter{
+					Rule:    rule.Name,
+					Minor:   minor,
+					EthType: eth,
+					IPProto: proto,
+					Src:     remote,
+					SrcPort: uint16(rule.DestPort),
+					DstPort: uint16(rule.SrcPort),
+				})
+			}
+		}
+	}
+	return filters, unmatched
+}
+
+// cakeArgs returns the tc arguments for a CAKE leaf. HTB does the
+// shaping, so CAKE runs unlimited and provides diffserv4 tins and
+// per-host fairness keyed on the internal address behind NAT.
+func cakeArgs(device string, minor uint16, direction string) []string {
+	args := []string{
+		"qdisc", "add", "dev", device,
+		"parent", fmt.Sprintf("1:%x", minor),
+		"handle", fmt.Sprintf("%x:", leafMajor(minor)),
+		"cake", "unlimited", "diffserv4", "nat",
+	}
+	if direction == directionIngress {
+		return append(args, "dual-dsthost", "ingress")
+	}
+	return append(args, "dual-srchost")
+}
+
+func parseIPNet(s string) *net.IPNet {
+	if _, n, err := net.ParseCIDR(s); err == nil {
+		return n
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil
+	}
+	bits := 128
+	if v4 := ip.To4(); v4 != nil {
+		ip, bits = v4, 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+}
+
+// Helpers
+
+func parseRate(mbps int) uint64 {
+	// Mbps to Bytes/s
+	// 1 Mbps = 1000 * 1000 bits / 8 = 125,000 bytes/s
+	return uint64(mbps) * 125000
+}
+
+func parseRateStr(rateStr string, parentRate uint64) uint64 {
+	if rateStr == "" {
+		return 0
+	}
+	// Handle percentages
+	if strings.HasSuffix(rateStr, "%") {
+		var percent float64
+		fmt.Sscanf(rateStr, "%f%%", &percent)
+		return uint64(float64(parentRate) * percent / 100.0)
+	}
+	// Handle raw numbers (assume mbit)
+	var rate int
+	_, err := fmt.Sscanf(rateStr, "%dmbit", &rate)
+	if err == nil {
+		return parseRate(rate)
+	}
+
+	return 0 // Fallback
+}
@@ -0,0 +1,165 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package qos
+
+import (
+	"reflect"
+	"testing"
+
+	"grimm.is/flywall/internal/config"
+)
+
+func testPolicy() config.QoSPolicy {
+	return config.QoSPolicy{
+		Name:         "wan",
+		Interface:    "eth0",
+		Enabled:      true,
+		UploadMbps:   20,
+		DownloadMbps: 100,
+		Classes: []config.QoSClass{
+			{Name: "voip", Priority: 1, Rate: "10%", QueueType: "pfifo"},
+			{Name: "bulk", Priority: 5, Rate: "50%", Ceil: "80%"},
+		},
+		Rules: []config.QoSRule{
+			{Name: "sip", Class: "voip", Protocol: "udp", DestPort: 5060},
+			{Name: "backup", Class: "bulk", DestIP: "203.0.113.0/24"},
+			{Name: "lan-host", Class: "bulk", SrcIP: "192.168.1.50"},
+		},
+	}
+}
+
+func TestBuildShapers_Directions(t *testing.T) {
+	pol := testPolicy()
+
+	shapers, _ := buildShapers(pol, 0)
+	if len(shapers) != 2 {
+		t.Fatalf("Expected egress and ingress trees, got %d", len(shapers))
+	}
+	egress, ingress := shapers[0], shapers[1]
+	if egress.Direction != directionEgress || egress.Device != "eth0" || egress.Rate != parseRate(20) {
+		t.Errorf("Unexpected egress tree: %+v", egress)
+	}
+	if ingress.Direction != directionIngress || ingress.Device != "ifb4eth0" || ingress.Rate != parseRate(100) {
+		t.Errorf("Unexpected ingress tree: %+v", ingress)
+	}
+
+	// Download only: must not fall back to shaping egress at the download rate
+	pol.UploadMbps = 0
+	shapers, _ = buildShapers(pol, 0)
+	if len(shapers) != 1 || shapers[0].Direction != directionIngress {
+		t.Errorf("Expected only an ingress tree, got %+v", shapers)
+	}
+
+	pol.UploadMbps = 20
+	pol.Direction = "egress"
+	shapers, _ = buildShapers(pol, 0)
+	if len(shapers) != 1 || shapers[0].Direction != directionEgress {
+		t.Errorf("Expected only an egress tree, got %+v", shapers)
+	}
+}
+
+func TestBuildShaper_Classes(t *testing.T) {
+	pol := testPolicy()
+	pol.QueueType = "cake"
+
+	s := buildShaper(pol, 2, directionEgress, "eth0", parseRate(20))
+	if len(s.Classes) != 3 {
+		t.Fatalf("Expected 2 classes plus catch-all, got %+v", s.Classes)
+	}
+
+	voip, bulk, def := s.Classes[0], s.Classes[1], s.Classes[2]
+	if voip.Minor != 10 || voip.Queue != "pfifo" || voip.Mark != CalculateFWMark(2, 0) {
+		t.Errorf("Unexpected voip class: %+v", voip)
+	}
+	if voip.Ceil != s.Rate {
+		t.Errorf("Expected unset ceil to default to the full rate, got %d", voip.Ceil)
+	}
+	if bulk.Queue != "cake" || bulk.Ceil != s.Rate*80/100 {
+		t.Errorf("Expected bulk to inherit the policy queue, got %+v", bulk)
+	}
+	if def.Name != "default" || def.Minor != defaultClassMinor || def.Mark != 0 || s.Default != defaultClassMinor {
+		t.Errorf("Unexpected catch-all class: %+v (default %d)", def, s.Default)
+	}
+
+	// A configured "default" class takes unclassified traffic instead
+	pol.Classes = append(pol.Classes, config.QoSClass{Name: "default", Rate: "40%"})
+	s = buildShaper(pol, 0, directionEgress, "eth0", parseRate(20))
+	if len(s.Classes) != 3 || s.Default != 12 {
+		t.Errorf("Expected configured default class 1:12, got default %d in %+v", s.Default, s.Classes)
+	}
+
+	// Rate and queue defaults
+	pol = config.QoSPolicy{Name: "p", Interface: "eth0", Classes: []config.QoSClass{{Name: "any"}}}
+	s = buildShaper(pol, 0, directionEgress, "eth0", 1000)
+	if c := s.Classes[0]; c.Rate != 100 || c.Queue != defaultQueue {
+		t.Errorf("Expected 10%% rate and fq_codel, got %+v", c)
+	}
+}
+
+func TestIngressFilters(t *testing.T) {
+	pol := testPolicy()
+	shapers, unmatched := buildShapers(pol, 0)
+	ingress := shapers[1]
+
+	if !reflect.DeepEqual(unmatched, []string{"lan-host"}) {
+		t.Errorf("Expected source-address rule to be unmatched, got %v", unmatched)
+	}
+
+	var sip, backup []flowFilter
+	for _, f := range ingress.Filters {
+		switch f.Rule {
+		case "sip":
+			sip = append(sip, f)
+		case "backup":
+			backup = append(backup, f)
+		}
+	}
+
+	// Replies come from the server port, over both families
+	if len(sip) != 2 {
+		t.Fatalf("Expected IPv4 and IPv6 filters for sip, got %+v", sip)
+	}
+	for _, f := range sip {
+		if f.Minor != 10 || f.IPProto != ipProtoUDP || f.SrcPort != 5060 || f.DstPort != 0 {
+			t.Errorf("Unexpected sip filter: %+v", f)
+		}
+	}
+	if sip[0].EthType != ethPIP || sip[1].EthType != ethPIPv6 {
+		t.Errorf("Expected one filter per family, got %+v", sip)
+	}
+
+	// The rule's destination network is the packet's source
+	if len(backup) != 1 || backup[0].Src.String() != "203.0.113.0/24" || backup[0].EthType != ethPIP {
+		t.Errorf("Unexpected backup filter: %+v", backup)
+	}
+
+	// Ports without a protocol expand to TCP and UDP
+	pol.Rules = []config.QoSRule{{Name: "game", Class: "voip", DestPort: 3074}}
+	filters, _ := ingressFilters(pol, ingress)
+	if len(filters) != 4 {
+		t.Errorf("Expected tcp/udp x ipv4/ipv6 filters, got %+v", filters)
+	}
+}
+
+func TestCakeArgs(t *testing.T) {
+	egress := cakeArgs("eth0", 10, directionEgress)
+	want := []string{"qdisc", "add", "dev", "eth0", "parent", "1:a", "handle", "64:",
+		"cake", "unlimited", "diffserv4", "nat", "dual-srchost"}
+	if !reflect.DeepEqual(egress, want) {
+		t.Errorf("cakeArgs egress = %v; want %v", egress, want)
+	}
+
+	ingress := cakeArgs("ifb4eth0", 11, directionIngress)
+	if got := ingress[len(ingress)-2:]; !reflect.DeepEqual(got, []string{"dual-dsthost", "ingress"}) {
+		t.Errorf("Expected ingress CAKE to key on destination hosts, got %v", ingress)
+	}
+}
+
+func TestIfbName(t *testing.T) {
+	if got := ifbName("eth0"); got != "ifb4eth0" {
+		t.Errorf("ifbName(eth0) = %q", got)
+	}
+	if got := ifbName("enp0s31f6.100"); len(got) != 15 {
+		t.Errorf("Expected name truncated to 15 characters, got %q", got)
+	}
+}
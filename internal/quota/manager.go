@@ -0,0 +1,477 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+// Package quota enforces daily, weekly and monthly traffic quotas on
+// devices and device groups, using usage recorded by flow analytics.
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"grimm.is/flywall/internal/alerting"
+	"grimm.is/flywall/internal/clock"
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/errors"
+	"grimm.is/flywall/internal/firewall"
+	"grimm.is/flywall/internal/logging"
+	"grimm.is/flywall/internal/state"
+)
+
+// BucketQuotas persists counter resets and alert state across restarts.
+const BucketQuotas = "quotas"
+
+// defaultWarnPercent is used when a quota doesn't set warn_percent.
+const defaultWarnPercent = 80
+
+// UsageSource reports bytes sent per source MAC over a time range.
+type UsageSource interface {
+	GetUsageByMAC(from, to time.Time) (map[string]int64, error)
+}
+
+// GroupResolver returns the MACs of a device group's members.
+type GroupResolver interface {
+	GroupMACs(group string) []string
+}
+
+// SetUpdater replaces the contents of an nftables set.
+type SetUpdater interface {
+	ReloadSet(name string, elements []string) error
+}
+
+// Alerter receives quota warnings.
+type Alerter interface {
+	Trigger(event alerting.AlertEvent)
+}
+
+// Status is the state of one counter: a subject's usage in one period.
+type Status struct {
+	Quota    string    `json:"quota"`
+	Subject  string    `json:"subject"` // MAC, or "group:<name>" for a shared counter
+	MACs     []string  `json:"macs"`
+	Period   string    `json:"period"`
+	Limit    int64     `json:"limit_bytes"`
+	Used     int64     `json:"used_bytes"`
+	Percent  float64   `json:"percent"`
+	Exceeded bool      `json:"exceeded"`
+	Action   string    `json:"action"`
+	Since    time.Time `json:"since"`     // Period start, or the last manual reset
+	ResetsAt time.Time `json:"resets_at"` // Next scheduled reset
+}
+
+// counterState is the persisted state of one counter.
+type counterState struct {
+	Start    time.Time `json:"start"`              // Period these flags belong to
+	ResetAt  time.Time `json:"reset_at,omitempty"` // Manual reset within the period
+	Warned   bool      `json:"warned,omitempty"`
+	Exceeded bool      `json:"exceeded,omitempty"`
+}
+
+type subject struct {
+	ID   string
+	MACs []string
+}
+
+// Manager evaluates quotas and keeps the firewall's quota sets in sync.
+type Manager struct {
+	store  state.Store
+	usage  UsageSource
+	groups GroupResolver
+	sets   SetUpdater
+	alerts Alerter
+	logger *logging.Logger
+	clock  clock.Clock
+
+	mu       sync.RWMutex
+	quotas   []config.DeviceQuota
+	policies []config.QoSPolicy
+	counters map[string]*counterState // "<quota>/<subject>/<period>"
+	status   []Status
+	flush    func(mac string) (int, error)
+	enforced map[string]bool // MACs in the blocked or a throttle set
+}
+
+// NewManager creates a quota manager. groups and alerts may be nil.
+func NewManager(store state.Store, usage UsageSource, groups GroupResolver, sets SetUpdater, alerts Alerter) *Manager {
+	m := &Manager{
+		store:    store,
+		usage:    usage,
+		groups:   groups,
+		sets:     sets,
+		alerts:   alerts,
+		logger:   logging.WithComponent("quota"),
+		clock:    &clock.RealClock{},
+		counters: make(map[string]*counterState),
+		enforced: make(map[string]bool),
+	}
+
+	if err := store.CreateBucket(BucketQuotas); err != nil && err != state.ErrBucketExists {
+		m.logger.Error("Failed to create quotas bucket", "error", err)
+	}
+	entries, err := store.List(BucketQuotas)
+	if err != nil {
+		m.logger.Error("Failed to load quota state", "error", err)
+	}
+	for key, data := range entries {
+		var c counterState
+		if err := json.Unmarshal(data, &c); err == nil {
+			m.counters[key] = &c
+		}
+	}
+	return m
+}
+
+// SetConnectionFlusher sets the function that drops a device's tracked
+// connections once it is blocked or throttled. Offloaded connections skip
+// the ruleset, so without it they would keep their full speed.
+func (m *Manager) SetConnectionFlusher(flush func(mac string) (int, error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.flush = flush
+}
+
+// UpdateConfig replaces the configured quotas and re-applies enforcement,
+// which also refills the quota sets after a firewall reload.
+func (m *Manager) UpdateConfig(cfg *config.Config) {
+	m.mu.Lock()
+	m.quotas = cfg.Quotas
+	m.policies = cfg.QoSPolicies
+	m.mu.Unlock()
+
+	m.Evaluate(m.clock.Now())
+}
+
+// Start re-evaluates quotas every minute until ctx is cancelled.
+func (m *Manager) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.Evaluate(m.clock.Now())
+			}
+		}
+	}()
+}
+
+// Status returns the counters from the last evaluation.
+func (m *Manager) Status() []Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res := make([]Status, len(m.status))
+	copy(res, m.status)
+	return res
+}
+
+// Reset zeroes a quota's counters for the current periods and lifts any
+// enforcement. An empty subject resets every subject of the quota.
+func (m *Manager) Reset(quotaName, subjectID string) error {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	var q *config.DeviceQuota
+	for i := range m.quotas {
+		if m.quotas[i].Name == quotaName {
+			q = &m.quotas[i]
+			break
+		}
+	}
+	if q == nil {
+		m.mu.Unlock()
+		return errors.Errorf(errors.KindNotFound, "quota %q not found", quotaName)
+	}
+
+	found := false
+	for _, s := range m.subjects(*q) {
+		if subjectID != "" && s.ID != subjectID {
+			continue
+		}
+		found = true
+		for _, period := range []string{PeriodDaily, PeriodWeekly, PeriodMonthly} {
+			key := counterKey(q.Name, s.ID, period)
+			m.counters[key] = &counterState{
+				Start:   periodStart(period, now, q.ResetDay),
+				ResetAt: now,
+			}
+			m.saveCounter(key)
+		}
+	}
+	m.mu.Unlock()
+
+	if !found {
+		return errors.Errorf(errors.KindNotFound, "quota %q has no subject %q", quotaName, subjectID)
+	}
+
+	m.Evaluate(now)
+	return nil
+}
+
+// Evaluate recomputes usage, raises alerts for newly crossed thresholds
+// and updates the quota sets.
+func (m *Manager) Evaluate(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Usage is queried once per distinct start time
+	usageFrom := make(map[time.Time]map[string]int64)
+	usageSince := func(from time.Time) map[string]int64 {
+		if u, ok := usageFrom[from]; ok {
+			return u
+		}
+		raw, err := m.usage.GetUsageByMAC(from, now)
+		if err != nil {
+			m.logger.Warn("Failed to read device usage", "error", err)
+		}
+		u := make(map[string]int64, len(raw))
+		for mac, b := range raw {
+			u[normalizeMAC(mac)] += b
+		}
+		usageFrom[from] = u
+		return u
+	}
+
+	var status []Status
+	blocked := make(map[string]bool)
+	throttled := make(map[string]map[string]bool)
+
+	for _, q := range m.quotas {
+		action := q.Action
+		if action == "" {
+			action = "block"
+		}
+		warnPercent := defaultWarnPercent
+		if q.WarnPercent != nil {
+			warnPercent = *q.WarnPercent
+		}
+		throttleSet, _, canThrottle := firewall.QuotaThrottleTarget(q, m.policies)
+		if canThrottle && throttled[throttleSet] == nil {
+			throttled[throttleSet] = make(map[string]bool)
+		}
+
+		for _, period := range []string{PeriodDaily, PeriodWeekly, PeriodMonthly} {
+			limit := limitFor(q, period)
+			if limit <= 0 {
+				continue
+			}
+			start := periodStart(period, now, q.ResetDay)
+
+			for _, s := range m.subjects(q) {
+				key := counterKey(q.Name, s.ID, period)
+				c := m.counters[key]
+				if c == nil || !c.Start.Equal(start) {
+					// New period: counters reset on schedule
+					c = &counterState{Start: start}
+					m.counters[key] = c
+					m.saveCounter(key)
+				}
+
+				since := start
+				if c.ResetAt.After(since) {
+					since = c.ResetAt
+				}
+				usage := usageSince(since)
+				var used int64
+				for _, mac := range s.MACs {
+					used += usage[mac]
+				}
+
+				st := Status{
+					Quota:    q.Name,
+					Subject:  s.ID,
+					MACs:     s.MACs,
+					Period:   period,
+					Limit:    limit,
+					Used:     used,
+					Percent:  float64(used) * 100 / float64(limit),
+					Exceeded: used >= limit,
+					Action:   action,
+					Since:    since,
+					ResetsAt: periodEnd(period, start),
+				}
+				status = append(status, st)
+
+				if st.Exceeded {
+					for _, mac := range s.MACs {
+						if canThrottle {
+							throttled[throttleSet][mac] = true
+						} else {
+							blocked[mac] = true
+						}
+					}
+					if !c.Exceeded {
+						c.Exceeded, c.Warned = true, true
+						m.saveCounter(key)
+						verb := "blocked"
+						if canThrottle {
+							verb = "throttled"
+						}
+						m.alert(st, alerting.LevelWarning, "quota.exceeded",
+							fmt.Sprintf("%s quota %q exceeded by %s: %d of %d bytes, %s until %s",
+								period, q.Name, s.ID, used, limit, verb, st.ResetsAt.Format(time.RFC1123)))
+					}
+				} else if warnPercent > 0 && used*100 >= limit*int64(warnPercent) && !c.Warned {
+					c.Warned = true
+					m.saveCounter(key)
+					m.alert(st, alerting.LevelInfo, "quota.warning",
+						fmt.Sprintf("%s quota %q for %s at %.0f%% (%d of %d bytes)",
+							period, q.Name, s.ID, st.Percent, used, limit))
+				}
+			}
+		}
+	}
+	m.status = status
+
+	// Sets are refilled on every pass: a firewall reload recreates them empty
+	if len(m.quotas) > 0 {
+		m.reloadSet(firewall.QuotaBlockedSet, blocked)
+	}
+	for set, macs := range throttled {
+		m.reloadSet(set, macs)
+	}
+
+	enforced := make(map[string]bool, len(blocked))
+	for mac := range blocked {
+		enforced[mac] = true
+	}
+	for _, macs := range throttled {
+		for mac := range macs {
+			enforced[mac] = true
+		}
+	}
+	m.flushNewlyEnforced(enforced)
+}
+
+// flushNewlyEnforced drops the connections of devices that were just added
+// to a quota set, after the sets are updated, so they restart under the
+// block or throttle. Caller must hold m.mu.
+func (m *Manager) flushNewlyEnforced(enforced map[string]bool) {
+	prev := m.enforced
+	m.enforced = enforced
+	if m.flush == nil {
+		return
+	}
+
+	macs := make([]string, 0, len(enforced))
+	for mac := range enforced {
+		if !prev[mac] {
+			macs = append(macs, mac)
+		}
+	}
+	sort.Strings(macs)
+	for _, mac := range macs {
+		if n, err := m.flush(mac); err != nil {
+			m.logger.Warn("Failed to drop connections of over-quota device", "mac", mac, "error", err)
+		} else if n > 0 {
+			m.logger.Info("Dropped connections of over-quota device", "mac", mac, "count", n)
+		}
+	}
+}
+
+// subjects returns the counters a quota keeps: one per listed device, plus
+// either one shared counter for the group or one per group member.
+// Caller must hold m.mu.
+func (m *Manager) subjects(q config.DeviceQuota) []subject {
+	var subjects []subject
+	seen := make(map[string]bool)
+	addDevice := func(mac string) {
+		mac = normalizeMAC(mac)
+		if !seen[mac] {
+			seen[mac] = true
+			subjects = append(subjects, subject{ID: mac, MACs: []string{mac}})
+		}
+	}
+
+	for _, mac := range q.Devices {
+		addDevice(mac)
+	}
+	if q.Group == "" || m.groups == nil {
+		return subjects
+	}
+
+	members := m.groups.GroupMACs(q.Group)
+	if q.PerDevice {
+		for _, mac := range members {
+			addDevice(mac)
+		}
+		return subjects
+	}
+
+	macs := make([]string, 0, len(members))
+	for _, mac := range members {
+		macs = append(macs, normalizeMAC(mac))
+	}
+	sort.Strings(macs)
+	return append(subjects, subject{ID: "group:" + q.Group, MACs: macs})
+}
+
+func (m *Manager) reloadSet(name string, macs map[string]bool) {
+	elems := make([]string, 0, len(macs))
+	for mac := range macs {
+		elems = append(elems, mac)
+	}
+	sort.Strings(elems)
+	if err := m.sets.ReloadSet(name, elems); err != nil {
+		m.logger.Warn("Failed to update quota set", "set", name, "count", len(elems), "error", err)
+	}
+}
+
+func (m *Manager) alert(st Status, level alerting.AlertLevel, ruleID, msg string) {
+	m.logger.Info(msg)
+	if m.alerts == nil {
+		return
+	}
+	m.alerts.Trigger(alerting.AlertEvent{
+		RuleID:    ruleID,
+		RuleName:  "Device Quota",
+		Message:   msg,
+		Severity:  level,
+		Timestamp: m.clock.Now(),
+		Data:      st,
+	})
+}
+
+// saveCounter persists a counter. Caller must hold m.mu.
+func (m *Manager) saveCounter(key string) {
+	if err := m.store.SetJSON(BucketQuotas, key, m.counters[key]); err != nil {
+		m.logger.Warn("Failed to persist quota state", "key", key, "error", err)
+	}
+}
+
+func limitFor(q config.DeviceQuota, period string) int64 {
+	var s string
+	switch period {
+	case PeriodDaily:
+		s = q.Daily
+	case PeriodWeekly:
+		s = q.Weekly
+	case PeriodMonthly:
+		s = q.Monthly
+	}
+	if s == "" {
+		return 0
+	}
+	n, err := config.ParseByteSize(s)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func counterKey(quota, subject, period string) string {
+	return quota + "/" + subject + "/" + period
+}
+
+func normalizeMAC(mac string) string {
+	if hw, err := net.ParseMAC(mac); err == nil {
+		return hw.String()
+	}
+	return mac
+}
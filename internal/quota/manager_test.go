@@ -0,0 +1,272 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package quota
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"grimm.is/flywall/internal/alerting"
+	"grimm.is/flywall/internal/clock"
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/firewall"
+	"grimm.is/flywall/internal/state"
+)
+
+const (
+	macA = "aa:bb:cc:dd:ee:01"
+	macB = "aa:bb:cc:dd:ee:02"
+)
+
+// fakeUsage reports usage recorded at given times, so counters only see
+// traffic after their start.
+type fakeUsage struct {
+	records []struct {
+		at    time.Time
+		mac   string
+		bytes int64
+	}
+}
+
+func (f *fakeUsage) add(at time.Time, mac string, bytes int64) {
+	f.records = append(f.records, struct {
+		at    time.Time
+		mac   string
+		bytes int64
+	}{at, mac, bytes})
+}
+
+func (f *fakeUsage) GetUsageByMAC(from, to time.Time) (map[string]int64, error) {
+	usage := make(map[string]int64)
+	for _, r := range f.records {
+		if !r.at.Before(from) && !r.at.After(to) {
+			usage[r.mac] += r.bytes
+		}
+	}
+	return usage, nil
+}
+
+type fakeGroups map[string][]string
+
+func (f fakeGroups) GroupMACs(group string) []string { return f[group] }
+
+type fakeSets map[string][]string
+
+func (f fakeSets) ReloadSet(name string, elements []string) error {
+	f[name] = elements
+	return nil
+}
+
+type fakeAlerts []alerting.AlertEvent
+
+func (f *fakeAlerts) Trigger(event alerting.AlertEvent) { *f = append(*f, event) }
+
+// testNow is a Wednesday afternoon, well clear of period boundaries.
+var testNow = time.Date(2026, 3, 4, 15, 30, 0, 0, time.Local)
+
+func newTestManager(t *testing.T, usage UsageSource, groups GroupResolver) (*Manager, fakeSets, *fakeAlerts) {
+	t.Helper()
+	store, err := state.NewSQLiteStore(state.DefaultOptions(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	sets := make(fakeSets)
+	alerts := &fakeAlerts{}
+	m := NewManager(store, usage, groups, sets, alerts)
+	m.clock = clock.NewMockClock(testNow)
+	return m, sets, alerts
+}
+
+func TestPeriodStart(t *testing.T) {
+	// Wednesday
+	now := time.Date(2026, 3, 4, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		period   string
+		resetDay int
+		want     time.Time
+	}{
+		{PeriodDaily, 0, time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{PeriodWeekly, 0, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{PeriodMonthly, 0, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{PeriodMonthly, 15, time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := periodStart(tt.period, now, tt.resetDay); !got.Equal(tt.want) {
+			t.Errorf("periodStart(%s, reset %d) = %v; want %v", tt.period, tt.resetDay, got, tt.want)
+		}
+	}
+
+	// Sunday belongs to the week that started on Monday
+	sunday := time.Date(2026, 3, 8, 23, 0, 0, 0, time.UTC)
+	if got := periodStart(PeriodWeekly, sunday, 0); got.Day() != 2 {
+		t.Errorf("Expected Sunday to fall in the week of the 2nd, got %v", got)
+	}
+	if got := periodEnd(PeriodMonthly, time.Date(2026, 12, 15, 0, 0, 0, 0, time.UTC)); got.Year() != 2027 || got.Month() != 1 {
+		t.Errorf("Expected monthly period to roll over the year, got %v", got)
+	}
+}
+
+func TestEvaluate_BlockAndWarn(t *testing.T) {
+	now := testNow
+	usage := &fakeUsage{}
+	usage.add(now.Add(-time.Second), macA, 850)
+	usage.add(now.Add(-time.Second), macB, 100)
+
+	m, sets, alerts := newTestManager(t, usage, nil)
+	m.UpdateConfig(&config.Config{Quotas: []config.DeviceQuota{
+		{Name: "guests", Devices: []string{"AA:BB:CC:DD:EE:01", macB}, Daily: "1000"},
+	}})
+
+	if len(*alerts) != 1 || (*alerts)[0].RuleID != "quota.warning" {
+		t.Fatalf("Expected one warning at 85%%, got %+v", *alerts)
+	}
+	if len(sets[firewall.QuotaBlockedSet]) != 0 {
+		t.Errorf("Nothing should be blocked yet, got %v", sets[firewall.QuotaBlockedSet])
+	}
+
+	usage.add(now, macA, 200)
+	m.Evaluate(now)
+	if !reflect.DeepEqual(sets[firewall.QuotaBlockedSet], []string{macA}) {
+		t.Errorf("Expected %s blocked, got %v", macA, sets[firewall.QuotaBlockedSet])
+	}
+	if len(*alerts) != 2 || (*alerts)[1].RuleID != "quota.exceeded" {
+		t.Errorf("Expected an exceeded alert, got %+v", *alerts)
+	}
+
+	// Alerts fire once per period
+	m.Evaluate(now)
+	if len(*alerts) != 2 {
+		t.Errorf("Expected no repeated alerts, got %d", len(*alerts))
+	}
+
+	status := m.Status()
+	if len(status) != 2 || status[0].Subject != macA || !status[0].Exceeded || status[0].Used != 1050 {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+}
+
+func TestEvaluate_GroupThrottle(t *testing.T) {
+	now := testNow
+	usage := &fakeUsage{}
+	usage.add(now.Add(-time.Second), macA, 600)
+	usage.add(now.Add(-time.Second), macB, 600)
+	groups := fakeGroups{"kids": {macA, macB}}
+
+	m, sets, _ := newTestManager(t, usage, groups)
+	cfg := &config.Config{
+		QoSPolicies: []config.QoSPolicy{{Name: "wan", Classes: []config.QoSClass{{Name: "fast"}, {Name: "slow"}}}},
+		Quotas: []config.DeviceQuota{
+			{Name: "kids", Group: "kids", Weekly: "1000", Action: "throttle", QoSPolicy: "wan", QoSClass: "slow"},
+		},
+	}
+
+	// Shared counter: together the members are over quota
+	m.UpdateConfig(cfg)
+	if got := sets["quota_throttle_f001"]; !reflect.DeepEqual(got, []string{macA, macB}) {
+		t.Errorf("Expected both members throttled, got %v", got)
+	}
+	if len(sets[firewall.QuotaBlockedSet]) != 0 {
+		t.Errorf("Throttled quota must not block, got %v", sets[firewall.QuotaBlockedSet])
+	}
+
+	// Per device: neither member is over quota on its own
+	cfg.Quotas[0].PerDevice = true
+	m.UpdateConfig(cfg)
+	if got := sets["quota_throttle_f001"]; len(got) != 0 {
+		t.Errorf("Expected no throttled devices, got %v", got)
+	}
+	if len(m.Status()) != 2 {
+		t.Errorf("Expected a counter per member, got %+v", m.Status())
+	}
+}
+
+func TestEvaluate_FlushesNewlyEnforcedDevices(t *testing.T) {
+	now := testNow
+	usage := &fakeUsage{}
+	usage.add(now.Add(-time.Second), macA, 2000)
+
+	m, _, _ := newTestManager(t, usage, nil)
+	var flushed []string
+	m.SetConnectionFlusher(func(mac string) (int, error) {
+		flushed = append(flushed, mac)
+		return 1, nil
+	})
+	cfg := &config.Config{Quotas: []config.DeviceQuota{
+		{Name: "guests", Devices: []string{macA, macB}, Daily: "1000"},
+	}}
+
+	// Offloaded connections would bypass the new block
+	m.UpdateConfig(cfg)
+	if !reflect.DeepEqual(flushed, []string{macA}) {
+		t.Fatalf("Expected %s's connections dropped, got %v", macA, flushed)
+	}
+
+	// Only on entering a set, not on every pass
+	m.Evaluate(now)
+	usage.add(now, macB, 2000)
+	m.Evaluate(now)
+	if !reflect.DeepEqual(flushed, []string{macA, macB}) {
+		t.Errorf("Expected only %s dropped next, got %v", macB, flushed)
+	}
+}
+
+func TestReset(t *testing.T) {
+	now := testNow
+	usage := &fakeUsage{}
+	usage.add(now.Add(-time.Minute), macA, 2000)
+
+	m, sets, _ := newTestManager(t, usage, nil)
+	m.UpdateConfig(&config.Config{Quotas: []config.DeviceQuota{
+		{Name: "guests", Devices: []string{macA}, Daily: "1000", Monthly: "5000"},
+	}})
+	if len(sets[firewall.QuotaBlockedSet]) != 1 {
+		t.Fatalf("Expected device blocked before reset, got %v", sets[firewall.QuotaBlockedSet])
+	}
+
+	if err := m.Reset("guests", macA); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if len(sets[firewall.QuotaBlockedSet]) != 0 {
+		t.Errorf("Expected device unblocked after reset, got %v", sets[firewall.QuotaBlockedSet])
+	}
+	for _, st := range m.Status() {
+		if st.Used != 0 {
+			t.Errorf("Expected %s counter to restart from zero, got %d", st.Period, st.Used)
+		}
+	}
+
+	if err := m.Reset("nope", ""); err == nil {
+		t.Error("Expected error for unknown quota")
+	}
+	if err := m.Reset("guests", macB); err == nil {
+		t.Error("Expected error for unknown subject")
+	}
+}
+
+func TestManager_PersistsState(t *testing.T) {
+	store, err := state.NewSQLiteStore(state.DefaultOptions(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	now := testNow
+	usage := &fakeUsage{}
+	usage.add(now.Add(-time.Second), macA, 900)
+	cfg := &config.Config{Quotas: []config.DeviceQuota{{Name: "guests", Devices: []string{macA}, Daily: "1000"}}}
+
+	alerts := &fakeAlerts{}
+	for i := 0; i < 2; i++ {
+		// A restart must not repeat the warning
+		m := NewManager(store, usage, nil, make(fakeSets), alerts)
+		m.clock = clock.NewMockClock(now)
+		m.UpdateConfig(cfg)
+	}
+	if len(*alerts) != 1 {
+		t.Errorf("Expected one warning across restarts, got %d", len(*alerts))
+	}
+}
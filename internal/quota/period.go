@@ -0,0 +1,50 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package quota
+
+import "time"
+
+// Counting periods. Boundaries are in the system's local time.
+const (
+	PeriodDaily   = "daily"
+	PeriodWeekly  = "weekly"
+	PeriodMonthly = "monthly"
+)
+
+// MaxPeriod is the longest span of history a quota counts over. Flow
+// analytics must be retained at least this long.
+const MaxPeriod = 32 * 24 * time.Hour
+
+// periodStart returns the start of the period containing now. Weeks start
+// on Monday; months start on resetDay (1-28).
+func periodStart(period string, now time.Time, resetDay int) time.Time {
+	y, m, d := now.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+
+	switch period {
+	case PeriodWeekly:
+		return day.AddDate(0, 0, -((int(now.Weekday()) + 6) % 7))
+	case PeriodMonthly:
+		if resetDay < 1 {
+			resetDay = 1
+		}
+		if d < resetDay {
+			m--
+		}
+		return time.Date(y, m, resetDay, 0, 0, 0, 0, now.Location())
+	default:
+		return day
+	}
+}
+
+// periodEnd returns when a period that started at start resets.
+func periodEnd(period string, start time.Time) time.Time {
+	switch period {
+	case PeriodWeekly:
+		return start.AddDate(0, 0, 7)
+	case PeriodMonthly:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
@@ -4,8 +4,10 @@ package routing
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 
 	"grimm.is/flywall/internal/logging"
@@ -13,7 +15,14 @@ import (
 	"grimm.is/flywall/internal/config"
 )
 
+const (
+	frrConfPath    = "/etc/frr/frr.conf"
+	frrDaemonsPath = "/etc/frr/daemons"
+	frrReloadPath  = "/usr/lib/frr/frr-reload.py"
+)
+
 // ConfigureFRR generates the FRR configuration and starts/reloads the service.
+// It is a no-op when the generated files match what is already on disk.
 func ConfigureFRR(cfg *config.FRRConfig) error {
 	if cfg == nil || !cfg.Enabled {
 		return stopFRR()
@@ -28,42 +37,54 @@ func ConfigureFRR(cfg *config.FRRConfig) error {
 		return fmt.Errorf("failed to create /etc/frr: %w", err)
 	}
 
-	if err := os.WriteFile("/etc/frr/frr.conf", []byte(confContent), 0644); err != nil {
+	oldConf, _ := os.ReadFile(frrConfPath)
+	oldDaemons, _ := os.ReadFile(frrDaemonsPath)
+
+	// Neighbor passwords end up in the file, so keep it from other users
+	if err := os.WriteFile(frrConfPath, []byte(confContent), 0640); err != nil {
 		return fmt.Errorf("failed to write frr.conf: %w", err)
 	}
 
 	// Ensure daemons file enables required daemons
 	daemonsContent := generateDaemonsFile(cfg)
-	if err := os.WriteFile("/etc/frr/daemons", []byte(daemonsContent), 0644); err != nil {
+	if err := os.WriteFile(frrDaemonsPath, []byte(daemonsContent), 0644); err != nil {
 		return fmt.Errorf("failed to write daemons file: %w", err)
 	}
 
+	// Daemons are only (re)started by the init script, which also loads
+	// frr.conf, so a changed daemon set needs a restart rather than a reload.
+	if string(oldDaemons) != daemonsContent {
+		if output, err := exec.Command("rc-service", "frr", "restart").CombinedOutput(); err != nil {
+			logging.Warn("failed to restart FRR", "error", err, "output", string(output))
+		}
+		return nil
+	}
+	_ = exec.Command("rc-service", "frr", "start").Run() // No-op when already running
+
+	if string(oldConf) == confContent {
+		return nil
+	}
+
+	// frr-reload.py diffs against the running config, so removed neighbors
+	// and route-map entries are withdrawn rather than left behind.
+	if _, err := os.Stat(frrReloadPath); err == nil {
+		if output, err := exec.Command(frrReloadPath, "--reload", frrConfPath).CombinedOutput(); err != nil {
+			logging.Warn("frr-reload failed, falling back to vtysh", "error", err, "output", string(output))
+		} else {
+			return nil
+		}
+	}
+
 	// Apply config via vtysh (dynamic update)
 	// We pipe the config to vtysh to avoid ARG_MAX limits.
-	// We assume 'configure terminal' is implied or we prepend it?
-	// The generated config is a full file.
-	// We'll wrap it in 'configure terminal' block just in case, or rely on vtysh processing.
-	// Actually, vtysh handles full config import often.
-	// Let's prepend 'configure terminal' and append 'end' / 'write memory'.
-
 	// Note: header lines like 'frr version' might generate benign errors in vtysh
-
 	cmd := exec.Command("vtysh")
-
-	// Construct input: configure terminal <config> end write memory
-	// We strip "frr version" etc for cleaner apply?
-	// For now, pass as is, wrapping in conf t
-
 	input := "configure terminal\n" + confContent + "\nend\nwrite memory\n"
 	cmd.Stdin = strings.NewReader(input)
 
 	if output, err := cmd.CombinedOutput(); err != nil {
 		// Log warning but don't fail hard, as some lines might be idempotent errors
 		logging.Warn("partial failure applying FRR config via vtysh", "error", err, "output", string(output))
-
-		// Fallback to restart if dynamic apply looks completely broken?
-		// Or just stick to restart if this fails?
-		// The user urged Stdin to fix ARG_MAX.
 	}
 
 	return nil
@@ -84,6 +105,11 @@ func generateFRRConf(cfg *config.FRRConfig) string {
 	sb.WriteString("service integrated-vtysh-config\n")
 	sb.WriteString("!\n")
 
+	writeBFDProfiles(&sb, cfg.BFDProfiles)
+	families := writePrefixLists(&sb, cfg.PrefixLists)
+	writeCommunityLists(&sb, cfg.CommunityLists)
+	writeRouteMaps(&sb, cfg.RouteMaps, families)
+
 	if cfg.OSPF != nil {
 		sb.WriteString("router ospf\n")
 		if cfg.OSPF.RouterID != "" {
@@ -101,34 +127,287 @@ func generateFRRConf(cfg *config.FRRConfig) string {
 	}
 
 	if cfg.BGP != nil {
-		sb.WriteString(fmt.Sprintf("router bgp %d\n", cfg.BGP.ASN))
-		if cfg.BGP.RouterID != "" {
-			sb.WriteString(fmt.Sprintf(" bgp router-id %s\n", cfg.BGP.RouterID))
+		writeBGP(&sb, cfg.BGP)
+	}
+
+	sb.WriteString("line vty\n")
+	sb.WriteString("!\n")
+	return sb.String()
+}
+
+func writeBFDProfiles(sb *strings.Builder, profiles []config.BFDProfile) {
+	if len(profiles) == 0 {
+		return
+	}
+	sb.WriteString("bfd\n")
+	for _, p := range profiles {
+		sb.WriteString(fmt.Sprintf(" profile %s\n", p.Name))
+		if p.DetectMultiplier > 0 {
+			sb.WriteString(fmt.Sprintf("  detect-multiplier %d\n", p.DetectMultiplier))
 		}
-		for _, n := range cfg.BGP.Neighbors {
-			sb.WriteString(fmt.Sprintf(" neighbor %s remote-as %d\n", n.IP, n.RemoteASN))
+		if p.ReceiveInterval > 0 {
+			sb.WriteString(fmt.Sprintf("  receive-interval %d\n", p.ReceiveInterval))
 		}
-		for _, net := range cfg.BGP.Networks {
-			sb.WriteString(fmt.Sprintf(" network %s\n", net))
+		if p.TransmitInterval > 0 {
+			sb.WriteString(fmt.Sprintf("  transmit-interval %d\n", p.TransmitInterval))
 		}
-		sb.WriteString("!\n")
+		sb.WriteString(" exit\n")
 	}
+	sb.WriteString("exit\n")
+	sb.WriteString("!\n")
+}
 
-	sb.WriteString("line vty\n")
+// writePrefixLists emits prefix lists and returns the address family of
+// each, which route-maps need to pick "match ip" or "match ipv6". Lists
+// made only of "any" rules are emitted for both families.
+func writePrefixLists(sb *strings.Builder, lists []config.PrefixList) map[string]string {
+	families := make(map[string]string, len(lists))
+	if len(lists) == 0 {
+		return families
+	}
+	for _, pl := range lists {
+		family := prefixListFamily(pl)
+		families[pl.Name] = family
+
+		keywords := []string{"ip"}
+		switch family {
+		case "ipv6":
+			keywords = []string{"ipv6"}
+		case "":
+			keywords = []string{"ip", "ipv6"}
+		}
+		for _, kw := range keywords {
+			for i, r := range pl.Rules {
+				seq := r.Seq
+				if seq == 0 {
+					seq = (i + 1) * 5
+				}
+				line := fmt.Sprintf("%s prefix-list %s seq %d %s %s", kw, pl.Name, seq, action(r.Action), r.Prefix)
+				if r.GE > 0 {
+					line += fmt.Sprintf(" ge %d", r.GE)
+				}
+				if r.LE > 0 {
+					line += fmt.Sprintf(" le %d", r.LE)
+				}
+				sb.WriteString(line + "\n")
+			}
+		}
+	}
 	sb.WriteString("!\n")
-	return sb.String()
+	return families
+}
+
+// prefixListFamily returns "ipv4" or "ipv6" from the list's prefixes, or ""
+// if it only contains "any".
+func prefixListFamily(pl config.PrefixList) string {
+	for _, r := range pl.Rules {
+		if r.Prefix == "any" {
+			continue
+		}
+		if p, err := netip.ParsePrefix(r.Prefix); err == nil && p.Addr().Is6() {
+			return "ipv6"
+		}
+		return "ipv4"
+	}
+	return ""
+}
+
+func writeCommunityLists(sb *strings.Builder, lists []config.CommunityList) {
+	if len(lists) == 0 {
+		return
+	}
+	for _, cl := range lists {
+		// One entry per community: FRR requires all communities on a single
+		// line to be present, while the list matches any of them.
+		for _, comm := range cl.Communities {
+			sb.WriteString(fmt.Sprintf("bgp community-list standard %s %s %s\n", cl.Name, action(cl.Action), comm))
+		}
+	}
+	sb.WriteString("!\n")
+}
+
+func writeRouteMaps(sb *strings.Builder, maps []config.RouteMap, families map[string]string) {
+	for _, rm := range maps {
+		for i, r := range rm.Rules {
+			seq := r.Seq
+			if seq == 0 {
+				seq = (i + 1) * 10
+			}
+			sb.WriteString(fmt.Sprintf("route-map %s %s %d\n", rm.Name, action(r.Action), seq))
+			if r.MatchPrefixList != "" {
+				kw := "ip"
+				if families[r.MatchPrefixList] == "ipv6" {
+					kw = "ipv6"
+				}
+				sb.WriteString(fmt.Sprintf(" match %s address prefix-list %s\n", kw, r.MatchPrefixList))
+			}
+			if r.MatchCommunity != "" {
+				sb.WriteString(fmt.Sprintf(" match community %s\n", r.MatchCommunity))
+			}
+			if r.SetLocalPreference > 0 {
+				sb.WriteString(fmt.Sprintf(" set local-preference %d\n", r.SetLocalPreference))
+			}
+			if r.SetMetric != nil {
+				sb.WriteString(fmt.Sprintf(" set metric %d\n", *r.SetMetric))
+			}
+			if len(r.SetCommunity) > 0 {
+				line := " set community " + strings.Join(r.SetCommunity, " ")
+				if r.SetCommunityAdditive {
+					line += " additive"
+				}
+				sb.WriteString(line + "\n")
+			}
+			if len(r.SetASPathPrepend) > 0 {
+				asns := make([]string, len(r.SetASPathPrepend))
+				for j, asn := range r.SetASPathPrepend {
+					asns[j] = strconv.Itoa(asn)
+				}
+				sb.WriteString(" set as-path prepend " + strings.Join(asns, " ") + "\n")
+			}
+			sb.WriteString("exit\n")
+			sb.WriteString("!\n")
+		}
+	}
+}
+
+func writeBGP(sb *strings.Builder, bgp *config.BGP) {
+	sb.WriteString(fmt.Sprintf("router bgp %d\n", bgp.ASN))
+	if bgp.RouterID != "" {
+		sb.WriteString(fmt.Sprintf(" bgp router-id %s\n", bgp.RouterID))
+	}
+	// Peers are activated explicitly per address family below
+	sb.WriteString(" no bgp default ipv4-unicast\n")
+
+	for _, n := range bgp.Neighbors {
+		sb.WriteString(fmt.Sprintf(" neighbor %s remote-as %d\n", n.IP, n.RemoteASN))
+		if n.Description != "" {
+			sb.WriteString(fmt.Sprintf(" neighbor %s description %s\n", n.IP, n.Description))
+		}
+		if n.Password != "" {
+			sb.WriteString(fmt.Sprintf(" neighbor %s password %s\n", n.IP, string(n.Password)))
+		}
+		if n.EBGPMultihop > 0 {
+			sb.WriteString(fmt.Sprintf(" neighbor %s ebgp-multihop %d\n", n.IP, n.EBGPMultihop))
+		}
+		if n.UpdateSource != "" {
+			sb.WriteString(fmt.Sprintf(" neighbor %s update-source %s\n", n.IP, n.UpdateSource))
+		}
+		if n.BFDProfile != "" {
+			sb.WriteString(fmt.Sprintf(" neighbor %s bfd profile %s\n", n.IP, n.BFDProfile))
+		} else if n.BFD {
+			sb.WriteString(fmt.Sprintf(" neighbor %s bfd\n", n.IP))
+		}
+	}
+
+	for _, family := range []string{"ipv4", "ipv6"} {
+		var networks []string
+		for _, net := range bgp.Networks {
+			if networkFamily(net) == family {
+				networks = append(networks, net)
+			}
+		}
+		var peers []bgpPeer
+		for _, n := range bgp.Neighbors {
+			if af, ok := neighborFamily(n, family); ok {
+				peers = append(peers, bgpPeer{n.IP, af})
+			}
+		}
+		if len(networks) == 0 && len(peers) == 0 {
+			continue
+		}
+
+		sb.WriteString(" !\n")
+		sb.WriteString(fmt.Sprintf(" address-family %s unicast\n", family))
+		for _, net := range networks {
+			sb.WriteString(fmt.Sprintf("  network %s\n", net))
+		}
+		for _, p := range peers {
+			sb.WriteString(fmt.Sprintf("  neighbor %s activate\n", p.ip))
+			if p.af.NextHopSelf {
+				sb.WriteString(fmt.Sprintf("  neighbor %s next-hop-self\n", p.ip))
+			}
+			if p.af.SoftReconfiguration {
+				sb.WriteString(fmt.Sprintf("  neighbor %s soft-reconfiguration inbound\n", p.ip))
+			}
+			if p.af.DefaultOriginate {
+				sb.WriteString(fmt.Sprintf("  neighbor %s default-originate\n", p.ip))
+			}
+			if p.af.PrefixListIn != "" {
+				sb.WriteString(fmt.Sprintf("  neighbor %s prefix-list %s in\n", p.ip, p.af.PrefixListIn))
+			}
+			if p.af.PrefixListOut != "" {
+				sb.WriteString(fmt.Sprintf("  neighbor %s prefix-list %s out\n", p.ip, p.af.PrefixListOut))
+			}
+			if p.af.RouteMapIn != "" {
+				sb.WriteString(fmt.Sprintf("  neighbor %s route-map %s in\n", p.ip, p.af.RouteMapIn))
+			}
+			if p.af.RouteMapOut != "" {
+				sb.WriteString(fmt.Sprintf("  neighbor %s route-map %s out\n", p.ip, p.af.RouteMapOut))
+			}
+			if p.af.MaxPrefix > 0 {
+				sb.WriteString(fmt.Sprintf("  neighbor %s maximum-prefix %d\n", p.ip, p.af.MaxPrefix))
+			}
+		}
+		sb.WriteString(" exit-address-family\n")
+	}
+	sb.WriteString("!\n")
+}
+
+// bgpPeer is a neighbor activated in one address family.
+type bgpPeer struct {
+	ip string
+	af config.NeighborAddressFamily
+}
+
+// neighborFamily returns the neighbor's settings for family and whether it
+// is activated for it. Neighbors without address_family blocks are activated
+// for the family of their own address.
+func neighborFamily(n config.Neighbor, family string) (config.NeighborAddressFamily, bool) {
+	if len(n.AddressFamilies) == 0 {
+		return config.NeighborAddressFamily{Family: family}, networkFamily(n.IP) == family
+	}
+	for _, af := range n.AddressFamilies {
+		if af.Family == family {
+			return af, true
+		}
+	}
+	return config.NeighborAddressFamily{}, false
+}
+
+// networkFamily returns "ipv6" for IPv6 addresses and prefixes, "ipv4" otherwise.
+func networkFamily(s string) string {
+	if strings.Contains(s, ":") {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+func action(a string) string {
+	if a == "" {
+		return "permit"
+	}
+	return a
 }
 
 func generateDaemonsFile(cfg *config.FRRConfig) string {
 	// Enable/Disable daemons based on config
 	ospf := "no"
 	bgp := "no"
+	bfd := "no"
 
 	if cfg.OSPF != nil {
 		ospf = "yes"
 	}
 	if cfg.BGP != nil {
 		bgp = "yes"
+		for _, n := range cfg.BGP.Neighbors {
+			if n.BFD || n.BFDProfile != "" {
+				bfd = "yes"
+			}
+		}
+	}
+	if len(cfg.BFDProfiles) > 0 {
+		bfd = "yes"
 	}
 
 	return fmt.Sprintf(`
@@ -146,8 +425,8 @@ eigrp=no
 babeld=no
 sharpd=no
 pbrd=no
-bfdd=no
+bfdd=%s
 fabricd=no
 vrrpd=no
-`, bgp, ospf)
+`, bgp, ospf, bfd)
 }
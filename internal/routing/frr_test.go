@@ -3,12 +3,17 @@
 package routing
 
 import (
+	"flag"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"grimm.is/flywall/internal/config"
 )
 
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
 func TestGenerateDaemonsFile(t *testing.T) {
 	tests := []struct {
 		name string
@@ -40,7 +45,14 @@ func TestGenerateDaemonsFile(t *testing.T) {
 		{
 			name: "None Enabled",
 			cfg:  &config.FRRConfig{},
-			want: []string{"ospfd=no", "bgpd=no"},
+			want: []string{"ospfd=no", "bgpd=no", "bfdd=no"},
+		},
+		{
+			name: "BFD Neighbor",
+			cfg: &config.FRRConfig{
+				BGP: &config.BGP{Neighbors: []config.Neighbor{{IP: "10.0.0.2", BFD: true}}},
+			},
+			want: []string{"bgpd=yes", "bfdd=yes"},
 		},
 	}
 
@@ -120,3 +132,103 @@ func TestGenerateFRRConf(t *testing.T) {
 		})
 	}
 }
+
+func intPtr(i int) *int { return &i }
+
+// TestGenerateFRRConf_Golden compares full generated configs against
+// testdata/*.golden. Run with -update to regenerate them.
+func TestGenerateFRRConf_Golden(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *config.FRRConfig
+	}{
+		{
+			name: "ospf",
+			cfg: &config.FRRConfig{
+				OSPF: &config.OSPF{
+					RouterID: "10.255.0.2",
+					Areas:    []config.OSPFArea{{ID: "0.0.0.0", Networks: []string{"10.10.0.0/24", "10.255.0.0/30"}}},
+				},
+			},
+		},
+		{
+			name: "bgp_basic",
+			cfg: &config.FRRConfig{
+				BGP: &config.BGP{
+					ASN:       65001,
+					RouterID:  "10.255.0.2",
+					Networks:  []string{"10.20.0.0/24", "2001:db8:20::/48"},
+					Neighbors: []config.Neighbor{{IP: "10.255.0.1", RemoteASN: 65000}, {IP: "2001:db8::1", RemoteASN: 65000}},
+				},
+			},
+		},
+		{
+			name: "bgp_policy",
+			cfg: &config.FRRConfig{
+				PrefixLists: []config.PrefixList{
+					{Name: "own", Rules: []config.PrefixListRule{{Prefix: "203.0.113.0/24"}}},
+					{Name: "own6", Rules: []config.PrefixListRule{{Prefix: "2001:db8::/32", LE: 48}}},
+					{Name: "no-default", Rules: []config.PrefixListRule{
+						{Seq: 10, Action: "deny", Prefix: "0.0.0.0/0"},
+						{Seq: 20, Prefix: "0.0.0.0/0", LE: 24},
+					}},
+					{Name: "deny-all", Rules: []config.PrefixListRule{{Action: "deny", Prefix: "any"}}},
+				},
+				CommunityLists: []config.CommunityList{
+					{Name: "blackhole", Communities: []string{"65000:666", "blackhole"}},
+				},
+				RouteMaps: []config.RouteMap{
+					{Name: "transit-in", Rules: []config.RouteMapRule{
+						{Action: "deny", MatchCommunity: "blackhole"},
+						{MatchPrefixList: "no-default", SetLocalPreference: 200, SetCommunity: []string{"65001:100"}, SetCommunityAdditive: true},
+					}},
+					{Name: "transit-out", Rules: []config.RouteMapRule{
+						{MatchPrefixList: "own", SetMetric: intPtr(50), SetASPathPrepend: []int{65001, 65001}},
+					}},
+					{Name: "transit-out6", Rules: []config.RouteMapRule{{MatchPrefixList: "own6"}}},
+				},
+				BFDProfiles: []config.BFDProfile{
+					{Name: "fast", DetectMultiplier: 3, ReceiveInterval: 100, TransmitInterval: 100},
+				},
+				BGP: &config.BGP{
+					ASN:      65001,
+					RouterID: "192.0.2.1",
+					Networks: []string{"203.0.113.0/24", "2001:db8::/32"},
+					Neighbors: []config.Neighbor{
+						{
+							IP: "192.0.2.254", RemoteASN: 64500, Description: "transit", Password: "s3cret",
+							EBGPMultihop: 2, UpdateSource: "lo", BFDProfile: "fast",
+							AddressFamilies: []config.NeighborAddressFamily{
+								{Family: "ipv4", RouteMapIn: "transit-in", RouteMapOut: "transit-out", SoftReconfiguration: true, MaxPrefix: 1000000},
+								{Family: "ipv6", PrefixListIn: "deny-all", RouteMapOut: "transit-out6"},
+							},
+						},
+						{
+							IP: "10.0.0.2", RemoteASN: 65001, BFD: true,
+							AddressFamilies: []config.NeighborAddressFamily{{Family: "ipv4", NextHopSelf: true, DefaultOriginate: true}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := generateFRRConf(tt.cfg)
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden file (run with -update to create): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("generateFRRConf() mismatch with %s\n--- got ---\n%s\n--- want ---\n%s", golden, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Status is a snapshot of FRR's live protocol state.
+type Status struct {
+	Running       bool           `json:"running"`
+	BGPPeers      []BGPPeer      `json:"bgp_peers,omitempty"`
+	OSPFNeighbors []OSPFNeighbor `json:"ospf_neighbors,omitempty"`
+	BFDPeers      []BFDPeer      `json:"bfd_peers,omitempty"`
+	Routes        []Route        `json:"routes,omitempty"`
+	Errors        []string       `json:"errors,omitempty"` // Queries that failed while others succeeded
+}
+
+// BGPPeer is one BGP session in one address family.
+type BGPPeer struct {
+	Address          string `json:"address"`
+	Family           string `json:"family"` // "ipv4" or "ipv6"
+	RemoteASN        int64  `json:"remote_asn"`
+	Description      string `json:"description,omitempty"`
+	State            string `json:"state"`
+	Uptime           string `json:"uptime,omitempty"`
+	PrefixesReceived int    `json:"prefixes_received"`
+	PrefixesSent     int    `json:"prefixes_sent"`
+}
+
+// Established reports whether the session is up.
+func (p BGPPeer) Established() bool {
+	return p.State == "Established"
+}
+
+// OSPFNeighbor is an OSPF adjacency.
+type OSPFNeighbor struct {
+	RouterID  string `json:"router_id"`
+	Address   string `json:"address"`
+	Interface string `json:"interface"`
+	State     string `json:"state"` // e.g. "Full/DR"
+	Priority  int    `json:"priority"`
+}
+
+// BFDPeer is a BFD session.
+type BFDPeer struct {
+	Peer       string `json:"peer"`
+	Local      string `json:"local,omitempty"`
+	Interface  string `json:"interface,omitempty"`
+	Status     string `json:"status"`           // "up", "down", "init" or "shutdown"
+	Uptime     int64  `json:"uptime,omitempty"` // Seconds
+	Diagnostic string `json:"diagnostic,omitempty"`
+}
+
+// Route is an entry in FRR's RIB.
+type Route struct {
+	Prefix    string   `json:"prefix"`
+	Protocol  string   `json:"protocol"`
+	Selected  bool     `json:"selected"`
+	Installed bool     `json:"installed"`
+	Distance  int      `json:"distance"`
+	Metric    int      `json:"metric"`
+	Uptime    string   `json:"uptime,omitempty"`
+	Nexthops  []string `json:"nexthops,omitempty"` // "via 10.0.0.1 dev eth0" style
+}
+
+// vtyshTimeout bounds each show command so a wedged daemon can't hang callers.
+const vtyshTimeout = 5 * time.Second
+
+// runVtysh runs a single vtysh command. Replaced in tests.
+var runVtysh = func(ctx context.Context, command string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, vtyshTimeout)
+	defer cancel()
+	return exec.CommandContext(ctx, "vtysh", "-c", command).Output()
+}
+
+// GetStatus queries FRR for BGP, OSPF and BFD sessions and the routes it
+// knows about. It fails only when vtysh can't reach FRR at all; protocols
+// whose daemon isn't running are left empty.
+func GetStatus(ctx context.Context) (*Status, error) {
+	st := &Status{}
+	queries := []struct {
+		command string
+		parse   func([]byte) error
+	}{
+		{"show bgp summary json", func(b []byte) (err error) {
+			st.BGPPeers, err = parseBGPSummary(b)
+			return err
+		}},
+		{"show ip ospf neighbor json", func(b []byte) (err error) {
+			st.OSPFNeighbors, err = parseOSPFNeighbors(b)
+			return err
+		}},
+		{"show bfd peers json", func(b []byte) (err error) {
+			st.BFDPeers, err = parseBFDPeers(b)
+			return err
+		}},
+		{"show ip route json", func(b []byte) error {
+			routes, err := parseRoutes(b)
+			st.Routes = append(st.Routes, routes...)
+			return err
+		}},
+		{"show ipv6 route json", func(b []byte) error {
+			routes, err := parseRoutes(b)
+			st.Routes = append(st.Routes, routes...)
+			return err
+		}},
+	}
+
+	for _, q := range queries {
+		out, err := runVtysh(ctx, q.command)
+		if err != nil {
+			st.Errors = append(st.Errors, fmt.Sprintf("%s: %v", q.command, err))
+			continue
+		}
+		st.Running = true
+		// Daemons that aren't running answer with a plain-text notice
+		if !json.Valid(out) {
+			continue
+		}
+		if err := q.parse(out); err != nil {
+			st.Errors = append(st.Errors, fmt.Sprintf("%s: %v", q.command, err))
+		}
+	}
+
+	if !st.Running {
+		return nil, fmt.Errorf("FRR is not running: %s", strings.Join(st.Errors, "; "))
+	}
+	return st, nil
+}
+
+// parseBGPSummary parses "show bgp summary json", which is keyed by
+// address family ("ipv4Unicast", "ipv6Unicast", ...).
+func parseBGPSummary(data []byte) ([]BGPPeer, error) {
+	var summary map[string]struct {
+		Peers map[string]struct {
+			RemoteAs   int64  `json:"remoteAs"`
+			State      string `json:"state"`
+			PeerUptime string `json:"peerUptime"`
+			PfxRcd     int    `json:"pfxRcd"`
+			PfxSnt     int    `json:"pfxSnt"`
+			Desc       string `json:"desc"`
+		} `json:"peers"`
+	}
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, fmt.Errorf("parse bgp summary: %w", err)
+	}
+
+	var peers []BGPPeer
+	for afi, af := range summary {
+		family := "ipv4"
+		switch {
+		case strings.HasPrefix(afi, "ipv6"):
+			family = "ipv6"
+		case !strings.HasPrefix(afi, "ipv4"):
+			continue
+		}
+		for addr, p := range af.Peers {
+			peers = append(peers, BGPPeer{
+				Address:          addr,
+				Family:           family,
+				RemoteASN:        p.RemoteAs,
+				Description:      p.Desc,
+				State:            p.State,
+				Uptime:           p.PeerUptime,
+				PrefixesReceived: p.PfxRcd,
+				PrefixesSent:     p.PfxSnt,
+			})
+		}
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		if peers[i].Family != peers[j].Family {
+			return peers[i].Family < peers[j].Family
+		}
+		return peers[i].Address < peers[j].Address
+	})
+	return peers, nil
+}
+
+// parseOSPFNeighbors parses "show ip ospf neighbor json". The field names
+// changed across FRR releases, so both spellings are accepted.
+func parseOSPFNeighbors(data []byte) ([]OSPFNeighbor, error) {
+	var out struct {
+		Neighbors map[string][]struct {
+			Priority     int    `json:"priority"`
+			State        string `json:"state"`
+			NbrState     string `json:"nbrState"`
+			Address      string `json:"address"`
+			IfaceAddress string `json:"ifaceAddress"`
+			IfaceName    string `json:"ifaceName"`
+		} `json:"neighbors"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("parse ospf neighbors: %w", err)
+	}
+
+	var neighbors []OSPFNeighbor
+	for routerID, adjs := range out.Neighbors {
+		for _, a := range adjs {
+			n := OSPFNeighbor{
+				RouterID: routerID,
+				Address:  a.Address,
+				State:    a.State,
+				Priority: a.Priority,
+				// "eth0:10.0.0.2" names the local interface and address
+				Interface: strings.SplitN(a.IfaceName, ":", 2)[0],
+			}
+			if n.Address == "" {
+				n.Address = a.IfaceAddress
+			}
+			if n.State == "" {
+				n.State = a.NbrState
+			}
+			neighbors = append(neighbors, n)
+		}
+	}
+	sort.Slice(neighbors, func(i, j int) bool { return neighbors[i].RouterID < neighbors[j].RouterID })
+	return neighbors, nil
+}
+
+// parseBFDPeers parses "show bfd peers json".
+func parseBFDPeers(data []byte) ([]BFDPeer, error) {
+	var raw []struct {
+		Peer       string `json:"peer"`
+		Local      string `json:"local"`
+		Interface  string `json:"interface"`
+		Status     string `json:"status"`
+		Uptime     int64  `json:"uptime"`
+		Diagnostic string `json:"diagnostic"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse bfd peers: %w", err)
+	}
+
+	peers := make([]BFDPeer, 0, len(raw))
+	for _, p := range raw {
+		peers = append(peers, BFDPeer{
+			Peer:       p.Peer,
+			Local:      p.Local,
+			Interface:  p.Interface,
+			Status:     p.Status,
+			Uptime:     p.Uptime,
+			Diagnostic: p.Diagnostic,
+		})
+	}
+	return peers, nil
+}
+
+// parseRoutes parses "show ip route json" or "show ipv6 route json", which
+// map each prefix to the candidate routes for it.
+func parseRoutes(data []byte) ([]Route, error) {
+	var rib map[string][]struct {
+		Prefix    string `json:"prefix"`
+		Protocol  string `json:"protocol"`
+		Selected  bool   `json:"selected"`
+		Installed bool   `json:"installed"`
+		Distance  int    `json:"distance"`
+		Metric    int    `json:"metric"`
+		Uptime    string `json:"uptime"`
+		Nexthops  []struct {
+			IP            string `json:"ip"`
+			InterfaceName string `json:"interfaceName"`
+			DirectlyConn  bool   `json:"directlyConnected"`
+			Active        bool   `json:"active"`
+		} `json:"nexthops"`
+	}
+	if err := json.Unmarshal(data, &rib); err != nil {
+		return nil, fmt.Errorf("parse routes: %w", err)
+	}
+
+	var routes []Route
+	for prefix, entries := range rib {
+		for _, e := range entries {
+			r := Route{
+				Prefix:    prefix,
+				Protocol:  e.Protocol,
+				Selected:  e.Selected,
+				Installed: e.Installed,
+				Distance:  e.Distance,
+				Metric:    e.Metric,
+				Uptime:    e.Uptime,
+			}
+			for _, nh := range e.Nexthops {
+				var desc string
+				switch {
+				case nh.IP != "":
+					desc = "via " + nh.IP
+					if nh.InterfaceName != "" {
+						desc += " dev " + nh.InterfaceName
+					}
+				case nh.DirectlyConn:
+					desc = "connected dev " + nh.InterfaceName
+				default:
+					desc = "dev " + nh.InterfaceName
+				}
+				if !nh.Active {
+					desc += " (inactive)"
+				}
+				r.Nexthops = append(r.Nexthops, desc)
+			}
+			routes = append(routes, r)
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Prefix < routes[j].Prefix })
+	return routes, nil
+}
@@ -0,0 +1,134 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package routing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseBGPSummary(t *testing.T) {
+	peers, err := parseBGPSummary(readFixture(t, "bgp_summary.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 3 {
+		t.Fatalf("Expected 3 peers (EVPN ignored), got %d: %+v", len(peers), peers)
+	}
+
+	transit := peers[1]
+	if transit.Address != "192.0.2.254" || transit.Family != "ipv4" || !transit.Established() {
+		t.Errorf("Unexpected transit peer: %+v", transit)
+	}
+	if transit.RemoteASN != 64500 || transit.PrefixesReceived != 912345 || transit.Description != "transit" {
+		t.Errorf("Unexpected transit counters: %+v", transit)
+	}
+	if peers[0].Address != "10.0.0.2" || peers[0].Established() {
+		t.Errorf("Expected idle iBGP peer first, got %+v", peers[0])
+	}
+	if peers[2].Family != "ipv6" {
+		t.Errorf("Expected ipv6 session last, got %+v", peers[2])
+	}
+}
+
+func TestParseOSPFNeighbors(t *testing.T) {
+	neighbors, err := parseOSPFNeighbors(readFixture(t, "ospf_neighbors.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(neighbors) != 2 {
+		t.Fatalf("Expected 2 neighbors, got %d", len(neighbors))
+	}
+	if n := neighbors[0]; n.RouterID != "10.255.0.1" || n.State != "Full/DR" || n.Interface != "eth1" {
+		t.Errorf("Unexpected neighbor: %+v", n)
+	}
+	// Older field names
+	if n := neighbors[1]; n.Address != "10.255.0.3" || n.State != "2-Way/DROther" {
+		t.Errorf("Unexpected neighbor: %+v", n)
+	}
+}
+
+func TestParseBFDPeers(t *testing.T) {
+	peers, err := parseBFDPeers(readFixture(t, "bfd_peers.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 2 || peers[0].Status != "up" || peers[0].Uptime != 3723 {
+		t.Fatalf("Unexpected BFD peers: %+v", peers)
+	}
+	if peers[1].Status != "down" || peers[1].Diagnostic == "" {
+		t.Errorf("Expected down peer with diagnostic, got %+v", peers[1])
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := parseRoutes(readFixture(t, "ip_route.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 3 {
+		t.Fatalf("Expected 3 routes, got %d", len(routes))
+	}
+	if r := routes[0]; r.Protocol != "bgp" || !r.Selected || r.Nexthops[0] != "via 192.0.2.254 dev eth0" {
+		t.Errorf("Unexpected BGP default route: %+v", r)
+	}
+	if r := routes[1]; r.Selected || r.Nexthops[0] != "via 198.51.100.1 dev eth2 (inactive)" {
+		t.Errorf("Unexpected kernel default route: %+v", r)
+	}
+	if r := routes[2]; r.Nexthops[0] != "connected dev eth1" {
+		t.Errorf("Unexpected connected route: %+v", r)
+	}
+}
+
+func TestGetStatus(t *testing.T) {
+	orig := runVtysh
+	defer func() { runVtysh = orig }()
+
+	fixtures := map[string]string{
+		"show bgp summary json":      "bgp_summary.json",
+		"show bfd peers json":        "bfd_peers.json",
+		"show ip route json":         "ip_route.json",
+		"show ipv6 route json":       "",
+		"show ip ospf neighbor json": "",
+	}
+	runVtysh = func(_ context.Context, command string) ([]byte, error) {
+		name, ok := fixtures[command]
+		if !ok {
+			return nil, errors.New("unexpected command")
+		}
+		if name == "" {
+			return []byte("% ospfd is not running\n"), nil
+		}
+		return readFixture(t, name), nil
+	}
+
+	st, err := GetStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Running || len(st.BGPPeers) != 3 || len(st.BFDPeers) != 2 || len(st.Routes) != 3 {
+		t.Errorf("Unexpected status: %+v", st)
+	}
+	if len(st.OSPFNeighbors) != 0 || len(st.Errors) != 0 {
+		t.Errorf("Stopped daemon should be skipped quietly: %+v", st)
+	}
+
+	runVtysh = func(context.Context, string) ([]byte, error) {
+		return nil, errors.New("vtysh: not found")
+	}
+	if _, err := GetStatus(context.Background()); err == nil {
+		t.Error("Expected error when FRR is unreachable")
+	}
+}
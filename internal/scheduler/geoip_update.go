@@ -0,0 +1,417 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package scheduler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"grimm.is/flywall/internal/events"
+	"grimm.is/flywall/internal/logging"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+const (
+	// geoIPStagedSuffix marks a downloaded database awaiting verification.
+	geoIPStagedSuffix = ".staged"
+	// geoIPPreviousSuffix marks the retained copy used for rollback.
+	geoIPPreviousSuffix = ".prev"
+
+	// maxGeoIPDownload caps the download size (country DBs are ~10MB).
+	maxGeoIPDownload = 256 << 20
+
+	defaultGeoIPTestIP = "8.8.8.8"
+)
+
+// GeoIPUpdater fetches a new GeoIP database, verifies it and swaps it in
+// next to the live file, keeping the previous database for rollback.
+type GeoIPUpdater struct {
+	// LivePath is the database the firewall reads.
+	LivePath string
+
+	// URL and ChecksumURL are used when DropDir is empty.
+	URL         string
+	ChecksumURL string
+
+	// DropDir is an offline drop directory containing *.mmdb files with
+	// *.mmdb.sha256 sidecars. Takes precedence over URL.
+	DropDir string
+
+	// TestIP is looked up in the staged database before the swap.
+	TestIP string
+
+	// Reload is called after the swap so consumers reopen the database.
+	// A failing Reload rolls the swap back.
+	Reload func() error
+
+	// Events receives EventGeoIPUpdated/EventGeoIPRollback (optional).
+	Events *events.Hub
+
+	HTTPClient *http.Client
+
+	// verify checks that a staged database is usable. Overridable for tests.
+	verify func(path, testIP string) error
+}
+
+// Run performs one update cycle. It returns nil without swapping when the
+// source database is identical to the live one.
+func (u *GeoIPUpdater) Run(ctx context.Context) error {
+	if u.LivePath == "" {
+		return fmt.Errorf("GeoIP database path not configured")
+	}
+
+	var (
+		data     []byte
+		checksum string
+		source   string
+		err      error
+	)
+	if u.DropDir != "" {
+		data, checksum, source, err = u.readDropDir()
+	} else if u.URL != "" {
+		data, checksum, source, err = u.download(ctx)
+	} else {
+		return fmt.Errorf("no GeoIP update source configured")
+	}
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return nil // Nothing new in the drop directory
+	}
+
+	sum := sha256.Sum256(data)
+	actual := hex.EncodeToString(sum[:])
+	if !strings.EqualFold(actual, checksum) {
+		return fmt.Errorf("GeoIP checksum mismatch for %s: expected %s, got %s", source, checksum, actual)
+	}
+
+	if current, err := fileSHA256(u.LivePath); err == nil && current == actual {
+		logging.Debug("GeoIP database unchanged", "source", source)
+		return nil
+	}
+
+	return u.install(data, actual, source)
+}
+
+// install stages data next to the live file, verifies it and swaps it in.
+func (u *GeoIPUpdater) install(data []byte, checksum, source string) error {
+	if err := os.MkdirAll(filepath.Dir(u.LivePath), 0755); err != nil {
+		return fmt.Errorf("failed to create GeoIP directory: %w", err)
+	}
+
+	staged := u.LivePath + geoIPStagedSuffix
+	if err := os.WriteFile(staged, data, 0644); err != nil {
+		return fmt.Errorf("failed to stage GeoIP database: %w", err)
+	}
+
+	verify := u.verify
+	if verify == nil {
+		verify = verifyGeoIPDatabase
+	}
+	testIP := u.TestIP
+	if testIP == "" {
+		testIP = defaultGeoIPTestIP
+	}
+	if err := verify(staged, testIP); err != nil {
+		os.Remove(staged)
+		return fmt.Errorf("staged GeoIP database rejected: %w", err)
+	}
+
+	// Keep the current database for rollback. Both renames stay within one
+	// directory, so readers see either the old or the new file, never a
+	// partial one.
+	previous := u.LivePath + geoIPPreviousSuffix
+	hadPrevious := false
+	if _, err := os.Stat(u.LivePath); err == nil {
+		if err := os.Rename(u.LivePath, previous); err != nil {
+			os.Remove(staged)
+			return fmt.Errorf("failed to retain previous GeoIP database: %w", err)
+		}
+		hadPrevious = true
+	}
+	if err := os.Rename(staged, u.LivePath); err != nil {
+		if hadPrevious {
+			os.Rename(previous, u.LivePath)
+		}
+		return fmt.Errorf("failed to swap GeoIP database: %w", err)
+	}
+
+	event := events.GeoIPUpdateData{
+		Path:   u.LivePath,
+		Source: source,
+		SHA256: checksum,
+	}
+	if hadPrevious {
+		event.Previous = previous
+	}
+
+	if u.Reload != nil {
+		if err := u.Reload(); err != nil {
+			if !hadPrevious {
+				return fmt.Errorf("GeoIP reload failed: %w", err)
+			}
+			if rbErr := u.rollback(err); rbErr != nil {
+				return fmt.Errorf("GeoIP reload failed: %v (rollback also failed: %w)", err, rbErr)
+			}
+			return fmt.Errorf("GeoIP reload failed, rolled back: %w", err)
+		}
+	}
+
+	logging.Info("GeoIP database updated", "source", source, "sha256", checksum)
+	u.emit(events.EventGeoIPUpdated, event)
+	return nil
+}
+
+// Rollback restores the database retained by the last swap and reloads it.
+func (u *GeoIPUpdater) Rollback() error {
+	return u.rollback(nil)
+}
+
+// rollback restores the previous database; reason, if set, is why the
+// swapped-in one was rejected.
+func (u *GeoIPUpdater) rollback(reason error) error {
+	previous := u.LivePath + geoIPPreviousSuffix
+	if _, err := os.Stat(previous); err != nil {
+		return fmt.Errorf("no previous GeoIP database to roll back to: %w", err)
+	}
+
+	// Swap live and previous so a rollback can itself be undone.
+	tmp := u.LivePath + geoIPStagedSuffix
+	if err := os.Rename(u.LivePath, tmp); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to move live GeoIP database aside: %w", err)
+	}
+	if err := os.Rename(previous, u.LivePath); err != nil {
+		os.Rename(tmp, u.LivePath)
+		return fmt.Errorf("failed to restore previous GeoIP database: %w", err)
+	}
+	os.Rename(tmp, previous)
+
+	if u.Reload != nil {
+		if err := u.Reload(); err != nil {
+			return fmt.Errorf("GeoIP reload after rollback failed: %w", err)
+		}
+	}
+
+	checksum, _ := fileSHA256(u.LivePath)
+	logging.Warn("GeoIP database rolled back", "path", u.LivePath)
+	event := events.GeoIPUpdateData{
+		Path:     u.LivePath,
+		Source:   previous,
+		SHA256:   checksum,
+		Previous: previous,
+	}
+	if reason != nil {
+		event.Error = reason.Error()
+	}
+	u.emit(events.EventGeoIPRollback, event)
+	return nil
+}
+
+func (u *GeoIPUpdater) emit(eventType events.EventType, data events.GeoIPUpdateData) {
+	if u.Events != nil {
+		u.Events.EmitGeoIPUpdate(eventType, data)
+	}
+}
+
+// download fetches the database and its checksum over HTTP.
+func (u *GeoIPUpdater) download(ctx context.Context) ([]byte, string, string, error) {
+	client := u.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 2 * time.Minute}
+	}
+
+	checksumURL := u.ChecksumURL
+	if checksumURL == "" {
+		checksumURL = u.URL + ".sha256"
+	}
+
+	sumData, err := httpGet(ctx, client, checksumURL, 4096)
+	if err != nil {
+		return nil, "", u.URL, fmt.Errorf("failed to fetch GeoIP checksum: %w", err)
+	}
+	checksum, err := parseChecksum(sumData)
+	if err != nil {
+		return nil, "", u.URL, err
+	}
+
+	data, err := httpGet(ctx, client, u.URL, maxGeoIPDownload)
+	if err != nil {
+		return nil, "", u.URL, fmt.Errorf("failed to download GeoIP database: %w", err)
+	}
+
+	// Publishers checksum either the compressed or the raw file. Accept a
+	// gzip payload whose compressed bytes match, then install the raw MMDB.
+	if isGzip(data) {
+		sum := sha256.Sum256(data)
+		if strings.EqualFold(hex.EncodeToString(sum[:]), checksum) {
+			raw, err := gunzip(data)
+			if err != nil {
+				return nil, "", u.URL, err
+			}
+			rawSum := sha256.Sum256(raw)
+			return raw, hex.EncodeToString(rawSum[:]), u.URL, nil
+		}
+		if data, err = gunzip(data); err != nil {
+			return nil, "", u.URL, err
+		}
+	}
+	return data, checksum, u.URL, nil
+}
+
+// readDropDir returns the newest *.mmdb in DropDir that has a sidecar
+// checksum. It returns nil data when the directory holds nothing usable.
+func (u *GeoIPUpdater) readDropDir() ([]byte, string, string, error) {
+	entries, err := os.ReadDir(u.DropDir)
+	if err != nil {
+		return nil, "", u.DropDir, fmt.Errorf("failed to read GeoIP drop directory: %w", err)
+	}
+
+	type candidate struct {
+		path    string
+		modTime time.Time
+	}
+	var candidates []candidate
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".mmdb" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, candidate{
+			path:    filepath.Join(u.DropDir, entry.Name()),
+			modTime: info.ModTime(),
+		})
+	}
+	// Newest first
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].modTime.After(candidates[j].modTime)
+	})
+
+	for _, c := range candidates {
+		sumData, err := os.ReadFile(c.path + ".sha256")
+		if err != nil {
+			logging.Warn("GeoIP drop file has no checksum sidecar, skipping", "path", c.path)
+			continue
+		}
+		checksum, err := parseChecksum(sumData)
+		if err != nil {
+			return nil, "", c.path, err
+		}
+		data, err := os.ReadFile(c.path)
+		if err != nil {
+			return nil, "", c.path, fmt.Errorf("failed to read GeoIP drop file: %w", err)
+		}
+		return data, checksum, c.path, nil
+	}
+	return nil, "", u.DropDir, nil
+}
+
+// verifyGeoIPDatabase opens a database and requires a country for testIP.
+func verifyGeoIPDatabase(path, testIP string) error {
+	ip := net.ParseIP(testIP)
+	if ip == nil {
+		return fmt.Errorf("invalid test IP %q", testIP)
+	}
+
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer reader.Close()
+
+	record, err := reader.Country(ip)
+	if err != nil {
+		return fmt.Errorf("test lookup failed: %w", err)
+	}
+	if record.Country.IsoCode == "" {
+		return fmt.Errorf("test lookup for %s returned no country", testIP)
+	}
+	return nil
+}
+
+// parseChecksum extracts the hex digest from "<sha256>" or sha256sum output.
+func parseChecksum(data []byte) (string, error) {
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty GeoIP checksum")
+	}
+	sum := strings.ToLower(fields[0])
+	if len(sum) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid GeoIP checksum %q", fields[0])
+	}
+	if _, err := hex.DecodeString(sum); err != nil {
+		return "", fmt.Errorf("invalid GeoIP checksum %q", fields[0])
+	}
+	return sum, nil
+}
+
+func httpGet(ctx context.Context, client *http.Client, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("response exceeds %d bytes", limit)
+	}
+	return data, nil
+}
+
+func isGzip(data []byte) bool {
+	return len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress GeoIP database: %w", err)
+	}
+	defer zr.Close()
+	raw, err := io.ReadAll(io.LimitReader(zr, maxGeoIPDownload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress GeoIP database: %w", err)
+	}
+	return raw, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,228 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package scheduler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"grimm.is/flywall/internal/events"
+)
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func acceptAll(path, testIP string) error { return nil }
+
+func writeDropFile(t *testing.T, dir, name string, data []byte, checksum string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+".sha256", []byte(checksum+"  "+name+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeoIPUpdater_DropDirSwap(t *testing.T) {
+	liveDir := t.TempDir()
+	dropDir := t.TempDir()
+	livePath := filepath.Join(liveDir, "country.mmdb")
+
+	if err := os.WriteFile(livePath, []byte("old-db"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	newDB := []byte("new-db")
+	writeDropFile(t, dropDir, "country.mmdb", newDB, sha256Hex(newDB))
+
+	hub := events.NewHub()
+	sub := hub.Subscribe(4, events.EventGeoIPUpdated)
+
+	reloads := 0
+	u := &GeoIPUpdater{
+		LivePath: livePath,
+		DropDir:  dropDir,
+		Reload:   func() error { reloads++; return nil },
+		Events:   hub,
+		verify:   acceptAll,
+	}
+
+	if err := u.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if got, _ := os.ReadFile(livePath); string(got) != "new-db" {
+		t.Errorf("live database = %q, want new-db", got)
+	}
+	if got, _ := os.ReadFile(livePath + geoIPPreviousSuffix); string(got) != "old-db" {
+		t.Errorf("previous database = %q, want old-db", got)
+	}
+	if _, err := os.Stat(livePath + geoIPStagedSuffix); !os.IsNotExist(err) {
+		t.Error("staged file should not remain after swap")
+	}
+	if reloads != 1 {
+		t.Errorf("reloads = %d, want 1", reloads)
+	}
+
+	select {
+	case ev := <-sub:
+		data := ev.Data.(events.GeoIPUpdateData)
+		if data.SHA256 != sha256Hex(newDB) {
+			t.Errorf("event checksum = %s, want %s", data.SHA256, sha256Hex(newDB))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected geoip.updated event")
+	}
+
+	// A second run with the same drop file is a no-op.
+	if err := u.Run(context.Background()); err != nil {
+		t.Fatalf("second Run() error = %v", err)
+	}
+	if reloads != 1 {
+		t.Errorf("unchanged database should not reload, reloads = %d", reloads)
+	}
+}
+
+func TestGeoIPUpdater_ChecksumMismatch(t *testing.T) {
+	liveDir := t.TempDir()
+	dropDir := t.TempDir()
+	livePath := filepath.Join(liveDir, "country.mmdb")
+	os.WriteFile(livePath, []byte("old-db"), 0644)
+
+	writeDropFile(t, dropDir, "country.mmdb", []byte("tampered"), sha256Hex([]byte("original")))
+
+	u := &GeoIPUpdater{LivePath: livePath, DropDir: dropDir, verify: acceptAll}
+	if err := u.Run(context.Background()); err == nil {
+		t.Fatal("expected checksum mismatch error")
+	}
+	if got, _ := os.ReadFile(livePath); string(got) != "old-db" {
+		t.Errorf("live database changed on checksum mismatch: %q", got)
+	}
+}
+
+func TestGeoIPUpdater_VerifyFailureKeepsLive(t *testing.T) {
+	liveDir := t.TempDir()
+	dropDir := t.TempDir()
+	livePath := filepath.Join(liveDir, "country.mmdb")
+	os.WriteFile(livePath, []byte("old-db"), 0644)
+
+	newDB := []byte("broken-db")
+	writeDropFile(t, dropDir, "country.mmdb", newDB, sha256Hex(newDB))
+
+	u := &GeoIPUpdater{
+		LivePath: livePath,
+		DropDir:  dropDir,
+		verify:   func(path, testIP string) error { return errors.New("no country") },
+	}
+	if err := u.Run(context.Background()); err == nil {
+		t.Fatal("expected verification error")
+	}
+	if got, _ := os.ReadFile(livePath); string(got) != "old-db" {
+		t.Errorf("live database changed on failed verification: %q", got)
+	}
+	if _, err := os.Stat(livePath + geoIPStagedSuffix); !os.IsNotExist(err) {
+		t.Error("rejected staged file should be removed")
+	}
+}
+
+func TestGeoIPUpdater_ReloadFailureRollsBack(t *testing.T) {
+	liveDir := t.TempDir()
+	dropDir := t.TempDir()
+	livePath := filepath.Join(liveDir, "country.mmdb")
+	os.WriteFile(livePath, []byte("old-db"), 0644)
+
+	newDB := []byte("new-db")
+	writeDropFile(t, dropDir, "country.mmdb", newDB, sha256Hex(newDB))
+
+	hub := events.NewHub()
+	sub := hub.Subscribe(4, events.EventGeoIPRollback)
+
+	calls := 0
+	u := &GeoIPUpdater{
+		LivePath: livePath,
+		DropDir:  dropDir,
+		Events:   hub,
+		verify:   acceptAll,
+		Reload: func() error {
+			calls++
+			if calls == 1 {
+				return errors.New("reload failed")
+			}
+			return nil
+		},
+	}
+	if err := u.Run(context.Background()); err == nil {
+		t.Fatal("expected reload error")
+	}
+	if got, _ := os.ReadFile(livePath); string(got) != "old-db" {
+		t.Errorf("live database = %q, want old-db after rollback", got)
+	}
+
+	select {
+	case e := <-sub:
+		if data := e.Data.(events.GeoIPUpdateData); data.Error != "reload failed" {
+			t.Errorf("rollback reason = %q, want the reload error", data.Error)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected geoip.rollback event")
+	}
+}
+
+func TestGeoIPUpdater_DownloadGzip(t *testing.T) {
+	raw := []byte("downloaded-db")
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(raw)
+	zw.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/country.mmdb.gz":
+			w.Write(gz.Bytes())
+		case "/country.mmdb.gz.sha256":
+			w.Write([]byte(sha256Hex(gz.Bytes()) + "  country.mmdb.gz\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	livePath := filepath.Join(t.TempDir(), "geoip", "country.mmdb")
+	u := &GeoIPUpdater{
+		LivePath: livePath,
+		URL:      srv.URL + "/country.mmdb.gz",
+		verify:   acceptAll,
+	}
+	if err := u.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got, _ := os.ReadFile(livePath); string(got) != string(raw) {
+		t.Errorf("live database = %q, want %q", got, raw)
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	valid := sha256Hex([]byte("x"))
+	if got, err := parseChecksum([]byte(valid + "  file.mmdb\n")); err != nil || got != valid {
+		t.Errorf("parseChecksum() = %q, %v", got, err)
+	}
+	if _, err := parseChecksum([]byte("deadbeef")); err == nil {
+		t.Error("expected error for short checksum")
+	}
+	if _, err := parseChecksum(nil); err == nil {
+		t.Error("expected error for empty checksum")
+	}
+}
@@ -8,9 +8,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"grimm.is/flywall/internal/clock"
+	"grimm.is/flywall/internal/events"
 	"grimm.is/flywall/internal/logging"
 
 	"grimm.is/flywall/internal/config"
@@ -24,6 +26,8 @@ type TaskRegistry struct {
 	ApplyConfig   func(*config.Config) error
 	RefreshIPSets func() error
 	RefreshDNS    func() error
+	ReloadGeoIP   func() error
+	Events        *events.Hub
 }
 
 // NewIPSetUpdateTask creates a task to update IPSets from external sources.
@@ -64,6 +68,36 @@ func NewDNSBlocklistUpdateTask(registry *TaskRegistry, interval time.Duration) *
 	}
 }
 
+// NewGeoIPUpdateTask creates a task to fetch, verify and swap in a new GeoIP
+// database from the configured URL or local drop directory.
+func NewGeoIPUpdateTask(registry *TaskRegistry, geoCfg *config.GeoIPConfig, livePath string) *Task {
+	interval := 24 * time.Hour
+	if geoCfg.UpdateIntervalHours > 0 {
+		interval = time.Duration(geoCfg.UpdateIntervalHours) * time.Hour
+	}
+
+	updater := &GeoIPUpdater{
+		LivePath:    livePath,
+		URL:         strings.ReplaceAll(geoCfg.UpdateURL, "{license_key}", geoCfg.LicenseKey),
+		ChecksumURL: strings.ReplaceAll(geoCfg.ChecksumURL, "{license_key}", geoCfg.LicenseKey),
+		DropDir:     geoCfg.UpdateDir,
+		TestIP:      geoCfg.TestIP,
+		Reload:      registry.ReloadGeoIP,
+		Events:      registry.Events,
+	}
+
+	return &Task{
+		ID:          "geoip-update",
+		Name:        "GeoIP Database Update",
+		Description: "Fetch, verify and swap in a new GeoIP database",
+		Schedule:    Every(interval),
+		Enabled:     true,
+		RunOnStart:  true,
+		Timeout:     10 * time.Minute,
+		Func:        updater.Run,
+	}
+}
+
 // NewConfigBackupTask creates a task to backup the configuration.
 func NewConfigBackupTask(registry *TaskRegistry, schedule Schedule, keepCount int) *Task {
 	if keepCount <= 0 {
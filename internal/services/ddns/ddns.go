@@ -11,6 +11,8 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"grimm.is/flywall/internal/config"
 )
 
 // Provider is the interface for DDNS providers.
@@ -21,15 +23,69 @@ type Provider interface {
 
 // Config holds DDNS configuration.
 type Config struct {
-	Enabled   bool   `hcl:"enabled,optional" json:"enabled"`
-	Provider  string `hcl:"provider" json:"provider"`                      // duckdns, cloudflare, noip
-	Hostname  string `hcl:"hostname" json:"hostname"`                      // Hostname to update
-	Token     string `hcl:"token,optional" json:"token,omitempty"`         // API token/password
-	Username  string `hcl:"username,optional" json:"username,omitempty"`   // For providers requiring username
-	ZoneID    string `hcl:"zone_id,optional" json:"zone_id,omitempty"`     // For Cloudflare
-	RecordID  string `hcl:"record_id,optional" json:"record_id,omitempty"` // For Cloudflare
-	Interface string `hcl:"interface,optional" json:"interface,omitempty"` // Interface to get IP from
-	Interval  int    `hcl:"interval,optional" json:"interval,omitempty"`   // Update interval in minutes (default: 5)
+	Enabled    bool   `hcl:"enabled,optional" json:"enabled"`
+	Provider   string `hcl:"provider" json:"provider"`                            // duckdns, cloudflare, noip, rfc2136, http
+	Hostname   string `hcl:"hostname" json:"hostname"`                            // Hostname to update
+	Token      string `hcl:"token,optional" json:"token,omitempty"`               // API token/password
+	Username   string `hcl:"username,optional" json:"username,omitempty"`         // For providers requiring username
+	ZoneID     string `hcl:"zone_id,optional" json:"zone_id,omitempty"`           // For Cloudflare
+	RecordID   string `hcl:"record_id,optional" json:"record_id,omitempty"`       // For Cloudflare
+	RecordIDv6 string `hcl:"record_id_v6,optional" json:"record_id_v6,omitempty"` // Cloudflare AAAA record
+	Interface  string `hcl:"interface,optional" json:"interface,omitempty"`       // Interface to get IP from
+	Interval   int    `hcl:"interval,optional" json:"interval,omitempty"`         // Update interval in minutes (default: 5)
+	Family     string `hcl:"family,optional" json:"family,omitempty"`             // ipv4 (default), ipv6 or dual
+	TTL        int    `hcl:"ttl,optional" json:"ttl,omitempty"`                   // Record TTL in seconds (default: 300)
+
+	// RFC 2136
+	Server        string `hcl:"server,optional" json:"server,omitempty"`
+	Zone          string `hcl:"zone,optional" json:"zone,omitempty"`
+	TSIGKey       string `hcl:"tsig_key,optional" json:"tsig_key,omitempty"`
+	TSIGSecret    string `hcl:"tsig_secret,optional" json:"tsig_secret,omitempty"`
+	TSIGAlgorithm string `hcl:"tsig_algorithm,optional" json:"tsig_algorithm,omitempty"`
+
+	// Generic HTTP
+	URL     string            `hcl:"url,optional" json:"url,omitempty"`
+	Method  string            `hcl:"method,optional" json:"method,omitempty"`
+	Headers map[string]string `hcl:"headers,optional" json:"headers,omitempty"`
+	Body    string            `hcl:"body,optional" json:"body,omitempty"`
+}
+
+// ConfigFromGlobal converts the global DDNS configuration.
+func ConfigFromGlobal(c *config.DDNSConfig) Config {
+	if c == nil {
+		return Config{}
+	}
+	return Config{
+		Enabled:       c.Enabled,
+		Provider:      c.Provider,
+		Hostname:      c.Hostname,
+		Token:         c.Token,
+		Username:      c.Username,
+		ZoneID:        c.ZoneID,
+		RecordID:      c.RecordID,
+		RecordIDv6:    c.RecordIDv6,
+		Interface:     c.Interface,
+		Interval:      c.Interval,
+		Family:        c.Family,
+		TTL:           c.TTL,
+		Server:        c.Server,
+		Zone:          c.Zone,
+		TSIGKey:       c.TSIGKey,
+		TSIGSecret:    string(c.TSIGSecret),
+		TSIGAlgorithm: c.TSIGAlgorithm,
+		URL:           c.URL,
+		Method:        c.Method,
+		Headers:       c.Headers,
+		Body:          c.Body,
+	}
+}
+
+// ttl returns the configured record TTL, defaulting to 300 seconds.
+func (c Config) ttl() int {
+	if c.TTL > 0 {
+		return c.TTL
+	}
+	return 300
 }
 
 // NewProvider creates a provider based on config.
@@ -39,10 +95,12 @@ func NewProvider(cfg Config) (Provider, error) {
 		return &DuckDNS{token: cfg.Token, hostname: cfg.Hostname}, nil
 	case "cloudflare":
 		return &Cloudflare{
-			token:    cfg.Token,
-			zoneID:   cfg.ZoneID,
-			recordID: cfg.RecordID,
-			hostname: cfg.Hostname,
+			token:      cfg.Token,
+			zoneID:     cfg.ZoneID,
+			recordID:   cfg.RecordID,
+			recordIDv6: cfg.RecordIDv6,
+			hostname:   cfg.Hostname,
+			ttl:        cfg.ttl(),
 		}, nil
 	case "noip", "no-ip":
 		return &NoIP{
@@ -50,6 +108,10 @@ func NewProvider(cfg Config) (Provider, error) {
 			password: cfg.Token,
 			hostname: cfg.Hostname,
 		}, nil
+	case "rfc2136":
+		return newRFC2136(cfg)
+	case "http":
+		return newHTTPTemplate(cfg)
 	default:
 		return nil, fmt.Errorf("unknown DDNS provider: %s", cfg.Provider)
 	}
@@ -66,8 +128,13 @@ func (d *DuckDNS) Name() string { return "DuckDNS" }
 
 func (d *DuckDNS) Update(hostname, ip string) error {
 	// DuckDNS API: https://www.duckdns.org/update?domains=HOSTNAME&token=TOKEN&ip=IP
-	u := fmt.Sprintf("https://www.duckdns.org/update?domains=%s&token=%s&ip=%s",
-		url.QueryEscape(hostname), url.QueryEscape(d.token), url.QueryEscape(ip))
+	// IPv6 addresses go in the ipv6 parameter instead.
+	param := "ip"
+	if recordType(ip) == "AAAA" {
+		param = "ipv6"
+	}
+	u := fmt.Sprintf("https://www.duckdns.org/update?domains=%s&token=%s&%s=%s",
+		url.QueryEscape(hostname), url.QueryEscape(d.token), param, url.QueryEscape(ip))
 
 	resp, err := http.Get(u)
 	if err != nil {
@@ -85,24 +152,34 @@ func (d *DuckDNS) Update(hostname, ip string) error {
 // --- Cloudflare Provider ---
 
 type Cloudflare struct {
-	token    string
-	zoneID   string
-	recordID string
-	hostname string
+	token      string
+	zoneID     string
+	recordID   string
+	recordIDv6 string
+	hostname   string
+	ttl        int
 }
 
 func (c *Cloudflare) Name() string { return "Cloudflare" }
 
 func (c *Cloudflare) Update(hostname, ip string) error {
 	// Cloudflare API: PATCH /zones/{zone_id}/dns_records/{record_id}
+	// A and AAAA are separate records with their own IDs.
+	rtype, recordID := recordType(ip), c.recordID
+	if rtype == "AAAA" {
+		if c.recordIDv6 == "" {
+			return fmt.Errorf("cloudflare update failed: record_id_v6 is required for AAAA records")
+		}
+		recordID = c.recordIDv6
+	}
 	u := fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records/%s",
-		c.zoneID, c.recordID)
+		c.zoneID, recordID)
 
 	payload := map[string]interface{}{
-		"type":    "A",
+		"type":    rtype,
 		"name":    hostname,
 		"content": ip,
-		"ttl":     300,
+		"ttl":     c.ttl,
 	}
 	body, _ := json.Marshal(payload)
 
@@ -136,8 +213,13 @@ func (n *NoIP) Name() string { return "No-IP" }
 
 func (n *NoIP) Update(hostname, ip string) error {
 	// No-IP API: GET https://dynupdate.no-ip.com/nic/update?hostname=HOSTNAME&myip=IP
-	u := fmt.Sprintf("https://dynupdate.no-ip.com/nic/update?hostname=%s&myip=%s",
-		url.QueryEscape(hostname), url.QueryEscape(ip))
+	// IPv6 addresses go in myipv6 instead.
+	param := "myip"
+	if recordType(ip) == "AAAA" {
+		param = "myipv6"
+	}
+	u := fmt.Sprintf("https://dynupdate.no-ip.com/nic/update?hostname=%s&%s=%s",
+		url.QueryEscape(hostname), param, url.QueryEscape(ip))
 
 	req, _ := http.NewRequest("GET", u, nil)
 	req.SetBasicAuth(n.username, n.password)
@@ -162,9 +244,27 @@ func (n *NoIP) Update(hostname, ip string) error {
 
 // --- Utilities ---
 
+// recordType returns the DNS record type that publishes ip.
+func recordType(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		return "AAAA"
+	}
+	return "A"
+}
+
 // GetPublicIP fetches the current public IP address.
 func GetPublicIP() (string, error) {
-	resp, err := http.Get("https://api.ipify.org")
+	return getPublicIP("https://api.ipify.org")
+}
+
+// GetPublicIPv6 fetches the current public IPv6 address.
+func GetPublicIPv6() (string, error) {
+	return getPublicIP("https://api6.ipify.org")
+}
+
+func getPublicIP(u string) (string, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(u)
 	if err != nil {
 		return "", err
 	}
@@ -196,3 +296,28 @@ func GetInterfaceIP(ifaceName string) (string, error) {
 	}
 	return "", fmt.Errorf("no IPv4 address found on %s", ifaceName)
 }
+
+// GetInterfaceIPv6 gets the global IPv6 address of a specific interface.
+// Link-local and unique local addresses are skipped.
+func GetInterfaceIPv6(ifaceName string) (string, error) {
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return "", err
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", err
+	}
+
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.To4() != nil {
+			continue
+		}
+		if ipNet.IP.IsGlobalUnicast() && !ipNet.IP.IsPrivate() {
+			return ipNet.IP.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no global IPv6 address found on %s", ifaceName)
+}
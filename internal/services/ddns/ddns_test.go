@@ -3,7 +3,14 @@
 package ddns
 
 import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
+
+	"grimm.is/flywall/internal/logging"
 )
 
 func TestNewProvider_DuckDNS(t *testing.T) {
@@ -120,5 +127,118 @@ func TestConfig_Struct(t *testing.T) {
 	}
 }
 
-// Note: Actual Update() methods require network access and real credentials
-// Integration tests should be done manually or with mocked HTTP clients
+func TestHTTPTemplate_Update(t *testing.T) {
+	var gotMethod, gotQuery, gotAuth, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotQuery, gotAuth = r.Method, r.URL.RawQuery, r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		if r.URL.Query().Get("host") == "bad.example.com" {
+			http.Error(w, "nohost", http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	p, err := NewProvider(Config{
+		Provider: "http",
+		URL:      srv.URL + "/update?host={hostname}&addr={ip}",
+		Headers:  map[string]string{"Authorization": "Bearer abc"},
+		Body:     `{"name":"{hostname}","type":"{type}","content":"{ip}"}`,
+	})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+
+	if err := p.Update("gw.example.com", "2001:db8::1"); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if gotMethod != http.MethodPost {
+		t.Errorf("Expected POST with a body, got %s", gotMethod)
+	}
+	if gotQuery != "host=gw.example.com&addr=2001%3Adb8%3A%3A1" {
+		t.Errorf("Unexpected query: %s", gotQuery)
+	}
+	if gotAuth != "Bearer abc" {
+		t.Errorf("Unexpected Authorization header: %s", gotAuth)
+	}
+	if gotBody != `{"name":"gw.example.com","type":"AAAA","content":"2001:db8::1"}` {
+		t.Errorf("Unexpected body: %s", gotBody)
+	}
+
+	if err := p.Update("bad.example.com", "203.0.113.1"); err == nil {
+		t.Error("Expected error on HTTP 404")
+	}
+	if _, err := NewProvider(Config{Provider: "http"}); err == nil {
+		t.Error("Expected error without url")
+	}
+}
+
+func TestService_DualStackStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("type") == "AAAA" {
+			http.Error(w, "refused", http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	s := NewService(logging.WithComponent("ddns"))
+	s.lookupIP = func(iface string, v6 bool) (string, error) {
+		if v6 {
+			return "2001:db8::1", nil
+		}
+		return "203.0.113.1", nil
+	}
+	s.Reload(Config{
+		Enabled:  true,
+		Provider: "http",
+		Hostname: "gw.example.com",
+		Family:   "dual",
+		URL:      srv.URL + "/?ip={ip}&type={type}",
+	})
+	s.update()
+
+	st := s.Status()
+	if st.Provider != "http" || st.Hostname != "gw.example.com" || len(st.Records) != 2 {
+		t.Fatalf("Unexpected status: %+v", st)
+	}
+	a, aaaa := st.Records[0], st.Records[1]
+	if a.Type != "A" || a.IP != "203.0.113.1" || a.Error != "" || a.LastSuccess.IsZero() {
+		t.Errorf("Expected successful A record, got %+v", a)
+	}
+	if aaaa.Type != "AAAA" || aaaa.IP != "2001:db8::1" || aaaa.Error == "" || !aaaa.LastSuccess.IsZero() {
+		t.Errorf("Expected failed AAAA record, got %+v", aaaa)
+	}
+}
+
+func TestService_SkipsUnchanged(t *testing.T) {
+	s := NewService(logging.WithComponent("ddns"))
+	ip := "203.0.113.1"
+
+	s.record("A", ip, nil)
+	if !s.current("A", ip) {
+		t.Error("Expected freshly published IP to be current")
+	}
+	if s.current("A", "203.0.113.2") {
+		t.Error("Expected a changed IP to need an update")
+	}
+
+	// Failures are retried
+	s.record("A", ip, errors.New("refused"))
+	if s.current("A", ip) {
+		t.Error("Expected failed update to be retried")
+	}
+
+	// Stale successes are refreshed
+	s.records["A"] = &RecordStatus{Type: "A", IP: ip, LastSuccess: time.Now().Add(-2 * refreshInterval)}
+	if s.current("A", ip) {
+		t.Error("Expected stale record to be refreshed")
+	}
+
+	// Reload starts over, and lookup errors are recorded too
+	s.lookupIP = func(string, bool) (string, error) { return "", errors.New("no address") }
+	s.Reload(Config{Enabled: true, Provider: "duckdns", Family: "ipv6"})
+	s.update()
+	if st := s.Status(); len(st.Records) != 1 || st.Records[0].Type != "AAAA" || st.Records[0].Error != "no address" {
+		t.Errorf("Expected only the lookup error recorded, got %+v", st.Records)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package ddns
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// --- Generic HTTP Provider ---
+
+// HTTPTemplate calls an arbitrary update URL. The URL, headers and body
+// may contain {hostname}, {ip} and {type} placeholders.
+type HTTPTemplate struct {
+	url     string
+	method  string
+	headers map[string]string
+	body    string
+	client  *http.Client
+}
+
+func newHTTPTemplate(cfg Config) (*HTTPTemplate, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("http provider requires a url")
+	}
+
+	method := strings.ToUpper(cfg.Method)
+	if method == "" {
+		method = http.MethodGet
+		if cfg.Body != "" {
+			method = http.MethodPost
+		}
+	}
+
+	return &HTTPTemplate{
+		url:     cfg.URL,
+		method:  method,
+		headers: cfg.Headers,
+		body:    cfg.Body,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (h *HTTPTemplate) Name() string { return "HTTP" }
+
+func (h *HTTPTemplate) Update(hostname, ip string) error {
+	rtype := recordType(ip)
+	// Values substituted into the URL are query-escaped
+	urlVars := strings.NewReplacer(
+		"{hostname}", url.QueryEscape(hostname),
+		"{ip}", url.QueryEscape(ip),
+		"{type}", rtype,
+	)
+	vars := strings.NewReplacer("{hostname}", hostname, "{ip}", ip, "{type}", rtype)
+
+	var body io.Reader
+	if h.body != "" {
+		body = strings.NewReader(vars.Replace(h.body))
+	}
+	req, err := http.NewRequest(h.method, urlVars.Replace(h.url), body)
+	if err != nil {
+		return fmt.Errorf("http update failed: %w", err)
+	}
+	req.Header.Set("User-Agent", "flywall/1.0")
+	for k, v := range h.headers {
+		req.Header.Set(k, vars.Replace(v))
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("http update failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("http update failed: %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package ddns
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// --- RFC 2136 Provider ---
+
+// RFC2136 sends DNS UPDATE messages straight to the zone's primary server,
+// signed with TSIG when a key is configured.
+type RFC2136 struct {
+	server    string // host:port
+	zone      string // FQDN; empty derives it from the hostname
+	keyName   string // FQDN
+	secret    string // base64
+	algorithm string // FQDN, e.g. hmac-sha256.
+	ttl       uint32
+	timeout   time.Duration
+}
+
+func newRFC2136(cfg Config) (*RFC2136, error) {
+	if cfg.Server == "" {
+		return nil, fmt.Errorf("rfc2136 provider requires a server")
+	}
+
+	server := cfg.Server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+	}
+
+	r := &RFC2136{
+		server:  server,
+		ttl:     uint32(cfg.ttl()),
+		timeout: 10 * time.Second,
+	}
+	if cfg.Zone != "" {
+		r.zone = dns.Fqdn(cfg.Zone)
+	}
+	if cfg.TSIGKey != "" {
+		alg := strings.ToLower(cfg.TSIGAlgorithm)
+		if alg == "" {
+			alg = "hmac-sha256"
+		}
+		r.keyName = dns.Fqdn(cfg.TSIGKey)
+		r.secret = cfg.TSIGSecret
+		r.algorithm = dns.Fqdn(alg)
+	}
+	return r, nil
+}
+
+func (r *RFC2136) Name() string { return "RFC2136" }
+
+// Update replaces the A or AAAA RRset of hostname with ip.
+func (r *RFC2136) Update(hostname, ip string) error {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return fmt.Errorf("rfc2136 update failed: invalid IP %q", ip)
+	}
+
+	name := dns.Fqdn(hostname)
+	zone := r.zone
+	if zone == "" {
+		labels := dns.Split(name)
+		if len(labels) < 2 {
+			return fmt.Errorf("rfc2136 update failed: cannot derive zone from %q, set zone", hostname)
+		}
+		zone = name[labels[1]:]
+	}
+
+	hdr := dns.RR_Header{Name: name, Class: dns.ClassINET, Ttl: r.ttl}
+	var rr dns.RR
+	if v4 := addr.To4(); v4 != nil {
+		hdr.Rrtype = dns.TypeA
+		rr = &dns.A{Hdr: hdr, A: v4}
+	} else {
+		hdr.Rrtype = dns.TypeAAAA
+		rr = &dns.AAAA{Hdr: hdr, AAAA: addr}
+	}
+
+	m := new(dns.Msg)
+	m.SetUpdate(zone)
+	m.RemoveRRset([]dns.RR{rr})
+	m.Insert([]dns.RR{rr})
+
+	client := &dns.Client{Net: "tcp", Timeout: r.timeout}
+	if r.keyName != "" {
+		m.SetTsig(r.keyName, r.algorithm, 300, time.Now().Unix())
+		client.TsigSecret = map[string]string{r.keyName: r.secret}
+	}
+
+	resp, _, err := client.Exchange(m, r.server)
+	if err != nil {
+		return fmt.Errorf("rfc2136 update failed: %w", err)
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("rfc2136 update failed: server returned %s", dns.RcodeToString[resp.Rcode])
+	}
+	return nil
+}
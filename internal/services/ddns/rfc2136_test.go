@@ -0,0 +1,153 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package ddns
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	testKey    = "ddns-key."
+	testSecret = "c2VjcmV0LXNlY3JldC1zZWNyZXQ=" // "secret-secret-secret"
+)
+
+// updateServer is a minimal primary that accepts TSIG-signed updates for
+// example.com and records them.
+type updateServer struct {
+	mu      sync.Mutex
+	updates []*dns.Msg
+	addr    string
+}
+
+func startUpdateServer(t *testing.T) *updateServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	us := &updateServer{addr: ln.Addr().String()}
+	srv := &dns.Server{
+		Listener:   ln,
+		TsigSecret: map[string]string{testKey: testSecret},
+		// The default accept func answers UPDATE with NOTIMP
+		MsgAcceptFunc: func(dns.Header) dns.MsgAcceptAction { return dns.MsgAccept },
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			resp := new(dns.Msg)
+			resp.SetReply(r)
+			switch {
+			case r.IsTsig() == nil || w.TsigStatus() != nil:
+				resp.Rcode = dns.RcodeNotAuth
+			case r.Opcode != dns.OpcodeUpdate || r.Question[0].Name != "example.com.":
+				resp.Rcode = dns.RcodeNotZone
+			default:
+				us.mu.Lock()
+				us.updates = append(us.updates, r)
+				us.mu.Unlock()
+			}
+			if tsig := r.IsTsig(); tsig != nil {
+				resp.SetTsig(tsig.Hdr.Name, tsig.Algorithm, 300, int64(tsig.TimeSigned))
+			}
+			w.WriteMsg(resp)
+		}),
+	}
+	started := make(chan struct{})
+	srv.NotifyStartedFunc = func() { close(started) }
+	go srv.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { srv.Shutdown() })
+	return us
+}
+
+func TestRFC2136_Update(t *testing.T) {
+	us := startUpdateServer(t)
+
+	p, err := NewProvider(Config{
+		Provider:   "rfc2136",
+		Server:     us.addr,
+		TSIGKey:    "ddns-key",
+		TSIGSecret: testSecret,
+		TTL:        60,
+	})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+
+	if err := p.Update("gw.example.com", "203.0.113.7"); err != nil {
+		t.Fatalf("Update(A) error = %v", err)
+	}
+	if err := p.Update("gw.example.com", "2001:db8::7"); err != nil {
+		t.Fatalf("Update(AAAA) error = %v", err)
+	}
+
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	if len(us.updates) != 2 {
+		t.Fatalf("Expected 2 updates, got %d", len(us.updates))
+	}
+
+	// Each update deletes the old RRset, then adds the new record
+	ns := us.updates[0].Ns
+	if len(ns) != 2 || ns[0].Header().Class != dns.ClassANY || ns[0].Header().Rrtype != dns.TypeA {
+		t.Fatalf("Expected RRset delete first, got %v", ns)
+	}
+	a, ok := ns[1].(*dns.A)
+	if !ok || a.A.String() != "203.0.113.7" || a.Hdr.Name != "gw.example.com." || a.Hdr.Ttl != 60 {
+		t.Errorf("Unexpected A record: %v", ns[1])
+	}
+	if aaaa, ok := us.updates[1].Ns[1].(*dns.AAAA); !ok || aaaa.AAAA.String() != "2001:db8::7" {
+		t.Errorf("Unexpected AAAA record: %v", us.updates[1].Ns[1])
+	}
+}
+
+func TestRFC2136_Errors(t *testing.T) {
+	us := startUpdateServer(t)
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		hostname string
+	}{
+		{"wrong secret", Config{TSIGKey: "ddns-key", TSIGSecret: "d3Jvbmc="}, "gw.example.com"},
+		{"unsigned", Config{}, "gw.example.com"},
+		{"wrong zone", Config{TSIGKey: "ddns-key", TSIGSecret: testSecret, Zone: "example.org"}, "gw.example.org"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Provider = "rfc2136"
+			tt.cfg.Server = us.addr
+			p, err := NewProvider(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewProvider() error = %v", err)
+			}
+			if err := p.Update(tt.hostname, "203.0.113.7"); err == nil {
+				t.Error("Expected update to fail")
+			}
+		})
+	}
+
+	if len(us.updates) != 0 {
+		t.Errorf("No update should have been accepted, got %d", len(us.updates))
+	}
+}
+
+func TestNewRFC2136_Defaults(t *testing.T) {
+	r, err := newRFC2136(Config{Server: "2001:db8::53", TSIGKey: "k", TSIGSecret: testSecret})
+	if err != nil {
+		t.Fatalf("newRFC2136() error = %v", err)
+	}
+	if r.server != "[2001:db8::53]:53" {
+		t.Errorf("Expected default port, got %s", r.server)
+	}
+	if r.algorithm != dns.HmacSHA256 || r.keyName != "k." || r.ttl != 300 {
+		t.Errorf("Unexpected defaults: %+v", r)
+	}
+
+	if _, err := NewProvider(Config{Provider: "rfc2136"}); err == nil {
+		t.Error("Expected error without server")
+	}
+}
@@ -5,37 +5,82 @@ package ddns
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"grimm.is/flywall/internal/logging"
 )
 
+// refreshInterval is how often an unchanged record is re-published anyway,
+// so a record changed behind our back is eventually corrected.
+const refreshInterval = 24 * time.Hour
+
+// RecordStatus is the outcome of the latest update of one record.
+type RecordStatus struct {
+	Type        string    `json:"type"` // A or AAAA
+	IP          string    `json:"ip,omitempty"`
+	LastAttempt time.Time `json:"last_attempt"`
+	LastSuccess time.Time `json:"last_success,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Status describes the DDNS service and its published records.
+type Status struct {
+	Running  bool           `json:"running"`
+	Provider string         `json:"provider,omitempty"`
+	Hostname string         `json:"hostname,omitempty"`
+	Records  []RecordStatus `json:"records,omitempty"`
+}
+
 // Service manages dynamic DNS updates.
 type Service struct {
 	config  Config
 	logger  *logging.Logger
 	mu      sync.Mutex
 	running bool
+	parent  context.Context
+	cancel  context.CancelFunc
+	records map[string]*RecordStatus // by record type
+
+	// lookupIP returns the current address to publish. Replaced in tests.
+	lookupIP func(iface string, v6 bool) (string, error)
 }
 
 // NewService creates a new DDNS service.
 func NewService(logger *logging.Logger) *Service {
 	return &Service{
-		logger: logger,
+		logger:   logger,
+		records:  make(map[string]*RecordStatus),
+		lookupIP: lookupIP,
 	}
 }
 
-// Reload updates the configuration and restarts the service if running.
+// lookupIP reads the address from iface, or asks a public echo service.
+func lookupIP(iface string, v6 bool) (string, error) {
+	switch {
+	case iface != "" && v6:
+		return GetInterfaceIPv6(iface)
+	case iface != "":
+		return GetInterfaceIP(iface)
+	case v6:
+		return GetPublicIPv6()
+	default:
+		return GetPublicIP()
+	}
+}
+
+// Reload updates the configuration and restarts the update loop if running.
+// Previous results are dropped so the new settings are published at once.
 func (s *Service) Reload(cfg Config) (bool, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	s.config = cfg
+	s.records = make(map[string]*RecordStatus)
 	if s.running {
-		// If running, we might need to restart to pick up changes (e.g. interval)
-		// For now, assume simple restart or let the next tick handle it if interval didn't change.
-		// Detailed reload logic omitted for brevity.
+		s.cancel()
+		s.startLoop()
 	}
 	return true, nil
 }
@@ -43,26 +88,44 @@ func (s *Service) Reload(cfg Config) (bool, error) {
 // Start starts the DDNS update loop.
 func (s *Service) Start(ctx context.Context) error {
 	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.running {
-		s.mu.Unlock()
 		return fmt.Errorf("ddns service already running")
 	}
 	s.running = true
-	s.mu.Unlock()
+	s.parent = ctx
+	s.startLoop()
 
 	s.logger.Info("DDNS Service started")
-
-	go s.runLoop(ctx)
 	return nil
 }
 
-// runLoop executes the periodic updates.
-func (s *Service) runLoop(ctx context.Context) {
+// Stop stops the DDNS update loop.
+func (s *Service) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		return
+	}
+	s.cancel()
+	s.running = false
+}
+
+// startLoop launches the update loop. Caller must hold s.mu.
+func (s *Service) startLoop() {
+	ctx, cancel := context.WithCancel(s.parent)
+	s.cancel = cancel
+
 	interval := s.config.Interval
 	if interval < 1 {
 		interval = 5
 	}
-	ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+	go s.runLoop(ctx, time.Duration(interval)*time.Minute)
+}
+
+// runLoop executes the periodic updates.
+func (s *Service) runLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	// Initial update
@@ -71,9 +134,11 @@ func (s *Service) runLoop(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
-			s.logger.Info("DDNS Service stopping")
 			s.mu.Lock()
-			s.running = false
+			if s.parent.Err() != nil {
+				s.logger.Info("DDNS Service stopping")
+				s.running = false
+			}
 			s.mu.Unlock()
 			return
 		case <-ticker.C:
@@ -82,42 +147,109 @@ func (s *Service) runLoop(ctx context.Context) {
 	}
 }
 
-// update performs the DDNS update.
+// update publishes the current address of each configured family.
 func (s *Service) update() {
-	if !s.config.Enabled {
+	s.mu.Lock()
+	cfg := s.config
+	s.mu.Unlock()
+
+	if !cfg.Enabled || cfg.Provider == "" {
 		return
 	}
 
-	providerName := s.config.Provider
-	if providerName == "" {
-		return
+	families := []bool{false}
+	switch cfg.Family {
+	case "ipv6":
+		families = []bool{true}
+	case "dual":
+		families = []bool{false, true}
 	}
 
-	provider, err := NewProvider(s.config)
+	provider, err := NewProvider(cfg)
 	if err != nil {
-		s.logger.Error(fmt.Sprintf("Failed to create DDNS provider %s: %v", providerName, err))
+		s.logger.Error(fmt.Sprintf("Failed to create DDNS provider %s: %v", cfg.Provider, err))
+		for _, v6 := range families {
+			s.record(familyType(v6), "", err)
+		}
 		return
 	}
 
-	// Determine IP
-	var ip string
-	if s.config.Interface != "" {
-		ip, err = GetInterfaceIP(s.config.Interface)
-	} else {
-		ip, err = GetPublicIP()
+	for _, v6 := range families {
+		rtype := familyType(v6)
+
+		ip, err := s.lookupIP(cfg.Interface, v6)
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("Failed to get IP for DDNS %s record: %v", rtype, err))
+			s.record(rtype, "", err)
+			continue
+		}
+		if s.current(rtype, ip) {
+			continue
+		}
+
+		err = provider.Update(cfg.Hostname, ip)
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("DDNS update failed for %s %s: %v", cfg.Hostname, rtype, err))
+		} else {
+			s.logger.Info(fmt.Sprintf("DDNS update success for %s %s (%s)", cfg.Hostname, rtype, ip))
+		}
+		s.record(rtype, ip, err)
 	}
+}
+
+func familyType(v6 bool) string {
+	if v6 {
+		return "AAAA"
+	}
+	return "A"
+}
 
+// current reports whether ip was already published recently.
+func (s *Service) current(rtype, ip string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec := s.records[rtype]
+	return rec != nil && rec.Error == "" && rec.IP == ip && time.Since(rec.LastSuccess) < refreshInterval
+}
+
+// record stores the outcome of an update attempt.
+func (s *Service) record(rtype, ip string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec := s.records[rtype]
+	if rec == nil {
+		rec = &RecordStatus{Type: rtype}
+		s.records[rtype] = rec
+	}
+	rec.LastAttempt = time.Now()
+	if ip != "" {
+		rec.IP = ip
+	}
 	if err != nil {
-		s.logger.Error(fmt.Sprintf("Failed to get IP for DDNS: %v", err))
+		rec.Error = err.Error()
 		return
 	}
+	rec.Error = ""
+	rec.LastSuccess = rec.LastAttempt
+}
+
+// Status returns the service state and the result of the latest update of
+// each record.
+func (s *Service) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	// Update
-	if err := provider.Update(s.config.Hostname, ip); err != nil {
-		s.logger.Error(fmt.Sprintf("DDNS update failed for %s: %v", s.config.Hostname, err))
-	} else {
-		s.logger.Info(fmt.Sprintf("DDNS update success for %s (%s)", s.config.Hostname, ip))
+	st := Status{
+		Running:  s.running,
+		Provider: s.config.Provider,
+		Hostname: s.config.Hostname,
+	}
+	for _, rec := range s.records {
+		st.Records = append(st.Records, *rec)
 	}
+	sort.Slice(st.Records, func(i, j int) bool { return st.Records[i].Type < st.Records[j].Type })
+	return st
 }
 
 // IsRunning returns true if the service is running.
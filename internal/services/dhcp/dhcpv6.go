@@ -0,0 +1,643 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package dhcp
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"grimm.is/flywall/internal/clock"
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/errors"
+	"grimm.is/flywall/internal/logging"
+	"grimm.is/flywall/internal/state"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/dhcpv6/server6"
+	"github.com/insomniacslk/dhcp/iana"
+	"golang.org/x/net/ipv6"
+)
+
+// addressRemover is implemented by DNS updaters that can drop a single
+// address family's record for a name, so an expiring DHCPv6 lease does
+// not take the host's A record with it.
+type addressRemover interface {
+	RemoveAddress(name string, ip net.IP)
+}
+
+type dhcpv6Instance struct {
+	conn    net.PacketConn
+	handler func(conn net.PacketConn, peer net.Addr, m *dhcpv6.Message)
+}
+
+// LeaseStoreV6 tracks DHCPv6 IA_NA leases for a scope, keyed by client
+// DUID (hex). Allocation mirrors LeaseStore:
+// 1. Static Reservations (by DUID, or by the MAC inside DUID-LL/LLT)
+// 2. Existing Lease reuse
+// 3. First-available address from pool
+type LeaseStoreV6 struct {
+	sync.Mutex
+	Leases       map[string]netip.Addr             // DUID -> address
+	TakenIPs     map[netip.Addr]string             // address -> DUID
+	Reservations map[string]config.DHCPReservation // DUID or MAC -> Reservation
+	ReservedIPs  map[netip.Addr]string             // address -> reservation key
+	RangeStart   netip.Addr
+	RangeEnd     netip.Addr
+	Interface    string
+	bucket       *state.DHCPBucket
+	failover     *failover // HA pool sharing (nil when standalone)
+
+	clock       clock.Clock
+	leaseTime   time.Duration
+	hostnames   map[string]string    // DUID -> hostname for DNS cleanup
+	macs        map[string]string    // DUID -> MAC (if the DUID carries one)
+	leaseExpiry map[string]time.Time // DUID -> expiration time
+}
+
+func newLeaseStoreV6(start, end netip.Addr) *LeaseStoreV6 {
+	return &LeaseStoreV6{
+		Leases:       make(map[string]netip.Addr),
+		TakenIPs:     make(map[netip.Addr]string),
+		Reservations: make(map[string]config.DHCPReservation),
+		ReservedIPs:  make(map[netip.Addr]string),
+		RangeStart:   start,
+		RangeEnd:     end,
+		hostnames:    make(map[string]string),
+		macs:         make(map[string]string),
+		leaseExpiry:  make(map[string]time.Time),
+	}
+}
+
+// duidKey returns the canonical lease key for a DUID.
+func duidKey(d dhcpv6.DUID) string {
+	return hex.EncodeToString(d.ToBytes())
+}
+
+// normalizeDUID strips separators from a configured DUID.
+func normalizeDUID(s string) string {
+	return strings.ToLower(strings.NewReplacer(":", "", "-", "", " ", "").Replace(s))
+}
+
+// duidMAC returns the link-layer address embedded in a DUID-LL or
+// DUID-LLT, or "" for other DUID types.
+func duidMAC(d dhcpv6.DUID) string {
+	switch v := d.(type) {
+	case *dhcpv6.DUIDLL:
+		if len(v.LinkLayerAddr) == 6 {
+			return v.LinkLayerAddr.String()
+		}
+	case *dhcpv6.DUIDLLT:
+		if len(v.LinkLayerAddr) == 6 {
+			return v.LinkLayerAddr.String()
+		}
+	}
+	return ""
+}
+
+func (s *LeaseStoreV6) inRange(addr netip.Addr) bool {
+	return addr.Compare(s.RangeStart) >= 0 && addr.Compare(s.RangeEnd) <= 0
+}
+
+// reservation looks up a static reservation by DUID, then MAC.
+// Caller must hold the lock.
+func (s *LeaseStoreV6) reservation(duid, mac string) (config.DHCPReservation, bool) {
+	if res, ok := s.Reservations[duid]; ok {
+		return res, true
+	}
+	if mac != "" {
+		if res, ok := s.Reservations[mac]; ok {
+			return res, true
+		}
+	}
+	return config.DHCPReservation{}, false
+}
+
+// Allocate returns the address for a client, allocating one from the
+// pool if needed. New dynamic leases are persisted immediately.
+func (s *LeaseStoreV6) Allocate(duid, mac string) (netip.Addr, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	// 1. Check for static reservation
+	if res, ok := s.reservation(duid, mac); ok {
+		if addr, err := netip.ParseAddr(res.IPv6); err == nil {
+			return addr, nil
+		}
+	}
+
+	// 2. Check existing dynamic lease
+	if addr, ok := s.Leases[duid]; ok {
+		// Re-validate against current range in case of config change
+		if s.inRange(addr) {
+			return addr, nil
+		}
+		logging.WithComponent("dhcp").Warn("Existing DHCPv6 lease no longer in range", "ip", addr, "duid", duid)
+		delete(s.Leases, duid)
+		delete(s.TakenIPs, addr)
+	}
+
+	// 3. Allocate new dynamic address (linear scan)
+	for addr := s.RangeStart; addr.IsValid() && s.inRange(addr); addr = addr.Next() {
+		if _, reserved := s.ReservedIPs[addr]; reserved {
+			continue
+		}
+		if _, taken := s.TakenIPs[addr]; taken {
+			continue
+		}
+
+		if err := s.persistLease(duid, mac, addr, ""); err != nil {
+			return netip.Addr{}, errors.Wrap(err, errors.KindInternal, "failed to persist lease")
+		}
+		s.Leases[duid] = addr
+		s.TakenIPs[addr] = duid
+		s.macs[duid] = mac
+		s.leaseExpiry[duid] = s.getNow().Add(s.getLeaseTime())
+		return addr, nil
+	}
+
+	return netip.Addr{}, fmt.Errorf("no IPv6 addresses available")
+}
+
+// Commit records that the client confirmed its lease (Request, Renew,
+// Rebind or rapid-commit Solicit), extending it and storing the hostname.
+func (s *LeaseStoreV6) Commit(duid, mac string, addr netip.Addr, hostname string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.Leases[duid]; !ok {
+		// Reserved addresses are tracked as leases once committed
+		s.Leases[duid] = addr
+		s.TakenIPs[addr] = duid
+	}
+	s.macs[duid] = mac
+	if hostname != "" {
+		s.hostnames[duid] = hostname
+	}
+	s.leaseExpiry[duid] = s.getNow().Add(s.getLeaseTime())
+	return s.persistLease(duid, mac, addr, hostname)
+}
+
+// Release removes a client's lease, returning what it held.
+func (s *LeaseStoreV6) Release(duid string) (netip.Addr, string, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	addr, ok := s.Leases[duid]
+	if !ok {
+		return netip.Addr{}, "", false
+	}
+	if s.bucket != nil && s.failover.ownsStore() {
+		if err := s.bucket.Delete("duid:" + duid); err != nil {
+			logging.WithComponent("dhcp").WithError(err).Warn("Failed to delete released DHCPv6 lease", "duid", duid)
+		}
+	}
+	hostname := s.hostnames[duid]
+	delete(s.Leases, duid)
+	delete(s.TakenIPs, addr)
+	delete(s.hostnames, duid)
+	delete(s.macs, duid)
+	delete(s.leaseExpiry, duid)
+	return addr, hostname, true
+}
+
+func (s *LeaseStoreV6) persistLease(duid, mac string, addr netip.Addr, hostname string) error {
+	if s.bucket == nil {
+		return nil
+	}
+	if hostname == "" {
+		hostname = s.hostnames[duid]
+	}
+	now := s.getNow()
+	lease := &state.DHCPLease{
+		MAC:        mac,
+		IP:         addr.String(),
+		Hostname:   hostname,
+		Interface:  s.Interface,
+		LeaseStart: now,
+		LeaseEnd:   now.Add(s.getLeaseTime()),
+		ClientID:   duid,
+		DUID:       duid,
+	}
+
+	// Replicas hand the write to the primary; it comes back via replication
+	if forwarded, err := s.failover.forward(lease); forwarded {
+		return err
+	}
+
+	if err := s.bucket.Set(lease); err != nil {
+		return errors.Wrap(err, errors.KindInternal, "failed to persist lease to state store")
+	}
+	logging.WithComponent("dhcp").Debug("Persisted DHCPv6 lease", "duid", duid, "ip", addr)
+	return nil
+}
+
+func (s *LeaseStoreV6) getNow() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return clock.Now()
+}
+
+// getLeaseTime returns the configured lease time or default 24 hours,
+// capped at the failover MCLT while the peer is unreachable
+func (s *LeaseStoreV6) getLeaseTime() time.Duration {
+	if s.leaseTime > 0 {
+		return s.failover.leaseTime(s.leaseTime)
+	}
+	return s.failover.leaseTime(24 * time.Hour)
+}
+
+// ExpireLeases removes expired leases and their DNS records.
+// Returns the number of leases expired.
+func (s *LeaseStoreV6) ExpireLeases(dnsUpdater DNSUpdater, listener ExpirationListener) int {
+	s.Lock()
+	defer s.Unlock()
+
+	now := s.getNow()
+	expired := 0
+	for duid, expiry := range s.leaseExpiry {
+		if !now.After(expiry) {
+			continue
+		}
+		// Same ordering as LeaseStore: only forget the lease once the
+		// persistent copy is gone, so the reaper retries on failure.
+		if s.bucket != nil && s.failover.ownsStore() {
+			if err := s.bucket.Delete("duid:" + duid); err != nil {
+				logging.WithComponent("dhcp").WithError(err).Warn("Failed to delete expired DHCPv6 lease from store, will retry", "duid", duid)
+				continue
+			}
+		}
+
+		addr := s.Leases[duid]
+		hostname := s.hostnames[duid]
+		mac := s.macs[duid]
+		delete(s.Leases, duid)
+		delete(s.TakenIPs, addr)
+		delete(s.leaseExpiry, duid)
+		delete(s.hostnames, duid)
+		delete(s.macs, duid)
+
+		if hostname != "" {
+			removeDNSAddress(dnsUpdater, hostname, addr)
+		}
+		if listener != nil && mac != "" {
+			listener.OnLeaseExpired(mac, addr.AsSlice(), hostname)
+		}
+
+		logging.WithComponent("dhcp").Info("Expired DHCPv6 lease", "duid", duid, "ip", addr)
+		expired++
+	}
+	return expired
+}
+
+// removeDNSAddress drops only the AAAA record when the updater supports it.
+func removeDNSAddress(dnsUpdater DNSUpdater, hostname string, addr netip.Addr) {
+	if dnsUpdater == nil {
+		return
+	}
+	if r, ok := dnsUpdater.(addressRemover); ok {
+		r.RemoveAddress(hostname, addr.AsSlice())
+		return
+	}
+	dnsUpdater.RemoveRecord(hostname)
+}
+
+// createLeaseStoreV6 builds the DHCPv6 lease store for a scope, loading
+// persisted leases from the shared DHCP bucket. Scopes without a v6 range
+// run stateless and get a nil store.
+func (s *Service) createLeaseStoreV6(scope config.DHCPScope) (*LeaseStoreV6, error) {
+	if scope.RangeStartV6 == "" {
+		return nil, nil
+	}
+	start, err1 := netip.ParseAddr(scope.RangeStartV6)
+	end, err2 := netip.ParseAddr(scope.RangeEndV6)
+	if err1 != nil || err2 != nil || !start.Is6() || !end.Is6() || end.Less(start) {
+		return nil, errors.New(errors.KindValidation, "invalid IPv6 range configuration for scope")
+	}
+
+	ls := newLeaseStoreV6(start, end)
+	ls.Interface = scope.Interface
+	logger := logging.WithComponent("dhcp")
+
+	if scope.LeaseTime != "" {
+		if d, err := time.ParseDuration(scope.LeaseTime); err == nil {
+			ls.leaseTime = d
+		}
+	}
+
+	if s.store != nil {
+		bucket, err := state.NewDHCPBucket(s.store)
+		if err != nil {
+			logger.WithError(err).Warn("Failed to create/open DHCP bucket")
+		} else {
+			ls.bucket = bucket
+			leases, err := bucket.List()
+			if err != nil {
+				logger.WithError(err).Warn("Failed to list existing leases")
+			} else {
+				count := 0
+				for _, l := range leases {
+					addr, err := netip.ParseAddr(l.IP)
+					if l.DUID == "" || err != nil || !addr.Is6() {
+						continue
+					}
+					if l.Interface != "" && l.Interface != scope.Interface {
+						continue
+					}
+					ls.Leases[l.DUID] = addr
+					ls.TakenIPs[addr] = l.DUID
+					ls.macs[l.DUID] = l.MAC
+					ls.leaseExpiry[l.DUID] = l.LeaseEnd
+					if l.Hostname != "" {
+						ls.hostnames[l.DUID] = l.Hostname
+					}
+					count++
+				}
+				logger.Info("Loaded DHCPv6 leases from state store", "scope", scope.Name, "count", count)
+			}
+		}
+	}
+
+	for _, res := range scope.Reservations {
+		addr, err := netip.ParseAddr(res.IPv6)
+		if err != nil {
+			continue
+		}
+		key := normalizeDUID(res.DUID)
+		if key == "" {
+			hw, err := net.ParseMAC(res.MAC)
+			if err != nil {
+				continue
+			}
+			key = hw.String()
+		}
+		ls.Reservations[key] = res
+		ls.ReservedIPs[addr] = key
+	}
+
+	return ls, nil
+}
+
+// createServerV6 builds the DHCPv6 server for a scope.
+func (s *Service) createServerV6(scope config.DHCPScope) (*dhcpv6Instance, *LeaseStoreV6, error) {
+	ls, err := s.createLeaseStoreV6(scope)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ifi, err := net.InterfaceByName(scope.Interface)
+	if err != nil {
+		return nil, nil, fmt.Errorf("interface not found: %w", err)
+	}
+	if len(ifi.HardwareAddr) < 6 {
+		return nil, nil, fmt.Errorf("interface %s has no hardware address for a server DUID", scope.Interface)
+	}
+	serverID := &dhcpv6.DUIDLL{HWType: iana.HWTypeEthernet, LinkLayerAddr: ifi.HardwareAddr}
+
+	handler := func(conn net.PacketConn, peer net.Addr, m *dhcpv6.Message) {
+		s.mu.RLock()
+		dnsUpdater, listener := s.dnsUpdater, s.leaseListener
+		s.mu.RUnlock()
+
+		reply, err := handleDHCPv6(m, ls, scope, serverID, dnsUpdater, listener)
+		if err != nil {
+			logging.WithComponent("dhcp").WithError(err).Error("DHCPv6 error", "type", m.MessageType)
+			return
+		}
+		if reply == nil {
+			return
+		}
+		if _, err := conn.WriteTo(reply.ToBytes(), peer); err != nil {
+			logging.WithComponent("dhcp").WithError(err).Error("DHCPv6 write error", "dest", peer)
+		}
+	}
+
+	conn, err := s.bindSocketV6(scope, ifi)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &dhcpv6Instance{conn: conn, handler: handler}, ls, nil
+}
+
+func (s *Service) bindSocketV6(scope config.DHCPScope, ifi *net.Interface) (net.PacketConn, error) {
+	logger := logging.WithComponent("dhcp")
+	linkName := "dhcp-v6-" + scope.Interface
+
+	if s.upgradeMgr != nil {
+		if existing, ok := s.upgradeMgr.GetPacketConn(linkName); ok {
+			logger.Info("Inherited socket", "link", linkName)
+			return existing, nil
+		}
+	}
+
+	udpConn, err := server6.NewIPv6UDPConn(scope.Interface, &net.UDPAddr{
+		IP:   net.IPv6unspecified,
+		Port: dhcpv6.DefaultServerPort,
+	})
+	if err != nil {
+		return nil, err
+	}
+	group := &net.UDPAddr{IP: dhcpv6.AllDHCPRelayAgentsAndServers, Port: dhcpv6.DefaultServerPort}
+	if err := ipv6.NewPacketConn(udpConn).JoinGroup(ifi, group); err != nil {
+		udpConn.Close()
+		return nil, fmt.Errorf("failed to join %s on %s: %w", group.IP, scope.Interface, err)
+	}
+
+	if s.upgradeMgr != nil {
+		s.upgradeMgr.RegisterPacketConn(linkName, udpConn)
+	}
+	return udpConn, nil
+}
+
+// serveDHCPv6 runs the read loop for a DHCPv6 server instance
+func (s *Service) serveDHCPv6(ctx context.Context, inst *dhcpv6Instance) {
+	buf := make([]byte, 4096)
+	logger := logging.WithComponent("dhcp")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			inst.conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+			n, addr, err := inst.conn.ReadFrom(buf)
+			if err != nil {
+				if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+					continue
+				}
+				if s.IsRunning() {
+					logger.WithError(err).Error("DHCPv6 read error")
+				}
+				return
+			}
+
+			pkt, err := dhcpv6.MessageFromBytes(buf[:n])
+			if err != nil {
+				// Relay-forward messages are not supported yet
+				continue
+			}
+			inst.handler(inst.conn, addr, pkt)
+		}
+	}
+}
+
+// handleDHCPv6 processes a client message and returns the reply, or nil
+// when the message should be ignored (RFC 8415 Section 16).
+func handleDHCPv6(m *dhcpv6.Message, store *LeaseStoreV6, scope config.DHCPScope, serverID dhcpv6.DUID, dnsUpdater DNSUpdater, listener LeaseListener) (*dhcpv6.Message, error) {
+	// Hot-standby backups stay quiet until they take over
+	if store != nil && !store.failover.answers() {
+		return nil, nil
+	}
+	clientID := m.Options.ClientID()
+	if clientID == nil && m.MessageType != dhcpv6.MessageTypeInformationRequest {
+		return nil, nil
+	}
+	if sid := m.Options.ServerID(); sid != nil && !sid.Equal(serverID) {
+		return nil, nil // Addressed to another server
+	}
+
+	common := []dhcpv6.Modifier{dhcpv6.WithServerID(serverID)}
+	if dns := parseIPs(scope.DNSServersV6); len(dns) > 0 {
+		common = append(common, dhcpv6.WithDNS(dns...))
+	}
+	if scope.Domain != "" {
+		common = append(common, dhcpv6.WithDomainSearchList(scope.Domain))
+	}
+
+	if m.MessageType == dhcpv6.MessageTypeInformationRequest {
+		if clientID == nil {
+			// Client ID is optional here, which NewReplyFromMessage rejects
+			rep := &dhcpv6.Message{MessageType: dhcpv6.MessageTypeReply, TransactionID: m.TransactionID}
+			for _, mod := range common {
+				mod(rep)
+			}
+			return rep, nil
+		}
+		return dhcpv6.NewReplyFromMessage(m, common...)
+	}
+	if store == nil {
+		// Stateless scope: only Information-Request is served
+		return nil, nil
+	}
+
+	duid := duidKey(clientID)
+	mac := duidMAC(clientID)
+	iaNA := m.Options.OneIANA()
+
+	switch m.MessageType {
+	case dhcpv6.MessageTypeSolicit:
+		if iaNA == nil {
+			return nil, nil
+		}
+		addr, err := store.Allocate(duid, mac)
+		if err != nil {
+			logging.WithComponent("dhcp").Warn("DHCPv6 pool exhausted", "scope", scope.Name, "duid", duid)
+			return dhcpv6.NewAdvertiseFromSolicit(m, append(common, withIANAStatus(iaNA, iana.StatusNoAddrsAvail, "no addresses available"))...)
+		}
+		if m.GetOneOption(dhcpv6.OptionRapidCommit) != nil {
+			commitDHCPv6(m, store, scope, duid, mac, addr, dnsUpdater, listener)
+			return dhcpv6.NewReplyFromMessage(m, append(common, withIANAAddr(iaNA, addr, store.getLeaseTime()))...)
+		}
+		return dhcpv6.NewAdvertiseFromSolicit(m, append(common, withIANAAddr(iaNA, addr, store.getLeaseTime()))...)
+
+	case dhcpv6.MessageTypeRequest, dhcpv6.MessageTypeRenew, dhcpv6.MessageTypeRebind:
+		if iaNA == nil {
+			return dhcpv6.NewReplyFromMessage(m, common...)
+		}
+		addr, err := store.Allocate(duid, mac)
+		if err != nil {
+			return dhcpv6.NewReplyFromMessage(m, append(common, withIANAStatus(iaNA, iana.StatusNoAddrsAvail, "no addresses available"))...)
+		}
+		commitDHCPv6(m, store, scope, duid, mac, addr, dnsUpdater, listener)
+		return dhcpv6.NewReplyFromMessage(m, append(common, withIANAAddr(iaNA, addr, store.getLeaseTime()))...)
+
+	case dhcpv6.MessageTypeRelease, dhcpv6.MessageTypeDecline:
+		if addr, hostname, ok := store.Release(duid); ok {
+			if hostname != "" {
+				removeDNSAddress(dnsUpdater, hostname, addr)
+			}
+			logging.WithComponent("dhcp").Info("DHCPv6 lease released", "duid", duid, "ip", addr, "type", m.MessageType)
+		}
+		return dhcpv6.NewReplyFromMessage(m, append(common, dhcpv6.WithOption(&dhcpv6.OptStatusCode{StatusCode: iana.StatusSuccess}))...)
+
+	case dhcpv6.MessageTypeConfirm:
+		// Confirm only asks whether the addresses are still on-link
+		code := iana.StatusSuccess
+		for _, ia := range m.Options.IANA() {
+			for _, a := range ia.Options.Addresses() {
+				addr, ok := netip.AddrFromSlice(a.IPv6Addr)
+				if !ok || !samePrefix64(addr.Unmap(), store.RangeStart) {
+					code = iana.StatusNotOnLink
+				}
+			}
+		}
+		return dhcpv6.NewReplyFromMessage(m, append(common, dhcpv6.WithOption(&dhcpv6.OptStatusCode{StatusCode: code}))...)
+	}
+
+	return nil, nil
+}
+
+// commitDHCPv6 finalizes a lease and registers it with DNS and listeners.
+func commitDHCPv6(m *dhcpv6.Message, store *LeaseStoreV6, scope config.DHCPScope, duid, mac string, addr netip.Addr, dnsUpdater DNSUpdater, listener LeaseListener) {
+	hostname := ""
+	if fqdn := m.Options.FQDN(); fqdn != nil && fqdn.DomainName != nil && len(fqdn.DomainName.Labels) > 0 {
+		hostname = strings.SplitN(fqdn.DomainName.Labels[0], ".", 2)[0]
+	}
+	store.Lock()
+	res, hasRes := store.reservation(duid, mac)
+	store.Unlock()
+	if hasRes && res.Hostname != "" {
+		hostname = res.Hostname
+	}
+
+	if hostname != "" && scope.Domain != "" {
+		hostname = hostname + "." + scope.Domain
+	}
+	if err := store.Commit(duid, mac, addr, hostname); err != nil {
+		logging.WithComponent("dhcp").WithError(err).Error("Failed to persist DHCPv6 lease", "duid", duid)
+	}
+
+	if hostname != "" && dnsUpdater != nil {
+		dnsUpdater.AddRecord(hostname, addr.AsSlice())
+	}
+	if listener != nil && mac != "" {
+		go listener.OnLease(mac, addr.AsSlice(), hostname)
+	}
+}
+
+// withIANAAddr answers an IA_NA with the leased address.
+func withIANAAddr(req *dhcpv6.OptIANA, addr netip.Addr, lifetime time.Duration) dhcpv6.Modifier {
+	return dhcpv6.WithOption(&dhcpv6.OptIANA{
+		IaId: req.IaId,
+		T1:   lifetime / 2,
+		T2:   lifetime * 4 / 5,
+		Options: dhcpv6.IdentityOptions{Options: dhcpv6.Options{&dhcpv6.OptIAAddress{
+			IPv6Addr:          addr.AsSlice(),
+			PreferredLifetime: lifetime,
+			ValidLifetime:     lifetime,
+		}}},
+	})
+}
+
+// withIANAStatus answers an IA_NA with a status code and no addresses.
+func withIANAStatus(req *dhcpv6.OptIANA, code iana.StatusCode, msg string) dhcpv6.Modifier {
+	return dhcpv6.WithOption(&dhcpv6.OptIANA{
+		IaId: req.IaId,
+		Options: dhcpv6.IdentityOptions{Options: dhcpv6.Options{
+			&dhcpv6.OptStatusCode{StatusCode: code, StatusMessage: msg},
+		}},
+	})
+}
+
+func samePrefix64(a, b netip.Addr) bool {
+	pa, _ := a.Prefix(64)
+	pb, _ := b.Prefix(64)
+	return pa == pb
+}
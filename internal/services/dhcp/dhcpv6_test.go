@@ -0,0 +1,200 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package dhcp
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"grimm.is/flywall/internal/clock"
+	"grimm.is/flywall/internal/config"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/iana"
+)
+
+var testServerDUID = &dhcpv6.DUIDLL{HWType: iana.HWTypeEthernet, LinkLayerAddr: net.HardwareAddr{0x02, 0, 0, 0, 0, 0x01}}
+
+func newTestSolicit(t *testing.T, mac string, rapidCommit bool) *dhcpv6.Message {
+	t.Helper()
+	hw, _ := net.ParseMAC(mac)
+	mods := []dhcpv6.Modifier{dhcpv6.WithFQDN(0, "laptop")}
+	if rapidCommit {
+		mods = append(mods, dhcpv6.WithRapidCommit)
+	}
+	sol, err := dhcpv6.NewSolicit(hw, mods...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Stable DUID-LL rather than the time-based default
+	sol.UpdateOption(dhcpv6.OptClientID(&dhcpv6.DUIDLL{HWType: iana.HWTypeEthernet, LinkLayerAddr: hw}))
+	return sol
+}
+
+func replyAddr(t *testing.T, m *dhcpv6.Message) netip.Addr {
+	t.Helper()
+	ia := m.Options.OneIANA()
+	if ia == nil || len(ia.Options.Addresses()) == 0 {
+		t.Fatalf("reply has no IA_NA address: %s", m.Summary())
+	}
+	addr, _ := netip.AddrFromSlice(ia.Options.Addresses()[0].IPv6Addr)
+	return addr
+}
+
+func TestHandleDHCPv6_SolicitRapidCommit(t *testing.T) {
+	store := newLeaseStoreV6(netip.MustParseAddr("2001:db8::100"), netip.MustParseAddr("2001:db8::1ff"))
+	scope := config.DHCPScope{Domain: "lan", DNSServersV6: []string{"2001:db8::1"}}
+	dnsUpdater := &MockDNSUpdater{}
+
+	// Without rapid commit: Advertise, nothing registered yet
+	adv, err := handleDHCPv6(newTestSolicit(t, "00:11:22:33:44:55", false), store, scope, testServerDUID, dnsUpdater, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if adv.MessageType != dhcpv6.MessageTypeAdvertise {
+		t.Fatalf("Expected Advertise, got %s", adv.MessageType)
+	}
+	if got := replyAddr(t, adv); got.String() != "2001:db8::100" {
+		t.Errorf("Expected first pool address, got %s", got)
+	}
+	if len(dnsUpdater.records) != 0 {
+		t.Error("Advertise should not register DNS")
+	}
+
+	// With rapid commit: Reply, lease committed and AAAA registered
+	rep, err := handleDHCPv6(newTestSolicit(t, "00:11:22:33:44:55", true), store, scope, testServerDUID, dnsUpdater, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.MessageType != dhcpv6.MessageTypeReply || rep.GetOneOption(dhcpv6.OptionRapidCommit) == nil {
+		t.Fatalf("Expected rapid-commit Reply, got %s", rep.Summary())
+	}
+	if got := replyAddr(t, rep); got.String() != "2001:db8::100" {
+		t.Errorf("Expected same address on commit, got %s", got)
+	}
+	if dns := rep.Options.DNS(); len(dns) != 1 || !dns[0].Equal(net.ParseIP("2001:db8::1")) {
+		t.Errorf("Expected option 23 DNS, got %v", dns)
+	}
+	if rep.Options.DomainSearchList() == nil {
+		t.Error("Expected option 24 domain search list")
+	}
+	if ip := dnsUpdater.records["laptop.lan"]; !ip.Equal(net.ParseIP("2001:db8::100")) {
+		t.Errorf("Expected AAAA for laptop.lan, got %v", ip)
+	}
+}
+
+func TestHandleDHCPv6_Reservations(t *testing.T) {
+	store := newLeaseStoreV6(netip.MustParseAddr("2001:db8::100"), netip.MustParseAddr("2001:db8::1ff"))
+	byMAC := config.DHCPReservation{MAC: "00:11:22:33:44:66", IPv6: "2001:db8::10", Hostname: "printer"}
+	store.Reservations["00:11:22:33:44:66"] = byMAC
+	store.ReservedIPs[netip.MustParseAddr("2001:db8::10")] = "00:11:22:33:44:66"
+
+	sol := newTestSolicit(t, "00:11:22:33:44:66", true)
+	byDUID := config.DHCPReservation{MAC: "00:11:22:33:44:77", IPv6: "2001:db8::20"}
+	store.Reservations[normalizeDUID("00:03:00:01:00:11:22:33:44:77")] = byDUID
+	store.ReservedIPs[netip.MustParseAddr("2001:db8::100")] = "reserved-in-pool"
+
+	rep, err := handleDHCPv6(sol, store, config.DHCPScope{}, testServerDUID, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := replyAddr(t, rep); got.String() != "2001:db8::10" {
+		t.Errorf("Expected MAC reservation, got %s", got)
+	}
+
+	rep, _ = handleDHCPv6(newTestSolicit(t, "00:11:22:33:44:77", true), store, config.DHCPScope{}, testServerDUID, nil, nil)
+	if got := replyAddr(t, rep); got.String() != "2001:db8::20" {
+		t.Errorf("Expected DUID reservation, got %s", got)
+	}
+
+	// Dynamic clients skip addresses reserved inside the pool
+	rep, _ = handleDHCPv6(newTestSolicit(t, "00:11:22:33:44:88", true), store, config.DHCPScope{}, testServerDUID, nil, nil)
+	if got := replyAddr(t, rep); got.String() != "2001:db8::101" {
+		t.Errorf("Expected reserved pool address to be skipped, got %s", got)
+	}
+}
+
+func TestHandleDHCPv6_ReleaseAndForeignServer(t *testing.T) {
+	store := newLeaseStoreV6(netip.MustParseAddr("2001:db8::100"), netip.MustParseAddr("2001:db8::1ff"))
+	scope := config.DHCPScope{Domain: "lan"}
+	dnsUpdater := &MockDNSUpdater{}
+
+	sol := newTestSolicit(t, "00:11:22:33:44:55", true)
+	if _, err := handleDHCPv6(sol, store, scope, testServerDUID, dnsUpdater, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	rel, _ := dhcpv6.NewMessage()
+	rel.MessageType = dhcpv6.MessageTypeRelease
+	rel.AddOption(sol.GetOneOption(dhcpv6.OptionClientID))
+
+	// A Release for another server is ignored
+	rel.AddOption(dhcpv6.OptServerID(&dhcpv6.DUIDLL{HWType: iana.HWTypeEthernet, LinkLayerAddr: net.HardwareAddr{0x02, 0, 0, 0, 0, 0x99}}))
+	if rep, _ := handleDHCPv6(rel, store, scope, testServerDUID, dnsUpdater, nil); rep != nil {
+		t.Error("Expected message for another server to be ignored")
+	}
+
+	rel.UpdateOption(dhcpv6.OptServerID(testServerDUID))
+	rep, err := handleDHCPv6(rel, store, scope, testServerDUID, dnsUpdater, nil)
+	if err != nil || rep == nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if len(store.Leases) != 0 {
+		t.Error("Lease not released")
+	}
+	if _, ok := dnsUpdater.records["laptop.lan"]; ok {
+		t.Error("DNS record not removed on release")
+	}
+}
+
+func TestHandleDHCPv6_Stateless(t *testing.T) {
+	scope := config.DHCPScope{DNSServersV6: []string{"2001:db8::53"}}
+
+	info, _ := dhcpv6.NewMessage()
+	info.MessageType = dhcpv6.MessageTypeInformationRequest
+	rep, err := handleDHCPv6(info, nil, scope, testServerDUID, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dns := rep.Options.DNS(); len(dns) != 1 {
+		t.Errorf("Expected DNS in Information-Request reply, got %v", dns)
+	}
+
+	// No range configured: stateful requests are ignored
+	if rep, _ := handleDHCPv6(newTestSolicit(t, "00:11:22:33:44:55", true), nil, scope, testServerDUID, nil, nil); rep != nil {
+		t.Error("Expected Solicit to be ignored by stateless scope")
+	}
+}
+
+func TestLeaseStoreV6_Expiration(t *testing.T) {
+	mockClock := clock.NewMockClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := newLeaseStoreV6(netip.MustParseAddr("2001:db8::100"), netip.MustParseAddr("2001:db8::1ff"))
+	store.clock = mockClock
+	store.leaseTime = time.Hour
+
+	addr, err := store.Allocate("000300010011223344aa", "00:11:22:33:44:aa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Commit("000300010011223344aa", "00:11:22:33:44:aa", addr, "tv.lan"); err != nil {
+		t.Fatal(err)
+	}
+
+	dnsUpdater := &MockDNSUpdater{records: map[string]net.IP{"tv.lan": addr.AsSlice()}}
+	if n := store.ExpireLeases(dnsUpdater, nil); n != 0 {
+		t.Errorf("Expected no expirations yet, got %d", n)
+	}
+
+	mockClock.Advance(2 * time.Hour)
+	if n := store.ExpireLeases(dnsUpdater, nil); n != 1 {
+		t.Errorf("Expected 1 expiration, got %d", n)
+	}
+	if _, ok := dnsUpdater.records["tv.lan"]; ok {
+		t.Error("DNS record not removed on expiry")
+	}
+	if _, taken := store.TakenIPs[addr]; taken {
+		t.Error("Address still taken after expiry")
+	}
+}
@@ -0,0 +1,332 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package dhcp
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"grimm.is/flywall/internal/clock"
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/errors"
+	"grimm.is/flywall/internal/logging"
+	"grimm.is/flywall/internal/state"
+)
+
+// Failover modes.
+const (
+	FailoverHotStandby = "hot_standby"
+	FailoverSplit      = "split"
+)
+
+const (
+	defaultFailoverSplit = 50
+	defaultMCLT          = time.Hour
+)
+
+// FailoverState is this node's view of the HA pair.
+type FailoverState struct {
+	Primary      bool      // This node holds the HA primary role
+	PeerAlive    bool      // The peer is answering heartbeats
+	PeerLastSeen time.Time // Last heartbeat from the peer (zero if never seen)
+}
+
+// LeaseForwarder sends lease writes to the node that owns the replicated
+// store. This is satisfied by state.Replicator.
+type LeaseForwarder interface {
+	Forward(bucket, key string, value []byte) error
+	IsPrimary() bool
+}
+
+// failover holds the pool-sharing policy for one Reload of the service.
+// A nil *failover means the server runs standalone.
+type failover struct {
+	mode      string
+	split     int
+	mclt      time.Duration
+	state     func() FailoverState
+	forwarder LeaseForwarder
+	started   time.Time
+}
+
+func newFailover(cfg *config.DHCPFailover, stateFn func() FailoverState, fwd LeaseForwarder) *failover {
+	if cfg == nil || stateFn == nil {
+		return nil
+	}
+	f := &failover{
+		mode:      cfg.Mode,
+		split:     cfg.Split,
+		mclt:      defaultMCLT,
+		state:     stateFn,
+		forwarder: fwd,
+		started:   clock.Now(),
+	}
+	if f.mode == "" {
+		f.mode = FailoverHotStandby
+	}
+	if f.split <= 0 || f.split >= 100 {
+		f.split = defaultFailoverSplit
+	}
+	if cfg.MCLT != "" {
+		if d, err := time.ParseDuration(cfg.MCLT); err == nil && d > 0 {
+			f.mclt = d
+		} else {
+			logging.WithComponent("dhcp").Warn("Invalid failover mclt, using default", "mclt", cfg.MCLT, "default", defaultMCLT)
+		}
+	}
+	return f
+}
+
+// answers reports whether this node should respond to clients at all.
+// In hot-standby only the primary serves; in split mode both do.
+func (f *failover) answers() bool {
+	if f == nil || f.mode == FailoverSplit {
+		return true
+	}
+	return f.state().Primary
+}
+
+// partnerDown reports whether the peer has been unreachable for at least
+// MCLT, after which any lease it handed out without telling us has expired
+// and its share of the pool is safe to use.
+func (f *failover) partnerDown(st FailoverState, now time.Time) bool {
+	if st.PeerAlive {
+		return false
+	}
+	since := st.PeerLastSeen
+	if since.Before(f.started) {
+		since = f.started
+	}
+	return now.Sub(since) >= f.mclt
+}
+
+// owns reports whether a new lease for ip may be allocated by this node.
+// The primary owns the first split% of the range, the backup the rest.
+func (f *failover) owns(ip, start, end net.IP, now time.Time) bool {
+	if f == nil || f.mode != FailoverSplit {
+		return true
+	}
+	st := f.state()
+	if f.partnerDown(st, now) {
+		return true
+	}
+	first, last, off := ipToUint32(start), ipToUint32(end), ipToUint32(ip)
+	if last < first || off < first {
+		return true
+	}
+	boundary := first + uint32(uint64(last-first+1)*uint64(f.split)/100)
+	return (off < boundary) == st.Primary
+}
+
+// leaseTime caps the lease at MCLT while the peer can't be told about it,
+// so a lease the peer never heard of outlives the outage by at most MCLT.
+func (f *failover) leaseTime(d time.Duration) time.Duration {
+	if f == nil || f.state().PeerAlive {
+		return d
+	}
+	return min(d, f.mclt)
+}
+
+// forward sends a lease write to the primary when this node is a replica.
+// It returns false when the caller should write to the local store instead.
+// A replica must never write locally, as that would break the change chain,
+// so a failed forward is returned as an error and the lease isn't handed out.
+func (f *failover) forward(lease *state.DHCPLease) (bool, error) {
+	if f == nil || f.forwarder == nil || f.forwarder.IsPrimary() {
+		return false, nil
+	}
+	data, err := json.Marshal(lease)
+	if err != nil {
+		return true, errors.Wrap(err, errors.KindInternal, "failed to encode lease")
+	}
+	if err := f.forwarder.Forward(state.BucketDHCPLeases, lease.Key(), data); err != nil {
+		return true, errors.Wrap(err, errors.KindUnavailable, "failed to forward lease to primary")
+	}
+	return true, nil
+}
+
+// ownsStore reports whether this node should delete expired leases from
+// the store. Replicas leave that to the primary, whose deletes replicate.
+func (f *failover) ownsStore() bool {
+	return f == nil || f.forwarder == nil || f.forwarder.IsPrimary()
+}
+
+func ipToUint32(ip net.IP) uint32 {
+	if v4 := ip.To4(); v4 != nil {
+		return binary.BigEndian.Uint32(v4)
+	}
+	return 0
+}
+
+// SetFailover wires the HA state and replication link used when the config
+// has a dhcp.failover block. It takes effect on the next Reload.
+func (s *Service) SetFailover(stateFn func() FailoverState, fwd LeaseForwarder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failoverState = stateFn
+	s.forwarder = fwd
+}
+
+// watchPeerLeases applies lease changes arriving over replication to the
+// in-memory pools, so addresses the peer handed out are never reallocated.
+func (s *Service) watchPeerLeases(ctx context.Context, stores []*LeaseStore, v6stores []*LeaseStoreV6) {
+	for change := range s.store.Subscribe(ctx) {
+		if change.Bucket != state.BucketDHCPLeases {
+			continue
+		}
+		duid, isV6 := strings.CutPrefix(change.Key, "duid:")
+		if change.Type == state.ChangeDelete {
+			if isV6 {
+				for _, ls := range v6stores {
+					ls.forgetPeerLease(duid)
+				}
+				continue
+			}
+			for _, ls := range stores {
+				ls.forgetPeerLease(change.Key)
+			}
+			continue
+		}
+		var lease state.DHCPLease
+		if err := json.Unmarshal(change.Value, &lease); err != nil {
+			continue
+		}
+		if isV6 {
+			for _, ls := range v6stores {
+				ls.applyPeerLease(&lease)
+			}
+			continue
+		}
+		for _, ls := range stores {
+			ls.applyPeerLease(&lease)
+		}
+	}
+}
+
+// applyPeerLease records a lease learned from the store. If the address is
+// held locally by another client the newer write wins and the conflict is
+// logged; the losing client gets a NAK on its next renewal.
+func (s *LeaseStore) applyPeerLease(lease *state.DHCPLease) {
+	ip := net.ParseIP(lease.IP).To4()
+	if ip == nil || (s.Subnet != nil && !s.Subnet.Contains(ip)) {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if holder, ok := s.TakenIPs[ip.String()]; ok && holder != lease.MAC {
+		logging.WithComponent("dhcp").Warn("Lease conflict with failover peer, peer lease wins", "ip", ip, "local_mac", holder, "peer_mac", lease.MAC)
+		delete(s.Leases, holder)
+		delete(s.leaseExpiry, holder)
+	}
+	if old, ok := s.Leases[lease.MAC]; ok && !old.Equal(ip) {
+		delete(s.TakenIPs, old.String())
+	}
+
+	s.Leases[lease.MAC] = ip
+	s.TakenIPs[ip.String()] = lease.MAC
+	if !lease.LeaseEnd.IsZero() {
+		if s.leaseExpiry == nil {
+			s.leaseExpiry = make(map[string]time.Time)
+		}
+		s.leaseExpiry[lease.MAC] = lease.LeaseEnd
+	}
+	if lease.Hostname != "" && lease.Hostname != "hostname-unknown" {
+		if s.hostnames == nil {
+			s.hostnames = make(map[string]string)
+		}
+		s.hostnames[lease.MAC] = lease.Hostname
+	}
+}
+
+// heldInStore double-checks the store before a new address is handed out
+// under failover. The change feed drops events for slow subscribers, so the
+// in-memory pool alone could miss a lease the peer just granted. Called with
+// the store lock held.
+func (s *LeaseStore) heldInStore(ip net.IP, mac string) bool {
+	if s.failover == nil || s.bucket == nil {
+		return false
+	}
+	lease, err := s.bucket.GetByIP(ip.String())
+	if err != nil || lease.MAC == mac {
+		return false
+	}
+	if !lease.LeaseEnd.IsZero() && s.getNow().After(lease.LeaseEnd) {
+		return false
+	}
+	logging.WithComponent("dhcp").Warn("Address leased by failover peer, skipping", "ip", ip, "peer_mac", lease.MAC)
+	s.TakenIPs[ip.String()] = lease.MAC
+	s.Leases[lease.MAC] = ip
+	return true
+}
+
+// forgetPeerLease drops a lease deleted from the store.
+func (s *LeaseStore) forgetPeerLease(mac string) {
+	s.Lock()
+	defer s.Unlock()
+	ip, ok := s.Leases[mac]
+	if !ok {
+		return
+	}
+	delete(s.Leases, mac)
+	delete(s.TakenIPs, ip.String())
+	delete(s.leaseExpiry, mac)
+	delete(s.hostnames, mac)
+}
+
+// applyPeerLease records a DHCPv6 lease learned from the store, with the
+// same newer-write-wins handling of conflicts as LeaseStore.
+func (s *LeaseStoreV6) applyPeerLease(lease *state.DHCPLease) {
+	addr, err := netip.ParseAddr(lease.IP)
+	if lease.DUID == "" || err != nil || !s.inRange(addr) {
+		return
+	}
+	if lease.Interface != "" && s.Interface != "" && lease.Interface != s.Interface {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if holder, ok := s.TakenIPs[addr]; ok && holder != lease.DUID {
+		logging.WithComponent("dhcp").Warn("DHCPv6 lease conflict with failover peer, peer lease wins", "ip", addr, "local_duid", holder, "peer_duid", lease.DUID)
+		delete(s.Leases, holder)
+		delete(s.leaseExpiry, holder)
+		delete(s.hostnames, holder)
+		delete(s.macs, holder)
+	}
+	if old, ok := s.Leases[lease.DUID]; ok && old != addr {
+		delete(s.TakenIPs, old)
+	}
+
+	s.Leases[lease.DUID] = addr
+	s.TakenIPs[addr] = lease.DUID
+	s.macs[lease.DUID] = lease.MAC
+	if !lease.LeaseEnd.IsZero() {
+		s.leaseExpiry[lease.DUID] = lease.LeaseEnd
+	}
+	if lease.Hostname != "" {
+		s.hostnames[lease.DUID] = lease.Hostname
+	}
+}
+
+// forgetPeerLease drops a DHCPv6 lease deleted from the store.
+func (s *LeaseStoreV6) forgetPeerLease(duid string) {
+	s.Lock()
+	defer s.Unlock()
+	addr, ok := s.Leases[duid]
+	if !ok {
+		return
+	}
+	delete(s.Leases, duid)
+	delete(s.TakenIPs, addr)
+	delete(s.leaseExpiry, duid)
+	delete(s.hostnames, duid)
+	delete(s.macs, duid)
+}
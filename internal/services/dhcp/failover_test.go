@@ -0,0 +1,220 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package dhcp
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"grimm.is/flywall/internal/clock"
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/errors"
+	"grimm.is/flywall/internal/state"
+)
+
+// mockForwarder records forwarded writes in place of a replica's link.
+type mockForwarder struct {
+	primary   bool
+	forwarded map[string][]byte
+	err       error
+}
+
+func (m *mockForwarder) Forward(bucket, key string, value []byte) error {
+	if m.err != nil {
+		return m.err
+	}
+	if m.forwarded == nil {
+		m.forwarded = make(map[string][]byte)
+	}
+	m.forwarded[key] = value
+	return nil
+}
+
+func (m *mockForwarder) IsPrimary() bool { return m.primary }
+
+func newFailoverStore(fo *failover, mockClock clock.Clock) *LeaseStore {
+	return &LeaseStore{
+		Leases:       make(map[string]net.IP),
+		TakenIPs:     make(map[string]string),
+		Reservations: make(map[string]config.DHCPReservation),
+		ReservedIPs:  make(map[string]string),
+		RangeStart:   net.ParseIP("192.168.1.100").To4(),
+		RangeEnd:     net.ParseIP("192.168.1.199").To4(),
+		clock:        mockClock,
+		leaseTime:    12 * time.Hour,
+		failover:     fo,
+	}
+}
+
+func TestFailover_SplitScope(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	mockClock := clock.NewMockClock(now)
+
+	st := FailoverState{Primary: true, PeerAlive: true, PeerLastSeen: now}
+	fo := newFailover(&config.DHCPFailover{Mode: "split", Split: 50, MCLT: "30m"}, func() FailoverState { return st }, nil)
+	fo.started = now.Add(-time.Hour)
+	store := newFailoverStore(fo, mockClock)
+
+	ip, err := store.Allocate("00:11:22:33:44:01")
+	if err != nil || ip.String() != "192.168.1.100" {
+		t.Fatalf("Primary should allocate from the low half, got %v (%v)", ip, err)
+	}
+
+	st.Primary = false
+	ip, _ = store.Allocate("00:11:22:33:44:02")
+	if ip.String() != "192.168.1.150" {
+		t.Errorf("Backup should allocate from the high half, got %v", ip)
+	}
+
+	// Peer down, but not for MCLT yet: still confined to our half
+	st.PeerAlive = false
+	mockClock.Advance(10 * time.Minute)
+	if fo.owns(net.ParseIP("192.168.1.101"), store.RangeStart, store.RangeEnd, mockClock.Now()) {
+		t.Error("Backup must not use the primary's half before MCLT elapses")
+	}
+	if got := store.getLeaseTime(); got != 30*time.Minute {
+		t.Errorf("Expected lease time capped at MCLT while peer is down, got %v", got)
+	}
+
+	// Partner down for MCLT: the whole pool is ours
+	mockClock.Advance(25 * time.Minute)
+	if !fo.owns(net.ParseIP("192.168.1.101"), store.RangeStart, store.RangeEnd, mockClock.Now()) {
+		t.Error("Expected peer's half to be usable after MCLT")
+	}
+}
+
+func TestFailover_HotStandby(t *testing.T) {
+	st := FailoverState{Primary: false, PeerAlive: true}
+	fwd := &mockForwarder{}
+	fo := newFailover(&config.DHCPFailover{}, func() FailoverState { return st }, fwd)
+
+	if fo.mode != FailoverHotStandby || fo.mclt != defaultMCLT {
+		t.Fatalf("Unexpected defaults: mode=%s mclt=%v", fo.mode, fo.mclt)
+	}
+	if fo.answers() {
+		t.Error("Hot-standby backup should not answer clients")
+	}
+	if fo.ownsStore() {
+		t.Error("Replica should leave store deletes to the primary")
+	}
+
+	// Writes on a replica go to the primary instead of the local store
+	lease := &state.DHCPLease{MAC: "00:11:22:33:44:01", IP: "192.168.1.100"}
+	if ok, err := fo.forward(lease); !ok || err != nil || fwd.forwarded["00:11:22:33:44:01"] == nil {
+		t.Errorf("Expected lease to be forwarded: %v", err)
+	}
+
+	// A replica that can't reach the primary fails rather than write locally
+	fwd.err = errors.New(errors.KindUnavailable, "not connected to primary")
+	if ok, err := fo.forward(lease); !ok || err == nil {
+		t.Error("Expected a failed forward to be reported, not written locally")
+	}
+	fwd.err = nil
+
+	st.Primary = true
+	fwd.primary = true
+	if ok, _ := fo.forward(lease); !fo.answers() || ok {
+		t.Error("Primary should answer and write locally")
+	}
+}
+
+func TestLeaseStore_PeerLeases(t *testing.T) {
+	store, err := state.NewSQLiteStore(state.DefaultOptions(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	bucket, err := state.NewDHCPBucket(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now() // The bucket derives TTLs from wall time
+	st := FailoverState{Primary: true, PeerAlive: true}
+	ls := newFailoverStore(newFailover(&config.DHCPFailover{}, func() FailoverState { return st }, nil), clock.NewMockClock(now))
+	ls.bucket = bucket
+
+	// A lease arriving over replication is never handed to anyone else
+	ls.applyPeerLease(&state.DHCPLease{MAC: "00:11:22:33:44:aa", IP: "192.168.1.100", LeaseEnd: now.Add(time.Hour)})
+	ip, _ := ls.Allocate("00:11:22:33:44:01")
+	if ip.String() != "192.168.1.101" {
+		t.Errorf("Expected peer lease to be skipped, got %v", ip)
+	}
+
+	// One the change feed missed is still caught by the store check
+	if err := bucket.Set(&state.DHCPLease{MAC: "00:11:22:33:44:bb", IP: "192.168.1.102", LeaseEnd: now.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	ip, _ = ls.Allocate("00:11:22:33:44:02")
+	if ip.String() != "192.168.1.103" {
+		t.Errorf("Expected stored peer lease to be skipped, got %v", ip)
+	}
+
+	ls.forgetPeerLease("00:11:22:33:44:aa")
+	if ls.isTaken(net.ParseIP("192.168.1.100")) {
+		t.Error("Deleted peer lease still marks the address taken")
+	}
+}
+
+func TestHandleDHCPv6_Failover(t *testing.T) {
+	store, err := state.NewSQLiteStore(state.DefaultOptions(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	bucket, err := state.NewDHCPBucket(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := FailoverState{Primary: false, PeerAlive: true}
+	fwd := &mockForwarder{}
+	ls := newLeaseStoreV6(netip.MustParseAddr("2001:db8::100"), netip.MustParseAddr("2001:db8::1ff"))
+	ls.bucket = bucket
+	ls.failover = newFailover(&config.DHCPFailover{}, func() FailoverState { return st }, fwd)
+	scope := config.DHCPScope{}
+
+	// Hot-standby backup stays quiet
+	rep, err := handleDHCPv6(newTestSolicit(t, "00:11:22:33:44:55", true), ls, scope, testServerDUID, nil, nil)
+	if err != nil || rep != nil {
+		t.Fatalf("Hot-standby backup should not answer a SOLICIT, got %v (%v)", rep, err)
+	}
+
+	// A replica that answers forwards the lease instead of writing locally
+	ls.failover.mode = FailoverSplit
+	sol := newTestSolicit(t, "00:11:22:33:44:55", true)
+	rep, err = handleDHCPv6(sol, ls, scope, testServerDUID, nil, nil)
+	if err != nil || rep == nil {
+		t.Fatalf("Expected a Reply from the replica, got %v (%v)", rep, err)
+	}
+	addr := replyAddr(t, rep)
+	key := "duid:" + duidKey(sol.Options.ClientID())
+	if fwd.forwarded[key] == nil {
+		t.Errorf("Expected lease forwarded under %s, got %v", key, fwd.forwarded)
+	}
+	if leases, _ := bucket.List(); len(leases) != 0 {
+		t.Errorf("Replica wrote %d leases to its local store", len(leases))
+	}
+
+	// Releasing on the replica leaves the store delete to the primary
+	if err := bucket.Set(&state.DHCPLease{IP: addr.String(), DUID: duidKey(sol.Options.ClientID()), LeaseEnd: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	ls.Release(duidKey(sol.Options.ClientID()))
+	if lease, err := bucket.Get(key); err != nil || lease == nil {
+		t.Errorf("Replica deleted a lease from the store: %v", err)
+	}
+
+	// Leases the peer hands out are never reallocated
+	ls.applyPeerLease(&state.DHCPLease{DUID: "0003000100aabbccddee", IP: "2001:db8::100", LeaseEnd: time.Now().Add(time.Hour)})
+	got, err := ls.Allocate("000300010011223344ff", "")
+	if err != nil || got.String() != "2001:db8::101" {
+		t.Errorf("Expected peer lease to be skipped, got %v (%v)", got, err)
+	}
+	ls.forgetPeerLease("0003000100aabbccddee")
+	if _, taken := ls.TakenIPs[netip.MustParseAddr("2001:db8::100")]; taken {
+		t.Error("Deleted peer lease still marks the address taken")
+	}
+}
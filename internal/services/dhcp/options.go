@@ -156,6 +156,10 @@ func parseOption(key, value string) (dhcpv4.Option, error) {
 	case "classless_static_route_ms", "ms_classless_static_route":
 		code = dhcpv4.GenericOptionCode(249)
 
+	// Captive portal API URI (114, RFC 8910)
+	case "captive_portal", "captive_portal_url":
+		code = dhcpv4.GenericOptionCode(114)
+
 	// Proxy and URL (252)
 	case "wpad", "proxy_autodiscovery", "auto_proxy_config":
 		code = dhcpv4.GenericOptionCode(252)
@@ -219,6 +223,7 @@ func parseOption(key, value string) (dhcpv4.Option, error) {
 			dhcpv4.GenericOptionCode(18),  // Extensions Path
 			dhcpv4.GenericOptionCode(47),  // NetBIOS Scope
 			dhcpv4.GenericOptionCode(66),  // TFTP Server Name
+			dhcpv4.GenericOptionCode(114), // Captive Portal API URI
 			dhcpv4.GenericOptionCode(252): // WPAD
 			typePrefix = "str"
 
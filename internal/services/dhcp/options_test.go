@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/insomniacslk/dhcp/dhcpv4"
+	"grimm.is/flywall/internal/config"
 )
 
 func TestParseOption(t *testing.T) {
@@ -51,6 +52,7 @@ func TestParseOption(t *testing.T) {
 		{"domain_name", "str:example.com", dhcpv4.OptionDomainName, false},
 		{"wins_server", "ip:192.168.1.10", dhcpv4.GenericOptionCode(44), false}, // NetBIOS
 		{"wpad", "str:http://proxy.example.com/wpad.dat", dhcpv4.GenericOptionCode(252), false},
+		{"captive_portal", "https://portal.example.com/api/captive", dhcpv4.GenericOptionCode(114), false},
 
 		// Auto-type inference (no prefix needed for named options)
 		{"dns_server", "8.8.8.8", dhcpv4.OptionDomainNameServer, false},
@@ -138,3 +140,36 @@ func TestParseIPList_Empty(t *testing.T) {
 		t.Errorf("expected 0 IPs for empty string, got %d", len(ips))
 	}
 }
+
+func TestWithPortalOption(t *testing.T) {
+	cfg := &config.Config{
+		Interfaces: []config.Interface{{Name: "eth1", Zone: "lan"}, {Name: "eth2", Zone: "guest"}},
+		Zones: []config.Zone{
+			{Name: "lan"},
+			{Name: "guest", Services: &config.ZoneServices{CaptivePortal: true}},
+		},
+	}
+	lan := config.DHCPScope{Name: "lan", Interface: "eth1", Router: "192.168.1.1"}
+	guest := config.DHCPScope{Name: "guest", Interface: "eth2", Router: "10.0.50.1", Options: map[string]string{"mtu": "1500"}}
+
+	if got := withPortalOption(cfg, lan); got.Options != nil {
+		t.Errorf("Options = %v for a scope outside the portal zone", got.Options)
+	}
+	got := withPortalOption(cfg, guest)
+	if got.Options["captive_portal"] != "http://10.0.50.1:8880/api/captive" || got.Options["mtu"] != "1500" {
+		t.Errorf("Options = %v", got.Options)
+	}
+	if _, ok := guest.Options["captive_portal"]; ok {
+		t.Error("withPortalOption() modified the configured scope")
+	}
+
+	cfg.CaptivePortal = &config.CaptivePortalConfig{APIURL: "https://portal.example.com/api/captive"}
+	if got := withPortalOption(cfg, guest); got.Options["captive_portal"] != "https://portal.example.com/api/captive" {
+		t.Errorf("Options = %v; want the configured api_url", got.Options)
+	}
+
+	guest.Options = map[string]string{"114": "https://other.example.com/"}
+	if got := withPortalOption(cfg, guest); len(got.Options) != 1 || got.Options["114"] != "https://other.example.com/" {
+		t.Errorf("Options = %v; an explicit option 114 must win", got.Options)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package dhcp
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"grimm.is/flywall/internal/config"
+)
+
+// withPortalOption returns scope with the RFC 8910 captive portal API URI
+// (option 114) added when its interface is in a captive portal zone. An
+// option 114 set on the scope is left alone.
+func withPortalOption(cfg *config.Config, scope config.DHCPScope) config.DHCPScope {
+	p := cfg.PortalSettings()
+	if p == nil {
+		return scope
+	}
+	for k := range scope.Options {
+		if k == "114" || strings.HasPrefix(strings.ReplaceAll(strings.ToLower(k), "-", "_"), "captive_portal") {
+			return scope
+		}
+	}
+
+	zone := ""
+	for _, iface := range cfg.Interfaces {
+		if iface.Name == scope.Interface {
+			zone = iface.Zone
+			break
+		}
+	}
+	if zone == "" {
+		zone = config.NewZoneResolver(cfg.Zones).ResolveInterface(scope.Interface)
+	}
+	inPortal := false
+	for _, z := range cfg.PortalZones() {
+		if strings.EqualFold(z, zone) {
+			inPortal = true
+			break
+		}
+	}
+	if !inPortal {
+		return scope
+	}
+
+	uri := p.APIURL
+	if uri == "" {
+		if net.ParseIP(scope.Router) == nil {
+			return scope
+		}
+		uri = fmt.Sprintf("http://%s/api/captive", net.JoinHostPort(scope.Router, strconv.Itoa(p.Port)))
+	}
+
+	opts := make(map[string]string, len(scope.Options)+1)
+	for k, v := range scope.Options {
+		opts[k] = v
+	}
+	opts["captive_portal"] = uri
+	scope.Options = opts
+	return scope
+}
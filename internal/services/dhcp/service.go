@@ -62,6 +62,8 @@ type Service struct {
 	mu             sync.RWMutex
 	servers        []*dhcpInstance
 	leaseStores    []*LeaseStore // Track stores for expiration reaper
+	v6servers      []*dhcpv6Instance
+	v6LeaseStores  []*LeaseStoreV6 // Stateful DHCPv6 scopes only
 	dnsUpdater     DNSUpdater
 	leaseListener  LeaseListener
 	packetListener PacketListener // Passive sniffing listener
@@ -69,6 +71,11 @@ type Service struct {
 	running        bool
 	stopReaper     chan struct{} // Signal to stop expiration reaper
 	upgradeMgr     *upgrade.Manager
+
+	// HA failover (see failover.go)
+	failoverState func() FailoverState
+	forwarder     LeaseForwarder
+	stopSync      context.CancelFunc // Stops the peer lease watcher
 }
 
 // SetUpgradeManager sets the upgrade manager for socket handoff.
@@ -117,6 +124,9 @@ func (s *Service) Start(ctx context.Context) error {
 			s.serveDHCP(ctx, inst.conn, inst.handler)
 		}(srv)
 	}
+	for _, srv := range s.v6servers {
+		go s.serveDHCPv6(ctx, srv)
+	}
 	s.running = true
 	return nil
 }
@@ -139,6 +149,13 @@ func (s *Service) Stop(ctx context.Context) error {
 			c.Close()
 		}
 	}
+	for _, srv := range s.v6servers {
+		srv.conn.Close()
+	}
+	if s.stopSync != nil {
+		s.stopSync()
+		s.stopSync = nil
+	}
 	s.running = false
 	return nil
 }
@@ -162,10 +179,19 @@ func (s *Service) Reload(cfg *config.Config) (bool, error) {
 				c.Close()
 			}
 		}
+		for _, srv := range s.v6servers {
+			srv.conn.Close()
+		}
+		if s.stopSync != nil {
+			s.stopSync()
+			s.stopSync = nil
+		}
 		s.running = false
 	}
 	s.servers = nil     // Clear old servers
 	s.leaseStores = nil // Clear old lease stores
+	s.v6servers = nil
+	s.v6LeaseStores = nil
 
 	if cfg.DHCP == nil || !cfg.DHCP.Enabled {
 		return true, nil
@@ -177,6 +203,11 @@ func (s *Service) Reload(cfg *config.Config) (bool, error) {
 		return true, nil
 	}
 
+	fo := newFailover(cfg.DHCP.Failover, s.failoverState, s.forwarder)
+	if cfg.DHCP.Failover != nil && fo == nil {
+		logging.WithComponent("dhcp").Warn("Failover configured but HA is not running, serving standalone")
+	}
+
 	// Parse scopes (only if built-in)
 	for _, scope := range cfg.DHCP.Scopes {
 		var srv *dhcpInstance
@@ -230,14 +261,29 @@ func (s *Service) Reload(cfg *config.Config) (bool, error) {
 			}
 		} else {
 			// Normal Server Mode
+			scope = withPortalOption(cfg, scope)
 			srv, ls, err = s.createServer(scope, cfg.DHCP.VendorClasses)
 			if err != nil {
 				return true, fmt.Errorf("failed to create DHCP server for scope %s: %w", scope.Name, err)
 			}
 		}
 
+		ls.failover = fo
 		s.servers = append(s.servers, srv)
 		s.leaseStores = append(s.leaseStores, ls)
+
+		// DHCPv6 alongside the v4 scope (not relayed)
+		if len(scope.RelayTo) == 0 && scope.HasDHCPv6() {
+			srv6, ls6, err := s.createServerV6(scope)
+			if err != nil {
+				return true, fmt.Errorf("failed to create DHCPv6 server for scope %s: %w", scope.Name, err)
+			}
+			s.v6servers = append(s.v6servers, srv6)
+			if ls6 != nil {
+				ls6.failover = fo
+				s.v6LeaseStores = append(s.v6LeaseStores, ls6)
+			}
+		}
 	}
 
 	// Restart servers
@@ -247,6 +293,9 @@ func (s *Service) Reload(cfg *config.Config) (bool, error) {
 			s.serveDHCP(context.Background(), inst.conn, inst.handler) // Background ctx for Handover
 		}(srv)
 	}
+	for _, srv := range s.v6servers {
+		go s.serveDHCPv6(context.Background(), srv)
+	}
 
 	// Start expiration reaper
 	// Calculate reaper interval based on minimum lease time
@@ -257,6 +306,9 @@ func (s *Service) Reload(cfg *config.Config) (bool, error) {
 			minInterval = lt
 		}
 	}
+	for _, ls := range s.v6LeaseStores {
+		minInterval = min(minInterval, ls.getLeaseTime())
+	}
 	// Run reaper at least at 1/2 of min lease time, or max 1 minute, min 1 second
 	reaperInterval := minInterval / 2
 	if reaperInterval > 1*time.Minute {
@@ -271,6 +323,14 @@ func (s *Service) Reload(cfg *config.Config) (bool, error) {
 	s.stopReaper = make(chan struct{})
 	go s.runExpirationReaper(s.stopReaper, reaperInterval)
 
+	// Follow leases written by the failover peer
+	if fo != nil && s.store != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		s.stopSync = cancel
+		go s.watchPeerLeases(ctx, s.leaseStores, s.v6LeaseStores)
+		logging.WithComponent("dhcp").Info("DHCP failover enabled", "mode", fo.mode, "split", fo.split, "mclt", fo.mclt)
+	}
+
 	s.running = true
 
 	return true, nil
@@ -299,6 +359,7 @@ func (s *Service) runExpirationReaper(stop <-chan struct{}, interval time.Durati
 func (s *Service) expireLeases() {
 	s.mu.RLock()
 	stores := s.leaseStores
+	v6Stores := s.v6LeaseStores
 	dnsUpdater := s.dnsUpdater
 	listener := s.leaseListener
 	s.mu.RUnlock()
@@ -314,6 +375,9 @@ func (s *Service) expireLeases() {
 	for _, store := range stores {
 		totalExpired += store.ExpireLeases(dnsUpdater, expListener)
 	}
+	for _, store := range v6Stores {
+		totalExpired += store.ExpireLeases(dnsUpdater, expListener)
+	}
 
 	if totalExpired > 0 {
 		logging.WithComponent("dhcp").Info("Expired leases", "count", totalExpired)
@@ -354,6 +418,7 @@ type LeaseStore struct {
 	RangeEnd     net.IP
 	Subnet       *net.IPNet        // Interface subnet for validation
 	bucket       *state.DHCPBucket // Persistent storage
+	failover     *failover         // HA pool sharing (nil when standalone)
 
 	// Expiration support
 	clock       clock.Clock          // Injectable clock for testing
@@ -392,6 +457,7 @@ func (s *LeaseStore) Allocate(mac string) (net.IP, error) {
 	}
 
 	// 3. Allocate new dynamic IP (Naive linear scan)
+	now := s.getNow()
 	for ip := s.RangeStart; !ipMatches(ip, s.RangeEnd); ip = incIP(ip) {
 		ipStr := ip.String()
 
@@ -400,13 +466,18 @@ func (s *LeaseStore) Allocate(mac string) (net.IP, error) {
 			continue
 		}
 
+		// Skip addresses in the failover peer's share of the pool
+		if !s.failover.owns(ip, s.RangeStart, s.RangeEnd, now) {
+			continue
+		}
+
 		// Skip if this IP is reserved for another MAC
 		if _, reserved := s.ReservedIPs[ipStr]; reserved {
 			continue
 		}
 
 		// Skip if currently leased
-		if !s.isTaken(ip) {
+		if !s.isTaken(ip) && !s.heldInStore(ip, mac) {
 			newIP := make(net.IP, len(ip))
 			copy(newIP, ip)
 
@@ -425,8 +496,8 @@ func (s *LeaseStore) Allocate(mac string) (net.IP, error) {
 	}
 
 	// Check the last one (RangeEnd)
-	if s.Subnet == nil || s.Subnet.Contains(s.RangeEnd) {
-		if _, reserved := s.ReservedIPs[s.RangeEnd.String()]; !reserved && !s.isTaken(s.RangeEnd) {
+	if (s.Subnet == nil || s.Subnet.Contains(s.RangeEnd)) && s.failover.owns(s.RangeEnd, s.RangeStart, s.RangeEnd, now) {
+		if _, reserved := s.ReservedIPs[s.RangeEnd.String()]; !reserved && !s.isTaken(s.RangeEnd) && !s.heldInStore(s.RangeEnd, mac) {
 			newIP := make(net.IP, len(s.RangeEnd))
 			copy(newIP, s.RangeEnd)
 
@@ -480,6 +551,11 @@ func (s *LeaseStore) persistLease(mac string, ip net.IP, hostname string) error
 		LeaseEnd:   clock.Now().Add(s.getLeaseTime()),
 	}
 
+	// Replicas hand the write to the primary; it comes back via replication
+	if forwarded, err := s.failover.forward(lease); forwarded {
+		return err
+	}
+
 	// Synchronous write to ensure persistence
 	if err := s.bucket.Set(lease); err != nil {
 		return errors.Wrap(err, errors.KindInternal, "failed to persist lease to state store")
@@ -496,13 +572,14 @@ func (s *LeaseStore) getNow() time.Time {
 	return clock.Now()
 }
 
-// getLeaseTime returns the configured lease time or default 24 hours
+// getLeaseTime returns the configured lease time or default 24 hours,
+// capped at the failover MCLT while the peer is unreachable
 func (s *LeaseStore) getLeaseTime() time.Duration {
 	if s.leaseTime > 0 {
-		return s.leaseTime
+		return s.failover.leaseTime(s.leaseTime)
 	}
 	logging.WithComponent("dhcp").Debug("getLeaseTime returning default 24h", "lease_time", s.leaseTime)
-	return 24 * time.Hour
+	return s.failover.leaseTime(24 * time.Hour)
 }
 
 // SetHostname associates a hostname with a MAC for expiration callbacks
@@ -568,7 +645,7 @@ func (s *LeaseStore) ExpireLeases(dnsUpdater DNSUpdater, listener ExpirationList
 			// under I/O pressure), skip the in-memory cleanup and let the reaper
 			// retry on the next tick. This prevents state divergence where the
 			// allocator thinks an IP is free but the DB still has the lease.
-			if s.bucket != nil {
+			if s.bucket != nil && s.failover.ownsStore() {
 				if err := s.bucket.Delete(mac); err != nil {
 					logging.WithComponent("dhcp").WithError(err).Warn("Failed to delete expired lease from store, will retry", "mac", mac)
 					continue
@@ -635,6 +712,11 @@ func (s *Service) createServer(scope config.DHCPScope, vendorClasses []config.DH
 			}
 		}
 
+		// Hot-standby backups stay quiet until they take over
+		if !ls.failover.answers() {
+			return
+		}
+
 		switch m.MessageType() {
 		case dhcpv4.MessageTypeDiscover:
 			offer, err := handleDiscover(m, ls, scope, routerIP, vendorClasses)
@@ -973,12 +1055,14 @@ type Lease struct {
 	IP         net.IP
 	Hostname   string
 	Expiration time.Time
+	DUID       string // DHCPv6 leases only
 }
 
 // GetLeases returns all active leases across all scopes
 func (s *Service) GetLeases() []Lease {
 	s.mu.RLock()
 	stores := s.leaseStores
+	v6Stores := s.v6LeaseStores
 	s.mu.RUnlock()
 
 	var leases []Lease
@@ -1004,6 +1088,20 @@ func (s *Service) GetLeases() []Lease {
 		}
 		store.Unlock()
 	}
+
+	for _, store := range v6Stores {
+		store.Lock()
+		for duid, addr := range store.Leases {
+			leases = append(leases, Lease{
+				MAC:        store.macs[duid],
+				IP:         addr.AsSlice(),
+				Hostname:   store.hostnames[duid],
+				Expiration: store.leaseExpiry[duid],
+				DUID:       duid,
+			})
+		}
+		store.Unlock()
+	}
 	return leases
 }
 
@@ -0,0 +1,221 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package dns
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/miekg/dns"
+	"grimm.is/flywall/internal/clock"
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/services/nat64"
+)
+
+// dns64MaxTTL caps synthesized records when the negative AAAA answer
+// carried no SOA (RFC 6147 section 5.1.7).
+const dns64MaxTTL = 600
+
+// mappedPrefix holds IPv4-mapped addresses, which never count as a
+// name's IPv6 addresses (RFC 6147 section 5.1.4).
+var mappedPrefix = netip.MustParsePrefix("::ffff:0:0/96")
+
+// dns64Scope is a serve block with DNS64 enabled: the client networks of
+// its zone and the prefix to synthesize addresses in.
+type dns64Scope struct {
+	prefix netip.Prefix
+	any    bool // Wildcard zone: every client
+	nets   []netip.Prefix
+}
+
+// dns64Nets returns the client networks of a zone: the subnets of its
+// interfaces and the networks it matches by source.
+func dns64Nets(cfg *config.Config, zoneName string) []netip.Prefix {
+	var cidrs []string
+	for _, z := range cfg.Zones {
+		if !strings.EqualFold(z.Name, zoneName) {
+			continue
+		}
+		cidrs = append(cidrs, z.Networks...)
+		cidrs = append(cidrs, z.Src)
+		cidrs = append(cidrs, z.IPv4...)
+		cidrs = append(cidrs, z.IPv6...)
+		for _, m := range z.Matches {
+			cidrs = append(cidrs, m.Src)
+		}
+		for _, iface := range cfg.Interfaces {
+			member := strings.EqualFold(iface.Zone, zoneName) || (z.Interface != "" && z.Interface == iface.Name)
+			for _, m := range z.Matches {
+				member = member || m.Interface == iface.Name
+			}
+			if member {
+				cidrs = append(cidrs, iface.IPv4...)
+				cidrs = append(cidrs, iface.IPv6...)
+			}
+		}
+	}
+
+	var nets []netip.Prefix
+	for _, c := range cidrs {
+		if p, err := netip.ParsePrefix(c); err == nil {
+			nets = append(nets, p.Masked())
+		} else if a, err := netip.ParseAddr(c); err == nil {
+			nets = append(nets, netip.PrefixFrom(a, a.BitLen()))
+		}
+	}
+	return nets
+}
+
+// dns64Prefix returns the DNS64 prefix for a client, if its zone has
+// DNS64 enabled.
+func (s *Service) dns64Prefix(clientIP string) (netip.Prefix, bool) {
+	addr, err := netip.ParseAddr(clientIP)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, scope := range s.dns64 {
+		if scope.any {
+			return scope.prefix, true
+		}
+		for _, n := range scope.nets {
+			if n.Contains(addr) {
+				return scope.prefix, true
+			}
+		}
+	}
+	return netip.Prefix{}, false
+}
+
+// synthesizeDNS64 implements RFC 6147 for a client whose zone uses DNS64:
+// when a AAAA query yields no IPv6 addresses, the name's A records are
+// returned as AAAA records in the NAT64 prefix. Other answers are returned
+// unchanged, as are queries whose client validates DNSSEC itself.
+func (s *Service) synthesizeDNS64(clientIP string, r, resp *dns.Msg) *dns.Msg {
+	q := r.Question[0]
+	if q.Qtype != dns.TypeAAAA || q.Qclass != dns.ClassINET || resp.Rcode == dns.RcodeNameError {
+		return resp
+	}
+	if opt := r.IsEdns0(); r.CheckingDisabled && opt != nil && opt.Do() {
+		return resp
+	}
+	prefix, ok := s.dns64Prefix(clientIP)
+	if !ok {
+		return resp
+	}
+	if resp.Rcode == dns.RcodeSuccess {
+		for _, rr := range resp.Answer {
+			if aaaa, ok := rr.(*dns.AAAA); ok {
+				if addr, ok := netip.AddrFromSlice(aaaa.AAAA); ok && !mappedPrefix.Contains(addr) {
+					return resp
+				}
+			}
+		}
+	}
+
+	aResp := s.resolveA(r)
+	if aResp == nil || aResp.Rcode != dns.RcodeSuccess {
+		return resp
+	}
+
+	maxTTL := uint32(dns64MaxTTL)
+	for _, rr := range resp.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			maxTTL = min(soa.Minttl, soa.Hdr.Ttl)
+		}
+	}
+
+	var answer []dns.RR
+	synthesized := 0
+	for _, rr := range aResp.Answer {
+		switch v := rr.(type) {
+		case *dns.CNAME:
+			answer = append(answer, dns.Copy(v))
+		case *dns.A:
+			v4, ok := netip.AddrFromSlice(v.A.To4())
+			if !ok || !nat64.CanEmbed(prefix, v4) {
+				continue
+			}
+			hdr := v.Hdr
+			hdr.Rrtype = dns.TypeAAAA
+			hdr.Ttl = min(hdr.Ttl, maxTTL)
+			answer = append(answer, &dns.AAAA{Hdr: hdr, AAAA: net.IP(nat64.Embed(prefix, v4).AsSlice())})
+			synthesized++
+		}
+	}
+	if synthesized == 0 {
+		return resp
+	}
+
+	out := resp.Copy()
+	out.Rcode = dns.RcodeSuccess
+	out.AuthenticatedData = false
+	out.Answer = answer
+	out.Ns = nil
+	out.Extra = nil
+	if opt := resp.IsEdns0(); opt != nil {
+		out.Extra = []dns.RR{opt}
+	}
+	s.snoopResponse(out)
+	return out
+}
+
+// resolveA looks up the A records for the name of a AAAA query, from the
+// local records, the cache or the upstreams that would answer it.
+func (s *Service) resolveA(r *dns.Msg) *dns.Msg {
+	req := r.Copy()
+	req.Id = dns.Id()
+	req.Question[0].Qtype = dns.TypeA
+	name := strings.ToLower(req.Question[0].Name)
+
+	s.mu.RLock()
+	rec, local := s.records[name]
+	s.mu.RUnlock()
+	if local {
+		if rr := s.createRR(req.Question[0], rec); rr != nil {
+			msg := new(dns.Msg)
+			msg.SetReply(req)
+			msg.Answer = []dns.RR{rr}
+			return msg
+		}
+	}
+
+	cacheKey := fmt.Sprintf("%s:%d", name, dns.TypeA)
+	shard := s.getShard(cacheKey)
+	shard.mu.RLock()
+	cached, found := shard.items[cacheKey]
+	shard.mu.RUnlock()
+	if found && clock.Now().Before(cached.expiresAt) {
+		return cached.msg.Copy()
+	}
+
+	resp, _ := s.forward(req, s.upstreamsFor(name))
+	return resp
+}
+
+// upstreamsFor returns the upstreams a name is forwarded to: those of the
+// matching conditional forwarder, else the static and dynamic upstreams.
+func (s *Service) upstreamsFor(name string) []upstream {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, cf := range s.config.ConditionalForwarders {
+		if strings.HasSuffix(name, strings.ToLower(dns.Fqdn(cf.Domain))) {
+			var ups []upstream
+			for _, srv := range cf.Servers {
+				ups = append(ups, upstream{Addr: srv, Protocol: "udp"})
+			}
+			return ups
+		}
+	}
+
+	var ups []upstream
+	ups = append(ups, s.upstreams...)
+	ups = append(ups, s.dynamicUpstreams...)
+	return ups
+}
@@ -0,0 +1,127 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package dns
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"grimm.is/flywall/internal/config"
+
+	"github.com/miekg/dns"
+)
+
+func cacheMsg(s *Service, name string, qtype uint16, rcode int, answer, ns []dns.RR) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, qtype)
+	msg.Response = true
+	msg.Rcode = rcode
+	msg.Answer = answer
+	msg.Ns = ns
+
+	key := fmt.Sprintf("%s:%d", name, qtype)
+	shard := s.getShard(key)
+	shard.mu.Lock()
+	shard.items[key] = cachedResponse{msg: msg, expiresAt: time.Now().Add(time.Hour)}
+	shard.mu.Unlock()
+}
+
+func TestBuildServerState_DNS64(t *testing.T) {
+	s, _ := newTestService(&config.DNSServer{})
+	cfg := &config.Config{
+		Interfaces: []config.Interface{
+			{Name: "eth1", Zone: "iot6", IPv6: []string{"2001:db8:1::1/64"}},
+			{Name: "eth2", Zone: "lan", IPv4: []string{"192.168.1.1/24"}},
+		},
+		Zones: []config.Zone{{Name: "iot6"}, {Name: "lan"}},
+		DNS: &config.DNS{Serve: []config.DNSServe{
+			{Zone: "iot6", DNS64: true},
+			{Zone: "lan", DNS64: true, DNS64Prefix: "2001:db8:64::/96"},
+		}},
+	}
+
+	state := s.buildServerState(cfg)
+	if len(state.dns64) != 2 {
+		t.Fatalf("got %d DNS64 scopes; want 2", len(state.dns64))
+	}
+	if got := state.dns64[0]; got.prefix.String() != "64:ff9b::/96" || len(got.nets) != 1 || got.nets[0].String() != "2001:db8:1::/64" {
+		t.Errorf("iot6 scope = %+v", got)
+	}
+	if got := state.dns64[1]; got.prefix.String() != "2001:db8:64::/96" || len(got.nets) != 1 || got.nets[0].String() != "192.168.1.0/24" {
+		t.Errorf("lan scope = %+v", got)
+	}
+}
+
+func TestServeDNS_DNS64(t *testing.T) {
+	s, _ := newTestService(&config.DNSServer{Enabled: true})
+	// MockResponseWriter queries from 192.168.1.100
+	s.dns64 = []dns64Scope{
+		{prefix: netip.MustParsePrefix("64:ff9b::/96"), nets: []netip.Prefix{netip.MustParsePrefix("192.168.1.0/24")}},
+	}
+
+	soa := &dns.SOA{Hdr: dns.RR_Header{Name: "test.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 900}, Ns: "ns.test.", Mbox: "admin.test.", Minttl: 60}
+	a := func(name, ip string) dns.RR {
+		return &dns.A{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300}, A: net.ParseIP(ip)}
+	}
+
+	cacheMsg(s, "v4only.test.", dns.TypeAAAA, dns.RcodeSuccess, nil, []dns.RR{soa})
+	cacheMsg(s, "v4only.test.", dns.TypeA, dns.RcodeSuccess, []dns.RR{
+		&dns.CNAME{Hdr: dns.RR_Header{Name: "v4only.test.", Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 300}, Target: "www.v4only.test."},
+		a("www.v4only.test.", "198.51.100.7"),
+	}, nil)
+
+	cacheMsg(s, "dual.test.", dns.TypeAAAA, dns.RcodeSuccess, []dns.RR{
+		&dns.AAAA{Hdr: dns.RR_Header{Name: "dual.test.", Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: 300}, AAAA: net.ParseIP("2001:db8::7")},
+	}, nil)
+	cacheMsg(s, "dual.test.", dns.TypeA, dns.RcodeSuccess, []dns.RR{a("dual.test.", "198.51.100.8")}, nil)
+
+	cacheMsg(s, "private.test.", dns.TypeAAAA, dns.RcodeSuccess, nil, []dns.RR{soa})
+	cacheMsg(s, "private.test.", dns.TypeA, dns.RcodeSuccess, []dns.RR{a("private.test.", "10.0.0.1")}, nil)
+
+	s.records["printer.lan."] = config.DNSRecord{Name: "printer.lan", Type: "A", Value: "192.0.2.5", TTL: 3600}
+
+	query := func(name string, qtype uint16) *dns.Msg {
+		req := new(dns.Msg)
+		req.SetQuestion(name, qtype)
+		w := &MockResponseWriter{}
+		s.ServeDNS(w, req)
+		if w.msg == nil {
+			t.Fatalf("%s: no response written", name)
+		}
+		return w.msg
+	}
+
+	resp := query("v4only.test.", dns.TypeAAAA)
+	if len(resp.Answer) != 2 {
+		t.Fatalf("v4only.test. answer = %v; want a CNAME and a synthesized AAAA", resp.Answer)
+	}
+	aaaa, ok := resp.Answer[1].(*dns.AAAA)
+	if !ok || aaaa.Hdr.Name != "www.v4only.test." || aaaa.AAAA.String() != "64:ff9b::c633:6407" {
+		t.Errorf("synthesized %v; want www.v4only.test. AAAA 64:ff9b::c633:6407", resp.Answer[1])
+	}
+	if aaaa != nil && aaaa.Hdr.Ttl != 60 {
+		t.Errorf("synthesized TTL = %d; want the SOA minimum 60", aaaa.Hdr.Ttl)
+	}
+
+	if resp := query("printer.lan.", dns.TypeAAAA); len(resp.Answer) != 1 || resp.Answer[0].(*dns.AAAA).AAAA.String() != "64:ff9b::c000:205" {
+		t.Errorf("printer.lan. answer = %v; want a AAAA synthesized from the local record", resp.Answer)
+	}
+
+	if resp := query("dual.test.", dns.TypeAAAA); len(resp.Answer) != 1 || resp.Answer[0].(*dns.AAAA).AAAA.String() != "2001:db8::7" {
+		t.Errorf("dual.test. answer = %v; want the real AAAA", resp.Answer)
+	}
+	if resp := query("private.test.", dns.TypeAAAA); len(resp.Answer) != 0 {
+		t.Errorf("private.test. answer = %v; the well-known prefix must not embed private addresses", resp.Answer)
+	}
+	if resp := query("v4only.test.", dns.TypeA); len(resp.Answer) != 2 {
+		t.Errorf("A query answer = %v; want the cached A answer", resp.Answer)
+	}
+
+	s.dns64[0].nets = []netip.Prefix{netip.MustParsePrefix("2001:db8:1::/64")}
+	if resp := query("v4only.test.", dns.TypeAAAA); len(resp.Answer) != 0 {
+		t.Errorf("client outside the DNS64 zone got %v", resp.Answer)
+	}
+}
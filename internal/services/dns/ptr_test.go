@@ -57,6 +57,10 @@ func TestService_PTR_IPv6(t *testing.T) {
 
 	fqdn := "ipv6-host."
 	// Just check if we have an ip6.arpa record pointing to fqdn
+	if rec, ok := s.records6["ipv6-host."]; !ok || rec.Type != "AAAA" {
+		t.Errorf("AAAA record not created for %s", ip)
+	}
+
 	found := false
 	for name, rec := range s.records {
 		if strings.HasSuffix(name, "ip6.arpa.") && rec.Value == fqdn {
@@ -70,6 +74,38 @@ func TestService_PTR_IPv6(t *testing.T) {
 	}
 }
 
+func TestService_DualStackRecords(t *testing.T) {
+	s := &Service{
+		records: make(map[string]config.DNSRecord),
+	}
+	v4 := net.ParseIP("192.168.1.50")
+	v6 := net.ParseIP("2001:db8::50")
+
+	s.AddRecord("laptop.lan", v4)
+	s.AddRecord("laptop.lan", v6)
+
+	if rec := s.records["laptop.lan."]; rec.Type != "A" || rec.Value != v4.String() {
+		t.Errorf("A record overwritten by AAAA: %+v", rec)
+	}
+	if rec := s.records6["laptop.lan."]; rec.Type != "AAAA" || rec.Value != v6.String() {
+		t.Errorf("AAAA record missing: %+v", rec)
+	}
+
+	// DHCPv6 lease expiry only drops the AAAA and its PTR
+	s.RemoveAddress("laptop.lan", v6)
+	if _, ok := s.records6["laptop.lan."]; ok {
+		t.Error("AAAA record still exists after RemoveAddress")
+	}
+	if _, ok := s.records["laptop.lan."]; !ok {
+		t.Error("A record removed by RemoveAddress for IPv6")
+	}
+	for name := range s.records {
+		if strings.HasSuffix(name, "ip6.arpa.") {
+			t.Errorf("IPv6 PTR %s still exists", name)
+		}
+	}
+}
+
 func TestService_PTR_Overwrite(t *testing.T) {
 	s := &Service{
 		records: make(map[string]config.DNSRecord),
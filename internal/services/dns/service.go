@@ -44,7 +44,9 @@ type Service struct {
 	upstreams        []upstream                  // Unified list of upstreams (UDP, DoT, DoH)
 	dynamicUpstreams []upstream                  // From DHCP/etc
 	records          map[string]config.DNSRecord // FQDN -> Record
+	records6         map[string]config.DNSRecord // FQDN -> dynamic AAAA (DHCPv6), kept apart from A records
 	blockedDomains   map[string]bool             // Blocked domains
+	dns64            []dns64Scope                // Serve blocks that synthesize AAAA records
 
 	// Sharded Cache
 	shards [256]*cacheShard
@@ -246,6 +248,7 @@ func (s *Service) Reload(cfg *config.Config) (bool, error) {
 		s.upstreams = newUpstreams
 		s.records = state.records
 		s.blockedDomains = state.blockedDomains
+		s.dns64 = state.dns64
 		s.servers = newServers
 		s.mu.Unlock()
 
@@ -411,6 +414,7 @@ func (s *Service) Reload(cfg *config.Config) (bool, error) {
 		s.upstreams = newUpstreams
 		s.records = newRecords
 		s.blockedDomains = newBlocked
+		s.dns64 = nil
 		s.servers = newServers
 		s.mu.Unlock()
 
@@ -422,6 +426,7 @@ func (s *Service) Reload(cfg *config.Config) (bool, error) {
 		s.upstreams = newUpstreams
 		s.records = newRecords
 		s.blockedDomains = newBlocked
+		s.dns64 = nil
 		s.mu.Unlock()
 		logging.Info("[DNS] Hot-reloaded configuration (no restart)")
 		return true, nil
@@ -625,6 +630,8 @@ func loadBlocklistsFromConfig(blocklists []config.DNSBlocklist) (map[string]bool
 }
 
 // AddRecord adds or updates a dynamic DNS record.
+// IPv6 addresses are stored separately so a dual-stack host keeps both
+// its DHCPv4 A record and its DHCPv6 AAAA record.
 func (s *Service) AddRecord(name string, ip net.IP) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -639,10 +646,15 @@ func (s *Service) AddRecord(name string, ip net.IP) {
 	}
 	if ip.To4() == nil {
 		rec.Type = "AAAA"
+		if s.records6 == nil {
+			s.records6 = make(map[string]config.DNSRecord)
+		}
+		s.removePTR(s.records6[strings.ToLower(fqdn)])
+		s.records6[strings.ToLower(fqdn)] = rec
+	} else {
+		s.records[strings.ToLower(fqdn)] = rec
 	}
 
-	s.records[strings.ToLower(fqdn)] = rec
-
 	// Automatic PTR record
 	ptrZone, err := dns.ReverseAddr(ip.String())
 	if err == nil {
@@ -668,17 +680,50 @@ func (s *Service) RemoveRecord(name string) {
 
 	// If it's an A record, try to find and remove associated PTR
 	if rec, ok := s.records[lowerName]; ok && (rec.Type == "A" || rec.Type == "AAAA") {
-		ptrZone, err := dns.ReverseAddr(rec.Value)
-		if err == nil {
-			delete(s.records, strings.ToLower(ptrZone))
-			logging.Debug("[DNS] Removed dynamic PTR record: %s", ptrZone)
-		}
+		s.removePTR(rec)
+	}
+	if rec, ok := s.records6[lowerName]; ok {
+		s.removePTR(rec)
+		delete(s.records6, lowerName)
 	}
 
 	delete(s.records, lowerName)
 	logging.Debug("[DNS] Removed dynamic record: %s", fqdn)
 }
 
+// RemoveAddress removes the dynamic record for name only if it still
+// points at ip, leaving the host's record for the other address family.
+func (s *Service) RemoveAddress(name string, ip net.IP) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lowerName := strings.ToLower(dns.Fqdn(name))
+	records := s.records
+	if ip.To4() == nil {
+		records = s.records6
+	}
+	rec, ok := records[lowerName]
+	if !ok || !ip.Equal(net.ParseIP(rec.Value)) {
+		return
+	}
+	s.removePTR(rec)
+	delete(records, lowerName)
+	logging.Debug("[DNS] Removed dynamic record: %s -> %s", lowerName, ip)
+}
+
+// removePTR drops the automatic PTR record for an address record.
+// Caller must hold s.mu.
+func (s *Service) removePTR(rec config.DNSRecord) {
+	if rec.Value == "" {
+		return
+	}
+	ptrZone, err := dns.ReverseAddr(rec.Value)
+	if err == nil {
+		delete(s.records, strings.ToLower(ptrZone))
+		logging.Debug("[DNS] Removed dynamic PTR record: %s", ptrZone)
+	}
+}
+
 // UpdateBlockedDomains updates the set of blocked domains dynamically
 func (s *Service) UpdateBlockedDomains(domains []string) {
 	s.mu.Lock()
@@ -875,6 +920,7 @@ func (s *Service) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 		fmt.Fprintf(os.Stderr, "DEBUG: Cache hit\n")
 		resp = cached.msg.Copy()
 		resp.SetReply(r)
+		resp = s.synthesizeDNS64(clientIP, r, resp)
 		rcode = resp.Rcode
 		w.WriteMsg(resp)
 		return
@@ -885,6 +931,11 @@ func (s *Service) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 	s.mu.RLock()
 	fmt.Fprintf(os.Stderr, "DEBUG: Check local\n")
 	rec, ok := s.records[name]
+	if q.Qtype == dns.TypeAAAA {
+		if rec6, ok6 := s.records6[name]; ok6 {
+			rec, ok = rec6, true
+		}
+	}
 	s.mu.RUnlock()
 
 	if ok {
@@ -894,6 +945,11 @@ func (s *Service) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 			w.WriteMsg(msg)
 			return
 		}
+		// A name with only an A record still gets a synthesized AAAA
+		if resp = s.synthesizeDNS64(clientIP, r, msg); resp != msg {
+			w.WriteMsg(resp)
+			return
+		}
 	}
 
 	// Conditional Forwarding
@@ -909,6 +965,7 @@ func (s *Service) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 
 			resp, upstreamAddr = s.forward(r, cfUpstreams)
 			if resp != nil {
+				resp = s.synthesizeDNS64(clientIP, r, resp)
 				rcode = resp.Rcode
 				w.WriteMsg(resp)
 			} else {
@@ -936,6 +993,7 @@ func (s *Service) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 		fmt.Fprintf(os.Stderr, "DEBUG: Forwarding to upstreams\n")
 		resp, upstreamAddr = s.forward(r, allUpstreams)
 		if resp != nil {
+			resp = s.synthesizeDNS64(clientIP, r, resp)
 			rcode = resp.Rcode
 			w.WriteMsg(resp)
 		} else {
@@ -16,6 +16,7 @@ type serverState struct {
 	records        map[string]config.DNSRecord
 	blockedDomains map[string]bool
 	forwarders     []string
+	dns64          []dns64Scope
 }
 
 // buildServerState constructs the DNS server state from the new config format.
@@ -83,6 +84,18 @@ func (s *Service) buildServerState(cfg *config.Config) *serverState {
 			}
 		}
 
+		// DNS64
+		if serve.DNS64 {
+			prefix, err := config.ParseNAT64Prefix(cfg.DNS64Prefix(&serve))
+			if err != nil {
+				logging.Warn("[DNS] Warning: serve zone %q has an invalid DNS64 prefix: %v", serve.Zone, err)
+			} else if zoneName == "*" || zoneName == "any" {
+				state.dns64 = append(state.dns64, dns64Scope{prefix: prefix, any: true})
+			} else {
+				state.dns64 = append(state.dns64, dns64Scope{prefix: prefix, nets: dns64Nets(cfg, zoneName)})
+			}
+		}
+
 		// Hosts
 		for _, host := range serve.Hosts {
 			for _, hostname := range host.Hostnames {
@@ -0,0 +1,243 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+//go:build linux
+// +build linux
+
+package ha
+
+import (
+	"net/netip"
+	"sort"
+	"time"
+
+	"grimm.is/flywall/internal/clock"
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/netutil"
+)
+
+// resource is a virtual IP or MAC that moves between active/active nodes.
+type resource struct {
+	key   string
+	iface string
+	zone  string
+	owner string // Configured owner: virtual IP owner, then zone owner
+	vip   *config.VirtualIP
+	vmac  *config.VirtualMAC
+}
+
+// resources lists the virtual IPs and MACs with their configured owners.
+// Virtual MACs follow the owner of their interface's zone.
+func (s *Service) resources() []resource {
+	zoneOf := func(iface string) string {
+		if s.zoneOf == nil {
+			return ""
+		}
+		return s.zoneOf(iface)
+	}
+
+	var res []resource
+	for i := range s.haConf.VirtualIPs {
+		vip := &s.haConf.VirtualIPs[i]
+		zone := zoneOf(vip.Interface)
+		owner := vip.Owner
+		if owner == "" {
+			owner = s.haConf.ZoneOwners[zone]
+		}
+		res = append(res, resource{key: vip.Address, iface: vip.Interface, zone: zone, owner: owner, vip: vip})
+	}
+	for i := range s.haConf.VirtualMACs {
+		vmac := &s.haConf.VirtualMACs[i]
+		zone := zoneOf(vmac.Interface)
+		res = append(res, resource{key: vmacKey(*vmac), iface: vmac.Interface, zone: zone, owner: s.haConf.ZoneOwners[zone], vmac: vmac})
+	}
+	return res
+}
+
+// ownerOf returns the node a resource belongs to while both nodes are up,
+// or "" if it is not known yet. It is called with s.mu held.
+func (s *Service) ownerOf(r resource) string {
+	if s.peer.NodeID == "" {
+		return r.owner
+	}
+	return preferredOwner(r.owner,
+		nodeRef{s.nodeID, s.haConf.Priority},
+		nodeRef{s.peer.NodeID, s.peer.Priority})
+}
+
+// ownershipPlan decides which resources to take and which to give back.
+// It is called with s.mu held.
+func (s *Service) ownershipPlan(now time.Time) (take, give []resource) {
+	peerHeld := make(map[string]bool)
+	for _, k := range s.peer.Held {
+		peerHeld[k] = true
+	}
+
+	for _, r := range s.resources() {
+		var mine bool
+		switch {
+		case s.peerDown:
+			mine = true
+		case s.peer.NodeID == "":
+			// Not heard from the peer yet: it may hold everything after
+			// taking over from us, so wait for its heartbeat
+			continue
+		default:
+			mine = s.ownerOf(r) == s.nodeID
+		}
+
+		held := s.held[r.key]
+		switch {
+		case mine && !held:
+			// The peer gives it back first, so it is never on both nodes
+			if !s.peerDown && peerHeld[r.key] {
+				continue
+			}
+			take = append(take, r)
+		case !mine && held:
+			if s.failbackHold || now.Before(s.failbackAt) {
+				continue
+			}
+			give = append(give, r)
+		}
+	}
+	return take, give
+}
+
+// reconcileOwnership takes and gives back virtual IPs and MACs so this
+// node holds what it owns in active/active mode.
+func (s *Service) reconcileOwnership() {
+	s.ownerMu.Lock()
+	defer s.ownerMu.Unlock()
+
+	s.mu.RLock()
+	take, give := s.ownershipPlan(clock.Now())
+	s.mu.RUnlock()
+	if len(take) == 0 && len(give) == 0 {
+		return
+	}
+
+	keys := func(rs []resource) []string {
+		var k []string
+		for _, r := range rs {
+			k = append(k, r.key)
+		}
+		return k
+	}
+	s.logger.Info("Rebalancing active/active ownership", "take", keys(take), "give", keys(give))
+
+	for _, r := range give {
+		var err error
+		if r.vip != nil {
+			err = s.removeVirtualIP(*r.vip)
+		} else if origMAC, ok := s.origMACs[r.iface]; ok {
+			s.logger.Info("Restoring original MAC", "interface", r.iface, "mac", netutil.FormatMAC(origMAC))
+			err = s.linkMgr.SetHardwareAddr(r.iface, origMAC)
+		}
+		if err != nil {
+			s.logger.Error("Failed to give back resource", "resource", r.key, "error", err)
+			continue
+		}
+		s.setHeld(r.key, false)
+	}
+
+	for _, r := range take {
+		var err error
+		if r.vip != nil {
+			if err = s.applyVirtualIP(*r.vip); err == nil {
+				if addr, perr := parseAddr(r.vip.Address); perr == nil {
+					if ip, ok := netip.AddrFromSlice(addr.IP); ok {
+						if aerr := announceAddress(r.iface, ip.Unmap()); aerr != nil {
+							s.logger.Warn("Failed to announce virtual IP", "address", r.key, "error", aerr)
+						}
+					}
+				}
+			}
+		} else {
+			err = s.applyVirtualMAC(*r.vmac)
+		}
+		if err != nil {
+			s.logger.Error("Failed to take resource", "resource", r.key, "error", err)
+			continue
+		}
+		s.setHeld(r.key, true)
+	}
+
+	// Tell the peer right away so it can take what we gave back
+	select {
+	case s.heartbeatNow <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Service) setHeld(key string, held bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if held {
+		s.held[key] = true
+	} else {
+		delete(s.held, key)
+	}
+}
+
+// heldKeys lists the resources this node holds. It is called with s.mu held.
+func (s *Service) heldKeys() []string {
+	keys := make([]string, 0, len(s.held))
+	for k := range s.held {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// peerRecovered applies failback_mode when a peer declared down is heard
+// from again. It is called with s.mu held.
+func (s *Service) peerRecovered(now time.Time) {
+	s.peerDown = false
+	switch s.haConf.FailbackMode {
+	case "manual", "never":
+		s.failbackHold = true
+		s.logger.Info("Peer is back, keeping its virtual IPs until failover is triggered",
+			"peer", s.peer.NodeID, "failback_mode", s.haConf.FailbackMode)
+	default:
+		delay := time.Duration(s.haConf.FailbackDelay) * time.Second
+		s.failbackAt = now.Add(delay)
+		s.logger.Info("Peer is back, giving back its virtual IPs after failback delay",
+			"peer", s.peer.NodeID, "delay", delay)
+	}
+}
+
+// Ownership lists the virtual IPs with their owner and current holder in
+// active/active mode.
+func (s *Service) Ownership() []Ownership {
+	if s.haConf.Mode != ModeActiveActive {
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	peerHeld := make(map[string]bool)
+	for _, k := range s.peer.Held {
+		peerHeld[k] = true
+	}
+
+	var out []Ownership
+	for _, r := range s.resources() {
+		if r.vip == nil {
+			continue
+		}
+		o := Ownership{
+			Address:   r.key,
+			Interface: r.iface,
+			Zone:      r.zone,
+			Owner:     s.ownerOf(r),
+		}
+		if s.held[r.key] {
+			o.Holder = s.nodeID
+		} else if peerHeld[r.key] {
+			o.Holder = s.peer.NodeID
+		}
+		out = append(out, o)
+	}
+	return out
+}
@@ -0,0 +1,186 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+//go:build linux
+// +build linux
+
+package ha
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/logging"
+)
+
+func TestPreferredOwner(t *testing.T) {
+	a := nodeRef{"fw-a", 50}
+	b := nodeRef{"fw-b", 150}
+
+	tests := []struct {
+		name  string
+		owner string
+		self  nodeRef
+		peer  nodeRef
+		want  string
+	}{
+		{"configured self", "fw-a", a, b, "fw-a"},
+		{"configured peer", "fw-b", a, b, "fw-b"},
+		{"unassigned goes to lower priority", "", b, a, "fw-a"},
+		{"unknown owner goes to lower priority", "fw-c", a, b, "fw-a"},
+		{"equal priority uses node ID", "", nodeRef{"fw-b", 100}, nodeRef{"fw-a", 100}, "fw-a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := preferredOwner(tt.owner, tt.self, tt.peer); got != tt.want {
+				t.Errorf("preferredOwner() = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func newActiveActiveService(t *testing.T, failbackMode string) *Service {
+	t.Helper()
+	cfg := &config.ReplicationConfig{
+		Mode: "primary",
+		HA: &config.HAConfig{
+			Enabled:      true,
+			Mode:         ModeActiveActive,
+			Priority:     50,
+			FailbackMode: failbackMode,
+			ZoneOwners:   map[string]string{"dmz": "fw-b"},
+			VirtualIPs: []config.VirtualIP{
+				{Address: "10.0.1.1/24", Interface: "eth1", Owner: "fw-a"},
+				{Address: "10.0.2.1/24", Interface: "eth2"},
+				{Address: "10.0.3.1/24", Interface: "eth3"},
+			},
+		},
+	}
+	svc, err := NewService(cfg, "fw-a", NewMockLinkManager(), logging.WithComponent("ha-test"))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	svc.SetZoneLookup(func(iface string) string {
+		return map[string]string{"eth1": "lan", "eth2": "dmz"}[iface]
+	})
+	return svc
+}
+
+func planKeys(svc *Service, now time.Time) (take, give []string) {
+	t, g := svc.ownershipPlan(now)
+	for _, r := range t {
+		take = append(take, r.key)
+	}
+	for _, r := range g {
+		give = append(give, r.key)
+	}
+	return take, give
+}
+
+func TestOwnershipPlan(t *testing.T) {
+	svc := newActiveActiveService(t, "")
+	if role := svc.GetRole(); role != RoleActive {
+		t.Fatalf("GetRole() = %s; want %s", role, RoleActive)
+	}
+	now := time.Now()
+
+	// Nothing is taken before the peer is heard from or declared down
+	if take, give := planKeys(svc, now); take != nil || give != nil {
+		t.Fatalf("plan before first heartbeat = %v, %v; want nothing", take, give)
+	}
+
+	// The peer holds everything after taking over from us: wait for it
+	svc.handleHeartbeat(&HeartbeatMessage{NodeID: "fw-b", Role: RoleActive, Priority: 150,
+		Held: []string{"10.0.1.1/24", "10.0.2.1/24", "10.0.3.1/24"}})
+	if take, _ := planKeys(svc, now); take != nil {
+		t.Fatalf("take = %v while the peer holds the addresses", take)
+	}
+
+	// Once it gives back our share, take it: the owner, then the lower priority
+	svc.handleHeartbeat(&HeartbeatMessage{NodeID: "fw-b", Role: RoleActive, Priority: 150,
+		Held: []string{"10.0.2.1/24"}})
+	take, _ := planKeys(svc, now)
+	if want := []string{"10.0.1.1/24", "10.0.3.1/24"}; !reflect.DeepEqual(take, want) {
+		t.Fatalf("take = %v; want %v", take, want)
+	}
+	svc.setHeld("10.0.1.1/24", true)
+	svc.setHeld("10.0.3.1/24", true)
+
+	owners := svc.Ownership()
+	if len(owners) != 3 || owners[1].Zone != "dmz" || owners[1].Owner != "fw-b" || owners[1].Holder != "fw-b" ||
+		owners[0].Holder != "fw-a" {
+		t.Errorf("Ownership() = %+v", owners)
+	}
+
+	// The peer dies: take its share
+	svc.mu.Lock()
+	svc.peer.MissedHeartbeats = svc.haConf.FailureThreshold
+	svc.peerDown = true
+	svc.peer.Held = nil
+	svc.mu.Unlock()
+	take, _ = planKeys(svc, now)
+	if want := []string{"10.0.2.1/24"}; !reflect.DeepEqual(take, want) {
+		t.Fatalf("take after peer failure = %v; want %v", take, want)
+	}
+	svc.setHeld("10.0.2.1/24", true)
+
+	// It comes back: give its share back after the failback delay
+	svc.handleHeartbeat(&HeartbeatMessage{NodeID: "fw-b", Role: RoleActive, Priority: 150})
+	if _, give := planKeys(svc, now.Add(time.Second)); give != nil {
+		t.Errorf("give = %v before failback delay", give)
+	}
+	_, give := planKeys(svc, now.Add(time.Duration(DefaultFailbackDelay+1)*time.Second))
+	if want := []string{"10.0.2.1/24"}; !reflect.DeepEqual(give, want) {
+		t.Errorf("give after failback delay = %v; want %v", give, want)
+	}
+}
+
+func TestOwnershipPlan_ManualFailback(t *testing.T) {
+	svc := newActiveActiveService(t, "manual")
+	now := time.Now()
+
+	svc.mu.Lock()
+	svc.peerDown = true
+	svc.mu.Unlock()
+	for _, k := range []string{"10.0.1.1/24", "10.0.2.1/24", "10.0.3.1/24"} {
+		svc.setHeld(k, true)
+	}
+
+	svc.handleHeartbeat(&HeartbeatMessage{NodeID: "fw-b", Role: RoleActive, Priority: 150})
+	if _, give := planKeys(svc, now.Add(time.Hour)); give != nil {
+		t.Errorf("give = %v; want to keep the peer's addresses with failback_mode manual", give)
+	}
+
+	svc.mu.Lock()
+	svc.failbackHold = false
+	svc.mu.Unlock()
+	if _, give := planKeys(svc, now); !reflect.DeepEqual(give, []string{"10.0.2.1/24"}) {
+		t.Errorf("give after releasing the hold = %v", give)
+	}
+}
+
+func TestHeartbeatSignatureCoversHeld(t *testing.T) {
+	cfg := &config.ReplicationConfig{
+		Mode:      "primary",
+		SecretKey: "secret",
+		HA:        &config.HAConfig{Enabled: true, Mode: ModeActiveActive},
+	}
+	svc, err := NewService(cfg, "fw-a", NewMockLinkManager(), logging.WithComponent("ha-test"))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	msg := &HeartbeatMessage{NodeID: "fw-b", Role: RoleActive, Priority: 100, Held: []string{"10.0.2.1/24"},
+		Timestamp: time.Now().UTC()}
+	msg.Signature = svc.signHeartbeat(msg)
+	if !svc.verifyHeartbeat(msg) {
+		t.Fatal("verifyHeartbeat() = false for a valid signature")
+	}
+
+	msg.Held = append(msg.Held, "10.0.1.1/24")
+	if svc.verifyHeartbeat(msg) {
+		t.Error("verifyHeartbeat() = true after changing the held list")
+	}
+}
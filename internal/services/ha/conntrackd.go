@@ -30,6 +30,10 @@ type ConntrackdManager struct {
 	cmd       *exec.Cmd
 	ctx       context.Context
 	cancel    context.CancelFunc
+
+	// activeActive syncs both ways with states injected into the kernel
+	// table as they arrive, so asymmetric return paths survive
+	activeActive bool
 }
 
 // Default conntrackd settings
@@ -46,7 +50,7 @@ const conntrackdConfigTemplate = `#
 
 Sync {
 	Mode FTFW {
-		DisableExternalCache Off
+		DisableExternalCache {{if .ActiveActive}}On{{else}}Off{{end}}
 		CommitTimeout 1800
 		PurgeTimeout 5
 	}
@@ -157,6 +161,7 @@ func (m *ConntrackdManager) GenerateConfig() (string, error) {
 		Port           int
 		Multicast      bool
 		MulticastGroup string
+		ActiveActive   bool
 	}{
 		Interface:      iface,
 		SyncIP:         syncIP,
@@ -164,6 +169,7 @@ func (m *ConntrackdManager) GenerateConfig() (string, error) {
 		Port:           port,
 		Multicast:      useMulticast,
 		MulticastGroup: multicastGroup,
+		ActiveActive:   m.activeActive,
 	}
 
 	tmpl, err := template.New("conntrackd").Parse(conntrackdConfigTemplate)
@@ -31,6 +31,30 @@
 //  4. Transition to primary role
 //  5. Start accepting traffic
 //
+// # Active/Active
+//
+// With mode = "active_active" both nodes forward traffic and each holds the
+// virtual IPs it owns: a virtual IP's owner, else the owner of its zone in
+// zone_owners, else the node with the lower priority value. Heartbeats
+// carry the addresses each node holds, and a node only adds an address
+// once the peer has dropped it. When a peer is declared down the survivor
+// takes all of its addresses, and gives them back according to
+// failback_mode when it returns. conntrackd then injects the peer's
+// connections straight into the kernel table so asymmetric return paths
+// keep working.
+//
+// # VRRP
+//
+// With protocol = "vrrp" the heartbeat is replaced by VRRPv3 (RFC 5798) so
+// virtual IPs can be shared with other vendors' routers and watched by
+// switches. Each virtual router (interface, VRID and address family) runs
+// its own election with a per-VIP priority, optional preemption delay and
+// tracked interfaces or uplinks that lower the priority while down. The
+// node acts as primary while it is master of any virtual router. Owner
+// mode (priority 255) and the RFC virtual MAC are not used: masters answer
+// with the interface MAC and announce moves with gratuitous ARP and
+// unsolicited neighbor advertisements.
+//
 // # Configuration
 //
 // See config.HAConfig for configuration options.
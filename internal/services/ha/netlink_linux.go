@@ -10,6 +10,7 @@ import (
 	"net"
 
 	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 )
 
 // parseAddr parses an IP address in CIDR notation.
@@ -19,7 +20,13 @@ func parseAddr(addrStr string) (*netlink.Addr, error) {
 		return nil, err
 	}
 	ipNet.IP = ip
-	return &netlink.Addr{IPNet: ipNet}, nil
+	addr := &netlink.Addr{IPNet: ipNet}
+	if ip.To4() == nil {
+		// The address moves between nodes; duplicate detection would only
+		// keep it unusable for the first second after failover
+		addr.Flags = unix.IFA_F_NODAD
+	}
+	return addr, nil
 }
 
 // addIPAddress adds an IP address to an interface.
@@ -82,3 +89,14 @@ func hasIPAddress(ifaceName string, targetIP net.IP) (bool, error) {
 
 	return false, nil
 }
+
+// linkUp reports whether an interface exists, is up and has carrier.
+func linkUp(ifaceName string) bool {
+	link, err := netlink.LinkByName(ifaceName)
+	if err != nil {
+		return false
+	}
+	attrs := link.Attrs()
+	return attrs.Flags&net.FlagUp != 0 &&
+		(attrs.OperState == netlink.OperUp || attrs.OperState == netlink.OperUnknown)
+}
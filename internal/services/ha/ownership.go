@@ -0,0 +1,44 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package ha
+
+import "grimm.is/flywall/internal/config"
+
+// Sharing modes (HAConfig.Mode).
+const (
+	ModeActivePassive = "active_passive"
+	ModeActiveActive  = "active_active"
+)
+
+// Ownership describes who holds a virtual IP in active/active mode.
+type Ownership struct {
+	Address   string `json:"address"`
+	Interface string `json:"interface"`
+	Zone      string `json:"zone,omitempty"`
+	Owner     string `json:"owner"`            // Node assigned the address while both nodes are up
+	Holder    string `json:"holder,omitempty"` // Node holding it now, empty while it moves
+}
+
+// nodeRef identifies an HA node for ownership decisions.
+type nodeRef struct {
+	id       string
+	priority int
+}
+
+// preferredOwner returns the node that holds a resource while both nodes
+// are up: its configured owner if that is one of them, or else the node
+// with the lower priority value (then the lower node ID).
+func preferredOwner(owner string, self, peer nodeRef) string {
+	if owner == self.id || owner == peer.id {
+		return owner
+	}
+	if self.priority < peer.priority || (self.priority == peer.priority && self.id < peer.id) {
+		return self.id
+	}
+	return peer.id
+}
+
+// vmacKey names a virtual MAC in the list of held resources.
+func vmacKey(vmac config.VirtualMAC) string {
+	return "mac:" + vmac.Interface
+}
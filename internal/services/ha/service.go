@@ -35,6 +35,10 @@ const (
 
 	// RoleFailed indicates this node has failed and is not participating.
 	RoleFailed Role = "failed"
+
+	// RoleActive indicates this node forwards traffic alongside its peer
+	// in active/active mode.
+	RoleActive Role = "active"
 )
 
 // Default configuration values.
@@ -60,6 +64,9 @@ type HeartbeatMessage struct {
 	// StateVersion is the current replication state version.
 	StateVersion uint64 `json:"state_version"`
 
+	// Held lists the virtual IPs and MACs the sender holds (active/active).
+	Held []string `json:"held,omitempty"`
+
 	// Timestamp is when this heartbeat was sent.
 	Timestamp time.Time `json:"timestamp"`
 
@@ -69,23 +76,29 @@ type HeartbeatMessage struct {
 
 // PeerState tracks the state of the peer node.
 type PeerState struct {
+	// NodeID is the peer's node ID.
+	NodeID string `json:"node_id,omitempty"`
+
 	// Alive indicates whether the peer is responding to heartbeats.
-	Alive bool
+	Alive bool `json:"alive"`
 
 	// LastSeen is when we last received a heartbeat from the peer.
-	LastSeen time.Time
+	LastSeen time.Time `json:"last_seen"`
 
 	// Role is the peer's last reported role.
-	Role Role
+	Role Role `json:"role,omitempty"`
 
 	// Priority is the peer's configured priority.
-	Priority int
+	Priority int `json:"priority,omitempty"`
 
 	// StateVersion is the peer's last reported replication version.
-	StateVersion uint64
+	StateVersion uint64 `json:"state_version"`
 
 	// MissedHeartbeats counts consecutive missed heartbeats.
-	MissedHeartbeats int
+	MissedHeartbeats int `json:"missed_heartbeats"`
+
+	// Held lists the virtual IPs and MACs the peer holds (active/active).
+	Held []string `json:"held,omitempty"`
 }
 
 // LinkManager defines the interface for link layer operations.
@@ -106,6 +119,12 @@ type Replicator interface {
 	CurrentVersion() uint64
 }
 
+// UplinkMonitor reports uplink health for VRRP tracking.
+// This is satisfied by network.UplinkManager.
+type UplinkMonitor interface {
+	UplinkHealthy(name string) (healthy, found bool)
+}
+
 // Service manages high-availability failover.
 type Service struct {
 	config     *config.ReplicationConfig
@@ -116,6 +135,7 @@ type Service struct {
 	linkMgr    LinkManager
 	dhcpMgr    DHCPReclaimer
 	replicator Replicator
+	uplinks    UplinkMonitor
 	logger     *logging.Logger
 
 	// Callbacks for role transitions
@@ -136,6 +156,23 @@ type Service struct {
 
 	// Original MAC addresses saved before virtual MAC application
 	origMACs map[string][]byte
+
+	// Active/active ownership (mode = "active_active")
+	zoneOf       func(iface string) string
+	held         map[string]bool // Virtual IPs and MACs held by this node
+	peerDown     bool            // Peer declared down; we hold everything
+	failbackAt   time.Time       // Give back the peer's resources after this
+	failbackHold bool            // Keep the peer's resources (failback_mode manual or never)
+	ownerMu      sync.Mutex      // Serializes ownership changes
+	heartbeatNow chan struct{}
+
+	// VRRP virtual routers and their sockets (protocol = "vrrp")
+	vrrp        []*vrrpRouter
+	vrrp4       *vrrpSocket
+	vrrp6       *vrrpSocket
+	vrrpWG      sync.WaitGroup
+	vrrpMu      sync.Mutex // Serializes role changes driven by routers
+	vrrpTimeout time.Duration
 }
 
 func NewService(cfg *config.ReplicationConfig, nodeID string, linkMgr LinkManager, logger *logging.Logger) (*Service, error) {
@@ -162,9 +199,11 @@ func NewService(cfg *config.ReplicationConfig, nodeID string, linkMgr LinkManage
 		haConf.FailbackDelay = DefaultFailbackDelay
 	}
 
-	// Determine initial role from config
+	// Determine initial role from config. With VRRP the election decides.
 	role := RoleBackup
-	if cfg.Mode == "primary" {
+	if haConf.Mode == ModeActiveActive && haConf.Protocol != ProtocolVRRP {
+		role = RoleActive
+	} else if cfg.Mode == "primary" && haConf.Protocol != ProtocolVRRP {
 		role = RolePrimary
 	}
 
@@ -180,6 +219,9 @@ func NewService(cfg *config.ReplicationConfig, nodeID string, linkMgr LinkManage
 		ctx:      ctx,
 		cancel:   cancel,
 		origMACs: make(map[string][]byte),
+
+		held:         make(map[string]bool),
+		heartbeatNow: make(chan struct{}, 1),
 	}, nil
 }
 
@@ -214,6 +256,22 @@ func (s *Service) SetReplicator(r Replicator) {
 	s.replicator = r
 }
 
+// SetUplinkMonitor sets the source of uplink health for VRRP tracking.
+// This must be called before Start() if any virtual IP tracks an uplink.
+func (s *Service) SetUplinkMonitor(m UplinkMonitor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.uplinks = m
+}
+
+// SetZoneLookup sets how interfaces map to zones for zone_owners.
+// This must be called before Start() in active/active mode.
+func (s *Service) SetZoneLookup(fn func(iface string) string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.zoneOf = fn
+}
+
 func (s *Service) Start() error {
 	if !s.haConf.Enabled {
 		s.logger.Info("HA is disabled, skipping start")
@@ -222,10 +280,35 @@ func (s *Service) Start() error {
 
 	s.logger.Info("Starting HA service",
 		"node_id", s.nodeID,
+		"protocol", s.haConf.Protocol,
+		"mode", s.haConf.Mode,
 		"role", s.role,
 		"priority", s.haConf.Priority,
 		"peer", s.config.PeerAddr)
 
+	if s.haConf.Protocol == ProtocolVRRP {
+		if err := s.startVRRP(); err != nil {
+			return err
+		}
+	} else if err := s.startHeartbeat(); err != nil {
+		return err
+	}
+
+	// Start conntrackd if configured
+	if s.haConf.ConntrackSync != nil && s.haConf.ConntrackSync.Enabled {
+		s.conntrackdMgr = NewConntrackdManager(s.haConf.ConntrackSync, s.config.PeerAddr, "", s.logger)
+		// Both nodes forward, so peer states go straight into the kernel table
+		s.conntrackdMgr.activeActive = s.haConf.Mode == ModeActiveActive
+		if err := s.conntrackdMgr.Start(); err != nil {
+			s.logger.Warn("Failed to start conntrackd", "error", err)
+		}
+	}
+
+	return nil
+}
+
+// startHeartbeat starts the two-node heartbeat protocol.
+func (s *Service) startHeartbeat() error {
 	// Initialize peer state to avoid immediate failover on startup
 	// We act as if we just saw the peer, giving us HeartbeatInterval time to receive the first real packet
 	s.mu.Lock()
@@ -256,14 +339,6 @@ func (s *Service) Start() error {
 	go s.runHeartbeatSender()
 	go s.runHeartbeatReceiver()
 
-	// Start conntrackd if configured
-	if s.haConf.ConntrackSync != nil && s.haConf.ConntrackSync.Enabled {
-		s.conntrackdMgr = NewConntrackdManager(s.haConf.ConntrackSync, s.config.PeerAddr, "", s.logger)
-		if err := s.conntrackdMgr.Start(); err != nil {
-			s.logger.Warn("Failed to start conntrackd", "error", err)
-		}
-	}
-
 	return nil
 }
 
@@ -276,6 +351,7 @@ func (s *Service) Stop() {
 	if s.recvConn != nil {
 		s.recvConn.Close()
 	}
+	s.stopVRRP()
 
 	s.wg.Wait()
 
@@ -287,6 +363,14 @@ func (s *Service) Stop() {
 	s.logger.Info("HA service stopped")
 }
 
+// Mode returns how the virtual IPs are shared: active_passive or active_active.
+func (s *Service) Mode() string {
+	if s.haConf.Mode == "" {
+		return ModeActivePassive
+	}
+	return s.haConf.Mode
+}
+
 func (s *Service) GetRole() Role {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -296,11 +380,34 @@ func (s *Service) GetRole() Role {
 func (s *Service) GetPeerState() PeerState {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.peer
+	peer := s.peer
+	// VRRP peers only advertise while they are master
+	if s.vrrpTimeout > 0 && clock.Now().Sub(peer.LastSeen) > s.vrrpTimeout {
+		peer.Alive = false
+	}
+	return peer
 }
 
 // TriggerFailover manually initiates failover (for testing/maintenance).
+// In active/active mode it gives back the peer's virtual IPs held because of
+// failback_mode or failback_delay.
 func (s *Service) TriggerFailover() error {
+	if s.haConf.Protocol == ProtocolVRRP {
+		return fmt.Errorf("manual failover is not supported with VRRP; lower the master's priority instead")
+	}
+
+	if s.haConf.Mode == ModeActiveActive {
+		s.mu.Lock()
+		if s.peerDown {
+			s.mu.Unlock()
+			return fmt.Errorf("peer is down, nothing to give back")
+		}
+		s.failbackAt, s.failbackHold = time.Time{}, false
+		s.mu.Unlock()
+		s.reconcileOwnership()
+		return nil
+	}
+
 	s.mu.Lock()
 	if s.role != RoleBackup {
 		s.mu.Unlock()
@@ -345,6 +452,10 @@ func (s *Service) runHeartbeatSender() {
 			if err := s.sendHeartbeat(); err != nil {
 				s.logger.Warn("Failed to send heartbeat", "error", err)
 			}
+		case <-s.heartbeatNow:
+			if err := s.sendHeartbeat(); err != nil {
+				s.logger.Warn("Failed to send heartbeat", "error", err)
+			}
 		}
 	}
 }
@@ -360,22 +471,14 @@ func (s *Service) sendHeartbeat() error {
 		Role:         s.role,
 		Priority:     s.haConf.Priority,
 		StateVersion: version,
+		Held:         s.heldKeys(),
 		Timestamp:    clock.Now(),
 	}
 	s.mu.RUnlock()
 
 	// Sign the message if secret key is configured
 	if s.config.SecretKey != "" {
-		msgBytes, _ := json.Marshal(HeartbeatMessage{
-			NodeID:       msg.NodeID,
-			Role:         msg.Role,
-			Priority:     msg.Priority,
-			StateVersion: msg.StateVersion,
-			Timestamp:    msg.Timestamp,
-		})
-		mac := hmac.New(sha256.New, []byte(s.config.SecretKey))
-		mac.Write(msgBytes)
-		msg.Signature = mac.Sum(nil)
+		msg.Signature = s.signHeartbeat(&msg)
 	}
 
 	data, err := json.Marshal(msg)
@@ -437,6 +540,9 @@ func (s *Service) runHeartbeatReceiver() {
 			}
 
 			s.handleHeartbeat(&msg)
+			if s.haConf.Mode == ModeActiveActive {
+				s.reconcileOwnership()
+			}
 		}
 	}
 }
@@ -447,21 +553,18 @@ func (s *Service) verifyHeartbeat(msg *HeartbeatMessage) bool {
 		return false
 	}
 
-	// Reconstruct the signed portion
-	unsigned := HeartbeatMessage{
-		NodeID:       msg.NodeID,
-		Role:         msg.Role,
-		Priority:     msg.Priority,
-		StateVersion: msg.StateVersion,
-		Timestamp:    msg.Timestamp,
-	}
+	return hmac.Equal(msg.Signature, s.signHeartbeat(msg))
+}
+
+// signHeartbeat returns the HMAC-SHA256 of a heartbeat without its signature.
+func (s *Service) signHeartbeat(msg *HeartbeatMessage) []byte {
+	unsigned := *msg
+	unsigned.Signature = nil
 	msgBytes, _ := json.Marshal(unsigned)
 
 	mac := hmac.New(sha256.New, []byte(s.config.SecretKey))
 	mac.Write(msgBytes)
-	expectedSig := mac.Sum(nil)
-
-	return hmac.Equal(msg.Signature, expectedSig)
+	return mac.Sum(nil)
 }
 
 // handleHeartbeat processes a received heartbeat message.
@@ -470,12 +573,18 @@ func (s *Service) handleHeartbeat(msg *HeartbeatMessage) {
 	defer s.mu.Unlock()
 
 	// Update peer state
+	s.peer.NodeID = msg.NodeID
 	s.peer.Alive = true
 	s.peer.LastSeen = clock.Now()
 	s.peer.Role = msg.Role
 	s.peer.Priority = msg.Priority
 	s.peer.StateVersion = msg.StateVersion
 	s.peer.MissedHeartbeats = 0
+	s.peer.Held = msg.Held
+
+	if s.peerDown {
+		s.peerRecovered(s.peer.LastSeen)
+	}
 
 	// Log significant state changes
 	s.logger.Debug("Received heartbeat",
@@ -522,6 +631,22 @@ func (s *Service) checkPeerHealth() {
 		s.peer.MissedHeartbeats++
 	}
 
+	// Active/active: take over everything the dead peer held
+	if s.haConf.Mode == ModeActiveActive {
+		if !s.peerDown && s.peer.MissedHeartbeats >= s.haConf.FailureThreshold {
+			s.logger.Warn("Peer appears to be down, taking over its virtual IPs",
+				"missed_heartbeats", s.peer.MissedHeartbeats,
+				"last_seen", s.peer.LastSeen)
+			s.peer.Alive = false
+			s.peer.Held = nil
+			s.peerDown = true
+			s.failbackAt, s.failbackHold = time.Time{}, false
+		}
+		s.mu.Unlock()
+		s.reconcileOwnership()
+		return
+	}
+
 	// Check if we should trigger failover
 	shouldTakeover := s.role == RoleBackup &&
 		s.peer.MissedHeartbeats >= s.haConf.FailureThreshold
@@ -633,6 +758,16 @@ func (s *Service) removeVirtualResources() error {
 	}
 
 	// Restore original MACs
+	if err := s.restoreOriginalMACs(); err != nil {
+		lastErr = err
+	}
+
+	return lastErr
+}
+
+// restoreOriginalMACs puts back the MAC addresses replaced by virtual MACs.
+func (s *Service) restoreOriginalMACs() error {
+	var lastErr error
 	for _, vmac := range s.haConf.VirtualMACs {
 		if origMAC, ok := s.origMACs[vmac.Interface]; ok {
 			s.logger.Info("Restoring original MAC", "interface", vmac.Interface, "mac", netutil.FormatMAC(origMAC))
@@ -642,7 +777,6 @@ func (s *Service) removeVirtualResources() error {
 			}
 		}
 	}
-
 	return lastErr
 }
 
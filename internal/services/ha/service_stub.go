@@ -7,6 +7,7 @@ package ha
 
 import (
 	"fmt"
+	"time"
 
 	"grimm.is/flywall/internal/config"
 	"grimm.is/flywall/internal/logging"
@@ -20,6 +21,7 @@ const (
 	RoleBackup     Role = "backup"
 	RoleTakingOver Role = "taking_over"
 	RoleFailed     Role = "failed"
+	RoleActive     Role = "active"
 )
 
 // Default configuration values.
@@ -33,11 +35,14 @@ const (
 
 // PeerState tracks the state of the peer node.
 type PeerState struct {
-	Alive            bool
-	Role             Role
-	Priority         int
-	StateVersion     uint64
-	MissedHeartbeats int
+	NodeID           string    `json:"node_id,omitempty"`
+	Alive            bool      `json:"alive"`
+	LastSeen         time.Time `json:"last_seen"`
+	Role             Role      `json:"role,omitempty"`
+	Priority         int       `json:"priority,omitempty"`
+	StateVersion     uint64    `json:"state_version"`
+	MissedHeartbeats int       `json:"missed_heartbeats"`
+	Held             []string  `json:"held,omitempty"`
 }
 
 // LinkManager defines the interface for link layer operations.
@@ -73,6 +78,11 @@ func (s *Service) GetRole() Role {
 	return RoleFailed
 }
 
+// Mode returns active_passive on non-Linux.
+func (s *Service) Mode() string {
+	return ModeActivePassive
+}
+
 // GetPeerState returns an empty state on non-Linux.
 func (s *Service) GetPeerState() PeerState {
 	return PeerState{}
@@ -98,3 +108,24 @@ type Replicator interface {
 
 // SetReplicator is a no-op on non-Linux.
 func (s *Service) SetReplicator(r Replicator) {}
+
+// UplinkMonitor reports uplink health for VRRP tracking.
+type UplinkMonitor interface {
+	UplinkHealthy(name string) (healthy, found bool)
+}
+
+// SetUplinkMonitor is a no-op on non-Linux.
+func (s *Service) SetUplinkMonitor(m UplinkMonitor) {}
+
+// VRRPStatus returns nil on non-Linux.
+func (s *Service) VRRPStatus() []VRRPStatus {
+	return nil
+}
+
+// SetZoneLookup is a no-op on non-Linux.
+func (s *Service) SetZoneLookup(fn func(iface string) string) {}
+
+// Ownership returns nil on non-Linux.
+func (s *Service) Ownership() []Ownership {
+	return nil
+}
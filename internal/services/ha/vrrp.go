@@ -0,0 +1,671 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+//go:build linux
+// +build linux
+
+package ha
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"grimm.is/flywall/internal/clock"
+	"grimm.is/flywall/internal/config"
+)
+
+type vrrpState int
+
+const (
+	vrrpInit vrrpState = iota
+	vrrpBackup
+	vrrpMaster
+	vrrpFault // A tracked object with weight 0 is down
+)
+
+func (st vrrpState) String() string {
+	switch st {
+	case vrrpBackup:
+		return "backup"
+	case vrrpMaster:
+		return "master"
+	case vrrpFault:
+		return "fault"
+	}
+	return "init"
+}
+
+// vrrpReceived is an advertisement for a router, with its sender.
+type vrrpReceived struct {
+	advert *vrrpAdvert
+	src    netip.Addr
+}
+
+// vrrpRouter is one VRRP virtual router: the virtual IPs of one address
+// family sharing a VRID on an interface. It implements the state machine of
+// RFC 5798 section 6.4, plus preemption delay and priority tracking.
+type vrrpRouter struct {
+	iface        string
+	ifindex      int
+	vrid         uint8
+	v6           bool
+	vips         []config.VirtualIP
+	addrs        []netip.Addr
+	priority     uint8 // Configured priority
+	preempt      bool
+	preemptDelay time.Duration
+	interval     time.Duration
+	tracks       []config.VRRPTrack
+
+	// Hooks into the service. send, takeover and release run with mu held;
+	// send logs its own errors since the next advertisement may succeed.
+	send     func(r *vrrpRouter, priority uint8)
+	takeover func(r *vrrpRouter)
+	release  func(r *vrrpRouter)
+	trackUp  func(t config.VRRPTrack) bool
+	changed  func(r *vrrpRouter)
+
+	adverts chan vrrpReceived
+
+	mu             sync.Mutex
+	state          vrrpState
+	localAddr      netip.Addr // Source of our advertisements, set by send
+	effPriority    uint8      // Priority after tracking
+	trackedDown    []string
+	masterAddr     netip.Addr
+	masterInterval time.Duration // Advertisement interval of the master
+	downAt         time.Time     // Master_Down_Timer (backup)
+	advertAt       time.Time     // Adver_Timer (master)
+	preemptSince   time.Time     // When we first saw a lower-priority master
+}
+
+// newVRRPRouter creates a router from the settings of its first virtual IP.
+func newVRRPRouter(vip config.VirtualIP, vrid int, v6 bool) (*vrrpRouter, error) {
+	r := &vrrpRouter{
+		iface:    vip.Interface,
+		vrid:     uint8(vrid),
+		v6:       v6,
+		priority: DefaultVRRPPriority,
+		preempt:  true,
+		interval: DefaultVRRPInterval,
+		adverts:  make(chan vrrpReceived, 16),
+	}
+	if vip.Priority > 0 {
+		r.priority = uint8(vip.Priority)
+	}
+	if vip.Preempt != nil {
+		r.preempt = *vip.Preempt
+	}
+	if vip.PreemptDelay != "" {
+		d, err := time.ParseDuration(vip.PreemptDelay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid preempt_delay %q: %w", vip.PreemptDelay, err)
+		}
+		r.preemptDelay = d
+	}
+	if vip.AdvertInterval != "" {
+		d, err := time.ParseDuration(vip.AdvertInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid advert_interval %q: %w", vip.AdvertInterval, err)
+		}
+		r.interval = min(max(d, 10*time.Millisecond), vrrpMaxInterval)
+	}
+	return r, nil
+}
+
+// buildVRRPRouters groups the virtual IPs into virtual routers by interface,
+// VRID and address family.
+func buildVRRPRouters(haConf *config.HAConfig) ([]*vrrpRouter, error) {
+	var routers []*vrrpRouter
+	byKey := make(map[string]*vrrpRouter)
+
+	for i, vip := range haConf.VirtualIPs {
+		prefix, err := netip.ParsePrefix(vip.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid virtual IP %s: %w", vip.Address, err)
+		}
+		vrid := haConf.VirtualRouterID(i)
+		if vrid > 255 {
+			return nil, fmt.Errorf("virtual IP %s: vrid %d out of range", vip.Address, vrid)
+		}
+
+		addr := prefix.Addr()
+		key := fmt.Sprintf("%s/%d/%t", vip.Interface, vrid, addr.Is6())
+		r, ok := byKey[key]
+		if !ok {
+			if r, err = newVRRPRouter(vip, vrid, addr.Is6()); err != nil {
+				return nil, fmt.Errorf("virtual IP %s: %w", vip.Address, err)
+			}
+			byKey[key] = r
+			routers = append(routers, r)
+		}
+		r.vips = append(r.vips, vip)
+		r.addrs = append(r.addrs, addr)
+		r.tracks = append(r.tracks, vip.Track...)
+	}
+	return routers, nil
+}
+
+func (r *vrrpRouter) family() string {
+	if r.v6 {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+// Status returns a snapshot of the router's state.
+func (r *vrrpRouter) Status() VRRPStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st := VRRPStatus{
+		Interface:   r.iface,
+		VRID:        int(r.vrid),
+		Family:      r.family(),
+		State:       r.state.String(),
+		Priority:    int(r.effPriority),
+		TrackedDown: r.trackedDown,
+	}
+	if r.masterAddr.IsValid() && (r.state == vrrpBackup || r.state == vrrpMaster) {
+		st.Master = r.masterAddr.String()
+	}
+	for _, addr := range r.addrs {
+		st.Addresses = append(st.Addresses, addr.String())
+	}
+	return st
+}
+
+func (r *vrrpRouter) isMaster() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state == vrrpMaster
+}
+
+// run drives the router until ctx is cancelled, then gives up mastership.
+func (r *vrrpRouter) run(ctx context.Context) {
+	r.event(func(now time.Time) {
+		prio, down, fault := r.evaluate()
+		r.start(now, prio, down, fault)
+	})
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	trackTicker := time.NewTicker(time.Second)
+	defer trackTicker.Stop()
+
+	for {
+		r.mu.Lock()
+		wait := r.nextTimeout(clock.Now())
+		r.mu.Unlock()
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-ctx.Done():
+			r.event(func(time.Time) { r.shutdown() })
+			return
+		case <-timer.C:
+			r.event(r.tick)
+		case rx := <-r.adverts:
+			r.event(func(now time.Time) { r.handleAdvert(rx.advert, rx.src, now) })
+		case <-trackTicker.C:
+			prio, down, fault := r.evaluate()
+			r.event(func(now time.Time) { r.setTracking(prio, down, fault, now) })
+		}
+	}
+}
+
+// event runs fn with the router locked and reports a state change.
+func (r *vrrpRouter) event(fn func(now time.Time)) {
+	r.mu.Lock()
+	before := r.state
+	fn(clock.Now())
+	after := r.state
+	r.mu.Unlock()
+
+	if before != after && r.changed != nil {
+		r.changed(r)
+	}
+}
+
+// nextTimeout returns how long until the running timer fires.
+func (r *vrrpRouter) nextTimeout(now time.Time) time.Duration {
+	var at time.Time
+	switch r.state {
+	case vrrpBackup:
+		at = r.downAt
+	case vrrpMaster:
+		at = r.advertAt
+	default:
+		return time.Hour // Tracking wakes us up
+	}
+	return max(at.Sub(now), 0)
+}
+
+// evaluate checks the interface and tracked objects, returning the effective
+// priority and whether the router must give up its addresses.
+func (r *vrrpRouter) evaluate() (priority uint8, down []string, fault bool) {
+	prio := int(r.priority)
+	tracks := append([]config.VRRPTrack{{Interface: r.iface}}, r.tracks...)
+	for _, t := range tracks {
+		if r.trackUp(t) {
+			continue
+		}
+		name := t.Interface
+		if t.Uplink != "" {
+			name = "uplink " + t.Uplink
+		}
+		down = append(down, name)
+		if t.Weight == 0 {
+			fault = true
+		}
+		prio -= t.Weight
+	}
+	return uint8(max(prio, 1)), down, fault
+}
+
+func (r *vrrpRouter) masterDownInterval() time.Duration {
+	return 3*r.masterInterval + r.skewTime()
+}
+
+func (r *vrrpRouter) skewTime() time.Duration {
+	return time.Duration(256-int(r.effPriority)) * r.masterInterval / 256
+}
+
+// start leaves Init (RFC 5798 6.4.1). Routers never own their addresses
+// (priority 255), so they always wait as backup for a master first.
+func (r *vrrpRouter) start(now time.Time, priority uint8, down []string, fault bool) {
+	r.effPriority, r.trackedDown = priority, down
+	if fault {
+		r.state = vrrpFault
+		return
+	}
+	r.becomeBackup(now, r.interval)
+}
+
+func (r *vrrpRouter) becomeBackup(now time.Time, masterInterval time.Duration) {
+	if masterInterval <= 0 {
+		masterInterval = r.interval
+	}
+	r.state = vrrpBackup
+	r.masterInterval = masterInterval
+	r.downAt = now.Add(r.masterDownInterval())
+	r.advertAt = time.Time{}
+}
+
+func (r *vrrpRouter) becomeMaster(now time.Time) {
+	r.state = vrrpMaster
+	r.downAt = time.Time{}
+	r.preemptSince = time.Time{}
+	r.advertise(now)
+	r.masterAddr = r.localAddr
+	r.takeover(r)
+}
+
+func (r *vrrpRouter) advertise(now time.Time) {
+	r.send(r, r.effPriority)
+	r.advertAt = now.Add(r.interval)
+}
+
+// tick handles an expired Master_Down_Timer or Adver_Timer.
+func (r *vrrpRouter) tick(now time.Time) {
+	switch r.state {
+	case vrrpBackup:
+		if !now.Before(r.downAt) {
+			r.becomeMaster(now)
+		}
+	case vrrpMaster:
+		if !now.Before(r.advertAt) {
+			r.advertise(now)
+		}
+	}
+}
+
+// handleAdvert processes an advertisement for this router (RFC 5798 6.4.2
+// and 6.4.3).
+func (r *vrrpRouter) handleAdvert(adv *vrrpAdvert, src netip.Addr, now time.Time) {
+	switch r.state {
+	case vrrpBackup:
+		if adv.Priority == 0 {
+			// The master is shutting down: take over after the skew time
+			r.downAt = now.Add(r.skewTime())
+			return
+		}
+		if adv.Priority < r.effPriority && r.preempt {
+			if !r.delayPreempt(now) {
+				// Ignore the lower-priority master; our timer expires and we preempt
+				return
+			}
+		} else {
+			r.preemptSince = time.Time{}
+		}
+		r.masterAddr = src
+		r.becomeBackup(now, adv.Interval)
+
+	case vrrpMaster:
+		if adv.Priority == 0 {
+			r.advertise(now)
+			return
+		}
+		if adv.Priority > r.effPriority || (adv.Priority == r.effPriority && src.Compare(r.localAddr) > 0) {
+			r.release(r)
+			r.masterAddr = src
+			r.becomeBackup(now, adv.Interval)
+		}
+	}
+}
+
+// delayPreempt reports whether a backup should still accept a lower-priority
+// master, because preempt_delay has not passed since it was first seen.
+func (r *vrrpRouter) delayPreempt(now time.Time) bool {
+	if r.preemptDelay <= 0 {
+		return false
+	}
+	if r.preemptSince.IsZero() {
+		r.preemptSince = now
+	}
+	return now.Sub(r.preemptSince) < r.preemptDelay
+}
+
+// setTracking applies a change in tracked interfaces and uplinks. A lower
+// priority is carried by the next advertisement; a fault gives up the
+// addresses at once.
+func (r *vrrpRouter) setTracking(priority uint8, down []string, fault bool, now time.Time) {
+	r.effPriority, r.trackedDown = priority, down
+	switch {
+	case fault && r.state != vrrpFault:
+		if r.state == vrrpMaster {
+			r.send(r, 0)
+			r.release(r)
+		}
+		r.state = vrrpFault
+		r.downAt, r.advertAt = time.Time{}, time.Time{}
+	case !fault && r.state == vrrpFault:
+		r.becomeBackup(now, r.interval)
+	}
+}
+
+// shutdown leaves the election, letting a backup take over without waiting
+// for the master down interval.
+func (r *vrrpRouter) shutdown() {
+	if r.state == vrrpMaster {
+		r.send(r, 0)
+		r.release(r)
+	}
+	r.state = vrrpInit
+}
+
+// startVRRP builds the virtual routers, joins the VRRP group on their
+// interfaces and starts the elections. The node is primary while it is
+// master of at least one virtual router.
+func (s *Service) startVRRP() error {
+	routers, err := buildVRRPRouters(s.haConf)
+	if err != nil {
+		return err
+	}
+
+	var uplinkTracked bool
+	for _, r := range routers {
+		ifi, err := net.InterfaceByName(r.iface)
+		if err != nil {
+			s.closeVRRPSockets()
+			return fmt.Errorf("VRRP interface %s not found: %w", r.iface, err)
+		}
+		sock, err := s.vrrpSocket(r.v6)
+		if err != nil {
+			s.closeVRRPSockets()
+			return err
+		}
+		if err := sock.join(ifi); err != nil {
+			s.closeVRRPSockets()
+			return fmt.Errorf("failed to join VRRP group on %s: %w", r.iface, err)
+		}
+
+		r.ifindex = ifi.Index
+		r.send = s.vrrpSend
+		r.takeover = s.vrrpTakeover
+		r.release = s.vrrpRelease
+		r.trackUp = s.vrrpTrackUp
+		r.changed = s.vrrpChanged
+		for _, t := range r.tracks {
+			uplinkTracked = uplinkTracked || t.Uplink != ""
+		}
+		// Backups are silent, so a peer is only alive while it is master
+		s.vrrpTimeout = max(s.vrrpTimeout, 3*r.interval+time.Second)
+
+		s.logger.Info("Starting VRRP virtual router",
+			"interface", r.iface,
+			"vrid", r.vrid,
+			"family", r.family(),
+			"priority", r.priority,
+			"addresses", r.addrs)
+	}
+	if uplinkTracked && s.uplinks == nil {
+		s.logger.Warn("VRRP tracks uplinks but no uplink health is available; treating them as up")
+	}
+	s.vrrp = routers
+
+	for _, sock := range []*vrrpSocket{s.vrrp4, s.vrrp6} {
+		if sock != nil {
+			s.wg.Add(1)
+			go s.runVRRPReceiver(sock)
+		}
+	}
+	for _, r := range routers {
+		s.vrrpWG.Add(1)
+		go func(r *vrrpRouter) {
+			defer s.vrrpWG.Done()
+			r.run(s.ctx)
+		}(r)
+	}
+	return nil
+}
+
+// stopVRRP waits for the routers to give up their addresses, then closes
+// the sockets.
+func (s *Service) stopVRRP() {
+	s.vrrpWG.Wait()
+	s.closeVRRPSockets()
+}
+
+func (s *Service) vrrpSocket(v6 bool) (*vrrpSocket, error) {
+	sock := &s.vrrp4
+	if v6 {
+		sock = &s.vrrp6
+	}
+	if *sock == nil {
+		conn, err := listenVRRP(v6)
+		if err != nil {
+			return nil, err
+		}
+		*sock = conn
+	}
+	return *sock, nil
+}
+
+func (s *Service) closeVRRPSockets() {
+	for _, sock := range []*vrrpSocket{s.vrrp4, s.vrrp6} {
+		if sock != nil {
+			sock.Close()
+		}
+	}
+}
+
+// runVRRPReceiver hands received advertisements to their virtual routers.
+func (s *Service) runVRRPReceiver(sock *vrrpSocket) {
+	defer s.wg.Done()
+
+	buf := make([]byte, 1500)
+	for {
+		n, src, dst, ifindex, ttl, err := sock.read(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			s.logger.Warn("Error receiving VRRP advertisement", "error", err)
+			continue
+		}
+		if ttl != vrrpTTL {
+			s.logger.Debug("Dropping VRRP packet with bad TTL", "from", src, "ttl", ttl)
+			continue
+		}
+		adv, err := parseVRRPAdvert(buf[:n], src, dst)
+		if err != nil {
+			s.logger.Debug("Dropping invalid VRRP packet", "from", src, "error", err)
+			continue
+		}
+
+		for _, r := range s.vrrp {
+			if r.ifindex != ifindex || r.vrid != adv.VRID || r.v6 != sock.v6 {
+				continue
+			}
+			s.mu.Lock()
+			s.peer.LastSeen = clock.Now()
+			s.peer.Alive = adv.Priority > 0
+			s.peer.Role = RolePrimary
+			s.peer.Priority = int(adv.Priority)
+			s.mu.Unlock()
+
+			select {
+			case r.adverts <- vrrpReceived{advert: adv, src: src}:
+			default:
+				// Router busy; the master advertises again shortly
+			}
+		}
+	}
+}
+
+func (s *Service) vrrpSend(r *vrrpRouter, priority uint8) {
+	src, err := vrrpSourceAddr(r.iface, r.v6, r.addrs)
+	if err != nil {
+		s.logger.Warn("Cannot send VRRP advertisement", "interface", r.iface, "vrid", r.vrid, "error", err)
+		return
+	}
+	r.localAddr = src
+
+	adv := vrrpAdvert{VRID: r.vrid, Priority: priority, Interval: r.interval, Addrs: r.addrs}
+	sock, _ := s.vrrpSocket(r.v6)
+	if err := sock.send(r.ifindex, src, adv.marshal(src)); err != nil {
+		s.logger.Warn("Failed to send VRRP advertisement", "interface", r.iface, "vrid", r.vrid, "error", err)
+	}
+}
+
+// vrrpTakeover adds the router's addresses and announces them to neighbours.
+func (s *Service) vrrpTakeover(r *vrrpRouter) {
+	for i, vip := range r.vips {
+		if err := s.applyVirtualIP(vip); err != nil {
+			s.logger.Error("Failed to apply virtual IP", "address", vip.Address, "error", err)
+			continue
+		}
+		if err := announceAddress(vip.Interface, r.addrs[i]); err != nil {
+			s.logger.Warn("Failed to announce virtual IP", "address", vip.Address, "error", err)
+		}
+	}
+}
+
+func (s *Service) vrrpRelease(r *vrrpRouter) {
+	for _, vip := range r.vips {
+		if err := s.removeVirtualIP(vip); err != nil {
+			s.logger.Error("Failed to remove virtual IP", "address", vip.Address, "error", err)
+		}
+	}
+}
+
+func (s *Service) vrrpTrackUp(t config.VRRPTrack) bool {
+	if t.Uplink != "" {
+		s.mu.RLock()
+		uplinks := s.uplinks
+		s.mu.RUnlock()
+		if uplinks == nil {
+			return true
+		}
+		healthy, found := uplinks.UplinkHealthy(t.Uplink)
+		return found && healthy
+	}
+	return linkUp(t.Interface)
+}
+
+// vrrpChanged logs a router's new state and moves the node between primary
+// and backup when it gains its first or loses its last master role.
+func (s *Service) vrrpChanged(r *vrrpRouter) {
+	st := r.Status()
+	s.logger.Info("VRRP state changed",
+		"interface", st.Interface,
+		"vrid", st.VRID,
+		"family", st.Family,
+		"state", st.State,
+		"priority", st.Priority,
+		"master", st.Master,
+		"tracked_down", st.TrackedDown)
+
+	s.vrrpMu.Lock()
+	defer s.vrrpMu.Unlock()
+
+	master := false
+	for _, vr := range s.vrrp {
+		if vr.isMaster() {
+			master = true
+			break
+		}
+	}
+
+	s.mu.Lock()
+	switch {
+	case master && s.role != RolePrimary:
+		s.role = RolePrimary
+	case !master && s.role == RolePrimary:
+		s.role = RoleBackup
+	default:
+		s.mu.Unlock()
+		return
+	}
+	onBecomePrimary, onBecomeBackup := s.onBecomePrimary, s.onBecomeBackup
+	s.mu.Unlock()
+
+	if master {
+		for _, vmac := range s.haConf.VirtualMACs {
+			if err := s.applyVirtualMAC(vmac); err != nil {
+				s.logger.Error("Failed to apply virtual MAC", "interface", vmac.Interface, "error", err)
+			}
+		}
+		if onBecomePrimary != nil {
+			if err := onBecomePrimary(); err != nil {
+				s.logger.Error("onBecomePrimary callback failed", "error", err)
+			}
+		}
+		if s.conntrackdMgr != nil {
+			if err := s.conntrackdMgr.NotifyFailover(); err != nil {
+				s.logger.Warn("Conntrack state commit failed", "error", err)
+			}
+		}
+		s.logger.Info("Now operating as primary (VRRP master)")
+		return
+	}
+
+	s.restoreOriginalMACs()
+	if onBecomeBackup != nil {
+		if err := onBecomeBackup(); err != nil {
+			s.logger.Error("onBecomeBackup callback failed", "error", err)
+		}
+	}
+	s.logger.Info("Now operating as backup (no VRRP master role)")
+}
+
+// VRRPStatus returns the state of each VRRP virtual router, or nil when
+// the heartbeat protocol is used.
+func (s *Service) VRRPStatus() []VRRPStatus {
+	var status []VRRPStatus
+	for _, r := range s.vrrp {
+		status = append(status, r.Status())
+	}
+	return status
+}
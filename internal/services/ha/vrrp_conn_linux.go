@@ -0,0 +1,249 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+//go:build linux
+// +build linux
+
+package ha
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/mdlayher/ndp"
+	"github.com/mdlayher/packet"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"golang.org/x/sys/unix"
+)
+
+// vrrpSocket is a raw IP socket for VRRP advertisements of one address family.
+type vrrpSocket struct {
+	v6     bool
+	c4     *ipv4.PacketConn
+	c6     *ipv6.PacketConn
+	joined map[int]bool
+}
+
+func listenVRRP(v6 bool) (*vrrpSocket, error) {
+	s := &vrrpSocket{v6: v6, joined: make(map[int]bool)}
+	if !v6 {
+		c, err := net.ListenPacket("ip4:112", "0.0.0.0")
+		if err != nil {
+			return nil, fmt.Errorf("failed to open VRRP socket: %w", err)
+		}
+		s.c4 = ipv4.NewPacketConn(c)
+		// RFC 5798 requires TTL 255 so receivers can reject forwarded packets
+		if err := s.c4.SetMulticastTTL(vrrpTTL); err != nil {
+			c.Close()
+			return nil, err
+		}
+		s.c4.SetMulticastLoopback(false)
+		if err := s.c4.SetControlMessage(ipv4.FlagTTL|ipv4.FlagDst|ipv4.FlagInterface, true); err != nil {
+			c.Close()
+			return nil, err
+		}
+		return s, nil
+	}
+
+	c, err := net.ListenPacket("ip6:112", "::")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open VRRPv6 socket: %w", err)
+	}
+	s.c6 = ipv6.NewPacketConn(c)
+	if err := s.c6.SetMulticastHopLimit(vrrpTTL); err != nil {
+		c.Close()
+		return nil, err
+	}
+	s.c6.SetMulticastLoopback(false)
+	// Let the kernel fill in and verify the checksum, which covers the
+	// source address it picks
+	if err := s.c6.SetChecksum(true, 6); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if err := s.c6.SetControlMessage(ipv6.FlagHopLimit|ipv6.FlagDst|ipv6.FlagInterface, true); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// join subscribes to the VRRP group on an interface.
+func (s *vrrpSocket) join(ifi *net.Interface) error {
+	if s.joined[ifi.Index] {
+		return nil
+	}
+	var err error
+	if s.v6 {
+		err = s.c6.JoinGroup(ifi, &net.IPAddr{IP: vrrpGroup6.AsSlice()})
+	} else {
+		err = s.c4.JoinGroup(ifi, &net.IPAddr{IP: vrrpGroup4.AsSlice()})
+	}
+	if err != nil {
+		return err
+	}
+	s.joined[ifi.Index] = true
+	return nil
+}
+
+func (s *vrrpSocket) send(ifindex int, src netip.Addr, b []byte) error {
+	var err error
+	if s.v6 {
+		cm := &ipv6.ControlMessage{IfIndex: ifindex, Src: src.AsSlice(), HopLimit: vrrpTTL}
+		_, err = s.c6.WriteTo(b, cm, &net.IPAddr{IP: vrrpGroup6.AsSlice()})
+	} else {
+		cm := &ipv4.ControlMessage{IfIndex: ifindex, Src: src.AsSlice()}
+		_, err = s.c4.WriteTo(b, cm, &net.IPAddr{IP: vrrpGroup4.AsSlice()})
+	}
+	return err
+}
+
+// read returns the next VRRP packet with its addresses, the interface it
+// arrived on and its TTL or hop limit.
+func (s *vrrpSocket) read(buf []byte) (n int, src, dst netip.Addr, ifindex, ttl int, err error) {
+	var from net.Addr
+	if s.v6 {
+		var cm *ipv6.ControlMessage
+		if n, cm, from, err = s.c6.ReadFrom(buf); err != nil {
+			return 0, src, dst, 0, 0, err
+		}
+		dst = vrrpGroup6
+		if cm != nil {
+			ifindex, ttl = cm.IfIndex, cm.HopLimit
+			if a, ok := netip.AddrFromSlice(cm.Dst); ok {
+				dst = a
+			}
+		}
+	} else {
+		var cm *ipv4.ControlMessage
+		if n, cm, from, err = s.c4.ReadFrom(buf); err != nil {
+			return 0, src, dst, 0, 0, err
+		}
+		dst = vrrpGroup4
+		if cm != nil {
+			ifindex, ttl = cm.IfIndex, cm.TTL
+			if a, ok := netip.AddrFromSlice(cm.Dst); ok {
+				dst = a.Unmap()
+			}
+		}
+	}
+	if ipa, ok := from.(*net.IPAddr); ok {
+		src, _ = netip.AddrFromSlice(ipa.IP)
+		src = src.Unmap()
+	}
+	return n, src, dst, ifindex, ttl, nil
+}
+
+func (s *vrrpSocket) Close() error {
+	if s.v6 {
+		return s.c6.Close()
+	}
+	return s.c4.Close()
+}
+
+// vrrpSourceAddr picks the address advertisements are sent from: the
+// interface's primary IPv4 address, or its IPv6 link-local address.
+func vrrpSourceAddr(ifaceName string, v6 bool, vips []netip.Addr) (netip.Addr, error) {
+	link, err := netlink.LinkByName(ifaceName)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("interface %s not found: %w", ifaceName, err)
+	}
+	family := netlink.FAMILY_V4
+	if v6 {
+		family = netlink.FAMILY_V6
+	}
+	addrs, err := netlink.AddrList(link, family)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("failed to list addresses on %s: %w", ifaceName, err)
+	}
+
+next:
+	for _, a := range addrs {
+		addr, ok := netip.AddrFromSlice(a.IP)
+		if !ok {
+			continue
+		}
+		addr = addr.Unmap()
+		for _, vip := range vips {
+			if addr == vip {
+				continue next
+			}
+		}
+		if v6 && !addr.IsLinkLocalUnicast() {
+			continue
+		}
+		if a.Flags&(unix.IFA_F_SECONDARY|unix.IFA_F_TENTATIVE) != 0 {
+			continue
+		}
+		return addr, nil
+	}
+	if v6 {
+		return netip.Addr{}, fmt.Errorf("no usable link-local address on %s", ifaceName)
+	}
+	return netip.Addr{}, fmt.Errorf("no IPv4 address on %s", ifaceName)
+}
+
+// announceAddress tells neighbours a virtual IP moved here, with a
+// gratuitous ARP or an unsolicited neighbor advertisement.
+func announceAddress(ifaceName string, addr netip.Addr) error {
+	ifi, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return err
+	}
+	if addr.Is6() {
+		return sendUnsolicitedNA(ifi, addr)
+	}
+	return sendGratuitousARP(ifi, addr)
+}
+
+func sendGratuitousARP(ifi *net.Interface, addr netip.Addr) error {
+	if len(ifi.HardwareAddr) != 6 {
+		return nil // No ARP on this link
+	}
+	conn, err := packet.Listen(ifi, packet.Raw, unix.ETH_P_ARP, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	broadcast := net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	ip := addr.As4()
+
+	frame := make([]byte, 14+28)
+	copy(frame[0:], broadcast)
+	copy(frame[6:], ifi.HardwareAddr)
+	binary.BigEndian.PutUint16(frame[12:], unix.ETH_P_ARP)
+
+	arp := frame[14:]
+	binary.BigEndian.PutUint16(arp[0:], 1) // Ethernet
+	binary.BigEndian.PutUint16(arp[2:], unix.ETH_P_IP)
+	arp[4], arp[5] = 6, 4
+	binary.BigEndian.PutUint16(arp[6:], 1) // Request, with sender = target
+	copy(arp[8:], ifi.HardwareAddr)
+	copy(arp[14:], ip[:])
+	copy(arp[24:], ip[:])
+
+	_, err = conn.WriteTo(frame, &packet.Addr{HardwareAddr: broadcast})
+	return err
+}
+
+func sendUnsolicitedNA(ifi *net.Interface, addr netip.Addr) error {
+	conn, _, err := ndp.Listen(ifi, ndp.LinkLocal)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	na := &ndp.NeighborAdvertisement{
+		Router:        true,
+		Override:      true,
+		TargetAddress: addr,
+		Options: []ndp.Option{
+			&ndp.LinkLayerAddress{Direction: ndp.Target, Addr: ifi.HardwareAddr},
+		},
+	}
+	return conn.WriteTo(na, nil, netip.IPv6LinkLocalAllNodes())
+}
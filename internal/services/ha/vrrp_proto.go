@@ -0,0 +1,143 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package ha
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"time"
+)
+
+// Failover protocols (HAConfig.Protocol).
+const (
+	ProtocolHeartbeat = "heartbeat"
+	ProtocolVRRP      = "vrrp"
+)
+
+// VRRPv3 constants (RFC 5798).
+const (
+	vrrpProto       = 112
+	vrrpVersion     = 3
+	vrrpTypeAdvert  = 1
+	vrrpHeaderLen   = 8
+	vrrpTTL         = 255
+	vrrpMaxInterval = 4095 * 10 * time.Millisecond // 12 bits of centiseconds
+
+	DefaultVRRPPriority = 100
+	DefaultVRRPInterval = time.Second
+)
+
+var (
+	vrrpGroup4 = netip.MustParseAddr("224.0.0.18")
+	vrrpGroup6 = netip.MustParseAddr("ff02::12")
+)
+
+// VRRPStatus describes one VRRP virtual router on this node.
+type VRRPStatus struct {
+	Interface string   `json:"interface"`
+	VRID      int      `json:"vrid"`
+	Family    string   `json:"family"` // "ipv4" or "ipv6"
+	State     string   `json:"state"`  // init, backup, master or fault
+	Priority  int      `json:"priority"`
+	Master    string   `json:"master,omitempty"` // Address of the current master
+	Addresses []string `json:"addresses"`
+
+	// TrackedDown lists tracked interfaces and uplinks that are down
+	TrackedDown []string `json:"tracked_down,omitempty"`
+}
+
+// vrrpAdvert is a VRRPv3 advertisement.
+type vrrpAdvert struct {
+	VRID     uint8
+	Priority uint8
+	Interval time.Duration
+	Addrs    []netip.Addr
+}
+
+// marshal encodes the advertisement sent from src to the VRRP group,
+// including the checksum over the IP pseudo-header.
+func (a *vrrpAdvert) marshal(src netip.Addr) []byte {
+	alen := 4
+	dst := vrrpGroup4
+	if src.Is6() {
+		alen = 16
+		dst = vrrpGroup6
+	}
+
+	b := make([]byte, vrrpHeaderLen+alen*len(a.Addrs))
+	b[0] = vrrpVersion<<4 | vrrpTypeAdvert
+	b[1] = a.VRID
+	b[2] = a.Priority
+	b[3] = uint8(len(a.Addrs))
+	binary.BigEndian.PutUint16(b[4:], uint16(a.Interval/(10*time.Millisecond))&0x0fff)
+	for i, addr := range a.Addrs {
+		copy(b[vrrpHeaderLen+i*alen:], addr.AsSlice())
+	}
+	binary.BigEndian.PutUint16(b[6:], vrrpChecksum(b, src, dst))
+	return b
+}
+
+// parseVRRPAdvert decodes an advertisement received from src on dst.
+func parseVRRPAdvert(b []byte, src, dst netip.Addr) (*vrrpAdvert, error) {
+	if len(b) < vrrpHeaderLen {
+		return nil, fmt.Errorf("short packet (%d bytes)", len(b))
+	}
+	if b[0]>>4 != vrrpVersion || b[0]&0x0f != vrrpTypeAdvert {
+		return nil, fmt.Errorf("unsupported version/type %#x", b[0])
+	}
+
+	alen := 4
+	if src.Is6() {
+		alen = 16
+	}
+	count := int(b[3])
+	if len(b) < vrrpHeaderLen+count*alen {
+		return nil, fmt.Errorf("truncated address list (%d addresses)", count)
+	}
+	b = b[:vrrpHeaderLen+count*alen]
+	if vrrpChecksum(b, src, dst) != 0 {
+		return nil, fmt.Errorf("bad checksum")
+	}
+
+	a := &vrrpAdvert{
+		VRID:     b[1],
+		Priority: b[2],
+		Interval: time.Duration(binary.BigEndian.Uint16(b[4:])&0x0fff) * 10 * time.Millisecond,
+	}
+	for i := 0; i < count; i++ {
+		addr, _ := netip.AddrFromSlice(b[vrrpHeaderLen+i*alen : vrrpHeaderLen+(i+1)*alen])
+		a.Addrs = append(a.Addrs, addr)
+	}
+	return a, nil
+}
+
+// vrrpChecksum is the Internet checksum of the packet and the IPv4 or IPv6
+// pseudo-header. It is zero for a packet carrying a valid checksum.
+func vrrpChecksum(b []byte, src, dst netip.Addr) uint16 {
+	var sum uint32
+	add := func(p []byte) {
+		for i := 0; i+1 < len(p); i += 2 {
+			sum += uint32(p[i])<<8 | uint32(p[i+1])
+		}
+		if len(p)%2 == 1 {
+			sum += uint32(p[len(p)-1]) << 8
+		}
+	}
+
+	add(src.AsSlice())
+	add(dst.AsSlice())
+	if src.Is6() {
+		add(binary.BigEndian.AppendUint32(nil, uint32(len(b))))
+		add([]byte{0, 0, 0, vrrpProto})
+	} else {
+		add([]byte{0, vrrpProto})
+		add(binary.BigEndian.AppendUint16(nil, uint16(len(b))))
+	}
+	add(b)
+
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
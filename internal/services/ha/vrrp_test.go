@@ -0,0 +1,399 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+//go:build linux
+// +build linux
+
+package ha
+
+import (
+	"net/netip"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/logging"
+)
+
+func TestVRRPAdvert(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   netip.Addr
+		dst   netip.Addr
+		addrs []netip.Addr
+	}{
+		{"ipv4", netip.MustParseAddr("192.168.1.2"), vrrpGroup4,
+			[]netip.Addr{netip.MustParseAddr("192.168.1.1"), netip.MustParseAddr("192.168.1.254")}},
+		{"ipv6", netip.MustParseAddr("fe80::2"), vrrpGroup6,
+			[]netip.Addr{netip.MustParseAddr("fe80::1"), netip.MustParseAddr("2001:db8::1")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adv := &vrrpAdvert{VRID: 42, Priority: 200, Interval: 150 * time.Millisecond, Addrs: tt.addrs}
+			b := adv.marshal(tt.src)
+
+			if b[0] != 0x31 {
+				t.Errorf("version/type = %#x; want 0x31", b[0])
+			}
+			got, err := parseVRRPAdvert(b, tt.src, tt.dst)
+			if err != nil {
+				t.Fatalf("parseVRRPAdvert() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, adv) {
+				t.Errorf("parseVRRPAdvert() = %+v; want %+v", got, adv)
+			}
+
+			b[2] = 201
+			if _, err := parseVRRPAdvert(b, tt.src, tt.dst); err == nil {
+				t.Error("Expected checksum error for modified packet")
+			}
+			if _, err := parseVRRPAdvert(b[:vrrpHeaderLen+1], tt.src, tt.dst); err == nil {
+				t.Error("Expected error for truncated packet")
+			}
+		})
+	}
+}
+
+// testRouter is a virtual router with recording hooks.
+type testRouter struct {
+	*vrrpRouter
+	sent     []uint8 // Advertised priorities
+	holding  bool
+	linkDown map[string]bool
+}
+
+func newTestRouter(t *testing.T, vip config.VirtualIP) *testRouter {
+	t.Helper()
+	r, err := newVRRPRouter(vip, 1, false)
+	if err != nil {
+		t.Fatalf("newVRRPRouter() error = %v", err)
+	}
+	r.tracks = vip.Track
+	r.localAddr = netip.MustParseAddr("10.0.0.2")
+
+	tr := &testRouter{vrrpRouter: r, linkDown: make(map[string]bool)}
+	r.send = func(_ *vrrpRouter, prio uint8) { tr.sent = append(tr.sent, prio) }
+	r.takeover = func(*vrrpRouter) { tr.holding = true }
+	r.release = func(*vrrpRouter) { tr.holding = false }
+	r.trackUp = func(track config.VRRPTrack) bool { return !tr.linkDown[track.Interface] }
+	return tr
+}
+
+func (tr *testRouter) startAt(now time.Time) {
+	prio, down, fault := tr.evaluate()
+	tr.start(now, prio, down, fault)
+}
+
+func (tr *testRouter) track(now time.Time) {
+	prio, down, fault := tr.evaluate()
+	tr.setTracking(prio, down, fault, now)
+}
+
+func TestVRRPRouter_Election(t *testing.T) {
+	r := newTestRouter(t, config.VirtualIP{Interface: "eth1", Priority: 100})
+	peer := netip.MustParseAddr("10.0.0.3")
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	r.startAt(now)
+	if r.state != vrrpBackup {
+		t.Fatalf("state after start = %s; want backup", r.state)
+	}
+	// Master_Down_Interval = 3 * 1s + (256-100)/256 s
+	if want := now.Add(3*time.Second + 609375*time.Microsecond); !r.downAt.Equal(want) {
+		t.Errorf("downAt = %v; want %v", r.downAt, want)
+	}
+
+	// A higher-priority master keeps us in backup
+	now = now.Add(time.Second)
+	r.handleAdvert(&vrrpAdvert{Priority: 150, Interval: time.Second}, peer, now)
+	r.tick(now.Add(3 * time.Second))
+	if r.state != vrrpBackup || r.masterAddr != peer {
+		t.Fatalf("state = %s, master = %s; want backup of %s", r.state, r.masterAddr, peer)
+	}
+
+	// No advertisements: take over
+	now = now.Add(4 * time.Second)
+	r.tick(now)
+	if r.state != vrrpMaster || !r.holding || len(r.sent) != 1 || r.sent[0] != 100 {
+		t.Fatalf("state = %s, holding = %v, sent = %v; want master advertising 100", r.state, r.holding, r.sent)
+	}
+
+	// A master with equal priority and a higher address wins
+	r.handleAdvert(&vrrpAdvert{Priority: 100, Interval: time.Second}, peer, now)
+	if r.state != vrrpBackup || r.holding {
+		t.Fatalf("state = %s, holding = %v; want backup without addresses", r.state, r.holding)
+	}
+
+	// The master shutting down hands over after the skew time only
+	r.handleAdvert(&vrrpAdvert{Priority: 0, Interval: time.Second}, peer, now)
+	r.tick(now.Add(610 * time.Millisecond))
+	if r.state != vrrpMaster {
+		t.Fatalf("state = %s; want master after skew time", r.state)
+	}
+
+	// Leaving the election releases the addresses with a priority 0 advert
+	r.shutdown()
+	if r.holding || r.sent[len(r.sent)-1] != 0 {
+		t.Errorf("holding = %v, sent = %v; want released with priority 0", r.holding, r.sent)
+	}
+}
+
+func TestVRRPRouter_Preempt(t *testing.T) {
+	lower := &vrrpAdvert{Priority: 100, Interval: time.Second}
+	peer := netip.MustParseAddr("10.0.0.3")
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	noPreempt := false
+	r := newTestRouter(t, config.VirtualIP{Interface: "eth1", Priority: 200, Preempt: &noPreempt})
+	r.startAt(now)
+	for i := 0; i < 5; i++ {
+		now = now.Add(time.Second)
+		r.handleAdvert(lower, peer, now)
+		r.tick(now)
+	}
+	if r.state != vrrpBackup {
+		t.Fatalf("state = %s; want backup without preemption", r.state)
+	}
+
+	r = newTestRouter(t, config.VirtualIP{Interface: "eth1", Priority: 200, PreemptDelay: "10s"})
+	r.startAt(now)
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Second)
+		r.handleAdvert(lower, peer, now)
+		r.tick(now)
+		if r.state != vrrpBackup {
+			t.Fatalf("preempted after %ds; want to wait 10s", i+1)
+		}
+	}
+	// The delay has passed: the lower-priority master is ignored
+	for i := 0; i < 4 && r.state == vrrpBackup; i++ {
+		now = now.Add(time.Second)
+		r.handleAdvert(lower, peer, now)
+		r.tick(now)
+	}
+	if r.state != vrrpMaster {
+		t.Fatalf("state = %s; want master after preempt_delay", r.state)
+	}
+}
+
+func TestVRRPRouter_Tracking(t *testing.T) {
+	r := newTestRouter(t, config.VirtualIP{Interface: "eth1", Priority: 200, Track: []config.VRRPTrack{
+		{Interface: "eth0", Weight: 150},
+		{Interface: "eth2"},
+	}})
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	r.startAt(now)
+	r.tick(now.Add(5 * time.Second))
+	if r.state != vrrpMaster {
+		t.Fatalf("state = %s; want master", r.state)
+	}
+
+	r.linkDown["eth0"] = true
+	r.track(now)
+	if r.state != vrrpMaster || r.effPriority != 50 {
+		t.Fatalf("state = %s, priority = %d; want master at priority 50", r.state, r.effPriority)
+	}
+	if want := []string{"eth0"}; !reflect.DeepEqual(r.trackedDown, want) {
+		t.Errorf("trackedDown = %v; want %v", r.trackedDown, want)
+	}
+
+	// A weight 0 object gives up the addresses at once
+	r.linkDown["eth2"] = true
+	r.track(now)
+	if r.state != vrrpFault || r.holding || r.sent[len(r.sent)-1] != 0 {
+		t.Fatalf("state = %s, holding = %v, sent = %v; want fault after releasing", r.state, r.holding, r.sent)
+	}
+
+	r.linkDown = map[string]bool{}
+	r.track(now)
+	if r.state != vrrpBackup || r.effPriority != 200 {
+		t.Errorf("state = %s, priority = %d; want backup at priority 200", r.state, r.effPriority)
+	}
+}
+
+func TestBuildVRRPRouters(t *testing.T) {
+	haConf := &config.HAConfig{VirtualIPs: []config.VirtualIP{
+		{Address: "10.0.0.1/24", Interface: "eth1", Priority: 150},
+		{Address: "10.0.0.2/24", Interface: "eth1", VRID: 1},
+		{Address: "fd00::1/64", Interface: "eth1", VRID: 1},
+		{Address: "10.1.0.1/24", Interface: "eth2", AdvertInterval: "100ms"},
+	}}
+	routers, err := buildVRRPRouters(haConf)
+	if err != nil {
+		t.Fatalf("buildVRRPRouters() error = %v", err)
+	}
+	if len(routers) != 3 {
+		t.Fatalf("got %d routers; want 3", len(routers))
+	}
+	if r := routers[0]; len(r.addrs) != 2 || r.priority != 150 || r.v6 {
+		t.Errorf("router 0 = %d addresses, priority %d; want 2 IPv4 addresses at 150", len(r.addrs), r.priority)
+	}
+	if r := routers[1]; !r.v6 || r.vrid != 1 {
+		t.Errorf("router 1 = vrid %d, v6 %v; want IPv6 vrid 1", r.vrid, r.v6)
+	}
+	if r := routers[2]; r.vrid != 4 || r.interval != 100*time.Millisecond || r.priority != DefaultVRRPPriority {
+		t.Errorf("router 2 = vrid %d, interval %s, priority %d; want defaults with vrid 4", r.vrid, r.interval, r.priority)
+	}
+}
+
+const vrrpNodeEnv = "FLYWALL_VRRP_TEST_NODE"
+
+// TestVRRPHelperNode runs one VRRP node for TestVRRPNamespaces inside a
+// network namespace. FLYWALL_VRRP_TEST_NODE is "interface,priority[,track]".
+func TestVRRPHelperNode(t *testing.T) {
+	spec := os.Getenv(vrrpNodeEnv)
+	if spec == "" {
+		t.Skip("helper process for TestVRRPNamespaces")
+	}
+	parts := strings.Split(spec, ",")
+	iface := parts[0]
+	priority, _ := strconv.Atoi(parts[1])
+
+	var track []config.VRRPTrack
+	if len(parts) > 2 {
+		track = []config.VRRPTrack{{Interface: parts[2], Weight: 150}}
+	}
+	cfg := &config.ReplicationConfig{
+		Mode: "primary",
+		HA: &config.HAConfig{
+			Enabled:  true,
+			Protocol: ProtocolVRRP,
+			VirtualIPs: []config.VirtualIP{
+				{Address: "10.99.0.1/24", Interface: iface, VRID: 42, Priority: priority, AdvertInterval: "100ms", Track: track},
+				{Address: "fd99::1/64", Interface: iface, VRID: 42, Priority: priority, AdvertInterval: "100ms", Track: track},
+			},
+		},
+	}
+
+	svc, err := NewService(cfg, iface, NewMockLinkManager(), logging.WithComponent("ha-test"))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, os.Interrupt)
+	<-sig
+	svc.Stop()
+}
+
+// TestVRRPNamespaces elects a master between two nodes in separate network
+// namespaces joined by a veth pair, then checks failover, preemption and
+// interface tracking. It needs root and iproute2.
+func TestVRRPNamespaces(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping network namespace test in short mode")
+	}
+	if os.Getenv(vrrpNodeEnv) != "" {
+		t.Skip("running as helper")
+	}
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	if _, err := exec.LookPath("ip"); err != nil {
+		t.Skip("requires iproute2")
+	}
+
+	ip := func(args ...string) {
+		t.Helper()
+		if out, err := exec.Command("ip", args...).CombinedOutput(); err != nil {
+			t.Fatalf("ip %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	suffix := strconv.Itoa(os.Getpid())
+	nsA, nsB := "vrrp-a-"+suffix, "vrrp-b-"+suffix
+	for _, ns := range []string{nsA, nsB} {
+		if out, err := exec.Command("ip", "netns", "add", ns).CombinedOutput(); err != nil {
+			t.Skipf("cannot create network namespaces: %v\n%s", err, out)
+		}
+		t.Cleanup(func() { exec.Command("ip", "netns", "del", ns).Run() })
+	}
+
+	ip("link", "add", "vrrp-a", "netns", nsA, "type", "veth", "peer", "name", "vrrp-b", "netns", nsB)
+	ip("-n", nsA, "link", "add", "vrrp-trk", "type", "veth", "peer", "name", "vrrp-trk-p")
+	for _, node := range []struct{ ns, iface, addr string }{
+		{nsA, "vrrp-a", "10.99.0.2/24"},
+		{nsB, "vrrp-b", "10.99.0.3/24"},
+	} {
+		// Link-local addresses must be usable right away to advertise
+		exec.Command("ip", "netns", "exec", node.ns, "sh", "-c",
+			"echo 0 > /proc/sys/net/ipv6/conf/"+node.iface+"/accept_dad").Run()
+		ip("-n", node.ns, "addr", "add", node.addr, "dev", node.iface)
+		ip("-n", node.ns, "link", "set", "lo", "up")
+		ip("-n", node.ns, "link", "set", node.iface, "up")
+	}
+	ip("-n", nsA, "link", "set", "vrrp-trk-p", "up")
+	ip("-n", nsA, "link", "set", "vrrp-trk", "up")
+
+	logs := t.TempDir()
+	start := func(ns, spec string) *exec.Cmd {
+		t.Helper()
+		logFile, err := os.OpenFile(filepath.Join(logs, ns+".log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command("ip", "netns", "exec", ns, os.Args[0], "-test.run=^TestVRRPHelperNode$", "-test.v")
+		cmd.Env = append(os.Environ(), vrrpNodeEnv+"="+spec)
+		cmd.Stdout, cmd.Stderr = logFile, logFile
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("failed to start %s: %v", ns, err)
+		}
+		t.Cleanup(func() {
+			cmd.Process.Kill()
+			cmd.Wait()
+			logFile.Close()
+		})
+		return cmd
+	}
+	stop := func(cmd *exec.Cmd) {
+		cmd.Process.Signal(syscall.SIGTERM)
+		cmd.Wait()
+	}
+
+	holds := func(ns, iface string) bool {
+		out, _ := exec.Command("ip", "-n", ns, "-o", "addr", "show", "dev", iface).Output()
+		return strings.Contains(string(out), " 10.99.0.1/24 ") && strings.Contains(string(out), " fd99::1/64 ")
+	}
+	waitOwner := func(want string) {
+		t.Helper()
+		var a, b bool
+		for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+			a, b = holds(nsA, "vrrp-a"), holds(nsB, "vrrp-b")
+			if (want == "a" && a && !b) || (want == "b" && b && !a) {
+				return
+			}
+		}
+		for _, ns := range []string{nsA, nsB} {
+			out, _ := os.ReadFile(filepath.Join(logs, ns+".log"))
+			t.Logf("%s log:\n%s", ns, out)
+		}
+		t.Fatalf("node %s does not hold the virtual IPs alone (a: %v, b: %v)", want, a, b)
+	}
+
+	a := start(nsA, "vrrp-a,200,vrrp-trk")
+	start(nsB, "vrrp-b,100")
+	waitOwner("a")
+
+	// Failover: A leaves with a priority 0 advertisement
+	stop(a)
+	waitOwner("b")
+
+	// Preemption: A returns with the higher priority
+	start(nsA, "vrrp-a,200,vrrp-trk")
+	waitOwner("a")
+
+	// Tracking: A's priority drops to 50 when its tracked link goes down
+	ip("-n", nsA, "link", "set", "vrrp-trk", "down")
+	waitOwner("b")
+}
@@ -0,0 +1,68 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package nat64
+
+import "net/netip"
+
+// WellKnownPrefix is the RFC 6052 well-known prefix.
+var WellKnownPrefix = netip.MustParsePrefix("64:ff9b::/96")
+
+// sharedAddressSpace is the RFC 6598 carrier-grade NAT range, which is not
+// global either.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Embed returns the IPv4-embedded IPv6 address of v4 in prefix, following
+// RFC 6052 section 2.2: the IPv4 address follows the prefix, skipping bits
+// 64 to 71, and the suffix is zero.
+func Embed(prefix netip.Prefix, v4 netip.Addr) netip.Addr {
+	b := prefix.Masked().Addr().As16()
+	a := v4.As4()
+	i := prefix.Bits() / 8
+	for j := 0; j < 4; i++ {
+		if i == 8 {
+			continue
+		}
+		b[i] = a[j]
+		j++
+	}
+	return netip.AddrFrom16(b)
+}
+
+// Extract returns the IPv4 address embedded in v6, if v6 is in prefix.
+func Extract(prefix netip.Prefix, v6 netip.Addr) (netip.Addr, bool) {
+	if !prefix.Contains(v6) {
+		return netip.Addr{}, false
+	}
+	b := v6.As16()
+	var a [4]byte
+	i := prefix.Bits() / 8
+	for j := 0; j < 4; i++ {
+		if i == 8 {
+			continue
+		}
+		a[j] = b[i]
+		j++
+	}
+	return netip.AddrFrom4(a), true
+}
+
+// CanEmbed reports whether v4 may be represented in prefix. RFC 6052
+// section 3.1 forbids the well-known prefix for non-global addresses, so
+// hosts behind NAT64 cannot reach private networks through it.
+func CanEmbed(prefix netip.Prefix, v4 netip.Addr) bool {
+	if !v4.Is4() {
+		return false
+	}
+	if prefix != WellKnownPrefix {
+		return true
+	}
+	return isGlobal4(v4)
+}
+
+// isGlobal4 reports whether v4 is a globally reachable unicast address.
+func isGlobal4(v4 netip.Addr) bool {
+	a := v4.As4()
+	return !(a[0] == 0 || a[0] >= 224 ||
+		v4.IsPrivate() || v4.IsLoopback() || v4.IsLinkLocalUnicast() ||
+		sharedAddressSpace.Contains(v4))
+}
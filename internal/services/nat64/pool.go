@@ -0,0 +1,159 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package nat64
+
+import (
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// Binding maps an IPv6 client to its address in the IPv4 pool.
+type Binding struct {
+	IPv6     netip.Addr `json:"ipv6"`
+	IPv4     netip.Addr `json:"ipv4"`
+	LastUsed time.Time  `json:"last_used"`
+}
+
+// pool hands out IPv4 addresses to IPv6 clients. The kernel masquerades the
+// pool, so one address per client is enough to keep its connections apart;
+// a binding is reused by another client only after it has been idle for
+// the timeout.
+type pool struct {
+	mu      sync.Mutex
+	self    netip.Addr // Translator's own address, never bound
+	first   netip.Addr
+	last    netip.Addr
+	next    netip.Addr // Where the search for a free address starts
+	timeout time.Duration
+	now     func() time.Time
+
+	by6 map[netip.Addr]*Binding
+	by4 map[netip.Addr]*Binding
+}
+
+// newPool creates a pool over p, leaving out the network, broadcast and
+// first host addresses. p must be /30 or larger.
+func newPool(p netip.Prefix, timeout time.Duration) *pool {
+	network := p.Masked().Addr()
+	a := network.As4()
+	host := uint32(1)<<(32-p.Bits()) - 1
+	n := uint32(a[0])<<24 | uint32(a[1])<<16 | uint32(a[2])<<8 | uint32(a[3]) | host
+	broadcast := netip.AddrFrom4([4]byte{byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)})
+
+	self := network.Next()
+	return &pool{
+		self:    self,
+		first:   self.Next(),
+		last:    broadcast.Prev(),
+		next:    self.Next(),
+		timeout: timeout,
+		now:     time.Now,
+		by6:     make(map[netip.Addr]*Binding),
+		by4:     make(map[netip.Addr]*Binding),
+	}
+}
+
+// lookup6 returns the pool address of an IPv6 client, binding one if
+// allocate is set and the client has none.
+func (p *pool) lookup6(v6 netip.Addr, allocate bool) (netip.Addr, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	if b, ok := p.by6[v6]; ok {
+		b.LastUsed = now
+		return b.IPv4, true
+	}
+	if !allocate {
+		return netip.Addr{}, false
+	}
+
+	v4, ok := p.free(now)
+	if !ok {
+		return netip.Addr{}, false
+	}
+	b := &Binding{IPv6: v6, IPv4: v4, LastUsed: now}
+	p.by6[v6] = b
+	p.by4[v4] = b
+	return v4, true
+}
+
+// lookup4 returns the IPv6 client bound to a pool address.
+func (p *pool) lookup4(v4 netip.Addr) (netip.Addr, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	b, ok := p.by4[v4]
+	if !ok {
+		return netip.Addr{}, false
+	}
+	b.LastUsed = p.now()
+	return b.IPv6, true
+}
+
+// free finds an unbound address, or takes over the binding idle longest if
+// it has expired. Caller must hold p.mu.
+func (p *pool) free(now time.Time) (netip.Addr, bool) {
+	addr := p.next
+	for {
+		if _, used := p.by4[addr]; !used {
+			p.next = p.advance(addr)
+			return addr, true
+		}
+		addr = p.advance(addr)
+		if addr == p.next {
+			break
+		}
+	}
+
+	var oldest *Binding
+	for _, b := range p.by4 {
+		if oldest == nil || b.LastUsed.Before(oldest.LastUsed) {
+			oldest = b
+		}
+	}
+	if oldest == nil || now.Sub(oldest.LastUsed) < p.timeout {
+		return netip.Addr{}, false
+	}
+	delete(p.by6, oldest.IPv6)
+	delete(p.by4, oldest.IPv4)
+	return oldest.IPv4, true
+}
+
+// advance returns the pool address after addr, wrapping around.
+func (p *pool) advance(addr netip.Addr) netip.Addr {
+	if addr == p.last {
+		return p.first
+	}
+	return addr.Next()
+}
+
+// expire drops bindings idle for longer than the timeout.
+func (p *pool) expire() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	n := 0
+	for v4, b := range p.by4 {
+		if now.Sub(b.LastUsed) >= p.timeout {
+			delete(p.by4, v4)
+			delete(p.by6, b.IPv6)
+			n++
+		}
+	}
+	return n
+}
+
+// bindings returns a copy of the current bindings.
+func (p *pool) bindings() []Binding {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	res := make([]Binding, 0, len(p.by4))
+	for _, b := range p.by4 {
+		res = append(res, *b)
+	}
+	return res
+}
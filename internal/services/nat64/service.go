@@ -0,0 +1,245 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+// Package nat64 translates between IPv6-only clients and IPv4 hosts for
+// zones with services { nat64 = true }. Clients reach IPv4 hosts at
+// addresses embedded in the NAT64 prefix, which DNS64 hands out. The prefix
+// is routed into a TUN device, where the translator maps each client to an
+// address from a private IPv4 pool (RFC 7915); the firewall masquerades
+// the pool out the uplinks, which together make a stateful NAT64
+// (RFC 6146).
+package nat64
+
+import (
+	"context"
+	"io"
+	"net/netip"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/errors"
+	"grimm.is/flywall/internal/logging"
+	"grimm.is/flywall/internal/services"
+)
+
+// expireInterval is how often idle bindings are dropped.
+const expireInterval = time.Minute
+
+// Service runs the NAT64 translator.
+type Service struct {
+	logger *logging.Logger
+
+	// open creates the TUN device and routes the prefix and pool into it
+	open func(cfg *config.NAT64Config, prefix, pool netip.Prefix) (io.ReadWriteCloser, error)
+
+	mu      sync.Mutex
+	cfg     *config.NAT64Config // nil while no zone uses NAT64
+	dev     io.ReadWriteCloser
+	xlat    *translator
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	running bool
+
+	errMu   sync.Mutex // Separate so the translate loop can record failures
+	lastErr error
+}
+
+// NewService creates the NAT64 service. It is idle until a zone enables
+// NAT64.
+func NewService() *Service {
+	return &Service{
+		logger: logging.WithComponent("nat64"),
+		open:   openTUN,
+	}
+}
+
+// Name returns the service name.
+func (s *Service) Name() string { return "nat64" }
+
+// Start brings up the translator if a zone uses NAT64.
+func (s *Service) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return nil
+	}
+	s.ctx = ctx
+	s.running = true
+	return s.up()
+}
+
+// Stop takes the translator down. Clients lose their bindings.
+func (s *Service) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		return nil
+	}
+	s.running = false
+	s.down()
+	return nil
+}
+
+// Reload applies a new configuration. Any change to the NAT64 settings
+// restarts the translator, which drops all bindings.
+func (s *Service) Reload(cfg *config.Config) (bool, error) {
+	settings := cfg.NAT64Settings()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if reflect.DeepEqual(s.cfg, settings) {
+		return false, nil
+	}
+	s.cfg = settings
+	if !s.running {
+		return false, nil
+	}
+	s.down()
+	return true, s.up()
+}
+
+// Status reports whether the translator is running.
+func (s *Service) Status() services.ServiceStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := services.ServiceStatus{Name: s.Name(), Running: s.dev != nil}
+	if err := s.err(); err != nil {
+		st.Error = err.Error()
+	}
+	return st
+}
+
+func (s *Service) setErr(err error) {
+	s.errMu.Lock()
+	s.lastErr = err
+	s.errMu.Unlock()
+}
+
+func (s *Service) err() error {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	return s.lastErr
+}
+
+// Bindings lists the clients mapped into the IPv4 pool, most recently
+// active first.
+func (s *Service) Bindings() []Binding {
+	s.mu.Lock()
+	xlat := s.xlat
+	s.mu.Unlock()
+	if xlat == nil {
+		return nil
+	}
+	res := xlat.pool.bindings()
+	sort.Slice(res, func(i, j int) bool { return res[i].LastUsed.After(res[j].LastUsed) })
+	return res
+}
+
+// up opens the device and starts translating. Caller must hold s.mu.
+func (s *Service) up() error {
+	s.setErr(nil)
+	if s.cfg == nil {
+		return nil
+	}
+
+	prefix, err := config.ParseNAT64Prefix(s.cfg.Prefix)
+	if err != nil {
+		s.setErr(err)
+		return errors.Wrap(err, errors.KindValidation, "invalid NAT64 prefix")
+	}
+	pool4, err := netip.ParsePrefix(s.cfg.Pool4)
+	if err != nil || !pool4.Addr().Is4() || pool4.Bits() > 30 {
+		err = errors.Errorf(errors.KindValidation, "invalid NAT64 pool %q", s.cfg.Pool4)
+		s.setErr(err)
+		return err
+	}
+
+	dev, err := s.open(s.cfg, prefix, pool4.Masked())
+	if err != nil {
+		s.setErr(err)
+		return errors.Wrapf(err, errors.KindUnavailable, "failed to create NAT64 interface %s", s.cfg.Interface)
+	}
+	s.dev = dev
+	s.xlat = newTranslator(prefix, newPool(pool4, s.cfg.BindingDuration()))
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.cancel = cancel
+	s.wg.Add(2)
+	go s.translate(ctx, dev, s.xlat)
+	go s.expire(ctx, s.xlat.pool)
+
+	s.logger.Info("NAT64 translator running", "interface", s.cfg.Interface,
+		"prefix", prefix.String(), "pool4", pool4.Masked().String())
+	return nil
+}
+
+// down stops translating and closes the device. Caller must hold s.mu.
+func (s *Service) down() {
+	if s.dev == nil {
+		return
+	}
+	s.cancel()
+	if err := s.dev.Close(); err != nil {
+		s.logger.Warn("Failed to close NAT64 interface", "error", err)
+	}
+	s.wg.Wait()
+	s.dev = nil
+	s.xlat = nil
+}
+
+// translate reads packets from the device and writes back their
+// translations until the device is closed.
+func (s *Service) translate(ctx context.Context, dev io.ReadWriter, xlat *translator) {
+	defer s.wg.Done()
+
+	buf := make([]byte, 65535)
+	for {
+		n, err := dev.Read(buf)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Error("NAT64 device read failed", "error", err)
+				s.setErr(err)
+			}
+			return
+		}
+		if n == 0 {
+			continue
+		}
+
+		var out []byte
+		var ok bool
+		switch buf[0] >> 4 {
+		case 6:
+			out, ok = xlat.toIPv4(buf[:n])
+		case 4:
+			out, ok = xlat.toIPv6(buf[:n])
+		}
+		if !ok {
+			continue
+		}
+		if _, err := dev.Write(out); err != nil && ctx.Err() == nil {
+			s.logger.Debug("NAT64 device write failed", "error", err)
+		}
+	}
+}
+
+// expire drops idle bindings periodically.
+func (s *Service) expire(ctx context.Context, p *pool) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(expireInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n := p.expire(); n > 0 {
+				s.logger.Debug("Expired NAT64 bindings", "count", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package nat64
+
+import (
+	"context"
+	"io"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+
+	"grimm.is/flywall/internal/config"
+)
+
+// fakeTUN hands packets written to in to the translator and collects its
+// output on out.
+type fakeTUN struct {
+	in     chan []byte
+	out    chan []byte
+	closed chan struct{}
+}
+
+func newFakeTUN() *fakeTUN {
+	return &fakeTUN{in: make(chan []byte, 1), out: make(chan []byte, 1), closed: make(chan struct{})}
+}
+
+func (d *fakeTUN) Read(b []byte) (int, error) {
+	select {
+	case p := <-d.in:
+		return copy(b, p), nil
+	case <-d.closed:
+		return 0, io.EOF
+	}
+}
+
+func (d *fakeTUN) Write(b []byte) (int, error) {
+	d.out <- append([]byte(nil), b...)
+	return len(b), nil
+}
+
+func (d *fakeTUN) Close() error {
+	close(d.closed)
+	return nil
+}
+
+func TestServiceLifecycle(t *testing.T) {
+	var devs []*fakeTUN
+	var pools []netip.Prefix
+	s := NewService()
+	s.open = func(cfg *config.NAT64Config, prefix, pool netip.Prefix) (io.ReadWriteCloser, error) {
+		d := newFakeTUN()
+		devs = append(devs, d)
+		pools = append(pools, pool)
+		return d, nil
+	}
+
+	cfg := &config.Config{Zones: []config.Zone{{Name: "iot6", Services: &config.ZoneServices{NAT64: true}}}}
+	if _, err := s.Reload(cfg); err != nil {
+		t.Fatalf("Reload() = %v", err)
+	}
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+	defer s.Stop(context.Background())
+	if len(devs) != 1 || !s.Status().Running {
+		t.Fatalf("Start() opened %d devices; want the translator running", len(devs))
+	}
+
+	ip6 := ipv6Layer(client, Embed(WellKnownPrefix, server), layers.IPProtocolUDP)
+	udp := &layers.UDP{SrcPort: 40000, DstPort: 123}
+	udp.SetNetworkLayerForChecksum(ip6)
+	devs[0].in <- serialize(t, ip6, udp, gopacket.Payload("ntp"))
+	select {
+	case out := <-devs[0].out:
+		if _, dst := checkIPv4(t, out); dst != server {
+			t.Errorf("translated to %s; want %s", dst, server)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no translated packet")
+	}
+	if b := s.Bindings(); len(b) != 1 || b[0].IPv6 != client {
+		t.Errorf("Bindings() = %+v; want the client bound", b)
+	}
+
+	if restarted, _ := s.Reload(cfg); restarted {
+		t.Errorf("Reload() with the same config restarted the translator")
+	}
+	cfg.NAT64 = &config.NAT64Config{Pool4: "100.127.0.0/22"}
+	if restarted, err := s.Reload(cfg); !restarted || err != nil {
+		t.Fatalf("Reload() = %v, %v; want a restart", restarted, err)
+	}
+	if len(devs) != 2 || pools[1] != netip.MustParsePrefix("100.127.0.0/22") {
+		t.Errorf("restart opened %d devices with pool %v", len(devs), pools)
+	}
+	if len(s.Bindings()) != 0 {
+		t.Errorf("bindings survived a restart")
+	}
+
+	cfg.Zones[0].Services.NAT64 = false
+	s.Reload(cfg)
+	if s.Status().Running {
+		t.Errorf("translator still running without a NAT64 zone")
+	}
+}
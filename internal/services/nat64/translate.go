@@ -0,0 +1,518 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package nat64
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"sync/atomic"
+)
+
+const (
+	ipv4HeaderLen = 20
+	ipv6HeaderLen = 40
+
+	protoICMP   = 1
+	protoTCP    = 6
+	protoUDP    = 17
+	protoICMPv6 = 58
+
+	// minIPv6MTU caps translated ICMPv6 errors and floors the MTUs they carry.
+	minIPv6MTU = 1280
+)
+
+// translator rewrites packets between IPv6 clients and IPv4 hosts as
+// described in RFC 7915. Clients reach a host at its address embedded in
+// prefix; the client's own address is replaced by its pool binding. TCP,
+// UDP and ICMP echo are translated, as are ICMP errors about them, so path
+// MTU discovery works across the translator. IPv6 extension headers and
+// IPv4 fragments are not supported and are dropped.
+type translator struct {
+	prefix netip.Prefix
+	pool   *pool
+	self6  netip.Addr // Source of errors from IPv4 hosts that can't be embedded
+	ipID   atomic.Uint32
+}
+
+func newTranslator(prefix netip.Prefix, pool *pool) *translator {
+	return &translator{
+		prefix: prefix,
+		pool:   pool,
+		self6:  Embed(prefix, pool.self),
+	}
+}
+
+// toIPv4 translates a packet from an IPv6 client towards an IPv4 host.
+func (t *translator) toIPv4(p []byte) ([]byte, bool) {
+	if len(p) < ipv6HeaderLen || p[0]>>4 != 6 {
+		return nil, false
+	}
+	end := ipv6HeaderLen + int(binary.BigEndian.Uint16(p[4:6]))
+	if end > len(p) {
+		return nil, false
+	}
+	p = p[:end]
+	hop := p[7]
+	if hop <= 1 {
+		return nil, false
+	}
+
+	dst4, ok := Extract(t.prefix, addr16(p[24:40]))
+	if !ok || !CanEmbed(t.prefix, dst4) {
+		return nil, false
+	}
+	next := p[6]
+	payload := p[ipv6HeaderLen:]
+
+	src := addr16(p[8:24])
+	var src4 netip.Addr
+	if next == protoICMPv6 && len(payload) > 0 && payload[0] < 128 {
+		// Errors can come from routers without a binding of their own
+		if src4, ok = t.pool.lookup6(src, false); !ok {
+			src4 = t.pool.self
+		}
+	} else if src4, ok = t.pool.lookup6(src, true); !ok {
+		return nil, false
+	}
+
+	var body []byte
+	proto := next
+	switch next {
+	case protoTCP, protoUDP:
+		if !l4HeaderComplete(payload, next) {
+			return nil, false
+		}
+		body = append([]byte(nil), payload...)
+		adjustL4Checksum(body, next, p[8:40], addrs4(src4, dst4))
+	case protoICMPv6:
+		if body, ok = t.icmp6To4(payload); !ok {
+			return nil, false
+		}
+		proto = protoICMP
+	default:
+		return nil, false
+	}
+
+	out := make([]byte, ipv4HeaderLen, ipv4HeaderLen+len(body))
+	t.writeIPv4Header(out, trafficClass(p), ipv4HeaderLen+len(body), hop-1, proto, src4, dst4)
+	return append(out, body...), true
+}
+
+// toIPv6 translates a packet from an IPv4 host back to the IPv6 client
+// bound to its destination.
+func (t *translator) toIPv6(p []byte) ([]byte, bool) {
+	if len(p) < ipv4HeaderLen || p[0]>>4 != 4 {
+		return nil, false
+	}
+	ihl := int(p[0]&0x0f) * 4
+	total := int(binary.BigEndian.Uint16(p[2:4]))
+	if ihl < ipv4HeaderLen || total < ihl || total > len(p) {
+		return nil, false
+	}
+	p = p[:total]
+	if binary.BigEndian.Uint16(p[6:8])&0x3fff != 0 {
+		return nil, false // Fragment
+	}
+	ttl := p[8]
+	if ttl <= 1 {
+		return nil, false
+	}
+
+	dst6, ok := t.pool.lookup4(addr4(p[16:20]))
+	if !ok {
+		return nil, false
+	}
+	proto := p[9]
+	payload := p[ihl:]
+
+	src4 := addr4(p[12:16])
+	var src6 netip.Addr
+	switch {
+	case CanEmbed(t.prefix, src4):
+		src6 = Embed(t.prefix, src4)
+	case proto == protoICMP && len(payload) > 0 && isICMPError(payload[0]):
+		src6 = t.self6
+	default:
+		return nil, false
+	}
+
+	var body []byte
+	next := proto
+	switch proto {
+	case protoTCP, protoUDP:
+		if !l4HeaderComplete(payload, proto) {
+			return nil, false
+		}
+		body = append([]byte(nil), payload...)
+		adjustL4Checksum(body, proto, p[12:20], addrs6(src6, dst6))
+		if proto == protoUDP && binary.BigEndian.Uint16(body[6:8]) == 0 {
+			// IPv4 UDP may omit the checksum, IPv6 UDP may not
+			binary.BigEndian.PutUint16(body[6:8], udpChecksum(checksum(body, pseudo6(src6, dst6, len(body), protoUDP))))
+		}
+	case protoICMP:
+		if body, ok = t.icmp4To6(payload, src6, dst6); !ok {
+			return nil, false
+		}
+		next = protoICMPv6
+	default:
+		return nil, false
+	}
+
+	out := make([]byte, ipv6HeaderLen, ipv6HeaderLen+len(body))
+	writeIPv6Header(out, p[1], len(body), next, ttl-1, src6, dst6)
+	return append(out, body...), true
+}
+
+// icmp6To4 translates an ICMPv6 message to ICMP.
+func (t *translator) icmp6To4(m []byte) ([]byte, bool) {
+	if len(m) < 8 {
+		return nil, false
+	}
+	out := make([]byte, 8, len(m))
+	code := m[1]
+	switch m[0] {
+	case 128, 129: // Echo request, reply
+		out[0] = 8
+		if m[0] == 129 {
+			out[0] = 0
+		}
+		copy(out[4:8], m[4:8])
+		out = append(out, m[8:]...)
+
+	case 1, 2, 3:
+		switch m[0] {
+		case 1: // Destination unreachable
+			out[0] = 3
+			switch code {
+			case 0, 2, 3:
+				out[1] = 1 // Host unreachable
+			case 1:
+				out[1] = 10 // Administratively prohibited
+			case 4:
+				out[1] = 3 // Port unreachable
+			default:
+				return nil, false
+			}
+		case 2: // Packet too big: fragmentation needed
+			out[0], out[1] = 3, 4
+			mtu := binary.BigEndian.Uint32(m[4:8])
+			if mtu < minIPv6MTU {
+				mtu = minIPv6MTU
+			}
+			mtu -= ipv6HeaderLen - ipv4HeaderLen
+			if mtu > 0xffff {
+				mtu = 0xffff
+			}
+			binary.BigEndian.PutUint16(out[6:8], uint16(mtu))
+		case 3: // Time exceeded
+			out[0], out[1] = 11, code
+		}
+		inner, ok := t.inner6To4(m[8:])
+		if !ok {
+			return nil, false
+		}
+		out = append(out, inner...)
+
+	default:
+		return nil, false
+	}
+	binary.BigEndian.PutUint16(out[2:4], checksum(out, 0))
+	return out, true
+}
+
+// icmp4To6 translates an ICMP message to ICMPv6.
+func (t *translator) icmp4To6(m []byte, src6, dst6 netip.Addr) ([]byte, bool) {
+	if len(m) < 8 {
+		return nil, false
+	}
+	out := make([]byte, 8, len(m)+ipv6HeaderLen-ipv4HeaderLen)
+	code := m[1]
+	switch m[0] {
+	case 8, 0: // Echo request, reply
+		out[0] = 128
+		if m[0] == 0 {
+			out[0] = 129
+		}
+		copy(out[4:8], m[4:8])
+		out = append(out, m[8:]...)
+
+	case 3, 11:
+		if m[0] == 11 { // Time exceeded
+			out[0], out[1] = 3, code
+		} else {
+			switch code {
+			case 0, 1, 5, 6, 7, 8, 11, 12:
+				out[0] = 1 // No route
+			case 3:
+				out[0], out[1] = 1, 4 // Port unreachable
+			case 4: // Fragmentation needed: packet too big
+				out[0] = 2
+				mtu := uint32(binary.BigEndian.Uint16(m[6:8])) + ipv6HeaderLen - ipv4HeaderLen
+				if mtu < minIPv6MTU {
+					mtu = minIPv6MTU
+				}
+				binary.BigEndian.PutUint32(out[4:8], mtu)
+			case 9, 10, 13:
+				out[0], out[1] = 1, 1 // Administratively prohibited
+			default:
+				return nil, false
+			}
+		}
+		inner, ok := t.inner4To6(m[8:])
+		if !ok {
+			return nil, false
+		}
+		out = append(out, inner...)
+		if len(out) > minIPv6MTU-ipv6HeaderLen {
+			out = out[:minIPv6MTU-ipv6HeaderLen]
+		}
+
+	default:
+		return nil, false
+	}
+	binary.BigEndian.PutUint16(out[2:4], checksum(out, pseudo6(src6, dst6, len(out), protoICMPv6)))
+	return out, true
+}
+
+// inner6To4 translates the packet quoted in an ICMPv6 error: one an IPv4
+// host sent to a client, so its source is embedded and its destination is
+// bound.
+func (t *translator) inner6To4(p []byte) ([]byte, bool) {
+	if len(p) < ipv6HeaderLen || p[0]>>4 != 6 {
+		return nil, false
+	}
+	src4, ok := Extract(t.prefix, addr16(p[8:24]))
+	if !ok {
+		return nil, false
+	}
+	dst4, ok := t.pool.lookup6(addr16(p[24:40]), false)
+	if !ok {
+		return nil, false
+	}
+
+	next := p[6]
+	body := append([]byte(nil), p[ipv6HeaderLen:]...)
+	proto := next
+	switch next {
+	case protoTCP, protoUDP:
+		adjustL4Checksum(body, next, p[8:40], addrs4(src4, dst4))
+	case protoICMPv6:
+		proto = protoICMP
+		if len(body) > 0 {
+			body[0] = echoType6To4(body[0])
+		}
+	}
+
+	hdr := make([]byte, ipv4HeaderLen, ipv4HeaderLen+len(body))
+	hdr[0] = 0x45
+	hdr[1] = trafficClass(p)
+	binary.BigEndian.PutUint16(hdr[2:4], uint16(ipv4HeaderLen+int(binary.BigEndian.Uint16(p[4:6]))))
+	hdr[8] = p[7]
+	hdr[9] = proto
+	copy(hdr[12:20], addrs4(src4, dst4))
+	binary.BigEndian.PutUint16(hdr[10:12], checksum(hdr, 0))
+	return append(hdr, body...), true
+}
+
+// inner4To6 translates the packet quoted in an ICMP error: one a client
+// sent to an IPv4 host, so its source is bound and its destination gets
+// embedded.
+func (t *translator) inner4To6(p []byte) ([]byte, bool) {
+	if len(p) < ipv4HeaderLen || p[0]>>4 != 4 {
+		return nil, false
+	}
+	ihl := int(p[0]&0x0f) * 4
+	if ihl < ipv4HeaderLen || len(p) < ihl {
+		return nil, false
+	}
+	src6, ok := t.pool.lookup4(addr4(p[12:16]))
+	if !ok {
+		return nil, false
+	}
+	dst4 := addr4(p[16:20])
+	if !CanEmbed(t.prefix, dst4) {
+		return nil, false
+	}
+	dst6 := Embed(t.prefix, dst4)
+
+	proto := p[9]
+	body := append([]byte(nil), p[ihl:]...)
+	next := proto
+	switch proto {
+	case protoTCP, protoUDP:
+		adjustL4Checksum(body, proto, p[12:20], addrs6(src6, dst6))
+	case protoICMP:
+		next = protoICMPv6
+		if len(body) > 0 {
+			body[0] = echoType4To6(body[0])
+		}
+	}
+
+	payloadLen := int(binary.BigEndian.Uint16(p[2:4])) - ihl
+	if payloadLen < 0 {
+		payloadLen = 0
+	}
+	hdr := make([]byte, ipv6HeaderLen, ipv6HeaderLen+len(body))
+	writeIPv6Header(hdr, p[1], payloadLen, next, p[8], src6, dst6)
+	return append(hdr, body...), true
+}
+
+// writeIPv4Header fills in b[:20]. Following RFC 7915, packets small
+// enough to cross any IPv4 link unfragmented are left fragmentable, with
+// an identification; larger ones get DF so path MTU discovery works.
+func (t *translator) writeIPv4Header(b []byte, tos byte, totalLen int, ttl, proto byte, src, dst netip.Addr) {
+	b[0] = 0x45
+	b[1] = tos
+	binary.BigEndian.PutUint16(b[2:4], uint16(totalLen))
+	if totalLen <= minIPv6MTU-(ipv6HeaderLen-ipv4HeaderLen) {
+		binary.BigEndian.PutUint16(b[4:6], uint16(t.ipID.Add(1)))
+		binary.BigEndian.PutUint16(b[6:8], 0)
+	} else {
+		binary.BigEndian.PutUint16(b[4:6], 0)
+		binary.BigEndian.PutUint16(b[6:8], 0x4000) // DF
+	}
+	b[8] = ttl
+	b[9] = proto
+	b[10], b[11] = 0, 0
+	copy(b[12:20], addrs4(src, dst))
+	binary.BigEndian.PutUint16(b[10:12], checksum(b[:ipv4HeaderLen], 0))
+}
+
+// writeIPv6Header fills in b[:40]. The flow label is left zero.
+func writeIPv6Header(b []byte, tc byte, payloadLen int, next, hop byte, src, dst netip.Addr) {
+	b[0] = 0x60 | tc>>4
+	b[1] = tc << 4
+	b[2], b[3] = 0, 0
+	binary.BigEndian.PutUint16(b[4:6], uint16(payloadLen))
+	b[6] = next
+	b[7] = hop
+	copy(b[8:40], addrs6(src, dst))
+}
+
+// trafficClass returns the traffic class of an IPv6 header.
+func trafficClass(p []byte) byte {
+	return p[0]<<4 | p[1]>>4
+}
+
+// isICMPError reports whether an ICMP type is an error the translator
+// passes on.
+func isICMPError(typ byte) bool {
+	return typ == 3 || typ == 11
+}
+
+func echoType6To4(typ byte) byte {
+	switch typ {
+	case 128:
+		return 8
+	case 129:
+		return 0
+	}
+	return typ
+}
+
+func echoType4To6(typ byte) byte {
+	switch typ {
+	case 8:
+		return 128
+	case 0:
+		return 129
+	}
+	return typ
+}
+
+// l4HeaderComplete reports whether seg holds a whole TCP or UDP header.
+func l4HeaderComplete(seg []byte, proto byte) bool {
+	if proto == protoTCP {
+		return len(seg) >= 20
+	}
+	return len(seg) >= 8
+}
+
+// adjustL4Checksum updates a TCP or UDP checksum for the addresses of the
+// new pseudo-header. The length and protocol words sum the same in both
+// families, so only the addresses change. Truncated segments, quoted in
+// ICMP errors, are fixed as far as they go.
+func adjustL4Checksum(seg []byte, proto byte, oldAddrs, newAddrs []byte) {
+	off := 6
+	if proto == protoTCP {
+		off = 16
+	}
+	if len(seg) < off+2 {
+		return
+	}
+	c := binary.BigEndian.Uint16(seg[off : off+2])
+	if proto == protoUDP && c == 0 {
+		return
+	}
+	c = adjustChecksum(c, oldAddrs, newAddrs)
+	if proto == protoUDP {
+		c = udpChecksum(c)
+	}
+	binary.BigEndian.PutUint16(seg[off:off+2], c)
+}
+
+// udpChecksum maps a computed zero to all ones, as zero means no checksum.
+func udpChecksum(c uint16) uint16 {
+	if c == 0 {
+		return 0xffff
+	}
+	return c
+}
+
+// checksum returns the Internet checksum of b, starting from a partial sum.
+func checksum(b []byte, initial uint32) uint16 {
+	return ^csumFold(csumAdd(initial, b))
+}
+
+// adjustChecksum updates csum for covered bytes old replaced by new
+// (RFC 1624).
+func adjustChecksum(csum uint16, old, new []byte) uint16 {
+	s := uint32(^csum) + uint32(^csumFold(csumAdd(0, old)))
+	return ^csumFold(csumAdd(s, new))
+}
+
+// pseudo6 returns the partial sum of an IPv6 pseudo-header.
+func pseudo6(src, dst netip.Addr, length int, next byte) uint32 {
+	s := csumAdd(0, addrs6(src, dst))
+	return s + uint32(length>>16) + uint32(length&0xffff) + uint32(next)
+}
+
+// csumAdd adds b to a ones' complement sum as big-endian 16-bit words.
+func csumAdd(s uint32, b []byte) uint32 {
+	for len(b) >= 2 {
+		s += uint32(b[0])<<8 | uint32(b[1])
+		b = b[2:]
+	}
+	if len(b) == 1 {
+		s += uint32(b[0]) << 8
+	}
+	return s
+}
+
+// csumFold folds the carries of a ones' complement sum into 16 bits.
+func csumFold(s uint32) uint16 {
+	for s > 0xffff {
+		s = s&0xffff + s>>16
+	}
+	return uint16(s)
+}
+
+func addr16(b []byte) netip.Addr {
+	return netip.AddrFrom16([16]byte(b))
+}
+
+func addr4(b []byte) netip.Addr {
+	return netip.AddrFrom4([4]byte(b))
+}
+
+// addrs4 returns src and dst as the 8 address bytes of an IPv4 header.
+func addrs4(src, dst netip.Addr) []byte {
+	s, d := src.As4(), dst.As4()
+	return append(s[:], d[:]...)
+}
+
+// addrs6 returns src and dst as the 32 address bytes of an IPv6 header.
+func addrs6(src, dst netip.Addr) []byte {
+	s, d := src.As16(), dst.As16()
+	return append(s[:], d[:]...)
+}
@@ -0,0 +1,333 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package nat64
+
+import (
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+var (
+	client = netip.MustParseAddr("2001:db8:1::10")
+	server = netip.MustParseAddr("198.51.100.7")
+)
+
+// TestEmbedExtract uses the examples of RFC 6052 section 2.4.
+func TestEmbedExtract(t *testing.T) {
+	v4 := netip.MustParseAddr("192.0.2.33")
+	tests := map[string]string{
+		"2001:db8::/32":         "2001:db8:c000:221::",
+		"2001:db8:100::/40":     "2001:db8:1c0:2:21::",
+		"2001:db8:122::/48":     "2001:db8:122:c000:2:2100::",
+		"2001:db8:122:300::/56": "2001:db8:122:3c0:0:221::",
+		"2001:db8:122:344::/64": "2001:db8:122:344:c0:2:2100:0",
+		"2001:db8:122:344::/96": "2001:db8:122:344::c000:221",
+		"64:ff9b::/96":          "64:ff9b::c000:221",
+	}
+	for p, want := range tests {
+		prefix := netip.MustParsePrefix(p)
+		got := Embed(prefix, v4)
+		if got != netip.MustParseAddr(want) {
+			t.Errorf("Embed(%s, %s) = %s; want %s", p, v4, got, want)
+		}
+		back, ok := Extract(prefix, got)
+		if !ok || back != v4 {
+			t.Errorf("Extract(%s, %s) = %s, %v; want %s", p, got, back, ok, v4)
+		}
+	}
+
+	if _, ok := Extract(WellKnownPrefix, client); ok {
+		t.Errorf("Extract() of an address outside the prefix should fail")
+	}
+}
+
+func TestCanEmbed(t *testing.T) {
+	custom := netip.MustParsePrefix("2001:db8:64::/96")
+	tests := []struct {
+		prefix netip.Prefix
+		addr   string
+		want   bool
+	}{
+		{WellKnownPrefix, "198.51.100.7", true},
+		{WellKnownPrefix, "10.1.2.3", false},
+		{WellKnownPrefix, "192.168.1.1", false},
+		{WellKnownPrefix, "100.64.0.1", false},
+		{WellKnownPrefix, "127.0.0.1", false},
+		{WellKnownPrefix, "224.0.0.1", false},
+		{custom, "10.1.2.3", true},
+	}
+	for _, tt := range tests {
+		if got := CanEmbed(tt.prefix, netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("CanEmbed(%s, %s) = %v; want %v", tt.prefix, tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestPool(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	p := newPool(netip.MustParsePrefix("100.127.255.0/30"), time.Hour)
+	p.now = func() time.Time { return now }
+
+	if p.self != netip.MustParseAddr("100.127.255.1") {
+		t.Fatalf("self = %s; want the first host address", p.self)
+	}
+	a, ok := p.lookup6(client, true)
+	if !ok || a != netip.MustParseAddr("100.127.255.2") {
+		t.Fatalf("lookup6() = %s, %v; want 100.127.255.2", a, ok)
+	}
+	if again, _ := p.lookup6(client, true); again != a {
+		t.Errorf("lookup6() rebound the client to %s", again)
+	}
+	if v6, ok := p.lookup4(a); !ok || v6 != client {
+		t.Errorf("lookup4(%s) = %s, %v; want %s", a, v6, ok, client)
+	}
+
+	other := netip.MustParseAddr("2001:db8:1::20")
+	if _, ok := p.lookup6(other, false); ok {
+		t.Errorf("lookup6() without allocate bound a client")
+	}
+	if _, ok := p.lookup6(other, true); ok {
+		t.Errorf("lookup6() bound a client in a full pool")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if b, ok := p.lookup6(other, true); !ok || b != a {
+		t.Errorf("lookup6() = %s, %v; want the idle binding taken over", b, ok)
+	}
+	if _, ok := p.lookup6(client, false); ok {
+		t.Errorf("the idle client kept its binding")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if n := p.expire(); n != 1 || len(p.bindings()) != 0 {
+		t.Errorf("expire() = %d, %d left; want all bindings dropped", n, len(p.bindings()))
+	}
+}
+
+func newTestTranslator() *translator {
+	return newTranslator(WellKnownPrefix, newPool(netip.MustParsePrefix("100.127.255.0/24"), time.Hour))
+}
+
+// serialize builds a packet with lengths and checksums filled in.
+func serialize(t *testing.T, ls ...gopacket.SerializableLayer) []byte {
+	t.Helper()
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, ls...); err != nil {
+		t.Fatalf("serialize: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func ipv6Layer(src, dst netip.Addr, next layers.IPProtocol) *layers.IPv6 {
+	return &layers.IPv6{Version: 6, HopLimit: 64, NextHeader: next,
+		SrcIP: net.IP(src.AsSlice()), DstIP: net.IP(dst.AsSlice())}
+}
+
+func ipv4Layer(src, dst netip.Addr, proto layers.IPProtocol) *layers.IPv4 {
+	return &layers.IPv4{Version: 4, IHL: 5, TTL: 64, Protocol: proto,
+		SrcIP: net.IP(src.AsSlice()), DstIP: net.IP(dst.AsSlice())}
+}
+
+// pseudo4 returns the partial sum of an IPv4 pseudo-header.
+func pseudo4(src, dst netip.Addr, length int, proto byte) uint32 {
+	return csumAdd(0, addrs4(src, dst)) + uint32(length) + uint32(proto)
+}
+
+// checkIPv4 verifies the header and transport checksums of a translated
+// packet and returns its addresses.
+func checkIPv4(t *testing.T, p []byte) (src, dst netip.Addr) {
+	t.Helper()
+	if len(p) < ipv4HeaderLen || p[0] != 0x45 || int(binary.BigEndian.Uint16(p[2:4])) != len(p) {
+		t.Fatalf("bad IPv4 header: % x", p[:min(len(p), ipv4HeaderLen)])
+	}
+	if c := checksum(p[:ipv4HeaderLen], 0); c != 0 {
+		t.Errorf("IPv4 header checksum does not verify")
+	}
+	src, dst = addr4(p[12:16]), addr4(p[16:20])
+	initial := uint32(0)
+	if p[9] != protoICMP {
+		initial = pseudo4(src, dst, len(p)-ipv4HeaderLen, p[9])
+	}
+	if c := checksum(p[ipv4HeaderLen:], initial); c != 0 {
+		t.Errorf("protocol %d checksum does not verify", p[9])
+	}
+	return src, dst
+}
+
+// checkIPv6 verifies the transport checksum of a translated packet and
+// returns its addresses.
+func checkIPv6(t *testing.T, p []byte) (src, dst netip.Addr) {
+	t.Helper()
+	if len(p) < ipv6HeaderLen || p[0]>>4 != 6 || ipv6HeaderLen+int(binary.BigEndian.Uint16(p[4:6])) != len(p) {
+		t.Fatalf("bad IPv6 header: % x", p[:min(len(p), ipv6HeaderLen)])
+	}
+	src, dst = addr16(p[8:24]), addr16(p[24:40])
+	if c := checksum(p[ipv6HeaderLen:], pseudo6(src, dst, len(p)-ipv6HeaderLen, p[6])); c != 0 {
+		t.Errorf("next header %d checksum does not verify", p[6])
+	}
+	return src, dst
+}
+
+func TestTranslateTCP(t *testing.T) {
+	x := newTestTranslator()
+	embedded := Embed(WellKnownPrefix, server)
+
+	ip6 := ipv6Layer(client, embedded, layers.IPProtocolTCP)
+	tcp := &layers.TCP{SrcPort: 40000, DstPort: 443, Seq: 1, SYN: true, Window: 65535}
+	tcp.SetNetworkLayerForChecksum(ip6)
+	out, ok := x.toIPv4(serialize(t, ip6, tcp, gopacket.Payload("hello")))
+	if !ok {
+		t.Fatal("toIPv4() dropped a TCP SYN")
+	}
+	src, dst := checkIPv4(t, out)
+	if src != netip.MustParseAddr("100.127.255.2") || dst != server {
+		t.Errorf("translated %s -> %s; want 100.127.255.2 -> %s", src, dst, server)
+	}
+	if out[8] != 63 || out[9] != protoTCP {
+		t.Errorf("TTL %d, protocol %d; want 63, tcp", out[8], out[9])
+	}
+	if binary.BigEndian.Uint16(out[6:8]) != 0 || binary.BigEndian.Uint16(out[4:6]) == 0 {
+		t.Errorf("small packets should be fragmentable with an ID")
+	}
+
+	ip4 := ipv4Layer(server, src, layers.IPProtocolTCP)
+	reply := &layers.TCP{SrcPort: 443, DstPort: 40000, Seq: 9, Ack: 2, SYN: true, ACK: true, Window: 65535}
+	reply.SetNetworkLayerForChecksum(ip4)
+	back, ok := x.toIPv6(serialize(t, ip4, reply))
+	if !ok {
+		t.Fatal("toIPv6() dropped the SYN-ACK")
+	}
+	src6, dst6 := checkIPv6(t, back)
+	if src6 != embedded || dst6 != client {
+		t.Errorf("translated %s -> %s; want %s -> %s", src6, dst6, embedded, client)
+	}
+}
+
+func TestTranslateUDPZeroChecksum(t *testing.T) {
+	x := newTestTranslator()
+	a, _ := x.pool.lookup6(client, true)
+
+	ip4 := ipv4Layer(server, a, layers.IPProtocolUDP)
+	udp := &layers.UDP{SrcPort: 53, DstPort: 5353}
+	udp.SetNetworkLayerForChecksum(ip4)
+	pkt := serialize(t, ip4, udp, gopacket.Payload("answer"))
+	binary.BigEndian.PutUint16(pkt[ipv4HeaderLen+6:], 0)
+
+	out, ok := x.toIPv6(pkt)
+	if !ok {
+		t.Fatal("toIPv6() dropped UDP without a checksum")
+	}
+	checkIPv6(t, out)
+	if binary.BigEndian.Uint16(out[ipv6HeaderLen+6:]) == 0 {
+		t.Errorf("IPv6 UDP checksum left zero")
+	}
+}
+
+func TestTranslateEcho(t *testing.T) {
+	x := newTestTranslator()
+	embedded := Embed(WellKnownPrefix, server)
+
+	ip6 := ipv6Layer(client, embedded, layers.IPProtocolICMPv6)
+	icmp6 := &layers.ICMPv6{TypeCode: layers.CreateICMPv6TypeCode(layers.ICMPv6TypeEchoRequest, 0)}
+	icmp6.SetNetworkLayerForChecksum(ip6)
+	echo := &layers.ICMPv6Echo{Identifier: 7, SeqNumber: 1}
+	out, ok := x.toIPv4(serialize(t, ip6, icmp6, echo, gopacket.Payload("ping")))
+	if !ok {
+		t.Fatal("toIPv4() dropped an echo request")
+	}
+	src, _ := checkIPv4(t, out)
+	if out[9] != protoICMP || out[ipv4HeaderLen] != 8 {
+		t.Errorf("protocol %d type %d; want ICMP echo request", out[9], out[ipv4HeaderLen])
+	}
+
+	ip4 := ipv4Layer(server, src, layers.IPProtocolICMPv4)
+	icmp4 := &layers.ICMPv4{TypeCode: layers.CreateICMPv4TypeCode(layers.ICMPv4TypeEchoReply, 0), Id: 7, Seq: 1}
+	back, ok := x.toIPv6(serialize(t, ip4, icmp4, gopacket.Payload("ping")))
+	if !ok {
+		t.Fatal("toIPv6() dropped an echo reply")
+	}
+	checkIPv6(t, back)
+	if back[6] != protoICMPv6 || back[ipv6HeaderLen] != 129 {
+		t.Errorf("next header %d type %d; want ICMPv6 echo reply", back[6], back[ipv6HeaderLen])
+	}
+}
+
+func TestTranslatePacketTooBig(t *testing.T) {
+	x := newTestTranslator()
+	embedded := Embed(WellKnownPrefix, server)
+
+	ip6 := ipv6Layer(client, embedded, layers.IPProtocolTCP)
+	tcp := &layers.TCP{SrcPort: 40000, DstPort: 443, ACK: true, Window: 65535}
+	tcp.SetNetworkLayerForChecksum(ip6)
+	sent, ok := x.toIPv4(serialize(t, ip6, tcp, gopacket.Payload(make([]byte, 1400))))
+	if !ok {
+		t.Fatal("toIPv4() dropped the data segment")
+	}
+	if binary.BigEndian.Uint16(sent[6:8]) != 0x4000 {
+		t.Errorf("large packets should have DF set")
+	}
+	pool4 := addr4(sent[12:16])
+
+	// A router on the IPv4 path that can't be embedded reports the MTU
+	router := netip.MustParseAddr("10.9.9.9")
+	ip4 := ipv4Layer(router, pool4, layers.IPProtocolICMPv4)
+	icmp4 := &layers.ICMPv4{TypeCode: layers.CreateICMPv4TypeCode(layers.ICMPv4TypeDestinationUnreachable, layers.ICMPv4CodeFragmentationNeeded)}
+	quoted := sent[:ipv4HeaderLen+8]
+	pkt := serialize(t, ip4, icmp4, gopacket.Payload(quoted))
+	binary.BigEndian.PutUint16(pkt[ipv4HeaderLen+6:], 1400)
+	binary.BigEndian.PutUint16(pkt[ipv4HeaderLen+2:], 0)
+	binary.BigEndian.PutUint16(pkt[ipv4HeaderLen+2:], checksum(pkt[ipv4HeaderLen:], 0))
+
+	out, ok := x.toIPv6(pkt)
+	if !ok {
+		t.Fatal("toIPv6() dropped fragmentation needed")
+	}
+	src6, dst6 := checkIPv6(t, out)
+	if src6 != x.self6 || dst6 != client {
+		t.Errorf("translated %s -> %s; want %s -> %s", src6, dst6, x.self6, client)
+	}
+	m := out[ipv6HeaderLen:]
+	if m[0] != 2 || binary.BigEndian.Uint32(m[4:8]) != 1420 {
+		t.Errorf("type %d MTU %d; want packet too big with MTU 1420", m[0], binary.BigEndian.Uint32(m[4:8]))
+	}
+	inner := m[8:]
+	if addr16(inner[8:24]) != client || addr16(inner[24:40]) != embedded {
+		t.Errorf("quoted packet %s -> %s; want %s -> %s", addr16(inner[8:24]), addr16(inner[24:40]), client, embedded)
+	}
+}
+
+func TestTranslateDrops(t *testing.T) {
+	x := newTestTranslator()
+
+	// The well-known prefix never reaches private networks
+	ip6 := ipv6Layer(client, Embed(WellKnownPrefix, netip.MustParseAddr("192.168.1.10")), layers.IPProtocolUDP)
+	udp := &layers.UDP{SrcPort: 40000, DstPort: 53}
+	udp.SetNetworkLayerForChecksum(ip6)
+	if _, ok := x.toIPv4(serialize(t, ip6, udp)); ok {
+		t.Errorf("toIPv4() translated a packet to a private address")
+	}
+
+	// Nothing is bound to the destination
+	ip4 := ipv4Layer(server, netip.MustParseAddr("100.127.255.99"), layers.IPProtocolUDP)
+	reply := &layers.UDP{SrcPort: 53, DstPort: 40000}
+	reply.SetNetworkLayerForChecksum(ip4)
+	if _, ok := x.toIPv6(serialize(t, ip4, reply)); ok {
+		t.Errorf("toIPv6() translated a packet to an unbound address")
+	}
+
+	// Fragments
+	a, _ := x.pool.lookup6(client, true)
+	ip4 = ipv4Layer(server, a, layers.IPProtocolUDP)
+	ip4.Flags = layers.IPv4MoreFragments
+	reply.SetNetworkLayerForChecksum(ip4)
+	if _, ok := x.toIPv6(serialize(t, ip4, reply)); ok {
+		t.Errorf("toIPv6() translated a fragment")
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+//go:build linux
+// +build linux
+
+package nat64
+
+import (
+	"io"
+	"net"
+	"net/netip"
+
+	"github.com/vishvananda/netlink"
+
+	"grimm.is/flywall/internal/config"
+)
+
+// openTUN creates the translator's TUN device and routes the NAT64 prefix
+// and the IPv4 pool into it. The device is not persistent: closing it
+// removes the interface and its routes.
+func openTUN(cfg *config.NAT64Config, prefix, pool netip.Prefix) (io.ReadWriteCloser, error) {
+	tun := &netlink.Tuntap{
+		LinkAttrs:  netlink.LinkAttrs{Name: cfg.Interface},
+		Mode:       netlink.TUNTAP_MODE_TUN,
+		Flags:      netlink.TUNTAP_NO_PI,
+		Queues:     1,
+		NonPersist: true,
+	}
+	if err := netlink.LinkAdd(tun); err != nil {
+		return nil, err
+	}
+	dev := tun.Fds[0]
+
+	link, err := netlink.LinkByName(cfg.Interface)
+	if err == nil {
+		err = netlink.LinkSetUp(link)
+	}
+	for _, dst := range []netip.Prefix{prefix, pool} {
+		if err != nil {
+			break
+		}
+		err = netlink.RouteReplace(&netlink.Route{
+			LinkIndex: link.Attrs().Index,
+			Dst:       &net.IPNet{IP: dst.Addr().AsSlice(), Mask: net.CIDRMask(dst.Bits(), dst.Addr().BitLen())},
+			Scope:     netlink.SCOPE_LINK,
+		})
+	}
+	if err != nil {
+		dev.Close()
+		return nil, err
+	}
+	return dev, nil
+}
@@ -0,0 +1,19 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+//go:build !linux
+// +build !linux
+
+package nat64
+
+import (
+	"io"
+	"net/netip"
+
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/errors"
+)
+
+// openTUN is not supported on this platform.
+func openTUN(cfg *config.NAT64Config, prefix, pool netip.Prefix) (io.ReadWriteCloser, error) {
+	return nil, errors.New(errors.KindUnavailable, "NAT64 requires Linux")
+}
@@ -0,0 +1,240 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package portal
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"grimm.is/flywall/internal/config"
+)
+
+// Password and voucher attempts allowed per client address.
+const (
+	loginAttempts = 5
+	loginWindow   = time.Minute
+)
+
+// pageData fills the portal page template.
+type pageData struct {
+	Title    string
+	Terms    string
+	Auth     string
+	Next     string
+	Error    string
+	LoggedIn bool
+	Expires  time.Time
+}
+
+var pageTmpl = template.Must(template.New("portal").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; background: #f4f5f7; margin: 0; }
+main { max-width: 26rem; margin: 4rem auto; background: #fff; padding: 2rem; border-radius: 8px; box-shadow: 0 1px 4px rgba(0,0,0,.1); }
+h1 { font-size: 1.4rem; margin-top: 0; }
+.terms { white-space: pre-wrap; max-height: 12rem; overflow: auto; font-size: .9rem; color: #444; border: 1px solid #ddd; padding: .75rem; }
+input[type=text], input[type=password] { width: 100%; box-sizing: border-box; padding: .6rem; margin: .5rem 0 1rem; font-size: 1rem; }
+button { width: 100%; padding: .7rem; font-size: 1rem; border: 0; border-radius: 4px; background: #2563eb; color: #fff; cursor: pointer; }
+.error { color: #b91c1c; }
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Title}}</h1>
+{{if .LoggedIn}}
+<p>You are online until {{.Expires.Format "Jan 2 15:04"}}.</p>
+{{if .Next}}<p><a href="{{.Next}}">Continue</a></p>{{end}}
+{{else}}
+{{if .Terms}}<div class="terms">{{.Terms}}</div>{{end}}
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post" action="/login">
+<input type="hidden" name="next" value="{{.Next}}">
+{{if eq .Auth "password"}}<label>Password<input type="password" name="password" autofocus></label>{{end}}
+{{if eq .Auth "voucher"}}<label>Voucher code<input type="text" name="voucher" autocomplete="off" autocapitalize="characters" autofocus></label>{{end}}
+<button type="submit">{{if .Terms}}Accept and connect{{else}}Connect{{end}}</button>
+</form>
+{{end}}
+</main>
+</body>
+</html>
+`))
+
+// captiveStatus is the RFC 8908 captive portal API response.
+type captiveStatus struct {
+	Captive          bool   `json:"captive"`
+	UserPortalURL    string `json:"user-portal-url,omitempty"`
+	SecondsRemaining int64  `json:"seconds-remaining,omitempty"`
+	CanExtendSession bool   `json:"can-extend-session"`
+}
+
+func (s *Service) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/captive", s.handleCaptiveAPI)
+	mux.HandleFunc("POST /login", s.handleLogin)
+	mux.HandleFunc("/", s.handlePage)
+	return mux
+}
+
+// portalURL is the portal's address as seen by the client: the local
+// address its connection arrived on, whatever host it asked for.
+func portalURL(r *http.Request, port int) string {
+	host := "localhost"
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		if h, _, err := net.SplitHostPort(addr.String()); err == nil {
+			host = h
+		}
+	}
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(port)) + "/"
+}
+
+// clientMAC identifies the client by its neighbor entry.
+func (s *Service) clientMAC(r *http.Request) (string, net.IP) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "", nil
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return "", nil
+	}
+	return normalizeMAC(s.neighbor(ip)), ip
+}
+
+// handlePage shows the login page. Requests redirected from other sites
+// are sent to the portal's own address first, keeping the page they asked
+// for to return to.
+func (s *Service) handlePage(w http.ResponseWriter, r *http.Request) {
+	p := s.settings()
+	if p == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	base := portalURL(r, p.Port)
+	if u, err := url.Parse(base); err == nil && r.Host != u.Host {
+		orig := (&url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}).String()
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, r, base+"?next="+url.QueryEscape(orig), http.StatusFound)
+		return
+	}
+
+	data := pageData{Title: p.Title, Terms: p.Terms, Auth: p.Auth, Next: safeNext(r.URL.Query().Get("next"))}
+	if mac, _ := s.clientMAC(r); mac != "" {
+		if sess, ok := s.Session(mac); ok {
+			data.LoggedIn, data.Expires = true, sess.Expires
+		}
+	}
+	s.render(w, http.StatusOK, data)
+}
+
+// handleLogin checks the submitted credential and lets the client through.
+func (s *Service) handleLogin(w http.ResponseWriter, r *http.Request) {
+	p := s.settings()
+	if p == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	next := safeNext(r.FormValue("next"))
+	data := pageData{Title: p.Title, Terms: p.Terms, Auth: p.Auth, Next: next}
+
+	mac, ip := s.clientMAC(r)
+	if mac == "" {
+		data.Error = "Your device could not be identified. Reconnect to the network and try again."
+		s.render(w, http.StatusBadRequest, data)
+		return
+	}
+
+	now := s.clock.Now()
+	sess := Session{MAC: mac, IP: ip.String(), Method: p.Auth, Started: now, Expires: now.Add(p.SessionDuration())}
+
+	switch p.Auth {
+	case config.PortalAuthPassword, config.PortalAuthVoucher:
+		if !s.limiter.Allow("login:"+ip.String(), loginAttempts, loginWindow) {
+			data.Error = "Too many attempts. Wait a minute and try again."
+			s.render(w, http.StatusTooManyRequests, data)
+			return
+		}
+		if p.Auth == config.PortalAuthPassword {
+			if subtle.ConstantTimeCompare([]byte(r.FormValue("password")), []byte(string(p.Password))) != 1 {
+				data.Error = "Wrong password."
+				s.render(w, http.StatusForbidden, data)
+				return
+			}
+			break
+		}
+		code, expires, err := s.redeemVoucher(r.FormValue("voucher"), mac)
+		if err != nil {
+			data.Error = "That voucher is not valid or has expired."
+			s.render(w, http.StatusForbidden, data)
+			return
+		}
+		sess.Voucher, sess.Expires = code, expires
+	}
+
+	if err := s.Authorize(sess); err != nil {
+		s.logger.Error("Failed to authorize portal client", "mac", mac, "error", err)
+		data.Error = "Something went wrong. Please try again."
+		s.render(w, http.StatusInternalServerError, data)
+		return
+	}
+
+	switch {
+	case p.RedirectURL != "":
+		http.Redirect(w, r, p.RedirectURL, http.StatusSeeOther)
+	case next != "":
+		http.Redirect(w, r, next, http.StatusSeeOther)
+	default:
+		data.LoggedIn, data.Expires = true, sess.Expires
+		s.render(w, http.StatusOK, data)
+	}
+}
+
+// handleCaptiveAPI answers RFC 8908 captive portal API queries.
+func (s *Service) handleCaptiveAPI(w http.ResponseWriter, r *http.Request) {
+	p := s.settings()
+	if p == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	st := captiveStatus{Captive: true, UserPortalURL: portalURL(r, p.Port)}
+	if mac, _ := s.clientMAC(r); mac != "" {
+		if sess, ok := s.Session(mac); ok {
+			st.Captive = false
+			st.SecondsRemaining = int64(sess.Expires.Sub(s.clock.Now()) / time.Second)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/captive+json")
+	w.Header().Set("Cache-Control", "private")
+	json.NewEncoder(w).Encode(st)
+}
+
+func (s *Service) render(w http.ResponseWriter, status int, data pageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := pageTmpl.Execute(w, data); err != nil {
+		s.logger.Warn("Failed to render portal page", "error", err)
+	}
+}
+
+// safeNext accepts only absolute http(s) URLs to return to after login.
+func safeNext(next string) string {
+	u, err := url.Parse(next)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return u.String()
+}
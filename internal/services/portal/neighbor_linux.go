@@ -0,0 +1,32 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+//go:build linux
+// +build linux
+
+package portal
+
+import (
+	"net"
+
+	"github.com/vishvananda/netlink"
+)
+
+// lookupMAC returns the MAC of a directly connected client from the
+// kernel's neighbor table, or "" if it has no entry.
+func lookupMAC(ip net.IP) string {
+	family := netlink.FAMILY_V6
+	if ip.To4() != nil {
+		family = netlink.FAMILY_V4
+	}
+	neighs, err := netlink.NeighList(0, family)
+	if err != nil {
+		return ""
+	}
+	for _, n := range neighs {
+		if n.IP.Equal(ip) && len(n.HardwareAddr) == 6 &&
+			n.State&(netlink.NUD_INCOMPLETE|netlink.NUD_FAILED) == 0 {
+			return n.HardwareAddr.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,13 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+//go:build !linux
+// +build !linux
+
+package portal
+
+import "net"
+
+// lookupMAC is not supported on this platform.
+func lookupMAC(ip net.IP) string {
+	return ""
+}
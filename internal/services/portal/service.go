@@ -0,0 +1,308 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+// Package portal serves the captive portal for zones with services
+// { captive_portal = true }: the login page, the RFC 8908 captive portal
+// API, and the set of authorized client MACs the firewall lets through.
+package portal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"grimm.is/flywall/internal/clock"
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/errors"
+	"grimm.is/flywall/internal/firewall"
+	"grimm.is/flywall/internal/logging"
+	"grimm.is/flywall/internal/ratelimit"
+	"grimm.is/flywall/internal/services"
+	"grimm.is/flywall/internal/state"
+)
+
+// State buckets. Both replicate to an HA peer with the rest of the store.
+const (
+	BucketSessions = "portal_sessions"
+	BucketVouchers = "portal_vouchers"
+)
+
+// syncInterval is how often expired sessions are dropped and the
+// authorized set is refilled from the store.
+const syncInterval = 30 * time.Second
+
+// SetUpdater replaces the contents of an nftables set.
+type SetUpdater interface {
+	ReloadSet(name string, elements []string) error
+}
+
+// Session is a client allowed through the portal.
+type Session struct {
+	MAC     string    `json:"mac"`
+	IP      string    `json:"ip,omitempty"`
+	Method  string    `json:"method"` // click_through, password or voucher
+	Voucher string    `json:"voucher,omitempty"`
+	Started time.Time `json:"started"`
+	Expires time.Time `json:"expires"`
+}
+
+// Service runs the captive portal.
+type Service struct {
+	store   state.Store
+	sets    SetUpdater
+	logger  *logging.Logger
+	clock   clock.Clock
+	limiter *ratelimit.Limiter
+
+	// neighbor resolves a client address to its MAC
+	neighbor func(ip net.IP) string
+
+	voucherMu sync.Mutex // Serializes redemption
+
+	mu      sync.RWMutex
+	cfg     *config.CaptivePortalConfig // nil while no zone uses the portal
+	server  *http.Server
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running bool
+	lastErr error
+}
+
+// NewService creates the portal service. sets receives the authorized
+// client MACs; the firewall declares the set when a zone uses the portal.
+func NewService(store state.Store, sets SetUpdater) *Service {
+	s := &Service{
+		store:    store,
+		sets:     sets,
+		logger:   logging.WithComponent("portal"),
+		clock:    &clock.RealClock{},
+		limiter:  ratelimit.NewLimiter(),
+		neighbor: lookupMAC,
+	}
+	for _, bucket := range []string{BucketSessions, BucketVouchers} {
+		if err := store.CreateBucket(bucket); err != nil && err != state.ErrBucketExists {
+			s.logger.Error("Failed to create portal bucket", "bucket", bucket, "error", err)
+		}
+	}
+	return s
+}
+
+// Name returns the service name.
+func (s *Service) Name() string { return "portal" }
+
+// Start begins expiring sessions and serves the portal if a zone uses it.
+func (s *Service) Start(ctx context.Context) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.running = true
+	err := s.listen()
+	s.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(syncInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				s.Sync()
+			}
+		}
+	}()
+	s.Sync()
+	return err
+}
+
+// Stop shuts the portal down. Authorized clients stay in the firewall set
+// until their sessions expire.
+func (s *Service) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		return nil
+	}
+	s.cancel()
+	s.running = false
+	return s.shutdown(ctx)
+}
+
+// Reload applies a new configuration and refills the authorized set, which
+// a firewall reload recreates empty. The listener restarts if the port
+// changed.
+func (s *Service) Reload(cfg *config.Config) (bool, error) {
+	settings := cfg.PortalSettings()
+
+	s.mu.Lock()
+	restart := (s.cfg == nil) != (settings == nil) ||
+		(s.cfg != nil && settings != nil && s.cfg.Port != settings.Port)
+	s.cfg = settings
+	var err error
+	if restart && s.running {
+		if err = s.shutdown(context.Background()); err == nil {
+			err = s.listen()
+		}
+	}
+	s.mu.Unlock()
+
+	s.Sync()
+	return restart, err
+}
+
+// Status reports whether the portal is serving.
+func (s *Service) Status() services.ServiceStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	st := services.ServiceStatus{Name: s.Name(), Running: s.server != nil}
+	if s.lastErr != nil {
+		st.Error = s.lastErr.Error()
+	}
+	return st
+}
+
+// listen starts the portal's HTTP server. Caller must hold s.mu.
+func (s *Service) listen() error {
+	s.lastErr = nil
+	if s.cfg == nil {
+		return nil
+	}
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", s.cfg.Port))
+	if err != nil {
+		s.lastErr = err
+		return errors.Wrapf(err, errors.KindUnavailable, "failed to listen on portal port %d", s.cfg.Port)
+	}
+	s.server = &http.Server{
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	s.logger.Info("Captive portal listening", "port", s.cfg.Port, "auth", s.cfg.Auth)
+	go func(srv *http.Server) {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("Captive portal server failed", "error", err)
+		}
+	}(s.server)
+	return nil
+}
+
+// shutdown stops the HTTP server. Caller must hold s.mu.
+func (s *Service) shutdown(ctx context.Context) error {
+	if s.server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err := s.server.Shutdown(ctx)
+	s.server = nil
+	return err
+}
+
+// settings returns the current portal configuration, or nil.
+func (s *Service) settings() *config.CaptivePortalConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+// Sessions lists the clients currently allowed through, oldest first.
+func (s *Service) Sessions() []Session {
+	now := s.clock.Now()
+	var res []Session
+	for _, sess := range s.loadSessions() {
+		if sess.Expires.After(now) {
+			res = append(res, sess)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Started.Before(res[j].Started) })
+	return res
+}
+
+// Session returns the active session of a client, if any.
+func (s *Service) Session(mac string) (Session, bool) {
+	var sess Session
+	if err := s.store.GetJSON(BucketSessions, normalizeMAC(mac), &sess); err != nil {
+		return Session{}, false
+	}
+	return sess, sess.Expires.After(s.clock.Now())
+}
+
+// Authorize lets a client through until sess.Expires.
+func (s *Service) Authorize(sess Session) error {
+	sess.MAC = normalizeMAC(sess.MAC)
+	if err := s.store.SetJSON(BucketSessions, sess.MAC, sess); err != nil {
+		return errors.Wrap(err, errors.KindInternal, "failed to save portal session")
+	}
+	s.logger.Info("Client logged in to captive portal", "mac", sess.MAC, "ip", sess.IP,
+		"method", sess.Method, "expires", sess.Expires.Format(time.RFC3339))
+	s.Sync()
+	return nil
+}
+
+// Revoke ends a client's session. It has to log in again.
+func (s *Service) Revoke(mac string) error {
+	mac = normalizeMAC(mac)
+	if _, ok := s.Session(mac); !ok {
+		return errors.Errorf(errors.KindNotFound, "no portal session for %s", mac)
+	}
+	if err := s.store.Delete(BucketSessions, mac); err != nil {
+		return errors.Wrap(err, errors.KindInternal, "failed to delete portal session")
+	}
+	s.logger.Info("Captive portal session revoked", "mac", mac)
+	s.Sync()
+	return nil
+}
+
+// Sync drops expired sessions and refills the authorized set. Each element
+// carries its remaining time, so the kernel expires clients on schedule
+// even between passes.
+func (s *Service) Sync() {
+	if s.settings() == nil {
+		return
+	}
+	now := s.clock.Now()
+
+	var elems []string
+	for _, sess := range s.loadSessions() {
+		if !sess.Expires.After(now) {
+			if err := s.store.Delete(BucketSessions, sess.MAC); err != nil {
+				s.logger.Warn("Failed to delete expired portal session", "mac", sess.MAC, "error", err)
+			}
+			continue
+		}
+		elems = append(elems, firewall.PortalAuthElement(sess.MAC, sess.Expires.Sub(now)))
+	}
+	sort.Strings(elems)
+	if err := s.sets.ReloadSet(firewall.PortalAuthSet, elems); err != nil {
+		s.logger.Warn("Failed to update portal set", "count", len(elems), "error", err)
+	}
+}
+
+func (s *Service) loadSessions() []Session {
+	entries, err := s.store.List(BucketSessions)
+	if err != nil {
+		s.logger.Warn("Failed to load portal sessions", "error", err)
+		return nil
+	}
+	res := make([]Session, 0, len(entries))
+	for _, data := range entries {
+		var sess Session
+		if err := json.Unmarshal(data, &sess); err == nil {
+			res = append(res, sess)
+		}
+	}
+	return res
+}
+
+func normalizeMAC(mac string) string {
+	if hw, err := net.ParseMAC(mac); err == nil {
+		return hw.String()
+	}
+	return mac
+}
@@ -0,0 +1,258 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package portal
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"grimm.is/flywall/internal/clock"
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/firewall"
+	"grimm.is/flywall/internal/state"
+)
+
+const clientMAC = "aa:bb:cc:dd:ee:01"
+
+type fakeSets map[string][]string
+
+func (f fakeSets) ReloadSet(name string, elements []string) error {
+	f[name] = elements
+	return nil
+}
+
+var testNow = time.Date(2026, 3, 4, 15, 30, 0, 0, time.UTC)
+
+func newTestService(t *testing.T, portal *config.CaptivePortalConfig) (*Service, fakeSets, *clock.MockClock) {
+	t.Helper()
+	store, err := state.NewSQLiteStore(state.DefaultOptions(":memory:"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	sets := make(fakeSets)
+	s := NewService(store, sets)
+	clk := clock.NewMockClock(testNow)
+	s.clock = clk
+	s.neighbor = func(net.IP) string { return clientMAC }
+
+	cfg := &config.Config{
+		Zones:         []config.Zone{{Name: "guest", Services: &config.ZoneServices{CaptivePortal: true}}},
+		CaptivePortal: portal,
+	}
+	if _, err := s.Reload(cfg); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	return s, sets, clk
+}
+
+// noRedirect keeps the client on the response the portal sent.
+var noRedirect = &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+func login(t *testing.T, srv *httptest.Server, form url.Values) *http.Response {
+	t.Helper()
+	resp, err := noRedirect.PostForm(srv.URL+"/login", form)
+	if err != nil {
+		t.Fatalf("POST /login: %v", err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestClickThrough(t *testing.T) {
+	s, sets, clk := newTestService(t, nil)
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	resp := login(t, srv, url.Values{"next": {"http://example.com/news"}})
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "http://example.com/news" {
+		t.Fatalf("login = %d to %q; want redirect back to the requested page", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	want := []string{firewall.PortalAuthElement(clientMAC, config.DefaultPortalSessionTimeout)}
+	if got := sets[firewall.PortalAuthSet]; !reflect.DeepEqual(got, want) {
+		t.Errorf("portal set = %v; want %v", got, want)
+	}
+	if sessions := s.Sessions(); len(sessions) != 1 || sessions[0].Method != config.PortalAuthClickThrough {
+		t.Errorf("Sessions() = %+v", sessions)
+	}
+
+	// The session ends on schedule
+	clk.Advance(config.DefaultPortalSessionTimeout)
+	s.Sync()
+	if got := sets[firewall.PortalAuthSet]; len(got) != 0 {
+		t.Errorf("portal set after expiry = %v; want empty", got)
+	}
+	if sessions := s.Sessions(); len(sessions) != 0 {
+		t.Errorf("Sessions() after expiry = %+v", sessions)
+	}
+}
+
+func TestPasswordLogin(t *testing.T) {
+	s, sets, _ := newTestService(t, &config.CaptivePortalConfig{
+		Auth: config.PortalAuthPassword, Password: "letmein", SessionTimeout: "2h",
+	})
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	if resp := login(t, srv, url.Values{"password": {"guess"}}); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("wrong password = %d; want %d", resp.StatusCode, http.StatusForbidden)
+	}
+	if len(sets[firewall.PortalAuthSet]) != 0 {
+		t.Fatal("client authorized with a wrong password")
+	}
+
+	if resp := login(t, srv, url.Values{"password": {"letmein"}}); resp.StatusCode != http.StatusOK {
+		t.Errorf("right password = %d; want %d", resp.StatusCode, http.StatusOK)
+	}
+	want := []string{firewall.PortalAuthElement(clientMAC, 2*time.Hour)}
+	if got := sets[firewall.PortalAuthSet]; !reflect.DeepEqual(got, want) {
+		t.Errorf("portal set = %v; want %v", got, want)
+	}
+
+	// Attempts are rate limited
+	for i := 0; i < loginAttempts; i++ {
+		login(t, srv, url.Values{"password": {"guess"}})
+	}
+	if resp := login(t, srv, url.Values{"password": {"letmein"}}); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("login after %d attempts = %d; want %d", loginAttempts+2, resp.StatusCode, http.StatusTooManyRequests)
+	}
+}
+
+func TestVoucherLogin(t *testing.T) {
+	s, sets, clk := newTestService(t, &config.CaptivePortalConfig{Auth: config.PortalAuthVoucher})
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	vouchers, err := s.CreateVouchers(2, "1h", "lobby")
+	if err != nil {
+		t.Fatalf("CreateVouchers() error = %v", err)
+	}
+	if len(vouchers) != 2 || vouchers[0].Code == vouchers[1].Code || len(vouchers[0].Code) != voucherLength+1 {
+		t.Fatalf("CreateVouchers() = %+v", vouchers)
+	}
+	code := vouchers[0].Code
+
+	// Codes are accepted in any case and without the separator
+	clk.Advance(10 * time.Minute)
+	typed := strings.ToLower(strings.ReplaceAll(code, "-", " "))
+	if resp := login(t, srv, url.Values{"voucher": {typed}}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("voucher login = %d; want %d", resp.StatusCode, http.StatusOK)
+	}
+	want := []string{firewall.PortalAuthElement(clientMAC, time.Hour)}
+	if got := sets[firewall.PortalAuthSet]; !reflect.DeepEqual(got, want) {
+		t.Errorf("portal set = %v; want %v", got, want)
+	}
+
+	// The same device may log in again, keeping the original expiry
+	clk.Advance(30 * time.Minute)
+	if resp := login(t, srv, url.Values{"voucher": {code}}); resp.StatusCode != http.StatusOK {
+		t.Errorf("repeat voucher login = %d; want %d", resp.StatusCode, http.StatusOK)
+	}
+	if sess, ok := s.Session(clientMAC); !ok || !sess.Expires.Equal(testNow.Add(70*time.Minute)) || sess.Voucher != code {
+		t.Errorf("Session() = %+v, %v", sess, ok)
+	}
+
+	// Another device may not
+	s.neighbor = func(net.IP) string { return "aa:bb:cc:dd:ee:02" }
+	if resp := login(t, srv, url.Values{"voucher": {code}}); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("voucher on second device = %d; want %d", resp.StatusCode, http.StatusForbidden)
+	}
+
+	// Withdrawing the voucher ends its session
+	if err := s.DeleteVoucher(code); err != nil {
+		t.Fatalf("DeleteVoucher() error = %v", err)
+	}
+	if _, ok := s.Session(clientMAC); ok {
+		t.Error("session survived deleting its voucher")
+	}
+	if got := s.Vouchers(); len(got) != 1 || got[0].Code != vouchers[1].Code {
+		t.Errorf("Vouchers() = %+v", got)
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	s, sets, _ := newTestService(t, nil)
+	if err := s.Authorize(Session{MAC: "AA-BB-CC-DD-EE-01", Method: config.PortalAuthClickThrough,
+		Started: testNow, Expires: testNow.Add(time.Hour)}); err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+	if err := s.Revoke(clientMAC); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if len(sets[firewall.PortalAuthSet]) != 0 {
+		t.Errorf("portal set after revoke = %v", sets[firewall.PortalAuthSet])
+	}
+	if err := s.Revoke(clientMAC); err == nil {
+		t.Error("Revoke() of a missing session should fail")
+	}
+}
+
+func TestRedirectAndCaptiveAPI(t *testing.T) {
+	s, _, _ := newTestService(t, nil)
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	// Intercepted requests carry the host the client asked for
+	req, _ := http.NewRequest("GET", srv.URL+"/hotspot-detect.html", nil)
+	req.Host = "captive.apple.com"
+	resp, err := noRedirect.Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	loc := resp.Header.Get("Location")
+	wantLoc := "http://127.0.0.1:8880/?next=" + url.QueryEscape("http://captive.apple.com/hotspot-detect.html")
+	if resp.StatusCode != http.StatusFound || loc != wantLoc {
+		t.Errorf("intercepted request = %d to %q; want redirect to %q", resp.StatusCode, loc, wantLoc)
+	}
+
+	captive := func() captiveStatus {
+		resp, err := http.Get(srv.URL + "/api/captive")
+		if err != nil {
+			t.Fatalf("GET /api/captive: %v", err)
+		}
+		defer resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); ct != "application/captive+json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		var st captiveStatus
+		if err := json.Unmarshal(body, &st); err != nil {
+			t.Fatalf("Bad captive API response %s: %v", body, err)
+		}
+		return st
+	}
+
+	if st := captive(); !st.Captive || st.UserPortalURL != "http://127.0.0.1:8880/" {
+		t.Errorf("captive API before login = %+v", st)
+	}
+	login(t, srv, nil)
+	if st := captive(); st.Captive || st.SecondsRemaining != int64(config.DefaultPortalSessionTimeout/time.Second) {
+		t.Errorf("captive API after login = %+v", st)
+	}
+}
+
+func TestSafeNext(t *testing.T) {
+	tests := map[string]string{
+		"http://example.com/a?b=c": "http://example.com/a?b=c",
+		"https://example.com":      "https://example.com",
+		"/local":                   "",
+		"javascript:alert(1)":      "",
+		"":                         "",
+	}
+	for in, want := range tests {
+		if got := safeNext(in); got != want {
+			t.Errorf("safeNext(%q) = %q; want %q", in, got, want)
+		}
+	}
+}
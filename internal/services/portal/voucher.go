@@ -0,0 +1,170 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package portal
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"grimm.is/flywall/internal/errors"
+)
+
+// voucherAlphabet leaves out characters that are easy to misread (0/O, 1/I/L).
+const voucherAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// voucherLength is the number of code characters, printed in two groups.
+const voucherLength = 10
+
+// maxVouchers caps how many vouchers one request may create.
+const maxVouchers = 500
+
+// Voucher is a code that gets one device online for a fixed time. The time
+// starts when the code is first redeemed; the same device may log in again
+// with it until then.
+type Voucher struct {
+	Code       string     `json:"code"`
+	Duration   string     `json:"duration"`
+	Note       string     `json:"note,omitempty"`
+	Created    time.Time  `json:"created"`
+	RedeemedBy string     `json:"redeemed_by,omitempty"`
+	Expires    *time.Time `json:"expires,omitempty"`
+}
+
+// CreateVouchers generates count vouchers that each grant duration of
+// access once redeemed.
+func (s *Service) CreateVouchers(count int, duration, note string) ([]Voucher, error) {
+	if count < 1 || count > maxVouchers {
+		return nil, errors.Errorf(errors.KindValidation, "count must be between 1 and %d", maxVouchers)
+	}
+	if d, err := time.ParseDuration(duration); err != nil || d <= 0 {
+		return nil, errors.Errorf(errors.KindValidation, "invalid duration %q", duration)
+	}
+
+	now := s.clock.Now()
+	res := make([]Voucher, 0, count)
+	for len(res) < count {
+		code, err := newVoucherCode()
+		if err != nil {
+			return nil, errors.Wrap(err, errors.KindInternal, "failed to generate voucher code")
+		}
+		if _, err := s.store.Get(BucketVouchers, code); err == nil {
+			continue // Already issued
+		}
+		v := Voucher{Code: code, Duration: duration, Note: note, Created: now}
+		if err := s.store.SetJSON(BucketVouchers, code, v); err != nil {
+			return nil, errors.Wrap(err, errors.KindInternal, "failed to save voucher")
+		}
+		res = append(res, v)
+	}
+	s.logger.Info("Created captive portal vouchers", "count", count, "duration", duration)
+	return res, nil
+}
+
+// Vouchers lists issued vouchers, newest first. Expired vouchers are kept
+// until deleted so their use can be reviewed.
+func (s *Service) Vouchers() []Voucher {
+	entries, err := s.store.List(BucketVouchers)
+	if err != nil {
+		s.logger.Warn("Failed to load vouchers", "error", err)
+		return nil
+	}
+	res := make([]Voucher, 0, len(entries))
+	for _, data := range entries {
+		var v Voucher
+		if err := json.Unmarshal(data, &v); err == nil {
+			res = append(res, v)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if !res[i].Created.Equal(res[j].Created) {
+			return res[i].Created.After(res[j].Created)
+		}
+		return res[i].Code < res[j].Code
+	})
+	return res
+}
+
+// DeleteVoucher withdraws a voucher and ends the session it granted.
+func (s *Service) DeleteVoucher(code string) error {
+	code = normalizeVoucher(code)
+	var v Voucher
+	if err := s.store.GetJSON(BucketVouchers, code, &v); err != nil {
+		return errors.Errorf(errors.KindNotFound, "voucher %q not found", code)
+	}
+	if err := s.store.Delete(BucketVouchers, code); err != nil {
+		return errors.Wrap(err, errors.KindInternal, "failed to delete voucher")
+	}
+	if sess, ok := s.Session(v.RedeemedBy); ok && sess.Voucher == code {
+		return s.Revoke(v.RedeemedBy)
+	}
+	return nil
+}
+
+// redeemVoucher checks a code for mac and starts its clock on first use.
+// It returns when the access it grants ends.
+func (s *Service) redeemVoucher(code, mac string) (string, time.Time, error) {
+	s.voucherMu.Lock()
+	defer s.voucherMu.Unlock()
+
+	code = normalizeVoucher(code)
+	invalid := errors.New(errors.KindPermission, "invalid or expired voucher")
+
+	var v Voucher
+	if err := s.store.GetJSON(BucketVouchers, code, &v); err != nil {
+		return "", time.Time{}, invalid
+	}
+	now := s.clock.Now()
+	if v.RedeemedBy != "" {
+		if v.RedeemedBy != mac || v.Expires == nil || !v.Expires.After(now) {
+			return "", time.Time{}, invalid
+		}
+		return code, *v.Expires, nil
+	}
+
+	d, err := time.ParseDuration(v.Duration)
+	if err != nil {
+		return "", time.Time{}, invalid
+	}
+	expires := now.Add(d)
+	v.RedeemedBy, v.Expires = mac, &expires
+	if err := s.store.SetJSON(BucketVouchers, code, v); err != nil {
+		return "", time.Time{}, errors.Wrap(err, errors.KindInternal, "failed to save voucher")
+	}
+	return code, expires, nil
+}
+
+func newVoucherCode() (string, error) {
+	var b strings.Builder
+	max := big.NewInt(int64(len(voucherAlphabet)))
+	for i := 0; i < voucherLength; i++ {
+		if i == voucherLength/2 {
+			b.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(voucherAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+// normalizeVoucher accepts codes typed in any case, with or without the
+// separator or spaces.
+func normalizeVoucher(code string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(code) {
+		if strings.ContainsRune(voucherAlphabet, r) {
+			b.WriteRune(r)
+		}
+	}
+	s := b.String()
+	if len(s) != voucherLength {
+		return s
+	}
+	return s[:voucherLength/2] + "-" + s[voucherLength/2:]
+}
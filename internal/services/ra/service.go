@@ -7,17 +7,36 @@ import (
 	"log"
 	"net"
 	"net/netip"
+	"reflect"
 	"sync"
 	"time"
 
 	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/services"
 
 	"github.com/mdlayher/ndp"
 )
 
+// deprecatedLifetime is how long a withdrawn prefix keeps being advertised
+// with a zero preferred lifetime, so hosts stop using it for new
+// connections straight away (RFC 7084 L-13).
+const deprecatedLifetime = 2 * time.Hour
+
+// raFlags are the RA "managed" (M) and "other config" (O) bits for an
+// interface, telling hosts whether to use the DHCPv6 server.
+type raFlags struct {
+	managed bool
+	other   bool
+}
+
 // Service provides IPv6 Router Advertisements.
 type Service struct {
 	config []config.Interface
+	flags  map[string]raFlags
+
+	// deprecated holds withdrawn prefixes per interface and when to stop
+	// advertising them.
+	deprecated map[string]map[netip.Prefix]time.Time
 
 	ctx     context.Context
 	cancel  context.CancelFunc
@@ -27,66 +46,145 @@ type Service struct {
 }
 
 func NewService(cfg *config.Config) *Service {
-	// Filter for interfaces with RA enabled
+	return &Service{
+		config:     raInterfaces(cfg),
+		flags:      dhcpv6Flags(cfg),
+		deprecated: make(map[string]map[netip.Prefix]time.Time),
+	}
+}
+
+// raInterfaces filters for interfaces with RA enabled and a prefix to
+// advertise.
+func raInterfaces(cfg *config.Config) []config.Interface {
 	var raIfaces []config.Interface
 	for _, iface := range cfg.Interfaces {
 		if iface.RA && len(iface.IPv6) > 0 {
 			raIfaces = append(raIfaces, iface)
 		}
 	}
+	return raIfaces
+}
 
-	return &Service{
-		config: raIfaces,
+// dhcpv6Flags derives the M/O bits from the built-in DHCP server's scopes:
+// a v6 range means addresses come from DHCPv6 (M), and any DHCPv6 scope
+// serves DNS and domain options (O).
+func dhcpv6Flags(cfg *config.Config) map[string]raFlags {
+	flags := make(map[string]raFlags)
+	if cfg.DHCP == nil || !cfg.DHCP.Enabled || cfg.DHCP.Mode == "external" || cfg.DHCP.Mode == "import" {
+		return flags
 	}
+	for _, scope := range cfg.DHCP.Scopes {
+		if len(scope.RelayTo) > 0 || !scope.HasDHCPv6() {
+			continue
+		}
+		flags[scope.Interface] = raFlags{
+			managed: scope.RangeStartV6 != "",
+			other:   true,
+		}
+	}
+	return flags
+}
+
+func (s *Service) Name() string {
+	return "RA"
 }
 
-func (s *Service) Start() {
+func (s *Service) Start(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if s.running {
-		return
+		return nil
 	}
 	s.running = true
-	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.ctx, s.cancel = context.WithCancel(ctx)
 
 	for _, iface := range s.config {
 		s.wg.Add(1)
 		go s.runRA(iface)
 	}
 	log.Printf("[RA] Service started on %d interfaces", len(s.config))
+	return nil
 }
 
-func (s *Service) Stop() {
+func (s *Service) Stop(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if !s.running {
-		return
+		return nil
 	}
 	s.cancel()
 	s.wg.Wait()
 	s.running = false
 	log.Printf("[RA] Service stopped")
+	return nil
 }
 
-func (s *Service) runRA(cfg config.Interface) {
-	defer s.wg.Done()
+// Reload picks up interface and prefix changes, including prefixes
+// delegated by the upstream DHCPv6 client. Prefixes that disappear are
+// advertised as deprecated for a while rather than silently dropped.
+func (s *Service) Reload(cfg *config.Config) (bool, error) {
+	next := raInterfaces(cfg)
+	nextFlags := dhcpv6Flags(cfg)
 
-	// Open NDP connection
-	ifi, err := net.InterfaceByName(cfg.Name)
-	if err != nil {
-		log.Printf("[RA] Failed to get interface %s: %v", cfg.Name, err)
-		return
+	s.mu.Lock()
+	if reflect.DeepEqual(s.config, next) && reflect.DeepEqual(s.flags, nextFlags) {
+		s.mu.Unlock()
+		return false, nil
 	}
 
-	conn, _, err := ndp.Listen(ifi, ndp.LinkLocal)
-	if err != nil {
-		log.Printf("[RA] Failed to listen on %s: %v", cfg.Name, err)
-		return
+	now := time.Now()
+	for _, old := range s.config {
+		kept := make(map[netip.Prefix]bool)
+		for _, iface := range next {
+			if iface.Name == old.Name {
+				for _, p := range advertisedPrefixes(iface) {
+					kept[p] = true
+				}
+			}
+		}
+		for _, p := range advertisedPrefixes(old) {
+			if kept[p] {
+				continue
+			}
+			if s.deprecated[old.Name] == nil {
+				s.deprecated[old.Name] = make(map[netip.Prefix]time.Time)
+			}
+			s.deprecated[old.Name][p] = now.Add(deprecatedLifetime)
+			log.Printf("[RA] Deprecating prefix %s on %s", p, old.Name)
+		}
 	}
-	defer conn.Close()
+	for _, iface := range next {
+		for _, p := range advertisedPrefixes(iface) {
+			delete(s.deprecated[iface.Name], p)
+		}
+	}
+
+	wasRunning := s.running
+	s.mu.Unlock()
+
+	if wasRunning {
+		s.Stop(context.Background())
+	}
+	s.mu.Lock()
+	s.config = next
+	s.flags = nextFlags
+	s.mu.Unlock()
+	return true, s.Start(context.Background())
+}
+
+func (s *Service) Status() services.ServiceStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return services.ServiceStatus{
+		Name:    s.Name(),
+		Running: s.running,
+	}
+}
 
-	// Parse prefixes from config
-	var prefixOpts []ndp.Option
+// advertisedPrefixes returns the on-link prefixes of an interface's
+// global IPv6 addresses.
+func advertisedPrefixes(cfg config.Interface) []netip.Prefix {
+	var prefixes []netip.Prefix
 	for _, cidr := range cfg.IPv6 {
 		prefix, err := netip.ParsePrefix(cidr)
 		if err != nil {
@@ -97,7 +195,18 @@ func (s *Service) runRA(cfg config.Interface) {
 		if prefix.Addr().IsLinkLocalUnicast() {
 			continue
 		}
-		prefixOpts = append(prefixOpts, &ndp.PrefixInformation{
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
+
+// prefixOptions builds the Prefix Information options for an interface:
+// its current prefixes, plus any still-deprecated withdrawn ones with a
+// zero preferred lifetime.
+func (s *Service) prefixOptions(cfg config.Interface, now time.Time) []ndp.Option {
+	var opts []ndp.Option
+	for _, prefix := range advertisedPrefixes(cfg) {
+		opts = append(opts, &ndp.PrefixInformation{
 			PrefixLength:                   uint8(prefix.Bits()),
 			OnLink:                         true,
 			AutonomousAddressConfiguration: true,                // Enable SLAAC
@@ -107,7 +216,44 @@ func (s *Service) runRA(cfg config.Interface) {
 		})
 	}
 
-	if len(prefixOpts) == 0 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for prefix, until := range s.deprecated[cfg.Name] {
+		remaining := until.Sub(now).Truncate(time.Second)
+		if remaining <= 0 {
+			delete(s.deprecated[cfg.Name], prefix)
+			continue
+		}
+		opts = append(opts, &ndp.PrefixInformation{
+			PrefixLength:                   uint8(prefix.Bits()),
+			OnLink:                         true,
+			AutonomousAddressConfiguration: true,
+			ValidLifetime:                  remaining,
+			PreferredLifetime:              0,
+			Prefix:                         prefix.Addr(),
+		})
+	}
+	return opts
+}
+
+func (s *Service) runRA(cfg config.Interface) {
+	defer s.wg.Done()
+
+	// Open NDP connection
+	ifi, err := net.InterfaceByName(cfg.Name)
+	if err != nil {
+		log.Printf("[RA] Failed to get interface %s: %v", cfg.Name, err)
+		return
+	}
+
+	conn, _, err := ndp.Listen(ifi, ndp.LinkLocal)
+	if err != nil {
+		log.Printf("[RA] Failed to listen on %s: %v", cfg.Name, err)
+		return
+	}
+	defer conn.Close()
+
+	if len(advertisedPrefixes(cfg)) == 0 {
 		log.Printf("[RA] No valid prefixes for %s, RA will not advertise SLAAC", cfg.Name)
 	}
 
@@ -121,8 +267,12 @@ func (s *Service) runRA(cfg config.Interface) {
 	// Start RS listener goroutine
 	go s.listenForRS(conn, cfg.Name, rsChan)
 
+	s.mu.Lock()
+	flags := s.flags[cfg.Name]
+	s.mu.Unlock()
+
 	// Send initial RA immediately
-	s.sendRA(conn, cfg.Name, prefixOpts)
+	s.sendRA(conn, cfg.Name, flags, s.prefixOptions(cfg, time.Now()))
 
 	for {
 		select {
@@ -130,10 +280,10 @@ func (s *Service) runRA(cfg config.Interface) {
 			return
 		case <-ticker.C:
 			// Periodic unsolicited RA
-			s.sendRA(conn, cfg.Name, prefixOpts)
+			s.sendRA(conn, cfg.Name, flags, s.prefixOptions(cfg, time.Now()))
 		case srcAddr := <-rsChan:
 			// Respond to Router Solicitation with unicast RA
-			s.sendRATo(conn, cfg.Name, prefixOpts, srcAddr)
+			s.sendRATo(conn, cfg.Name, flags, s.prefixOptions(cfg, time.Now()), srcAddr)
 		}
 	}
 }
@@ -168,13 +318,8 @@ func (s *Service) listenForRS(conn *ndp.Conn, ifaceName string, rsChan chan<- ne
 	}
 }
 
-func (s *Service) sendRA(conn *ndp.Conn, ifaceName string, prefixOpts []ndp.Option) {
-	ra := &ndp.RouterAdvertisement{
-		CurrentHopLimit:           64,
-		RouterSelectionPreference: ndp.Medium,
-		RouterLifetime:            1800 * time.Second, // 30 mins
-		Options:                   prefixOpts,
-	}
+func (s *Service) sendRA(conn *ndp.Conn, ifaceName string, flags raFlags, prefixOpts []ndp.Option) {
+	ra := newRA(flags, prefixOpts)
 
 	dst, _ := netip.ParseAddr("ff02::1")
 	if err := conn.WriteTo(ra, nil, dst); err != nil {
@@ -183,15 +328,21 @@ func (s *Service) sendRA(conn *ndp.Conn, ifaceName string, prefixOpts []ndp.Opti
 }
 
 // sendRATo sends a Router Advertisement to a specific address (unicast response to RS).
-func (s *Service) sendRATo(conn *ndp.Conn, ifaceName string, prefixOpts []ndp.Option, dst netip.Addr) {
-	ra := &ndp.RouterAdvertisement{
+func (s *Service) sendRATo(conn *ndp.Conn, ifaceName string, flags raFlags, prefixOpts []ndp.Option, dst netip.Addr) {
+	ra := newRA(flags, prefixOpts)
+
+	if err := conn.WriteTo(ra, nil, dst); err != nil {
+		log.Printf("[RA] Failed to send RA to %s on %s: %v", dst, ifaceName, err)
+	}
+}
+
+func newRA(flags raFlags, prefixOpts []ndp.Option) *ndp.RouterAdvertisement {
+	return &ndp.RouterAdvertisement{
 		CurrentHopLimit:           64,
+		ManagedConfiguration:      flags.managed,
+		OtherConfiguration:        flags.other,
 		RouterSelectionPreference: ndp.Medium,
 		RouterLifetime:            1800 * time.Second, // 30 mins
 		Options:                   prefixOpts,
 	}
-
-	if err := conn.WriteTo(ra, nil, dst); err != nil {
-		log.Printf("[RA] Failed to send RA to %s on %s: %v", dst, ifaceName, err)
-	}
 }
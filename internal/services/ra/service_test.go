@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"grimm.is/flywall/internal/config"
+
+	"github.com/mdlayher/ndp"
 )
 
 func TestNewService(t *testing.T) {
@@ -62,7 +64,7 @@ func TestService_Lifecycle(t *testing.T) {
 	svc := NewService(cfg)
 
 	// Start (non-blocking)
-	svc.Start()
+	svc.Start(context.Background())
 
 	// Wait a tiny bit (allow runRA to start and likely fail)
 	time.Sleep(10 * time.Millisecond)
@@ -74,7 +76,7 @@ func TestService_Lifecycle(t *testing.T) {
 	// Stop
 	done := make(chan bool)
 	go func() {
-		svc.Stop()
+		svc.Stop(context.Background())
 		done <- true
 	}()
 
@@ -95,8 +97,8 @@ func TestService_StartStop_Idempotency(t *testing.T) {
 	svc := NewService(cfg)
 
 	// Multiple Start
-	svc.Start()
-	svc.Start() // Should no-op
+	svc.Start(context.Background())
+	svc.Start(context.Background()) // Should no-op
 	if !svc.running {
 		t.Error("Service should be running")
 	}
@@ -105,8 +107,8 @@ func TestService_StartStop_Idempotency(t *testing.T) {
 	}
 
 	// Multiple Stop
-	svc.Stop()
-	svc.Stop() // Should no-op
+	svc.Stop(context.Background())
+	svc.Stop(context.Background()) // Should no-op
 	if svc.running {
 		t.Error("Service should be stopped")
 	}
@@ -116,3 +118,87 @@ func TestService_StartStop_Idempotency(t *testing.T) {
 		t.Error("Context should be canceled")
 	}
 }
+
+func TestService_Reload_DeprecatesWithdrawnPrefix(t *testing.T) {
+	svc := NewService(&config.Config{
+		Interfaces: []config.Interface{
+			{Name: "lan0", RA: true, IPv6: []string{"2001:db8:aa00:1::1/64"}},
+		},
+	})
+
+	// Upstream delegation changed: the LAN now carries a new prefix
+	changed, err := svc.Reload(&config.Config{
+		Interfaces: []config.Interface{
+			{Name: "lan0", RA: true, IPv6: []string{"2001:db8:bb00:1::1/64"}},
+		},
+	})
+	defer svc.Stop(context.Background())
+	if err != nil || !changed {
+		t.Fatalf("Reload() = %v, %v; want true, nil", changed, err)
+	}
+
+	now := time.Now()
+	opts := svc.prefixOptions(svc.config[0], now)
+	if len(opts) != 2 {
+		t.Fatalf("Expected 2 prefix options, got %d", len(opts))
+	}
+	for _, opt := range opts {
+		pi := opt.(*ndp.PrefixInformation)
+		switch pi.Prefix.String() {
+		case "2001:db8:bb00:1::":
+			if pi.PreferredLifetime == 0 {
+				t.Error("Current prefix should be preferred")
+			}
+		case "2001:db8:aa00:1::":
+			if pi.PreferredLifetime != 0 || pi.ValidLifetime <= 0 {
+				t.Errorf("Withdrawn prefix should be deprecated, got preferred=%v valid=%v", pi.PreferredLifetime, pi.ValidLifetime)
+			}
+		default:
+			t.Errorf("Unexpected prefix %s", pi.Prefix)
+		}
+	}
+
+	// Deprecated prefixes age out
+	if opts := svc.prefixOptions(svc.config[0], now.Add(deprecatedLifetime+time.Second)); len(opts) != 1 {
+		t.Errorf("Expected withdrawn prefix to expire, got %d options", len(opts))
+	}
+
+	// Same config again is a no-op
+	changed, _ = svc.Reload(&config.Config{
+		Interfaces: []config.Interface{
+			{Name: "lan0", RA: true, IPv6: []string{"2001:db8:bb00:1::1/64"}},
+		},
+	})
+	if changed {
+		t.Error("Reload with unchanged config should report no change")
+	}
+}
+
+func TestNewRA_DHCPv6Flags(t *testing.T) {
+	cfg := &config.Config{
+		DHCP: &config.DHCPServer{
+			Enabled: true,
+			Scopes: []config.DHCPScope{
+				{Name: "lan", Interface: "lan0", RangeStartV6: "2001:db8::100", RangeEndV6: "2001:db8::1ff"},
+				{Name: "iot", Interface: "iot0", DNSServersV6: []string{"2001:db8::1"}},
+				{Name: "guest", Interface: "guest0"},
+			},
+		},
+	}
+	flags := dhcpv6Flags(cfg)
+
+	if ra := newRA(flags["lan0"], nil); !ra.ManagedConfiguration || !ra.OtherConfiguration {
+		t.Error("Stateful DHCPv6 scope should set M and O")
+	}
+	if ra := newRA(flags["iot0"], nil); ra.ManagedConfiguration || !ra.OtherConfiguration {
+		t.Error("Stateless DHCPv6 scope should set only O")
+	}
+	if ra := newRA(flags["guest0"], nil); ra.ManagedConfiguration || ra.OtherConfiguration {
+		t.Error("SLAAC-only interface should set neither flag")
+	}
+
+	cfg.DHCP.Mode = "external"
+	if len(dhcpv6Flags(cfg)) != 0 {
+		t.Error("External DHCP should not set flags")
+	}
+}
@@ -53,11 +53,17 @@ func NewServer(cfg *config.Config, authStore *auth.Store) (*Server, error) {
 			return false
 		}
 		user := ctx.User()
-		_, err := authStore.Authenticate(user, password)
+		sess, err := authStore.Authenticate(user, password)
 		if err != nil {
 			fwlog.Warn(fmt.Sprintf("SSH: Auth failed for user %s: %v", user, err))
 			return false
 		}
+		// Password auth has no way to present a second factor
+		if sess.MFA != "" {
+			fwlog.Warn(fmt.Sprintf("SSH: Denied user %s: second factor required", user))
+			authStore.Logout(sess.Token)
+			return false
+		}
 		fwlog.Info(fmt.Sprintf("SSH: Authenticated user %s", user))
 		return true
 	}
@@ -36,6 +36,16 @@ type DHCPLease struct {
 	LeaseEnd   time.Time `json:"lease_end"`
 	ClientID   string    `json:"client_id,omitempty"`
 	VendorID   string    `json:"vendor_id,omitempty"`
+	DUID       string    `json:"duid,omitempty"` // DHCPv6 leases are keyed by DUID
+}
+
+// Key returns the bucket key for the lease: the MAC for DHCPv4 and
+// "duid:<hex>" for DHCPv6, so both leases of a dual-stack host coexist.
+func (l *DHCPLease) Key() string {
+	if l.DUID != "" {
+		return "duid:" + l.DUID
+	}
+	return normalizeMAC(l.MAC)
 }
 
 // DHCPBucket provides typed access to DHCP leases.
@@ -69,10 +79,10 @@ func (b *DHCPBucket) Set(lease *DHCPLease) error {
 		// Lease already expired, don't store
 		return nil
 	}
-	return b.store.SetJSONWithTTL(b.bucket, normalizeMAC(lease.MAC), lease, ttl)
+	return b.store.SetJSONWithTTL(b.bucket, lease.Key(), lease, ttl)
 }
 
-// Delete removes a lease.
+// Delete removes a lease by MAC address or Key.
 func (b *DHCPBucket) Delete(mac string) error {
 	return b.store.Delete(b.bucket, normalizeMAC(mac))
 }
@@ -91,8 +91,19 @@ type replicaConn struct {
 type primaryConn struct {
 	conn    net.Conn
 	decoder *json.Decoder
+	encoder *json.Encoder
+}
+
+// forwardableBuckets lists the buckets a replica may write through to the
+// primary. Everything else only flows primary -> replica.
+var forwardableBuckets = map[string]bool{
+	BucketDHCPLeases: true,
 }
 
+// replicaKeepalive is how often a replica pings the primary so the
+// connection isn't reaped as idle.
+const replicaKeepalive = 10 * time.Second
+
 // NewReplicator creates a new replicator.
 func NewReplicator(store *SQLiteStore, config ReplicationConfig, logger *logging.Logger) *Replicator {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -378,13 +389,12 @@ func (r *Replicator) handleReplica(conn net.Conn) {
 	}
 	r.mu.Unlock()
 
-	// Keep connection alive and handle disconnects
+	// Keep connection alive, accept forwarded writes and handle disconnects
 	go func() {
-		buf := make([]byte, 1)
 		for {
 			conn.SetReadDeadline(clock.Now().Add(30 * time.Second))
-			_, err := conn.Read(buf)
-			if err != nil {
+			var msg replicationMessage
+			if err := decoder.Decode(&msg); err != nil {
 				r.mu.Lock()
 				delete(r.replicas, addr)
 				r.mu.Unlock()
@@ -392,10 +402,81 @@ func (r *Replicator) handleReplica(conn net.Conn) {
 				r.logger.Info("Replica disconnected", "addr", addr)
 				return
 			}
+			if msg.Type == "forward" && msg.Change != nil {
+				if err := r.applyForwarded(*msg.Change); err != nil {
+					r.logger.Warn("Rejected forwarded change", "addr", addr, "bucket", msg.Change.Bucket, "error", err)
+				}
+			}
 		}
 	}()
 }
 
+// applyForwarded writes a change sent up by a replica into the local store.
+// It is recorded as a normal local write, so it gets a primary version and
+// is broadcast back to every replica, including the one that sent it.
+// Leases go through DHCPBucket so they expire like locally written ones.
+func (r *Replicator) applyForwarded(change Change) error {
+	if !forwardableBuckets[change.Bucket] {
+		return fmt.Errorf("bucket %q does not accept forwarded writes", change.Bucket)
+	}
+	if err := r.store.CreateBucket(change.Bucket); err != nil && err != ErrBucketExists {
+		return err
+	}
+	if change.Type == ChangeDelete {
+		return r.store.Delete(change.Bucket, change.Key)
+	}
+	if change.Bucket == BucketDHCPLeases {
+		var lease DHCPLease
+		if err := json.Unmarshal(change.Value, &lease); err != nil {
+			return fmt.Errorf("invalid lease: %w", err)
+		}
+		if lease.Key() != change.Key {
+			return fmt.Errorf("lease key %q does not match %q", lease.Key(), change.Key)
+		}
+		leases, err := NewDHCPBucket(r.store)
+		if err != nil {
+			return err
+		}
+		return leases.Set(&lease)
+	}
+	return r.store.Set(change.Bucket, change.Key, change.Value)
+}
+
+// Forward sends a write to the primary instead of applying it locally.
+// Replicas must not write to their own store (it would break the change
+// chain), so services running on a replica forward their writes and pick
+// them up again when the primary broadcasts them. A nil value deletes the key.
+func (r *Replicator) Forward(bucket, key string, value []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.config.Mode != ModeReplica {
+		return fmt.Errorf("forwarding requires replica mode, current mode: %s", r.config.Mode)
+	}
+	if r.primary == nil {
+		return fmt.Errorf("not connected to primary")
+	}
+
+	change := Change{
+		Bucket:    bucket,
+		Key:       key,
+		Value:     value,
+		Type:      ChangeUpdate,
+		Timestamp: clock.Now(),
+	}
+	if value == nil {
+		change.Type = ChangeDelete
+	}
+	return r.primary.encoder.Encode(replicationMessage{Type: "forward", Change: &change})
+}
+
+// IsPrimary reports whether this node currently owns the replicated store.
+func (r *Replicator) IsPrimary() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config.Mode == ModePrimary
+}
+
 // broadcastChanges subscribes to store changes and sends to all replicas.
 func (r *Replicator) broadcastChanges() {
 	changes := r.store.Subscribe(r.ctx)
@@ -540,6 +621,7 @@ func (r *Replicator) connectToPrimary() error {
 	r.primary = &primaryConn{
 		conn:    conn,
 		decoder: decoder,
+		encoder: encoder,
 	}
 	r.mu.Unlock()
 
@@ -557,6 +639,11 @@ func (r *Replicator) receiveUpdates() error {
 		return fmt.Errorf("not connected to primary")
 	}
 
+	// Ping the primary while connected; it drops replicas that stay silent
+	done := make(chan struct{})
+	defer close(done)
+	go r.keepalive(primary, done)
+
 	for {
 		var msg replicationMessage
 		if err := primary.decoder.Decode(&msg); err != nil {
@@ -577,6 +664,25 @@ func (r *Replicator) receiveUpdates() error {
 	}
 }
 
+// keepalive pings the primary until done is closed or a write fails.
+func (r *Replicator) keepalive(primary *primaryConn, done <-chan struct{}) {
+	ticker := time.NewTicker(replicaKeepalive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			r.mu.Lock()
+			err := primary.encoder.Encode(replicationMessage{Type: "ping"})
+			r.mu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
 // applyChange applies a replicated change to the local store.
 func (r *Replicator) applyChange(change Change) error {
 	return r.store.ApplyReplicatedChange(change)
@@ -635,7 +741,7 @@ type syncResponse struct {
 }
 
 type replicationMessage struct {
-	Type   string  `json:"type"` // "change"
+	Type   string  `json:"type"` // "change", "forward" (replica -> primary) or "ping"
 	Change *Change `json:"change,omitempty"`
 }
 
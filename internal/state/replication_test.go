@@ -3,6 +3,9 @@
 package state
 
 import (
+	"encoding/json"
+	"net"
+	"strings"
 	"testing"
 	"time"
 
@@ -128,3 +131,93 @@ func TestReplicator_AutoRecovery(t *testing.T) {
 		t.Errorf("Expected request version 0 (full sync) when forceSnapshot is true, got %d", reqVer)
 	}
 }
+
+func TestReplicator_ForwardFromReplica(t *testing.T) {
+	logger := logging.New(logging.Config{Level: logging.LevelError})
+
+	// Grab a free port for the primary listener
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	primaryStore, err := NewSQLiteStore(DefaultOptions(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer primaryStore.Close()
+	replicaStore, err := NewSQLiteStore(DefaultOptions(":memory:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replicaStore.Close()
+
+	primary := NewReplicator(primaryStore, ReplicationConfig{Mode: ModePrimary, ListenAddr: addr}, logger)
+	if err := primary.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer primary.Stop()
+
+	replica := NewReplicator(replicaStore, ReplicationConfig{
+		Mode: ModeReplica, PrimaryAddr: addr, ReconnectDelay: 50 * time.Millisecond, SyncTimeout: time.Second,
+	}, logger)
+	if err := primary.Forward(BucketDHCPLeases, "k", []byte("v")); err == nil {
+		t.Error("Expected Forward to fail on the primary")
+	}
+	if err := replica.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer replica.Stop()
+
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(3 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor("replica to connect", func() bool { return replica.Status().Connected })
+
+	lease := DHCPLease{MAC: "aa:bb:cc:dd:ee:ff", IP: "10.0.0.50", LeaseEnd: time.Now().Add(time.Hour)}
+	data, _ := json.Marshal(lease)
+	if err := replica.Forward(BucketDHCPLeases, lease.Key(), data); err != nil {
+		t.Fatal(err)
+	}
+	// Lands on the primary, then comes back to the replica as a normal change
+	waitFor("write on primary", func() bool {
+		entry, err := primaryStore.GetWithMeta(BucketDHCPLeases, "aa:bb:cc:dd:ee:ff")
+		return err == nil && strings.Contains(string(entry.Value), "10.0.0.50")
+	})
+	// Forwarded leases keep the TTL a local DHCPBucket write would set
+	entry, _ := primaryStore.GetWithMeta(BucketDHCPLeases, "aa:bb:cc:dd:ee:ff")
+	if d := time.Until(entry.ExpiresAt); d <= 0 || d > time.Hour {
+		t.Errorf("forwarded lease expires in %v; want its lease end", d)
+	}
+	if err := primary.applyForwarded(Change{Bucket: BucketDHCPLeases, Key: "11:22:33:44:55:66", Value: data}); err == nil {
+		t.Error("Expected a lease forwarded under another key to be rejected")
+	}
+	waitFor("write echoed to replica", func() bool {
+		_, err := replicaStore.Get(BucketDHCPLeases, "aa:bb:cc:dd:ee:ff")
+		return err == nil
+	})
+
+	// Other buckets stay primary-owned
+	if err := replica.Forward("config", "x", []byte("y")); err != nil {
+		t.Fatal(err)
+	}
+	if err := replica.Forward(BucketDHCPLeases, "aa:bb:cc:dd:ee:ff", nil); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("delete on primary", func() bool {
+		_, err := primaryStore.Get(BucketDHCPLeases, "aa:bb:cc:dd:ee:ff")
+		return err != nil
+	})
+	if _, err := primaryStore.Get("config", "x"); err == nil {
+		t.Error("Forward to a non-forwardable bucket should be rejected")
+	}
+}
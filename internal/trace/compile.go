@@ -0,0 +1,47 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package trace
+
+import (
+	"os/exec"
+
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/errors"
+	"grimm.is/flywall/internal/firewall"
+	"grimm.is/flywall/internal/logging"
+)
+
+// Compile generates the ruleset for cfg exactly as it would be applied,
+// without touching the kernel.
+func Compile(cfg *config.Config) (*Ruleset, error) {
+	fwMgr := firewall.NewManagerWithConn(nil, logging.New(logging.DefaultConfig()), "")
+	script, err := fwMgr.GenerateRules(firewall.FromGlobalConfig(cfg), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.KindValidation, "failed to compile ruleset")
+	}
+	return Parse(script)
+}
+
+// LoadKernelRuleset reads the live ruleset with rule handles.
+func LoadKernelRuleset() (*Ruleset, error) {
+	out, err := exec.Command("nft", "-a", "list", "ruleset").Output()
+	if err != nil {
+		return nil, errors.Wrap(err, errors.KindUnavailable, "failed to list nftables ruleset")
+	}
+	return Parse(string(out))
+}
+
+// NewOfflineTracer builds a tracer for a candidate configuration: the
+// compiled ruleset, config-derived routing and zones, and no learning
+// engine.
+func NewOfflineTracer(cfg *config.Config) (*Tracer, error) {
+	rs, err := Compile(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Tracer{
+		Ruleset: rs,
+		Router:  NewConfigRouter(cfg),
+		Zones:   ConfigZones(cfg),
+	}, nil
+}
@@ -0,0 +1,840 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package trace
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// ruleEval evaluates one rule against the packet.
+type ruleEval struct {
+	toks  []string
+	i     int
+	p     *pktState
+	table *Table
+	t     *Tracer
+}
+
+// outcome is what a matching rule did.
+type outcome struct {
+	verdict string // accept, drop, jump, goto, return, continue or empty
+	target  string
+}
+
+func (r *ruleEval) done() bool { return r.i >= len(r.toks) }
+
+func (r *ruleEval) peek() string {
+	if r.done() {
+		return ""
+	}
+	return r.toks[r.i]
+}
+
+func (r *ruleEval) next() string {
+	tok := r.peek()
+	r.i++
+	return tok
+}
+
+// skipIf consumes the next token if it is one of words.
+func (r *ruleEval) skipIf(words ...string) bool {
+	for _, w := range words {
+		if r.peek() == w {
+			r.i++
+			return true
+		}
+	}
+	return false
+}
+
+// eval walks the rule left to right. Matches short-circuit on the first
+// mismatch; statements apply in order until a verdict.
+func (r *ruleEval) eval() (bool, outcome, error) {
+	for !r.done() {
+		tok := r.peek()
+		switch tok {
+		case "accept", "drop", "continue", "return":
+			r.next()
+			return true, outcome{verdict: tok}, nil
+
+		case "reject":
+			r.next()
+			if r.skipIf("with") {
+				for !r.done() && r.peek() != "comment" {
+					r.next()
+				}
+			}
+			r.p.note("rejected")
+			return true, outcome{verdict: "drop"}, nil
+
+		case "jump", "goto":
+			r.next()
+			return true, outcome{verdict: tok, target: unquote(r.next())}, nil
+
+		case "counter":
+			r.next()
+			for r.skipIf("name", "packets", "bytes") {
+				r.next()
+			}
+
+		case "log":
+			r.next()
+			for r.skipIf("prefix", "group", "level", "snaplen", "queue-threshold", "flags") {
+				r.next()
+				for r.skipIf(",") {
+					r.next()
+				}
+			}
+
+		case "comment":
+			r.next()
+			r.next()
+
+		case "notrack":
+			r.next()
+			r.p.note("connection tracking disabled")
+
+		case "limit":
+			r.next()
+			if !r.limit() {
+				return false, outcome{}, nil
+			}
+
+		case "quota":
+			r.next()
+			over := r.skipIf("over", "until")
+			r.next() // amount
+			r.skipIf("bytes", "kbytes", "mbytes", "gbytes")
+			if r.skipIf("used") {
+				r.next()
+				r.skipIf("bytes", "kbytes", "mbytes", "gbytes")
+			}
+			if over {
+				r.p.note("assumed within quota")
+				return false, outcome{}, nil
+			}
+
+		case "queue":
+			r.next()
+			return true, r.queue(), nil
+
+		case "dnat", "snat":
+			if err := r.nat(r.next()); err != nil {
+				return false, outcome{}, err
+			}
+			return true, outcome{verdict: "accept"}, nil
+
+		case "masquerade":
+			r.next()
+			r.masquerade()
+			return true, outcome{verdict: "accept"}, nil
+
+		case "redirect":
+			r.next()
+			if err := r.redirect(); err != nil {
+				return false, outcome{}, err
+			}
+			return true, outcome{verdict: "accept"}, nil
+
+		case "flow":
+			// flow add @ft / flow offload @ft
+			r.next()
+			r.next()
+			r.p.note("flow offloaded to flowtable %s", r.next())
+
+		case "add", "update", "delete":
+			// Dynamic set update: add @set { ip saddr }
+			r.next()
+			set := r.next()
+			depth := 0
+			for !r.done() {
+				t := r.next()
+				if t == "{" {
+					depth++
+				} else if t == "}" {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			r.p.note("%s packet to set %s", tok, set)
+
+		default:
+			if !selectorKeywords[tok] {
+				return false, outcome{}, errUnsupported(tok)
+			}
+			ok, out, err := r.selectorExpr()
+			if err != nil || !ok || out.verdict != "" {
+				return ok, out, err
+			}
+		}
+	}
+	return true, outcome{}, nil
+}
+
+// limit consumes a limit statement. Traced packets are assumed to be within
+// the rate, so "limit rate over" never matches.
+func (r *ruleEval) limit() bool {
+	r.skipIf("rate")
+	over := r.skipIf("over")
+	r.next()
+	if strings.Contains(r.peek(), "/") {
+		r.next() // "mbytes/second"
+	}
+	if r.skipIf("burst") {
+		r.next()
+		r.skipIf("packets", "bytes", "kbytes", "mbytes")
+	}
+	if over {
+		r.p.note("assumed below the rate limit")
+		return false
+	}
+	return true
+}
+
+// queue hands the packet to the learning engine.
+func (r *ruleEval) queue() outcome {
+	bypass := false
+	for !r.done() {
+		switch r.peek() {
+		case "num", "to", "flags":
+			r.next()
+			r.next()
+		case "bypass":
+			r.next()
+			bypass = true
+		case "fanout":
+			r.next()
+		default:
+			goto done
+		}
+	}
+done:
+	if r.t.Learning != nil {
+		verdict, note := r.t.Learning(r.p.packet(Packet{InInterface: r.p.iif, Protocol: protoNames[r.p.proto], SrcMAC: r.p.srcMAC}))
+		r.p.note("learning engine: %s", note)
+		return outcome{verdict: verdict}
+	}
+	if bypass {
+		r.p.note("queued to the learning engine; verdict unknown here, bypass assumes accept")
+		return outcome{verdict: "accept"}
+	}
+	r.p.note("queued with no learning engine listening")
+	return outcome{verdict: "drop"}
+}
+
+// selectorExpr handles "<selector> [. <selector>...]" followed by a match,
+// a "set" statement or a verdict map.
+func (r *ruleEval) selectorExpr() (bool, outcome, error) {
+	first, err := r.parseSelector()
+	if err != nil {
+		return false, outcome{}, err
+	}
+	sels := []*selector{first}
+	for r.peek() == "." {
+		r.next()
+		s, err := r.parseSelector()
+		if err != nil {
+			return false, outcome{}, err
+		}
+		sels = append(sels, s)
+	}
+
+	switch r.peek() {
+	case "set":
+		r.next()
+		return true, outcome{}, r.setStmt(first)
+
+	case "vmap":
+		r.next()
+		vals, ok, err := r.values(sels)
+		if err != nil {
+			return false, outcome{}, err
+		}
+		el, err := r.lookup(sels, vals, ok)
+		if err != nil || el == nil {
+			return false, outcome{}, err
+		}
+		return true, verdictOf(el.Value), nil
+	}
+
+	vals, ok, err := r.values(sels)
+	if err != nil {
+		return false, outcome{}, err
+	}
+
+	// Bitmask: "tcp flags & (syn|ack)", "meta mark & 0xff00"
+	if r.peek() == "&" && len(sels) == 1 {
+		r.next()
+		mask, err := r.bitOperand(first)
+		if err != nil {
+			return false, outcome{}, err
+		}
+		vals[0].n &= mask
+	}
+
+	op := ""
+	switch r.peek() {
+	case "==", "eq", "!=", "ne", "<", "lt", ">", "gt", "<=", "le", ">=", "ge":
+		op = r.next()
+	}
+
+	// Right-hand side: set reference, anonymous set or literal list
+	var matched bool
+	switch {
+	case strings.HasPrefix(r.peek(), "@"):
+		set, err := r.namedSet(r.next())
+		if err != nil {
+			return false, outcome{}, err
+		}
+		if ok {
+			if matched, err = matchElements(sels, vals, set.Elements); err != nil {
+				return false, outcome{}, err
+			}
+		}
+	case r.peek() == "{":
+		items := r.braces()
+		if ok {
+			if matched, err = matchElements(sels, vals, parseElements(items)); err != nil {
+				return false, outcome{}, err
+			}
+		}
+	default:
+		lits := []string{r.next()}
+		for r.peek() == "," {
+			r.next()
+			lits = append(lits, r.next())
+		}
+		if !ok {
+			break
+		}
+		if len(sels) > 1 {
+			return false, outcome{}, errUnsupported("concatenation without a set")
+		}
+		if matched, err = compare(first, vals[0], op, lits); err != nil {
+			return false, outcome{}, err
+		}
+		op = "" // already applied
+	}
+
+	if !ok {
+		// A field the packet does not have never matches, not even !=
+		return false, outcome{}, nil
+	}
+	if op == "!=" || op == "ne" {
+		matched = !matched
+	}
+	return matched, outcome{}, nil
+}
+
+// values evaluates the selectors. ok is false if the packet lacks one of
+// the fields.
+func (r *ruleEval) values(sels []*selector) ([]value, bool, error) {
+	vals := make([]value, len(sels))
+	for i, s := range sels {
+		v, ok, err := s.get(r.p)
+		if err != nil {
+			return nil, false, err
+		}
+		if !ok {
+			return vals, false, nil
+		}
+		vals[i] = v
+	}
+	return vals, true, nil
+}
+
+// compare applies a relational operator against a literal or comma list.
+func compare(sel *selector, v value, op string, lits []string) (bool, error) {
+	if sel.kind == kindFlags && (op == "" || op == "!=" || op == "ne") {
+		// Bare flag match: any of the listed bits
+		var mask uint64
+		for _, l := range lits {
+			n, err := parseSym(sel, l)
+			if err != nil {
+				return false, errUnsupported(fmt.Sprintf("%s value %q", sel.name, l))
+			}
+			mask |= n
+		}
+		hit := v.n&mask != 0
+		if op != "" {
+			hit = !hit
+		}
+		return hit, nil
+	}
+
+	switch op {
+	case "<", "lt", ">", "gt", "<=", "le", ">=", "ge":
+		n, err := parseSym(sel, lits[0])
+		if err != nil {
+			return false, errUnsupported(fmt.Sprintf("%s value %q", sel.name, lits[0]))
+		}
+		switch op {
+		case "<", "lt":
+			return v.n < n, nil
+		case ">", "gt":
+			return v.n > n, nil
+		case "<=", "le":
+			return v.n <= n, nil
+		default:
+			return v.n >= n, nil
+		}
+	}
+
+	hit := false
+	for _, l := range lits {
+		m, err := matchLiteral(sel, v, l)
+		if err != nil {
+			return false, err
+		}
+		hit = hit || m
+	}
+	if op == "!=" || op == "ne" {
+		hit = !hit
+	}
+	return hit, nil
+}
+
+// matchElements reports whether the values match any element.
+func matchElements(sels []*selector, vals []value, elements []Element) (bool, error) {
+	el, err := findElement(sels, vals, elements)
+	return el != nil, err
+}
+
+func findElement(sels []*selector, vals []value, elements []Element) (*Element, error) {
+	for i := range elements {
+		el := &elements[i]
+		if len(el.Key) != len(sels) {
+			continue
+		}
+		hit := true
+		for j, s := range sels {
+			m, err := matchLiteral(s, vals[j], el.Key[j])
+			if err != nil {
+				return nil, err
+			}
+			if !m {
+				hit = false
+				break
+			}
+		}
+		if hit {
+			return el, nil
+		}
+	}
+	return nil, nil
+}
+
+// lookup finds the map element for the values, reading the map from the
+// current position ("@name" or an inline "{ ... }").
+func (r *ruleEval) lookup(sels []*selector, vals []value, ok bool) (*Element, error) {
+	var elements []Element
+	if strings.HasPrefix(r.peek(), "@") {
+		set, err := r.namedSet(r.next())
+		if err != nil {
+			return nil, err
+		}
+		elements = set.Elements
+	} else if r.peek() == "{" {
+		elements = parseElements(r.braces())
+	} else {
+		return nil, errUnsupported("map " + r.peek())
+	}
+	if !ok {
+		return nil, nil
+	}
+	el, err := findElement(sels, vals, elements)
+	if err != nil {
+		return nil, err
+	}
+	if el == nil {
+		keys := make([]string, len(vals))
+		for i, v := range vals {
+			keys[i] = v.String()
+		}
+		r.p.note("no map element for %s", strings.Join(keys, " . "))
+	}
+	return el, nil
+}
+
+func (r *ruleEval) namedSet(ref string) (*Set, error) {
+	name := strings.TrimPrefix(ref, "@")
+	set, ok := r.table.Sets[name]
+	if !ok {
+		return nil, errUnsupported("set " + ref + " not in ruleset")
+	}
+	return set, nil
+}
+
+// braces consumes a { ... } group and returns its contents.
+func (r *ruleEval) braces() []string {
+	start := r.i
+	depth := 0
+	for !r.done() {
+		t := r.next()
+		if t == "{" {
+			depth++
+		} else if t == "}" {
+			depth--
+			if depth == 0 {
+				break
+			}
+		}
+	}
+	return braceBody(r.toks[start:r.i])
+}
+
+// bitOperand reads "0xff", "syn" or "(syn|ack)".
+func (r *ruleEval) bitOperand(sel *selector) (uint64, error) {
+	paren := r.skipIf("(")
+	var mask uint64
+	for {
+		tok := r.next()
+		n, err := parseSym(sel, tok)
+		if err != nil {
+			return 0, errUnsupported(fmt.Sprintf("%s mask %q", sel.name, tok))
+		}
+		mask |= n
+		if !r.skipIf("|") {
+			break
+		}
+	}
+	if paren {
+		r.skipIf(")")
+	}
+	return mask, nil
+}
+
+func verdictOf(toks []string) outcome {
+	if len(toks) == 0 {
+		return outcome{}
+	}
+	out := outcome{verdict: toks[0]}
+	if len(toks) > 1 {
+		out.target = unquote(toks[1])
+	}
+	return out
+}
+
+// setStmt applies "meta mark set <expr>" and friends.
+func (r *ruleEval) setStmt(target *selector) error {
+	if target.set == nil {
+		return errUnsupported(target.name + " set")
+	}
+	if target.noop {
+		arg := r.next()
+		if arg == "rt" {
+			r.next()
+		}
+		if target.name == "ct helper" {
+			r.p.note("conntrack helper %s assigned", unquote(arg))
+		}
+		return nil
+	}
+
+	n, err := r.arith()
+	if err != nil {
+		return err
+	}
+	target.set(r.p, n)
+	r.p.note("%s set to 0x%x", target.name, n)
+	return nil
+}
+
+// arith reads "<operand> [& | ^ <number>]...".
+func (r *ruleEval) arith() (uint64, error) {
+	var n uint64
+	tok := r.peek()
+	if selectorKeywords[tok] {
+		s, err := r.parseSelector()
+		if err != nil {
+			return 0, err
+		}
+		v, _, err := s.get(r.p)
+		if err != nil {
+			return 0, err
+		}
+		n = v.n
+	} else {
+		r.next()
+		var err error
+		if n, err = parseUint(tok); err != nil {
+			return 0, errUnsupported("value " + tok)
+		}
+	}
+	for {
+		op := r.peek()
+		if op != "&" && op != "|" && op != "^" && op != "and" && op != "or" && op != "xor" {
+			return n, nil
+		}
+		r.next()
+		m, err := parseUint(r.next())
+		if err != nil {
+			return 0, errUnsupported("operand for " + op)
+		}
+		switch op {
+		case "&", "and":
+			n &= m
+		case "|", "or":
+			n |= m
+		default:
+			n ^= m
+		}
+	}
+}
+
+// nat applies "dnat ..." or "snat ...".
+func (r *ruleEval) nat(kind string) error {
+	prefix := false
+	for !r.done() && r.peek() != "to" {
+		if r.next() == "prefix" {
+			prefix = true
+		}
+	}
+	if !r.skipIf("to") {
+		return errUnsupported(kind + " without target")
+	}
+	target, err := r.natTarget()
+	if err != nil || target == nil {
+		return err
+	}
+	for r.skipIf("random", "persistent", "fully-random", ",") {
+	}
+
+	addr, port, err := splitTarget(target)
+	if err != nil {
+		return err
+	}
+
+	if prefix {
+		pfx, err := netip.ParsePrefix(addr)
+		if err != nil {
+			return errUnsupported(kind + " prefix to " + addr)
+		}
+		orig := r.p.dst
+		if kind == "snat" {
+			orig = r.p.src
+		}
+		mapped := netmap(pfx, orig)
+		r.applyNAT(kind, mapped, 0)
+		return nil
+	}
+
+	var a netip.Addr
+	if addr != "" {
+		lo, _, isRange := strings.Cut(addr, "-")
+		if a, err = netip.ParseAddr(lo); err != nil {
+			return errUnsupported(kind + " to " + addr)
+		}
+		if isRange {
+			r.p.note("address range %s: first address assumed", addr)
+		}
+	}
+	var pn uint16
+	if port != "" {
+		lo, _, isRange := strings.Cut(port, "-")
+		n, err := strconv.ParseUint(lo, 10, 16)
+		if err != nil {
+			return errUnsupported(kind + " port " + port)
+		}
+		pn = uint16(n)
+		if isRange {
+			r.p.note("port range %s: first port assumed", port)
+		}
+	}
+	r.applyNAT(kind, a, pn)
+	return nil
+}
+
+func (r *ruleEval) applyNAT(kind string, a netip.Addr, port uint16) {
+	p := r.p
+	if kind == "dnat" {
+		if a.IsValid() {
+			p.dst = a
+		}
+		if port != 0 && portProtos[p.proto] {
+			p.dport = port
+		}
+		p.ctStatus |= uint32(ctStatusBits["dnat"])
+		p.note("destination translated to %s", hostPort(p.dst, p.dport, p.proto))
+		return
+	}
+	if a.IsValid() {
+		p.src = a
+	}
+	if port != 0 && portProtos[p.proto] {
+		p.sport = port
+	}
+	p.ctStatus |= uint32(ctStatusBits["snat"])
+	p.note("source translated to %s", hostPort(p.src, p.sport, p.proto))
+}
+
+// natTarget reads the "to" expression: a literal such as 10.0.0.1:80 or a
+// map lookup keyed by a selector. It returns the chosen value tokens.
+func (r *ruleEval) natTarget() ([]string, error) {
+	tok := r.peek()
+
+	// "10.0.0.5:tcp dport map { ... }": address with a mapped port
+	if i := strings.LastIndex(tok, ":"); i > 0 && selectorKeywords[tok[i+1:]] {
+		r.toks[r.i] = tok[i+1:]
+		port, err := r.mappedValue()
+		if err != nil || port == nil {
+			return nil, err
+		}
+		return []string{tok[:i] + ":" + strings.Join(port, "")}, nil
+	}
+	if selectorKeywords[tok] {
+		return r.mappedValue()
+	}
+	r.next()
+	return []string{tok}, nil
+}
+
+// mappedValue evaluates "<selector>[ . <selector>] map { ... }".
+func (r *ruleEval) mappedValue() ([]string, error) {
+	first, err := r.parseSelector()
+	if err != nil {
+		return nil, err
+	}
+	sels := []*selector{first}
+	for r.peek() == "." {
+		r.next()
+		s, err := r.parseSelector()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, s)
+	}
+	vals, ok, err := r.values(sels)
+	if err != nil {
+		return nil, err
+	}
+	if !r.skipIf("map") {
+		if len(sels) == 1 && ok {
+			return []string{vals[0].String()}, nil
+		}
+		return nil, errUnsupported("expression without map")
+	}
+	el, err := r.lookup(sels, vals, ok)
+	if err != nil || el == nil {
+		return nil, err
+	}
+	return el.Value, nil
+}
+
+// splitTarget splits NAT target tokens into address and port.
+func splitTarget(toks []string) (addr, port string, err error) {
+	// "10.0.0.1 . 8080" from an addr . port map
+	parts := splitTop(toks, ".")
+	if len(parts) == 2 && len(parts[0]) == 1 && len(parts[1]) == 1 {
+		return parts[0][0], parts[1][0], nil
+	}
+	if len(toks) != 1 {
+		return "", "", errUnsupported("nat target " + strings.Join(toks, " "))
+	}
+	s := toks[0]
+	switch {
+	case strings.HasPrefix(s, "["):
+		// [2001:db8::1]:80
+		end := strings.Index(s, "]")
+		if end < 0 {
+			return "", "", errUnsupported("nat target " + s)
+		}
+		addr = s[1:end]
+		port = strings.TrimPrefix(s[end+1:], ":")
+	case strings.HasPrefix(s, ":"):
+		port = s[1:]
+	case strings.Count(s, ":") == 1:
+		addr, port, _ = strings.Cut(s, ":")
+	default:
+		addr = s
+	}
+	return addr, port, nil
+}
+
+// netmap maps the host part of a onto prefix pfx.
+func netmap(pfx netip.Prefix, a netip.Addr) netip.Addr {
+	base := pfx.Masked().Addr().AsSlice()
+	orig := a.AsSlice()
+	if len(base) != len(orig) {
+		return a
+	}
+	bits := pfx.Bits()
+	out := make([]byte, len(base))
+	for i := range out {
+		var mask byte
+		switch {
+		case bits >= 8:
+			mask = 0xff
+			bits -= 8
+		case bits > 0:
+			mask = ^byte(0xff >> bits)
+			bits = 0
+		}
+		out[i] = base[i]&mask | orig[i]&^mask
+	}
+	res, _ := netip.AddrFromSlice(out)
+	return res
+}
+
+// masquerade rewrites the source to the outgoing interface address.
+func (r *ruleEval) masquerade() {
+	port := ""
+	for !r.done() {
+		if r.skipIf("random", "persistent", "fully-random", ",") {
+			continue
+		}
+		if r.skipIf("to") {
+			port = strings.TrimPrefix(r.next(), ":")
+			continue
+		}
+		break
+	}
+	p := r.p
+	var src netip.Addr
+	if p.route != nil && p.route.Source != "" {
+		src, _ = netip.ParseAddr(p.route.Source)
+	}
+	if !src.IsValid() {
+		p.note("masqueraded to the address of %s (not known here)", orDash(p.oif))
+		p.ctStatus |= uint32(ctStatusBits["snat"])
+		return
+	}
+	var pn uint16
+	if n, err := strconv.ParseUint(strings.SplitN(port, "-", 2)[0], 10, 16); err == nil {
+		pn = uint16(n)
+	}
+	r.applyNAT("snat", src, pn)
+}
+
+// redirect sends the packet to a local port.
+func (r *ruleEval) redirect() error {
+	p := r.p
+	if r.skipIf("to") {
+		port := strings.TrimPrefix(r.next(), ":")
+		n, err := strconv.ParseUint(strings.SplitN(port, "-", 2)[0], 10, 16)
+		if err != nil {
+			return errUnsupported("redirect to " + port)
+		}
+		if portProtos[p.proto] {
+			p.dport = uint16(n)
+		}
+	}
+	for r.skipIf("random", "persistent", "fully-random", ",") {
+	}
+	p.redirected = true
+	p.ctStatus |= uint32(ctStatusBits["dnat"])
+	p.note("redirected to local port %d", p.dport)
+	return nil
+}
+
+func hostPort(a netip.Addr, port uint16, proto uint8) string {
+	if port == 0 || !portProtos[proto] {
+		return a.String()
+	}
+	return netip.AddrPortFrom(a, port).String()
+}
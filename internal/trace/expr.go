@@ -0,0 +1,517 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package trace
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"grimm.is/flywall/internal/engine"
+)
+
+// valueKind describes how literals compared against a selector are read.
+type valueKind int
+
+const (
+	kindInt   valueKind = iota // numbers, ranges and symbolic names
+	kindFlags                  // bitmasks: a bare match tests any of the bits
+	kindAddr                   // IP addresses, prefixes and ranges
+	kindStr                    // interface names (with wildcards), symbols
+)
+
+// value is the result of evaluating a selector against the packet.
+type value struct {
+	kind valueKind
+	n    uint64
+	addr netip.Addr
+	s    string
+}
+
+func (v value) String() string {
+	switch v.kind {
+	case kindAddr:
+		return v.addr.String()
+	case kindStr:
+		return v.s
+	case kindFlags:
+		return fmt.Sprintf("0x%x", v.n)
+	}
+	return strconv.FormatUint(v.n, 10)
+}
+
+// selector is a packet or metadata expression such as "tcp dport".
+type selector struct {
+	name string
+	kind valueKind
+	syms map[string]uint64
+	// get returns the value, or false if the packet has no such field
+	// (e.g. "tcp dport" on a UDP packet).
+	get func(p *pktState) (value, bool, error)
+	// set writes the value for "... set" statements; nil if read-only.
+	set func(p *pktState, v uint64)
+	// noop marks settable fields that do not affect the trace
+	// (tcp option maxseg, ct helper, meta priority).
+	noop bool
+}
+
+// errUnsupported marks expressions the tracer cannot evaluate.
+type errUnsupported string
+
+func (e errUnsupported) Error() string { return "unsupported expression: " + string(e) }
+
+var protoNums = map[string]uint64{
+	"icmp": 1, "igmp": 2, "tcp": 6, "udp": 17, "gre": 47, "esp": 50, "ah": 51,
+	"icmpv6": 58, "ipv6-icmp": 58, "sctp": 132, "udplite": 136,
+}
+
+var protoNames = map[uint8]string{
+	1: "icmp", 2: "igmp", 6: "tcp", 17: "udp", 47: "gre", 50: "esp", 51: "ah",
+	58: "icmpv6", 132: "sctp", 136: "udplite",
+}
+
+var serviceNums = map[string]uint64{
+	"ftp": 21, "ssh": 22, "telnet": 23, "smtp": 25, "domain": 53, "bootps": 67,
+	"bootpc": 68, "http": 80, "pop3": 110, "ntp": 123, "imap": 143, "snmp": 161,
+	"https": 443, "isakmp": 500, "syslog": 514, "submission": 587,
+	"ipsec-nat-t": 4500, "mdns": 5353,
+}
+
+var ctStateBits = map[string]uint64{
+	"invalid": 1, "established": 2, "related": 4, "new": 8, "untracked": 64,
+}
+
+var ctStatusBits = map[string]uint64{
+	"expected": 1, "seen-reply": 2, "assured": 4, "confirmed": 8,
+	"snat": 16, "dnat": 32, "dying": 512,
+}
+
+var tcpFlagBits = map[string]uint64{
+	"fin": 1, "syn": 2, "rst": 4, "psh": 8, "ack": 16, "urg": 32, "ecn": 64, "cwr": 128,
+}
+
+var nfprotoNums = map[string]uint64{"ipv4": 2, "ipv6": 10}
+
+var etherTypes = map[string]uint64{"ip": 0x0800, "arp": 0x0806, "ip6": 0x86dd, "vlan": 0x8100}
+
+// l4 protocols carrying ports, for "th"
+var portProtos = map[uint8]bool{6: true, 17: true, 132: true, 136: true, 33: true}
+
+// Keywords that start a selector expression.
+var selectorKeywords = map[string]bool{
+	"meta": true, "iifname": true, "oifname": true, "iif": true, "oif": true,
+	"mark": true, "l4proto": true, "nfproto": true, "ip": true, "ip6": true,
+	"tcp": true, "udp": true, "udplite": true, "sctp": true, "dccp": true,
+	"th": true, "icmp": true, "icmpv6": true, "ct": true, "ether": true,
+	"numgen": true, "jhash": true, "symhash": true, "fib": true, "rt": true,
+	"socket": true, "osf": true, "ipsec": true, "vlan": true, "day": true, "hour": true,
+	"pkttype": true,
+}
+
+// parseSelector reads one selector expression at the current position.
+func (r *ruleEval) parseSelector() (*selector, error) {
+	tok := r.next()
+	switch tok {
+	case "meta":
+		key := r.next()
+		return r.metaSelector(key)
+	case "iifname", "oifname", "iif", "oif", "mark", "l4proto", "nfproto", "day", "hour", "pkttype":
+		return r.metaSelector(tok)
+
+	case "ip", "ip6":
+		key := r.next()
+		want := "ip"
+		if tok == "ip6" {
+			want = "ip6"
+		}
+		switch key {
+		case "saddr", "daddr":
+			return &selector{name: tok + " " + key, kind: kindAddr, get: func(p *pktState) (value, bool, error) {
+				if p.family != want {
+					return value{}, false, nil
+				}
+				a := p.src
+				if key == "daddr" {
+					a = p.dst
+				}
+				return value{kind: kindAddr, addr: a}, true, nil
+			}}, nil
+		case "protocol", "nexthdr":
+			return &selector{name: tok + " " + key, kind: kindInt, syms: protoNums, get: func(p *pktState) (value, bool, error) {
+				if p.family != want {
+					return value{}, false, nil
+				}
+				return value{kind: kindInt, n: uint64(p.proto)}, true, nil
+			}}, nil
+		}
+		return nil, errUnsupported(tok + " " + key)
+
+	case "tcp", "udp", "udplite", "sctp", "dccp", "th":
+		key := r.next()
+		if tok == "tcp" && key == "option" {
+			// tcp option maxseg size set rt mtu
+			name := "tcp option"
+			for !r.done() && r.peek() != "set" {
+				name += " " + r.next()
+			}
+			return &selector{name: name, noop: true, get: func(p *pktState) (value, bool, error) {
+				return value{}, false, errUnsupported(name)
+			}, set: func(p *pktState, v uint64) {}}, nil
+		}
+		if tok == "tcp" && key == "flags" {
+			return &selector{name: "tcp flags", kind: kindFlags, syms: tcpFlagBits, get: func(p *pktState) (value, bool, error) {
+				if p.proto != 6 {
+					return value{}, false, nil
+				}
+				return value{kind: kindFlags, n: uint64(p.tcpFlags)}, true, nil
+			}}, nil
+		}
+		if key != "sport" && key != "dport" {
+			return nil, errUnsupported(tok + " " + key)
+		}
+		proto := uint8(protoNums[tok])
+		return &selector{name: tok + " " + key, kind: kindInt, syms: serviceNums, get: func(p *pktState) (value, bool, error) {
+			if tok == "th" {
+				if !portProtos[p.proto] {
+					return value{}, false, nil
+				}
+			} else if p.proto != proto {
+				return value{}, false, nil
+			}
+			port := p.sport
+			if key == "dport" {
+				port = p.dport
+			}
+			return value{kind: kindInt, n: uint64(port)}, true, nil
+		}}, nil
+
+	case "icmp", "icmpv6":
+		key := r.next()
+		if key != "type" {
+			return nil, errUnsupported(tok + " " + key)
+		}
+		proto := uint8(protoNums[tok])
+		return &selector{name: tok + " type", kind: kindStr, get: func(p *pktState) (value, bool, error) {
+			if p.proto != proto {
+				return value{}, false, nil
+			}
+			return value{kind: kindStr, s: p.icmpType}, true, nil
+		}}, nil
+
+	case "ct":
+		key := r.next()
+		if key == "original" || key == "reply" {
+			key += " " + r.next()
+		}
+		return r.ctSelector(key)
+
+	case "ether":
+		key := r.next()
+		if key != "saddr" {
+			return nil, errUnsupported("ether " + key)
+		}
+		return &selector{name: "ether saddr", kind: kindStr, get: func(p *pktState) (value, bool, error) {
+			if p.srcMAC == "" {
+				return value{}, false, errUnsupported("ether saddr (source MAC unknown)")
+			}
+			return value{kind: kindStr, s: p.srcMAC}, true, nil
+		}}, nil
+
+	case "numgen":
+		// numgen inc|random mod N [offset M]: the first packet of the
+		// counter sees the offset.
+		mode := r.next()
+		var mod, offset uint64
+		if r.peek() == "mod" {
+			r.next()
+			mod, _ = parseUint(r.next())
+		}
+		if r.peek() == "offset" {
+			r.next()
+			offset, _ = parseUint(r.next())
+		}
+		return &selector{name: "numgen " + mode, kind: kindInt, get: func(p *pktState) (value, bool, error) {
+			if mode != "inc" {
+				return value{}, false, errUnsupported("numgen " + mode)
+			}
+			p.note("numgen inc mod %d assumed to be at %d", mod, offset)
+			return value{kind: kindInt, n: offset}, true, nil
+		}}, nil
+
+	case "jhash":
+		// jhash ip saddr mod N seed S [offset M]
+		var input []*selector
+		for !r.done() && r.peek() != "mod" {
+			if r.peek() == "." {
+				r.next()
+				continue
+			}
+			s, err := r.parseSelector()
+			if err != nil {
+				return nil, err
+			}
+			input = append(input, s)
+		}
+		var mod, seed, offset uint64
+		for !r.done() {
+			switch r.peek() {
+			case "mod":
+				r.next()
+				mod, _ = parseUint(r.next())
+				continue
+			case "seed":
+				r.next()
+				seed, _ = parseUint(r.next())
+				continue
+			case "offset":
+				r.next()
+				offset, _ = parseUint(r.next())
+				continue
+			}
+			break
+		}
+		return &selector{name: "jhash", kind: kindInt, get: func(p *pktState) (value, bool, error) {
+			if len(input) != 1 || input[0].kind != kindAddr || mod == 0 {
+				return value{}, false, errUnsupported("jhash over " + selectorNames(input))
+			}
+			v, ok, err := input[0].get(p)
+			if err != nil || !ok {
+				return value{}, ok, err
+			}
+			if !v.addr.Is4() {
+				return value{}, false, errUnsupported("jhash on IPv6")
+			}
+			key := v.addr.As4()
+			h := engine.ReciprocalScale(engine.JHash(key[:], uint32(seed)), uint32(mod))
+			return value{kind: kindInt, n: uint64(h) + offset}, true, nil
+		}}, nil
+
+	case "fib":
+		return r.fibSelector()
+	}
+	return nil, errUnsupported(tok)
+}
+
+func (r *ruleEval) metaSelector(key string) (*selector, error) {
+	switch key {
+	case "iifname", "iif":
+		return &selector{name: "iifname", kind: kindStr, get: func(p *pktState) (value, bool, error) {
+			return value{kind: kindStr, s: p.iif}, p.iif != "", nil
+		}}, nil
+	case "oifname", "oif":
+		return &selector{name: "oifname", kind: kindStr, get: func(p *pktState) (value, bool, error) {
+			return value{kind: kindStr, s: p.oif}, p.oif != "", nil
+		}}, nil
+	case "l4proto":
+		return &selector{name: "meta l4proto", kind: kindInt, syms: protoNums, get: func(p *pktState) (value, bool, error) {
+			return value{kind: kindInt, n: uint64(p.proto)}, true, nil
+		}}, nil
+	case "nfproto":
+		return &selector{name: "meta nfproto", kind: kindInt, syms: nfprotoNums, get: func(p *pktState) (value, bool, error) {
+			n := nfprotoNums["ipv4"]
+			if p.family == "ip6" {
+				n = nfprotoNums["ipv6"]
+			}
+			return value{kind: kindInt, n: n}, true, nil
+		}}, nil
+	case "protocol":
+		return &selector{name: "meta protocol", kind: kindInt, syms: etherTypes, get: func(p *pktState) (value, bool, error) {
+			n := etherTypes["ip"]
+			if p.family == "ip6" {
+				n = etherTypes["ip6"]
+			}
+			return value{kind: kindInt, n: n}, true, nil
+		}}, nil
+	case "mark":
+		return &selector{name: "meta mark", kind: kindInt, get: func(p *pktState) (value, bool, error) {
+			return value{kind: kindInt, n: uint64(p.mark)}, true, nil
+		}, set: func(p *pktState, v uint64) { p.mark = uint32(v) }}, nil
+	case "day":
+		return &selector{name: "meta day", kind: kindInt, get: func(p *pktState) (value, bool, error) {
+			return value{kind: kindInt, n: uint64(p.now.UTC().Weekday())}, true, nil
+		}}, nil
+	case "hour":
+		return &selector{name: "meta hour", kind: kindInt, get: func(p *pktState) (value, bool, error) {
+			return value{kind: kindInt, n: uint64(p.now.UTC().Hour())}, true, nil
+		}}, nil
+	case "pkttype":
+		return &selector{name: "meta pkttype", kind: kindStr, get: func(p *pktState) (value, bool, error) {
+			return value{kind: kindStr, s: "host"}, true, nil
+		}}, nil
+	case "nftrace", "priority":
+		return &selector{name: "meta " + key, noop: true, get: func(p *pktState) (value, bool, error) {
+			return value{}, false, errUnsupported("meta " + key)
+		}, set: func(p *pktState, v uint64) {}}, nil
+	}
+	return nil, errUnsupported("meta " + key)
+}
+
+func (r *ruleEval) ctSelector(key string) (*selector, error) {
+	switch key {
+	case "state":
+		return &selector{name: "ct state", kind: kindFlags, syms: ctStateBits, get: func(p *pktState) (value, bool, error) {
+			return value{kind: kindFlags, n: uint64(p.ctState)}, true, nil
+		}}, nil
+	case "status":
+		return &selector{name: "ct status", kind: kindFlags, syms: ctStatusBits, get: func(p *pktState) (value, bool, error) {
+			return value{kind: kindFlags, n: uint64(p.ctStatus)}, true, nil
+		}}, nil
+	case "mark":
+		return &selector{name: "ct mark", kind: kindInt, get: func(p *pktState) (value, bool, error) {
+			return value{kind: kindInt, n: uint64(p.ctMark)}, true, nil
+		}, set: func(p *pktState, v uint64) { p.ctMark = uint32(v) }}, nil
+	case "direction":
+		return &selector{name: "ct direction", kind: kindStr, get: func(p *pktState) (value, bool, error) {
+			return value{kind: kindStr, s: "original"}, true, nil
+		}}, nil
+	}
+	// ct helper, ct zone, ct count...: settable but not matchable
+	return &selector{name: "ct " + key, noop: true, get: func(p *pktState) (value, bool, error) {
+		return value{}, false, errUnsupported("ct " + key)
+	}, set: func(p *pktState, v uint64) {}}, nil
+}
+
+// fibSelector handles "fib daddr type" and "fib saddr . iif oif".
+func (r *ruleEval) fibSelector() (*selector, error) {
+	var flags []string
+	for !r.done() {
+		tok := r.peek()
+		if tok == "." {
+			r.next()
+			continue
+		}
+		if tok != "saddr" && tok != "daddr" && tok != "mark" && tok != "iif" && tok != "oif" {
+			break
+		}
+		flags = append(flags, r.next())
+	}
+	result := r.next()
+	name := "fib " + strings.Join(flags, " . ") + " " + result
+
+	has := func(f string) bool {
+		for _, x := range flags {
+			if x == f {
+				return true
+			}
+		}
+		return false
+	}
+
+	return &selector{name: name, kind: kindStr, get: func(p *pktState) (value, bool, error) {
+		if p.router == nil {
+			return value{}, false, errUnsupported(name + " (no router)")
+		}
+		addr := p.dst
+		if has("saddr") {
+			addr = p.src
+		}
+		iif := ""
+		if has("iif") {
+			iif = p.iif
+		}
+		var mark uint32
+		if has("mark") {
+			mark = p.mark
+		}
+		rt, err := p.router.Route(netip.Addr{}, addr, iif, mark)
+		switch result {
+		case "type":
+			if err != nil || rt == nil {
+				return value{kind: kindStr, s: "unreachable"}, true, nil
+			}
+			if rt.Local {
+				return value{kind: kindStr, s: "local"}, true, nil
+			}
+			return value{kind: kindStr, s: "unicast"}, true, nil
+		case "oif", "oifname":
+			if err != nil || rt == nil {
+				return value{kind: kindStr, s: "missing"}, true, nil
+			}
+			if rt.Local {
+				return value{kind: kindStr, s: p.iif}, true, nil
+			}
+			return value{kind: kindStr, s: rt.OutInterface}, true, nil
+		}
+		return value{}, false, errUnsupported(name)
+	}}, nil
+}
+
+func selectorNames(sels []*selector) string {
+	names := make([]string, len(sels))
+	for i, s := range sels {
+		names[i] = s.name
+	}
+	return strings.Join(names, " . ")
+}
+
+// matchLiteral reports whether v matches a literal element (a single
+// value, range, prefix or symbolic name).
+func matchLiteral(sel *selector, v value, lit string) (bool, error) {
+	lit = unquote(lit)
+	switch sel.kind {
+	case kindAddr:
+		return matchAddr(v.addr, lit)
+	case kindStr:
+		if strings.HasSuffix(lit, "*") {
+			return strings.HasPrefix(v.s, strings.TrimSuffix(lit, "*")), nil
+		}
+		return v.s == lit, nil
+	default:
+		lo, hi, err := parseRange(sel, lit)
+		if err != nil {
+			return false, err
+		}
+		return v.n >= lo && v.n <= hi, nil
+	}
+}
+
+// matchAddr matches an address against "a", "a/len" or "a-b".
+func matchAddr(a netip.Addr, lit string) (bool, error) {
+	if strings.Contains(lit, "/") {
+		pfx, err := netip.ParsePrefix(lit)
+		if err != nil {
+			return false, errUnsupported("address " + lit)
+		}
+		return a.IsValid() && pfx.Contains(a), nil
+	}
+	if lo, hi, ok := strings.Cut(lit, "-"); ok {
+		l, err1 := netip.ParseAddr(lo)
+		h, err2 := netip.ParseAddr(hi)
+		if err1 != nil || err2 != nil {
+			return false, errUnsupported("address range " + lit)
+		}
+		return a.IsValid() && a.BitLen() == l.BitLen() && a.Compare(l) >= 0 && a.Compare(h) <= 0, nil
+	}
+	b, err := netip.ParseAddr(lit)
+	if err != nil {
+		return false, errUnsupported("address " + lit)
+	}
+	return a == b, nil
+}
+
+// parseRange reads "n", "lo-hi" or a symbolic name for the selector.
+func parseRange(sel *selector, lit string) (uint64, uint64, error) {
+	if n, err := parseSym(sel, lit); err == nil {
+		return n, n, nil
+	}
+	if lo, hi, ok := strings.Cut(lit, "-"); ok {
+		l, err1 := parseSym(sel, lo)
+		h, err2 := parseSym(sel, hi)
+		if err1 == nil && err2 == nil {
+			return l, h, nil
+		}
+	}
+	return 0, 0, errUnsupported(fmt.Sprintf("%s value %q", sel.name, lit))
+}
+
+func parseSym(sel *selector, s string) (uint64, error) {
+	if n, ok := sel.syms[s]; ok {
+		return n, nil
+	}
+	return parseUint(s)
+}
+
+func parseUint(s string) (uint64, error) {
+	return strconv.ParseUint(s, 0, 64)
+}
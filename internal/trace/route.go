@@ -0,0 +1,271 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package trace
+
+import (
+	"fmt"
+	"net/netip"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/errors"
+)
+
+// Main routing table ID
+const mainTable = 254
+
+// ConfigRouter routes from a configuration alone, for offline traces. It
+// knows interface addresses, static routes, routing tables and policy
+// routes; addresses learned at runtime (DHCP) are not known.
+type ConfigRouter struct {
+	local  []netip.Addr
+	routes map[int][]cfgRoute
+	rules  []cfgRule
+}
+
+type cfgRoute struct {
+	dst     netip.Prefix
+	gateway string
+	iface   string
+	source  string
+	metric  int
+}
+
+type cfgRule struct {
+	name      string
+	priority  int
+	mark      uint32
+	mask      uint32
+	hasMark   bool
+	from, to  netip.Prefix
+	iif       string
+	table     int
+	blackhole bool
+}
+
+// NewConfigRouter builds a router from cfg.
+func NewConfigRouter(cfg *config.Config) *ConfigRouter {
+	r := &ConfigRouter{routes: make(map[int][]cfgRoute)}
+	r.local = append(r.local, netip.MustParseAddr("127.0.0.1"), netip.MustParseAddr("::1"))
+	r.routes[mainTable] = append(r.routes[mainTable], cfgRoute{dst: netip.MustParsePrefix("127.0.0.0/8"), iface: "lo"})
+
+	// Connected routes and default routes via interface gateways
+	for _, iface := range cfg.Interfaces {
+		if iface.Disabled {
+			continue
+		}
+		var source string
+		for _, cidr := range append(append([]string{}, iface.IPv4...), iface.IPv6...) {
+			pfx, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				continue
+			}
+			r.local = append(r.local, pfx.Addr())
+			if source == "" && pfx.Addr().Is4() {
+				source = pfx.Addr().String()
+			}
+			r.routes[mainTable] = append(r.routes[mainTable], cfgRoute{dst: pfx.Masked(), iface: iface.Name, source: pfx.Addr().String()})
+		}
+		if iface.Gateway != "" || iface.DHCP {
+			def := cfgRoute{dst: netip.MustParsePrefix("0.0.0.0/0"), gateway: iface.Gateway, iface: iface.Name, source: source, metric: 100}
+			if iface.DHCP && iface.Gateway == "" {
+				def.gateway = "(dhcp)"
+			}
+			table := mainTable
+			if iface.Table > 0 && iface.Table != mainTable {
+				// Split routing: the table is selected by a mark equal to its ID
+				table = iface.Table
+				r.rules = append(r.rules, cfgRule{
+					name: "iface-" + iface.Name, priority: 10000 + iface.Table,
+					mark: uint32(iface.Table), mask: 0xffffffff, hasMark: true, table: table,
+				})
+			}
+			r.routes[table] = append(r.routes[table], def)
+		}
+	}
+
+	for _, rt := range cfg.Routes {
+		r.addRoute(rt.Table, rt)
+	}
+	for _, tbl := range cfg.RoutingTables {
+		for _, rt := range tbl.Routes {
+			r.addRoute(tbl.ID, rt)
+		}
+	}
+
+	for _, pr := range cfg.PolicyRoutes {
+		if !pr.Enabled {
+			continue
+		}
+		rule := cfgRule{name: pr.Name, priority: pr.Priority, iif: pr.IIF, table: pr.Table, blackhole: pr.Blackhole || pr.Prohibit}
+		if rule.priority == 0 {
+			rule.priority = 20000
+		}
+		mark := pr.Mark
+		if mark == "" {
+			mark = pr.FWMark
+		}
+		if mark != "" {
+			m, err := strconv.ParseUint(mark, 0, 32)
+			if err != nil {
+				continue
+			}
+			rule.mark, rule.mask, rule.hasMark = uint32(m), 0xffffffff, true
+			if pr.MarkMask != "" {
+				if mm, err := strconv.ParseUint(pr.MarkMask, 0, 32); err == nil {
+					rule.mask = uint32(mm)
+				}
+			}
+		}
+		rule.from, _ = parsePrefix(pr.FromSource)
+		rule.to, _ = parsePrefix(pr.To)
+		r.rules = append(r.rules, rule)
+	}
+	sort.SliceStable(r.rules, func(i, j int) bool { return r.rules[i].priority < r.rules[j].priority })
+	return r
+}
+
+func (r *ConfigRouter) addRoute(table int, rt config.Route) {
+	if table == 0 {
+		table = mainTable
+	}
+	dst := rt.Destination
+	if dst == "default" {
+		dst = "0.0.0.0/0"
+	}
+	pfx, err := parsePrefix(dst)
+	if err != nil {
+		return
+	}
+	r.routes[table] = append(r.routes[table], cfgRoute{dst: pfx, gateway: rt.Gateway, iface: rt.Interface, metric: rt.Metric})
+}
+
+// parsePrefix accepts "a.b.c.d", "a.b.c.d/len" and empty (invalid prefix).
+func parsePrefix(s string) (netip.Prefix, error) {
+	if s == "" || s == "all" {
+		return netip.Prefix{}, nil
+	}
+	if !strings.Contains(s, "/") {
+		a, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return netip.PrefixFrom(a, a.BitLen()), nil
+	}
+	pfx, err := netip.ParsePrefix(s)
+	return pfx.Masked(), err
+}
+
+// Route implements Router.
+func (r *ConfigRouter) Route(src, dst netip.Addr, iif string, mark uint32) (*Route, error) {
+	for _, a := range r.local {
+		if a == dst {
+			return &Route{Local: true, Table: "local"}, nil
+		}
+	}
+
+	for _, rule := range r.rules {
+		if rule.hasMark && mark&rule.mask != rule.mark {
+			continue
+		}
+		if rule.from.IsValid() && (!src.IsValid() || !rule.from.Contains(src)) {
+			continue
+		}
+		if rule.to.IsValid() && !rule.to.Contains(dst) {
+			continue
+		}
+		if rule.iif != "" && !ifaceMatch(rule.iif, iif) {
+			continue
+		}
+		if rule.blackhole {
+			return nil, errors.Errorf(errors.KindValidation, "blackholed by policy route %s", rule.name)
+		}
+		if rt := r.lookup(rule.table, dst); rt != nil {
+			rt.Rule = rule.name
+			return rt, nil
+		}
+	}
+
+	if rt := r.lookup(mainTable, dst); rt != nil {
+		return rt, nil
+	}
+	return nil, errors.Errorf(errors.KindNotFound, "no route to %s", dst)
+}
+
+// lookup does a longest-prefix match in a table.
+func (r *ConfigRouter) lookup(table int, dst netip.Addr) *Route {
+	var best *cfgRoute
+	for i := range r.routes[table] {
+		rt := &r.routes[table][i]
+		if rt.dst.Addr().Is4() != dst.Is4() || !rt.dst.Contains(dst) {
+			continue
+		}
+		if best == nil || rt.dst.Bits() > best.dst.Bits() || (rt.dst.Bits() == best.dst.Bits() && rt.metric < best.metric) {
+			best = rt
+		}
+	}
+	if best == nil {
+		return nil
+	}
+
+	out := &Route{OutInterface: best.iface, Gateway: best.gateway, Source: best.source, Table: tableName(table)}
+	if out.OutInterface == "" && best.gateway != "" {
+		// Resolve the interface from the connected route to the gateway
+		if gw, err := netip.ParseAddr(best.gateway); err == nil {
+			if via := r.lookup(mainTable, gw); via != nil {
+				out.OutInterface = via.OutInterface
+			}
+		}
+	}
+	if out.Source == "" && out.OutInterface != "" {
+		for _, rt := range r.routes[mainTable] {
+			if rt.iface == out.OutInterface && rt.source != "" && rt.dst.Addr().Is4() == dst.Is4() {
+				out.Source = rt.source
+				break
+			}
+		}
+	}
+	return out
+}
+
+func tableName(id int) string {
+	if id == mainTable {
+		return "main"
+	}
+	return fmt.Sprintf("%d", id)
+}
+
+// ifaceMatch matches interface names with nft/iproute-style wildcards.
+func ifaceMatch(pattern, name string) bool {
+	if strings.HasSuffix(pattern, "+") {
+		return strings.HasPrefix(name, strings.TrimSuffix(pattern, "+"))
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// ConfigZones returns a function mapping interface names to zones as
+// configured (interface zone attributes, then zone interface matches).
+func ConfigZones(cfg *config.Config) func(string) string {
+	return func(iface string) string {
+		for _, i := range cfg.Interfaces {
+			if i.Name == iface && i.Zone != "" {
+				return i.Zone
+			}
+		}
+		for _, z := range cfg.Zones {
+			if z.Interface != "" && ifaceMatch(z.Interface, iface) {
+				return z.Name
+			}
+			for _, m := range z.Matches {
+				if m.Interface != "" && ifaceMatch(m.Interface, iface) {
+					return z.Name
+				}
+			}
+		}
+		return ""
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+//go:build linux
+// +build linux
+
+package trace
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"grimm.is/flywall/internal/errors"
+)
+
+// KernelRouter asks the kernel for the route (ip route get), including
+// policy routing by mark and input interface.
+type KernelRouter struct{}
+
+// Route implements Router.
+func (KernelRouter) Route(src, dst netip.Addr, iif string, mark uint32) (*Route, error) {
+	opts := &netlink.RouteGetOptions{Iif: iif, Mark: mark}
+	if src.IsValid() && iif != "" {
+		opts.SrcAddr = net.IP(src.AsSlice())
+	}
+	routes, err := netlink.RouteGetWithOptions(net.IP(dst.AsSlice()), opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, errors.KindNotFound, "no route to %s", dst)
+	}
+	if len(routes) == 0 {
+		return nil, errors.Errorf(errors.KindNotFound, "no route to %s", dst)
+	}
+
+	rt := routes[0]
+	out := &Route{Table: tableName(rt.Table)}
+	switch rt.Type {
+	case unix.RTN_LOCAL:
+		out.Local = true
+		out.Table = "local"
+		return out, nil
+	case unix.RTN_BLACKHOLE, unix.RTN_UNREACHABLE, unix.RTN_PROHIBIT:
+		return nil, errors.Errorf(errors.KindNotFound, "route to %s is unreachable", dst)
+	}
+	if rt.LinkIndex > 0 {
+		if link, err := netlink.LinkByIndex(rt.LinkIndex); err == nil {
+			out.OutInterface = link.Attrs().Name
+		}
+	}
+	if rt.Gw != nil {
+		out.Gateway = rt.Gw.String()
+	}
+	if rt.Src != nil {
+		out.Source = rt.Src.String()
+	}
+	return out, nil
+}
+
+// NeighborMAC returns the MAC address the kernel has for ip, if any.
+func NeighborMAC(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+	family := netlink.FAMILY_V4
+	if addr.To4() == nil {
+		family = netlink.FAMILY_V6
+	}
+	neighs, err := netlink.NeighList(0, family)
+	if err != nil {
+		return ""
+	}
+	for _, n := range neighs {
+		if n.IP.Equal(addr) && len(n.HardwareAddr) > 0 {
+			return strings.ToLower(n.HardwareAddr.String())
+		}
+	}
+	return ""
+}
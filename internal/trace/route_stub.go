@@ -0,0 +1,25 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+//go:build !linux
+// +build !linux
+
+package trace
+
+import (
+	"net/netip"
+
+	"grimm.is/flywall/internal/errors"
+)
+
+// KernelRouter asks the kernel for routes; only available on Linux.
+type KernelRouter struct{}
+
+// Route implements Router.
+func (KernelRouter) Route(src, dst netip.Addr, iif string, mark uint32) (*Route, error) {
+	return nil, errors.New(errors.KindUnavailable, "kernel routing lookup is only available on Linux")
+}
+
+// NeighborMAC is not available off Linux.
+func NeighborMAC(ip string) string {
+	return ""
+}
@@ -0,0 +1,622 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package trace
+
+import (
+	"bufio"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"grimm.is/flywall/internal/errors"
+)
+
+// Ruleset is a parsed nftables ruleset: either a script of "add ..."
+// commands as generated by the firewall script builder, or the block
+// syntax printed by "nft list ruleset".
+type Ruleset struct {
+	Tables []*Table
+}
+
+// Table is an nftables table.
+type Table struct {
+	Family string
+	Name   string
+	Chains map[string]*Chain
+	Sets   map[string]*Set
+
+	nextHandle int
+}
+
+// Chain is an nftables chain. Base chains have a Type and Hook.
+type Chain struct {
+	Table    *Table
+	Name     string
+	Type     string // filter, nat, route; empty for regular chains
+	Hook     string
+	Priority int
+	Policy   string // accept (default) or drop
+	Rules    []*Rule
+}
+
+// Rule is a single rule in a chain.
+type Rule struct {
+	Handle int
+	Text   string
+
+	tokens []string
+}
+
+// Set is a named set or map. Elements are stored as key parts (split on
+// concatenation) and, for maps, the value tokens.
+type Set struct {
+	Name     string
+	Map      bool
+	Elements []Element
+}
+
+// Element is one set or map element.
+type Element struct {
+	Key   []string
+	Value []string
+}
+
+var families = map[string]bool{"ip": true, "ip6": true, "inet": true, "arp": true, "bridge": true, "netdev": true}
+
+// Standard priority names, as printed by nft list ruleset.
+var priorityNames = map[string]int{
+	"raw":      -300,
+	"mangle":   -150,
+	"dstnat":   -100,
+	"filter":   0,
+	"security": 50,
+	"srcnat":   100,
+	"out":      100,
+}
+
+var handleRe = regexp.MustCompile(`\s*#\s*handle\s+(\d+)\s*$`)
+
+// Parse reads a ruleset in either nft script or listing syntax.
+func Parse(text string) (*Ruleset, error) {
+	rs := &Ruleset{}
+	p := &parser{rs: rs}
+
+	sc := bufio.NewScanner(strings.NewReader(text))
+	sc.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		if err := p.line(sc.Text()); err != nil {
+			return nil, errors.Wrapf(err, errors.KindValidation, "line %d", lineNo)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if p.collect != nil || p.chain != nil || p.table != nil {
+		return nil, errors.New(errors.KindValidation, "unexpected end of ruleset: unclosed block")
+	}
+	return rs, nil
+}
+
+// parser holds block-syntax state between lines.
+type parser struct {
+	rs    *Ruleset
+	table *Table
+	chain *Chain
+
+	// Set/map bodies spanning several lines
+	collect     []string
+	collectKind string
+	collectName string
+	depth       int
+}
+
+func (p *parser) line(raw string) error {
+	handle := 0
+	if m := handleRe.FindStringSubmatch(raw); m != nil {
+		handle, _ = strconv.Atoi(m[1])
+		raw = raw[:len(raw)-len(m[0])]
+	}
+	text := strings.TrimSpace(raw)
+
+	if p.collect != nil {
+		toks := tokenize(text)
+		p.collect = append(p.collect, toks...)
+		for _, t := range toks {
+			switch t {
+			case "{":
+				p.depth++
+			case "}":
+				p.depth--
+			}
+		}
+		if p.depth == 0 {
+			p.finishObject()
+		} else if p.depth == 1 {
+			p.collect = append(p.collect, ";")
+		}
+		return nil
+	}
+
+	if text == "" || strings.HasPrefix(text, "#") {
+		return nil
+	}
+	toks := tokenize(text)
+
+	// Inside a chain block
+	if p.chain != nil {
+		switch toks[0] {
+		case "}":
+			p.chain = nil
+		case "type":
+			parseChainSpec(p.chain, toks)
+		case "policy":
+			parseChainSpec(p.chain, toks)
+		case "comment":
+		default:
+			p.chain.addRule(text, toks, handle, false)
+		}
+		return nil
+	}
+
+	// Inside a table block
+	if p.table != nil {
+		switch toks[0] {
+		case "}":
+			p.table = nil
+		case "chain":
+			if len(toks) < 2 {
+				return errors.New(errors.KindValidation, "chain without name")
+			}
+			p.chain = p.table.chainFor(unquote(toks[1]))
+			if toks[len(toks)-1] == "}" {
+				// Empty chain on one line
+				parseChainSpec(p.chain, toks[2:])
+				p.chain = nil
+			}
+		case "set", "map", "flowtable", "counter", "quota", "limit", "ct", "secmark", "synproxy":
+			name := ""
+			if len(toks) > 1 {
+				name = unquote(toks[1])
+			}
+			if toks[0] == "ct" && len(toks) > 2 {
+				name = unquote(toks[2])
+			}
+			p.startObject(toks[0], name, toks)
+		}
+		return nil
+	}
+
+	switch toks[0] {
+	case "table":
+		fam, name, rest := familyAndName(toks[1:])
+		p.table = p.rs.table(fam, name)
+		if len(rest) > 0 && rest[len(rest)-1] == "}" {
+			p.table = nil
+		}
+		return nil
+	case "add", "create", "insert", "replace":
+		return p.command(toks[0], toks[1:], text)
+	case "flush", "delete", "destroy":
+		p.remove(toks[0], toks[1:])
+		return nil
+	}
+	return nil
+}
+
+// startObject begins collecting a set/map body that may span lines.
+func (p *parser) startObject(kind, name string, toks []string) {
+	depth := 0
+	for _, t := range toks {
+		switch t {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		}
+	}
+	p.collectKind, p.collectName = kind, name
+	p.collect = append([]string{}, toks...)
+	p.depth = depth
+	if depth == 0 {
+		p.finishObject()
+	} else {
+		p.collect = append(p.collect, ";")
+	}
+}
+
+func (p *parser) finishObject() {
+	if p.collectKind == "set" || p.collectKind == "map" {
+		body := braceBody(p.collect)
+		s := p.table.setFor(p.collectName)
+		parseSetBody(s, body)
+		if p.collectKind == "map" {
+			s.Map = true
+		}
+	}
+	p.collect = nil
+}
+
+// command handles one "add ..." script line.
+func (p *parser) command(verb string, toks []string, text string) error {
+	if len(toks) == 0 {
+		return nil
+	}
+	obj, args := toks[0], toks[1:]
+	switch obj {
+	case "table":
+		fam, name, _ := familyAndName(args)
+		p.rs.table(fam, name)
+
+	case "chain":
+		fam, tbl, rest := familyAndName(args)
+		if len(rest) == 0 {
+			return errors.New(errors.KindValidation, "chain without name")
+		}
+		c := p.rs.table(fam, tbl).chainFor(unquote(rest[0]))
+		parseChainSpec(c, braceBody(rest[1:]))
+
+	case "rule":
+		fam, tbl, rest := familyAndName(args)
+		if len(rest) == 0 {
+			return errors.New(errors.KindValidation, "rule without chain")
+		}
+		c := p.rs.table(fam, tbl).chainFor(unquote(rest[0]))
+		ruleToks := rest[1:]
+		// Optional "position N" / "handle N" / "index N" prefix
+		if len(ruleToks) >= 2 && (ruleToks[0] == "position" || ruleToks[0] == "handle" || ruleToks[0] == "index") {
+			ruleToks = ruleToks[2:]
+		}
+		c.addRule(ruleText(text, rest[0]), ruleToks, 0, verb == "insert")
+
+	case "set", "map":
+		fam, tbl, rest := familyAndName(args)
+		if len(rest) == 0 {
+			return errors.Errorf(errors.KindValidation, "%s without name", obj)
+		}
+		s := p.rs.table(fam, tbl).setFor(unquote(rest[0]))
+		parseSetBody(s, braceBody(rest[1:]))
+		if obj == "map" {
+			s.Map = true
+		}
+
+	case "element":
+		fam, tbl, rest := familyAndName(args)
+		if len(rest) == 0 {
+			return errors.New(errors.KindValidation, "element without set")
+		}
+		s := p.rs.table(fam, tbl).setFor(unquote(rest[0]))
+		s.Elements = append(s.Elements, parseElements(braceBody(rest[1:]))...)
+	}
+	return nil
+}
+
+// remove handles flush/delete commands.
+func (p *parser) remove(verb string, toks []string) {
+	if len(toks) == 0 {
+		return
+	}
+	switch toks[0] {
+	case "ruleset":
+		p.rs.Tables = nil
+	case "table":
+		fam, name, _ := familyAndName(toks[1:])
+		for i, t := range p.rs.Tables {
+			if t.Family == fam && t.Name == name {
+				if verb == "flush" {
+					for _, c := range t.Chains {
+						c.Rules = nil
+					}
+				} else {
+					p.rs.Tables = append(p.rs.Tables[:i], p.rs.Tables[i+1:]...)
+				}
+				return
+			}
+		}
+	case "chain":
+		fam, tbl, rest := familyAndName(toks[1:])
+		if len(rest) == 0 {
+			return
+		}
+		t := p.rs.table(fam, tbl)
+		if verb == "flush" {
+			t.chainFor(unquote(rest[0])).Rules = nil
+		} else {
+			delete(t.Chains, unquote(rest[0]))
+		}
+	case "set", "map":
+		fam, tbl, rest := familyAndName(toks[1:])
+		if len(rest) == 0 {
+			return
+		}
+		t := p.rs.table(fam, tbl)
+		if verb == "flush" {
+			t.setFor(unquote(rest[0])).Elements = nil
+		} else {
+			delete(t.Sets, unquote(rest[0]))
+		}
+	}
+}
+
+// ruleText returns the rule text following the chain name on a script line.
+func ruleText(line, chain string) string {
+	idx := strings.Index(line, " "+chain+" ")
+	if idx < 0 {
+		return line
+	}
+	return strings.TrimSpace(line[idx+len(chain)+2:])
+}
+
+func (rs *Ruleset) table(family, name string) *Table {
+	for _, t := range rs.Tables {
+		if t.Family == family && t.Name == name {
+			return t
+		}
+	}
+	t := &Table{Family: family, Name: name, Chains: make(map[string]*Chain), Sets: make(map[string]*Set)}
+	rs.Tables = append(rs.Tables, t)
+	return t
+}
+
+func (t *Table) chainFor(name string) *Chain {
+	c, ok := t.Chains[name]
+	if !ok {
+		c = &Chain{Table: t, Name: name, Policy: "accept"}
+		t.Chains[name] = c
+	}
+	return c
+}
+
+func (t *Table) setFor(name string) *Set {
+	s, ok := t.Sets[name]
+	if !ok {
+		s = &Set{Name: name}
+		t.Sets[name] = s
+	}
+	return s
+}
+
+func (c *Chain) addRule(text string, toks []string, handle int, prepend bool) {
+	if handle == 0 {
+		c.Table.nextHandle++
+		handle = c.Table.nextHandle
+	} else if handle > c.Table.nextHandle {
+		c.Table.nextHandle = handle
+	}
+	r := &Rule{Handle: handle, Text: text, tokens: toks}
+	if prepend {
+		c.Rules = append([]*Rule{r}, c.Rules...)
+		return
+	}
+	c.Rules = append(c.Rules, r)
+}
+
+// BaseChains returns the base chains attached to hook for packets of the
+// given family ("ip" or "ip6"), in priority order.
+func (rs *Ruleset) BaseChains(hook, family string) []*Chain {
+	var res []*Chain
+	for _, t := range rs.Tables {
+		if t.Family != "inet" && t.Family != family {
+			continue
+		}
+		for _, c := range t.Chains {
+			if c.Hook == hook && c.Type != "" {
+				res = append(res, c)
+			}
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Priority != res[j].Priority {
+			return res[i].Priority < res[j].Priority
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// parseChainSpec reads "type filter hook input priority 0; policy drop;".
+func parseChainSpec(c *Chain, toks []string) {
+	for i := 0; i < len(toks); i++ {
+		switch toks[i] {
+		case "type":
+			if i+1 < len(toks) {
+				c.Type = toks[i+1]
+				i++
+			}
+		case "hook":
+			if i+1 < len(toks) {
+				c.Hook = toks[i+1]
+				i++
+			}
+		case "priority":
+			i++
+			if i >= len(toks) {
+				break
+			}
+			prio, ok := priorityNames[toks[i]]
+			if !ok {
+				prio, _ = strconv.Atoi(toks[i])
+			}
+			if i+2 < len(toks) && (toks[i+1] == "+" || toks[i+1] == "-") {
+				n, _ := strconv.Atoi(toks[i+2])
+				if toks[i+1] == "-" {
+					n = -n
+				}
+				prio += n
+				i += 2
+			}
+			c.Priority = prio
+		case "policy":
+			if i+1 < len(toks) {
+				c.Policy = strings.TrimSuffix(toks[i+1], ";")
+				i++
+			}
+		case "comment":
+			i++
+		}
+	}
+}
+
+// parseSetBody reads "type ipv4_addr; flags interval; elements = { ... };".
+func parseSetBody(s *Set, toks []string) {
+	for _, stmt := range splitTop(toks, ";") {
+		if len(stmt) == 0 {
+			continue
+		}
+		switch stmt[0] {
+		case "type", "typeof":
+			for _, t := range stmt {
+				if t == ":" {
+					s.Map = true
+				}
+			}
+		case "elements":
+			s.Elements = append(s.Elements, parseElements(braceBody(stmt))...)
+		}
+	}
+}
+
+// Element attributes printed after the key in listings
+var elementAttrs = map[string]bool{"timeout": true, "expires": true, "counter": true, "packets": true, "bytes": true, "comment": true}
+
+// parseElements splits "a, b . c, d : jump x" into elements.
+func parseElements(toks []string) []Element {
+	var res []Element
+	for _, item := range splitTop(toks, ",") {
+		if len(item) == 0 {
+			continue
+		}
+		var el Element
+		key := item
+		for i, t := range item {
+			if t == ":" {
+				key, el.Value = item[:i], item[i+1:]
+				break
+			}
+		}
+		for _, part := range splitTop(key, ".") {
+			if len(part) == 0 {
+				continue
+			}
+			el.Key = append(el.Key, unquote(part[0]))
+		}
+		_ = elementAttrs
+		res = append(res, el)
+	}
+	return res
+}
+
+// braceBody returns the tokens inside the first top-level { ... } group.
+func braceBody(toks []string) []string {
+	start := -1
+	depth := 0
+	for i, t := range toks {
+		switch t {
+		case "{":
+			if depth == 0 {
+				start = i + 1
+			}
+			depth++
+		case "}":
+			depth--
+			if depth == 0 && start >= 0 {
+				return toks[start:i]
+			}
+		}
+	}
+	if start >= 0 {
+		return toks[start:]
+	}
+	return nil
+}
+
+// splitTop splits tokens on sep outside of braces and parentheses.
+func splitTop(toks []string, sep string) [][]string {
+	var res [][]string
+	var cur []string
+	depth := 0
+	for _, t := range toks {
+		switch t {
+		case "{", "(":
+			depth++
+		case "}", ")":
+			depth--
+		}
+		if t == sep && depth == 0 {
+			res = append(res, cur)
+			cur = nil
+			continue
+		}
+		cur = append(cur, t)
+	}
+	return append(res, cur)
+}
+
+// familyAndName parses "[family] name ..." as used by nft commands, where
+// the family defaults to ip.
+func familyAndName(toks []string) (family, name string, rest []string) {
+	if len(toks) == 0 {
+		return "ip", "", nil
+	}
+	if families[toks[0]] && len(toks) > 1 && toks[1] != "{" {
+		return toks[0], unquote(toks[1]), toks[2:]
+	}
+	return "ip", unquote(toks[0]), toks[1:]
+}
+
+// tokenize splits an nft statement into tokens. Quoted strings stay whole
+// (with their quotes), and braces, parentheses, commas, semicolons and the
+// bitwise operators are tokens of their own.
+func tokenize(s string) []string {
+	var toks []string
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case strings.IndexByte("{},;()|&", c) >= 0:
+			toks = append(toks, string(c))
+			i++
+		default:
+			start := i
+			for i < len(s) {
+				c = s[i]
+				if c == '"' {
+					// Quoted section, possibly inside a word ("09:00"-"17:00")
+					i++
+					for i < len(s) && s[i] != '"' {
+						if s[i] == '\\' {
+							i++
+						}
+						i++
+					}
+					i++
+					continue
+				}
+				if c == ' ' || c == '\t' || c == '\r' || c == '\n' || strings.IndexByte("{},;()|&", c) >= 0 {
+					break
+				}
+				i++
+			}
+			if i > len(s) {
+				i = len(s)
+			}
+			toks = append(toks, s[start:i])
+		}
+	}
+	return toks
+}
+
+// unquote strips surrounding double quotes.
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+		return s[1 : len(s)-1]
+	}
+	return s
+}
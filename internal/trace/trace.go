@@ -0,0 +1,567 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+// Package trace answers "why was this packet dropped?" by walking a packet
+// through a compiled nftables ruleset the way the kernel would: prerouting
+// (mangle marks, DNAT), the routing decision (including policy routing),
+// input/forward/output with zone dispatch, IPSets and learning-engine
+// queues, then postrouting (SNAT/masquerade). The output mirrors
+// "nft monitor trace".
+//
+// The same tracer runs against the live kernel ruleset or, offline, against
+// the ruleset compiled from a candidate configuration.
+package trace
+
+import (
+	"fmt"
+	"math/rand"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"grimm.is/flywall/internal/brand"
+	"grimm.is/flywall/internal/errors"
+)
+
+// Packet describes the packet to trace.
+type Packet struct {
+	InInterface string `json:"in_interface,omitempty"` // empty for locally generated packets
+	Protocol    string `json:"protocol"`               // tcp, udp, icmp, icmpv6 or a protocol number
+	Src         string `json:"src"`
+	Dst         string `json:"dst"`
+	SrcPort     int    `json:"src_port,omitempty"`
+	DstPort     int    `json:"dst_port,omitempty"`
+	State       string `json:"state,omitempty"` // conntrack state: new (default), established, related, invalid
+	Mark        uint32 `json:"mark,omitempty"`
+	SrcMAC      string `json:"src_mac,omitempty"`
+	ICMPType    string `json:"icmp_type,omitempty"` // default echo-request
+}
+
+// Route is the routing decision taken between prerouting and
+// input/forward.
+type Route struct {
+	Local        bool   `json:"local"`
+	OutInterface string `json:"out_interface,omitempty"`
+	Gateway      string `json:"gateway,omitempty"`
+	Table        string `json:"table,omitempty"`
+	Source       string `json:"source,omitempty"` // preferred source address, used by masquerade
+	Rule         string `json:"rule,omitempty"`   // policy routing rule that selected the table
+}
+
+// Router resolves routes, honouring policy routing by mark and interface.
+type Router interface {
+	Route(src, dst netip.Addr, iif string, mark uint32) (*Route, error)
+}
+
+// LearningFunc reports the learning engine's verdict ("accept" or "drop")
+// for a packet sent to its queue, with a short explanation.
+type LearningFunc func(pkt Packet) (verdict, note string)
+
+// Step is one line of the trace.
+type Step struct {
+	Kind    string   `json:"kind"` // packet, rule, return, policy, route, learning
+	Hook    string   `json:"hook,omitempty"`
+	Family  string   `json:"family,omitempty"`
+	Table   string   `json:"table,omitempty"`
+	Chain   string   `json:"chain,omitempty"`
+	Handle  int      `json:"handle,omitempty"`
+	Rule    string   `json:"rule,omitempty"`
+	Verdict string   `json:"verdict,omitempty"`
+	Packet  string   `json:"packet,omitempty"`
+	Notes   []string `json:"notes,omitempty"`
+}
+
+// Result is the outcome of a trace.
+type Result struct {
+	ID         string   `json:"id"`
+	Packet     Packet   `json:"packet"`
+	Translated *Packet  `json:"translated,omitempty"` // packet after NAT, if it changed
+	InZone     string   `json:"in_zone,omitempty"`
+	OutZone    string   `json:"out_zone,omitempty"`
+	Route      *Route   `json:"route,omitempty"`
+	Verdict    string   `json:"verdict"`            // accept or drop
+	Decision   *Step    `json:"decision,omitempty"` // rule or policy that decided the verdict
+	Steps      []Step   `json:"steps"`
+	Warnings   []string `json:"warnings,omitempty"`
+}
+
+// Tracer walks packets through a ruleset.
+type Tracer struct {
+	Ruleset  *Ruleset
+	Router   Router
+	Zones    func(iface string) string // interface to zone name
+	Learning LearningFunc              // nil when no learning engine is available
+	Now      func() time.Time          // for meta day/hour; defaults to time.Now
+}
+
+// Maximum jump depth, as enforced by the kernel
+const maxJumpDepth = 16
+
+// pktState is the packet as seen by the rules, including mangling.
+type pktState struct {
+	family       string // ip or ip6
+	iif, oif     string
+	proto        uint8
+	src, dst     netip.Addr
+	sport, dport uint16
+	ctState      uint32
+	ctStatus     uint32
+	mark, ctMark uint32
+	tcpFlags     uint32
+	icmpType     string
+	srcMAC       string
+	redirected   bool
+
+	router Router
+	route  *Route
+	now    time.Time
+	notes  []string
+}
+
+func (p *pktState) note(format string, args ...interface{}) {
+	p.notes = append(p.notes, fmt.Sprintf(format, args...))
+}
+
+// Trace runs pkt through the ruleset.
+func (t *Tracer) Trace(pkt Packet) (*Result, error) {
+	if t.Ruleset == nil {
+		return nil, errors.New(errors.KindValidation, "no ruleset to trace against")
+	}
+	p, err := newPktState(pkt)
+	if err != nil {
+		return nil, err
+	}
+	p.router = t.Router
+	p.now = time.Now()
+	if t.Now != nil {
+		p.now = t.Now()
+	}
+
+	res := &Result{
+		ID:     fmt.Sprintf("%08x", rand.Uint32()),
+		Packet: pkt,
+		InZone: t.zone(pkt.InInterface),
+	}
+	tr := &run{t: t, p: p, res: res}
+
+	if pkt.InInterface == "" {
+		// Locally generated: routed before the output hook
+		if !tr.routeStep() || !tr.hook("output") {
+			return tr.finish(), nil
+		}
+		tr.hook("postrouting")
+		return tr.finish(), nil
+	}
+
+	if !tr.hook("prerouting") || !tr.routeStep() {
+		return tr.finish(), nil
+	}
+	if p.route.Local {
+		tr.hook("input")
+		return tr.finish(), nil
+	}
+	if tr.hook("forward") {
+		tr.hook("postrouting")
+	}
+	return tr.finish(), nil
+}
+
+func (t *Tracer) zone(iface string) string {
+	if iface == "" || t.Zones == nil {
+		return ""
+	}
+	return t.Zones(iface)
+}
+
+// run is the state of one trace.
+type run struct {
+	t   *Tracer
+	p   *pktState
+	res *Result
+}
+
+// hook runs the base chains of a hook in priority order. It returns false
+// once the packet has been dropped.
+func (tr *run) hook(name string) bool {
+	for _, c := range tr.t.Ruleset.BaseChains(name, tr.p.family) {
+		if c.Type == "nat" && tr.p.ctState&uint32(ctStateBits["new"]) == 0 {
+			// NAT chains only see the first packet of a connection
+			continue
+		}
+		tr.add(Step{Kind: "packet", Hook: name, Family: c.Table.Family, Table: c.Table.Name, Chain: c.Name, Packet: tr.p.describe()})
+		verdict := tr.chain(name, c)
+		if verdict == "drop" {
+			tr.res.Verdict = "drop"
+			return false
+		}
+	}
+	return true
+}
+
+// chain evaluates a base chain and the chains it jumps to.
+func (tr *run) chain(hook string, base *Chain) string {
+	type frame struct {
+		c *Chain
+		i int
+	}
+	stack := []frame{{c: base}}
+
+	for len(stack) > 0 {
+		f := &stack[len(stack)-1]
+		if f.i >= len(f.c.Rules) {
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				break
+			}
+			tr.add(Step{Kind: "return", Hook: hook, Family: f.c.Table.Family, Table: f.c.Table.Name, Chain: f.c.Name, Verdict: "continue"})
+			continue
+		}
+		rule := f.c.Rules[f.i]
+		f.i++
+
+		tr.p.notes = nil
+		r := &ruleEval{toks: append([]string(nil), rule.tokens...), p: tr.p, table: f.c.Table, t: tr.t}
+		matched, out, err := r.eval()
+		if err != nil {
+			tr.warn("%s %s %s handle %d: %v; rule treated as not matching", f.c.Table.Family, f.c.Table.Name, f.c.Name, rule.Handle, err)
+			continue
+		}
+		if !matched {
+			continue
+		}
+
+		verdict := out.verdict
+		switch verdict {
+		case "":
+			verdict = "continue"
+		case "jump", "goto":
+			verdict += " " + out.target
+		}
+		step := Step{
+			Kind: "rule", Hook: hook, Family: f.c.Table.Family, Table: f.c.Table.Name, Chain: f.c.Name,
+			Handle: rule.Handle, Rule: rule.Text, Verdict: verdict, Notes: tr.p.notes,
+		}
+		tr.add(step)
+
+		switch out.verdict {
+		case "accept", "drop":
+			tr.decide(hook, out.verdict)
+			return out.verdict
+		case "return":
+			stack = stack[:len(stack)-1]
+		case "jump", "goto":
+			target, ok := f.c.Table.Chains[out.target]
+			if !ok {
+				tr.warn("chain %s not found in table %s %s", out.target, f.c.Table.Family, f.c.Table.Name)
+				continue
+			}
+			if len(stack) >= maxJumpDepth {
+				tr.warn("jump depth exceeded at chain %s", out.target)
+				tr.decide(hook, "drop")
+				return "drop"
+			}
+			if out.verdict == "goto" {
+				stack[len(stack)-1] = frame{c: target}
+			} else {
+				stack = append(stack, frame{c: target})
+			}
+		}
+	}
+
+	tr.add(Step{Kind: "policy", Hook: hook, Family: base.Table.Family, Table: base.Table.Name, Chain: base.Name, Verdict: base.Policy})
+	tr.decide(hook, base.Policy)
+	return base.Policy
+}
+
+// decide records the step that decided the packet's fate: the last drop,
+// or the last accept in a filter hook.
+func (tr *run) decide(hook, verdict string) {
+	last := tr.res.Steps[len(tr.res.Steps)-1]
+	switch {
+	case verdict == "drop":
+		tr.res.Decision = &last
+	case hook == "input" || hook == "forward" || hook == "output" || tr.res.Decision == nil:
+		tr.res.Decision = &last
+	}
+}
+
+// routeStep takes the routing decision. It returns false if the packet
+// cannot be routed.
+func (tr *run) routeStep() bool {
+	p := tr.p
+	step := Step{Kind: "route"}
+
+	switch {
+	case p.redirected:
+		p.route = &Route{Local: true}
+	case tr.t.Router == nil:
+		tr.warn("no router available; assuming the packet is forwarded")
+		p.route = &Route{}
+	default:
+		src := p.src
+		if p.iif == "" {
+			src = netip.Addr{}
+		}
+		rt, err := tr.t.Router.Route(src, p.dst, p.iif, p.mark)
+		if err != nil {
+			step.Verdict = "drop"
+			step.Notes = []string{err.Error()}
+			tr.add(step)
+			tr.res.Verdict = "drop"
+			tr.res.Decision = &step
+			return false
+		}
+		p.route = rt
+	}
+
+	tr.res.Route = p.route
+	if p.route.Local {
+		tr.res.OutZone = brand.LowerName
+	} else {
+		p.oif = p.route.OutInterface
+		tr.res.OutZone = tr.t.zone(p.oif)
+	}
+	if p.iif == "" {
+		tr.res.InZone = brand.LowerName
+	}
+	step.Packet = p.route.describe(p.dst)
+	if tr.res.InZone != "" || tr.res.OutZone != "" {
+		step.Notes = append(step.Notes, fmt.Sprintf("zones %s -> %s", orDash(tr.res.InZone), orDash(tr.res.OutZone)))
+	}
+	tr.add(step)
+	return true
+}
+
+func (tr *run) add(s Step) {
+	tr.res.Steps = append(tr.res.Steps, s)
+}
+
+func (tr *run) warn(format string, args ...interface{}) {
+	tr.res.Warnings = append(tr.res.Warnings, fmt.Sprintf(format, args...))
+}
+
+func (tr *run) finish() *Result {
+	if tr.res.Verdict == "" {
+		tr.res.Verdict = "accept"
+	}
+	out := tr.p.packet(tr.res.Packet)
+	if out != tr.res.Packet {
+		tr.res.Translated = &out
+	}
+	return tr.res
+}
+
+// newPktState validates a packet description.
+func newPktState(pkt Packet) (*pktState, error) {
+	p := &pktState{iif: pkt.InInterface, mark: pkt.Mark, srcMAC: strings.ToLower(pkt.SrcMAC)}
+
+	var err error
+	if p.src, err = netip.ParseAddr(pkt.Src); err != nil {
+		return nil, errors.Errorf(errors.KindValidation, "invalid source address %q", pkt.Src)
+	}
+	if p.dst, err = netip.ParseAddr(pkt.Dst); err != nil {
+		return nil, errors.Errorf(errors.KindValidation, "invalid destination address %q", pkt.Dst)
+	}
+	p.src, p.dst = p.src.Unmap(), p.dst.Unmap()
+	if p.src.Is4() != p.dst.Is4() {
+		return nil, errors.New(errors.KindValidation, "source and destination must be the same address family")
+	}
+	p.family = "ip"
+	if p.src.Is6() {
+		p.family = "ip6"
+	}
+
+	proto := strings.ToLower(pkt.Protocol)
+	if proto == "" {
+		return nil, errors.New(errors.KindValidation, "protocol is required")
+	}
+	if n, ok := protoNums[proto]; ok {
+		p.proto = uint8(n)
+	} else if n, err := strconv.ParseUint(proto, 10, 8); err == nil {
+		p.proto = uint8(n)
+	} else {
+		return nil, errors.Errorf(errors.KindValidation, "unknown protocol %q", pkt.Protocol)
+	}
+
+	if portProtos[p.proto] {
+		if pkt.DstPort <= 0 || pkt.DstPort > 65535 {
+			return nil, errors.Errorf(errors.KindValidation, "destination port is required for %s", proto)
+		}
+		if pkt.SrcPort < 0 || pkt.SrcPort > 65535 {
+			return nil, errors.Errorf(errors.KindValidation, "invalid source port %d", pkt.SrcPort)
+		}
+		p.sport, p.dport = uint16(pkt.SrcPort), uint16(pkt.DstPort)
+	}
+
+	state := strings.ToLower(pkt.State)
+	if state == "" {
+		state = "new"
+	}
+	bit, ok := ctStateBits[state]
+	if !ok {
+		return nil, errors.Errorf(errors.KindValidation, "unknown conntrack state %q", pkt.State)
+	}
+	p.ctState = uint32(bit)
+	if p.proto == 6 {
+		p.tcpFlags = uint32(tcpFlagBits["syn"])
+		if state != "new" {
+			p.tcpFlags = uint32(tcpFlagBits["ack"])
+		}
+	}
+	if state == "established" {
+		p.ctStatus = uint32(ctStatusBits["seen-reply"] | ctStatusBits["confirmed"])
+	}
+
+	p.icmpType = pkt.ICMPType
+	if p.icmpType == "" && (p.proto == 1 || p.proto == 58) {
+		p.icmpType = "echo-request"
+	}
+	return p, nil
+}
+
+// packet returns the current (translated) packet in the shape of orig.
+func (p *pktState) packet(orig Packet) Packet {
+	out := orig
+	out.Src, out.Dst = p.src.String(), p.dst.String()
+	if portProtos[p.proto] {
+		out.SrcPort, out.DstPort = int(p.sport), int(p.dport)
+	}
+	return out
+}
+
+// describe prints the packet the way nft monitor trace does.
+func (p *pktState) describe() string {
+	var b strings.Builder
+	if p.iif != "" {
+		fmt.Fprintf(&b, "iif %q ", p.iif)
+	}
+	if p.oif != "" {
+		fmt.Fprintf(&b, "oif %q ", p.oif)
+	}
+	if p.srcMAC != "" {
+		fmt.Fprintf(&b, "ether saddr %s ", p.srcMAC)
+	}
+	protoKey := "protocol"
+	if p.family == "ip6" {
+		protoKey = "nexthdr"
+	}
+	name, ok := protoNames[p.proto]
+	if !ok {
+		name = strconv.Itoa(int(p.proto))
+	}
+	fmt.Fprintf(&b, "%s saddr %s %s daddr %s %s %s %s", p.family, p.src, p.family, p.dst, p.family, protoKey, name)
+	if portProtos[p.proto] {
+		fmt.Fprintf(&b, " %s sport %d %s dport %d", name, p.sport, name, p.dport)
+	}
+	if p.proto == 6 {
+		fmt.Fprintf(&b, " tcp flags == %s", flagNames(p.tcpFlags))
+	}
+	if p.proto == 1 || p.proto == 58 {
+		fmt.Fprintf(&b, " %s type %s", name, p.icmpType)
+	}
+	if p.mark != 0 {
+		fmt.Fprintf(&b, " meta mark 0x%08x", p.mark)
+	}
+	if p.ctMark != 0 {
+		fmt.Fprintf(&b, " ct mark 0x%08x", p.ctMark)
+	}
+	for name, bit := range ctStateBits {
+		if p.ctState == uint32(bit) {
+			fmt.Fprintf(&b, " ct state %s", name)
+		}
+	}
+	return b.String()
+}
+
+func flagNames(flags uint32) string {
+	var names []string
+	for _, n := range []string{"fin", "syn", "rst", "psh", "ack", "urg", "ecn", "cwr"} {
+		if flags&uint32(tcpFlagBits[n]) != 0 {
+			names = append(names, n)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+func (rt *Route) describe(dst netip.Addr) string {
+	if rt.Local {
+		return fmt.Sprintf("local %s", dst)
+	}
+	s := dst.String()
+	if rt.Gateway != "" {
+		s += " via " + rt.Gateway
+	}
+	if rt.OutInterface != "" {
+		s += " dev " + rt.OutInterface
+	}
+	if rt.Table != "" {
+		s += " table " + rt.Table
+	}
+	if rt.Rule != "" {
+		s += " (policy " + rt.Rule + ")"
+	}
+	return s
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Format renders the trace in the style of nft monitor trace.
+func (r *Result) Format() string {
+	var b strings.Builder
+	for _, s := range r.Steps {
+		prefix := fmt.Sprintf("trace id %s %s %s %s", r.ID, s.Family, s.Table, s.Chain)
+		switch s.Kind {
+		case "packet":
+			fmt.Fprintf(&b, "%s packet: %s\n", prefix, s.Packet)
+		case "rule":
+			fmt.Fprintf(&b, "%s rule %s (verdict %s)\n", prefix, s.Rule, s.Verdict)
+		case "return":
+			fmt.Fprintf(&b, "%s verdict %s\n", prefix, s.Verdict)
+		case "policy":
+			fmt.Fprintf(&b, "%s policy %s\n", prefix, s.Verdict)
+		case "route":
+			if s.Verdict == "drop" {
+				fmt.Fprintf(&b, "trace id %s route: no route (verdict drop)\n", r.ID)
+			} else {
+				fmt.Fprintf(&b, "trace id %s route: %s\n", r.ID, s.Packet)
+			}
+		}
+		for _, n := range s.Notes {
+			fmt.Fprintf(&b, "    # %s\n", n)
+		}
+	}
+	for _, w := range r.Warnings {
+		fmt.Fprintf(&b, "warning: %s\n", w)
+	}
+
+	fmt.Fprintf(&b, "verdict %s", r.Verdict)
+	if d := r.Decision; d != nil {
+		switch d.Kind {
+		case "rule":
+			fmt.Fprintf(&b, " by %s %s %s rule handle %d", d.Family, d.Table, d.Chain, d.Handle)
+		case "policy":
+			fmt.Fprintf(&b, " by %s %s %s policy", d.Family, d.Table, d.Chain)
+		case "route":
+			b.WriteString(" by routing")
+		}
+	}
+	b.WriteString("\n")
+	if r.Translated != nil {
+		t := r.Translated
+		fmt.Fprintf(&b, "translated: %s", t.Src)
+		if t.SrcPort != 0 {
+			fmt.Fprintf(&b, ":%d", t.SrcPort)
+		}
+		fmt.Fprintf(&b, " -> %s", t.Dst)
+		if t.DstPort != 0 {
+			fmt.Fprintf(&b, ":%d", t.DstPort)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
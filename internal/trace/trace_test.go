@@ -0,0 +1,288 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package trace
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"grimm.is/flywall/internal/config"
+)
+
+const portForwardHCL = `
+schema_version = "1.0"
+ip_forwarding = true
+
+interface "eth0" {
+  zone    = "wan"
+  ipv4    = ["203.0.113.1/24"]
+  gateway = "203.0.113.254"
+}
+
+interface "eth1" {
+  zone = "lan"
+  ipv4 = ["10.0.0.1/24"]
+}
+
+nat "web-server" {
+  type         = "dnat"
+  in_interface = "eth0"
+  proto        = "tcp"
+  dest_port    = "80"
+  to_ip        = "10.0.0.10"
+  to_port      = "8080"
+}
+
+nat "masquerade" {
+  type          = "masquerade"
+  out_interface = "eth0"
+}
+
+policy "wan" "lan" {
+  rule "allow-web" {
+    proto     = "tcp"
+    dest_ip   = "10.0.0.10"
+    dest_port = 8080
+    action    = "accept"
+  }
+}
+
+policy "lan" "wan" {
+  rule "allow-all" {
+    action = "accept"
+  }
+}
+`
+
+func offlineTracer(t *testing.T, hcl string) *Tracer {
+	t.Helper()
+	cfg, err := config.LoadHCL([]byte(hcl), "test.hcl")
+	require.NoError(t, err)
+	tracer, err := NewOfflineTracer(cfg)
+	require.NoError(t, err)
+	return tracer
+}
+
+func TestTrace_PortForward(t *testing.T) {
+	tracer := offlineTracer(t, portForwardHCL)
+
+	res, err := tracer.Trace(Packet{
+		InInterface: "eth0", Protocol: "tcp",
+		Src: "198.51.100.7", SrcPort: 40000, Dst: "203.0.113.1", DstPort: 80,
+	})
+	require.NoError(t, err)
+	t.Log("\n" + res.Format())
+
+	assert.Equal(t, "accept", res.Verdict)
+	require.NotNil(t, res.Translated)
+	assert.Equal(t, "10.0.0.10", res.Translated.Dst)
+	assert.Equal(t, 8080, res.Translated.DstPort)
+	assert.Equal(t, "wan", res.InZone)
+	assert.Equal(t, "lan", res.OutZone)
+	require.NotNil(t, res.Route)
+	assert.Equal(t, "eth1", res.Route.OutInterface)
+	require.NotNil(t, res.Decision)
+	assert.Equal(t, "policy_wan_lan", res.Decision.Chain)
+	assert.Contains(t, res.Decision.Rule, "allow-web")
+	assert.Empty(t, res.Warnings)
+}
+
+func TestTrace_DroppedAtInput(t *testing.T) {
+	tracer := offlineTracer(t, portForwardHCL)
+
+	res, err := tracer.Trace(Packet{
+		InInterface: "eth0", Protocol: "tcp",
+		Src: "198.51.100.7", SrcPort: 40000, Dst: "203.0.113.1", DstPort: 22,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "drop", res.Verdict)
+	assert.Nil(t, res.Translated)
+	require.NotNil(t, res.Route)
+	assert.True(t, res.Route.Local)
+	require.NotNil(t, res.Decision)
+	assert.Equal(t, "input", res.Decision.Chain)
+	assert.Contains(t, res.Format(), "verdict drop by inet flywall input")
+}
+
+func TestTrace_Masquerade(t *testing.T) {
+	tracer := offlineTracer(t, portForwardHCL)
+
+	res, err := tracer.Trace(Packet{
+		InInterface: "eth1", Protocol: "udp",
+		Src: "10.0.0.50", SrcPort: 5000, Dst: "1.1.1.1", DstPort: 53,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "accept", res.Verdict)
+	assert.Equal(t, "203.0.113.254", res.Route.Gateway)
+	require.NotNil(t, res.Translated)
+	assert.Equal(t, "203.0.113.1", res.Translated.Src)
+	assert.Equal(t, "1.1.1.1", res.Translated.Dst)
+}
+
+func TestTrace_ConntrackHelper(t *testing.T) {
+	tracer := offlineTracer(t, strings.Replace(portForwardHCL, `policy "lan" "wan" {`, `policy "lan" "wan" {
+  helpers = ["ftp"]`, 1))
+
+	res, err := tracer.Trace(Packet{
+		InInterface: "eth1", Protocol: "tcp",
+		Src: "10.0.0.50", SrcPort: 40000, Dst: "198.51.100.7", DstPort: 21,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "accept", res.Verdict)
+	assert.Contains(t, res.Format(), "conntrack helper helper_ftp assigned")
+	assert.Empty(t, res.Warnings)
+}
+
+func TestTrace_Validation(t *testing.T) {
+	tracer := &Tracer{Ruleset: &Ruleset{}}
+	for _, pkt := range []Packet{
+		{Protocol: "tcp", Src: "bogus", Dst: "10.0.0.1", DstPort: 80},
+		{Protocol: "tcp", Src: "10.0.0.1", Dst: "2001:db8::1", DstPort: 80},
+		{Protocol: "tcp", Src: "10.0.0.1", Dst: "10.0.0.2"},
+		{Protocol: "nope", Src: "10.0.0.1", Dst: "10.0.0.2"},
+		{Protocol: "icmp", Src: "10.0.0.1", Dst: "10.0.0.2", State: "sideways"},
+	} {
+		_, err := tracer.Trace(pkt)
+		assert.Error(t, err, "%+v", pkt)
+	}
+}
+
+// Output of "nft -a list ruleset" (trimmed)
+const listing = `table inet filter { # handle 1
+	set blocked { # handle 3
+		type ipv4_addr
+		flags interval
+		elements = { 192.0.2.0/24,
+			     198.51.100.66 }
+	}
+
+	map zone_vmap { # handle 4
+		type ifname . ifname : verdict
+		elements = { "lan0" . "wan0" : jump lan_wan,
+			     "wan0" . "lan0" : drop }
+	}
+
+	chain prerouting { # handle 5
+		type filter hook prerouting priority mangle; policy accept;
+		iifname "lan0" ip saddr 10.1.0.0/16 meta mark set 0x00000100 ct mark set meta mark # handle 10
+	}
+
+	chain forward { # handle 6
+		type filter hook forward priority filter; policy drop;
+		ct state established,related accept # handle 11
+		ip daddr @blocked counter packets 0 bytes 0 drop # handle 12
+		iifname . oifname vmap @zone_vmap # handle 13
+	}
+
+	chain lan_wan { # handle 7
+		tcp dport { 22, 8000-9000 } reject with tcp reset # handle 14
+		meta mark & 0x0000ff00 == 0x00000100 accept # handle 15
+		tcp flags & (syn | ack) == syn counter accept comment "new tcp" # handle 16
+	}
+}
+`
+
+// routerFunc adapts a function to Router.
+type routerFunc func(src, dst netip.Addr, iif string, mark uint32) (*Route, error)
+
+func (f routerFunc) Route(src, dst netip.Addr, iif string, mark uint32) (*Route, error) {
+	return f(src, dst, iif, mark)
+}
+
+func TestTrace_KernelListing(t *testing.T) {
+	rs, err := Parse(listing)
+	require.NoError(t, err)
+	require.Len(t, rs.Tables, 1)
+	assert.Len(t, rs.Tables[0].Sets["blocked"].Elements, 2)
+	assert.Equal(t, -150, rs.Tables[0].Chains["prerouting"].Priority)
+
+	// Policy routing: marked traffic leaves via wan1
+	router := routerFunc(func(src, dst netip.Addr, iif string, mark uint32) (*Route, error) {
+		if mark == 0x100 {
+			return &Route{OutInterface: "wan1", Table: "100"}, nil
+		}
+		return &Route{OutInterface: "wan0", Table: "main"}, nil
+	})
+	tracer := &Tracer{Ruleset: rs, Router: router}
+
+	tests := []struct {
+		name    string
+		pkt     Packet
+		verdict string
+		handle  int
+	}{
+		{"blocked set", Packet{InInterface: "lan0", Protocol: "tcp", Src: "10.2.0.5", Dst: "198.51.100.66", DstPort: 443}, "drop", 12},
+		{"rejected port", Packet{InInterface: "lan0", Protocol: "tcp", Src: "10.2.0.5", Dst: "1.1.1.1", DstPort: 8080}, "drop", 14},
+		{"no vmap entry for marked route", Packet{InInterface: "lan0", Protocol: "tcp", Src: "10.1.0.5", Dst: "1.1.1.1", DstPort: 443}, "drop", 0},
+		{"new tcp", Packet{InInterface: "lan0", Protocol: "tcp", Src: "10.2.0.5", Dst: "1.1.1.1", DstPort: 443}, "accept", 16},
+		{"established", Packet{InInterface: "wan0", Protocol: "tcp", Src: "1.1.1.1", Dst: "10.2.0.5", SrcPort: 443, DstPort: 50000, State: "established"}, "accept", 11},
+		{"wan to lan", Packet{InInterface: "wan0", Protocol: "udp", Src: "1.1.1.1", Dst: "10.2.0.5", DstPort: 53}, "drop", 13},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := routerFunc(func(src, dst netip.Addr, iif string, mark uint32) (*Route, error) {
+				if dst.String() == "10.2.0.5" {
+					return &Route{OutInterface: "lan0"}, nil
+				}
+				return router(src, dst, iif, mark)
+			})
+			tracer.Router = router
+
+			res, err := tracer.Trace(tt.pkt)
+			require.NoError(t, err)
+			assert.Equal(t, tt.verdict, res.Verdict, res.Format())
+			require.NotNil(t, res.Decision)
+			assert.Equal(t, tt.handle, res.Decision.Handle, res.Format())
+			assert.Empty(t, res.Warnings)
+		})
+	}
+}
+
+func TestConfigRouter_PolicyRoutes(t *testing.T) {
+	cfg := &config.Config{
+		Interfaces: []config.Interface{
+			{Name: "wan0", IPv4: []string{"198.51.100.2/30"}, Gateway: "198.51.100.1"},
+			{Name: "wan1", IPv4: []string{"203.0.113.2/30"}},
+			{Name: "lan0", IPv4: []string{"10.0.0.1/24"}},
+		},
+		RoutingTables: []config.RoutingTable{
+			{Name: "isp2", ID: 100, Routes: []config.Route{{Destination: "0.0.0.0/0", Gateway: "203.0.113.1"}}},
+		},
+		PolicyRoutes: []config.PolicyRoute{
+			{Name: "guests", Priority: 100, FromSource: "10.0.0.128/25", Table: 100, Enabled: true},
+			{Name: "marked", Priority: 200, Mark: "0x10", Table: 100, Enabled: true},
+			{Name: "sinkhole", Priority: 300, To: "192.0.2.0/24", Blackhole: true, Enabled: true},
+		},
+	}
+	r := NewConfigRouter(cfg)
+	addr := netip.MustParseAddr
+
+	rt, err := r.Route(addr("10.0.0.5"), addr("8.8.8.8"), "lan0", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "wan0", rt.OutInterface)
+	assert.Equal(t, "main", rt.Table)
+	assert.Equal(t, "198.51.100.2", rt.Source)
+
+	rt, err = r.Route(addr("10.0.0.200"), addr("8.8.8.8"), "lan0", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "wan1", rt.OutInterface)
+	assert.Equal(t, "guests", rt.Rule)
+
+	rt, err = r.Route(addr("10.0.0.5"), addr("8.8.8.8"), "lan0", 0x10)
+	require.NoError(t, err)
+	assert.Equal(t, "100", rt.Table)
+
+	// Connected routes stay in main for unmatched traffic
+	rt, err = r.Route(addr("10.0.0.5"), addr("10.0.0.1"), "lan0", 0)
+	require.NoError(t, err)
+	assert.True(t, rt.Local)
+
+	_, err = r.Route(addr("10.0.0.5"), addr("192.0.2.7"), "lan0", 0)
+	assert.Error(t, err)
+}
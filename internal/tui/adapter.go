@@ -8,6 +8,7 @@ import (
 	"grimm.is/flywall/internal/alerting"
 	"grimm.is/flywall/internal/config"
 	"grimm.is/flywall/internal/ctlplane"
+	"grimm.is/flywall/internal/vpn"
 )
 
 // Ensure Backend implementation
@@ -125,3 +126,11 @@ func (b *LocalBackend) DenyFlow(id int64) error {
 func (b *LocalBackend) GetServices() ([]ctlplane.ServiceStatus, error) {
 	return b.client.GetServices()
 }
+
+func (b *LocalBackend) ProvisionWireGuardPeer(args *ctlplane.ProvisionWireGuardPeerArgs) (*vpn.ClientProfile, error) {
+	return b.client.ProvisionWireGuardPeer(args)
+}
+
+func (b *LocalBackend) RotateWireGuardPeer(args *ctlplane.RotateWireGuardPeerArgs) (*vpn.ClientProfile, error) {
+	return b.client.RotateWireGuardPeer(args)
+}
@@ -6,6 +6,7 @@ import (
 	"grimm.is/flywall/internal/alerting"
 	"grimm.is/flywall/internal/config"
 	"grimm.is/flywall/internal/ctlplane"
+	"grimm.is/flywall/internal/vpn"
 )
 
 // MockBackend implements Backend for testing purposes
@@ -21,6 +22,8 @@ type MockBackend struct {
 	RestartServiceCalled bool
 	Bandwidth            []ctlplane.BandwidthPoint
 	Alerts               []alerting.AlertEvent
+	ClientProfile        *vpn.ClientProfile
+	ProvisionArgs        *ctlplane.ProvisionWireGuardPeerArgs
 }
 
 func (m *MockBackend) GetStatus() (*EnrichedStatus, error) {
@@ -119,3 +122,12 @@ func (m *MockBackend) GetServices() ([]ctlplane.ServiceStatus, error) {
 	}
 	return nil, nil
 }
+
+func (m *MockBackend) ProvisionWireGuardPeer(args *ctlplane.ProvisionWireGuardPeerArgs) (*vpn.ClientProfile, error) {
+	m.ProvisionArgs = args
+	return m.ClientProfile, m.Err
+}
+
+func (m *MockBackend) RotateWireGuardPeer(args *ctlplane.RotateWireGuardPeerArgs) (*vpn.ClientProfile, error) {
+	return m.ClientProfile, m.Err
+}
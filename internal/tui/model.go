@@ -8,6 +8,7 @@ import (
 	"grimm.is/flywall/internal/alerting"
 	"grimm.is/flywall/internal/config"
 	"grimm.is/flywall/internal/ctlplane"
+	"grimm.is/flywall/internal/vpn"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -23,6 +24,9 @@ const (
 	ViewHistory
 	ViewSystem
 	ViewConfigTree // Full config graph
+	ViewWireGuard
+
+	viewCount = iota
 )
 
 // Backend defines the interface for data retrieval and actions.
@@ -43,6 +47,9 @@ type Backend interface {
 	GetServices() ([]ctlplane.ServiceStatus, error)
 	ApproveFlow(id int64) error
 	DenyFlow(id int64) error
+
+	ProvisionWireGuardPeer(args *ctlplane.ProvisionWireGuardPeerArgs) (*vpn.ClientProfile, error)
+	RotateWireGuardPeer(args *ctlplane.RotateWireGuardPeerArgs) (*vpn.ClientProfile, error)
 }
 
 // Model is the main application state
@@ -62,6 +69,7 @@ type Model struct {
 	History   HistoryModel
 	System    SystemModel
 	Config    ConfigModel
+	WireGuard WireGuardModel
 }
 
 // NewModel creates a new initial model
@@ -75,6 +83,7 @@ func NewModel(backend Backend) Model {
 		History:    NewHistoryModel(backend),
 		System:     NewSystemModel(backend),
 		Config:     NewConfigModel(backend),
+		WireGuard:  NewWireGuardModel(backend),
 	}
 }
 
@@ -88,6 +97,7 @@ func (m Model) Init() tea.Cmd {
 		m.History.Init(),
 		m.System.Init(),
 		m.Config.Init(),
+		m.WireGuard.Init(),
 	)
 }
 
@@ -103,6 +113,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return RetryMsg{}
 		})
 
+	case WireGuardPeersMsg, WireGuardClientMsg:
+		// Results arrive whichever view is active
+		var cmd tea.Cmd
+		m.WireGuard, cmd = m.WireGuard.Update(msg)
+		return m, cmd
+
 	case RetryMsg:
 		if m.ConnectionError != "" {
 			m.ConnectionError = ""
@@ -111,6 +127,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.KeyMsg:
+		if m.ActiveView == ViewWireGuard && m.WireGuard.Capturing() {
+			var cmd tea.Cmd
+			m.WireGuard, cmd = m.WireGuard.Update(msg)
+			return m, cmd
+		}
+
 		// If editing form in config view, don't trap global keys if focusing?
 		// But let's keep global quit for now unless editing needs q/ctrl+c
 		if m.ActiveView != ViewConfigTree {
@@ -142,7 +164,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.ActiveView == ViewConfigTree && m.Config.Editing {
 				// consume
 			} else {
-				m.ActiveView = (m.ActiveView + 1) % 6
+				m.ActiveView = (m.ActiveView + 1) % viewCount
 				return m, nil
 			}
 		}
@@ -168,6 +190,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "6":
 				m.ActiveView = ViewConfigTree
 				return m, nil
+			case "7":
+				m.ActiveView = ViewWireGuard
+				return m, nil
 			case "R":
 				// Global config reload
 				return m, func() tea.Msg {
@@ -204,6 +229,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		m.Config, cmd = m.Config.Update(msg)
 		cmds = append(cmds, cmd)
+
+		m.WireGuard, cmd = m.WireGuard.Update(msg)
+		cmds = append(cmds, cmd)
 	}
 
 	// Delegate to active view
@@ -221,6 +249,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.System, cmd = m.System.Update(msg)
 	case ViewConfigTree:
 		m.Config, cmd = m.Config.Update(msg)
+	case ViewWireGuard:
+		m.WireGuard, cmd = m.WireGuard.Update(msg)
 	}
 	cmds = append(cmds, cmd)
 
@@ -257,6 +287,8 @@ func (m Model) View() string {
 		doc += m.System.View()
 	case ViewConfigTree:
 		doc += m.Config.View()
+	case ViewWireGuard:
+		doc += m.WireGuard.View()
 	}
 
 	return StyleApp.Render(doc)
@@ -277,6 +309,7 @@ func (m Model) ViewTopBar() string {
 		{ViewHistory, "History", "4"},
 		{ViewSystem, "System", "5"},
 		{ViewConfigTree, "Config", "6"},
+		{ViewWireGuard, "WireGuard", "7"},
 	}
 
 	for _, menu := range menus {
@@ -8,6 +8,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/stretchr/testify/assert"
+	"grimm.is/flywall/internal/vpn"
 )
 
 func TestModel_Update_TabSwitching(t *testing.T) {
@@ -68,3 +69,37 @@ func TestModel_Update_WindowSize(t *testing.T) {
 	assert.Equal(t, 100, m.Dashboard.Width)
 	assert.Equal(t, 50, m.Dashboard.Height)
 }
+
+func TestModel_WireGuardProvision(t *testing.T) {
+	backend := &MockBackend{
+		ClientProfile: &vpn.ClientProfile{Name: "phone", ClientConfig: "[Interface]\nPrivateKey = abc\n"},
+	}
+	m := NewModel(backend)
+
+	press := func(msg tea.KeyMsg) tea.Cmd {
+		newModel, cmd := m.Update(msg)
+		m = newModel.(Model)
+		return cmd
+	}
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("7")})
+	assert.Equal(t, ViewWireGuard, m.ActiveView)
+
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	assert.True(t, m.WireGuard.Adding)
+
+	// Global keys are typed into the name while adding
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q1")})
+	assert.Equal(t, ViewWireGuard, m.ActiveView)
+	assert.Equal(t, "q1", m.WireGuard.Input.Value())
+
+	cmd := press(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.NotNil(t, cmd)
+	newModel, _ := m.Update(cmd())
+	m = newModel.(Model)
+
+	assert.Equal(t, "q1", backend.ProvisionArgs.Name)
+	assert.True(t, backend.ProvisionArgs.Apply)
+	view := m.View()
+	assert.Contains(t, view, "WIREGUARD CLIENT: phone")
+	assert.Contains(t, view, "PrivateKey = abc")
+}
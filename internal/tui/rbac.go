@@ -0,0 +1,197 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package tui
+
+import (
+	"fmt"
+	"reflect"
+
+	"grimm.is/flywall/internal/api/storage"
+	"grimm.is/flywall/internal/auth"
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/ctlplane"
+	"grimm.is/flywall/internal/errors"
+)
+
+// Grant is a set of permissions a console session is limited to: a user
+// role, or the permissions of the API key a remote session uses.
+type Grant interface {
+	Can(resource, verb string) bool
+}
+
+// KeyGrant holds the permissions of an API key, as reported by the API.
+type KeyGrant struct {
+	Name        string
+	Permissions []storage.Permission
+}
+
+// Can reports whether the key's permissions cover verb on resource. The
+// API guards admin-only resources with admin: permissions.
+func (k KeyGrant) Can(resource, verb string) bool {
+	key := storage.APIKey{Permissions: k.Permissions}
+	if key.HasPermission(storage.Permission(resource + ":" + verb)) {
+		return true
+	}
+	return verb == auth.VerbWrite && key.HasPermission(storage.Permission("admin:"+resource))
+}
+
+func (k KeyGrant) String() string {
+	return fmt.Sprintf("API key %q", k.Name)
+}
+
+// GuardedBackend enforces a set of grants on top of another Backend; every
+// privileged call must be allowed by all of them. The API checks remote
+// calls too, but the console checks first so denied actions fail cleanly,
+// and local sessions talk to the control plane directly.
+type GuardedBackend struct {
+	Backend
+	grants []Grant
+}
+
+// NewGuardedBackend wraps backend so that every privileged call is checked
+// against role.
+func NewGuardedBackend(backend Backend, role auth.Role) *GuardedBackend {
+	return NewRestrictedBackend(backend, role)
+}
+
+// NewRestrictedBackend wraps backend so that every privileged call must be
+// allowed by each of grants.
+func NewRestrictedBackend(backend Backend, grants ...Grant) *GuardedBackend {
+	return &GuardedBackend{Backend: backend, grants: grants}
+}
+
+func (g *GuardedBackend) check(resource, verb string) error {
+	for _, grant := range g.grants {
+		if grant.Can(resource, verb) {
+			continue
+		}
+		name := fmt.Sprint(grant)
+		if role, ok := grant.(auth.Role); ok {
+			name = fmt.Sprintf("role %q", role)
+		}
+		return errors.Errorf(errors.KindPermission, "%s is not allowed to %s %s", name, verb, resource)
+	}
+	return nil
+}
+
+func (g *GuardedBackend) GetFlows(filter string) ([]Flow, error) {
+	if err := g.check(auth.ResourceLearning, auth.VerbRead); err != nil {
+		return nil, err
+	}
+	return g.Backend.GetFlows(filter)
+}
+
+func (g *GuardedBackend) GetConfig() (*config.Config, error) {
+	if err := g.check(auth.ResourceConfig, auth.VerbRead); err != nil {
+		return nil, err
+	}
+	return g.Backend.GetConfig()
+}
+
+// ApplyConfig requires config:apply plus write access to every resource
+// class the new config changes.
+func (g *GuardedBackend) ApplyConfig(cfg *config.Config) error {
+	if err := g.check(auth.ResourceConfig, auth.VerbApply); err != nil {
+		return err
+	}
+	current, err := g.Backend.GetConfig()
+	if err != nil {
+		return err
+	}
+	for _, resource := range changedResources(current, cfg) {
+		if err := g.check(resource, auth.VerbWrite); err != nil {
+			return err
+		}
+	}
+	return g.Backend.ApplyConfig(cfg)
+}
+
+func (g *GuardedBackend) ReloadConfig() error {
+	if err := g.check(auth.ResourceConfig, auth.VerbApply); err != nil {
+		return err
+	}
+	return g.Backend.ReloadConfig()
+}
+
+func (g *GuardedBackend) ListBackups() ([]ctlplane.BackupInfo, error) {
+	if err := g.check(auth.ResourceBackup, auth.VerbRead); err != nil {
+		return nil, err
+	}
+	return g.Backend.ListBackups()
+}
+
+func (g *GuardedBackend) RestoreBackup(version int) error {
+	if err := g.check(auth.ResourceBackup, auth.VerbWrite); err != nil {
+		return err
+	}
+	return g.Backend.RestoreBackup(version)
+}
+
+func (g *GuardedBackend) Reboot() error {
+	if err := g.check(auth.ResourceSystem, auth.VerbWrite); err != nil {
+		return err
+	}
+	return g.Backend.Reboot()
+}
+
+func (g *GuardedBackend) RestartService(name string) error {
+	if err := g.check(auth.ResourceSystem, auth.VerbWrite); err != nil {
+		return err
+	}
+	return g.Backend.RestartService(name)
+}
+
+func (g *GuardedBackend) ApproveFlow(id int64) error {
+	if err := g.check(auth.ResourceLearning, auth.VerbApply); err != nil {
+		return err
+	}
+	return g.Backend.ApproveFlow(id)
+}
+
+func (g *GuardedBackend) DenyFlow(id int64) error {
+	if err := g.check(auth.ResourceLearning, auth.VerbApply); err != nil {
+		return err
+	}
+	return g.Backend.DenyFlow(id)
+}
+
+// changedResources lists the resource classes that differ between two
+// configs. Sections without a dedicated class count as "config".
+func changedResources(old, new *config.Config) []string {
+	if old == nil {
+		old = &config.Config{}
+	}
+	a, b := *old, *new
+
+	var changed []string
+	section := func(resource string, x, y interface{}) {
+		if !reflect.DeepEqual(x, y) {
+			changed = append(changed, resource)
+		}
+	}
+	section(auth.ResourcePolicies, a.Policies, b.Policies)
+	section(auth.ResourceNAT, a.NAT, b.NAT)
+	section(auth.ResourceDHCP, a.DHCP, b.DHCP)
+	section(auth.ResourceDNS, []interface{}{a.DNS, a.DNSServer}, []interface{}{b.DNS, b.DNSServer})
+	section(auth.ResourceVPN, a.VPN, b.VPN)
+	section(auth.ResourceFirewall, []interface{}{a.Zones, a.IPSets, a.QoSPolicies, a.Protections},
+		[]interface{}{b.Zones, b.IPSets, b.QoSPolicies, b.Protections})
+	section(auth.ResourceLearning, a.RuleLearning, b.RuleLearning)
+	// Roles and SSO mappings live in the API block
+	section(auth.ResourceUsers, a.API, b.API)
+	// Exec channels run commands as root
+	section(auth.ResourceSystem, a.ExecChannels(), b.ExecChannels())
+
+	a.Policies, b.Policies = nil, nil
+	a.NAT, b.NAT = nil, nil
+	a.DHCP, b.DHCP = nil, nil
+	a.DNS, b.DNS, a.DNSServer, b.DNSServer = nil, nil, nil, nil
+	a.VPN, b.VPN = nil, nil
+	a.Zones, b.Zones, a.IPSets, b.IPSets = nil, nil, nil, nil
+	a.QoSPolicies, b.QoSPolicies, a.Protections, b.Protections = nil, nil, nil, nil
+	a.RuleLearning, b.RuleLearning = nil, nil
+	a.API, b.API = nil, nil
+	section(auth.ResourceConfig, a, b)
+
+	return changed
+}
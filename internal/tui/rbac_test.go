@@ -0,0 +1,106 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package tui
+
+import (
+	"testing"
+
+	"grimm.is/flywall/internal/api/storage"
+	"grimm.is/flywall/internal/auth"
+	"grimm.is/flywall/internal/config"
+)
+
+func TestGuardedBackend(t *testing.T) {
+	if err := auth.LoadRoles([]config.RoleConfig{{
+		Name:        "helpdesk",
+		Permissions: []string{"learning:apply", "learning:read", "dhcp:read"},
+	}}); err != nil {
+		t.Fatalf("LoadRoles failed: %v", err)
+	}
+	defer auth.LoadRoles(nil)
+
+	mock := &MockBackend{}
+	g := NewGuardedBackend(mock, "helpdesk")
+
+	if err := g.ApproveFlow(1); err != nil {
+		t.Errorf("ApproveFlow denied: %v", err)
+	}
+	if err := g.Reboot(); err == nil || mock.RebootCalled {
+		t.Error("Expected Reboot to be denied")
+	}
+	if err := g.ApplyConfig(&config.Config{}); err == nil || mock.ApplyCalled {
+		t.Error("Expected ApplyConfig to be denied")
+	}
+	if _, err := g.GetConfig(); err == nil {
+		t.Error("Expected GetConfig to be denied")
+	}
+}
+
+func TestGuardedBackendApplyChecksChangedResources(t *testing.T) {
+	if err := auth.LoadRoles([]config.RoleConfig{{
+		Name:        "dhcpadmin",
+		Permissions: []string{"config:read", "config:apply", "dhcp:write"},
+	}}); err != nil {
+		t.Fatalf("LoadRoles failed: %v", err)
+	}
+	defer auth.LoadRoles(nil)
+
+	mock := &MockBackend{Config: &config.Config{}}
+	g := NewGuardedBackend(mock, "dhcpadmin")
+
+	if err := g.ApplyConfig(&config.Config{DHCP: &config.DHCPServer{Enabled: true}}); err != nil {
+		t.Errorf("DHCP change denied: %v", err)
+	}
+	if err := g.ApplyConfig(&config.Config{NAT: []config.NATRule{{Type: "masquerade"}}}); err == nil {
+		t.Error("Expected NAT change to be denied")
+	}
+	if err := g.ApplyConfig(&config.Config{API: &config.APIConfig{Roles: []config.RoleConfig{{Name: "x"}}}}); err == nil {
+		t.Error("Expected role change to be denied")
+	}
+
+	key := KeyGrant{Name: "ops", Permissions: []storage.Permission{storage.PermReadConfig, storage.PermWriteConfig, storage.PermApplyConfig}}
+	g = NewRestrictedBackend(mock, key)
+	notify := func(ch config.NotificationChannel) *config.Config {
+		return &config.Config{Notifications: &config.NotificationsConfig{Channels: []config.NotificationChannel{ch}}}
+	}
+	if err := g.ApplyConfig(notify(config.NotificationChannel{Name: "n", Type: "ntfy", Topic: "fw"})); err != nil {
+		t.Errorf("ntfy channel change denied: %v", err)
+	}
+	if err := g.ApplyConfig(notify(config.NotificationChannel{Name: "x", Type: "exec", Command: []string{"/bin/sh"}})); err == nil {
+		t.Error("Expected exec channel change to need admin:system")
+	}
+}
+
+func TestRestrictedBackendKeyAndRole(t *testing.T) {
+	if err := auth.LoadRoles([]config.RoleConfig{{
+		Name:        "helpdesk",
+		Permissions: []string{"learning:apply", "learning:read", "system:write"},
+	}}); err != nil {
+		t.Fatalf("LoadRoles failed: %v", err)
+	}
+	defer auth.LoadRoles(nil)
+
+	key := KeyGrant{Name: "console", Permissions: []storage.Permission{storage.PermReadAll, storage.PermApplyLearning}}
+
+	mock := &MockBackend{}
+	g := NewRestrictedBackend(mock, key)
+	if err := g.ApproveFlow(1); err != nil {
+		t.Errorf("ApproveFlow denied: %v", err)
+	}
+	if err := g.Reboot(); err == nil || mock.RebootCalled {
+		t.Error("Expected Reboot to be denied without admin:system")
+	}
+
+	// The role flag only narrows what the key allows
+	g = NewRestrictedBackend(mock, key, auth.Role("helpdesk"))
+	if _, err := g.GetConfig(); err == nil {
+		t.Error("Expected GetConfig to be denied by the role")
+	}
+	if err := g.Reboot(); err == nil || mock.RebootCalled {
+		t.Error("Expected Reboot to be denied by the key")
+	}
+
+	if !(KeyGrant{Permissions: []storage.Permission{storage.PermAdminSystem}}).Can(auth.ResourceSystem, auth.VerbWrite) {
+		t.Error("Expected admin:system to grant system:write")
+	}
+}
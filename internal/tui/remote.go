@@ -11,8 +11,11 @@ import (
 	"time"
 
 	"grimm.is/flywall/internal/alerting"
+	"grimm.is/flywall/internal/api/storage"
+	"grimm.is/flywall/internal/auth"
 	"grimm.is/flywall/internal/config"
 	"grimm.is/flywall/internal/ctlplane"
+	"grimm.is/flywall/internal/vpn"
 )
 
 // RemoteBackend implements Backend using the HTTP API
@@ -65,6 +68,45 @@ func (b *RemoteBackend) do(method, path string) (*http.Response, error) {
 	return resp, nil
 }
 
+// Identity is who the API authenticates a remote session as.
+type Identity struct {
+	Type        string               `json:"type"` // "api_key", "user" or "anonymous"
+	Name        string               `json:"name,omitempty"`
+	Role        string               `json:"role,omitempty"`
+	Permissions []storage.Permission `json:"permissions,omitempty"`
+}
+
+// Grant returns the permissions the identity is limited to, or nil when
+// the API doesn't require authentication.
+func (id *Identity) Grant() Grant {
+	switch id.Type {
+	case "api_key":
+		return KeyGrant{Name: id.Name, Permissions: id.Permissions}
+	case "user":
+		return auth.Role(id.Role)
+	}
+	return nil
+}
+
+// WhoAmI asks the API who the backend's API key authenticates as.
+func (b *RemoteBackend) WhoAmI() (*Identity, error) {
+	resp, err := b.do("GET", "/api/auth/whoami")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("api error: %s", resp.Status)
+	}
+
+	var id Identity
+	if err := json.NewDecoder(resp.Body).Decode(&id); err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
 func (b *RemoteBackend) GetStatus() (*EnrichedStatus, error) {
 	resp, err := b.do("GET", "/api/status")
 	if err != nil {
@@ -425,3 +467,53 @@ func (b *RemoteBackend) GetAlerts(limit int) ([]alerting.AlertEvent, error) {
 	}
 	return data.Events, nil
 }
+
+func (b *RemoteBackend) ProvisionWireGuardPeer(args *ctlplane.ProvisionWireGuardPeerArgs) (*vpn.ClientProfile, error) {
+	return b.stageWireGuardClient("/api/wireguard/provision", args, args.Apply)
+}
+
+func (b *RemoteBackend) RotateWireGuardPeer(args *ctlplane.RotateWireGuardPeerArgs) (*vpn.ClientProfile, error) {
+	return b.stageWireGuardClient("/api/wireguard/rotate", args, args.Apply)
+}
+
+// stageWireGuardClient posts a provisioning request, which the API stages,
+// and applies the staged config if asked. The profile is returned even if
+// applying fails.
+func (b *RemoteBackend) stageWireGuardClient(path string, args interface{}, apply bool) (*vpn.ClientProfile, error) {
+	body, _ := json.Marshal(args)
+
+	req, err := http.NewRequest("POST", b.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+b.APIKey)
+	req.Header.Set("X-API-Key", b.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("api error: %s", resp.Status)
+	}
+
+	var profile vpn.ClientProfile
+	if err := json.NewDecoder(resp.Body).Decode(&profile); err != nil {
+		return nil, err
+	}
+	if !apply {
+		return &profile, nil
+	}
+
+	resp2, err := b.do("POST", "/api/config/apply")
+	if err != nil {
+		return &profile, err
+	}
+	defer resp2.Body.Close()
+	if resp2.StatusCode != http.StatusOK {
+		return &profile, fmt.Errorf("peer staged but apply failed: %s", resp2.Status)
+	}
+	return &profile, nil
+}
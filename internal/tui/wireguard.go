@@ -0,0 +1,255 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/ctlplane"
+	"grimm.is/flywall/internal/vpn"
+)
+
+// WireGuardModel lists WireGuard peers and provisions road-warrior clients,
+// showing their config as a QR code to scan with a phone.
+type WireGuardModel struct {
+	Backend Backend
+	Peers   []wgPeerRow
+	Cursor  int
+	Input   textinput.Model
+	Message string
+
+	// State
+	Adding  bool               // Typing the name of a new device
+	Profile *vpn.ClientProfile // Client config being shown
+
+	Width  int
+	Height int
+}
+
+type wgPeerRow struct {
+	Connection string
+	Peer       config.WireGuardPeerConfig
+}
+
+// WireGuardPeersMsg carries the configured peers.
+type WireGuardPeersMsg struct {
+	Peers []wgPeerRow
+}
+
+// WireGuardClientMsg carries a provisioned or rotated client.
+type WireGuardClientMsg struct {
+	Profile *vpn.ClientProfile
+}
+
+func NewWireGuardModel(backend Backend) WireGuardModel {
+	ti := textinput.New()
+	ti.Placeholder = "device name, e.g. alice-phone"
+	ti.Prompt = "Name: "
+	ti.PromptStyle = StyleInputPrompt
+	ti.TextStyle = StyleInputText
+	ti.PlaceholderStyle = StyleInputPlaceholder
+	ti.CharLimit = 64
+
+	return WireGuardModel{
+		Backend: backend,
+		Input:   ti,
+	}
+}
+
+func (m WireGuardModel) Init() tea.Cmd {
+	return func() tea.Msg {
+		cfg, err := m.Backend.GetConfig()
+		if err != nil {
+			return BackendError{Err: err}
+		}
+		var rows []wgPeerRow
+		if cfg.VPN != nil {
+			for _, wg := range cfg.VPN.WireGuard {
+				for _, p := range wg.Peers {
+					rows = append(rows, wgPeerRow{Connection: wg.Name, Peer: p})
+				}
+			}
+		}
+		return WireGuardPeersMsg{Peers: rows}
+	}
+}
+
+// Capturing reports whether the view wants all key presses.
+func (m WireGuardModel) Capturing() bool {
+	return m.Adding
+}
+
+func (m WireGuardModel) Update(msg tea.Msg) (WireGuardModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case WireGuardPeersMsg:
+		m.Peers = msg.Peers
+		if m.Cursor >= len(m.Peers) {
+			m.Cursor = max(len(m.Peers)-1, 0)
+		}
+		return m, nil
+
+	case WireGuardClientMsg:
+		m.Profile = msg.Profile
+		m.Message = ""
+		return m, m.Init()
+
+	case BackendError:
+		m.Message = fmt.Sprintf("Error: %v", msg.Err)
+		return m, nil
+
+	case tea.WindowSizeMsg:
+		m.Width = msg.Width
+		m.Height = msg.Height
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.Adding {
+			switch msg.Type {
+			case tea.KeyEsc:
+				m.Adding = false
+				m.Input.Blur()
+				return m, nil
+			case tea.KeyEnter:
+				name := strings.TrimSpace(m.Input.Value())
+				if name == "" {
+					return m, nil
+				}
+				m.Adding = false
+				m.Input.Blur()
+				m.Message = fmt.Sprintf("Provisioning %s...", name)
+				return m, m.provision(name)
+			}
+			var cmd tea.Cmd
+			m.Input, cmd = m.Input.Update(msg)
+			return m, cmd
+		}
+
+		if m.Profile != nil {
+			if msg.Type == tea.KeyEsc || msg.String() == "enter" {
+				m.Profile = nil
+			}
+			return m, nil
+		}
+
+		switch msg.String() {
+		case "up", "k":
+			if m.Cursor > 0 {
+				m.Cursor--
+			}
+		case "down", "j":
+			if m.Cursor < len(m.Peers)-1 {
+				m.Cursor++
+			}
+		case "n":
+			m.Adding = true
+			m.Input.SetValue("")
+			m.Input.Focus()
+			return m, textinput.Blink
+		case "x":
+			if len(m.Peers) == 0 {
+				return m, nil
+			}
+			row := m.Peers[m.Cursor]
+			m.Message = fmt.Sprintf("Rotating keys of %s...", row.Peer.Name)
+			return m, m.rotate(row)
+		case "r":
+			return m, m.Init()
+		}
+	}
+	return m, nil
+}
+
+// provision adds a peer on the only connection, or the selected peer's.
+func (m WireGuardModel) provision(name string) tea.Cmd {
+	var connection string
+	if len(m.Peers) > 0 {
+		connection = m.Peers[m.Cursor].Connection
+	}
+	return func() tea.Msg {
+		profile, err := m.Backend.ProvisionWireGuardPeer(&ctlplane.ProvisionWireGuardPeerArgs{
+			Connection: connection,
+			Name:       name,
+			Apply:      true,
+		})
+		if profile != nil {
+			// Show the config even if applying failed: the key is not stored
+			return WireGuardClientMsg{Profile: profile}
+		}
+		return BackendError{Err: err}
+	}
+}
+
+func (m WireGuardModel) rotate(row wgPeerRow) tea.Cmd {
+	return func() tea.Msg {
+		profile, err := m.Backend.RotateWireGuardPeer(&ctlplane.RotateWireGuardPeerArgs{
+			Connection: row.Connection,
+			Name:       row.Peer.Name,
+			Apply:      true,
+		})
+		if profile != nil {
+			return WireGuardClientMsg{Profile: profile}
+		}
+		return BackendError{Err: err}
+	}
+}
+
+func (m WireGuardModel) View() string {
+	if m.Profile != nil {
+		qr, err := vpn.QRCodeText(m.Profile.ClientConfig)
+		if err != nil {
+			qr = StyleStatusBad.Render(err.Error())
+		}
+		return lipgloss.JoinVertical(lipgloss.Left,
+			StyleHeader.Render("WIREGUARD CLIENT: "+m.Profile.Name),
+			lipgloss.JoinHorizontal(lipgloss.Top,
+				qr,
+				StyleCard.Render(m.Profile.ClientConfig),
+			),
+			StyleStatusWarn.Render("The private key is not stored; scan or copy it now."),
+			StyleSubtitle.Render("Esc to close"),
+		)
+	}
+
+	var rows []string
+	rows = append(rows, StyleTableHeader.Render(fmt.Sprintf("%-16s %-20s %-20s %-12s %s", "CONNECTION", "PEER", "ADDRESS", "EXPIRES", "STATE")))
+	if len(m.Peers) == 0 {
+		rows = append(rows, StyleSubtle.Render("No WireGuard peers configured."))
+	}
+	for i, row := range m.Peers {
+		state := StyleStatusOk.Render("enabled")
+		if row.Peer.Disabled {
+			state = StyleStatusErr.Render("disabled")
+		}
+		addr := ""
+		if len(row.Peer.AllowedIPs) > 0 {
+			addr = row.Peer.AllowedIPs[0]
+		}
+		expires := row.Peer.Expires
+		if expires == "" {
+			expires = "-"
+		}
+		line := fmt.Sprintf("%-16s %-20s %-20s %-12s %s", row.Connection, row.Peer.Name, addr, expires, state)
+		if i == m.Cursor {
+			rows = append(rows, StyleTableRowSelected.Render(line))
+		} else {
+			rows = append(rows, StyleTableRow.Render(line))
+		}
+	}
+
+	footer := StyleSubtitle.Render("[n] New device  [x] Rotate keys  [r] Refresh")
+	if m.Adding {
+		footer = m.Input.View()
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		StyleHeader.Render("WIREGUARD PEERS"),
+		StyleCard.Render(lipgloss.JoinVertical(lipgloss.Left, rows...)),
+		footer,
+		StyleStatusWarn.Render(m.Message),
+	)
+}
@@ -4,6 +4,7 @@ package vpn
 
 import (
 	"context"
+	"time"
 
 	"grimm.is/flywall/internal/config"
 	"grimm.is/flywall/internal/logging"
@@ -31,40 +32,7 @@ func NewManager(cfg *config.VPNConfig, logger *logging.Logger) (*Manager, error)
 			continue
 		}
 
-		// Default interface to Name if empty
-		if wgCfg.Interface == "" {
-			wgCfg.Interface = wgCfg.Name
-		}
-
-		// Map config to internal vpn type
-		internalCfg := WireGuardConfig{
-			Enabled:          wgCfg.Enabled,
-			Interface:        wgCfg.Interface,
-			ManagementAccess: wgCfg.ManagementAccess,
-			Zone:             wgCfg.Zone,
-			PrivateKey:       wgCfg.PrivateKey,
-			PrivateKeyFile:   wgCfg.PrivateKeyFile,
-			ListenPort:       wgCfg.ListenPort,
-			Address:          wgCfg.Address,
-			MTU:              wgCfg.MTU,
-			FWMark:           wgCfg.FWMark,
-			Table:            wgCfg.Table,
-		}
-
-		// Convert peers
-		for _, peer := range wgCfg.Peers {
-			internalPeer := WireGuardPeer{
-				Name:                peer.Name,
-				PublicKey:           peer.PublicKey,
-				PresharedKey:        peer.PresharedKey,
-				Endpoint:            peer.Endpoint,
-				AllowedIPs:          peer.AllowedIPs,
-				PersistentKeepalive: peer.PersistentKeepalive,
-			}
-			internalCfg.Peers = append(internalCfg.Peers, internalPeer)
-		}
-
-		provider := NewWireGuardManager(internalCfg, logger)
+		provider := NewWireGuardManager(wireGuardFromConfig(wgCfg), logger)
 		m.providers = append(m.providers, provider)
 	}
 
@@ -100,6 +68,79 @@ func NewManager(cfg *config.VPNConfig, logger *logging.Logger) (*Manager, error)
 	return m, nil
 }
 
+// wireGuardFromConfig maps a WireGuard connection to the internal type.
+func wireGuardFromConfig(wgCfg config.WireGuardConfig) WireGuardConfig {
+	// Default interface to Name if empty
+	if wgCfg.Interface == "" {
+		wgCfg.Interface = wgCfg.Name
+	}
+
+	// Map config to internal vpn type
+	internalCfg := WireGuardConfig{
+		Name:             wgCfg.Name,
+		Enabled:          wgCfg.Enabled,
+		Interface:        wgCfg.Interface,
+		ManagementAccess: wgCfg.ManagementAccess,
+		Zone:             wgCfg.Zone,
+		PrivateKey:       wgCfg.PrivateKey,
+		PrivateKeyFile:   wgCfg.PrivateKeyFile,
+		ListenPort:       wgCfg.ListenPort,
+		Address:          wgCfg.Address,
+		MTU:              wgCfg.MTU,
+		FWMark:           wgCfg.FWMark,
+		Table:            wgCfg.Table,
+	}
+	// Durations and times were checked by config validation
+	internalCfg.KeyRotation, _ = time.ParseDuration(wgCfg.KeyRotation)
+	internalCfg.IdleTimeout, _ = time.ParseDuration(wgCfg.IdleTimeout)
+
+	// Convert peers
+	for _, peer := range wgCfg.Peers {
+		internalPeer := WireGuardPeer{
+			Name:                peer.Name,
+			PublicKey:           peer.PublicKey,
+			PresharedKey:        peer.PresharedKey,
+			Endpoint:            peer.Endpoint,
+			AllowedIPs:          peer.AllowedIPs,
+			PersistentKeepalive: peer.PersistentKeepalive,
+			Disabled:            peer.Disabled,
+		}
+		internalPeer.Created, _ = peer.CreatedTime()
+		internalPeer.Expires, _ = peer.ExpiryTime()
+		internalCfg.Peers = append(internalCfg.Peers, internalPeer)
+	}
+	return internalCfg
+}
+
+// Reload applies changed WireGuard settings and peers to the running
+// connections. Added or removed connections take effect on restart.
+func (m *Manager) Reload(cfg *config.VPNConfig) {
+	if cfg == nil {
+		return
+	}
+	for _, wgCfg := range cfg.WireGuard {
+		if !wgCfg.Enabled {
+			continue
+		}
+		internalCfg := wireGuardFromConfig(wgCfg)
+
+		found := false
+		for _, p := range m.providers {
+			wg, ok := p.(*WireGuardManager)
+			if !ok || wg.Interface() != internalCfg.Interface {
+				continue
+			}
+			found = true
+			if err := wg.Reconfigure(internalCfg); err != nil {
+				m.logger.Warn("Failed to reconfigure WireGuard", "interface", internalCfg.Interface, "error", err)
+			}
+		}
+		if !found {
+			m.logger.Info("New WireGuard connection needs a restart to start", "interface", internalCfg.Interface)
+		}
+	}
+}
+
 // Start starts all managed VPN providers.
 func (m *Manager) Start(ctx context.Context) error {
 	for _, p := range m.providers {
@@ -0,0 +1,326 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package vpn
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+
+	"grimm.is/flywall/internal/config"
+	"grimm.is/flywall/internal/errors"
+)
+
+// maxAddressScan bounds the search for a free tunnel address, so a huge
+// IPv6 subnet can't stall provisioning.
+const maxAddressScan = 1 << 16
+
+// ProvisionRequest asks for a new road-warrior peer.
+type ProvisionRequest struct {
+	// WireGuard connection name or interface. May be empty when only one
+	// connection exists.
+	Connection string `json:"connection,omitempty"`
+
+	// Peer name, unique within the connection
+	Name string `json:"name"`
+
+	// Optional expiry (RFC 3339 or YYYY-MM-DD)
+	Expires string `json:"expires,omitempty"`
+}
+
+// ClientProfile is a provisioned peer together with the configuration the
+// client device needs to connect.
+type ClientProfile struct {
+	Connection string   `json:"connection"`
+	Name       string   `json:"name"`
+	PublicKey  string   `json:"public_key"`
+	Addresses  []string `json:"addresses"`
+	Expires    string   `json:"expires,omitempty"`
+
+	// Complete wg-quick style client configuration, including the client's
+	// private key. It is not stored anywhere else.
+	ClientConfig string `json:"client_config"`
+
+	// The peer as added to the configuration
+	Peer config.WireGuardPeerConfig `json:"-"`
+}
+
+// ProvisionPeer adds a peer for a new client device to cfg. It generates the
+// client's keys and a preshared key, allocates the next free tunnel address
+// in each of the connection's subnets and returns the client configuration.
+// cfg is modified in place; the caller stages or saves it.
+func ProvisionPeer(cfg *config.Config, req ProvisionRequest, now time.Time) (*ClientProfile, error) {
+	wg, err := findWireGuard(cfg, req.Connection)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name == "" {
+		return nil, errors.New(errors.KindValidation, "peer name is required")
+	}
+	for _, p := range wg.Peers {
+		if p.Name == req.Name {
+			return nil, errors.Errorf(errors.KindConflict, "peer %q already exists on %s", req.Name, wg.Name)
+		}
+	}
+	if req.Expires != "" {
+		if _, err := (config.WireGuardPeerConfig{Expires: req.Expires}).ExpiryTime(); err != nil {
+			return nil, errors.Errorf(errors.KindValidation, "invalid expiry %q: use RFC 3339 or YYYY-MM-DD", req.Expires)
+		}
+	}
+
+	addrs, err := allocateAddresses(wg)
+	if err != nil {
+		return nil, err
+	}
+
+	peer := config.WireGuardPeerConfig{
+		Name:       req.Name,
+		AllowedIPs: addrs,
+		Expires:    req.Expires,
+	}
+	privKey, err := rekeyPeer(&peer, now)
+	if err != nil {
+		return nil, err
+	}
+
+	clientConf, err := buildClientConfig(wg, peer, privKey)
+	if err != nil {
+		return nil, err
+	}
+	wg.Peers = append(wg.Peers, peer)
+
+	return newClientProfile(wg, peer, clientConf), nil
+}
+
+// RotatePeer replaces the keys of an existing peer, keeping its addresses,
+// and returns the new client configuration. A peer disabled for being idle
+// is enabled again by its new keys.
+func RotatePeer(cfg *config.Config, connection, name string, now time.Time) (*ClientProfile, error) {
+	wg, err := findWireGuard(cfg, connection)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range wg.Peers {
+		peer := &wg.Peers[i]
+		if peer.Name != name {
+			continue
+		}
+		privKey, err := rekeyPeer(peer, now)
+		if err != nil {
+			return nil, err
+		}
+		clientConf, err := buildClientConfig(wg, *peer, privKey)
+		if err != nil {
+			return nil, err
+		}
+		return newClientProfile(wg, *peer, clientConf), nil
+	}
+	return nil, errors.Errorf(errors.KindNotFound, "peer %q not found on %s", name, wg.Name)
+}
+
+func newClientProfile(wg *config.WireGuardConfig, peer config.WireGuardPeerConfig, clientConf string) *ClientProfile {
+	return &ClientProfile{
+		Connection:   wg.Name,
+		Name:         peer.Name,
+		PublicKey:    peer.PublicKey,
+		Addresses:    peer.AllowedIPs,
+		Expires:      peer.Expires,
+		ClientConfig: clientConf,
+		Peer:         peer,
+	}
+}
+
+// SetPeer adds peer to the named connection, replacing a peer of the same
+// name. It is used to stage a peer produced on a copy of the configuration.
+func SetPeer(cfg *config.Config, connection string, peer config.WireGuardPeerConfig) error {
+	wg, err := findWireGuard(cfg, connection)
+	if err != nil {
+		return err
+	}
+	for i := range wg.Peers {
+		if wg.Peers[i].Name == peer.Name {
+			wg.Peers[i] = peer
+			return nil
+		}
+	}
+	wg.Peers = append(wg.Peers, peer)
+	return nil
+}
+
+// findWireGuard returns the connection matching name or interface.
+func findWireGuard(cfg *config.Config, connection string) (*config.WireGuardConfig, error) {
+	if cfg.VPN == nil || len(cfg.VPN.WireGuard) == 0 {
+		return nil, errors.New(errors.KindNotFound, "no WireGuard connections configured")
+	}
+	if connection == "" {
+		if len(cfg.VPN.WireGuard) > 1 {
+			return nil, errors.New(errors.KindValidation, "several WireGuard connections configured; specify one")
+		}
+		return &cfg.VPN.WireGuard[0], nil
+	}
+	for i := range cfg.VPN.WireGuard {
+		wg := &cfg.VPN.WireGuard[i]
+		if wg.Name == connection || wg.Interface == connection {
+			return wg, nil
+		}
+	}
+	return nil, errors.Errorf(errors.KindNotFound, "WireGuard connection %q not found", connection)
+}
+
+// rekeyPeer gives peer a new key pair and preshared key and returns the
+// private key.
+func rekeyPeer(peer *config.WireGuardPeerConfig, now time.Time) (string, error) {
+	privKey, pubKey, err := GenerateKeyPair()
+	if err != nil {
+		return "", err
+	}
+	psk, err := wgtypes.GenerateKey()
+	if err != nil {
+		return "", errors.Wrap(err, errors.KindInternal, "failed to generate preshared key")
+	}
+	peer.PublicKey = pubKey
+	peer.PresharedKey = config.SecureString(psk.String())
+	peer.Created = now.UTC().Format(time.RFC3339)
+	return privKey, nil
+}
+
+// allocateAddresses picks the first free host address in each of the
+// connection's subnets. Addresses of the interface itself and those routed
+// to existing peers are taken. Peer routes wider than the subnet, such as a
+// site-to-site default route, are ignored.
+func allocateAddresses(wg *config.WireGuardConfig) ([]string, error) {
+	var subnets []netip.Prefix
+	used := make(map[netip.Addr]bool)
+	for _, a := range wg.Address {
+		prefix, err := netip.ParsePrefix(a)
+		if err != nil {
+			return nil, errors.Errorf(errors.KindValidation, "invalid interface address %q", a)
+		}
+		used[prefix.Addr()] = true
+		subnets = append(subnets, prefix.Masked())
+	}
+	if len(subnets) == 0 {
+		return nil, errors.Errorf(errors.KindValidation, "WireGuard connection %s has no address to allocate from", wg.Name)
+	}
+
+	var taken []netip.Prefix
+	for _, p := range wg.Peers {
+		for _, a := range p.AllowedIPs {
+			if prefix, err := netip.ParsePrefix(a); err == nil {
+				taken = append(taken, prefix)
+			} else if addr, err := netip.ParseAddr(a); err == nil {
+				used[addr] = true
+			}
+		}
+	}
+
+	var addrs []string
+	for _, subnet := range subnets {
+		var inSubnet []netip.Prefix
+		for _, p := range taken {
+			if p.Bits() >= subnet.Bits() && subnet.Overlaps(p) {
+				inSubnet = append(inSubnet, p)
+			}
+		}
+		addr, ok := freeAddress(subnet, used, inSubnet)
+		if !ok {
+			return nil, errors.Errorf(errors.KindConflict, "no free address left in %s", subnet)
+		}
+		addrs = append(addrs, netip.PrefixFrom(addr, addr.BitLen()).String())
+	}
+	return addrs, nil
+}
+
+// freeAddress returns the first host address of subnet that is neither used
+// nor inside a taken prefix. The network address and the IPv4 broadcast
+// address are skipped.
+func freeAddress(subnet netip.Prefix, used map[netip.Addr]bool, taken []netip.Prefix) (netip.Addr, bool) {
+	addr := subnet.Addr().Next()
+	for i := 0; i < maxAddressScan && addr.IsValid() && subnet.Contains(addr); i++ {
+		next := addr.Next()
+		if addr.Is4() && !subnet.Contains(next) {
+			// Broadcast
+			break
+		}
+		if !used[addr] && !containedIn(addr, taken) {
+			return addr, true
+		}
+		addr = next
+	}
+	return netip.Addr{}, false
+}
+
+func containedIn(addr netip.Addr, prefixes []netip.Prefix) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// serverPublicKey derives the connection's public key from its private key.
+func serverPublicKey(wg *config.WireGuardConfig) (string, error) {
+	keyStr := string(wg.PrivateKey)
+	if keyStr == "" && wg.PrivateKeyFile != "" {
+		data, err := os.ReadFile(wg.PrivateKeyFile)
+		if err != nil {
+			return "", errors.Wrap(err, errors.KindInternal, "failed to read private key file")
+		}
+		keyStr = strings.TrimSpace(string(data))
+	}
+	if keyStr == "" {
+		return "", errors.Errorf(errors.KindValidation, "WireGuard connection %s has no private key", wg.Name)
+	}
+	key, err := wgtypes.ParseKey(keyStr)
+	if err != nil {
+		return "", errors.Wrap(err, errors.KindValidation, "invalid private key")
+	}
+	return key.PublicKey().String(), nil
+}
+
+// buildClientConfig renders the wg-quick configuration for a client peer.
+func buildClientConfig(wg *config.WireGuardConfig, peer config.WireGuardPeerConfig, privateKey string) (string, error) {
+	if wg.ClientEndpoint == "" {
+		return "", errors.Errorf(errors.KindValidation, "WireGuard connection %s has no client_endpoint", wg.Name)
+	}
+	serverKey, err := serverPublicKey(wg)
+	if err != nil {
+		return "", err
+	}
+
+	dns := wg.ClientDNS
+	if len(dns) == 0 {
+		dns = wg.DNS
+	}
+	allowed := wg.ClientAllowedIPs
+	if len(allowed) == 0 {
+		allowed = []string{"0.0.0.0/0", "::/0"}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s (%s)\n", peer.Name, wg.Name)
+	b.WriteString("[Interface]\n")
+	fmt.Fprintf(&b, "PrivateKey = %s\n", privateKey)
+	fmt.Fprintf(&b, "Address = %s\n", strings.Join(peer.AllowedIPs, ", "))
+	if len(dns) > 0 {
+		fmt.Fprintf(&b, "DNS = %s\n", strings.Join(dns, ", "))
+	}
+	if wg.MTU != 0 {
+		fmt.Fprintf(&b, "MTU = %d\n", wg.MTU)
+	}
+	b.WriteString("\n[Peer]\n")
+	fmt.Fprintf(&b, "PublicKey = %s\n", serverKey)
+	if peer.PresharedKey != "" {
+		fmt.Fprintf(&b, "PresharedKey = %s\n", string(peer.PresharedKey))
+	}
+	fmt.Fprintf(&b, "Endpoint = %s\n", wg.ClientEndpoint)
+	fmt.Fprintf(&b, "AllowedIPs = %s\n", strings.Join(allowed, ", "))
+	b.WriteString("PersistentKeepalive = 25\n")
+	return b.String(), nil
+}
@@ -0,0 +1,191 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package vpn
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"grimm.is/flywall/internal/config"
+)
+
+func provisionTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+	serverKey, _, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair() error = %v", err)
+	}
+	return &config.Config{VPN: &config.VPNConfig{WireGuard: []config.WireGuardConfig{{
+		Name:           "roadwarrior",
+		Interface:      "wg0",
+		Enabled:        true,
+		PrivateKey:     config.SecureString(serverKey),
+		Address:        []string{"10.200.0.1/29", "fd00:200::1/64"},
+		DNS:            []string{"10.200.0.1"},
+		ClientEndpoint: "vpn.example.com:51820",
+		Peers: []config.WireGuardPeerConfig{
+			{Name: "laptop", PublicKey: "x", AllowedIPs: []string{"10.200.0.2/32", "fd00:200::2/128"}},
+			{Name: "office", PublicKey: "y", AllowedIPs: []string{"0.0.0.0/0"}},
+		},
+	}}}}
+}
+
+func TestProvisionPeer(t *testing.T) {
+	cfg := provisionTestConfig(t)
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	profile, err := ProvisionPeer(cfg, ProvisionRequest{Name: "phone", Expires: "2026-12-31"}, now)
+	if err != nil {
+		t.Fatalf("ProvisionPeer() error = %v", err)
+	}
+
+	want := []string{"10.200.0.3/32", "fd00:200::3/128"}
+	if !reflect.DeepEqual(profile.Addresses, want) {
+		t.Errorf("Addresses = %v; want %v", profile.Addresses, want)
+	}
+
+	wg := cfg.VPN.WireGuard[0]
+	if len(wg.Peers) != 3 {
+		t.Fatalf("Expected peer appended to config, got %d peers", len(wg.Peers))
+	}
+	peer := wg.Peers[2]
+	if peer.PublicKey != profile.PublicKey || peer.PresharedKey == "" {
+		t.Errorf("Expected keys stored on peer, got %+v", peer)
+	}
+	if peer.Created != "2026-05-01T12:00:00Z" || peer.Expires != "2026-12-31" {
+		t.Errorf("Unexpected lifetime: created %q, expires %q", peer.Created, peer.Expires)
+	}
+
+	serverPub, _ := serverPublicKey(&wg)
+	for _, line := range []string{
+		"Address = 10.200.0.3/32, fd00:200::3/128",
+		"DNS = 10.200.0.1",
+		"PublicKey = " + serverPub,
+		"PresharedKey = " + string(peer.PresharedKey),
+		"Endpoint = vpn.example.com:51820",
+		"AllowedIPs = 0.0.0.0/0, ::/0",
+	} {
+		if !strings.Contains(profile.ClientConfig, line+"\n") {
+			t.Errorf("Client config missing %q:\n%s", line, profile.ClientConfig)
+		}
+	}
+
+	if _, err := ProvisionPeer(cfg, ProvisionRequest{Name: "phone"}, now); err == nil {
+		t.Error("Expected error for duplicate peer name")
+	}
+	if _, err := ProvisionPeer(cfg, ProvisionRequest{Connection: "wg9", Name: "tablet"}, now); err == nil {
+		t.Error("Expected error for unknown connection")
+	}
+}
+
+func TestProvisionPeer_SubnetFull(t *testing.T) {
+	cfg := provisionTestConfig(t)
+	cfg.VPN.WireGuard[0].Address = []string{"10.200.0.1/29"}
+	now := time.Now()
+
+	// .1 is the interface, .2 the laptop and .7 broadcast: four remain
+	for i := 0; i < 4; i++ {
+		if _, err := ProvisionPeer(cfg, ProvisionRequest{Name: string(rune('a' + i))}, now); err != nil {
+			t.Fatalf("ProvisionPeer() #%d error = %v", i, err)
+		}
+	}
+	if _, err := ProvisionPeer(cfg, ProvisionRequest{Name: "z"}, now); err == nil {
+		t.Error("Expected error when the subnet is full")
+	}
+}
+
+func TestRotatePeer(t *testing.T) {
+	cfg := provisionTestConfig(t)
+	now := time.Now()
+
+	first, err := ProvisionPeer(cfg, ProvisionRequest{Name: "phone"}, now)
+	if err != nil {
+		t.Fatalf("ProvisionPeer() error = %v", err)
+	}
+	rotated, err := RotatePeer(cfg, "wg0", "phone", now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("RotatePeer() error = %v", err)
+	}
+	if rotated.PublicKey == first.PublicKey {
+		t.Error("Expected a new public key")
+	}
+	if !reflect.DeepEqual(rotated.Addresses, first.Addresses) {
+		t.Errorf("Expected addresses kept, got %v; want %v", rotated.Addresses, first.Addresses)
+	}
+	if got := cfg.VPN.WireGuard[0].Peers[2].PublicKey; got != rotated.PublicKey {
+		t.Errorf("Config peer key = %s; want %s", got, rotated.PublicKey)
+	}
+
+	if _, err := RotatePeer(cfg, "", "tablet", now); err == nil {
+		t.Error("Expected error for unknown peer")
+	}
+}
+
+func TestQRCode(t *testing.T) {
+	png, err := QRCodePNG("[Interface]\nPrivateKey = abc\n")
+	if err != nil {
+		t.Fatalf("QRCodePNG() error = %v", err)
+	}
+	if !strings.HasPrefix(string(png), "\x89PNG") {
+		t.Error("Expected PNG data")
+	}
+
+	text, err := QRCodeText("hello")
+	if err != nil {
+		t.Fatalf("QRCodeText() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if width := len([]rune(lines[0])); width != 21+2*qrQuiet {
+		t.Errorf("Expected a version 1 code with quiet zone, got width %d", width)
+	}
+}
+
+func TestPeerLifetimes(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	started := now.Add(-48 * time.Hour)
+
+	m := NewWireGuardManager(WireGuardConfig{KeyRotation: 30 * 24 * time.Hour}, nil)
+	m.config.Peers = []WireGuardPeer{
+		{Name: "old", PublicKey: "old", Created: now.Add(-31 * 24 * time.Hour)},
+		{Name: "new", PublicKey: "new", Created: now.Add(-time.Hour)},
+	}
+
+	tests := []struct {
+		peer WireGuardPeer
+		want string
+	}{
+		{WireGuardPeer{PublicKey: "a"}, ""},
+		{WireGuardPeer{PublicKey: "b", Disabled: true}, PeerDisabled},
+		{WireGuardPeer{PublicKey: "c", Expires: now}, PeerExpired},
+		{WireGuardPeer{PublicKey: "d", Expires: now.Add(time.Minute)}, ""},
+	}
+	for _, tt := range tests {
+		if got := m.inactiveReason(tt.peer, now); got != tt.want {
+			t.Errorf("inactiveReason(%s) = %q; want %q", tt.peer.PublicKey, got, tt.want)
+		}
+	}
+
+	if !m.rotationDue("old", now) || m.rotationDue("new", now) {
+		t.Error("Expected only the old peer due for rotation")
+	}
+
+	idle := 24 * time.Hour
+	if !isIdle(WireGuardPeer{}, now.Add(-25*time.Hour), started, idle, now) {
+		t.Error("Expected peer without recent handshake to be idle")
+	}
+	if isIdle(WireGuardPeer{}, now.Add(-time.Hour), started, idle, now) {
+		t.Error("Expected peer with recent handshake to be active")
+	}
+	// A freshly provisioned peer gets a full timeout to connect
+	if isIdle(WireGuardPeer{Created: now.Add(-time.Hour)}, time.Time{}, started, idle, now) {
+		t.Error("Expected new peer to be given time for its first handshake")
+	}
+	if !isIdle(WireGuardPeer{}, time.Time{}, started, idle, now) {
+		t.Error("Expected peer that never connected since start to be idle")
+	}
+	if isIdle(WireGuardPeer{}, time.Time{}, started, 0, now) {
+		t.Error("Expected no idle timeout when unset")
+	}
+}
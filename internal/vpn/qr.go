@@ -0,0 +1,61 @@
+// Copyright (C) 2026 Ben Grimm. Licensed under AGPL-3.0 (https://www.gnu.org/licenses/agpl-3.0.txt)
+
+package vpn
+
+import (
+	"strings"
+
+	"rsc.io/qr"
+
+	"grimm.is/flywall/internal/errors"
+)
+
+// qrQuiet is the blank margin around a QR code, in modules.
+const qrQuiet = 2
+
+// QRCodePNG encodes text, typically a client configuration, as a PNG QR
+// code that the WireGuard mobile apps can scan.
+func QRCodePNG(text string) ([]byte, error) {
+	code, err := qr.Encode(text, qr.M)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.KindInternal, "failed to encode QR code")
+	}
+	code.Scale = 6
+	return code.PNG(), nil
+}
+
+// QRCodeText renders text as a QR code for a terminal. Each character
+// covers two rows of modules using half blocks. Light modules are drawn,
+// so the code scans on a terminal with a dark background.
+func QRCodeText(text string) (string, error) {
+	code, err := qr.Encode(text, qr.M)
+	if err != nil {
+		return "", errors.Wrap(err, errors.KindInternal, "failed to encode QR code")
+	}
+
+	light := func(x, y int) bool {
+		if x < 0 || y < 0 || x >= code.Size || y >= code.Size {
+			return true
+		}
+		return !code.Black(x, y)
+	}
+
+	var b strings.Builder
+	for y := -qrQuiet; y < code.Size+qrQuiet; y += 2 {
+		for x := -qrQuiet; x < code.Size+qrQuiet; x++ {
+			top, bottom := light(x, y), light(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
@@ -42,6 +42,8 @@ type WireGuardConfig struct {
 	Table            string              `hcl:"table,optional" json:"table,omitempty"`         // Routing table ID or "auto"/"off"
 	PostUp           []string            `hcl:"post_up,optional" json:"post_up,omitempty"`     // Commands to run after up
 	PostDown         []string            `hcl:"post_down,optional" json:"post_down,omitempty"` // Commands to run after down
+	KeyRotation      time.Duration       `json:"key_rotation,omitempty"`                       // Peer keys older than this are due for rotation
+	IdleTimeout      time.Duration       `json:"idle_timeout,omitempty"`                       // Disable peers without a handshake for this long
 	Peers            []WireGuardPeer     `hcl:"peer,block" json:"peers,omitempty"`
 }
 
@@ -53,6 +55,9 @@ type WireGuardPeer struct {
 	Endpoint            string              `hcl:"endpoint" json:"endpoint,omitempty"`
 	AllowedIPs          []string            `hcl:"allowed_ips" json:"allowed_ips"`
 	PersistentKeepalive int                 `hcl:"persistent_keepalive" json:"persistent_keepalive,omitempty"`
+	Created             time.Time           `json:"created,omitempty"` // When the keys were generated
+	Expires             time.Time           `json:"expires,omitempty"` // Zero never expires
+	Disabled            bool                `json:"disabled,omitempty"`
 }
 
 // WireGuardStatus represents the current WireGuard status.
@@ -67,6 +72,7 @@ type WireGuardStatus struct {
 
 // WireGuardPeerStatus represents a peer's current status.
 type WireGuardPeerStatus struct {
+	Name                string    `json:"name,omitempty"`
 	PublicKey           string    `json:"public_key"`
 	Endpoint            string    `json:"endpoint,omitempty"`
 	AllowedIPs          []string  `json:"allowed_ips"`
@@ -74,8 +80,20 @@ type WireGuardPeerStatus struct {
 	TransferRx          uint64    `json:"transfer_rx"`
 	TransferTx          uint64    `json:"transfer_tx"`
 	PersistentKeepalive int       `json:"persistent_keepalive,omitempty"`
+
+	// Why the peer is kept off the interface: disabled, expired or idle.
+	DisabledReason string `json:"disabled_reason,omitempty"`
+	// The peer's keys are older than key_rotation.
+	RotationDue bool `json:"rotation_due,omitempty"`
 }
 
+// Reasons a configured peer is kept off the interface.
+const (
+	PeerDisabled = "disabled"
+	PeerExpired  = "expired"
+	PeerIdle     = "idle"
+)
+
 // DefaultWireGuardConfig returns sensible defaults.
 func DefaultWireGuardConfig() WireGuardConfig {
 	return WireGuardConfig{
@@ -98,17 +116,23 @@ type WireGuardManager struct {
 	wgClient *wgctrl.Client
 	ctx      context.Context
 	cancel   context.CancelFunc
+
+	started  time.Time
+	idle     map[string]bool // public keys disabled for inactivity
+	rotation map[string]bool // public keys already reported as due for rotation
 }
 
 // NewWireGuardManager creates a new WireGuard manager.
 func NewWireGuardManager(config WireGuardConfig, logger *logging.Logger) *WireGuardManager {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &WireGuardManager{
-		config: config,
-		logger: logger,
-		status: &WireGuardStatus{},
-		ctx:    ctx,
-		cancel: cancel,
+		config:   config,
+		logger:   logger,
+		status:   &WireGuardStatus{},
+		ctx:      ctx,
+		cancel:   cancel,
+		idle:     make(map[string]bool),
+		rotation: make(map[string]bool),
 	}
 }
 
@@ -122,6 +146,9 @@ func (m *WireGuardManager) Start(ctx context.Context) error {
 	// Note: We no longer check for 'wg' binary since we use native Go libraries,
 	// but the kernel module must be loaded.
 
+	// Peers that never completed a handshake are idle from here on
+	m.started = clock.Now()
+
 	// Bring up interface
 	if err := m.Up(); err != nil {
 		return errors.Wrap(err, errors.KindInternal, "failed to bring up wireguard interface")
@@ -168,9 +195,26 @@ func (m *WireGuardManager) Status() ProviderStatus {
 
 // GetConfig returns the current configuration.
 func (m *WireGuardManager) GetConfig() WireGuardConfig {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return m.config
 }
 
+// Reconfigure replaces the configuration and, if the interface is running,
+// re-applies it. Peers are replaced wholesale, so added, removed and
+// rotated peers take effect without restarting the tunnel.
+func (m *WireGuardManager) Reconfigure(cfg WireGuardConfig) error {
+	m.mu.Lock()
+	m.config = cfg
+	running := m.status.Running
+	m.mu.Unlock()
+
+	if !running {
+		return nil
+	}
+	return m.Up()
+}
+
 // IsEnabled returns true if WireGuard integration is enabled.
 func (m *WireGuardManager) IsEnabled() bool {
 	return m.config.Enabled
@@ -210,7 +254,9 @@ func (m *WireGuardManager) monitorLoop() {
 		case <-ticker.C:
 			if err := m.updateStatus(); err != nil {
 				m.logger.Debug("Failed to update WireGuard status", "error", err)
+				continue
 			}
+			m.enforcePeerLifetimes(clock.Now())
 		}
 	}
 }
@@ -248,6 +294,11 @@ func (m *WireGuardManager) updateStatus() error {
 	m.status.ListenPort = device.ListenPort
 	m.status.LastUpdate = clock.Now()
 
+	names := make(map[string]string, len(m.config.Peers))
+	for _, p := range m.config.Peers {
+		names[p.PublicKey] = p.Name
+	}
+
 	// Parse peers
 	peers := make([]WireGuardPeerStatus, 0, len(device.Peers))
 	for _, p := range device.Peers {
@@ -257,6 +308,7 @@ func (m *WireGuardManager) updateStatus() error {
 		}
 
 		peers = append(peers, WireGuardPeerStatus{
+			Name:                names[p.PublicKey.String()],
 			PublicKey:           p.PublicKey.String(),
 			Endpoint:            p.Endpoint.String(),
 			AllowedIPs:          allowedIPs,
@@ -266,11 +318,122 @@ func (m *WireGuardManager) updateStatus() error {
 			PersistentKeepalive: int(p.PersistentKeepaliveInterval.Seconds()),
 		})
 	}
+
+	// Configured peers kept off the interface
+	now := clock.Now()
+	for _, p := range m.config.Peers {
+		reason := m.inactiveReason(p, now)
+		if reason == "" {
+			continue
+		}
+		peers = append(peers, WireGuardPeerStatus{
+			Name:           p.Name,
+			PublicKey:      p.PublicKey,
+			AllowedIPs:     p.AllowedIPs,
+			DisabledReason: reason,
+		})
+	}
+	for i := range peers {
+		peers[i].RotationDue = m.rotationDue(peers[i].PublicKey, now)
+	}
 	m.status.Peers = peers
 
 	return nil
 }
 
+// inactiveReason returns why p must not be configured on the interface, or
+// "" if it may connect. Caller must hold m.mu.
+func (m *WireGuardManager) inactiveReason(p WireGuardPeer, now time.Time) string {
+	switch {
+	case p.Disabled:
+		return PeerDisabled
+	case !p.Expires.IsZero() && !now.Before(p.Expires):
+		return PeerExpired
+	case m.idle[p.PublicKey]:
+		return PeerIdle
+	}
+	return ""
+}
+
+// rotationDue reports whether the keys of the peer with publicKey are older
+// than the configured rotation period. Caller must hold m.mu.
+func (m *WireGuardManager) rotationDue(publicKey string, now time.Time) bool {
+	if m.config.KeyRotation <= 0 {
+		return false
+	}
+	for _, p := range m.config.Peers {
+		if p.PublicKey == publicKey {
+			return !p.Created.IsZero() && now.Sub(p.Created) >= m.config.KeyRotation
+		}
+	}
+	return false
+}
+
+// isIdle reports whether a peer has gone idleTimeout without a handshake.
+// Peers that never completed one are measured from their creation, or from
+// when the manager started, whichever is later.
+func isIdle(p WireGuardPeer, lastHandshake, started time.Time, idleTimeout time.Duration, now time.Time) bool {
+	if idleTimeout <= 0 {
+		return false
+	}
+	since := lastHandshake
+	if since.IsZero() {
+		since = started
+		if p.Created.After(since) {
+			since = p.Created
+		}
+	}
+	return now.Sub(since) >= idleTimeout
+}
+
+// enforcePeerLifetimes removes expired and idle peers from the interface and
+// reports peers whose keys are due for rotation. Removed peers stay in the
+// configuration; an idle peer comes back once its keys are rotated.
+func (m *WireGuardManager) enforcePeerLifetimes(now time.Time) {
+	m.mu.Lock()
+	handshakes := make(map[string]time.Time, len(m.status.Peers))
+	for _, ps := range m.status.Peers {
+		if ps.DisabledReason == "" {
+			handshakes[ps.PublicKey] = ps.LatestHandshake
+		}
+	}
+
+	conf := wgtypes.Config{}
+	for _, p := range m.config.Peers {
+		handshake, onDevice := handshakes[p.PublicKey]
+		if !onDevice {
+			continue
+		}
+		if isIdle(p, handshake, m.started, m.config.IdleTimeout, now) {
+			m.idle[p.PublicKey] = true
+		}
+		if reason := m.inactiveReason(p, now); reason != "" {
+			if key, err := wgtypes.ParseKey(p.PublicKey); err == nil {
+				conf.Peers = append(conf.Peers, wgtypes.PeerConfig{PublicKey: key, Remove: true})
+				m.logger.Warn("Disabling WireGuard peer", "peer", p.Name, "reason", reason)
+			}
+			continue
+		}
+		if m.rotationDue(p.PublicKey, now) && !m.rotation[p.PublicKey] {
+			m.logger.Warn("WireGuard peer keys are due for rotation", "peer", p.Name, "created", p.Created.Format(time.RFC3339))
+			m.rotation[p.PublicKey] = true
+		}
+	}
+	iface := m.config.Interface
+	m.mu.Unlock()
+
+	if len(conf.Peers) == 0 {
+		return
+	}
+	if err := m.wgClient.ConfigureDevice(iface, conf); err != nil {
+		m.logger.Warn("Failed to remove inactive WireGuard peers", "error", err)
+		return
+	}
+	if err := m.updateStatus(); err != nil {
+		m.logger.Debug("Failed to update WireGuard status", "error", err)
+	}
+}
+
 // Up brings WireGuard interface up using netlink and wgctrl.
 // Replaces wg-quick dependency.
 func (m *WireGuardManager) Up() error {
@@ -345,9 +508,24 @@ func (m *WireGuardManager) Up() error {
 		conf.FirewallMark = &mark
 	}
 
-	// Set Peers
+	// Set Peers, leaving out disabled, expired and idle ones
+	now := clock.Now()
+	inactive := make(map[string]bool)
+	m.mu.RLock()
+	for _, p := range m.config.Peers {
+		if reason := m.inactiveReason(p, now); reason != "" {
+			m.logger.Info("Skipping inactive peer", "peer", p.Name, "reason", reason)
+			inactive[p.PublicKey] = true
+		}
+	}
+	m.mu.RUnlock()
+
 	var peers []wgtypes.PeerConfig
 	for _, p := range m.config.Peers {
+		if inactive[p.PublicKey] {
+			continue
+		}
+
 		pubKey, err := wgtypes.ParseKey(p.PublicKey)
 		if err != nil {
 			m.logger.Warn("Invalid peer public key, skipping", "peer", p.Name, "error", err)
@@ -461,6 +639,9 @@ func (m *WireGuardManager) Up() error {
 	}
 
 	for _, p := range m.config.Peers {
+		if inactive[p.PublicKey] {
+			continue
+		}
 		for _, ipRange := range p.AllowedIPs {
 			_, dst, err := net.ParseCIDR(ipRange)
 			if err != nil {
@@ -168,6 +168,30 @@ func main() {
 			os.Exit(1)
 		}
 
+	case "alert":
+		if err := cmd.RunAlert(os.Args[2:]); err != nil {
+			printer.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+
+	case "wireguard", "wg":
+		if err := cmd.RunWireGuard(os.Args[2:]); err != nil {
+			printer.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+
+	case "trace":
+		if err := cmd.RunTrace(os.Args[2:]); err != nil {
+			printer.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+
+	case "conntrack":
+		if err := cmd.RunConntrack(os.Args[2:]); err != nil {
+			printer.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+
 	case "diff":
 		if len(os.Args) < 3 {
 			printer.Println("Usage: " + brand.BinaryName + " diff <config-file>")
@@ -179,6 +203,14 @@ func main() {
 		}
 
 	case "show":
+		if len(os.Args) > 2 && os.Args[2] == "routing" {
+			if err := cmd.RunShowRouting(os.Args[3:]); err != nil {
+				printer.Fprintf(os.Stderr, "Show failed: %v\n", err)
+				os.Exit(1)
+			}
+			break
+		}
+
 		showFlags := flag.NewFlagSet("show", flag.ExitOnError)
 		summary := showFlags.Bool("summary", false, "Show configuration summary")
 		showFlags.BoolVar(summary, "s", false, "Show configuration summary (short)")
@@ -285,9 +317,10 @@ func main() {
 		apiKey := consoleFlags.String("api-key", "", "API Key for remote authentication")
 		insecure := consoleFlags.Bool("insecure", false, "Skip TLS verification")
 		debug := consoleFlags.Bool("debug", false, "Enable debug logging to tui.log")
+		role := consoleFlags.String("role", "", "Restrict the session to a user role's permissions")
 		consoleFlags.Parse(os.Args[2:])
 
-		cmd.RunConsole(*remote, *apiKey, *insecure, *debug)
+		cmd.RunConsole(*remote, *apiKey, *insecure, *debug, *role)
 
 	case "config":
 		// Configuration management commands
@@ -478,6 +511,14 @@ func main() {
 				cmd.RunIPSet([]string{"help"})
 			case "config":
 				cmd.RunConfig([]string{"help"})
+			case "alert":
+				cmd.RunAlert([]string{"help"})
+			case "wireguard", "wg":
+				cmd.RunWireGuard([]string{"help"})
+			case "trace":
+				cmd.RunTrace([]string{"help"})
+			case "conntrack":
+				cmd.RunConntrack([]string{"help"})
 			default:
 				printer.Printf("No detailed help available for '%s'\n", os.Args[2])
 				printUsage()
@@ -515,14 +556,23 @@ Management Commands:
             Subcommands: show, edit, validate, export
   ipset     Manage IPSet blocklists
             Subcommands: list, update, add, remove, info
+  alert     Manage alerts and silences
+            Subcommands: list, ack, resolve, silence, help
+  wireguard Provision WireGuard road-warrior clients (alias: wg)
+            Subcommands: add, rotate, help
 
 Utility Commands:
   check     Validate configuration file
             Options: --verbose (-v)
   show      Display firewall rules
             Options: --summary (-s), --remote (-r) <url>, --api-key (-k) <key>
+            Subcommands: routing (BGP/OSPF/BFD state; --routes, --json)
   log       View and stream system logs
             Options: -f (follow), -n (lines), --remote <url>
+  trace     Trace a packet through the ruleset ("why was this dropped?")
+            Options: --in (-i), --src, --dst, --dport, --config (-c) <file>
+  conntrack Show, kill or watch tracked connections
+            Commands: list, kill, watch; filters: --zone, --ip, --port, --state
   diff      Compare two configuration files
   import    Import configuration from other firewalls
   console   Interactive TUI dashboard
@@ -6,6 +6,7 @@
  */
 
 import { writable, derived, get } from 'svelte/store';
+import { createCredential, getAssertion } from '$lib/utils/webauthn';
 
 // ============================================================================
 // Theme State
@@ -87,6 +88,18 @@ export interface TopologyGraph {
     links: TopologyLink[];
 }
 
+// A provisioned WireGuard client, returned once: the private key isn't stored
+export interface WireGuardClient {
+    connection: string;
+    name: string;
+    public_key: string;
+    addresses: string[];
+    expires?: string;
+    client_config: string;
+    qr_code: string; // base64 PNG
+    apply_error?: string; // Peer staged but the apply failed
+}
+
 export const topology = writable<TopologyGraph>({ nodes: [], links: [] });
 export const networkDevices = writable<any[]>([]);
 export const hasPendingChanges = writable<boolean>(false);
@@ -443,6 +456,41 @@ export const api = {
         return data;
     },
 
+    // Second factor. Each call that completes the login returns the same
+    // shape as login() and replaces the pending session.
+
+    async mfaVerify(method: 'totp' | 'recovery', code: string) {
+        const data = await this.post(`/auth/mfa/${method}`, { code });
+        authStatus.set(data);
+        return data;
+    },
+
+    async mfaWebAuthnLogin() {
+        const options = await this.post('/auth/mfa/webauthn/begin', {});
+        const assertion = await getAssertion(options);
+        const data = await this.post('/auth/mfa/webauthn/finish', assertion);
+        authStatus.set(data);
+        return data;
+    },
+
+    async mfaTOTPEnroll() {
+        return this.post('/auth/mfa/totp/enroll', {});
+    },
+
+    async mfaTOTPConfirm(code: string) {
+        const data = await this.post('/auth/mfa/totp/confirm', { code });
+        authStatus.set(data);
+        return data;
+    },
+
+    async mfaWebAuthnRegister(name: string) {
+        const options = await this.post('/auth/mfa/webauthn/register/begin', {});
+        const credential = await createCredential(options);
+        const data = await this.post('/auth/mfa/webauthn/register/finish', { name, credential });
+        authStatus.set(data);
+        return data;
+    },
+
     async logout() {
         await fetch(`${API_BASE}/auth/logout`, { method: 'POST' });
         authStatus.set(null);
@@ -1051,6 +1099,26 @@ export const api = {
         return apiRequest('/wireguard/generate-key', { method: 'POST' });
     },
 
+    // Provision a road-warrior peer (staged); returns the client config and a base64 PNG QR code
+    async provisionWireGuardPeer(connection: string, name: string, expires?: string): Promise<WireGuardClient> {
+        const result = await apiRequest('/wireguard/provision', {
+            method: 'POST',
+            body: JSON.stringify({ connection, name, expires }),
+        });
+        await this.reloadConfig();
+        return result;
+    },
+
+    // Replace a peer's keys (staged); the old config stops working once applied
+    async rotateWireGuardPeer(connection: string, name: string): Promise<WireGuardClient> {
+        const result = await apiRequest('/wireguard/rotate', {
+            method: 'POST',
+            body: JSON.stringify({ connection, name }),
+        });
+        await this.reloadConfig();
+        return result;
+    },
+
     // ========================================
     // Users
     // ========================================
@@ -0,0 +1,75 @@
+// WebAuthn helpers: the API exchanges binary fields as base64url strings
+
+function fromBase64url(value: string): ArrayBuffer {
+    const base64 = value.replace(/-/g, '+').replace(/_/g, '/');
+    const padded = base64 + '='.repeat((4 - (base64.length % 4)) % 4);
+    const binary = atob(padded);
+    const bytes = new Uint8Array(binary.length);
+    for (let i = 0; i < binary.length; i++) {
+        bytes[i] = binary.charCodeAt(i);
+    }
+    return bytes.buffer;
+}
+
+function toBase64url(buffer: ArrayBuffer): string {
+    let binary = '';
+    const bytes = new Uint8Array(buffer);
+    for (let i = 0; i < bytes.byteLength; i++) {
+        binary += String.fromCharCode(bytes[i]);
+    }
+    return btoa(binary).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '');
+}
+
+function descriptors(list: any[] | null | undefined): PublicKeyCredentialDescriptor[] {
+    return (list || []).map((c) => ({ type: c.type, id: fromBase64url(c.id) }));
+}
+
+export function webauthnSupported(): boolean {
+    return typeof window !== 'undefined' && !!window.PublicKeyCredential;
+}
+
+// Runs navigator.credentials.create() with options from the server and
+// returns the credential in the shape the server expects.
+export async function createCredential(options: any): Promise<any> {
+    const cred = (await navigator.credentials.create({
+        publicKey: {
+            ...options,
+            challenge: fromBase64url(options.challenge),
+            user: { ...options.user, id: fromBase64url(options.user.id) },
+            excludeCredentials: descriptors(options.excludeCredentials),
+        },
+    })) as PublicKeyCredential;
+    const response = cred.response as AuthenticatorAttestationResponse;
+    return {
+        id: cred.id,
+        rawId: toBase64url(cred.rawId),
+        type: cred.type,
+        response: {
+            clientDataJSON: toBase64url(response.clientDataJSON),
+            attestationObject: toBase64url(response.attestationObject),
+        },
+    };
+}
+
+// Runs navigator.credentials.get() with options from the server and returns
+// the assertion in the shape the server expects.
+export async function getAssertion(options: any): Promise<any> {
+    const cred = (await navigator.credentials.get({
+        publicKey: {
+            ...options,
+            challenge: fromBase64url(options.challenge),
+            allowCredentials: descriptors(options.allowCredentials),
+        },
+    })) as PublicKeyCredential;
+    const response = cred.response as AuthenticatorAssertionResponse;
+    return {
+        id: cred.id,
+        rawId: toBase64url(cred.rawId),
+        type: cred.type,
+        response: {
+            clientDataJSON: toBase64url(response.clientDataJSON),
+            authenticatorData: toBase64url(response.authenticatorData),
+            signature: toBase64url(response.signature),
+        },
+    };
+}